@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tungetti/igor/internal/cli"
+	"github.com/tungetti/igor/internal/config"
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/distro"
+	"github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/install/bundle"
+	"github.com/tungetti/igor/internal/install/steps"
+	"github.com/tungetti/igor/internal/pkg"
+	"github.com/tungetti/igor/internal/privilege"
+)
+
+// cmdBundle handles the bundle command.
+// It resolves the packages igor install would install on this system,
+// downloads them with their dependencies into the bundle directory and
+// writes the manifest read by igor install --from-bundle.
+func (c *CLI) cmdBundle(result *cli.ParseResult) int {
+	flags := result.BundleFlags.InstallFlags()
+	dryRun := c.config.DryRun
+
+	dir, err := filepath.Abs(result.Args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid bundle directory: %v\n", err)
+		return constants.ExitValidation.Int()
+	}
+
+	priv := privilege.NewManager()
+	if !dryRun {
+		if err := priv.RequireRoot(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return constants.ExitPermission.Int()
+		}
+	}
+
+	ctx, cancel := c.longCommandContext()
+	defer cancel()
+
+	// In dry-run mode the downloads run against a recording executor, so
+	// their commands are shown instead of run.
+	realExecutor := newLongCommandExecutor(priv)
+	var executor exec.Executor = realExecutor
+	var recorder *exec.RecordingExecutor
+	if dryRun {
+		recorder = exec.NewRecordingExecutor(realExecutor)
+		executor = recorder
+	}
+
+	dist, pm, err := detectPackageManager(ctx, executor)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return constants.ExitError.Int()
+	}
+
+	bundler, ok := pm.(pkg.BundleManager)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: offline bundles are not supported with %s\n", pm.Name())
+		return constants.ExitError.Int()
+	}
+
+	// The bundle may be created on a machine without the target GPU, so
+	// the driver branch is only matched to the GPU when one is found.
+	gpuInfo, err := newDetectionOrchestrator(realExecutor).DetectAll(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: GPU detection failed: %v\n", err)
+		gpuInfo = nil
+	}
+
+	resolved, err := resolveInstallPlan(ctx, dist, pm, gpuInfo, flags, c.config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return constants.ExitValidation.Int()
+	}
+	for _, warning := range resolved.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
+	packages, err := bundlePackages(dist, resolved)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return constants.ExitError.Int()
+	}
+
+	var out io.Writer = os.Stdout
+	if c.config.IsSilent() {
+		out = io.Discard
+	}
+
+	fmt.Fprintln(out, bundleHeader(dist, resolved, dir, dryRun))
+	for _, name := range packages {
+		fmt.Fprintf(out, "  %s\n", name)
+	}
+
+	if !dryRun {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to create bundle directory: %v\n", err)
+			return constants.ExitError.Int()
+		}
+	}
+
+	err = bundler.DownloadBundle(ctx, dir, packages...)
+	if recorder != nil {
+		for _, cmd := range recorder.Commands() {
+			if !cmd.Probe {
+				fmt.Fprintf(out, "    %s\n", formatRecordedCommand(cmd))
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to download the bundle: %v\n", err)
+		return constants.ExitError.Int()
+	}
+
+	if dryRun {
+		fmt.Fprintln(out, "[dry-run] No changes were made")
+		return constants.ExitSuccess.Int()
+	}
+
+	if err := bundle.Write(dir, newBundleManifest(dist, pm.Name(), resolved, packages)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return constants.ExitError.Int()
+	}
+
+	fmt.Fprintf(out, "Bundle written to %s\n", dir)
+	fmt.Fprintf(out, "Install it on %s with: sudo igor install --from-bundle %s\n", dist, dir)
+	return constants.ExitSuccess.Int()
+}
+
+// bundleHeader returns the first line printed by the bundle command.
+func bundleHeader(dist *distro.Distribution, plan *installPlan, dir string, dryRun bool) string {
+	header := strings.Replace(installHeader(dist, plan, dryRun), "Installing", "Bundling", 1)
+	return fmt.Sprintf("%s into %s:", header, dir)
+}
+
+// bundlePackages returns the packages the package installation step would
+// install for the plan.
+func bundlePackages(dist *distro.Distribution, plan *installPlan) ([]string, error) {
+	ctx := install.NewContext(
+		install.WithDistroInfo(dist),
+		install.WithDriverVersion(plan.DriverVersion),
+		install.WithComponents(plan.Components),
+	)
+
+	packages, err := steps.NewPackageInstallationStep(
+		steps.WithAdditionalPackages(plan.AdditionalPackages...),
+	).Packages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(packages) == 0 {
+		return nil, fmt.Errorf("no packages to bundle for %s", dist)
+	}
+	return packages, nil
+}
+
+// newBundleManifest returns the manifest of a bundle of the plan.
+func newBundleManifest(dist *distro.Distribution, pmName string, plan *installPlan, packages []string) *bundle.Manifest {
+	m := bundle.NewManifest(dist, pmName)
+	m.DriverVersion = plan.DriverVersion
+	m.Components = append([]string{}, plan.Components...)
+	m.AdditionalPackages = append([]string{}, plan.AdditionalPackages...)
+	m.Packages = append(m.Packages, packages...)
+	return m
+}
+
+// loadInstallBundle reads the bundle in dir for igor install --from-bundle
+// and checks it was built for this system. It returns the absolute bundle
+// directory and its manifest.
+func loadInstallBundle(dir string, dist *distro.Distribution, pm pkg.Manager) (string, *bundle.Manifest, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid bundle directory: %w", err)
+	}
+
+	manifest, err := bundle.Load(dir)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := manifest.Check(dist, pm.Name()); err != nil {
+		return "", nil, err
+	}
+	if _, ok := pm.(pkg.BundleManager); !ok {
+		return "", nil, fmt.Errorf("offline bundles are not supported with %s", pm.Name())
+	}
+	return dir, manifest, nil
+}
+
+// bundleInstallPlan returns the installation plan of a bundle. The driver
+// version and components are those the bundle was built for.
+func bundleInstallPlan(m *bundle.Manifest, flags cli.InstallFlags, cfg *config.Config) *installPlan {
+	return &installPlan{
+		DriverVersion:      m.DriverVersion,
+		Components:         append([]string{}, m.Components...),
+		AdditionalPackages: append([]string{}, m.AdditionalPackages...),
+		Reinstall:          flags.Force || cfg.ForceInstall,
+	}
+}
+
+// removeBundleRepository removes the bundle repository added by a completed
+// installation. The repository is temporary, so it is removed from the
+// state of the run too and a later rollback of the run leaves it alone.
+func removeBundleRepository(errOut io.Writer, pm pkg.Manager, ctx *install.Context) {
+	if ctx.GetStateString(steps.StateRepositoryName) != pkg.BundleRepositoryName {
+		return
+	}
+
+	if err := pm.RemoveRepository(ctx.Context(), pkg.BundleRepositoryName); err != nil {
+		fmt.Fprintf(errOut, "Warning: failed to remove the bundle repository: %v\n", err)
+		return
+	}
+	ctx.DeleteState(steps.StateRepositoryConfigured)
+	ctx.DeleteState(steps.StateRepositoryName)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/cli"
+	"github.com/tungetti/igor/internal/config"
+	"github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/install/bundle"
+	"github.com/tungetti/igor/internal/install/steps"
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+func TestBundleHeader(t *testing.T) {
+	plan := &installPlan{DriverVersion: "550"}
+
+	assert.Equal(t, "Bundling NVIDIA driver 550 on Debian 12 into /srv/nvidia:",
+		bundleHeader(newTestListDistro(), plan, "/srv/nvidia", false))
+	assert.Equal(t, "[dry-run] Bundling NVIDIA driver 550 on Debian 12 into /srv/nvidia:",
+		bundleHeader(newTestListDistro(), plan, "/srv/nvidia", true))
+}
+
+func TestBundlePackages(t *testing.T) {
+	packages, err := bundlePackages(newTestListDistro(), &installPlan{
+		DriverVersion:      "550",
+		AdditionalPackages: []string{"nvidia-cuda-toolkit"},
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, packages, "nvidia-driver-550")
+	assert.Contains(t, packages, "nvidia-cuda-toolkit")
+}
+
+func TestNewBundleManifest(t *testing.T) {
+	plan := &installPlan{DriverVersion: "550", Components: []string{"cuda"}}
+
+	m := newBundleManifest(newTestListDistro(), "apt", plan, []string{"nvidia-driver-550"})
+
+	assert.Equal(t, "debian", m.Distribution)
+	assert.Equal(t, "12", m.DistributionVersion)
+	assert.Equal(t, "apt", m.PackageManager)
+	assert.Equal(t, "550", m.DriverVersion)
+	assert.Equal(t, []string{"cuda"}, m.Components)
+	assert.Equal(t, []string{"nvidia-driver-550"}, m.Packages)
+}
+
+func TestLoadInstallBundle(t *testing.T) {
+	dir := t.TempDir()
+	pm, _ := newTestHoldManager()
+	dist := newTestListDistro()
+	require.NoError(t, bundle.Write(dir, newBundleManifest(dist, pm.Name(), &installPlan{DriverVersion: "550"}, nil)))
+
+	got, m, err := loadInstallBundle(dir, dist, pm)
+	require.NoError(t, err)
+
+	assert.Equal(t, dir, got)
+	assert.Equal(t, "550", m.DriverVersion)
+}
+
+func TestLoadInstallBundle_OtherDistribution(t *testing.T) {
+	dir := t.TempDir()
+	pm, _ := newTestHoldManager()
+	require.NoError(t, bundle.Write(dir, newBundleManifest(newTestFedoraDistro(), pm.Name(), &installPlan{}, nil)))
+
+	_, _, err := loadInstallBundle(dir, newTestListDistro(), pm)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bundle was built for fedora 40")
+}
+
+func TestLoadInstallBundle_NotABundle(t *testing.T) {
+	pm, _ := newTestHoldManager()
+
+	_, _, err := loadInstallBundle(t.TempDir(), newTestListDistro(), pm)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not a bundle")
+}
+
+func TestLoadInstallBundle_Unsupported(t *testing.T) {
+	dir := t.TempDir()
+	dist := newTestListDistro()
+	require.NoError(t, bundle.Write(dir, newBundleManifest(dist, "apt", &installPlan{}, nil)))
+
+	_, _, err := loadInstallBundle(dir, dist, newTestListManager())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not supported with apt")
+}
+
+func TestBundleInstallPlan(t *testing.T) {
+	m := &bundle.Manifest{
+		DriverVersion:      "550",
+		Components:         []string{"cuda"},
+		AdditionalPackages: []string{"cuda-toolkit-12-4"},
+	}
+
+	plan := bundleInstallPlan(m, cli.InstallFlags{Force: true}, config.DefaultConfig())
+
+	assert.Equal(t, "550", plan.DriverVersion)
+	assert.Equal(t, []string{"cuda"}, plan.Components)
+	assert.Equal(t, []string{"cuda-toolkit-12-4"}, plan.AdditionalPackages)
+	assert.True(t, plan.Reinstall)
+}
+
+func TestRemoveBundleRepository(t *testing.T) {
+	pm, mockExec := newTestHoldManager()
+	ctx := install.NewContext(install.WithContext(context.Background()))
+	ctx.SetState(steps.StateRepositoryConfigured, true)
+	ctx.SetState(steps.StateRepositoryName, pkg.BundleRepositoryName)
+
+	var errOut bytes.Buffer
+	removeBundleRepository(&errOut, pm, ctx)
+
+	assert.True(t, mockExec.WasCalledWith("rm", "-f", "/etc/apt/sources.list.d/igor-bundle.list"))
+	assert.False(t, ctx.GetStateBool(steps.StateRepositoryConfigured))
+	assert.Empty(t, ctx.GetStateString(steps.StateRepositoryName))
+	assert.Empty(t, errOut.String())
+}
+
+func TestRemoveBundleRepository_OtherRepository(t *testing.T) {
+	pm, mockExec := newTestHoldManager()
+	ctx := install.NewContext(install.WithContext(context.Background()))
+	ctx.SetState(steps.StateRepositoryName, "nvidia-cuda")
+
+	var errOut bytes.Buffer
+	removeBundleRepository(&errOut, pm, ctx)
+
+	assert.Equal(t, 0, mockExec.CallCount())
+	assert.Equal(t, "nvidia-cuda", ctx.GetStateString(steps.StateRepositoryName))
+}
+
+func TestRemoveBundleRepository_Failure(t *testing.T) {
+	pm, mockExec := newTestHoldManager()
+	mockExec.SetDefaultResponse(exec.FailureResult(1, "permission denied"))
+	ctx := install.NewContext(install.WithContext(context.Background()))
+	ctx.SetState(steps.StateRepositoryName, pkg.BundleRepositoryName)
+
+	var errOut bytes.Buffer
+	removeBundleRepository(&errOut, pm, ctx)
+
+	assert.Contains(t, errOut.String(), "Warning: failed to remove the bundle repository")
+	assert.Equal(t, pkg.BundleRepositoryName, ctx.GetStateString(steps.StateRepositoryName))
+}
@@ -1,13 +1,22 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/tungetti/igor/internal/cli"
 	"github.com/tungetti/igor/internal/config"
 	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/distro"
+	"github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/logging"
+	"github.com/tungetti/igor/internal/pkg"
+	"github.com/tungetti/igor/internal/pkg/factory"
+	"github.com/tungetti/igor/internal/privilege"
 	"github.com/tungetti/igor/internal/ui"
 )
 
@@ -48,6 +57,15 @@ func (c *CLI) Run(args []string) int {
 		return c.showHelp(result)
 	}
 
+	// Every command but version works on packages, so an invalid override
+	// file fails them all, rather than installing the wrong packages
+	if result.Command != cli.CommandVersion {
+		if err := c.loadPackageCatalog(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading package catalog: %v\n", err)
+			return constants.ExitError.Int()
+		}
+	}
+
 	// Execute command
 	return c.executeCommand(result)
 }
@@ -90,6 +108,71 @@ func (c *CLI) applyGlobalFlags(flags cli.GlobalFlags) {
 	}
 }
 
+// commandContext returns a context bounded by the configured overall timeout.
+func (c *CLI) commandContext() (context.Context, context.CancelFunc) {
+	timeout := c.config.Timeout
+	if timeout <= 0 {
+		timeout = constants.DefaultTimeout
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// longCommandContext returns the context for commands that change the system.
+// Package operations and DKMS builds routinely exceed the default timeout, so
+// the configured timeout is raised to at least constants.LongTimeout. The
+// context is also cancelled on SIGINT and SIGTERM.
+func (c *CLI) longCommandContext() (context.Context, context.CancelFunc) {
+	timeout := c.config.Timeout
+	if timeout < constants.LongTimeout {
+		timeout = constants.LongTimeout
+	}
+
+	ctx, cancelTimeout := context.WithTimeout(context.Background(), timeout)
+	ctx, cancelSignal := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+
+	return ctx, func() {
+		cancelSignal()
+		cancelTimeout()
+	}
+}
+
+// newLongCommandExecutor creates an executor for commands that change the
+// system, allowing individual package operations up to constants.LongTimeout.
+func newLongCommandExecutor(priv *privilege.Manager) *exec.RealExecutor {
+	opts := exec.DefaultOptions()
+	opts.Timeout = constants.LongTimeout
+	return exec.NewExecutor(opts, priv)
+}
+
+// stepLogger returns the logger passed to workflow steps.
+// Step logs are only shown in verbose mode; otherwise the progress lines
+// printed by each command are the only output.
+func (c *CLI) stepLogger() logging.Logger {
+	if !c.config.IsVerbose() {
+		return logging.NewNop()
+	}
+	opts := logging.DefaultOptions()
+	opts.Level = logging.LevelDebug
+	return logging.New(opts)
+}
+
+// detectPackageManager detects the running distribution and creates the
+// matching package manager.
+func detectPackageManager(ctx context.Context, executor exec.Executor) (*distro.Distribution, pkg.Manager, error) {
+	detector := distro.NewDetector(executor, nil)
+	dist, err := detector.Detect(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to detect distribution: %w", err)
+	}
+
+	pm, err := factory.NewFactory(executor, nil, detector).CreateForDistribution(dist)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return dist, pm, nil
+}
+
 // showHelp displays help information and returns an exit code.
 func (c *CLI) showHelp(result *cli.ParseResult) int {
 	if result.HelpCommand != "" {
@@ -113,6 +196,20 @@ func (c *CLI) executeCommand(result *cli.ParseResult) int {
 		return c.cmdDetect(result)
 	case cli.CommandList:
 		return c.cmdList(result)
+	case cli.CommandDoctor:
+		return c.cmdDoctor(result)
+	case cli.CommandPlan:
+		return c.cmdPlan(result)
+	case cli.CommandRollback:
+		return c.cmdRollback(result)
+	case cli.CommandHold, cli.CommandUnhold:
+		return c.cmdHold(result)
+	case cli.CommandHistory:
+		return c.cmdHistory(result)
+	case cli.CommandBundle:
+		return c.cmdBundle(result)
+	case cli.CommandPackages:
+		return c.cmdPackages(result)
 	case cli.CommandNone:
 		// No command specified - launch the interactive TUI
 		return c.cmdTUI()
@@ -144,75 +241,3 @@ func (c *CLI) cmdVersion() int {
 	fmt.Print(c.parser.VersionString())
 	return constants.ExitSuccess.Int()
 }
-
-// cmdInstall handles the install command.
-// TODO: Implement actual installation logic in future sprints.
-func (c *CLI) cmdInstall(result *cli.ParseResult) int {
-	if c.config.IsVerbose() {
-		fmt.Println("Install command called")
-		fmt.Printf("  Driver version: %s\n", result.InstallFlags.DriverVersion)
-		fmt.Printf("  CUDA version: %s\n", result.InstallFlags.CUDAVersion)
-		fmt.Printf("  Install CUDA: %v\n", result.InstallFlags.InstallCUDA)
-		fmt.Printf("  Force: %v\n", result.InstallFlags.Force)
-		fmt.Printf("  Skip reboot: %v\n", result.InstallFlags.SkipReboot)
-		fmt.Printf("  Dry run: %v\n", c.config.DryRun)
-	}
-
-	if c.config.DryRun {
-		fmt.Println("[dry-run] Would install NVIDIA drivers")
-		return constants.ExitSuccess.Int()
-	}
-
-	// Placeholder for actual implementation
-	fmt.Println("Install command not yet implemented")
-	return constants.ExitSuccess.Int()
-}
-
-// cmdUninstall handles the uninstall command.
-// TODO: Implement actual uninstallation logic in future sprints.
-func (c *CLI) cmdUninstall(result *cli.ParseResult) int {
-	if c.config.IsVerbose() {
-		fmt.Println("Uninstall command called")
-		fmt.Printf("  Purge: %v\n", result.UninstallFlags.Purge)
-		fmt.Printf("  Keep config: %v\n", result.UninstallFlags.KeepConfig)
-		fmt.Printf("  Dry run: %v\n", c.config.DryRun)
-	}
-
-	if c.config.DryRun {
-		fmt.Println("[dry-run] Would uninstall NVIDIA drivers")
-		return constants.ExitSuccess.Int()
-	}
-
-	// Placeholder for actual implementation
-	fmt.Println("Uninstall command not yet implemented")
-	return constants.ExitSuccess.Int()
-}
-
-// cmdDetect handles the detect command.
-// TODO: Implement actual GPU detection logic in future sprints.
-func (c *CLI) cmdDetect(result *cli.ParseResult) int {
-	if c.config.IsVerbose() {
-		fmt.Println("Detect command called")
-		fmt.Printf("  JSON output: %v\n", result.DetectFlags.JSON)
-		fmt.Printf("  Brief: %v\n", result.DetectFlags.Brief)
-	}
-
-	// Placeholder for actual implementation
-	fmt.Println("Detect command not yet implemented")
-	return constants.ExitSuccess.Int()
-}
-
-// cmdList handles the list command.
-// TODO: Implement actual driver listing logic in future sprints.
-func (c *CLI) cmdList(result *cli.ParseResult) int {
-	if c.config.IsVerbose() {
-		fmt.Println("List command called")
-		fmt.Printf("  Installed: %v\n", result.ListFlags.Installed)
-		fmt.Printf("  Available: %v\n", result.ListFlags.Available)
-		fmt.Printf("  JSON output: %v\n", result.ListFlags.JSON)
-	}
-
-	// Placeholder for actual implementation
-	fmt.Println("List command not yet implemented")
-	return constants.ExitSuccess.Int()
-}
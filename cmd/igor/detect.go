@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tungetti/igor/internal/cli"
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/gpu"
+	"github.com/tungetti/igor/internal/gpu/kernel"
+	"github.com/tungetti/igor/internal/gpu/nouveau"
+	"github.com/tungetti/igor/internal/gpu/nvidia"
+	"github.com/tungetti/igor/internal/gpu/pci"
+	"github.com/tungetti/igor/internal/gpu/smi"
+	"github.com/tungetti/igor/internal/gpu/validator"
+)
+
+// cmdDetect handles the detect command.
+// It runs the full GPU detection orchestrator and renders the result as
+// a brief summary, a full human-readable report, or versioned JSON.
+func (c *CLI) cmdDetect(result *cli.ParseResult) int {
+	ctx, cancel := c.commandContext()
+	defer cancel()
+
+	executor := exec.NewExecutor(exec.DefaultOptions(), nil)
+	orchestrator := newDetectionOrchestrator(executor)
+
+	info, err := orchestrator.DetectAll(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: GPU detection failed: %v\n", err)
+		return constants.ExitError.Int()
+	}
+
+	switch {
+	case result.DetectFlags.JSON:
+		if err := writeDetectJSON(os.Stdout, info); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to encode detection report: %v\n", err)
+			return constants.ExitError.Int()
+		}
+	case result.DetectFlags.Brief:
+		writeDetectBrief(os.Stdout, info)
+	default:
+		writeDetectReport(os.Stdout, info)
+	}
+
+	return constants.ExitSuccess.Int()
+}
+
+// newDetectionOrchestrator creates a GPU orchestrator wired with all detectors.
+func newDetectionOrchestrator(executor exec.Executor) *gpu.OrchestratorImpl {
+	return gpu.NewOrchestrator(
+		gpu.WithPCIScanner(pci.NewScanner()),
+		gpu.WithGPUDatabase(nvidia.NewDatabase()),
+		gpu.WithSMIParser(smi.NewParser(executor)),
+		gpu.WithNouveauDetector(nouveau.NewDetector()),
+		gpu.WithKernelDetector(kernel.NewDetector(kernel.WithExecutor(executor))),
+		gpu.WithSystemValidator(validator.NewValidator(validator.WithExecutor(executor))),
+	)
+}
+
+// writeDetectJSON writes the versioned detection report as indented JSON.
+func writeDetectJSON(w io.Writer, info *gpu.GPUInfo) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(gpu.NewReport(info))
+}
+
+// writeDetectBrief writes a condensed, one-line-per-item detection summary.
+func writeDetectBrief(w io.Writer, info *gpu.GPUInfo) {
+	report := gpu.NewReport(info)
+
+	if len(report.GPUs) == 0 {
+		fmt.Fprintln(w, "GPU: none detected")
+	}
+	for _, g := range report.GPUs {
+		fmt.Fprintf(w, "GPU %d: %s (%s)\n", g.Index, g.Name, g.Architecture)
+	}
+
+	fmt.Fprintf(w, "Driver: %s\n", driverSummary(report.Driver))
+
+	if report.Kernel != nil {
+		fmt.Fprintf(w, "Kernel: %s\n", report.Kernel.Version)
+	}
+
+	if report.Validation != nil {
+		fmt.Fprintf(w, "Validation: %s\n", validationSummary(report.Validation))
+	}
+
+	if len(report.Warnings) > 0 {
+		fmt.Fprintf(w, "Warnings: %d\n", len(report.Warnings))
+	}
+}
+
+// writeDetectReport writes the full human-readable detection report.
+func writeDetectReport(w io.Writer, info *gpu.GPUInfo) {
+	report := gpu.NewReport(info)
+
+	title := "NVIDIA GPU Detection Results"
+	fmt.Fprintln(w, title)
+	fmt.Fprintln(w, strings.Repeat("=", len(title)))
+	fmt.Fprintln(w)
+
+	if len(report.GPUs) == 0 {
+		fmt.Fprintln(w, "No NVIDIA GPU detected")
+		fmt.Fprintln(w)
+	}
+	for _, g := range report.GPUs {
+		fmt.Fprintf(w, "GPU #%d: %s\n", g.Index+1, g.Name)
+		fmt.Fprintf(w, "  - PCI Address: %s\n", g.PCIAddress)
+		fmt.Fprintf(w, "  - Device ID: %s:%s\n", g.VendorID, g.DeviceID)
+		fmt.Fprintf(w, "  - Architecture: %s\n", g.Architecture)
+		if g.ComputeCapability != "" {
+			fmt.Fprintf(w, "  - Compute Capability: %s\n", g.ComputeCapability)
+		}
+		if g.MinDriverVersion != "" {
+			fmt.Fprintf(w, "  - Minimum Driver: %s\n", g.MinDriverVersion)
+		}
+		if g.MemoryMiB > 0 {
+			fmt.Fprintf(w, "  - Memory: %d MiB\n", g.MemoryMiB)
+		}
+		if g.KernelDriver != "" {
+			fmt.Fprintf(w, "  - Kernel Driver: %s\n", g.KernelDriver)
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintln(w, "Driver Status:")
+	fmt.Fprintf(w, "  - Current Driver: %s\n", driverSummary(report.Driver))
+	if report.Driver != nil && report.Driver.CUDAVersion != "" {
+		fmt.Fprintf(w, "  - CUDA Version: %s\n", report.Driver.CUDAVersion)
+	}
+	if report.Nouveau != nil {
+		fmt.Fprintf(w, "  - Nouveau: %s\n", nouveauSummary(report.Nouveau))
+	}
+	fmt.Fprintln(w)
+
+	if report.Kernel != nil {
+		fmt.Fprintln(w, "System Information:")
+		fmt.Fprintf(w, "  - Kernel: %s\n", report.Kernel.Version)
+		if report.Kernel.Architecture != "" {
+			fmt.Fprintf(w, "  - Architecture: %s\n", report.Kernel.Architecture)
+		}
+		fmt.Fprintf(w, "  - Kernel Headers: %s\n", installedLabel(report.Kernel.HeadersInstalled))
+		fmt.Fprintf(w, "  - Secure Boot: %s\n", enabledLabel(report.Kernel.SecureBootEnabled))
+		fmt.Fprintln(w)
+	}
+
+	if report.Validation != nil {
+		fmt.Fprintf(w, "Validation: %s\n", validationSummary(report.Validation))
+		for _, check := range report.Validation.Checks {
+			if check.Passed || check.Severity == validator.SeverityInfo.String() {
+				continue
+			}
+			label := "Warning"
+			if check.Severity == validator.SeverityError.String() {
+				label = "Error"
+			}
+			fmt.Fprintf(w, "  - %s: %s\n", label, check.Message)
+			if check.Remediation != "" {
+				fmt.Fprintf(w, "    Fix: %s\n", check.Remediation)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(report.Warnings) > 0 {
+		fmt.Fprintln(w, "Detection Warnings:")
+		for _, warning := range report.Warnings {
+			fmt.Fprintf(w, "  - [%s] %s\n", warning.Code, warning.Message)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// driverSummary returns a short description of the installed driver.
+func driverSummary(d *gpu.ReportDriver) string {
+	if d == nil {
+		return "Unknown"
+	}
+	if !d.Installed {
+		return "Not installed"
+	}
+	if d.Version != "" {
+		return fmt.Sprintf("%s %s", d.Type, d.Version)
+	}
+	return d.Type
+}
+
+// nouveauSummary returns a short description of the Nouveau driver status.
+func nouveauSummary(n *gpu.ReportNouveau) string {
+	switch {
+	case n.Loaded && n.Blacklisted:
+		return "Loaded (blacklisted, reboot required)"
+	case n.Loaded:
+		return "Loaded (will be blacklisted)"
+	case n.Blacklisted:
+		return "Not loaded (blacklisted)"
+	default:
+		return "Not loaded"
+	}
+}
+
+// validationSummary returns the validation status with error and warning counts.
+func validationSummary(v *gpu.ReportValidation) string {
+	status := "PASSED"
+	if !v.Passed {
+		status = "FAILED"
+	}
+	return fmt.Sprintf("%s (%d errors, %d warnings)", status, v.Errors, v.Warnings)
+}
+
+// installedLabel returns "Installed" or "Not installed".
+func installedLabel(installed bool) string {
+	if installed {
+		return "Installed"
+	}
+	return "Not installed"
+}
+
+// enabledLabel returns "Enabled" or "Disabled".
+func enabledLabel(enabled bool) string {
+	if enabled {
+		return "Enabled"
+	}
+	return "Disabled"
+}
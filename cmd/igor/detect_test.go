@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/gpu"
+	"github.com/tungetti/igor/internal/gpu/kernel"
+	"github.com/tungetti/igor/internal/gpu/nouveau"
+	"github.com/tungetti/igor/internal/gpu/nvidia"
+	"github.com/tungetti/igor/internal/gpu/pci"
+	"github.com/tungetti/igor/internal/gpu/validator"
+)
+
+func newTestDetectInfo() *gpu.GPUInfo {
+	validation := validator.NewValidationReport()
+	validation.AddCheck(validator.NewCheckResult(validator.CheckName("nouveau"), false,
+		"Nouveau driver is currently active", validator.SeverityWarning).
+		WithRemediation("blacklist nouveau and reboot"))
+
+	return &gpu.GPUInfo{
+		NVIDIAGPUs: []gpu.NVIDIAGPUInfo{
+			{
+				PCIDevice: pci.PCIDevice{Address: "0000:01:00.0", VendorID: "10de", DeviceID: "2684", Name: "NVIDIA GeForce RTX 4090"},
+				Model:     &nvidia.GPUModel{Architecture: nvidia.ArchAdaLovelace, MinDriverVersion: "525.60"},
+			},
+		},
+		InstalledDriver:  &gpu.DriverInfo{Installed: false, Type: gpu.DriverTypeNone},
+		NouveauStatus:    &nouveau.Status{Loaded: true},
+		KernelInfo:       &kernel.KernelInfo{Version: "6.5.0-44-generic", HeadersInstalled: true},
+		ValidationReport: validation,
+		Errors:           []error{fmt.Errorf("nvidia-smi not found")},
+	}
+}
+
+func TestWriteDetectJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeDetectJSON(&buf, newTestDetectInfo()))
+
+	var report gpu.Report
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &report))
+	assert.Equal(t, gpu.ReportSchemaVersion, report.SchemaVersion)
+	require.Len(t, report.GPUs, 1)
+	assert.Equal(t, "NVIDIA GeForce RTX 4090", report.GPUs[0].Name)
+	require.Len(t, report.Warnings, 1)
+	assert.Equal(t, "nvidia-smi not found", report.Warnings[0].Message)
+}
+
+func TestWriteDetectBrief(t *testing.T) {
+	var buf bytes.Buffer
+	writeDetectBrief(&buf, newTestDetectInfo())
+
+	out := buf.String()
+	assert.Contains(t, out, "GPU 0: NVIDIA GeForce RTX 4090 (ada)")
+	assert.Contains(t, out, "Driver: Not installed")
+	assert.Contains(t, out, "Kernel: 6.5.0-44-generic")
+	assert.Contains(t, out, "Validation: PASSED (0 errors, 1 warnings)")
+	assert.Contains(t, out, "Warnings: 1")
+}
+
+func TestWriteDetectBrief_NoGPU(t *testing.T) {
+	var buf bytes.Buffer
+	writeDetectBrief(&buf, &gpu.GPUInfo{})
+
+	assert.Contains(t, buf.String(), "GPU: none detected")
+	assert.Contains(t, buf.String(), "Driver: Unknown")
+}
+
+func TestWriteDetectReport(t *testing.T) {
+	var buf bytes.Buffer
+	writeDetectReport(&buf, newTestDetectInfo())
+
+	out := buf.String()
+	assert.Contains(t, out, "NVIDIA GPU Detection Results")
+	assert.Contains(t, out, "GPU #1: NVIDIA GeForce RTX 4090")
+	assert.Contains(t, out, "PCI Address: 0000:01:00.0")
+	assert.Contains(t, out, "Minimum Driver: 525.60")
+	assert.Contains(t, out, "Nouveau: Loaded (will be blacklisted)")
+	assert.Contains(t, out, "Kernel Headers: Installed")
+	assert.Contains(t, out, "Secure Boot: Disabled")
+	assert.Contains(t, out, "Warning: Nouveau driver is currently active")
+	assert.Contains(t, out, "Fix: blacklist nouveau and reboot")
+	assert.Contains(t, out, "Detection Warnings:")
+	assert.Contains(t, out, "[Unknown] nvidia-smi not found")
+}
+
+func TestDriverSummary(t *testing.T) {
+	assert.Equal(t, "Unknown", driverSummary(nil))
+	assert.Equal(t, "Not installed", driverSummary(&gpu.ReportDriver{}))
+	assert.Equal(t, "nouveau", driverSummary(&gpu.ReportDriver{Installed: true, Type: "nouveau"}))
+	assert.Equal(t, "nvidia 550.54.14", driverSummary(&gpu.ReportDriver{Installed: true, Type: "nvidia", Version: "550.54.14"}))
+}
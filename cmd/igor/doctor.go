@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tungetti/igor/internal/cli"
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/distro"
+	"github.com/tungetti/igor/internal/doctor"
+	"github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/gpu/kernel"
+	"github.com/tungetti/igor/internal/gpu/nouveau"
+	"github.com/tungetti/igor/internal/gpu/validator"
+	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/privilege"
+)
+
+// cmdDoctor handles the doctor command.
+// It diagnoses the installed driver, prints the problems found most severe
+// first and, with --fix, runs the installation steps that repair them.
+func (c *CLI) cmdDoctor(result *cli.ParseResult) int {
+	fix := result.DoctorFlags.Fix
+	dryRun := c.config.DryRun
+
+	priv := privilege.NewManager()
+	if fix && !dryRun {
+		if err := priv.RequireRoot(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return constants.ExitPermission.Int()
+		}
+	}
+
+	var (
+		ctx      context.Context
+		cancel   context.CancelFunc
+		executor exec.Executor
+	)
+	if fix {
+		ctx, cancel = c.longCommandContext()
+		executor = newLongCommandExecutor(priv)
+	} else {
+		ctx, cancel = c.commandContext()
+		executor = exec.NewExecutor(exec.DefaultOptions(), nil)
+	}
+	defer cancel()
+
+	kernelDetector := kernel.NewDetector(kernel.WithExecutor(executor))
+	nouveauDetector := nouveau.NewDetector()
+
+	// The package manager is only needed for the repository conflict check,
+	// which is skipped on unsupported distributions.
+	dist, pm, detectErr := detectPackageManager(ctx, executor)
+	if detectErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: skipping the repository check: %v\n", detectErr)
+	}
+
+	d := doctor.NewDoctor(
+		doctor.WithExecutor(executor),
+		doctor.WithPackageManager(pm),
+		doctor.WithDistribution(dist),
+		doctor.WithKernelDetector(kernelDetector),
+		doctor.WithNouveauDetector(nouveauDetector),
+		doctor.WithValidator(validator.NewValidator(
+			validator.WithExecutor(executor),
+			validator.WithKernelDetector(kernelDetector),
+			validator.WithNouveauDetector(nouveauDetector),
+		)),
+	)
+
+	report, err := d.Diagnose(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: diagnosis failed: %v\n", err)
+		return constants.ExitError.Int()
+	}
+
+	writeDoctorReport(os.Stdout, report)
+
+	if !fix {
+		return doctorExitCode(report.Problems)
+	}
+
+	fixable := report.FixableProblems()
+	if len(fixable) == 0 {
+		if !report.Healthy() {
+			fmt.Println("None of the problems can be fixed automatically.")
+		}
+		return doctorExitCode(report.Problems)
+	}
+
+	if dist == nil {
+		dist, err = distro.NewDetector(executor, nil).Detect(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to detect distribution: %v\n", err)
+			return constants.ExitError.Int()
+		}
+	}
+
+	installCtx := install.NewContext(
+		install.WithDistroInfo(dist),
+		install.WithPackageManager(pm),
+		install.WithExecutor(executor),
+		install.WithPrivilege(priv),
+		install.WithLogger(c.stepLogger()),
+		install.WithDryRun(dryRun),
+		install.WithContext(ctx),
+	)
+
+	var out io.Writer = os.Stdout
+	if c.config.IsSilent() {
+		out = io.Discard
+	}
+
+	header := fmt.Sprintf("Fixing %d problems", len(fixable))
+	if dryRun {
+		header = "[dry-run] " + header
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, header)
+
+	orchestrator := install.NewOrchestrator(doctor.NewFixWorkflow(fixable, kernelDetector),
+		install.WithOrchestratorDryRun(dryRun),
+		install.WithOrchestratorProgress(func(p install.StepProgress) {
+			fmt.Fprintln(out, formatInstallProgress(p))
+		}),
+	)
+
+	fixReport := orchestrator.Execute(installCtx)
+	switch {
+	case fixReport.Status == install.WorkflowStatusCancelled:
+		fmt.Fprintln(os.Stderr, "Fix cancelled")
+		return installExitCode(fixReport, ctx.Err())
+	case fixReport.Status != install.WorkflowStatusCompleted:
+		fmt.Fprintf(os.Stderr, "Error: fix failed at step %s: %v\n", fixReport.FailedStep, fixReport.Error)
+		return installExitCode(fixReport, ctx.Err())
+	}
+
+	remaining := unfixedProblems(report.Problems)
+	writeDoctorFixResult(out, fixable, remaining, dryRun)
+	return doctorExitCode(remaining)
+}
+
+// writeDoctorReport writes the problems of a diagnosis, most severe first,
+// each with its remediation and whether --fix can repair it.
+func writeDoctorReport(w io.Writer, report *doctor.Report) {
+	passed := len(report.Checks) - len(report.Problems)
+
+	if report.Healthy() {
+		fmt.Fprintf(w, "No problems found (%d checks passed)\n", passed)
+		return
+	}
+
+	errorCount := 0
+	for _, p := range report.Problems {
+		if p.Severity.IsError() {
+			errorCount++
+		}
+	}
+	fmt.Fprintf(w, "Found %d problems (%d errors, %d warnings); %d checks passed\n\n",
+		len(report.Problems), errorCount, len(report.Problems)-errorCount, passed)
+
+	for i, p := range report.Problems {
+		fmt.Fprintf(w, "%d. [%s] %s: %s\n", i+1, strings.ToUpper(p.Severity.String()), p.Name, p.Message)
+		if evidence := p.Details["evidence"]; evidence != "" {
+			fmt.Fprintf(w, "   Log: %s\n", evidence)
+		}
+		if p.Remediation != "" {
+			fmt.Fprintf(w, "   Remediation: %s\n", p.Remediation)
+		}
+		if p.Fixable() {
+			fmt.Fprintf(w, "   Fixable with --fix (%s)\n", p.Fix)
+		}
+	}
+}
+
+// writeDoctorFixResult writes the outcome of a successful fix run.
+func writeDoctorFixResult(w io.Writer, fixed, remaining []doctor.Problem, dryRun bool) {
+	if dryRun {
+		fmt.Fprintf(w, "[dry-run] %d problems would be fixed\n", len(fixed))
+	} else {
+		fmt.Fprintf(w, "Fixed %d problems\n", len(fixed))
+	}
+
+	if len(remaining) > 0 {
+		fmt.Fprintf(w, "%d problems need manual attention; see the remediation above\n", len(remaining))
+	}
+
+	if dryRun {
+		return
+	}
+	for _, p := range fixed {
+		if p.Fix == doctor.FixRepositoryConflicts {
+			fmt.Fprintf(w, "Disabled the repositories competing with %s: %s\n",
+				p.Details["keep"], strings.ReplaceAll(p.Details["disable"], ",", ", "))
+		}
+	}
+	for _, p := range fixed {
+		if p.Fix == doctor.FixNouveauBlacklist {
+			fmt.Fprintln(w, "Reboot the system for the Nouveau blacklist to take effect.")
+			break
+		}
+	}
+}
+
+// unfixedProblems returns the problems that --fix does not repair.
+func unfixedProblems(problems []doctor.Problem) []doctor.Problem {
+	remaining := make([]doctor.Problem, 0)
+	for _, p := range problems {
+		if !p.Fixable() {
+			remaining = append(remaining, p)
+		}
+	}
+	return remaining
+}
+
+// doctorExitCode returns ExitValidation if any of the problems is an error
+// and ExitSuccess otherwise; warnings alone do not fail the command.
+func doctorExitCode(problems []doctor.Problem) int {
+	for _, p := range problems {
+		if p.Severity.IsError() {
+			return constants.ExitValidation.Int()
+		}
+	}
+	return constants.ExitSuccess.Int()
+}
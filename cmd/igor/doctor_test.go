@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/doctor"
+	"github.com/tungetti/igor/internal/gpu/validator"
+)
+
+func newTestProblem(name validator.CheckName, severity validator.Severity, fix doctor.FixAction) doctor.Problem {
+	result := validator.NewCheckResult(name, false, string(name)+" failed", severity).
+		WithRemediation("fix " + string(name))
+	return doctor.Problem{CheckResult: *result, Fix: fix}
+}
+
+func TestWriteDoctorReport(t *testing.T) {
+	t.Run("healthy", func(t *testing.T) {
+		var buf bytes.Buffer
+		writeDoctorReport(&buf, &doctor.Report{
+			Checks: []validator.CheckResult{
+				*validator.NewCheckResult(doctor.CheckNvidiaSmi, true, "ok", validator.SeverityInfo),
+			},
+		})
+		assert.Equal(t, "No problems found (1 checks passed)\n", buf.String())
+	})
+
+	t.Run("problems", func(t *testing.T) {
+		module := newTestProblem(doctor.CheckModuleLoaded, validator.SeverityError, doctor.FixModuleLoad)
+		secureBoot := newTestProblem(validator.CheckSecureBoot, validator.SeverityWarning, doctor.FixNone)
+
+		var buf bytes.Buffer
+		writeDoctorReport(&buf, &doctor.Report{
+			Checks: []validator.CheckResult{
+				module.CheckResult,
+				secureBoot.CheckResult,
+				*validator.NewCheckResult(doctor.CheckNvidiaSmi, true, "ok", validator.SeverityInfo),
+			},
+			Problems: []doctor.Problem{module, secureBoot},
+		})
+
+		out := buf.String()
+		assert.Contains(t, out, "Found 2 problems (1 errors, 1 warnings); 1 checks passed")
+		assert.Contains(t, out, "1. [ERROR] module_loaded: module_loaded failed\n   Remediation: fix module_loaded\n   Fixable with --fix (module_load)\n")
+		assert.Contains(t, out, "2. [WARNING] secure_boot: secure_boot failed\n   Remediation: fix secure_boot\n")
+		assert.NotContains(t, out, "Fixable with --fix (secure")
+	})
+
+	t.Run("diagnosed DKMS build", func(t *testing.T) {
+		dkms := newTestProblem(doctor.CheckDKMSStatus, validator.SeverityError, doctor.FixDKMSBuild)
+		dkms.WithDetail("evidence", "cc1: error: incompatible gcc/plugin versions")
+
+		var buf bytes.Buffer
+		writeDoctorReport(&buf, &doctor.Report{
+			Checks:   []validator.CheckResult{dkms.CheckResult},
+			Problems: []doctor.Problem{dkms},
+		})
+
+		assert.Contains(t, buf.String(), "1. [ERROR] dkms_status: dkms_status failed\n   Log: cc1: error: incompatible gcc/plugin versions\n   Remediation: fix dkms_status\n")
+	})
+}
+
+func TestWriteDoctorFixResult(t *testing.T) {
+	nouveauProblem := newTestProblem(validator.CheckNouveauStatus, validator.SeverityError, doctor.FixNouveauBlacklist)
+	gpuProblem := newTestProblem(doctor.CheckGPUDetected, validator.SeverityError, doctor.FixNone)
+
+	var buf bytes.Buffer
+	writeDoctorFixResult(&buf, []doctor.Problem{nouveauProblem}, []doctor.Problem{gpuProblem}, false)
+	assert.Contains(t, buf.String(), "Fixed 1 problems")
+	assert.Contains(t, buf.String(), "1 problems need manual attention")
+	assert.Contains(t, buf.String(), "Reboot")
+
+	buf.Reset()
+	writeDoctorFixResult(&buf, []doctor.Problem{nouveauProblem}, nil, true)
+	assert.Contains(t, buf.String(), "[dry-run] 1 problems would be fixed")
+	assert.NotContains(t, buf.String(), "Reboot")
+
+	repoProblem := newTestProblem(doctor.CheckRepositoryConflicts, validator.SeverityWarning, doctor.FixRepositoryConflicts)
+	repoProblem.CheckResult.WithDetail("keep", "rpmfusion-nonfree").WithDetail("disable", "fedora-nvidia,cuda-fedora39-x86_64")
+
+	buf.Reset()
+	writeDoctorFixResult(&buf, []doctor.Problem{repoProblem}, nil, false)
+	assert.Contains(t, buf.String(), "Disabled the repositories competing with rpmfusion-nonfree: fedora-nvidia, cuda-fedora39-x86_64")
+	assert.NotContains(t, buf.String(), "Reboot")
+
+	buf.Reset()
+	writeDoctorFixResult(&buf, []doctor.Problem{repoProblem}, nil, true)
+	assert.NotContains(t, buf.String(), "Disabled")
+}
+
+func TestDoctorExitCode(t *testing.T) {
+	warning := newTestProblem(validator.CheckSecureBoot, validator.SeverityWarning, doctor.FixNone)
+	fixableError := newTestProblem(doctor.CheckModuleLoaded, validator.SeverityError, doctor.FixModuleLoad)
+
+	assert.Equal(t, constants.ExitSuccess.Int(), doctorExitCode(nil))
+	assert.Equal(t, constants.ExitSuccess.Int(), doctorExitCode([]doctor.Problem{warning}))
+	assert.Equal(t, constants.ExitValidation.Int(), doctorExitCode([]doctor.Problem{warning, fixableError}))
+
+	// After --fix only the problems that were not fixed count.
+	assert.Equal(t, constants.ExitSuccess.Int(), doctorExitCode(unfixedProblems([]doctor.Problem{warning, fixableError})))
+}
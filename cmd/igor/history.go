@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tungetti/igor/internal/cli"
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/pkg"
+	"github.com/tungetti/igor/internal/privilege"
+	"github.com/tungetti/igor/internal/uninstall"
+)
+
+// historyPackagesShown is the number of packages listed per transaction.
+const historyPackagesShown = 4
+
+// cmdHistory handles the history command.
+// It lists the package manager transactions that changed NVIDIA packages,
+// or undoes a transaction with --undo.
+func (c *CLI) cmdHistory(result *cli.ParseResult) int {
+	flags := result.HistoryFlags
+	dryRun := c.config.DryRun
+	undo := flags.Undo != ""
+
+	priv := privilege.NewManager()
+	if undo && !dryRun {
+		if err := priv.RequireRoot(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return constants.ExitPermission.Int()
+		}
+	}
+
+	ctx, cancel := c.longCommandContext()
+	defer cancel()
+
+	// In dry-run mode the undo runs against a recording executor, so its
+	// commands are shown instead of run.
+	var executor exec.Executor = newLongCommandExecutor(priv)
+	var recorder *exec.RecordingExecutor
+	if undo && dryRun {
+		recorder = exec.NewRecordingExecutor(executor)
+		executor = recorder
+	}
+
+	_, pm, err := detectPackageManager(ctx, executor)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return constants.ExitError.Int()
+	}
+
+	historian, ok := pm.(pkg.HistoryManager)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: transaction history is not supported with %s\n", pm.Name())
+		return constants.ExitError.Int()
+	}
+
+	entries, err := historian.History(ctx, 0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to read the %s history: %v\n", pm.Name(), err)
+		return constants.ExitError.Int()
+	}
+
+	var out io.Writer = os.Stdout
+	if c.config.IsSilent() {
+		out = io.Discard
+	}
+
+	if !undo {
+		if !flags.All {
+			entries = nvidiaHistory(entries)
+		}
+		writeHistory(os.Stdout, pm.Name(), entries, flags.All, flags.Limit)
+		return constants.ExitSuccess.Int()
+	}
+
+	entry := findHistoryEntry(entries, flags.Undo)
+	if entry == nil {
+		fmt.Fprintf(os.Stderr, "Error: transaction %s is not in the %s history\n", flags.Undo, pm.Name())
+		return constants.ExitValidation.Int()
+	}
+
+	title := "The following transaction will be undone"
+	if dryRun {
+		title = "[dry-run] " + title
+	}
+	fmt.Fprintf(out, "%s:\n", title)
+	writeHistoryTable(out, []pkg.HistoryEntry{*entry})
+
+	if !dryRun && !flags.Yes {
+		if !confirm(os.Stdin, os.Stdout, fmt.Sprintf("Undo transaction %s?", entry.ID)) {
+			fmt.Fprintln(os.Stderr, "Undo cancelled (use --yes to skip this prompt)")
+			return constants.ExitUserAbort.Int()
+		}
+	}
+
+	err = historian.Undo(ctx, entry.ID)
+	if recorder != nil {
+		for _, cmd := range recorder.Commands() {
+			if !cmd.Probe {
+				fmt.Fprintf(out, "    %s\n", formatRecordedCommand(cmd))
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return constants.ExitError.Int()
+	}
+
+	if dryRun {
+		fmt.Fprintln(out, "[dry-run] No changes were made")
+		return constants.ExitSuccess.Int()
+	}
+	fmt.Fprintf(out, "Transaction %s was undone\n", entry.ID)
+	return constants.ExitSuccess.Int()
+}
+
+// nvidiaHistory returns the entries that changed NVIDIA packages, with only
+// their NVIDIA packages.
+func nvidiaHistory(entries []pkg.HistoryEntry) []pkg.HistoryEntry {
+	result := make([]pkg.HistoryEntry, 0)
+	for _, entry := range entries {
+		packages := uninstall.FilterNVIDIAPackages(entry.Packages)
+		if len(packages) == 0 {
+			continue
+		}
+		entry.Packages = packages
+		result = append(result, entry)
+	}
+	return result
+}
+
+// findHistoryEntry returns the entry with the given ID, or nil.
+func findHistoryEntry(entries []pkg.HistoryEntry, id string) *pkg.HistoryEntry {
+	for i := range entries {
+		if entries[i].ID == id {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+// writeHistory lists the newest limit history entries of a package manager.
+// A limit of 0 lists all of them.
+func writeHistory(w io.Writer, pmName string, entries []pkg.HistoryEntry, all bool, limit int) {
+	kind := "NVIDIA package transactions"
+	if all {
+		kind = "transactions"
+	}
+	if len(entries) == 0 {
+		fmt.Fprintf(w, "No %s in the %s history\n", kind, pmName)
+		return
+	}
+
+	shown := entries
+	if limit > 0 && len(shown) > limit {
+		shown = shown[:limit]
+	}
+
+	fmt.Fprintf(w, "%s in the %s history, newest first:\n", strings.ToUpper(kind[:1])+kind[1:], pmName)
+	writeHistoryTable(w, shown)
+	if len(shown) < len(entries) {
+		fmt.Fprintf(w, "Showing %d of %d %s (use --limit 0 to show all)\n", len(shown), len(entries), kind)
+	}
+}
+
+// writeHistoryTable writes history entries as a table, with the command
+// line of each transaction under it.
+func writeHistoryTable(w io.Writer, entries []pkg.HistoryEntry) {
+	fmt.Fprintf(w, "  %-6s %-16s %-18s %-7s %s\n", "ID", "Date", "Operation", "Result", "Packages")
+	fmt.Fprintf(w, "  %-6s %-16s %-18s %-7s %s\n", "--", "----", "---------", "------", "--------")
+	for _, entry := range entries {
+		date := "-"
+		if t := entry.Time(); !t.IsZero() {
+			date = t.Local().Format("2006-01-02 15:04")
+		}
+		status := "ok"
+		if !entry.Success {
+			status = "failed"
+		}
+
+		fmt.Fprintf(w, "  %-6s %-16s %-18s %-7s %s\n", entry.ID, date, entry.Operation, status, formatHistoryPackages(entry.Packages))
+		if entry.Details != "" {
+			fmt.Fprintf(w, "  %-6s %s\n", "", "Command: "+entry.Details)
+		}
+	}
+}
+
+// formatHistoryPackages lists the first packages of a transaction and how
+// many more it changed.
+func formatHistoryPackages(packages []string) string {
+	if len(packages) <= historyPackagesShown {
+		return strings.Join(packages, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(packages[:historyPackagesShown], ", "), len(packages)-historyPackagesShown)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+func testHistory() []pkg.HistoryEntry {
+	return []pkg.HistoryEntry{
+		{
+			ID:        "12",
+			Timestamp: time.Date(2024, 1, 15, 10, 23, 0, 0, time.Local).Unix(),
+			Operation: "upgrade",
+			Packages:  []string{"kernel", "xorg-x11-drv-nvidia", "akmod-nvidia"},
+			Success:   true,
+			Details:   "upgrade",
+		},
+		{
+			ID:        "11",
+			Operation: "install",
+			Packages:  []string{"vim-enhanced"},
+			Success:   true,
+		},
+		{
+			ID:        "10",
+			Operation: "remove",
+			Packages:  []string{"nvidia-settings"},
+		},
+	}
+}
+
+func TestNvidiaHistory(t *testing.T) {
+	entries := nvidiaHistory(testHistory())
+
+	require.Len(t, entries, 2)
+	assert.Equal(t, "12", entries[0].ID)
+	assert.Equal(t, []string{"akmod-nvidia", "xorg-x11-drv-nvidia"}, entries[0].Packages)
+	assert.Equal(t, "10", entries[1].ID)
+}
+
+func TestFindHistoryEntry(t *testing.T) {
+	entries := testHistory()
+
+	entry := findHistoryEntry(entries, "11")
+	require.NotNil(t, entry)
+	assert.Equal(t, []string{"vim-enhanced"}, entry.Packages)
+	assert.Nil(t, findHistoryEntry(entries, "99"))
+}
+
+func TestWriteHistory(t *testing.T) {
+	var buf bytes.Buffer
+	writeHistory(&buf, "dnf", nvidiaHistory(testHistory()), false, 20)
+
+	out := buf.String()
+	assert.Contains(t, out, "NVIDIA package transactions in the dnf history, newest first:")
+	assert.Contains(t, out, "2024-01-15 10:23")
+	assert.Contains(t, out, "akmod-nvidia, xorg-x11-drv-nvidia")
+	assert.Contains(t, out, "Command: upgrade")
+	assert.Contains(t, out, "failed")
+	assert.NotContains(t, out, "Showing")
+}
+
+func TestWriteHistory_Limit(t *testing.T) {
+	var buf bytes.Buffer
+	writeHistory(&buf, "dnf", testHistory(), true, 1)
+
+	out := buf.String()
+	assert.Contains(t, out, "Transactions in the dnf history")
+	assert.Contains(t, out, "kernel")
+	assert.NotContains(t, out, "vim-enhanced")
+	assert.Contains(t, out, "Showing 1 of 3 transactions (use --limit 0 to show all)")
+}
+
+func TestWriteHistory_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	writeHistory(&buf, "apt", nil, false, 20)
+	assert.Equal(t, "No NVIDIA package transactions in the apt history\n", buf.String())
+}
+
+func TestFormatHistoryPackages(t *testing.T) {
+	assert.Equal(t, "a, b", formatHistoryPackages([]string{"a", "b"}))
+	assert.Equal(t, "a, b, c, d and 2 more", formatHistoryPackages([]string{"a", "b", "c", "d", "e", "f"}))
+	assert.Equal(t, "", formatHistoryPackages(nil))
+}
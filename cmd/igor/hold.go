@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tungetti/igor/internal/cli"
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/distro"
+	"github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/install/steps"
+	"github.com/tungetti/igor/internal/pkg"
+	"github.com/tungetti/igor/internal/privilege"
+	"github.com/tungetti/igor/internal/uninstall"
+)
+
+// cmdHold handles the hold and unhold commands.
+// Without package names, it holds or releases the installed NVIDIA packages.
+func (c *CLI) cmdHold(result *cli.ParseResult) int {
+	flags := result.HoldFlags
+	hold := result.Command == cli.CommandHold
+	dryRun := c.config.DryRun
+
+	priv := privilege.NewManager()
+	if !dryRun && !flags.List {
+		if err := priv.RequireRoot(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return constants.ExitPermission.Int()
+		}
+	}
+
+	ctx, cancel := c.commandContext()
+	defer cancel()
+
+	// In dry-run mode holds are changed through a recording executor, so
+	// their commands are shown instead of run.
+	var executor exec.Executor = newLongCommandExecutor(priv)
+	var recorder *exec.RecordingExecutor
+	if dryRun && !flags.List {
+		recorder = exec.NewRecordingExecutor(executor)
+		executor = recorder
+	}
+
+	dist, pm, err := detectPackageManager(ctx, executor)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return constants.ExitError.Int()
+	}
+
+	holder, ok := pm.(pkg.HoldManager)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: package holds are not supported with %s\n", pm.Name())
+		return constants.ExitError.Int()
+	}
+
+	if flags.List {
+		held, err := holder.ListHeld(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to list held packages: %v\n", err)
+			return constants.ExitError.Int()
+		}
+		writeHeldPackages(os.Stdout, held)
+		return constants.ExitSuccess.Int()
+	}
+
+	packages := result.Args
+	if len(packages) == 0 {
+		packages, err = installedNVIDIAPackages(ctx, dist, pm, executor)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return constants.ExitError.Int()
+		}
+		if len(packages) == 0 {
+			fmt.Printf("No NVIDIA packages are installed; nothing to %s\n", result.Command)
+			return constants.ExitSuccess.Int()
+		}
+	}
+
+	if hold {
+		err = holder.Hold(ctx, packages...)
+	} else {
+		err = holder.Unhold(ctx, packages...)
+	}
+
+	var out io.Writer = os.Stdout
+	if c.config.IsSilent() {
+		out = io.Discard
+	}
+
+	if recorder != nil {
+		for _, cmd := range recorder.Commands() {
+			if !cmd.Probe {
+				fmt.Fprintf(out, "    %s\n", formatRecordedCommand(cmd))
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return constants.ExitError.Int()
+	}
+
+	if dryRun {
+		fmt.Fprintln(out, "[dry-run] No changes were made")
+		return constants.ExitSuccess.Int()
+	}
+	if hold {
+		fmt.Fprintf(out, "Held %d packages: %s\n", len(packages), strings.Join(packages, " "))
+	} else {
+		fmt.Fprintf(out, "Released the holds on %d packages: %s\n", len(packages), strings.Join(packages, " "))
+	}
+	return constants.ExitSuccess.Int()
+}
+
+// installedNVIDIAPackages returns the names of the installed NVIDIA packages.
+func installedNVIDIAPackages(ctx context.Context, dist *distro.Distribution, pm pkg.Manager, executor exec.Executor) ([]string, error) {
+	discovered, err := uninstall.NewPackageDiscovery(pm,
+		uninstall.WithDiscoveryDistro(dist),
+		uninstall.WithDiscoveryExecutor(executor),
+	).Discover(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover installed packages: %w", err)
+	}
+	return discovered.AllPackages, nil
+}
+
+// holdInstalledPackages holds the packages an installation installed, as
+// recorded in the installation context. A failure is reported as a warning:
+// the driver was installed, only the hold is missing.
+func holdInstalledPackages(errOut, out io.Writer, pm pkg.Manager, ctx *install.Context) {
+	holder, ok := pm.(pkg.HoldManager)
+	if !ok {
+		fmt.Fprintf(errOut, "Warning: package holds are not supported with %s; the driver packages were not held\n", pm.Name())
+		return
+	}
+
+	packages := installedPackagesState(ctx)
+	if len(packages) == 0 {
+		return
+	}
+
+	if err := holder.Hold(ctx.Context(), packages...); err != nil {
+		fmt.Fprintf(errOut, "Warning: failed to hold the driver packages: %v\n", err)
+		return
+	}
+	fmt.Fprintf(out, "Held %d packages; release them with: igor unhold\n", len(packages))
+}
+
+// releaseHolds releases the holds on the given packages before they are
+// removed, since held packages cannot be removed with some package managers.
+// Packages that are not held are left alone.
+func releaseHolds(ctx context.Context, pm pkg.Manager, packages []string) error {
+	holder, ok := pm.(pkg.HoldManager)
+	if !ok || len(packages) == 0 {
+		return nil
+	}
+
+	held, err := holder.ListHeld(ctx)
+	if err != nil {
+		return err
+	}
+
+	heldSet := make(map[string]bool, len(held))
+	for _, name := range held {
+		heldSet[name] = true
+	}
+	var release []string
+	for _, name := range packages {
+		if heldSet[name] {
+			release = append(release, name)
+		}
+	}
+	if len(release) == 0 {
+		return nil
+	}
+	return holder.Unhold(ctx, release...)
+}
+
+// installedPackagesState returns the packages an installation installed, as
+// recorded in the installation context by the package installation step.
+func installedPackagesState(ctx *install.Context) []string {
+	value, ok := ctx.GetState(steps.StateInstalledPackages)
+	if !ok {
+		return nil
+	}
+	packages, _ := value.([]string)
+	return packages
+}
+
+// writeHeldPackages lists the held packages.
+func writeHeldPackages(w io.Writer, held []string) {
+	if len(held) == 0 {
+		fmt.Fprintln(w, "No packages are held")
+		return
+	}
+	fmt.Fprintln(w, "Held packages:")
+	for _, name := range held {
+		fmt.Fprintf(w, "  - %s\n", name)
+	}
+}
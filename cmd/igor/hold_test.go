@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/install/steps"
+	"github.com/tungetti/igor/internal/pkg/apt"
+	"github.com/tungetti/igor/internal/privilege"
+)
+
+func newTestHoldManager() (*apt.Manager, *exec.MockExecutor) {
+	mockExec := exec.NewMockExecutor()
+	priv := privilege.NewManager()
+	priv.SetRoot(true)
+	return apt.NewManager(mockExec, priv), mockExec
+}
+
+func TestHoldInstalledPackages(t *testing.T) {
+	pm, mockExec := newTestHoldManager()
+	ctx := install.NewContext(install.WithContext(context.Background()))
+	ctx.SetState(steps.StateInstalledPackages, []string{"nvidia-driver-550", "nvidia-dkms-550"})
+
+	var errOut, out bytes.Buffer
+	holdInstalledPackages(&errOut, &out, pm, ctx)
+
+	assert.True(t, mockExec.WasCalledWith("apt-mark", "hold", "nvidia-driver-550", "nvidia-dkms-550"))
+	assert.Contains(t, out.String(), "Held 2 packages")
+	assert.Empty(t, errOut.String())
+}
+
+func TestHoldInstalledPackages_Failure(t *testing.T) {
+	pm, mockExec := newTestHoldManager()
+	mockExec.SetResponse("apt-mark", exec.FailureResult(1, "E: dpkg was interrupted"))
+	ctx := install.NewContext(install.WithContext(context.Background()))
+	ctx.SetState(steps.StateInstalledPackages, []string{"nvidia-driver-550"})
+
+	var errOut, out bytes.Buffer
+	holdInstalledPackages(&errOut, &out, pm, ctx)
+
+	assert.Contains(t, errOut.String(), "Warning: failed to hold the driver packages")
+	assert.Empty(t, out.String())
+}
+
+func TestHoldInstalledPackages_NothingInstalled(t *testing.T) {
+	pm, mockExec := newTestHoldManager()
+	ctx := install.NewContext(install.WithContext(context.Background()))
+
+	var errOut, out bytes.Buffer
+	holdInstalledPackages(&errOut, &out, pm, ctx)
+
+	assert.Equal(t, 0, mockExec.CallCount())
+	assert.Empty(t, out.String())
+}
+
+func TestHoldInstalledPackages_Unsupported(t *testing.T) {
+	ctx := install.NewContext(install.WithContext(context.Background()))
+	ctx.SetState(steps.StateInstalledPackages, []string{"nvidia-driver-550"})
+
+	var errOut, out bytes.Buffer
+	holdInstalledPackages(&errOut, &out, newTestListManager(), ctx)
+
+	assert.Contains(t, errOut.String(), "not supported with apt")
+}
+
+func TestReleaseHolds(t *testing.T) {
+	pm, mockExec := newTestHoldManager()
+	mockExec.SetResponse("apt-mark", exec.SuccessResult("nvidia-driver-550\nlinux-image-generic\n"))
+
+	err := releaseHolds(context.Background(), pm, []string{"nvidia-driver-550", "nvidia-dkms-550"})
+	require.NoError(t, err)
+
+	assert.True(t, mockExec.WasCalledWith("apt-mark", "showhold"))
+	assert.True(t, mockExec.WasCalledWith("apt-mark", "unhold", "nvidia-driver-550"))
+}
+
+func TestReleaseHolds_NothingHeld(t *testing.T) {
+	pm, mockExec := newTestHoldManager()
+	mockExec.SetResponse("apt-mark", exec.SuccessResult(""))
+
+	err := releaseHolds(context.Background(), pm, []string{"nvidia-driver-550"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, mockExec.CallCount())
+}
+
+func TestReleaseHolds_Unsupported(t *testing.T) {
+	err := releaseHolds(context.Background(), newTestListManager(), []string{"nvidia-driver-550"})
+	assert.NoError(t, err)
+}
+
+func TestWriteHeldPackages(t *testing.T) {
+	var buf bytes.Buffer
+	writeHeldPackages(&buf, nil)
+	assert.Equal(t, "No packages are held\n", buf.String())
+
+	buf.Reset()
+	writeHeldPackages(&buf, []string{"nvidia-driver-550", "nvidia-dkms-550"})
+	assert.Equal(t, "Held packages:\n  - nvidia-driver-550\n  - nvidia-dkms-550\n", buf.String())
+}
@@ -0,0 +1,446 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/tungetti/igor/internal/cli"
+	"github.com/tungetti/igor/internal/config"
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/distro"
+	"github.com/tungetti/igor/internal/dkms"
+	"github.com/tungetti/igor/internal/gpu"
+	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/install/builder"
+	"github.com/tungetti/igor/internal/install/bundle"
+	"github.com/tungetti/igor/internal/install/journal"
+	"github.com/tungetti/igor/internal/install/steps"
+	"github.com/tungetti/igor/internal/pkg"
+	"github.com/tungetti/igor/internal/pkg/nvidia"
+	"github.com/tungetti/igor/internal/privilege"
+	"github.com/tungetti/igor/internal/secureboot"
+)
+
+// installPlan holds the resolved inputs of a non-interactive installation.
+type installPlan struct {
+	DriverVersion      string
+	Components         []string
+	AdditionalPackages []string
+	Reinstall          bool
+	Warnings           []string
+}
+
+// cmdInstall handles the install command.
+// It detects the system, builds the installation workflow for the detected
+// distribution and executes it with automatic rollback, printing one line
+// per progress update. Runs that change the system are recorded in the run
+// journal so they can be undone later with the rollback command. With --hold
+// or hold_driver, the installed packages are held once the run completed.
+// With --from-bundle, the packages are installed from an offline bundle
+// created by the bundle command instead of the online repositories.
+func (c *CLI) cmdInstall(result *cli.ParseResult) int {
+	flags := result.InstallFlags
+	dryRun := c.config.DryRun
+
+	priv := privilege.NewManager()
+	if !dryRun {
+		if err := priv.RequireRoot(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return constants.ExitPermission.Int()
+		}
+	}
+
+	ctx, cancel := c.longCommandContext()
+	defer cancel()
+
+	executor := newLongCommandExecutor(priv)
+
+	dist, pm, err := detectPackageManager(ctx, executor)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return constants.ExitError.Int()
+	}
+
+	// With --from-bundle, the driver and packages are those of the bundle.
+	var bundleDir string
+	var manifest *bundle.Manifest
+	requested := requestedDriverVersion(flags, c.config)
+	if flags.FromBundle != "" {
+		bundleDir, manifest, err = loadInstallBundle(flags.FromBundle, dist, pm)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return constants.ExitValidation.Int()
+		}
+		requested = manifest.DriverVersion
+	}
+
+	gpuInfo, err := newDetectionOrchestrator(executor).DetectAll(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: GPU detection failed: %v\n", err)
+		return constants.ExitError.Int()
+	}
+
+	force := flags.Force || c.config.ForceInstall
+	if !gpuInfo.HasNVIDIAGPUs() && !force {
+		fmt.Fprintln(os.Stderr, "Error: no NVIDIA GPU detected (use --force to install anyway)")
+		return constants.ExitValidation.Int()
+	}
+
+	if !force && driverSatisfied(gpuInfo, requested) {
+		fmt.Printf("NVIDIA driver %s is already installed (use --force to reinstall)\n", gpuInfo.InstalledDriver.Version)
+		return constants.ExitSuccess.Int()
+	}
+
+	builderOpts := make([]builder.WorkflowBuilderOption, 0, 3)
+	var plan *installPlan
+	if manifest != nil {
+		bundler, ok := pm.(pkg.BundleManager)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: offline bundles are not supported with %s\n", pm.Name())
+			return constants.ExitValidation.Int()
+		}
+		plan = bundleInstallPlan(manifest, flags, c.config)
+		builderOpts = append(builderOpts, builder.WithRepository(bundler.BundleRepository(bundleDir)))
+	} else {
+		plan, err = resolveInstallPlan(ctx, dist, pm, gpuInfo, flags, c.config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return constants.ExitValidation.Int()
+		}
+	}
+	for _, warning := range plan.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
+	builderOpts = append(builderOpts,
+		builder.WithAdditionalPackages(plan.AdditionalPackages...),
+		builder.WithReinstall(plan.Reinstall),
+		builder.WithAllowEssentialRemovals(force),
+	)
+	workflow, err := builder.NewWorkflowBuilder(dist, builderOpts...).Build()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to build installation workflow: %v\n", err)
+		return constants.ExitError.Int()
+	}
+
+	installCtx := install.NewContext(
+		install.WithGPUInfo(gpuInfo),
+		install.WithDistroInfo(dist),
+		install.WithDriverVersion(plan.DriverVersion),
+		install.WithComponents(plan.Components),
+		install.WithPackageManager(pm),
+		install.WithExecutor(executor),
+		install.WithPrivilege(priv),
+		install.WithLogger(c.stepLogger()),
+		install.WithDryRun(dryRun),
+		install.WithContext(ctx),
+	)
+
+	var out io.Writer = os.Stdout
+	if c.config.IsSilent() {
+		out = io.Discard
+	}
+
+	fmt.Fprintln(out, installHeader(dist, plan, dryRun))
+	if manifest != nil {
+		fmt.Fprintf(out, "Using the offline bundle in %s (created %s)\n", bundleDir, manifest.Created.Format(time.RFC3339))
+	}
+
+	opts := []install.OrchestratorOption{
+		install.WithOrchestratorDryRun(dryRun),
+		install.WithOrchestratorProgress(installProgressPrinter(out)),
+	}
+
+	var runJournal *journal.Recorder
+	if !dryRun {
+		entry := journal.NewEntry(journal.NewID(time.Now()), cli.CommandInstall.String(), workflow.Name())
+		entry.Distribution = dist.String()
+		entry.DriverVersion = plan.DriverVersion
+
+		runJournal = journal.NewRecorder(journal.NewStore(runJournalDir(c.config)), entry)
+		if err := runJournal.Start(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: this run will not be recorded: %v\n", err)
+			runJournal = nil
+		} else {
+			opts = append(opts, install.WithPostStepHook(runJournal.PostStepHook()))
+		}
+	}
+
+	report := install.NewOrchestrator(workflow, opts...).ExecuteWithRollback(installCtx)
+
+	code := installExitCode(report, ctx.Err())
+	writeInstallResult(os.Stderr, out, report, ctx.Err(), flags.SkipReboot || c.config.SkipReboot)
+	if report.Status == install.WorkflowStatusCompleted {
+		writeMOKEnrollment(out, installCtx)
+	}
+	if !dryRun && report.Status == install.WorkflowStatusCompleted && (flags.Hold || c.config.HoldDriver) {
+		holdInstalledPackages(os.Stderr, out, pm, installCtx)
+	}
+	if !dryRun && manifest != nil && report.Status == install.WorkflowStatusCompleted {
+		removeBundleRepository(os.Stderr, pm, installCtx)
+	}
+	if runJournal != nil {
+		finishRunJournal(os.Stderr, out, runJournal, installCtx, report)
+	}
+	return code
+}
+
+// finishRunJournal records the result of a run in the journal and tells
+// how to undo the run if it changed the system.
+func finishRunJournal(errOut, out io.Writer, runJournal *journal.Recorder, ctx *install.Context, report install.ExecutionReport) {
+	if err := runJournal.Finish(ctx, report); err != nil {
+		fmt.Fprintf(errOut, "Warning: failed to record this run: %v\n", err)
+		return
+	}
+
+	entry := runJournal.Entry()
+	if needed, _ := entry.RollbackNeeded(); needed {
+		fmt.Fprintf(out, "Run %s recorded; undo it with: igor rollback %s\n", entry.ID, entry.ID)
+	}
+}
+
+// requestedDriverVersion returns the driver version requested on the command
+// line, falling back to the configuration file.
+func requestedDriverVersion(flags cli.InstallFlags, cfg *config.Config) string {
+	if flags.DriverVersion != "" {
+		return flags.DriverVersion
+	}
+	return cfg.DriverVersion
+}
+
+// driverSatisfied reports whether the installed NVIDIA driver already
+// satisfies the requested version. An empty version accepts any installed
+// NVIDIA driver.
+func driverSatisfied(info *gpu.GPUInfo, version string) bool {
+	if info == nil || !info.IsDriverInstalled() || info.InstalledDriver.Type != gpu.DriverTypeNVIDIA {
+		return false
+	}
+	if version == "" {
+		return true
+	}
+	return nvidia.DriverMajorVersion(info.InstalledDriver.Version) == nvidia.DriverMajorVersion(version)
+}
+
+// resolveInstallPlan determines the driver version, components and extra
+// packages to install from the flags, the configuration and the system.
+//
+// Without an explicit driver version, distributions with versioned driver
+// packages use the recommended branch for the detected GPU; the others
+// install their default driver packages.
+func resolveInstallPlan(ctx context.Context, dist *distro.Distribution, pm pkg.Manager, gpuInfo *gpu.GPUInfo, flags cli.InstallFlags, cfg *config.Config) (*installPlan, error) {
+	packageSet := nvidia.GetPackageSet(dist)
+	if packageSet == nil {
+		return nil, fmt.Errorf("no NVIDIA package set for distribution %s", dist)
+	}
+
+	plan := &installPlan{
+		DriverVersion: requestedDriverVersion(flags, cfg),
+		Components:    make([]string, 0),
+		Reinstall:     flags.Force || cfg.ForceInstall,
+	}
+
+	// Versioned package names only carry the branch, so "550.120" selects
+	// nvidia-driver-550.
+	if plan.DriverVersion != "" && packageSet.DriverVersionPattern != "" {
+		if major := nvidia.DriverMajorVersion(plan.DriverVersion); major > 0 {
+			plan.DriverVersion = strconv.Itoa(major)
+		}
+	}
+
+	if plan.DriverVersion == "" {
+		if packageSet.DriverVersionPattern == "" {
+			plan.Components = append(plan.Components, nvidia.ComponentDriver.String())
+		} else {
+			var gpus []gpu.NVIDIAGPUInfo
+			if gpuInfo != nil {
+				gpus = gpuInfo.NVIDIAGPUs
+			}
+			list, err := collectDriverList(ctx, dist, pm, gpus)
+			if err != nil {
+				return nil, err
+			}
+			plan.Warnings = append(plan.Warnings, list.Warnings...)
+			for _, b := range list.Branches {
+				if b.Recommended {
+					plan.DriverVersion = b.Version
+					break
+				}
+			}
+			if plan.DriverVersion == "" {
+				return nil, fmt.Errorf("no supported driver branch found in the configured repositories (use --driver to select one)")
+			}
+		}
+	}
+
+	cudaVersion := flags.CUDAVersion
+	if cudaVersion == "" {
+		cudaVersion = cfg.CUDAVersion
+	}
+
+	switch {
+	case cudaVersion != "":
+		packages := packageSet.GetCUDAPackagesForVersion(cudaVersion)
+		if equalStrings(packages, packageSet.CUDA) {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf(
+				"CUDA %s cannot be selected on %s; installing the distribution's CUDA packages", cudaVersion, dist))
+		}
+		plan.AdditionalPackages = append(plan.AdditionalPackages, packages...)
+	case flags.InstallCUDA || cfg.InstallCUDA:
+		plan.Components = append(plan.Components, nvidia.ComponentCUDA.String())
+	}
+
+	return plan, nil
+}
+
+// installHeader returns the first line printed by the install command.
+func installHeader(dist *distro.Distribution, plan *installPlan, dryRun bool) string {
+	driver := plan.DriverVersion
+	if driver == "" {
+		driver = "(distribution default)"
+	}
+
+	header := fmt.Sprintf("Installing NVIDIA driver %s on %s", driver, dist)
+	if len(plan.AdditionalPackages) > 0 || containsComponent(plan.Components, nvidia.ComponentCUDA) {
+		header += " with CUDA"
+	}
+	if dryRun {
+		header = "[dry-run] " + header
+	}
+	return header
+}
+
+// formatInstallProgress formats a workflow progress update as a single line.
+func formatInstallProgress(p install.StepProgress) string {
+	if p.StepName == "" {
+		return fmt.Sprintf("[%d/%d] %s", p.StepIndex, p.TotalSteps, p.Message)
+	}
+	return fmt.Sprintf("[%d/%d] %s: %s", p.StepIndex+1, p.TotalSteps, p.StepName, p.Message)
+}
+
+// installProgressPrinter returns a progress callback printing each update
+// with formatInstallProgress. Package managers report their progress many
+// times per package, so a package update is only printed when the package
+// or the phase changes.
+func installProgressPrinter(out io.Writer) func(install.StepProgress) {
+	var last install.StepProgress
+	return func(p install.StepProgress) {
+		if p.PackagePhase != "" && p.StepName == last.StepName &&
+			p.PackagePhase == last.PackagePhase && p.Package == last.Package {
+			return
+		}
+		last = p
+		fmt.Fprintln(out, formatInstallProgress(p))
+	}
+}
+
+// installExitCode maps an execution report to a process exit code.
+// ctxErr is the error of the command context, used to tell a user
+// interrupt from a timeout.
+func installExitCode(report install.ExecutionReport, ctxErr error) int {
+	switch report.Status {
+	case install.WorkflowStatusCompleted:
+		return constants.ExitSuccess.Int()
+	case install.WorkflowStatusCancelled:
+		if errors.Is(ctxErr, context.DeadlineExceeded) {
+			return constants.ExitError.Int()
+		}
+		return constants.ExitUserAbort.Int()
+	case install.WorkflowStatusFailed:
+		if report.FailedStep != "" {
+			return constants.ExitInstallation.Int()
+		}
+		return constants.ExitError.Int()
+	default:
+		return constants.ExitError.Int()
+	}
+}
+
+// writeInstallResult writes the outcome of an installation.
+// Failures go to errOut so they remain visible in quiet mode.
+func writeInstallResult(errOut, out io.Writer, report install.ExecutionReport, ctxErr error, skipReboot bool) {
+	switch report.Status {
+	case install.WorkflowStatusCompleted:
+		fmt.Fprintf(out, "Installation completed in %s (%d steps completed, %d skipped)\n",
+			report.TotalDuration.Round(time.Millisecond), report.StepsCompleted, report.StepsSkipped)
+		if !skipReboot {
+			fmt.Fprintln(out, "Reboot the system to load the NVIDIA driver.")
+		}
+		return
+	case install.WorkflowStatusCancelled:
+		if errors.Is(ctxErr, context.DeadlineExceeded) {
+			fmt.Fprintln(errOut, "Error: installation timed out")
+		} else {
+			fmt.Fprintln(errOut, "Installation cancelled")
+		}
+	default:
+		if report.FailedStep != "" {
+			fmt.Fprintf(errOut, "Error: installation failed at step %s: %v\n", report.FailedStep, report.Error)
+		} else {
+			fmt.Fprintf(errOut, "Error: installation failed: %v\n", report.Error)
+		}
+		var buildErr *dkms.BuildError
+		if errors.As(report.Error, &buildErr) && buildErr.Diagnosis != nil {
+			fmt.Fprintf(errOut, "Log: %s\n", buildErr.Diagnosis.Evidence)
+			fmt.Fprintf(errOut, "Remediation: %s\n", buildErr.Diagnosis.Remediation)
+		}
+		var akmodsErr *steps.AkmodsBuildError
+		if errors.As(report.Error, &akmodsErr) && akmodsErr.LogPath != "" {
+			fmt.Fprintf(errOut, "Build log %s:\n", akmodsErr.LogPath)
+			for _, line := range akmodsErr.LogTail {
+				fmt.Fprintf(errOut, "  %s\n", line)
+			}
+		}
+	}
+
+	if report.RollbackPerformed {
+		if report.RollbackSuccess {
+			fmt.Fprintln(errOut, "Completed steps were rolled back")
+		} else {
+			fmt.Fprintln(errOut, "Warning: rollback did not complete cleanly; the system may be partially configured")
+		}
+	}
+}
+
+// writeMOKEnrollment tells what to do at the next reboot to enroll the
+// Machine Owner Key queued by the module signing step, if any.
+func writeMOKEnrollment(out io.Writer, ctx *install.Context) {
+	value, ok := ctx.GetState(steps.StateMOKEnrollment)
+	if !ok {
+		return
+	}
+	enrollment, ok := value.(*secureboot.Enrollment)
+	if !ok || enrollment == nil {
+		return
+	}
+	for _, line := range enrollment.Instructions() {
+		fmt.Fprintln(out, line)
+	}
+}
+
+// containsComponent reports whether components includes c.
+func containsComponent(components []string, c nvidia.Component) bool {
+	for _, name := range components {
+		if name == c.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// equalStrings reports whether a and b hold the same strings in the same order.
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
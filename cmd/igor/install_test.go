@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/cli"
+	"github.com/tungetti/igor/internal/config"
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/distro"
+	"github.com/tungetti/igor/internal/dkms"
+	"github.com/tungetti/igor/internal/gpu"
+	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/install/steps"
+	"github.com/tungetti/igor/internal/pkg"
+	"github.com/tungetti/igor/internal/secureboot"
+)
+
+func newTestFedoraDistro() *distro.Distribution {
+	return &distro.Distribution{ID: "fedora", Name: "Fedora Linux", VersionID: "40", Family: constants.FamilyRHEL}
+}
+
+func TestResolveInstallPlan_RecommendedBranch(t *testing.T) {
+	info := &gpu.GPUInfo{NVIDIAGPUs: newTestListGPUs()}
+
+	plan, err := resolveInstallPlan(context.Background(), newTestListDistro(), newTestListManager(), info,
+		cli.InstallFlags{}, config.DefaultConfig())
+	require.NoError(t, err)
+
+	assert.Equal(t, "550", plan.DriverVersion)
+	assert.Empty(t, plan.Components)
+	assert.Empty(t, plan.AdditionalPackages)
+	assert.False(t, plan.Reinstall)
+}
+
+func TestResolveInstallPlan_ExplicitDriver(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.DriverVersion = "535"
+
+	plan, err := resolveInstallPlan(context.Background(), newTestListDistro(), newTestListManager(), nil,
+		cli.InstallFlags{DriverVersion: "550.120", Force: true}, cfg)
+	require.NoError(t, err)
+
+	assert.Equal(t, "550", plan.DriverVersion)
+	assert.True(t, plan.Reinstall)
+
+	plan, err = resolveInstallPlan(context.Background(), newTestListDistro(), newTestListManager(), nil,
+		cli.InstallFlags{}, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "535", plan.DriverVersion)
+}
+
+func TestResolveInstallPlan_NoSupportedBranch(t *testing.T) {
+	pm := newTestListManager()
+	pm.SetSearchError(errors.New("network unreachable"))
+
+	_, err := resolveInstallPlan(context.Background(), newTestListDistro(), pm, nil,
+		cli.InstallFlags{}, config.DefaultConfig())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--driver")
+}
+
+func TestResolveInstallPlan_UnversionedDriver(t *testing.T) {
+	plan, err := resolveInstallPlan(context.Background(), newTestFedoraDistro(), newTestListManager(), nil,
+		cli.InstallFlags{InstallCUDA: true}, config.DefaultConfig())
+	require.NoError(t, err)
+
+	assert.Empty(t, plan.DriverVersion)
+	assert.Equal(t, []string{"driver", "cuda"}, plan.Components)
+}
+
+func TestResolveInstallPlan_CUDAVersion(t *testing.T) {
+	plan, err := resolveInstallPlan(context.Background(), newTestFedoraDistro(), newTestListManager(), nil,
+		cli.InstallFlags{CUDAVersion: "12.4", InstallCUDA: true}, config.DefaultConfig())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"driver"}, plan.Components)
+	assert.Contains(t, plan.AdditionalPackages, "cuda-toolkit-12-4")
+	assert.Empty(t, plan.Warnings)
+
+	plan, err = resolveInstallPlan(context.Background(), newTestListDistro(), newTestListManager(), nil,
+		cli.InstallFlags{DriverVersion: "550", CUDAVersion: "12.4"}, config.DefaultConfig())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"nvidia-cuda-toolkit"}, plan.AdditionalPackages)
+	require.Len(t, plan.Warnings, 1)
+	assert.Contains(t, plan.Warnings[0], "CUDA 12.4 cannot be selected")
+}
+
+func TestDriverSatisfied(t *testing.T) {
+	installed := &gpu.GPUInfo{
+		InstalledDriver: &gpu.DriverInfo{Installed: true, Type: gpu.DriverTypeNVIDIA, Version: "550.54.14"},
+	}
+	nouveau := &gpu.GPUInfo{
+		InstalledDriver: &gpu.DriverInfo{Installed: true, Type: gpu.DriverTypeNouveau},
+	}
+
+	assert.True(t, driverSatisfied(installed, ""))
+	assert.True(t, driverSatisfied(installed, "550"))
+	assert.True(t, driverSatisfied(installed, "550.120"))
+	assert.False(t, driverSatisfied(installed, "535"))
+	assert.False(t, driverSatisfied(nouveau, ""))
+	assert.False(t, driverSatisfied(&gpu.GPUInfo{}, ""))
+	assert.False(t, driverSatisfied(nil, ""))
+}
+
+func TestFormatInstallProgress(t *testing.T) {
+	assert.Equal(t, "[2/8] repository: Starting: Configure NVIDIA repository",
+		formatInstallProgress(install.NewStepProgress("repository", 1, 8, "Starting: Configure NVIDIA repository")))
+	assert.Equal(t, "[8/8] Workflow completed successfully",
+		formatInstallProgress(install.NewStepProgress("", 8, 8, "Workflow completed successfully")))
+}
+
+func TestInstallProgressPrinter(t *testing.T) {
+	var out bytes.Buffer
+	report := installProgressPrinter(&out)
+
+	step := install.NewStepProgress("packages", 3, 8, "Starting: Install NVIDIA packages")
+	report(step)
+	for _, progress := range []pkg.Progress{
+		{Phase: pkg.ProgressDownload, Percent: 10},
+		{Phase: pkg.ProgressDownload, Percent: 60},
+		{Phase: pkg.ProgressInstall, Package: "nvidia-driver-550", Percent: 20},
+		{Phase: pkg.ProgressInstall, Package: "nvidia-driver-550", Percent: 40},
+		{Phase: pkg.ProgressInstall, Package: "nvidia-utils-550", Percent: 80},
+	} {
+		update := step.WithPackageProgress(progress)
+		update.Message = progress.String()
+		report(update)
+	}
+	report(install.NewStepProgress("packages", 3, 8, "Completed: installed 2 packages"))
+
+	assert.Equal(t, "[4/8] packages: Starting: Install NVIDIA packages\n"+
+		"[4/8] packages: Downloading packages (10%)\n"+
+		"[4/8] packages: Installing nvidia-driver-550 (20%)\n"+
+		"[4/8] packages: Installing nvidia-utils-550 (80%)\n"+
+		"[4/8] packages: Completed: installed 2 packages\n", out.String())
+}
+
+func TestInstallExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		report   install.ExecutionReport
+		ctxErr   error
+		expected constants.ExitCode
+	}{
+		{"completed", install.ExecutionReport{Status: install.WorkflowStatusCompleted}, nil, constants.ExitSuccess},
+		{"step failed", install.ExecutionReport{Status: install.WorkflowStatusFailed, FailedStep: "packages"}, nil, constants.ExitInstallation},
+		{"failed before steps", install.ExecutionReport{Status: install.WorkflowStatusFailed}, nil, constants.ExitError},
+		{"interrupted", install.ExecutionReport{Status: install.WorkflowStatusCancelled}, context.Canceled, constants.ExitUserAbort},
+		{"timed out", install.ExecutionReport{Status: install.WorkflowStatusCancelled}, context.DeadlineExceeded, constants.ExitError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected.Int(), installExitCode(tt.report, tt.ctxErr))
+		})
+	}
+}
+
+func TestWriteInstallResult(t *testing.T) {
+	t.Run("completed", func(t *testing.T) {
+		var out, errOut bytes.Buffer
+		report := install.ExecutionReport{
+			Status:         install.WorkflowStatusCompleted,
+			TotalDuration:  90 * time.Second,
+			StepsCompleted: 7,
+			StepsSkipped:   1,
+		}
+
+		writeInstallResult(&errOut, &out, report, nil, false)
+
+		assert.Contains(t, out.String(), "Installation completed in 1m30s (7 steps completed, 1 skipped)")
+		assert.Contains(t, out.String(), "Reboot")
+		assert.Empty(t, errOut.String())
+	})
+
+	t.Run("failed step with rollback", func(t *testing.T) {
+		var out, errOut bytes.Buffer
+		report := install.ExecutionReport{
+			Status:            install.WorkflowStatusFailed,
+			FailedStep:        "dkms",
+			Error:             errors.New("module build failed"),
+			RollbackPerformed: true,
+			RollbackSuccess:   true,
+		}
+
+		writeInstallResult(&errOut, &out, report, nil, false)
+
+		assert.Contains(t, errOut.String(), "installation failed at step dkms: module build failed")
+		assert.Contains(t, errOut.String(), "rolled back")
+		assert.Empty(t, out.String())
+	})
+
+	t.Run("diagnosed DKMS build failure", func(t *testing.T) {
+		var out, errOut bytes.Buffer
+		report := install.ExecutionReport{
+			Status:     install.WorkflowStatusFailed,
+			FailedStep: "dkms_build",
+			Error: &dkms.BuildError{
+				Err: errors.New("dkms build failed: Bad return status for module build"),
+				Diagnosis: &dkms.Diagnosis{
+					Summary:     "the headers of kernel 6.8.0-45-generic are not installed",
+					Remediation: "Install the headers package of kernel 6.8.0-45-generic",
+					Evidence:    "make: *** /lib/modules/6.8.0-45-generic/build: No such file or directory.  Stop.",
+				},
+			},
+		}
+
+		writeInstallResult(&errOut, &out, report, nil, false)
+
+		assert.Contains(t, errOut.String(), "Bad return status for module build: the headers of kernel 6.8.0-45-generic are not installed\n")
+		assert.Contains(t, errOut.String(), "Log: make: *** /lib/modules/6.8.0-45-generic/build: No such file or directory.  Stop.\n")
+		assert.Contains(t, errOut.String(), "Remediation: Install the headers package of kernel 6.8.0-45-generic\n")
+	})
+
+	t.Run("akmods build failure", func(t *testing.T) {
+		var out, errOut bytes.Buffer
+		logPath := "/var/cache/akmods/nvidia/550.54.14-1.fc40-for-6.8.5-301.fc40.x86_64.failed.log"
+		report := install.ExecutionReport{
+			Status:     install.WorkflowStatusFailed,
+			FailedStep: "akmods_build",
+			Error: &steps.AkmodsBuildError{
+				Kernel:  "6.8.5-301.fc40.x86_64",
+				Err:     errors.New("akmods failed: Building and installing nvidia-kmod [FAILED]"),
+				LogPath: logPath,
+				LogTail: []string{"error: Bad exit status from /var/tmp/rpm-tmp.fWXmCe (%build)", "RPM build errors:"},
+			},
+		}
+
+		writeInstallResult(&errOut, &out, report, nil, false)
+
+		assert.Contains(t, errOut.String(), "[FAILED] (build log: "+logPath+")\n")
+		assert.Contains(t, errOut.String(), "Build log "+logPath+":\n  error: Bad exit status from /var/tmp/rpm-tmp.fWXmCe (%build)\n  RPM build errors:\n")
+	})
+}
+
+func TestWriteMOKEnrollment(t *testing.T) {
+	var out bytes.Buffer
+	ctx := install.NewContext()
+
+	writeMOKEnrollment(&out, ctx)
+	assert.Empty(t, out.String())
+
+	ctx.SetState(steps.StateMOKEnrollment, &secureboot.Enrollment{Certificate: "/var/lib/dkms/mok.pub", Password: "12345678"})
+	writeMOKEnrollment(&out, ctx)
+	assert.Contains(t, out.String(), "/var/lib/dkms/mok.pub")
+	assert.Contains(t, out.String(), "type the password 12345678")
+}
+
+func TestInstallHeader(t *testing.T) {
+	dist := newTestListDistro()
+
+	assert.Equal(t, "Installing NVIDIA driver 550 on "+dist.String(),
+		installHeader(dist, &installPlan{DriverVersion: "550"}, false))
+	assert.Equal(t, "[dry-run] Installing NVIDIA driver (distribution default) on "+dist.String()+" with CUDA",
+		installHeader(dist, &installPlan{Components: []string{"driver", "cuda"}}, true))
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tungetti/igor/internal/cli"
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/distro"
+	"github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/gpu"
+	"github.com/tungetti/igor/internal/pkg"
+	"github.com/tungetti/igor/internal/pkg/nvidia"
+	"github.com/tungetti/igor/internal/uninstall"
+)
+
+// driverListSchemaVersion is the version of the "igor list --json" document.
+const driverListSchemaVersion = "1"
+
+// driverList is the result of the list command.
+// It is also the JSON document emitted by "igor list --json".
+type driverList struct {
+	SchemaVersion          string                `json:"schema_version"`
+	Distribution           string                `json:"distribution"`
+	PackageManager         string                `json:"package_manager"`
+	GPU                    string                `json:"gpu,omitempty"`
+	MinDriverVersion       string                `json:"min_driver_version,omitempty"`
+	InstalledDriverVersion string                `json:"installed_driver_version,omitempty"`
+	Branches               []nvidia.DriverBranch `json:"branches"`
+	InstalledPackages      []string              `json:"installed_packages"`
+	Warnings               []string              `json:"warnings"`
+}
+
+// cmdList handles the list command.
+// It lists driver branches offered by the configured repositories and
+// the NVIDIA packages currently installed.
+func (c *CLI) cmdList(result *cli.ParseResult) int {
+	ctx, cancel := c.commandContext()
+	defer cancel()
+
+	executor := exec.NewExecutor(exec.DefaultOptions(), nil)
+
+	dist, pm, err := detectPackageManager(ctx, executor)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return constants.ExitError.Int()
+	}
+
+	// The GPU is only needed to pick a recommendation, so a failed
+	// detection degrades to an unfiltered list.
+	gpus, gpuErr := newDetectionOrchestrator(executor).DetectGPUs(ctx)
+
+	list, err := collectDriverList(ctx, dist, pm, gpus)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return constants.ExitError.Int()
+	}
+	if gpuErr != nil {
+		list.Warnings = append(list.Warnings, fmt.Sprintf("GPU detection failed: %v", gpuErr))
+	}
+
+	filterDriverList(list, result.ListFlags)
+
+	if result.ListFlags.JSON {
+		if err := writeListJSON(os.Stdout, list); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to encode driver list: %v\n", err)
+			return constants.ExitError.Int()
+		}
+		return constants.ExitSuccess.Int()
+	}
+
+	writeListTable(os.Stdout, list, result.ListFlags)
+	return constants.ExitSuccess.Int()
+}
+
+// collectDriverList queries the package manager for available and installed
+// driver packages and groups them into branches.
+// Search failures are reported as warnings so installed packages are still listed.
+func collectDriverList(ctx context.Context, dist *distro.Distribution, pm pkg.Manager, gpus []gpu.NVIDIAGPUInfo) (*driverList, error) {
+	list := &driverList{
+		SchemaVersion:     driverListSchemaVersion,
+		PackageManager:    pm.Name(),
+		Branches:          make([]nvidia.DriverBranch, 0),
+		InstalledPackages: make([]string, 0),
+		Warnings:          make([]string, 0),
+	}
+	if dist != nil {
+		list.Distribution = dist.String()
+	}
+
+	list.GPU, list.MinDriverVersion = gpuDriverRequirement(gpus)
+
+	packageSet := nvidia.GetPackageSet(dist)
+	if packageSet == nil {
+		return nil, fmt.Errorf("no NVIDIA package set for distribution %s", list.Distribution)
+	}
+
+	available, err := pm.Search(ctx, "nvidia", pkg.DefaultSearchOptions())
+	if err != nil {
+		list.Warnings = append(list.Warnings, fmt.Sprintf("repository search failed: %v", err))
+		available = nil
+	}
+
+	discovered, err := uninstall.NewPackageDiscovery(pm,
+		uninstall.WithDiscoveryDistro(dist),
+	).Discover(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover installed packages: %w", err)
+	}
+	list.InstalledPackages = append(list.InstalledPackages, discovered.AllPackages...)
+	list.InstalledDriverVersion = discovered.DriverVersion
+
+	// Discovery only reports names, so look up installed versions for the
+	// NVIDIA packages it found.
+	var installed []pkg.Package
+	if !discovered.IsEmpty() {
+		all, err := pm.ListInstalled(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list installed packages: %w", err)
+		}
+		names := make(map[string]bool, len(discovered.AllPackages))
+		for _, name := range discovered.AllPackages {
+			names[name] = true
+		}
+		for _, p := range all {
+			if names[p.Name] {
+				installed = append(installed, p)
+			}
+		}
+	}
+
+	list.Branches = nvidia.BuildDriverBranches(packageSet, available, installed, list.MinDriverVersion)
+
+	return list, nil
+}
+
+// gpuDriverRequirement returns the name of the first detected GPU and the
+// most restrictive minimum driver version across all detected GPUs.
+func gpuDriverRequirement(gpus []gpu.NVIDIAGPUInfo) (string, string) {
+	var name, minVersion string
+	for i := range gpus {
+		if name == "" {
+			name = gpus[i].Name()
+		}
+		if gpus[i].Model == nil {
+			continue
+		}
+		v := gpus[i].Model.Architecture.MinDriverVersion()
+		if nvidia.DriverMajorVersion(v) > nvidia.DriverMajorVersion(minVersion) {
+			minVersion = v
+		}
+	}
+	return name, minVersion
+}
+
+// filterDriverList applies the --installed and --available flags.
+// With neither flag set every branch is kept.
+func filterDriverList(list *driverList, flags cli.ListFlags) {
+	if flags.Installed == flags.Available {
+		return
+	}
+
+	filtered := make([]nvidia.DriverBranch, 0, len(list.Branches))
+	for _, b := range list.Branches {
+		if (flags.Installed && b.Installed) || (flags.Available && b.Available) {
+			filtered = append(filtered, b)
+		}
+	}
+	list.Branches = filtered
+}
+
+// writeListJSON writes the driver list as indented JSON.
+func writeListJSON(w io.Writer, list *driverList) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(list)
+}
+
+// writeListTable writes the driver list as a human-readable table.
+func writeListTable(w io.Writer, list *driverList, flags cli.ListFlags) {
+	title := "NVIDIA Driver Branches"
+	if flags.Installed && !flags.Available {
+		title = "Installed NVIDIA Drivers"
+	} else if flags.Available && !flags.Installed {
+		title = "Available NVIDIA Drivers"
+	}
+	if list.GPU != "" {
+		title += " for " + list.GPU
+	}
+	fmt.Fprintln(w, title)
+	fmt.Fprintln(w, strings.Repeat("=", len(title)))
+	fmt.Fprintln(w)
+
+	if len(list.Branches) == 0 {
+		fmt.Fprintln(w, "  No driver branches found")
+	} else {
+		fmt.Fprintf(w, "  %-8s %-11s %-22s %-13s %s\n", "Branch", "Type", "Version", "Status", "Notes")
+		fmt.Fprintf(w, "  %-8s %-11s %-22s %-13s %s\n", "------", "----", "-------", "------", "-----")
+		for _, b := range list.Branches {
+			version := b.AvailableVersion
+			if b.Installed && b.InstalledVersion != "" {
+				version = b.InstalledVersion
+			}
+			if version == "" {
+				version = "-"
+			}
+			fmt.Fprintf(w, "  %-8s %-11s %-22s %-13s %s\n",
+				b.Version, b.Branch, version, branchStatus(b), branchNotes(b, list.MinDriverVersion))
+		}
+	}
+
+	if !flags.Available || flags.Installed {
+		fmt.Fprintln(w)
+		if len(list.InstalledPackages) == 0 {
+			fmt.Fprintln(w, "Installed NVIDIA packages: none")
+		} else {
+			fmt.Fprintf(w, "Installed NVIDIA packages (%d):\n", len(list.InstalledPackages))
+			for _, name := range list.InstalledPackages {
+				fmt.Fprintf(w, "  - %s\n", name)
+			}
+		}
+	}
+
+	for _, warning := range list.Warnings {
+		fmt.Fprintf(w, "\nWarning: %s\n", warning)
+	}
+}
+
+// branchStatus returns the status column for a driver branch.
+func branchStatus(b nvidia.DriverBranch) string {
+	switch {
+	case b.Installed:
+		return "Installed"
+	case b.Recommended:
+		return "Recommended"
+	case !b.Supported:
+		return "Unsupported"
+	case b.Available:
+		return "Available"
+	default:
+		return "-"
+	}
+}
+
+// branchNotes returns the notes column for a driver branch.
+func branchNotes(b nvidia.DriverBranch, minDriverVersion string) string {
+	var notes []string
+	if b.Installed && b.Recommended {
+		notes = append(notes, "recommended")
+	}
+	if b.Installed && !b.Available {
+		notes = append(notes, "not in repositories")
+	}
+	if !b.Supported && minDriverVersion != "" {
+		notes = append(notes, "GPU requires >= "+minDriverVersion)
+	}
+	return strings.Join(notes, ", ")
+}
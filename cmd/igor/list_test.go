@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/cli"
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/distro"
+	"github.com/tungetti/igor/internal/gpu"
+	"github.com/tungetti/igor/internal/gpu/nvidia"
+	"github.com/tungetti/igor/internal/gpu/pci"
+	"github.com/tungetti/igor/internal/pkg"
+	igortesting "github.com/tungetti/igor/internal/testing"
+)
+
+func newTestListManager() *igortesting.MockPackageManager {
+	pm := igortesting.NewMockPackageManager()
+	pm.SetName("apt")
+	pm.SetAvailablePackages([]pkg.Package{
+		{Name: "nvidia-driver-550", Version: "550.120-0ubuntu1"},
+		{Name: "nvidia-driver-535", Version: "535.183-0ubuntu1"},
+		{Name: "nvidia-driver-470", Version: "470.256-0ubuntu1"},
+	})
+	pm.SetInstalledPackages([]pkg.Package{
+		{Name: "nvidia-driver-535", Version: "535.183-0ubuntu1", Installed: true},
+		{Name: "nvidia-utils-535", Version: "535.183-0ubuntu1", Installed: true},
+		{Name: "bash", Version: "5.2", Installed: true},
+	})
+	return pm
+}
+
+func newTestListDistro() *distro.Distribution {
+	return &distro.Distribution{ID: "debian", Name: "Debian", VersionID: "12", Family: constants.FamilyDebian}
+}
+
+func newTestListGPUs() []gpu.NVIDIAGPUInfo {
+	return []gpu.NVIDIAGPUInfo{
+		{
+			PCIDevice: pci.PCIDevice{Name: "NVIDIA GeForce RTX 4090", DeviceID: "2684"},
+			Model:     &nvidia.GPUModel{Architecture: nvidia.ArchAdaLovelace},
+		},
+	}
+}
+
+func TestCollectDriverList(t *testing.T) {
+	list, err := collectDriverList(context.Background(), newTestListDistro(), newTestListManager(), newTestListGPUs())
+	require.NoError(t, err)
+
+	assert.Equal(t, driverListSchemaVersion, list.SchemaVersion)
+	assert.Equal(t, "apt", list.PackageManager)
+	assert.Equal(t, "NVIDIA GeForce RTX 4090", list.GPU)
+	assert.Equal(t, "525.60", list.MinDriverVersion)
+	assert.Equal(t, "535", list.InstalledDriverVersion)
+	assert.Equal(t, []string{"nvidia-driver-535", "nvidia-utils-535"}, list.InstalledPackages)
+	assert.Empty(t, list.Warnings)
+
+	require.Len(t, list.Branches, 3)
+	assert.Equal(t, "550", list.Branches[0].Version)
+	assert.True(t, list.Branches[0].Recommended)
+	assert.Equal(t, "535", list.Branches[1].Version)
+	assert.True(t, list.Branches[1].Installed)
+	assert.Equal(t, "535.183-0ubuntu1", list.Branches[1].InstalledVersion)
+	assert.False(t, list.Branches[2].Supported)
+}
+
+func TestCollectDriverList_SearchFailure(t *testing.T) {
+	pm := newTestListManager()
+	pm.SetSearchError(errors.New("network unreachable"))
+
+	list, err := collectDriverList(context.Background(), newTestListDistro(), pm, nil)
+	require.NoError(t, err)
+
+	require.Len(t, list.Warnings, 1)
+	assert.Contains(t, list.Warnings[0], "network unreachable")
+	require.Len(t, list.Branches, 1)
+	assert.True(t, list.Branches[0].Installed)
+	assert.False(t, list.Branches[0].Available)
+}
+
+func TestCollectDriverList_UnsupportedDistro(t *testing.T) {
+	dist := &distro.Distribution{ID: "gentoo", Family: constants.FamilyUnknown}
+
+	_, err := collectDriverList(context.Background(), dist, newTestListManager(), nil)
+	assert.Error(t, err)
+}
+
+func TestFilterDriverList(t *testing.T) {
+	tests := []struct {
+		name     string
+		flags    cli.ListFlags
+		expected []string
+	}{
+		{"no flags", cli.ListFlags{}, []string{"550", "535", "470"}},
+		{"installed", cli.ListFlags{Installed: true}, []string{"535"}},
+		{"available", cli.ListFlags{Available: true}, []string{"550", "535", "470"}},
+		{"both", cli.ListFlags{Installed: true, Available: true}, []string{"550", "535", "470"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := collectDriverList(context.Background(), newTestListDistro(), newTestListManager(), nil)
+			require.NoError(t, err)
+
+			filterDriverList(list, tt.flags)
+
+			var versions []string
+			for _, b := range list.Branches {
+				versions = append(versions, b.Version)
+			}
+			assert.Equal(t, tt.expected, versions)
+		})
+	}
+}
+
+func TestWriteListJSON(t *testing.T) {
+	list, err := collectDriverList(context.Background(), newTestListDistro(), newTestListManager(), newTestListGPUs())
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeListJSON(&buf, list))
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, driverListSchemaVersion, doc["schema_version"])
+	branches := doc["branches"].([]interface{})
+	require.Len(t, branches, 3)
+	first := branches[0].(map[string]interface{})
+	assert.Equal(t, "550", first["version"])
+	assert.Equal(t, true, first["recommended"])
+}
+
+func TestWriteListTable(t *testing.T) {
+	list, err := collectDriverList(context.Background(), newTestListDistro(), newTestListManager(), newTestListGPUs())
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	writeListTable(&buf, list, cli.ListFlags{})
+
+	out := buf.String()
+	assert.Contains(t, out, "NVIDIA Driver Branches for NVIDIA GeForce RTX 4090")
+	assert.Contains(t, out, "Recommended")
+	assert.Contains(t, out, "Installed")
+	assert.Contains(t, out, "GPU requires >= 525.60")
+	assert.Contains(t, out, "Installed NVIDIA packages (2):")
+}
+
+func TestWriteListTable_AvailableOnly(t *testing.T) {
+	list, err := collectDriverList(context.Background(), newTestListDistro(), newTestListManager(), nil)
+	require.NoError(t, err)
+	filterDriverList(list, cli.ListFlags{Available: true})
+
+	var buf bytes.Buffer
+	writeListTable(&buf, list, cli.ListFlags{Available: true})
+
+	out := buf.String()
+	assert.Contains(t, out, "Available NVIDIA Drivers")
+	assert.NotContains(t, out, "Installed NVIDIA packages")
+}
+
+func TestGPUDriverRequirement(t *testing.T) {
+	gpus := []gpu.NVIDIAGPUInfo{
+		{PCIDevice: pci.PCIDevice{Name: "GTX 1080"}, Model: &nvidia.GPUModel{Architecture: nvidia.ArchPascal}},
+		{PCIDevice: pci.PCIDevice{Name: "RTX 5090"}, Model: &nvidia.GPUModel{Architecture: nvidia.ArchBlackwell}},
+		{PCIDevice: pci.PCIDevice{Name: "Unknown"}},
+	}
+
+	name, minVersion := gpuDriverRequirement(gpus)
+	assert.Equal(t, "GTX 1080", name)
+	assert.Equal(t, "560.00", minVersion)
+
+	name, minVersion = gpuDriverRequirement(nil)
+	assert.Empty(t, name)
+	assert.Empty(t, minVersion)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tungetti/igor/internal/cli"
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/distro"
+	"github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/pkg/nvidia"
+)
+
+// packageCatalogSchemaVersion is the version of the "igor packages show --json" document.
+const packageCatalogSchemaVersion = "1"
+
+// Kinds of the package sets shown.
+const (
+	packageSetFamily       = "family"
+	packageSetDistribution = "distribution"
+)
+
+// packageSetView is a package set of the catalog with the name it is
+// defined under.
+type packageSetView struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	*nvidia.PackageSet
+}
+
+// packageCatalogView is the JSON document emitted by "igor packages show --json".
+type packageCatalogView struct {
+	SchemaVersion string           `json:"schema_version"`
+	Sources       []string         `json:"sources"`
+	PackageSets   []packageSetView `json:"package_sets"`
+}
+
+// loadPackageCatalog loads the NVIDIA package catalog with the override
+// files of the system and of the user configuration, and makes it the
+// catalog used by every command.
+func (c *CLI) loadPackageCatalog() error {
+	catalog, err := nvidia.LoadCatalog(nvidia.CatalogDirs(c.config.ConfigDir)...)
+	if err != nil {
+		return err
+	}
+	nvidia.SetCatalog(catalog)
+	return nil
+}
+
+// cmdPackages handles the packages command.
+// It shows the package sets of the catalog in use: the one of this
+// system, the one of a given distribution or family, or all of them.
+func (c *CLI) cmdPackages(result *cli.ParseResult) int {
+	catalog := nvidia.CurrentCatalog()
+
+	var sets []packageSetView
+	switch {
+	case result.PackagesFlags.All:
+		sets = catalogPackageSets(catalog)
+	case len(result.Args) > 0:
+		set, ok := lookupPackageSet(catalog, result.Args[0])
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: no package set for %s\n", result.Args[0])
+			return constants.ExitValidation.Int()
+		}
+		sets = append(sets, set)
+	default:
+		ctx, cancel := c.commandContext()
+		defer cancel()
+
+		detector := distro.NewDetector(exec.NewExecutor(exec.DefaultOptions(), nil), nil)
+		dist, err := detector.Detect(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to detect distribution: %v\n", err)
+			return constants.ExitError.Int()
+		}
+		set, ok := distroPackageSet(catalog, dist)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: no NVIDIA package set for distribution %s\n", dist.String())
+			return constants.ExitError.Int()
+		}
+		sets = append(sets, set)
+	}
+
+	if result.PackagesFlags.JSON {
+		view := packageCatalogView{
+			SchemaVersion: packageCatalogSchemaVersion,
+			Sources:       catalog.Sources(),
+			PackageSets:   sets,
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(view); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to encode package catalog: %v\n", err)
+			return constants.ExitError.Int()
+		}
+		return constants.ExitSuccess.Int()
+	}
+
+	writePackageSets(os.Stdout, catalog.Sources(), sets)
+	return constants.ExitSuccess.Int()
+}
+
+// catalogPackageSets returns the package sets of the families, then those
+// of the distributions.
+func catalogPackageSets(catalog *nvidia.Catalog) []packageSetView {
+	var sets []packageSetView
+	for _, family := range catalog.Families() {
+		sets = append(sets, packageSetView{Name: string(family), Kind: packageSetFamily, PackageSet: catalog.ForFamily(family)})
+	}
+	for _, id := range catalog.Distributions() {
+		sets = append(sets, packageSetView{Name: id, Kind: packageSetDistribution, PackageSet: catalog.ByID(id)})
+	}
+	return sets
+}
+
+// lookupPackageSet returns the package set of a distribution ID or, if the
+// distribution has none of its own, of a family name.
+func lookupPackageSet(catalog *nvidia.Catalog, name string) (packageSetView, bool) {
+	name = strings.ToLower(name)
+	if ps := catalog.ByID(name); ps != nil {
+		return packageSetView{Name: name, Kind: packageSetDistribution, PackageSet: ps}, true
+	}
+	if ps := catalog.ForFamily(constants.DistroFamily(name)); ps != nil {
+		return packageSetView{Name: name, Kind: packageSetFamily, PackageSet: ps}, true
+	}
+	return packageSetView{}, false
+}
+
+// distroPackageSet returns the package set used for a distribution, as
+// nvidia.GetPackageSet picks it.
+func distroPackageSet(catalog *nvidia.Catalog, dist *distro.Distribution) (packageSetView, bool) {
+	if ps := catalog.ByID(dist.ID); ps != nil {
+		return packageSetView{Name: dist.ID, Kind: packageSetDistribution, PackageSet: ps}, true
+	}
+	if ps := catalog.ForFamily(dist.Family); ps != nil {
+		return packageSetView{Name: string(dist.Family), Kind: packageSetFamily, PackageSet: ps}, true
+	}
+	return packageSetView{}, false
+}
+
+// writePackageSets writes the package sets in a human-readable form.
+func writePackageSets(w io.Writer, sources []string, sets []packageSetView) {
+	fmt.Fprintf(w, "Package catalog: %s\n", strings.Join(sources, ", "))
+
+	for _, set := range sets {
+		fmt.Fprintln(w)
+		if set.Kind == packageSetFamily {
+			fmt.Fprintf(w, "%s family (%s)\n", set.Name, set.Distribution)
+		} else {
+			fmt.Fprintf(w, "%s (%s family)\n", set.Name, set.Family)
+		}
+
+		rows := []struct {
+			label string
+			value string
+		}{
+			{"Driver", strings.Join(set.Driver, " ")},
+			{"Driver (DKMS)", strings.Join(set.DriverDKMS, " ")},
+			{"Utilities", strings.Join(set.Utils, " ")},
+			{"Settings", strings.Join(set.Settings, " ")},
+			{"CUDA", strings.Join(set.CUDA, " ")},
+			{"CUDA compiler", strings.Join(set.CUDACompiler, " ")},
+			{"CUDA libraries", strings.Join(set.CUDALibs, " ")},
+			{"cuDNN", strings.Join(set.CUDnn, " ")},
+			{"OpenCL", strings.Join(set.OpenCL, " ")},
+			{"Vulkan", strings.Join(set.Vulkan, " ")},
+			{"Driver pattern", set.DriverVersionPattern},
+			{"DKMS pattern", set.DKMSVersionPattern},
+			{"Notes", set.Notes},
+			{"Defined in", strings.Join(set.Sources, ", ")},
+		}
+		for _, row := range rows {
+			if row.value == "" {
+				continue
+			}
+			fmt.Fprintf(w, "  %-16s %s\n", row.label+":", row.value)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/config"
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/distro"
+	"github.com/tungetti/igor/internal/pkg/nvidia"
+)
+
+func TestLookupPackageSet(t *testing.T) {
+	catalog := nvidia.BuiltinCatalog()
+
+	set, ok := lookupPackageSet(catalog, "Ubuntu")
+	require.True(t, ok)
+	assert.Equal(t, "ubuntu", set.Name)
+	assert.Equal(t, packageSetDistribution, set.Kind)
+
+	set, ok = lookupPackageSet(catalog, "rhel")
+	require.True(t, ok)
+	assert.Equal(t, packageSetFamily, set.Kind)
+	assert.Equal(t, []string{"akmod-nvidia", "xorg-x11-drv-nvidia"}, set.Driver)
+
+	_, ok = lookupPackageSet(catalog, "gentoo")
+	assert.False(t, ok)
+}
+
+func TestDistroPackageSet(t *testing.T) {
+	catalog := nvidia.BuiltinCatalog()
+
+	set, ok := distroPackageSet(catalog, &distro.Distribution{ID: "debian", Family: constants.FamilyDebian})
+	require.True(t, ok)
+	assert.Equal(t, "debian", set.Name)
+	assert.Equal(t, packageSetFamily, set.Kind)
+
+	set, ok = distroPackageSet(catalog, &distro.Distribution{ID: "pop", Family: constants.FamilyDebian})
+	require.True(t, ok)
+	assert.Equal(t, packageSetDistribution, set.Kind)
+	assert.Equal(t, []string{"system76-driver-nvidia"}, set.Driver)
+
+	_, ok = distroPackageSet(catalog, &distro.Distribution{ID: "gentoo", Family: constants.FamilyUnknown})
+	assert.False(t, ok)
+}
+
+func TestCatalogPackageSets(t *testing.T) {
+	sets := catalogPackageSets(nvidia.BuiltinCatalog())
+
+	var names []string
+	for _, set := range sets {
+		names = append(names, set.Name)
+	}
+	assert.Equal(t, []string{
+		"debian", "rhel", "arch", "suse",
+		"fedora", "manjaro", "opensuse-leap", "opensuse-tumbleweed", "pop", "ubuntu",
+	}, names)
+}
+
+func TestWritePackageSets(t *testing.T) {
+	catalog := nvidia.BuiltinCatalog()
+	ubuntu, _ := lookupPackageSet(catalog, "ubuntu")
+	arch, _ := lookupPackageSet(catalog, "arch")
+
+	var buf bytes.Buffer
+	writePackageSets(&buf, []string{"builtin", "/etc/igor/packages.d/mirror.yaml"}, []packageSetView{ubuntu, arch})
+	out := buf.String()
+
+	assert.Contains(t, out, "Package catalog: builtin, /etc/igor/packages.d/mirror.yaml")
+	assert.Contains(t, out, "ubuntu (debian family)\n")
+	assert.Contains(t, out, "  Driver pattern:  nvidia-driver-%s\n")
+	assert.Contains(t, out, "  CUDA libraries:  nvidia-cuda-dev libcublas-dev\n")
+	assert.Contains(t, out, "arch family (arch)\n")
+	assert.Contains(t, out, "  Defined in:      builtin\n")
+	// Arch has no version patterns
+	assert.Equal(t, 2, bytes.Count(buf.Bytes(), []byte("pattern:")))
+}
+
+func TestLoadPackageCatalog(t *testing.T) {
+	t.Cleanup(func() { nvidia.SetCatalog(nil) })
+
+	cfg := config.DefaultConfig()
+	cfg.ConfigDir = t.TempDir()
+	dir := filepath.Join(cfg.ConfigDir, nvidia.CatalogDirName)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "mirror.yaml"),
+		[]byte("version: 1\ndistributions:\n  ubuntu:\n    utils: [mirror-nvidia-utils]\n"), 0644))
+
+	c := &CLI{config: cfg}
+	require.NoError(t, c.loadPackageCatalog())
+
+	ps := nvidia.GetPackageSet(&distro.Distribution{ID: "ubuntu", Family: constants.FamilyDebian})
+	require.NotNil(t, ps)
+	assert.Equal(t, []string{"mirror-nvidia-utils"}, ps.Utils)
+}
+
+func TestLoadPackageCatalog_Invalid(t *testing.T) {
+	t.Cleanup(func() { nvidia.SetCatalog(nil) })
+
+	cfg := config.DefaultConfig()
+	cfg.ConfigDir = t.TempDir()
+	dir := filepath.Join(cfg.ConfigDir, nvidia.CatalogDirName)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "mirror.yaml"), []byte("version: 2\n"), 0644))
+
+	c := &CLI{config: cfg}
+	err := c.loadPackageCatalog()
+	require.Error(t, err)
+	assert.ErrorIs(t, err, nvidia.ErrInvalidCatalog)
+
+	// The catalog in use is unchanged
+	assert.Equal(t, nvidia.BuiltinCatalog(), nvidia.CurrentCatalog())
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tungetti/igor/internal/cli"
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/install/builder"
+	"github.com/tungetti/igor/internal/install/plan"
+	"github.com/tungetti/igor/internal/install/steps"
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// installPlanSchemaVersion is the version of the "igor plan --json" document.
+const installPlanSchemaVersion = "1"
+
+// planDocument is the result of the plan command.
+// It is also the JSON document emitted by "igor plan --json".
+type planDocument struct {
+	SchemaVersion string   `json:"schema_version"`
+	Summary       string   `json:"summary"`
+	Distribution  string   `json:"distribution"`
+	DriverVersion string   `json:"driver_version,omitempty"`
+	Components    []string `json:"components"`
+	Warnings      []string `json:"warnings"`
+	// Transaction is the simulated package transaction, if the package
+	// manager could simulate it.
+	Transaction *pkg.Simulation `json:"transaction,omitempty"`
+	// DiskUsage is the disk space the transaction needs in each directory,
+	// with the free space of the filesystems receiving the files.
+	DiskUsage []pkg.DiskUsage `json:"disk_usage,omitempty"`
+	*plan.Plan
+}
+
+// cmdPlan handles the plan command.
+// It resolves the installation like the install command, then runs the
+// installation workflow against a recording executor and prints the steps,
+// packages, files and commands the installation would involve.
+func (c *CLI) cmdPlan(result *cli.ParseResult) int {
+	flags := result.PlanFlags
+	installFlags := flags.InstallFlags()
+
+	ctx, cancel := c.commandContext()
+	defer cancel()
+
+	executor := exec.NewExecutor(exec.DefaultOptions(), nil)
+	recorder := exec.NewRecordingExecutor(executor)
+
+	// The package manager records its commands too, so the package
+	// installation step shows the exact package manager invocation.
+	dist, pm, err := detectPackageManager(ctx, recorder)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return constants.ExitError.Int()
+	}
+
+	gpuInfo, err := newDetectionOrchestrator(executor).DetectAll(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: GPU detection failed: %v\n", err)
+		return constants.ExitError.Int()
+	}
+
+	force := installFlags.Force || c.config.ForceInstall
+	if !gpuInfo.HasNVIDIAGPUs() && !force {
+		fmt.Fprintln(os.Stderr, "Error: no NVIDIA GPU detected (use --force to plan anyway)")
+		return constants.ExitValidation.Int()
+	}
+
+	if !force && driverSatisfied(gpuInfo, requestedDriverVersion(installFlags, c.config)) {
+		fmt.Printf("NVIDIA driver %s is already installed; igor install would make no changes (use --force to plan a reinstall)\n",
+			gpuInfo.InstalledDriver.Version)
+		return constants.ExitSuccess.Int()
+	}
+
+	resolved, err := resolveInstallPlan(ctx, dist, pm, gpuInfo, installFlags, c.config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return constants.ExitValidation.Int()
+	}
+	for _, warning := range resolved.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
+	workflow, err := builder.NewWorkflowBuilder(dist,
+		builder.WithAdditionalPackages(resolved.AdditionalPackages...),
+		builder.WithReinstall(resolved.Reinstall),
+		builder.WithAllowEssentialRemovals(force),
+	).Build()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to build installation workflow: %v\n", err)
+		return constants.ExitError.Int()
+	}
+
+	installCtx := install.NewContext(
+		install.WithGPUInfo(gpuInfo),
+		install.WithDistroInfo(dist),
+		install.WithDriverVersion(resolved.DriverVersion),
+		install.WithComponents(resolved.Components),
+		install.WithPackageManager(pm),
+		install.WithExecutor(recorder),
+		install.WithLogger(c.stepLogger()),
+		install.WithContext(ctx),
+	)
+
+	p, err := plan.Generate(installCtx, workflow, recorder)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to plan installation: %v\n", err)
+		return constants.ExitError.Int()
+	}
+
+	doc := &planDocument{
+		SchemaVersion: installPlanSchemaVersion,
+		Summary:       installHeader(dist, resolved, false),
+		Distribution:  dist.String(),
+		DriverVersion: resolved.DriverVersion,
+		Components:    resolved.Components,
+		Warnings:      resolved.Warnings,
+		Plan:          p,
+	}
+	if doc.Warnings == nil {
+		doc.Warnings = make([]string, 0)
+	}
+	if sim, ok := installCtx.GetState(steps.StatePackageSimulation); ok {
+		doc.Transaction, _ = sim.(*pkg.Simulation)
+	}
+	if usage, ok := installCtx.GetState(steps.StateDiskUsage); ok {
+		doc.DiskUsage, _ = usage.([]pkg.DiskUsage)
+	}
+
+	switch {
+	case flags.JSON:
+		if err := writePlanJSON(os.Stdout, doc); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to encode plan: %v\n", err)
+			return constants.ExitError.Int()
+		}
+	case flags.Script:
+		writePlanScript(os.Stdout, doc)
+	default:
+		writePlanText(os.Stdout, doc, c.config.IsVerbose())
+	}
+
+	return constants.ExitSuccess.Int()
+}
+
+// writePlanJSON writes the plan as indented JSON.
+func writePlanJSON(w io.Writer, doc *planDocument) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// writePlanText writes the plan for reading in a terminal. Commands run
+// with root privileges are prefixed with "#", the others with "$". Probes
+// are only listed when verbose is set.
+func writePlanText(w io.Writer, doc *planDocument, verbose bool) {
+	fmt.Fprintf(w, "Plan: %s\n", doc.Summary)
+	fmt.Fprintf(w, "Nothing has been changed. Commands marked # run with root privileges.\n")
+
+	if doc.Transaction != nil {
+		writePlanTransaction(w, doc.Transaction)
+	}
+	if len(doc.DiskUsage) > 0 {
+		writePlanDiskUsage(w, doc.DiskUsage)
+	}
+
+	for i, step := range doc.Steps {
+		fmt.Fprintf(w, "\n%d. %s: %s\n", i+1, step.Name, step.Description)
+
+		if note := planStepNote(step); note != "" {
+			fmt.Fprintf(w, "   %s\n", note)
+		}
+
+		if len(step.Packages) > 0 {
+			fmt.Fprintf(w, "   Packages: %s\n", strings.Join(step.Packages, ", "))
+		}
+
+		commands := step.Changes()
+		if verbose {
+			commands = step.Commands
+		}
+		for _, cmd := range commands {
+			prompt := "$"
+			if cmd.Elevated {
+				prompt = "#"
+			}
+			line := fmt.Sprintf("   %s %s", prompt, cmd)
+			switch {
+			case cmd.Probe:
+				line += "  (read-only query)"
+			case cmd.Input != "":
+				line += fmt.Sprintf("  (%d bytes on stdin)", len(cmd.Input))
+			}
+			fmt.Fprintln(w, line)
+		}
+
+		for _, file := range step.Files {
+			verb := "Writes"
+			if file.Append {
+				verb = "Appends to"
+			}
+			fmt.Fprintf(w, "   %s %s:\n", verb, file.Path)
+			for _, line := range strings.Split(strings.TrimSuffix(file.Content, "\n"), "\n") {
+				fmt.Fprintf(w, "     | %s\n", line)
+			}
+		}
+	}
+}
+
+// writePlanTransaction writes the package changes of the simulated
+// transaction, marking the removals of essential packages.
+func writePlanTransaction(w io.Writer, sim *pkg.Simulation) {
+	fmt.Fprintf(w, "\nPackage changes: %s\n", sim.Summary())
+	for _, group := range []struct {
+		label   string
+		changes []pkg.PackageChange
+	}{
+		{"Install", sim.Install},
+		{"Upgrade", sim.Upgrade},
+		{"Downgrade", sim.Downgrade},
+		{"Remove", sim.Remove},
+	} {
+		if len(group.changes) == 0 {
+			continue
+		}
+		names := make([]string, 0, len(group.changes))
+		for _, change := range group.changes {
+			names = append(names, formatPackageChange(change))
+		}
+		fmt.Fprintf(w, "   %s: %s\n", group.label, strings.Join(names, ", "))
+	}
+}
+
+// writePlanDiskUsage writes the disk space the transaction needs in each
+// directory, and the filesystems without enough free space.
+func writePlanDiskUsage(w io.Writer, usage []pkg.DiskUsage) {
+	fmt.Fprintf(w, "Disk space:\n")
+	for _, u := range usage {
+		fmt.Fprintf(w, "   %s\n", u)
+	}
+	for _, shortage := range pkg.DiskShortages(usage) {
+		fmt.Fprintf(w, "   NOT ENOUGH SPACE: %s\n", shortage)
+	}
+}
+
+// formatPackageChange returns the package of a change with its versions
+// and, for removals, why it is removed.
+func formatPackageChange(change pkg.PackageChange) string {
+	s := change.Name
+	switch {
+	case change.OldVersion != "" && change.Version != "":
+		s += fmt.Sprintf(" (%s -> %s)", change.OldVersion, change.Version)
+	case change.Version != "":
+		s += " " + change.Version
+	}
+
+	var notes []string
+	if change.ReplacedBy != "" {
+		notes = append(notes, "replaced by "+change.ReplacedBy)
+	}
+	if change.Essential || pkg.IsEssentialPackage(change.Name) {
+		notes = append(notes, "ESSENTIAL")
+	}
+	if len(notes) > 0 {
+		s += " [" + strings.Join(notes, ", ") + "]"
+	}
+	return s
+}
+
+// planStepNote returns the note printed for a step that was skipped or
+// failed while planning, or "" if the step completed.
+func planStepNote(step plan.Step) string {
+	switch step.Status {
+	case install.StepStatusSkipped.String():
+		return "Skipped: " + step.Message
+	case install.StepStatusFailed.String():
+		note := "Failed while planning: " + step.Message
+		if step.Error != "" {
+			note += ": " + step.Error
+		}
+		return note
+	default:
+		return ""
+	}
+}
+
+// planScriptDelimiter ends the here-documents of a plan script.
+const planScriptDelimiter = "IGOR_EOF"
+
+// writePlanScript writes the commands of the plan that change the system as
+// a POSIX shell script meant to be reviewed. Probes are left out.
+func writePlanScript(w io.Writer, doc *planDocument) {
+	fmt.Fprintln(w, "#!/bin/sh")
+	fmt.Fprintf(w, "# %s\n", doc.Summary)
+	fmt.Fprintf(w, "# Generated by %s plan on %s.\n", constants.AppName, doc.GeneratedAt.Format(time.RFC3339))
+	fmt.Fprintln(w, "# Review before running; the script must run as root.")
+	fmt.Fprintln(w, "set -eu")
+
+	for i, step := range doc.Steps {
+		fmt.Fprintf(w, "\n# [%d/%d] %s: %s\n", i+1, len(doc.Steps), step.Name, step.Description)
+		if note := planStepNote(step); note != "" {
+			fmt.Fprintf(w, "# %s\n", note)
+		}
+
+		changes := step.Changes()
+		if len(changes) == 0 {
+			fmt.Fprintln(w, "# (no changes)")
+			continue
+		}
+		for _, cmd := range changes {
+			writeScriptCommand(w, cmd)
+		}
+	}
+}
+
+// writeScriptCommand writes a single command of a plan script, feeding its
+// input through a here-document when it has any.
+func writeScriptCommand(w io.Writer, cmd plan.Command) {
+	words := make([]string, 0, len(cmd.Args)+1)
+	for _, word := range append([]string{cmd.Command}, cmd.Args...) {
+		words = append(words, shellQuote(word))
+	}
+	line := strings.Join(words, " ")
+
+	if isTee(cmd) {
+		line += " >/dev/null"
+	}
+
+	switch {
+	case cmd.Input == "":
+		fmt.Fprintln(w, line)
+	case strings.HasSuffix(cmd.Input, "\n") && !strings.Contains("\n"+cmd.Input, "\n"+planScriptDelimiter+"\n"):
+		fmt.Fprintf(w, "%s <<'%s'\n%s%s\n", line, planScriptDelimiter, cmd.Input, planScriptDelimiter)
+	default:
+		fmt.Fprintf(w, "printf '%%s' %s | %s\n", shellQuote(cmd.Input), line)
+	}
+}
+
+// isTee reports whether the command writes its input to a file with tee.
+func isTee(cmd plan.Command) bool {
+	return cmd.Command == "tee" || (cmd.Command == "sudo" && len(cmd.Args) > 0 && cmd.Args[0] == "tee")
+}
+
+// shellSafe matches words that need no quoting in a POSIX shell.
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
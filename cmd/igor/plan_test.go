@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/install/plan"
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+func newTestPlanDocument() *planDocument {
+	return &planDocument{
+		SchemaVersion: installPlanSchemaVersion,
+		Summary:       "Installing NVIDIA driver 550 on Ubuntu 24.04",
+		Distribution:  "Ubuntu 24.04",
+		DriverVersion: "550",
+		Components:    []string{},
+		Warnings:      []string{},
+		Plan: &plan.Plan{
+			Workflow:    "ubuntu-nvidia-installation",
+			GeneratedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			Steps: []plan.Step{
+				{
+					Name:        "nouveau_blacklist",
+					Description: "Blacklist Nouveau driver",
+					Status:      "completed",
+					Commands: []plan.Command{
+						{Command: "test", Args: []string{"-f", "/etc/modprobe.d/blacklist-nouveau.conf"}, Probe: true},
+						{Command: "tee", Args: []string{"/etc/modprobe.d/blacklist-nouveau.conf"}, Input: "blacklist nouveau\n"},
+						{Command: "update-initramfs", Args: []string{"-u"}, Elevated: true},
+					},
+					Files: []plan.File{{Path: "/etc/modprobe.d/blacklist-nouveau.conf", Content: "blacklist nouveau\n"}},
+				},
+				{
+					Name:        "packages",
+					Description: "Install NVIDIA packages",
+					Status:      "completed",
+					Packages:    []string{"nvidia-driver-550", "nvidia-settings"},
+					Commands: []plan.Command{
+						{Command: "apt-get", Args: []string{"install", "-y", "nvidia-driver-550", "nvidia-settings"}, Elevated: true},
+					},
+				},
+				{
+					Name:        "dkms_build",
+					Description: "Build NVIDIA kernel modules with DKMS",
+					Status:      "skipped",
+					Message:     "DKMS is not available",
+					Commands:    []plan.Command{{Command: "which", Args: []string{"dkms"}, Probe: true}},
+				},
+			},
+		},
+	}
+}
+
+func TestWritePlanText(t *testing.T) {
+	var buf bytes.Buffer
+	writePlanText(&buf, newTestPlanDocument(), false)
+
+	out := buf.String()
+	assert.Contains(t, out, "Plan: Installing NVIDIA driver 550 on Ubuntu 24.04\n")
+	assert.Contains(t, out, "1. nouveau_blacklist: Blacklist Nouveau driver\n"+
+		"   $ tee /etc/modprobe.d/blacklist-nouveau.conf  (18 bytes on stdin)\n"+
+		"   # update-initramfs -u\n"+
+		"   Writes /etc/modprobe.d/blacklist-nouveau.conf:\n"+
+		"     | blacklist nouveau\n")
+	assert.Contains(t, out, "   Packages: nvidia-driver-550, nvidia-settings\n"+
+		"   # apt-get install -y nvidia-driver-550 nvidia-settings\n")
+	assert.Contains(t, out, "3. dkms_build: Build NVIDIA kernel modules with DKMS\n   Skipped: DKMS is not available\n")
+	assert.NotContains(t, out, "which dkms")
+
+	buf.Reset()
+	writePlanText(&buf, newTestPlanDocument(), true)
+	assert.Contains(t, buf.String(), "   $ which dkms  (read-only query)\n")
+}
+
+func TestWritePlanText_Transaction(t *testing.T) {
+	doc := newTestPlanDocument()
+	doc.Transaction = pkg.NewSimulation()
+	doc.Transaction.Install = []pkg.PackageChange{{Name: "nvidia-driver-550", Version: "550.120-0ubuntu1"}}
+	doc.Transaction.Upgrade = []pkg.PackageChange{{Name: "libnvidia-egl-wayland1", OldVersion: "1.1.9", Version: "1.1.13"}}
+	doc.Transaction.Remove = []pkg.PackageChange{
+		{Name: "xserver-xorg-video-nouveau", ReplacedBy: "nvidia-driver-550"},
+		{Name: "xserver-xorg-core"},
+	}
+	doc.Transaction.DownloadSize = 300 << 20
+
+	var buf bytes.Buffer
+	writePlanText(&buf, doc, false)
+
+	assert.Contains(t, buf.String(), "\nPackage changes: 1 to install, 1 to upgrade, 2 to remove, 300.0 MiB to download\n"+
+		"   Install: nvidia-driver-550 550.120-0ubuntu1\n"+
+		"   Upgrade: libnvidia-egl-wayland1 (1.1.9 -> 1.1.13)\n"+
+		"   Remove: xserver-xorg-video-nouveau [replaced by nvidia-driver-550], xserver-xorg-core [ESSENTIAL]\n")
+}
+
+func TestWritePlanText_DiskUsage(t *testing.T) {
+	doc := newTestPlanDocument()
+	doc.Transaction = pkg.NewSimulation()
+	doc.DiskUsage = []pkg.DiskUsage{
+		{Dir: pkg.PackageCacheDir, Required: 300 << 20, MountPoint: "/", Available: 1 << 30},
+		{Dir: pkg.SystemDir, Required: 1 << 30, MountPoint: "/", Available: 1 << 30},
+	}
+
+	var buf bytes.Buffer
+	writePlanText(&buf, doc, false)
+
+	assert.Contains(t, buf.String(), "Disk space:\n"+
+		"   /var/cache: 300.0 MiB needed, 1.0 GiB free on /\n"+
+		"   /usr: 1.0 GiB needed, 1.0 GiB free on /\n"+
+		"   NOT ENOUGH SPACE: / has 1.0 GiB free, 1.3 GiB needed for /var/cache, /usr\n")
+}
+
+func TestWritePlanScript(t *testing.T) {
+	var buf bytes.Buffer
+	writePlanScript(&buf, newTestPlanDocument())
+
+	out := buf.String()
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("#!/bin/sh\n")))
+	assert.Contains(t, out, "set -eu\n")
+	assert.Contains(t, out, "# [1/3] nouveau_blacklist: Blacklist Nouveau driver\n"+
+		"tee /etc/modprobe.d/blacklist-nouveau.conf >/dev/null <<'IGOR_EOF'\n"+
+		"blacklist nouveau\n"+
+		"IGOR_EOF\n"+
+		"update-initramfs -u\n")
+	assert.Contains(t, out, "apt-get install -y nvidia-driver-550 nvidia-settings\n")
+	assert.Contains(t, out, "# [3/3] dkms_build: Build NVIDIA kernel modules with DKMS\n# Skipped: DKMS is not available\n# (no changes)\n")
+	assert.NotContains(t, out, "\ntest -f")
+	assert.NotContains(t, out, "\nwhich dkms")
+}
+
+func TestWriteScriptCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		cmd      plan.Command
+		expected string
+	}{
+		{
+			name:     "quoting",
+			cmd:      plan.Command{Command: "sh", Args: []string{"-c", "curl -fsSL https://example.com/key | gpg --dearmor"}},
+			expected: "sh -c 'curl -fsSL https://example.com/key | gpg --dearmor'\n",
+		},
+		{
+			name:     "input without trailing newline",
+			cmd:      plan.Command{Command: "sudo", Args: []string{"tee", "/etc/apt/sources.list.d/cuda.list"}, Input: "deb https://example.com /"},
+			expected: "printf '%s' 'deb https://example.com /' | sudo tee /etc/apt/sources.list.d/cuda.list >/dev/null\n",
+		},
+		{
+			name:     "input containing the delimiter",
+			cmd:      plan.Command{Command: "cat", Input: "IGOR_EOF\n"},
+			expected: "printf '%s' 'IGOR_EOF\n' | cat\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			writeScriptCommand(&buf, tt.cmd)
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, "DEBIAN_FRONTEND=noninteractive", shellQuote("DEBIAN_FRONTEND=noninteractive"))
+	assert.Equal(t, "''", shellQuote(""))
+	assert.Equal(t, `'it'\''s'`, shellQuote("it's"))
+}
+
+func TestWritePlanJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writePlanJSON(&buf, newTestPlanDocument()))
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+
+	assert.Equal(t, installPlanSchemaVersion, doc["schema_version"])
+	assert.Equal(t, "ubuntu-nvidia-installation", doc["workflow"])
+
+	steps := doc["steps"].([]interface{})
+	require.Len(t, steps, 3)
+	first := steps[0].(map[string]interface{})
+	assert.Equal(t, "nouveau_blacklist", first["name"])
+	assert.Len(t, first["commands"], 3)
+	assert.Len(t, first["files"], 1)
+	assert.NotContains(t, doc, "transaction")
+
+	withTransaction := newTestPlanDocument()
+	withTransaction.Transaction = pkg.NewSimulation()
+	buf.Reset()
+	require.NoError(t, writePlanJSON(&buf, withTransaction))
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+	assert.Contains(t, doc, "transaction")
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/tungetti/igor/internal/cli"
+	"github.com/tungetti/igor/internal/config"
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/errors"
+	"github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/install/builder"
+	"github.com/tungetti/igor/internal/install/journal"
+	"github.com/tungetti/igor/internal/privilege"
+)
+
+// runJournalDir returns the directory of the run journal.
+func runJournalDir(cfg *config.Config) string {
+	return cfg.StatePath("runs")
+}
+
+// cmdRollback handles the rollback command.
+// It loads a run from the journal, rebuilds the steps the run completed and
+// rolls them back in reverse order with the state the run recorded.
+func (c *CLI) cmdRollback(result *cli.ParseResult) int {
+	flags := result.RollbackFlags
+	dryRun := c.config.DryRun
+	store := journal.NewStore(runJournalDir(c.config))
+
+	if flags.List {
+		entries, err := store.List()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return constants.ExitError.Int()
+		}
+		writeRunList(os.Stdout, entries)
+		return constants.ExitSuccess.Int()
+	}
+
+	priv := privilege.NewManager()
+	if !dryRun {
+		if err := priv.RequireRoot(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return constants.ExitPermission.Int()
+		}
+	}
+
+	entry, err := selectRun(store, result.Args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if errors.IsCode(err, errors.NotFound) {
+			return constants.ExitValidation.Int()
+		}
+		return constants.ExitError.Int()
+	}
+	if needed, reason := entry.RollbackNeeded(); !needed {
+		fmt.Fprintf(os.Stderr, "Error: %s; nothing to roll back\n", reason)
+		return constants.ExitValidation.Int()
+	}
+
+	ctx, cancel := c.longCommandContext()
+	defer cancel()
+
+	// In dry-run mode the rollbacks run against a recording executor, so
+	// their commands are shown instead of run.
+	var executor exec.Executor = newLongCommandExecutor(priv)
+	var recorder *exec.RecordingExecutor
+	if dryRun {
+		recorder = exec.NewRecordingExecutor(executor)
+		executor = recorder
+	}
+
+	dist, pm, err := detectPackageManager(ctx, executor)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return constants.ExitError.Int()
+	}
+	if entry.Distribution != "" && entry.Distribution != dist.String() {
+		fmt.Fprintf(os.Stderr, "Warning: run %s was made on %s, this system is %s\n", entry.ID, entry.Distribution, dist.String())
+	}
+
+	workflow, err := builder.NewWorkflowBuilder(dist).Build()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to build installation workflow: %v\n", err)
+		return constants.ExitError.Int()
+	}
+
+	steps, err := completedRunSteps(workflow, entry.CompletedSteps)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot roll back run %s: %v\n", entry.ID, err)
+		return constants.ExitValidation.Int()
+	}
+
+	writeRollbackPlan(os.Stdout, entry, steps, dryRun)
+
+	if !dryRun && !flags.Yes {
+		if !confirm(os.Stdin, os.Stdout, fmt.Sprintf("Roll back run %s?", entry.ID)) {
+			fmt.Fprintln(os.Stderr, "Rollback cancelled (use --yes to skip this prompt)")
+			return constants.ExitUserAbort.Int()
+		}
+	}
+
+	rollbackCtx := install.NewContext(
+		install.WithDistroInfo(dist),
+		install.WithDriverVersion(entry.DriverVersion),
+		install.WithPackageManager(pm),
+		install.WithExecutor(executor),
+		install.WithPrivilege(priv),
+		install.WithLogger(c.stepLogger()),
+		install.WithContext(ctx),
+	)
+	if err := entry.RestoreState(rollbackCtx); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: journal of run %s is corrupt: %v\n", entry.ID, err)
+		return constants.ExitError.Int()
+	}
+
+	var out io.Writer = os.Stdout
+	if c.config.IsSilent() {
+		out = io.Discard
+	}
+
+	// Commands recorded in dry-run mode are printed under the step that
+	// recorded them, before the progress line of the next step.
+	printed := 0
+	flushCommands := func() {
+		if recorder == nil {
+			return
+		}
+		commands := recorder.Commands()
+		for _, cmd := range commands[printed:] {
+			if !cmd.Probe {
+				fmt.Fprintf(out, "    %s\n", formatRecordedCommand(cmd))
+			}
+		}
+		printed = len(commands)
+	}
+
+	// Held packages cannot be removed by some package managers. In dry-run
+	// mode this shows the commands that release the holds.
+	if err := releaseHolds(ctx, pm, installedPackagesState(rollbackCtx)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to release package holds: %v\n", err)
+	}
+
+	rollbackErr := install.RollbackSteps(rollbackCtx, steps, func(p install.StepProgress) {
+		flushCommands()
+		fmt.Fprintln(out, formatInstallProgress(p))
+	})
+	flushCommands()
+
+	if dryRun {
+		if rollbackErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", rollbackErr)
+		}
+		fmt.Fprintln(out, "[dry-run] No changes were made")
+		return constants.ExitSuccess.Int()
+	}
+
+	entry.RolledBack = &journal.Rollback{Time: time.Now(), Success: rollbackErr == nil}
+	if rollbackErr != nil {
+		entry.RolledBack.Error = rollbackErr.Error()
+	}
+	if err := store.Save(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to update the run journal: %v\n", err)
+	}
+
+	if rollbackErr != nil {
+		fmt.Fprintf(os.Stderr, "Error: rollback of run %s did not complete cleanly: %v\n", entry.ID, rollbackErr)
+		return constants.ExitInstallation.Int()
+	}
+
+	fmt.Fprintf(out, "Run %s was rolled back\n", entry.ID)
+	fmt.Fprintln(out, "Reboot the system to finish restoring the previous configuration.")
+	return constants.ExitSuccess.Int()
+}
+
+// selectRun returns the run named in args, or the most recent run that was
+// not rolled back when args is empty.
+func selectRun(store *journal.Store, args []string) (*journal.Entry, error) {
+	if len(args) > 0 {
+		return store.Load(args[0])
+	}
+
+	entries, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if needed, _ := entry.RollbackNeeded(); needed {
+			return entry, nil
+		}
+	}
+	return nil, errors.Newf(errors.NotFound, "no run to roll back in %s (use --list to see the recorded runs)", store.Dir())
+}
+
+// completedRunSteps returns the workflow steps named in completed, in the
+// same order. Every completed step must still exist in the workflow.
+func completedRunSteps(workflow install.Workflow, completed []string) ([]install.Step, error) {
+	byName := make(map[string]install.Step)
+	for _, step := range workflow.Steps() {
+		byName[step.Name()] = step
+	}
+
+	steps := make([]install.Step, 0, len(completed))
+	for _, name := range completed {
+		step, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("step %q is not part of the %s workflow", name, workflow.Name())
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// writeRollbackPlan lists the steps of a run that will be rolled back, in
+// the order they are rolled back.
+func writeRollbackPlan(w io.Writer, entry *journal.Entry, steps []install.Step, dryRun bool) {
+	title := "The following steps of run %s will be rolled back"
+	if dryRun {
+		title = "[dry-run] " + title
+	}
+	fmt.Fprintf(w, title+":\n", entry.ID)
+	fmt.Fprintf(w, "  Run: %s %s on %s, %s\n", entry.Command, runDescription(entry),
+		entry.StartTime().Local().Format("2006-01-02 15:04"), entry.Status)
+
+	for i := len(steps) - 1; i >= 0; i-- {
+		step := steps[i]
+		if step.CanRollback() {
+			fmt.Fprintf(w, "    - %s: %s\n", step.Name(), step.Description())
+		} else {
+			fmt.Fprintf(w, "    - %s: %s (nothing to undo)\n", step.Name(), step.Description())
+		}
+	}
+}
+
+// writeRunList lists the runs of the journal, newest first.
+func writeRunList(w io.Writer, entries []*journal.Entry) {
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "No runs recorded")
+		return
+	}
+
+	fmt.Fprintf(w, "  %-22s %-16s %-10s %-10s %s\n", "Run", "Started", "Command", "Status", "Rollback")
+	fmt.Fprintf(w, "  %-22s %-16s %-10s %-10s %s\n", "---", "-------", "-------", "------", "--------")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "  %-22s %-16s %-10s %-10s %s\n",
+			entry.ID,
+			entry.StartTime().Local().Format("2006-01-02 15:04"),
+			entry.Command,
+			entry.Status,
+			runRollbackStatus(entry),
+		)
+	}
+}
+
+// runDescription describes what a run installed.
+func runDescription(entry *journal.Entry) string {
+	if entry.DriverVersion == "" {
+		return "(" + entry.Workflow + ")"
+	}
+	return "of driver " + entry.DriverVersion
+}
+
+// runRollbackStatus describes whether a run was rolled back.
+func runRollbackStatus(entry *journal.Entry) string {
+	switch {
+	case entry.RolledBack != nil && entry.RolledBack.Success:
+		return "rolled back"
+	case entry.RolledBack != nil:
+		return "rollback failed"
+	case entry.Report != nil && entry.Report.RollbackPerformed && entry.Report.RollbackSuccess:
+		return "rolled back automatically"
+	case len(entry.CompletedSteps) == 0:
+		return "-"
+	default:
+		return "available"
+	}
+}
+
+// formatRecordedCommand formats a command recorded in dry-run mode, with the
+// "#" prompt for commands run with root privileges and "$" for the others.
+func formatRecordedCommand(cmd exec.RecordedCommand) string {
+	prompt := "$"
+	if cmd.Elevated {
+		prompt = "#"
+	}
+	line := prompt + " " + cmd.Command
+	for _, arg := range cmd.Args {
+		line += " " + shellQuote(arg)
+	}
+	return line
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/distro"
+	"github.com/tungetti/igor/internal/errors"
+	"github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/install/builder"
+	"github.com/tungetti/igor/internal/install/journal"
+	"github.com/tungetti/igor/internal/pkg/apt"
+)
+
+func newTestRunEntry(t *testing.T, id string, start time.Time, completed ...string) *journal.Entry {
+	t.Helper()
+	entry := journal.NewEntry(id, "install", "debian-nvidia-installation")
+	entry.Status = install.WorkflowStatusCompleted.String()
+	entry.DriverVersion = "550"
+	entry.Report.StartTime = start
+	entry.CompletedSteps = completed
+	return entry
+}
+
+func TestSelectRun(t *testing.T) {
+	store := journal.NewStore(t.TempDir())
+
+	_, err := selectRun(store, nil)
+	assert.True(t, errors.IsCode(err, errors.NotFound))
+
+	now := time.Now()
+	rolledBack := newTestRunEntry(t, "newest", now, "packages")
+	rolledBack.RolledBack = &journal.Rollback{Time: now, Success: true}
+	require.NoError(t, store.Save(rolledBack))
+	require.NoError(t, store.Save(newTestRunEntry(t, "middle", now.Add(-time.Hour), "packages")))
+	require.NoError(t, store.Save(newTestRunEntry(t, "oldest", now.Add(-2*time.Hour), "packages")))
+
+	entry, err := selectRun(store, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "middle", entry.ID)
+
+	entry, err = selectRun(store, []string{"oldest"})
+	require.NoError(t, err)
+	assert.Equal(t, "oldest", entry.ID)
+
+	_, err = selectRun(store, []string{"missing"})
+	assert.True(t, errors.IsCode(err, errors.NotFound))
+}
+
+func TestCompletedRunSteps(t *testing.T) {
+	dist := &distro.Distribution{ID: "ubuntu", VersionID: "24.04", Family: constants.FamilyDebian}
+	workflow, err := builder.NewWorkflowBuilder(dist).Build()
+	require.NoError(t, err)
+
+	steps, err := completedRunSteps(workflow, []string{"nouveau_blacklist", "packages"})
+	require.NoError(t, err)
+	require.Len(t, steps, 2)
+	assert.Equal(t, "nouveau_blacklist", steps[0].Name())
+	assert.Equal(t, "packages", steps[1].Name())
+
+	_, err = completedRunSteps(workflow, []string{"packages", "akmods"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `"akmods"`)
+}
+
+// TestRollbackFromJournal rolls back a run recorded by another process:
+// the steps are rebuilt and only get the state saved in the journal.
+func TestRollbackFromJournal(t *testing.T) {
+	dist := &distro.Distribution{ID: "ubuntu", VersionID: "24.04", Family: constants.FamilyDebian}
+
+	// The run, as recorded by igor install
+	runCtx := install.NewContext()
+	runCtx.SetState("nouveau_blacklisted", true)
+	runCtx.SetState("nouveau_blacklist_file", "/etc/modprobe.d/blacklist-nouveau.conf")
+	runCtx.SetState("packages_installed", true)
+	runCtx.SetState("installed_packages", []string{"nvidia-driver-550", "nvidia-settings"})
+
+	store := journal.NewStore(filepath.Join(t.TempDir(), "runs"))
+	entry := newTestRunEntry(t, "run1", time.Now(), "nouveau_blacklist", "packages")
+	entry.CaptureState(runCtx)
+	require.NoError(t, store.Save(entry))
+
+	// The rollback, in a new process
+	loaded, err := store.Load("run1")
+	require.NoError(t, err)
+
+	workflow, err := builder.NewWorkflowBuilder(dist).Build()
+	require.NoError(t, err)
+	steps, err := completedRunSteps(workflow, loaded.CompletedSteps)
+	require.NoError(t, err)
+
+	recorder := exec.NewRecordingExecutor(nil)
+	ctx := install.NewContext(
+		install.WithDistroInfo(dist),
+		install.WithExecutor(recorder),
+		install.WithPackageManager(apt.NewManager(recorder, nil)),
+	)
+	require.NoError(t, loaded.RestoreState(ctx))
+	require.NoError(t, install.RollbackSteps(ctx, steps, nil))
+
+	var lines []string
+	for _, cmd := range recorder.Commands() {
+		if !cmd.Probe {
+			lines = append(lines, formatRecordedCommand(cmd))
+		}
+	}
+	joined := strings.Join(lines, "\n")
+
+	// Packages are removed before the blacklist file
+	removal := strings.Index(joined, "nvidia-driver-550")
+	blacklist := strings.Index(joined, "/etc/modprobe.d/blacklist-nouveau.conf")
+	require.GreaterOrEqual(t, removal, 0, joined)
+	require.GreaterOrEqual(t, blacklist, 0, joined)
+	assert.Less(t, removal, blacklist)
+}
+
+func TestWriteRollbackPlan(t *testing.T) {
+	withRollback := install.NewFuncStep("packages", "Install NVIDIA packages", func(ctx *install.Context) install.StepResult {
+		return install.CompleteStep("done")
+	}, install.WithRollbackFunc(func(ctx *install.Context) error { return nil }))
+	withoutRollback := install.NewFuncStep("validation", "Validate system", func(ctx *install.Context) install.StepResult {
+		return install.CompleteStep("done")
+	})
+
+	entry := newTestRunEntry(t, "run1", time.Date(2026, 10, 16, 10, 38, 0, 0, time.Local), "validation", "packages")
+
+	var buf bytes.Buffer
+	writeRollbackPlan(&buf, entry, []install.Step{withoutRollback, withRollback}, true)
+
+	assert.Equal(t, "[dry-run] The following steps of run run1 will be rolled back:\n"+
+		"  Run: install of driver 550 on 2026-10-16 10:38, completed\n"+
+		"    - packages: Install NVIDIA packages\n"+
+		"    - validation: Validate system (nothing to undo)\n", buf.String())
+}
+
+func TestWriteRunList(t *testing.T) {
+	var buf bytes.Buffer
+	writeRunList(&buf, nil)
+	assert.Equal(t, "No runs recorded\n", buf.String())
+
+	entry := newTestRunEntry(t, "20261016-103834-4f2a9c", time.Date(2026, 10, 16, 10, 38, 0, 0, time.Local), "packages")
+
+	buf.Reset()
+	writeRunList(&buf, []*journal.Entry{entry})
+	assert.Contains(t, buf.String(), "  20261016-103834-4f2a9c 2026-10-16 10:38 install    completed  available\n")
+}
+
+func TestRunRollbackStatus(t *testing.T) {
+	entry := newTestRunEntry(t, "run1", time.Now(), "packages")
+	assert.Equal(t, "available", runRollbackStatus(entry))
+
+	entry.Report.RollbackPerformed = true
+	entry.Report.RollbackSuccess = true
+	assert.Equal(t, "rolled back automatically", runRollbackStatus(entry))
+
+	entry.RolledBack = &journal.Rollback{Time: time.Now()}
+	assert.Equal(t, "rollback failed", runRollbackStatus(entry))
+
+	entry.RolledBack.Success = true
+	assert.Equal(t, "rolled back", runRollbackStatus(entry))
+
+	assert.Equal(t, "-", runRollbackStatus(newTestRunEntry(t, "run2", time.Now())))
+}
+
+func TestFormatRecordedCommand(t *testing.T) {
+	assert.Equal(t, "# apt-get remove -y nvidia-driver-550",
+		formatRecordedCommand(exec.RecordedCommand{Command: "apt-get", Args: []string{"remove", "-y", "nvidia-driver-550"}, Elevated: true}))
+	assert.Equal(t, "$ sh -c 'echo hi'",
+		formatRecordedCommand(exec.RecordedCommand{Command: "sh", Args: []string{"-c", "echo hi"}}))
+}
+
+func TestFinishRunJournal(t *testing.T) {
+	store := journal.NewStore(t.TempDir())
+	recorder := journal.NewRecorder(store, journal.NewEntry("run1", "install", "test"))
+
+	ctx := install.NewContext()
+	ctx.SetState("packages_installed", true)
+
+	var errOut, out bytes.Buffer
+	finishRunJournal(&errOut, &out, recorder, ctx, install.ExecutionReport{
+		Status:         install.WorkflowStatusCompleted,
+		CompletedSteps: []string{"packages"},
+	})
+
+	assert.Empty(t, errOut.String())
+	assert.Equal(t, "Run run1 recorded; undo it with: igor rollback run1\n", out.String())
+
+	saved, err := store.Load("run1")
+	require.NoError(t, err)
+	assert.Equal(t, "completed", saved.Status)
+	assert.Contains(t, saved.State, "packages_installed")
+
+	// Nothing to undo after a successful automatic rollback
+	out.Reset()
+	finishRunJournal(&errOut, &out, recorder, ctx, install.ExecutionReport{
+		Status:            install.WorkflowStatusFailed,
+		CompletedSteps:    []string{"packages"},
+		RollbackPerformed: true,
+		RollbackSuccess:   true,
+	})
+	assert.Empty(t, out.String())
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tungetti/igor/internal/cli"
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/distro"
+	"github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/gpu/kernel"
+	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/pkg"
+	"github.com/tungetti/igor/internal/pkg/nvidia"
+	"github.com/tungetti/igor/internal/privilege"
+	"github.com/tungetti/igor/internal/uninstall"
+	"github.com/tungetti/igor/internal/uninstall/steps"
+)
+
+// cmdUninstall handles the uninstall command.
+// It discovers the installed NVIDIA packages, lists them, asks for
+// confirmation unless --yes is given and then runs the uninstall workflow.
+func (c *CLI) cmdUninstall(result *cli.ParseResult) int {
+	flags := result.UninstallFlags
+	dryRun := c.config.DryRun
+
+	if flags.Purge && flags.KeepConfig {
+		fmt.Fprintln(os.Stderr, "Error: --purge and --keep-config cannot be used together")
+		return constants.ExitValidation.Int()
+	}
+
+	priv := privilege.NewManager()
+	if !dryRun {
+		if err := priv.RequireRoot(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return constants.ExitPermission.Int()
+		}
+	}
+
+	ctx, cancel := c.longCommandContext()
+	defer cancel()
+
+	executor := newLongCommandExecutor(priv)
+
+	dist, pm, err := detectPackageManager(ctx, executor)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return constants.ExitError.Int()
+	}
+
+	discovery := uninstall.NewPackageDiscovery(pm,
+		uninstall.WithDiscoveryDistro(dist),
+		uninstall.WithDiscoveryExecutor(executor),
+	)
+	discovered, err := discovery.Discover(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to discover installed packages: %v\n", err)
+		return constants.ExitError.Int()
+	}
+
+	if discovered.IsEmpty() {
+		fmt.Println("No NVIDIA packages are installed; nothing to remove")
+		return constants.ExitSuccess.Int()
+	}
+
+	// Configuration files are kept unless --purge is given.
+	purge := flags.Purge
+
+	writeUninstallPlan(os.Stdout, discovered, purge, dryRun)
+
+	if !dryRun && !flags.Yes {
+		if !confirm(os.Stdin, os.Stdout, fmt.Sprintf("Remove %d packages?", len(discovered.AllPackages))) {
+			fmt.Fprintln(os.Stderr, "Uninstall cancelled (use --yes to skip this prompt)")
+			return constants.ExitUserAbort.Int()
+		}
+	}
+
+	workflow := buildUninstallWorkflow(executor, discovered.AllPackages, purge)
+
+	uninstallCtx := uninstall.NewUninstallContext(
+		uninstall.WithUninstallDistroInfo(dist),
+		uninstall.WithUninstallPackageManager(pm),
+		uninstall.WithUninstallExecutor(executor),
+		uninstall.WithUninstallPrivilege(priv),
+		uninstall.WithUninstallLogger(c.stepLogger()),
+		uninstall.WithUninstallDryRun(dryRun),
+		uninstall.WithUninstallForce(flags.Yes),
+		uninstall.WithKeepConfig(!purge),
+		uninstall.WithInstalledDriver(discovered.DriverVersion),
+		uninstall.WithInstalledPackages(discovered.AllPackages),
+		uninstall.WithUninstallContext(ctx),
+	)
+
+	var out io.Writer = os.Stdout
+	if c.config.IsSilent() {
+		out = io.Discard
+	}
+
+	orchestrator := uninstall.NewUninstallOrchestrator(
+		uninstall.WithUninstallWorkflow(workflow),
+		uninstall.WithUninstallOrchestratorDiscovery(discovery),
+		uninstall.WithUninstallOrchestratorDryRun(dryRun),
+		uninstall.WithUninstallOrchestratorProgress(func(p install.StepProgress) {
+			fmt.Fprintln(out, formatInstallProgress(p))
+		}),
+	)
+
+	if !dryRun {
+		if err := releaseHolds(ctx, pm, discovered.AllPackages); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to release package holds: %v\n", err)
+		}
+	}
+
+	report := orchestrator.Execute(uninstallCtx)
+
+	if !dryRun && report.Status == uninstall.UninstallStatusCompleted {
+		if err := removeRepositoryPreference(ctx, pm, dist); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to remove the NVIDIA repository preference: %v\n", err)
+		}
+	}
+
+	code := uninstallExitCode(report, ctx.Err())
+	writeUninstallResult(os.Stderr, out, report, ctx.Err(), dryRun)
+	return code
+}
+
+// buildUninstallWorkflow creates the uninstall workflow.
+//
+// Packages are removed first so that a running display server holding the
+// kernel modules does not prevent the removal; unloading the modules comes
+// last and a failure there only means a reboot is needed to finish.
+// Configuration files are only cleaned up when purging.
+func buildUninstallWorkflow(executor exec.Executor, packages []string, purge bool) uninstall.UninstallWorkflow {
+	kernelDetector := kernel.NewDetector(kernel.WithExecutor(executor))
+
+	workflow := uninstall.NewUninstallWorkflow("nvidia-uninstallation")
+	workflow.AddStep(steps.NewPackageRemovalStep(
+		steps.WithPackagesToRemove(packages),
+		steps.WithPurge(purge),
+	))
+	if purge {
+		workflow.AddStep(steps.NewConfigCleanupStep())
+	}
+	workflow.AddStep(steps.NewNouveauRestoreStep(
+		steps.WithNouveauKernelDetector(kernelDetector),
+		// nouveau cannot bind while the NVIDIA modules are loaded; it is
+		// picked up on the next boot instead.
+		steps.WithLoadNouveauModule(false),
+	))
+	workflow.AddStep(steps.NewModuleUnloadStep(
+		steps.WithUnloadKernelDetector(kernelDetector),
+	))
+
+	return workflow
+}
+
+// removeRepositoryPreference removes the priority the installation gave the
+// NVIDIA repository, so that the packages of the distribution are no longer
+// held back once the driver is gone. The repository itself is kept.
+func removeRepositoryPreference(ctx context.Context, pm pkg.Manager, dist *distro.Distribution) error {
+	prefs, ok := pm.(pkg.PreferenceManager)
+	if !ok {
+		return nil
+	}
+
+	repo, err := nvidia.GetRepository(dist)
+	if err != nil || repo == nil {
+		return err
+	}
+	return prefs.RemoveRepositoryPreference(ctx, repo.Name)
+}
+
+// writeUninstallPlan lists the packages that will be removed, grouped by kind.
+func writeUninstallPlan(w io.Writer, discovered *uninstall.DiscoveredPackages, purge, dryRun bool) {
+	title := "The following NVIDIA packages will be removed"
+	if dryRun {
+		title = "[dry-run] " + title
+	}
+	if purge {
+		title += " together with their configuration files"
+	}
+	fmt.Fprintf(w, "%s:\n", title)
+
+	groups := []struct {
+		name     string
+		packages []string
+	}{
+		{"Driver", discovered.DriverPackages},
+		{"Kernel modules", discovered.KernelModulePackages},
+		{"CUDA", discovered.CUDAPackages},
+		{"Libraries", discovered.LibraryPackages},
+		{"Utilities", discovered.UtilityPackages},
+		{"Configuration", discovered.ConfigPackages},
+	}
+
+	listed := make(map[string]bool, len(discovered.AllPackages))
+	for _, group := range groups {
+		if len(group.packages) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "  %s:\n", group.name)
+		for _, name := range group.packages {
+			fmt.Fprintf(w, "    - %s\n", name)
+			listed[name] = true
+		}
+	}
+
+	var other []string
+	for _, name := range discovered.AllPackages {
+		if !listed[name] {
+			other = append(other, name)
+		}
+	}
+	if len(other) > 0 {
+		fmt.Fprintln(w, "  Other:")
+		for _, name := range other {
+			fmt.Fprintf(w, "    - %s\n", name)
+		}
+	}
+
+	fmt.Fprintf(w, "Total: %d packages", len(discovered.AllPackages))
+	if discovered.DriverVersion != "" {
+		fmt.Fprintf(w, " (driver %s)", discovered.DriverVersion)
+	}
+	fmt.Fprintln(w)
+}
+
+// confirm asks a yes/no question and reports whether the answer was yes.
+// Anything other than "y" or "yes", including end of input, means no.
+func confirm(r io.Reader, w io.Writer, prompt string) bool {
+	fmt.Fprintf(w, "%s [y/N]: ", prompt)
+
+	answer, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Fprintln(w)
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// uninstallExitCode maps an uninstall execution report to a process exit code.
+// ctxErr is the error of the command context, used to tell a user
+// interrupt from a timeout.
+func uninstallExitCode(report uninstall.UninstallExecutionReport, ctxErr error) int {
+	switch report.Status {
+	case uninstall.UninstallStatusCompleted:
+		return constants.ExitSuccess.Int()
+	case uninstall.UninstallStatusPartial:
+		return constants.ExitInstallation.Int()
+	case uninstall.UninstallStatusCancelled:
+		if errors.Is(ctxErr, context.DeadlineExceeded) {
+			return constants.ExitError.Int()
+		}
+		return constants.ExitUserAbort.Int()
+	case uninstall.UninstallStatusFailed:
+		if report.FailedStep != "" {
+			return constants.ExitInstallation.Int()
+		}
+		return constants.ExitError.Int()
+	default:
+		return constants.ExitError.Int()
+	}
+}
+
+// writeUninstallResult writes the final report of an uninstallation.
+// Failures go to errOut so they remain visible in quiet mode.
+func writeUninstallResult(errOut, out io.Writer, report uninstall.UninstallExecutionReport, ctxErr error, dryRun bool) {
+	switch report.Status {
+	case uninstall.UninstallStatusCompleted, uninstall.UninstallStatusPartial:
+		fmt.Fprintf(out, "Uninstall %s in %s (%d steps completed, %d skipped)\n",
+			report.Status, report.TotalDuration.Round(time.Millisecond), report.StepsCompleted, report.StepsSkipped)
+	case uninstall.UninstallStatusCancelled:
+		if errors.Is(ctxErr, context.DeadlineExceeded) {
+			fmt.Fprintln(errOut, "Error: uninstall timed out")
+		} else {
+			fmt.Fprintln(errOut, "Uninstall cancelled")
+		}
+	default:
+		if report.FailedStep != "" {
+			fmt.Fprintf(errOut, "Error: uninstall failed at step %s: %v\n", report.FailedStep, report.Error)
+		} else {
+			fmt.Fprintf(errOut, "Error: uninstall failed: %v\n", report.Error)
+		}
+	}
+
+	if len(report.RemovedPackages) > 0 {
+		fmt.Fprintf(out, "Removed packages (%d): %s\n", len(report.RemovedPackages), strings.Join(report.RemovedPackages, ", "))
+	}
+	if len(report.CleanedConfigs) > 0 {
+		fmt.Fprintf(out, "Removed configuration files (%d): %s\n", len(report.CleanedConfigs), strings.Join(report.CleanedConfigs, ", "))
+	}
+	if report.NouveauRestored {
+		fmt.Fprintln(out, "The nouveau driver was re-enabled")
+	}
+
+	if !dryRun && (report.NeedsReboot || len(report.RemovedPackages) > 0) {
+		fmt.Fprintln(out, "Reboot the system to complete the removal.")
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/distro"
+	"github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/install"
+	igortesting "github.com/tungetti/igor/internal/testing"
+	"github.com/tungetti/igor/internal/uninstall"
+)
+
+func uninstallStepNames(w uninstall.UninstallWorkflow) []string {
+	var names []string
+	for _, s := range w.Steps() {
+		names = append(names, s.Name())
+	}
+	return names
+}
+
+func TestBuildUninstallWorkflow(t *testing.T) {
+	executor := exec.NewMockExecutor()
+
+	w := buildUninstallWorkflow(executor, []string{"nvidia-driver-550"}, false)
+	assert.Equal(t, []string{"removal", "nouveau_restore", "module_unload"}, uninstallStepNames(w))
+
+	w = buildUninstallWorkflow(executor, []string{"nvidia-driver-550"}, true)
+	assert.Equal(t, []string{"removal", "config_cleanup", "nouveau_restore", "module_unload"}, uninstallStepNames(w))
+}
+
+func TestBuildUninstallWorkflow_DryRun(t *testing.T) {
+	executor := exec.NewMockExecutor()
+	pm := igortesting.NewMockPackageManager()
+
+	var progress []string
+	o := uninstall.NewUninstallOrchestrator(
+		uninstall.WithUninstallWorkflow(buildUninstallWorkflow(executor, []string{"nvidia-driver-550"}, false)),
+		uninstall.WithUninstallOrchestratorDryRun(true),
+		uninstall.WithUninstallOrchestratorProgress(func(p install.StepProgress) {
+			progress = append(progress, formatInstallProgress(p))
+		}),
+	)
+
+	report := o.Execute(uninstall.NewUninstallContext(
+		uninstall.WithUninstallPackageManager(pm),
+		uninstall.WithUninstallExecutor(executor),
+		uninstall.WithUninstallDistroInfo(newTestListDistro()),
+	))
+
+	assert.Equal(t, uninstall.UninstallStatusCompleted, report.Status)
+	assert.Empty(t, pm.RemoveCalls())
+	require.NotEmpty(t, progress)
+	assert.Equal(t, "[1/3] removal: Starting: Remove NVIDIA packages", progress[0])
+}
+
+func TestWriteUninstallPlan(t *testing.T) {
+	discovered := &uninstall.DiscoveredPackages{
+		DriverPackages:       []string{"nvidia-driver-550"},
+		DriverVersion:        "550",
+		KernelModulePackages: []string{"nvidia-dkms-550"},
+		AllPackages:          []string{"nvidia-driver-550", "nvidia-dkms-550", "libnvidia-extra"},
+	}
+
+	var buf bytes.Buffer
+	writeUninstallPlan(&buf, discovered, true, true)
+
+	out := buf.String()
+	assert.Contains(t, out, "[dry-run] The following NVIDIA packages will be removed together with their configuration files:")
+	assert.Contains(t, out, "  Driver:\n    - nvidia-driver-550\n")
+	assert.Contains(t, out, "  Kernel modules:\n    - nvidia-dkms-550\n")
+	assert.Contains(t, out, "  Other:\n    - libnvidia-extra\n")
+	assert.Contains(t, out, "Total: 3 packages (driver 550)")
+	assert.NotContains(t, out, "CUDA")
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"y\n", true},
+		{"YES\n", true},
+		{"yes", true},
+		{"n\n", false},
+		{"\n", false},
+		{"", false},
+		{"maybe\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.TrimSpace(tt.input), func(t *testing.T) {
+			var out bytes.Buffer
+			assert.Equal(t, tt.expected, confirm(strings.NewReader(tt.input), &out, "Remove 3 packages?"))
+			assert.Contains(t, out.String(), "Remove 3 packages? [y/N]: ")
+		})
+	}
+}
+
+func TestUninstallExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		report   uninstall.UninstallExecutionReport
+		ctxErr   error
+		expected constants.ExitCode
+	}{
+		{"completed", uninstall.UninstallExecutionReport{Status: uninstall.UninstallStatusCompleted}, nil, constants.ExitSuccess},
+		{"partial", uninstall.UninstallExecutionReport{Status: uninstall.UninstallStatusPartial}, nil, constants.ExitInstallation},
+		{"step failed", uninstall.UninstallExecutionReport{Status: uninstall.UninstallStatusFailed, FailedStep: "removal"}, nil, constants.ExitInstallation},
+		{"failed before steps", uninstall.UninstallExecutionReport{Status: uninstall.UninstallStatusFailed}, nil, constants.ExitError},
+		{"interrupted", uninstall.UninstallExecutionReport{Status: uninstall.UninstallStatusCancelled}, context.Canceled, constants.ExitUserAbort},
+		{"timed out", uninstall.UninstallExecutionReport{Status: uninstall.UninstallStatusCancelled}, context.DeadlineExceeded, constants.ExitError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected.Int(), uninstallExitCode(tt.report, tt.ctxErr))
+		})
+	}
+}
+
+func TestWriteUninstallResult(t *testing.T) {
+	t.Run("completed", func(t *testing.T) {
+		var out, errOut bytes.Buffer
+		report := uninstall.UninstallExecutionReport{
+			Status:          uninstall.UninstallStatusCompleted,
+			StepsCompleted:  3,
+			RemovedPackages: []string{"nvidia-driver-550", "nvidia-dkms-550"},
+			CleanedConfigs:  []string{"/etc/modprobe.d/nvidia.conf"},
+		}
+
+		writeUninstallResult(&errOut, &out, report, nil, false)
+
+		assert.Contains(t, out.String(), "Uninstall completed")
+		assert.Contains(t, out.String(), "Removed packages (2): nvidia-driver-550, nvidia-dkms-550")
+		assert.Contains(t, out.String(), "Removed configuration files (1): /etc/modprobe.d/nvidia.conf")
+		assert.Contains(t, out.String(), "Reboot")
+		assert.Empty(t, errOut.String())
+	})
+
+	t.Run("failed step", func(t *testing.T) {
+		var out, errOut bytes.Buffer
+		report := uninstall.UninstallExecutionReport{
+			Status:          uninstall.UninstallStatusFailed,
+			FailedStep:      "module_unload",
+			Error:           errors.New("module in use"),
+			RemovedPackages: []string{"nvidia-driver-550"},
+		}
+
+		writeUninstallResult(&errOut, &out, report, nil, false)
+
+		assert.Contains(t, errOut.String(), "uninstall failed at step module_unload: module in use")
+		assert.Contains(t, out.String(), "Reboot the system to complete the removal.")
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		var out, errOut bytes.Buffer
+		writeUninstallResult(&errOut, &out, uninstall.UninstallExecutionReport{Status: uninstall.UninstallStatusCompleted}, nil, true)
+
+		assert.NotContains(t, out.String(), "Reboot")
+	})
+}
+
+func TestRemoveRepositoryPreference(t *testing.T) {
+	pm, mockExec := newTestHoldManager()
+	mockExec.SetDefaultResponse(exec.SuccessResult(""))
+
+	dist := &distro.Distribution{ID: "debian", VersionID: "12", Family: constants.FamilyDebian}
+	err := removeRepositoryPreference(context.Background(), pm, dist)
+	require.NoError(t, err)
+	assert.True(t, mockExec.WasCalledWith("rm", "-f", "/etc/apt/preferences.d/igor-nvidia-cuda"))
+}
+
+func TestRemoveRepositoryPreference_NoRepository(t *testing.T) {
+	pm, mockExec := newTestHoldManager()
+
+	// Arch Linux has no NVIDIA repository
+	dist := &distro.Distribution{ID: "arch", Family: constants.FamilyArch}
+	err := removeRepositoryPreference(context.Background(), pm, dist)
+	require.NoError(t, err)
+	assert.Equal(t, 0, mockExec.CallCount())
+}
+
+func TestRemoveRepositoryPreference_Unsupported(t *testing.T) {
+	dist := &distro.Distribution{ID: "debian", VersionID: "12", Family: constants.FamilyDebian}
+	err := removeRepositoryPreference(context.Background(), newTestListManager(), dist)
+	assert.NoError(t, err)
+}
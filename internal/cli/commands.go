@@ -19,6 +19,30 @@ const (
 	// CommandList represents the list command for showing available/installed drivers.
 	CommandList
 
+	// CommandDoctor represents the doctor command for diagnosing an existing installation.
+	CommandDoctor
+
+	// CommandPlan represents the plan command for previewing an installation.
+	CommandPlan
+
+	// CommandRollback represents the rollback command for undoing a previous installation run.
+	CommandRollback
+
+	// CommandHold represents the hold command for pinning the installed driver packages.
+	CommandHold
+
+	// CommandUnhold represents the unhold command for releasing package holds.
+	CommandUnhold
+
+	// CommandHistory represents the history command for showing package manager transactions.
+	CommandHistory
+
+	// CommandBundle represents the bundle command for downloading offline package bundles.
+	CommandBundle
+
+	// CommandPackages represents the packages command for showing the NVIDIA package catalog.
+	CommandPackages
+
 	// CommandVersion represents the version command for displaying build information.
 	CommandVersion
 
@@ -37,6 +61,22 @@ func (c Command) String() string {
 		return "detect"
 	case CommandList:
 		return "list"
+	case CommandDoctor:
+		return "doctor"
+	case CommandPlan:
+		return "plan"
+	case CommandRollback:
+		return "rollback"
+	case CommandHold:
+		return "hold"
+	case CommandUnhold:
+		return "unhold"
+	case CommandHistory:
+		return "history"
+	case CommandBundle:
+		return "bundle"
+	case CommandPackages:
+		return "packages"
 	case CommandVersion:
 		return "version"
 	case CommandHelp:
@@ -87,12 +127,30 @@ Flags:
   --cuda VERSION      Install CUDA toolkit with specified version
   --with-cuda         Also install CUDA toolkit (latest compatible version)
   --force             Force installation even if driver is already installed
+                      or essential packages would be removed
   --skip-reboot       Don't prompt for reboot after installation
+  --hold              Hold the driver packages after installation
+  --from-bundle DIR   Install from a bundle created with igor bundle
+  --dry-run, -n       Show what would be done without making changes
+
+With --from-bundle, the bundle directory is added as a temporary local
+repository and the packages are installed from it only, without network
+access. The driver version and components are those of the bundle.
+
+The package transaction is simulated before installing. If it would
+remove packages essential to the system, such as the kernel or the
+C library, nothing is installed unless --force is given.
+
+Progress is printed one line per step. The exit code is 0 on success,
+4 if a step failed (the failing step is named on stderr), and 5 if the
+installation was interrupted.
 
 Examples:
   igor install                     Install recommended driver
   igor install --driver 535.104    Install specific driver version
-  igor install --with-cuda         Install driver and CUDA toolkit`,
+  igor install --with-cuda         Install driver and CUDA toolkit
+  igor install --from-bundle DIR   Install offline from a bundle
+  igor install --dry-run           Show the steps without changing the system`,
 		},
 		{
 			Name:        "uninstall",
@@ -102,15 +160,19 @@ Examples:
 			LongDescription: `Remove NVIDIA drivers from your system.
 
 This command will remove the installed NVIDIA drivers and related packages.
+The packages to be removed are listed and confirmed before anything changes.
 Use --purge to also remove configuration files.
 
 Flags:
   --purge         Remove configuration files too
   --keep-config   Keep configuration files (default behavior)
+  --yes, -y       Do not ask for confirmation
+  --dry-run, -n   Show what would be removed without making changes
 
 Examples:
-  igor uninstall          Remove drivers, keep configuration
-  igor uninstall --purge  Remove drivers and all configuration`,
+  igor uninstall              Remove drivers, keep configuration
+  igor uninstall --purge      Remove drivers and all configuration
+  igor uninstall --yes        Remove drivers without prompting`,
 		},
 		{
 			Name:        "detect",
@@ -150,6 +212,227 @@ Examples:
   igor list              List all available drivers
   igor list --installed  Show currently installed driver
   igor list --json       Output as JSON for scripting`,
+		},
+		{
+			Name:        "doctor",
+			Description: "Diagnose problems with the installed NVIDIA driver",
+			Usage:       "igor doctor [flags]",
+			LongDescription: `Diagnose the installed NVIDIA driver.
+
+This command checks the driver (nvidia-smi, kernel module, GPU visibility,
+X.org configuration), the Nouveau status, the DKMS module of every installed
+kernel, the enabled NVIDIA package sources and the system requirements.
+Problems are listed most severe first, each with instructions on how to fix
+it.
+
+With --fix, the installation steps that repair the fixable problems are run.
+Repositories competing with the NVIDIA package source of the distribution
+are disabled.
+
+Flags:
+  --fix           Run the installation steps that fix the problems found
+  --dry-run, -n   With --fix, show the steps without running them
+
+The exit code is 0 when no errors were found (or all were fixed), 3 when
+errors remain, and 4 if a fix failed.
+
+Examples:
+  igor doctor              Show a health report
+  sudo igor doctor --fix   Repair the fixable problems`,
+		},
+		{
+			Name:        "plan",
+			Description: "Show the steps and commands an installation would run",
+			Usage:       "igor plan [flags]",
+			LongDescription: `Show exactly what "igor install" would do, without changing the system.
+
+The installation workflow is run against a recording executor: read-only
+queries inspect the system, every other command is recorded instead of
+being run. The plan lists each step with the packages it would install,
+the files it would write and the commands it would run, marking the
+commands run with root privileges. The package changes of the simulated
+transaction are listed first, marking essential packages that would be
+removed.
+
+Steps that depend on earlier changes, such as building the DKMS module
+of packages that are not installed yet, may show as skipped.
+
+Flags:
+  --driver VERSION    Plan a specific driver version
+  --cuda VERSION      Plan CUDA toolkit with specified version
+  --with-cuda         Also plan CUDA toolkit (latest compatible version)
+  --force             Plan even if the driver is already installed or
+                      essential packages would be removed
+  --json              Output the plan in JSON format
+  --script            Output the plan as a shell script for review
+
+Examples:
+  igor plan                        Show the plan for the recommended driver
+  igor plan --driver 550 --json    Output the plan as JSON
+  igor plan --script > install.sh  Save the commands as a reviewable script`,
+		},
+		{
+			Name:        "rollback",
+			Description: "Undo a previous installation run",
+			Usage:       "igor rollback [flags] [run-id]",
+			LongDescription: `Undo a previous installation run.
+
+Every igor install run is recorded in a journal in the state directory,
+with the steps it completed and what they changed (installed packages,
+configuration backups, blacklist files). This command rolls back the
+completed steps of a run in reverse order, even after the terminal was
+closed or the system rebooted.
+
+Without a run ID, the most recent run that was not rolled back is used.
+Runs that failed and were rolled back automatically need no rollback.
+
+Flags:
+  --list          List the recorded runs
+  --yes, -y       Do not ask for confirmation
+  --dry-run, -n   Show the commands without running them
+
+The exit code is 0 on success, 3 if the run cannot be rolled back, and
+4 if a step failed to roll back.
+
+Examples:
+  igor rollback --list                       List the recorded runs
+  sudo igor rollback                         Undo the last run
+  sudo igor rollback 20261016-103834-4f2a9c  Undo a specific run
+  igor rollback --dry-run                    Show what would be undone`,
+		},
+		{
+			Name:        "hold",
+			Description: "Hold driver packages at their installed version",
+			Usage:       "igor hold [flags] [package...]",
+			LongDescription: `Hold packages at their installed version.
+
+Held packages are not upgraded by routine system upgrades, so the
+installed driver stays on its branch until the hold is released with
+igor unhold. Without package names, the installed NVIDIA packages are
+held.
+
+Holds use the mechanism of the package manager: apt-mark hold on apt,
+the versionlock plugin (or an exclude= entry in the configuration when
+the plugin is missing) on dnf and yum, IgnorePkg in pacman.conf on
+pacman and locks on zypper.
+
+Set hold_driver: true in the configuration, or pass --hold to igor
+install, to hold the driver packages after every installation.
+
+Flags:
+  --list          List the held packages
+  --dry-run, -n   Show the commands without running them
+
+Examples:
+  sudo igor hold                    Hold the installed NVIDIA packages
+  sudo igor hold nvidia-driver-550  Hold a specific package
+  igor hold --list                  List the held packages`,
+		},
+		{
+			Name:        "unhold",
+			Description: "Release package holds",
+			Usage:       "igor unhold [flags] [package...]",
+			LongDescription: `Release holds set with igor hold.
+
+Without package names, the holds on the installed NVIDIA packages are
+released. Packages that are not held are ignored.
+
+Flags:
+  --list          List the held packages
+  --dry-run, -n   Show the commands without running them
+
+Examples:
+  sudo igor unhold                    Release the NVIDIA package holds
+  sudo igor unhold nvidia-driver-550  Release the hold on one package`,
+		},
+		{
+			Name:        "history",
+			Description: "Show the package manager transactions of NVIDIA packages",
+			Usage:       "igor history [flags]",
+			LongDescription: `Show the package manager transactions that changed NVIDIA packages.
+
+Transactions are read from the history of the package manager, so they
+include upgrades made outside of igor, such as routine system upgrades.
+This shows which transaction changed the driver and when. The history is
+read from dnf history and yum history, /var/log/apt/history.log,
+/var/log/pacman.log and /var/log/zypp/history.
+
+With --undo, a transaction is undone with the package manager. Only dnf
+and yum can undo transactions.
+
+Flags:
+  --all           Show all transactions, not only the NVIDIA ones
+  --limit N       Show at most N transactions (default 20, 0 for all)
+  --undo ID       Undo the transaction with the given ID
+  --yes, -y       Do not ask for confirmation
+  --dry-run, -n   Show the commands without running them
+
+Examples:
+  igor history                 Show the recent NVIDIA package transactions
+  igor history --all           Show all recent transactions
+  sudo igor history --undo 42  Undo transaction 42 (dnf and yum)`,
+		},
+		{
+			Name:        "bundle",
+			Description: "Download the driver packages for an offline installation",
+			Usage:       "igor bundle [flags] DIR",
+			LongDescription: `Download the driver packages and their dependencies into a directory.
+
+The packages igor install would install are resolved for this system,
+then downloaded into DIR with their dependencies, using apt-get download,
+dnf download --resolve, pacman -Syw or zypper --download-only. The
+directory is indexed as a local repository and a manifest records what
+the bundle was built for.
+
+Copy the directory to a machine running the same distribution release,
+without network access, and install from it with
+igor install --from-bundle DIR.
+
+The dependencies are resolved as for a system with nothing installed, so
+the bundle also holds the packages already installed on this system and
+can be installed on a minimal system of the same release.
+
+The packages are downloaded from the repositories configured on the
+system creating the bundle, so the NVIDIA repository must be configured
+there, for example by a previous igor install.
+
+Flags:
+  --driver VERSION    Bundle a specific driver version
+  --cuda VERSION      Bundle CUDA toolkit with specified version
+  --with-cuda         Also bundle CUDA toolkit (latest compatible version)
+  --dry-run, -n       Show the commands without running them
+
+Examples:
+  sudo igor bundle ./nvidia                         Bundle the recommended driver
+  sudo igor bundle --driver 550 --with-cuda ./b550  Bundle driver 550 and CUDA
+  sudo igor install --from-bundle ./b550            Install the bundle offline`,
+		},
+		{
+			Name:        "packages",
+			Description: "Show the NVIDIA package names used for this system",
+			Usage:       "igor packages show [flags] [DISTRO]",
+			LongDescription: `Show the package names igor installs for each NVIDIA component.
+
+The package names come from a catalog embedded in igor, patched by the
+override files in /etc/igor/packages.d and then in the packages.d
+directory of the user configuration (~/.config/igor/packages.d). The
+.yaml, .yml and .json files of each directory are applied in lexical
+order; fields left out of a file keep their value.
+
+An override file that does not match the catalog schema is an error for
+every command, so a typo never installs the wrong packages.
+
+DISTRO is a distribution ID, such as ubuntu, or a family: debian, rhel,
+arch or suse. Without it, the package set of this system is shown.
+
+Flags:
+  --all     Show every package set of the catalog
+  --json    Output in JSON format
+
+Examples:
+  igor packages show              Show the packages for this system
+  igor packages show fedora       Show the packages for Fedora
+  igor packages show --all --json Dump the catalog in use`,
 		},
 		{
 			Name:        "version",
@@ -221,6 +504,22 @@ func commandFromName(name string) Command {
 		return CommandDetect
 	case "list":
 		return CommandList
+	case "doctor":
+		return CommandDoctor
+	case "plan":
+		return CommandPlan
+	case "rollback":
+		return CommandRollback
+	case "hold":
+		return CommandHold
+	case "unhold":
+		return CommandUnhold
+	case "history":
+		return CommandHistory
+	case "bundle":
+		return CommandBundle
+	case "packages":
+		return CommandPackages
 	case "version":
 		return CommandVersion
 	case "help":
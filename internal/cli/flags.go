@@ -47,6 +47,12 @@ type InstallFlags struct {
 
 	// SkipReboot skips the reboot prompt after installation.
 	SkipReboot bool
+
+	// Hold holds the installed driver packages after installation.
+	Hold bool
+
+	// FromBundle is the directory of a bundle to install from.
+	FromBundle string
 }
 
 // UninstallFlags holds uninstall command specific flags.
@@ -57,6 +63,9 @@ type UninstallFlags struct {
 
 	// KeepConfig preserves configuration files during uninstallation.
 	KeepConfig bool
+
+	// Yes skips the confirmation prompt for unattended use.
+	Yes bool
 }
 
 // DetectFlags holds detect command specific flags.
@@ -82,6 +91,109 @@ type ListFlags struct {
 	JSON bool
 }
 
+// DoctorFlags holds doctor command specific flags.
+// These flags control whether detected problems are repaired.
+type DoctorFlags struct {
+	// Fix runs the installation steps that repair fixable problems.
+	Fix bool
+}
+
+// PlanFlags holds plan command specific flags.
+// The installation flags select what is planned; the others select the
+// output format.
+type PlanFlags struct {
+	// DriverVersion specifies the exact driver version to plan.
+	DriverVersion string
+
+	// CUDAVersion specifies the CUDA toolkit version to plan.
+	CUDAVersion string
+
+	// InstallCUDA indicates whether to also plan the CUDA toolkit.
+	InstallCUDA bool
+
+	// Force plans a reinstallation even if the driver is already installed.
+	Force bool
+
+	// JSON outputs the plan in JSON format.
+	JSON bool
+
+	// Script outputs the plan as a shell script.
+	Script bool
+}
+
+// InstallFlags returns the install command flags the plan is made for.
+func (f PlanFlags) InstallFlags() InstallFlags {
+	return InstallFlags{
+		DriverVersion: f.DriverVersion,
+		CUDAVersion:   f.CUDAVersion,
+		InstallCUDA:   f.InstallCUDA,
+		Force:         f.Force,
+	}
+}
+
+// RollbackFlags holds rollback command specific flags.
+type RollbackFlags struct {
+	// List lists the recorded runs instead of rolling one back.
+	List bool
+
+	// Yes skips the confirmation prompt for unattended use.
+	Yes bool
+}
+
+// HoldFlags holds hold and unhold command specific flags.
+type HoldFlags struct {
+	// List lists the held packages instead of changing holds.
+	List bool
+}
+
+// BundleFlags holds bundle command specific flags.
+type BundleFlags struct {
+	// DriverVersion specifies the exact driver version to bundle.
+	DriverVersion string
+
+	// CUDAVersion specifies the CUDA toolkit version to bundle.
+	CUDAVersion string
+
+	// InstallCUDA indicates whether to also bundle the CUDA toolkit.
+	InstallCUDA bool
+}
+
+// InstallFlags returns the install command flags the bundle is made for.
+func (f BundleFlags) InstallFlags() InstallFlags {
+	return InstallFlags{
+		DriverVersion: f.DriverVersion,
+		CUDAVersion:   f.CUDAVersion,
+		InstallCUDA:   f.InstallCUDA,
+	}
+}
+
+// PackagesFlags holds packages command specific flags.
+type PackagesFlags struct {
+	// All shows every package set of the catalog.
+	All bool
+
+	// JSON outputs the package sets in JSON format.
+	JSON bool
+}
+
+// DefaultHistoryLimit is the number of transactions shown by igor history.
+const DefaultHistoryLimit = 20
+
+// HistoryFlags holds history command specific flags.
+type HistoryFlags struct {
+	// All shows all transactions instead of only those changing NVIDIA packages.
+	All bool
+
+	// Limit is the maximum number of transactions shown (0 = all).
+	Limit int
+
+	// Undo is the ID of the transaction to undo.
+	Undo string
+
+	// Yes skips the confirmation prompt for unattended use.
+	Yes bool
+}
+
 // Validate checks GlobalFlags for conflicting options.
 // It returns an error if incompatible flags are set together.
 func (f *GlobalFlags) Validate() error {
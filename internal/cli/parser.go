@@ -27,6 +27,27 @@ type ParseResult struct {
 	// ListFlags contains list command flag values.
 	ListFlags ListFlags
 
+	// DoctorFlags contains doctor command flag values.
+	DoctorFlags DoctorFlags
+
+	// PlanFlags contains plan command flag values.
+	PlanFlags PlanFlags
+
+	// RollbackFlags contains rollback command flag values.
+	RollbackFlags RollbackFlags
+
+	// HoldFlags contains hold and unhold command flag values.
+	HoldFlags HoldFlags
+
+	// HistoryFlags contains history command flag values.
+	HistoryFlags HistoryFlags
+
+	// BundleFlags contains bundle command flag values.
+	BundleFlags BundleFlags
+
+	// PackagesFlags contains packages command flag values.
+	PackagesFlags PackagesFlags
+
 	// Args contains any remaining positional arguments.
 	Args []string
 
@@ -164,6 +185,20 @@ func (p *Parser) parseCommandFlags(result *ParseResult, args []string) error {
 		return p.parseDetectFlags(result, args)
 	case CommandList:
 		return p.parseListFlags(result, args)
+	case CommandDoctor:
+		return p.parseDoctorFlags(result, args)
+	case CommandPlan:
+		return p.parsePlanFlags(result, args)
+	case CommandRollback:
+		return p.parseRollbackFlags(result, args)
+	case CommandHold, CommandUnhold:
+		return p.parseHoldFlags(result, args)
+	case CommandHistory:
+		return p.parseHistoryFlags(result, args)
+	case CommandBundle:
+		return p.parseBundleFlags(result, args)
+	case CommandPackages:
+		return p.parsePackagesFlags(result, args)
 	case CommandHelp:
 		return p.parseHelpFlags(result, args)
 	case CommandVersion:
@@ -184,11 +219,25 @@ func (p *Parser) parseInstallFlags(result *ParseResult, args []string) error {
 	fs.BoolVar(&result.InstallFlags.Force, "force", false, "Force installation even if already installed")
 	fs.BoolVar(&result.InstallFlags.Force, "f", false, "Force installation (shorthand)")
 	fs.BoolVar(&result.InstallFlags.SkipReboot, "skip-reboot", false, "Don't prompt for reboot")
+	fs.BoolVar(&result.InstallFlags.Hold, "hold", false, "Hold the driver packages after installation")
+	fs.StringVar(&result.InstallFlags.FromBundle, "from-bundle", "", "Install from a bundle directory")
+	// Accept --dry-run after the command as well, as in "igor install --dry-run".
+	fs.BoolVar(&result.GlobalFlags.DryRun, "dry-run", result.GlobalFlags.DryRun, "Show what would be done without making changes")
+	fs.BoolVar(&result.GlobalFlags.DryRun, "n", result.GlobalFlags.DryRun, "Show what would be done (shorthand)")
 
 	if err := fs.Parse(args); err != nil {
 		return fmt.Errorf("invalid install flags: %w", err)
 	}
 	result.Args = fs.Args()
+
+	// The bundle fixes the driver version and the components
+	flags := result.InstallFlags
+	if flags.FromBundle != "" && (flags.DriverVersion != "" || flags.CUDAVersion != "" || flags.InstallCUDA) {
+		return &FlagError{
+			Flag:    "from-bundle",
+			Message: "cannot use --from-bundle with --driver, --cuda or --with-cuda",
+		}
+	}
 	return nil
 }
 
@@ -198,6 +247,10 @@ func (p *Parser) parseUninstallFlags(result *ParseResult, args []string) error {
 
 	fs.BoolVar(&result.UninstallFlags.Purge, "purge", false, "Remove configuration files too")
 	fs.BoolVar(&result.UninstallFlags.KeepConfig, "keep-config", false, "Keep configuration files")
+	fs.BoolVar(&result.UninstallFlags.Yes, "yes", false, "Do not ask for confirmation")
+	fs.BoolVar(&result.UninstallFlags.Yes, "y", false, "Do not ask for confirmation (shorthand)")
+	fs.BoolVar(&result.GlobalFlags.DryRun, "dry-run", result.GlobalFlags.DryRun, "Show what would be done without making changes")
+	fs.BoolVar(&result.GlobalFlags.DryRun, "n", result.GlobalFlags.DryRun, "Show what would be done (shorthand)")
 
 	if err := fs.Parse(args); err != nil {
 		return fmt.Errorf("invalid uninstall flags: %w", err)
@@ -236,6 +289,238 @@ func (p *Parser) parseListFlags(result *ParseResult, args []string) error {
 	return nil
 }
 
+func (p *Parser) parseDoctorFlags(result *ParseResult, args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	fs.BoolVar(&result.DoctorFlags.Fix, "fix", false, "Fix the problems found")
+	fs.BoolVar(&result.GlobalFlags.DryRun, "dry-run", result.GlobalFlags.DryRun, "Show what would be done without making changes")
+	fs.BoolVar(&result.GlobalFlags.DryRun, "n", result.GlobalFlags.DryRun, "Show what would be done (shorthand)")
+
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("invalid doctor flags: %w", err)
+	}
+	result.Args = fs.Args()
+	return nil
+}
+
+func (p *Parser) parsePlanFlags(result *ParseResult, args []string) error {
+	fs := flag.NewFlagSet("plan", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	fs.StringVar(&result.PlanFlags.DriverVersion, "driver", "", "Driver version to plan")
+	fs.StringVar(&result.PlanFlags.CUDAVersion, "cuda", "", "CUDA version to plan")
+	fs.BoolVar(&result.PlanFlags.InstallCUDA, "with-cuda", false, "Also plan CUDA toolkit")
+	fs.BoolVar(&result.PlanFlags.Force, "force", false, "Plan even if already installed")
+	fs.BoolVar(&result.PlanFlags.Force, "f", false, "Plan even if already installed (shorthand)")
+	fs.BoolVar(&result.PlanFlags.JSON, "json", false, "Output in JSON format")
+	fs.BoolVar(&result.PlanFlags.Script, "script", false, "Output as a shell script")
+
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("invalid plan flags: %w", err)
+	}
+	if result.PlanFlags.JSON && result.PlanFlags.Script {
+		return &FlagError{
+			Flag:    "json/script",
+			Message: "cannot use --json and --script together",
+		}
+	}
+	result.Args = fs.Args()
+	return nil
+}
+
+func (p *Parser) parseRollbackFlags(result *ParseResult, args []string) error {
+	fs := flag.NewFlagSet("rollback", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	fs.BoolVar(&result.RollbackFlags.List, "list", false, "List the recorded runs")
+	fs.BoolVar(&result.RollbackFlags.Yes, "yes", false, "Do not ask for confirmation")
+	fs.BoolVar(&result.RollbackFlags.Yes, "y", false, "Do not ask for confirmation (shorthand)")
+	fs.BoolVar(&result.GlobalFlags.DryRun, "dry-run", result.GlobalFlags.DryRun, "Show what would be done without making changes")
+	fs.BoolVar(&result.GlobalFlags.DryRun, "n", result.GlobalFlags.DryRun, "Show what would be done (shorthand)")
+
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("invalid rollback flags: %w", err)
+	}
+
+	// Accept flags after the run ID too, as in "igor rollback <run-id> --yes".
+	if fs.NArg() > 0 {
+		runID := fs.Arg(0)
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return fmt.Errorf("invalid rollback flags: %w", err)
+		}
+		result.Args = append([]string{runID}, fs.Args()...)
+	}
+
+	if len(result.Args) > 1 {
+		return &FlagError{
+			Flag:    "run-id",
+			Message: "rollback takes at most one run ID",
+		}
+	}
+	if result.RollbackFlags.List && len(result.Args) > 0 {
+		return &FlagError{
+			Flag:    "list",
+			Message: "cannot use --list with a run ID",
+		}
+	}
+	return nil
+}
+
+func (p *Parser) parseHoldFlags(result *ParseResult, args []string) error {
+	name := result.Command.String()
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	fs.BoolVar(&result.HoldFlags.List, "list", false, "List the held packages")
+	fs.BoolVar(&result.GlobalFlags.DryRun, "dry-run", result.GlobalFlags.DryRun, "Show what would be done without making changes")
+	fs.BoolVar(&result.GlobalFlags.DryRun, "n", result.GlobalFlags.DryRun, "Show what would be done (shorthand)")
+
+	// Accept flags between package names too, as in "igor hold nvidia-driver-550 --dry-run".
+	packages := make([]string, 0)
+	for {
+		if err := fs.Parse(args); err != nil {
+			return fmt.Errorf("invalid %s flags: %w", name, err)
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		packages = append(packages, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	result.Args = packages
+
+	if result.HoldFlags.List && len(result.Args) > 0 {
+		return &FlagError{
+			Flag:    "list",
+			Message: "cannot use --list with package names",
+		}
+	}
+	return nil
+}
+
+func (p *Parser) parseHistoryFlags(result *ParseResult, args []string) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	fs.BoolVar(&result.HistoryFlags.All, "all", false, "Show all transactions")
+	fs.IntVar(&result.HistoryFlags.Limit, "limit", DefaultHistoryLimit, "Maximum number of transactions shown")
+	fs.StringVar(&result.HistoryFlags.Undo, "undo", "", "Undo the transaction with the given ID")
+	fs.BoolVar(&result.HistoryFlags.Yes, "yes", false, "Do not ask for confirmation")
+	fs.BoolVar(&result.HistoryFlags.Yes, "y", false, "Do not ask for confirmation (shorthand)")
+	fs.BoolVar(&result.GlobalFlags.DryRun, "dry-run", result.GlobalFlags.DryRun, "Show what would be done without making changes")
+	fs.BoolVar(&result.GlobalFlags.DryRun, "n", result.GlobalFlags.DryRun, "Show what would be done (shorthand)")
+
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("invalid history flags: %w", err)
+	}
+	result.Args = fs.Args()
+
+	if len(result.Args) > 0 {
+		return &FlagError{
+			Flag:    "history",
+			Message: fmt.Sprintf("unexpected argument %q (use --undo to undo a transaction)", result.Args[0]),
+		}
+	}
+	if result.HistoryFlags.Limit < 0 {
+		return &FlagError{
+			Flag:    "limit",
+			Message: "limit must be 0 or more",
+		}
+	}
+	if result.HistoryFlags.Undo != "" && result.HistoryFlags.All {
+		return &FlagError{
+			Flag:    "undo",
+			Message: "cannot use --undo with --all",
+		}
+	}
+	return nil
+}
+
+func (p *Parser) parseBundleFlags(result *ParseResult, args []string) error {
+	fs := flag.NewFlagSet("bundle", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	fs.StringVar(&result.BundleFlags.DriverVersion, "driver", "", "Driver version to bundle")
+	fs.StringVar(&result.BundleFlags.CUDAVersion, "cuda", "", "CUDA version to bundle")
+	fs.BoolVar(&result.BundleFlags.InstallCUDA, "with-cuda", false, "Also bundle CUDA toolkit")
+	fs.BoolVar(&result.GlobalFlags.DryRun, "dry-run", result.GlobalFlags.DryRun, "Show what would be done without making changes")
+	fs.BoolVar(&result.GlobalFlags.DryRun, "n", result.GlobalFlags.DryRun, "Show what would be done (shorthand)")
+
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("invalid bundle flags: %w", err)
+	}
+
+	// Accept flags after the directory too, as in "igor bundle DIR --with-cuda".
+	if fs.NArg() > 0 {
+		dir := fs.Arg(0)
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return fmt.Errorf("invalid bundle flags: %w", err)
+		}
+		result.Args = append([]string{dir}, fs.Args()...)
+	}
+
+	switch len(result.Args) {
+	case 0:
+		return &FlagError{
+			Flag:    "bundle",
+			Message: "missing bundle directory",
+		}
+	case 1:
+		return nil
+	default:
+		return &FlagError{
+			Flag:    "bundle",
+			Message: fmt.Sprintf("unexpected argument %q", result.Args[1]),
+		}
+	}
+}
+
+// PackagesShow is the subcommand of the packages command showing the catalog.
+const PackagesShow = "show"
+
+func (p *Parser) parsePackagesFlags(result *ParseResult, args []string) error {
+	fs := flag.NewFlagSet("packages", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	fs.BoolVar(&result.PackagesFlags.All, "all", false, "Show every package set")
+	fs.BoolVar(&result.PackagesFlags.JSON, "json", false, "Output in JSON format")
+
+	if len(args) == 0 || args[0] != PackagesShow {
+		return &FlagError{
+			Flag:    "packages",
+			Message: fmt.Sprintf("expected the %q subcommand", PackagesShow),
+		}
+	}
+
+	// Accept flags after the distribution too, as in "igor packages show fedora --json".
+	args = args[1:]
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("invalid packages flags: %w", err)
+	}
+	if fs.NArg() > 0 {
+		distro := fs.Arg(0)
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return fmt.Errorf("invalid packages flags: %w", err)
+		}
+		result.Args = append([]string{distro}, fs.Args()...)
+	}
+
+	if len(result.Args) > 1 {
+		return &FlagError{
+			Flag:    "packages",
+			Message: fmt.Sprintf("unexpected argument %q", result.Args[1]),
+		}
+	}
+	if result.PackagesFlags.All && len(result.Args) > 0 {
+		return &FlagError{
+			Flag:    "all",
+			Message: "cannot use --all with a distribution",
+		}
+	}
+	return nil
+}
+
 func (p *Parser) parseHelpFlags(result *ParseResult, args []string) error {
 	result.ShowHelp = true
 	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
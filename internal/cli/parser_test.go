@@ -59,6 +59,35 @@ func TestParseListCommand(t *testing.T) {
 	assert.Equal(t, CommandList, result.Command)
 }
 
+func TestParseDoctorCommand(t *testing.T) {
+	p := newTestParser()
+	result, err := p.Parse([]string{"doctor"})
+
+	require.NoError(t, err)
+	assert.Equal(t, CommandDoctor, result.Command)
+	assert.False(t, result.DoctorFlags.Fix)
+}
+
+func TestParsePlanCommand(t *testing.T) {
+	p := newTestParser()
+	result, err := p.Parse([]string{"plan"})
+
+	require.NoError(t, err)
+	assert.Equal(t, CommandPlan, result.Command)
+	assert.False(t, result.PlanFlags.JSON)
+	assert.False(t, result.PlanFlags.Script)
+}
+
+func TestParseRollbackCommand(t *testing.T) {
+	p := newTestParser()
+	result, err := p.Parse([]string{"rollback"})
+
+	require.NoError(t, err)
+	assert.Equal(t, CommandRollback, result.Command)
+	assert.False(t, result.RollbackFlags.Yes)
+	assert.Empty(t, result.Args)
+}
+
 func TestParseVersionCommand(t *testing.T) {
 	p := newTestParser()
 	result, err := p.Parse([]string{"version"})
@@ -313,6 +342,54 @@ func TestParseInstallSkipRebootFlag(t *testing.T) {
 	assert.True(t, result.InstallFlags.SkipReboot)
 }
 
+func TestParseInstallHoldFlag(t *testing.T) {
+	p := newTestParser()
+	result, err := p.Parse([]string{"install", "--hold"})
+
+	require.NoError(t, err)
+	assert.True(t, result.InstallFlags.Hold)
+}
+
+func TestParseInstallFromBundleFlag(t *testing.T) {
+	p := newTestParser()
+	result, err := p.Parse([]string{"install", "--from-bundle", "/srv/bundle", "--force"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "/srv/bundle", result.InstallFlags.FromBundle)
+	assert.True(t, result.InstallFlags.Force)
+
+	for _, args := range [][]string{
+		{"install", "--from-bundle", "/srv/bundle", "--driver", "550"},
+		{"install", "--from-bundle", "/srv/bundle", "--cuda", "12.4"},
+		{"install", "--with-cuda", "--from-bundle", "/srv/bundle"},
+	} {
+		_, err := p.Parse(args)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot use --from-bundle")
+	}
+}
+
+func TestParseInstallDryRunFlag(t *testing.T) {
+	tests := []struct {
+		args   []string
+		dryRun bool
+	}{
+		{[]string{"install", "--dry-run"}, true},
+		{[]string{"install", "-n"}, true},
+		{[]string{"--dry-run", "install"}, true},
+		{[]string{"install"}, false},
+	}
+
+	p := newTestParser()
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			result, err := p.Parse(tt.args)
+			require.NoError(t, err)
+			assert.Equal(t, tt.dryRun, result.GlobalFlags.DryRun)
+		})
+	}
+}
+
 func TestParseInstallAllFlags(t *testing.T) {
 	p := newTestParser()
 	result, err := p.Parse([]string{
@@ -352,6 +429,35 @@ func TestParseUninstallKeepConfigFlag(t *testing.T) {
 	assert.True(t, result.UninstallFlags.KeepConfig)
 }
 
+func TestParseUninstallYesFlag(t *testing.T) {
+	tests := []struct {
+		args []string
+		yes  bool
+	}{
+		{[]string{"uninstall", "--yes"}, true},
+		{[]string{"uninstall", "-y"}, true},
+		{[]string{"uninstall"}, false},
+	}
+
+	p := newTestParser()
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			result, err := p.Parse(tt.args)
+			require.NoError(t, err)
+			assert.Equal(t, tt.yes, result.UninstallFlags.Yes)
+		})
+	}
+}
+
+func TestParseUninstallDryRunFlag(t *testing.T) {
+	p := newTestParser()
+	result, err := p.Parse([]string{"uninstall", "--purge", "--dry-run"})
+
+	require.NoError(t, err)
+	assert.True(t, result.UninstallFlags.Purge)
+	assert.True(t, result.GlobalFlags.DryRun)
+}
+
 // ============================================================================
 // Detect Command Flags Tests
 // ============================================================================
@@ -428,6 +534,316 @@ func TestParseGlobalAndCommandFlags(t *testing.T) {
 	assert.True(t, result.InstallFlags.Force)
 }
 
+// ============================================================================
+// Doctor Command Flags Tests
+// ============================================================================
+
+func TestParseDoctorFixFlag(t *testing.T) {
+	p := newTestParser()
+	result, err := p.Parse([]string{"doctor", "--fix", "-n"})
+
+	require.NoError(t, err)
+	assert.True(t, result.DoctorFlags.Fix)
+	assert.True(t, result.GlobalFlags.DryRun)
+}
+
+// ============================================================================
+// Plan Command Flags Tests
+// ============================================================================
+
+func TestParsePlanFlags(t *testing.T) {
+	p := newTestParser()
+	result, err := p.Parse([]string{"plan", "--driver", "550", "--with-cuda", "--force", "--script"})
+
+	require.NoError(t, err)
+	assert.True(t, result.PlanFlags.Script)
+	assert.Equal(t, InstallFlags{DriverVersion: "550", InstallCUDA: true, Force: true}, result.PlanFlags.InstallFlags())
+}
+
+func TestParsePlanFlags_JSONAndScript(t *testing.T) {
+	p := newTestParser()
+	_, err := p.Parse([]string{"plan", "--json", "--script"})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--json and --script")
+}
+
+// ============================================================================
+// Rollback Command Flags Tests
+// ============================================================================
+
+func TestParseRollbackFlags(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		runID  string
+		yes    bool
+		dryRun bool
+	}{
+		{"run ID", []string{"rollback", "20261016-103834-4f2a9c"}, "20261016-103834-4f2a9c", false, false},
+		{"flags before run ID", []string{"rollback", "-y", "--dry-run", "run1"}, "run1", true, true},
+		{"flags after run ID", []string{"rollback", "run1", "--yes", "-n"}, "run1", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestParser()
+			result, err := p.Parse(tt.args)
+
+			require.NoError(t, err)
+			assert.Equal(t, []string{tt.runID}, result.Args)
+			assert.Equal(t, tt.yes, result.RollbackFlags.Yes)
+			assert.Equal(t, tt.dryRun, result.GlobalFlags.DryRun)
+		})
+	}
+}
+
+func TestParseRollbackFlags_List(t *testing.T) {
+	p := newTestParser()
+	result, err := p.Parse([]string{"rollback", "--list"})
+
+	require.NoError(t, err)
+	assert.True(t, result.RollbackFlags.List)
+
+	_, err = p.Parse([]string{"rollback", "--list", "run1"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--list with a run ID")
+}
+
+func TestParseRollbackFlags_TooManyRunIDs(t *testing.T) {
+	p := newTestParser()
+	_, err := p.Parse([]string{"rollback", "run1", "run2"})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "at most one run ID")
+}
+
+// ============================================================================
+// Hold Command Flags Tests
+// ============================================================================
+
+func TestParseHoldFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		command  Command
+		packages []string
+		dryRun   bool
+	}{
+		{"no packages", []string{"hold"}, CommandHold, []string{}, false},
+		{"packages", []string{"hold", "nvidia-driver-550", "nvidia-dkms-550"}, CommandHold, []string{"nvidia-driver-550", "nvidia-dkms-550"}, false},
+		{"flags between packages", []string{"hold", "nvidia-driver-550", "-n", "nvidia-dkms-550"}, CommandHold, []string{"nvidia-driver-550", "nvidia-dkms-550"}, true},
+		{"unhold", []string{"unhold", "--dry-run", "nvidia-driver-550"}, CommandUnhold, []string{"nvidia-driver-550"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestParser()
+			result, err := p.Parse(tt.args)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.command, result.Command)
+			assert.Equal(t, tt.packages, result.Args)
+			assert.Equal(t, tt.dryRun, result.GlobalFlags.DryRun)
+		})
+	}
+}
+
+func TestParseHoldFlags_List(t *testing.T) {
+	p := newTestParser()
+	result, err := p.Parse([]string{"hold", "--list"})
+
+	require.NoError(t, err)
+	assert.True(t, result.HoldFlags.List)
+
+	_, err = p.Parse([]string{"unhold", "--list", "nvidia-driver-550"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--list with package names")
+}
+
+func TestParseHoldFlags_Invalid(t *testing.T) {
+	p := newTestParser()
+	_, err := p.Parse([]string{"hold", "--yes"})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid hold flags")
+}
+
+// ============================================================================
+// History Command Flags Tests
+// ============================================================================
+
+func TestParseHistoryFlags(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		all    bool
+		limit  int
+		undo   string
+		yes    bool
+		dryRun bool
+	}{
+		{"defaults", []string{"history"}, false, DefaultHistoryLimit, "", false, false},
+		{"all with limit", []string{"history", "--all", "--limit", "5"}, true, 5, "", false, false},
+		{"no limit", []string{"history", "--limit", "0"}, false, 0, "", false, false},
+		{"undo", []string{"history", "--undo", "42", "-y", "-n"}, false, DefaultHistoryLimit, "42", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestParser()
+			result, err := p.Parse(tt.args)
+
+			require.NoError(t, err)
+			assert.Equal(t, CommandHistory, result.Command)
+			assert.Equal(t, tt.all, result.HistoryFlags.All)
+			assert.Equal(t, tt.limit, result.HistoryFlags.Limit)
+			assert.Equal(t, tt.undo, result.HistoryFlags.Undo)
+			assert.Equal(t, tt.yes, result.HistoryFlags.Yes)
+			assert.Equal(t, tt.dryRun, result.GlobalFlags.DryRun)
+		})
+	}
+}
+
+func TestParseHistoryFlags_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		message string
+	}{
+		{"argument", []string{"history", "42"}, "use --undo"},
+		{"negative limit", []string{"history", "--limit", "-1"}, "limit must be 0 or more"},
+		{"undo with all", []string{"history", "--all", "--undo", "42"}, "--undo with --all"},
+		{"unknown flag", []string{"history", "--purge"}, "invalid history flags"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestParser()
+			_, err := p.Parse(tt.args)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.message)
+		})
+	}
+}
+
+// ============================================================================
+// Bundle Command Flags Tests
+// ============================================================================
+
+func TestParseBundleFlags(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		driver string
+		cuda   string
+		with   bool
+		dryRun bool
+	}{
+		{"defaults", []string{"bundle", "/srv/bundle"}, "", "", false, false},
+		{"flags before directory", []string{"bundle", "--driver", "550", "--with-cuda", "/srv/bundle"}, "550", "", true, false},
+		{"flags after directory", []string{"bundle", "/srv/bundle", "--cuda", "12.4", "-n"}, "", "12.4", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestParser()
+			result, err := p.Parse(tt.args)
+
+			require.NoError(t, err)
+			assert.Equal(t, CommandBundle, result.Command)
+			assert.Equal(t, []string{"/srv/bundle"}, result.Args)
+			assert.Equal(t, tt.driver, result.BundleFlags.DriverVersion)
+			assert.Equal(t, tt.cuda, result.BundleFlags.CUDAVersion)
+			assert.Equal(t, tt.with, result.BundleFlags.InstallCUDA)
+			assert.Equal(t, tt.dryRun, result.GlobalFlags.DryRun)
+
+			flags := result.BundleFlags.InstallFlags()
+			assert.Equal(t, tt.driver, flags.DriverVersion)
+			assert.Equal(t, tt.with, flags.InstallCUDA)
+		})
+	}
+}
+
+func TestParseBundleFlags_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		message string
+	}{
+		{"missing directory", []string{"bundle"}, "missing bundle directory"},
+		{"two directories", []string{"bundle", "/a", "/b"}, "unexpected argument"},
+		{"unknown flag", []string{"bundle", "--purge", "/a"}, "invalid bundle flags"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestParser()
+			_, err := p.Parse(tt.args)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.message)
+		})
+	}
+}
+
+// ============================================================================
+// Packages Command Flags Tests
+// ============================================================================
+
+func TestParsePackagesFlags(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		distro []string
+		all    bool
+		json   bool
+	}{
+		{"this system", []string{"packages", "show"}, nil, false, false},
+		{"distribution", []string{"packages", "show", "fedora"}, []string{"fedora"}, false, false},
+		{"flags after distribution", []string{"packages", "show", "fedora", "--json"}, []string{"fedora"}, false, true},
+		{"all", []string{"packages", "show", "--all", "--json"}, nil, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestParser()
+			result, err := p.Parse(tt.args)
+
+			require.NoError(t, err)
+			assert.Equal(t, CommandPackages, result.Command)
+			assert.Equal(t, tt.distro, result.Args)
+			assert.Equal(t, tt.all, result.PackagesFlags.All)
+			assert.Equal(t, tt.json, result.PackagesFlags.JSON)
+		})
+	}
+}
+
+func TestParsePackagesFlags_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		message string
+	}{
+		{"missing subcommand", []string{"packages"}, `expected the "show" subcommand`},
+		{"unknown subcommand", []string{"packages", "edit"}, `expected the "show" subcommand`},
+		{"two distributions", []string{"packages", "show", "fedora", "ubuntu"}, "unexpected argument"},
+		{"all with distribution", []string{"packages", "show", "--all", "fedora"}, "cannot use --all with a distribution"},
+		{"unknown flag", []string{"packages", "show", "--purge"}, "invalid packages flags"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestParser()
+			_, err := p.Parse(tt.args)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.message)
+		})
+	}
+}
+
 // ============================================================================
 // Command Type Tests
 // ============================================================================
@@ -442,6 +858,14 @@ func TestCommandString(t *testing.T) {
 		{CommandUninstall, "uninstall"},
 		{CommandDetect, "detect"},
 		{CommandList, "list"},
+		{CommandDoctor, "doctor"},
+		{CommandPlan, "plan"},
+		{CommandRollback, "rollback"},
+		{CommandHold, "hold"},
+		{CommandUnhold, "unhold"},
+		{CommandHistory, "history"},
+		{CommandBundle, "bundle"},
+		{CommandPackages, "packages"},
 		{CommandVersion, "version"},
 		{CommandHelp, "help"},
 	}
@@ -463,6 +887,14 @@ func TestCommandIsValid(t *testing.T) {
 		{CommandUninstall, true},
 		{CommandDetect, true},
 		{CommandList, true},
+		{CommandDoctor, true},
+		{CommandPlan, true},
+		{CommandRollback, true},
+		{CommandHold, true},
+		{CommandUnhold, true},
+		{CommandHistory, true},
+		{CommandBundle, true},
+		{CommandPackages, true},
 		{CommandVersion, true},
 		{CommandHelp, true},
 		{Command(99), false},
@@ -490,6 +922,14 @@ func TestParseCommand(t *testing.T) {
 		{"list", CommandList},
 		{"l", CommandList},
 		{"ls", CommandList},
+		{"doctor", CommandDoctor},
+		{"plan", CommandPlan},
+		{"rollback", CommandRollback},
+		{"hold", CommandHold},
+		{"unhold", CommandUnhold},
+		{"history", CommandHistory},
+		{"bundle", CommandBundle},
+		{"packages", CommandPackages},
 		{"version", CommandVersion},
 		{"v", CommandVersion},
 		{"help", CommandHelp},
@@ -512,7 +952,7 @@ func TestParseCommand(t *testing.T) {
 func TestCommandsReturnsAllCommands(t *testing.T) {
 	cmds := Commands()
 
-	assert.Len(t, cmds, 6)
+	assert.Len(t, cmds, 14)
 
 	names := make(map[string]bool)
 	for _, cmd := range cmds {
@@ -523,6 +963,14 @@ func TestCommandsReturnsAllCommands(t *testing.T) {
 	assert.True(t, names["uninstall"])
 	assert.True(t, names["detect"])
 	assert.True(t, names["list"])
+	assert.True(t, names["doctor"])
+	assert.True(t, names["plan"])
+	assert.True(t, names["rollback"])
+	assert.True(t, names["hold"])
+	assert.True(t, names["unhold"])
+	assert.True(t, names["history"])
+	assert.True(t, names["bundle"])
+	assert.True(t, names["packages"])
 	assert.True(t, names["version"])
 	assert.True(t, names["help"])
 }
@@ -23,6 +23,7 @@ type Config struct {
 	// Directories
 	ConfigDir string `yaml:"config_dir"`
 	CacheDir  string `yaml:"cache_dir"`
+	StateDir  string `yaml:"state_dir"`
 
 	// Timeouts
 	Timeout        time.Duration `yaml:"timeout"`
@@ -34,6 +35,9 @@ type Config struct {
 	CUDAVersion   string `yaml:"cuda_version"`
 	DriverVersion string `yaml:"driver_version"`
 	AllowUnsigned bool   `yaml:"allow_unsigned"`
+	// HoldDriver holds the installed driver packages after an installation,
+	// so system upgrades do not move them to another driver branch.
+	HoldDriver bool `yaml:"hold_driver"`
 
 	// Advanced
 	ForceInstall bool `yaml:"force_install"`
@@ -51,6 +55,11 @@ func (c *Config) CachePath(name string) string {
 	return filepath.Join(c.CacheDir, name)
 }
 
+// StatePath returns a path within the state directory.
+func (c *Config) StatePath(name string) string {
+	return filepath.Join(c.StateDir, name)
+}
+
 // IsVerbose returns true if verbose output is enabled and quiet is not.
 func (c *Config) IsVerbose() bool {
 	return c.Verbose && !c.Quiet
@@ -28,6 +28,7 @@ func TestDefaultConfig(t *testing.T) {
 	assert.Equal(t, "", cfg.CUDAVersion)
 	assert.Equal(t, "", cfg.DriverVersion)
 	assert.False(t, cfg.AllowUnsigned)
+	assert.False(t, cfg.HoldDriver)
 	assert.False(t, cfg.ForceInstall)
 	assert.False(t, cfg.SkipReboot)
 	assert.False(t, cfg.NoBackup)
@@ -71,6 +72,25 @@ func TestXDGCacheDir(t *testing.T) {
 	assert.Equal(t, filepath.Join(testPath, "igor"), cfg.CacheDir)
 }
 
+// TestXDGStateDir tests XDG_STATE_HOME compliance
+func TestXDGStateDir(t *testing.T) {
+	// Save original value
+	original := os.Getenv("XDG_STATE_HOME")
+	defer os.Setenv("XDG_STATE_HOME", original)
+
+	// Test with XDG_STATE_HOME set
+	testPath := "/tmp/test-xdg-state"
+	os.Setenv("XDG_STATE_HOME", testPath)
+
+	cfg := DefaultConfig()
+	assert.Equal(t, filepath.Join(testPath, "igor"), cfg.StateDir)
+
+	// Test fallback to ~/.local/state
+	os.Unsetenv("XDG_STATE_HOME")
+	home, _ := os.UserHomeDir()
+	assert.Equal(t, filepath.Join(home, ".local", "state", "igor"), DefaultConfig().StateDir)
+}
+
 // TestXDGFallback tests fallback to ~/.config and ~/.cache
 func TestXDGFallback(t *testing.T) {
 	// Save original values
@@ -100,6 +120,14 @@ func TestConfigPath(t *testing.T) {
 	assert.Equal(t, "/test/config/config.yaml", cfg.ConfigPath())
 }
 
+// TestStatePath tests StatePath method
+func TestStatePath(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.StateDir = "/test/state"
+
+	assert.Equal(t, "/test/state/runs", cfg.StatePath("runs"))
+}
+
 // TestCachePath tests CachePath method
 func TestCachePath(t *testing.T) {
 	cfg := DefaultConfig()
@@ -213,6 +241,7 @@ dry_run: false
 		"IGOR_CUDA_VERSION":    "12.1",
 		"IGOR_DRIVER_VERSION":  "535.104",
 		"IGOR_ALLOW_UNSIGNED":  "on",
+		"IGOR_HOLD_DRIVER":     "yes",
 		"IGOR_FORCE_INSTALL":   "true",
 		"IGOR_SKIP_REBOOT":     "true",
 		"IGOR_NO_BACKUP":       "true",
@@ -239,6 +268,7 @@ dry_run: false
 	assert.Equal(t, "12.1", cfg.CUDAVersion)
 	assert.Equal(t, "535.104", cfg.DriverVersion)
 	assert.True(t, cfg.AllowUnsigned)
+	assert.True(t, cfg.HoldDriver)
 	assert.True(t, cfg.ForceInstall)
 	assert.True(t, cfg.SkipReboot)
 	assert.True(t, cfg.NoBackup)
@@ -249,13 +279,16 @@ func TestLoaderEnvironmentDirectories(t *testing.T) {
 	// Set environment variables
 	origConfig := os.Getenv("IGOR_CONFIG_DIR")
 	origCache := os.Getenv("IGOR_CACHE_DIR")
+	origState := os.Getenv("IGOR_STATE_DIR")
 	defer func() {
 		os.Setenv("IGOR_CONFIG_DIR", origConfig)
 		os.Setenv("IGOR_CACHE_DIR", origCache)
+		os.Setenv("IGOR_STATE_DIR", origState)
 	}()
 
 	os.Setenv("IGOR_CONFIG_DIR", "/custom/config")
 	os.Setenv("IGOR_CACHE_DIR", "/custom/cache")
+	os.Setenv("IGOR_STATE_DIR", "/custom/state")
 
 	loader := NewLoader("")
 	cfg, err := loader.Load()
@@ -263,6 +296,7 @@ func TestLoaderEnvironmentDirectories(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "/custom/config", cfg.ConfigDir)
 	assert.Equal(t, "/custom/cache", cfg.CacheDir)
+	assert.Equal(t, "/custom/state", cfg.StateDir)
 }
 
 // TestLoaderWithCustomPrefix tests custom environment prefix
@@ -687,6 +721,7 @@ install_cuda: true
 cuda_version: "12.2"
 driver_version: "535.86.10"
 allow_unsigned: true
+hold_driver: true
 force_install: true
 skip_reboot: true
 no_backup: true
@@ -712,6 +747,7 @@ no_backup: true
 	assert.Equal(t, "12.2", cfg.CUDAVersion)
 	assert.Equal(t, "535.86.10", cfg.DriverVersion)
 	assert.True(t, cfg.AllowUnsigned)
+	assert.True(t, cfg.HoldDriver)
 	assert.True(t, cfg.ForceInstall)
 	assert.True(t, cfg.SkipReboot)
 	assert.True(t, cfg.NoBackup)
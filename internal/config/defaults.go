@@ -34,6 +34,7 @@ func DefaultConfig() *Config {
 		Quiet:          false,
 		ConfigDir:      defaultConfigDir(),
 		CacheDir:       defaultCacheDir(),
+		StateDir:       defaultStateDir(),
 		Timeout:        DefaultTimeout,
 		NetworkTimeout: DefaultNetworkTimeout,
 		CommandTimeout: DefaultCommandTimeout,
@@ -41,6 +42,7 @@ func DefaultConfig() *Config {
 		CUDAVersion:    "",
 		DriverVersion:  "",
 		AllowUnsigned:  false,
+		HoldDriver:     false,
 		ForceInstall:   false,
 		SkipReboot:     false,
 		NoBackup:       false,
@@ -75,6 +77,20 @@ func defaultCacheDir() string {
 	return filepath.Join(home, ".cache", AppName)
 }
 
+// defaultStateDir returns the XDG state directory for igor.
+// Falls back to ~/.local/state/igor if XDG_STATE_HOME is not set.
+func defaultStateDir() string {
+	if xdg := os.Getenv("XDG_STATE_HOME"); xdg != "" {
+		return filepath.Join(xdg, AppName)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		// Fallback to current directory if home can't be determined
+		return filepath.Join(".", ".local", "state", AppName)
+	}
+	return filepath.Join(home, ".local", "state", AppName)
+}
+
 // GetConfigDir returns the configuration directory, respecting XDG.
 // This is exported for use by other packages that need the config path.
 func GetConfigDir() string {
@@ -86,3 +102,9 @@ func GetConfigDir() string {
 func GetCacheDir() string {
 	return defaultCacheDir()
 }
+
+// GetStateDir returns the state directory, respecting XDG.
+// This is exported for use by other packages that need the state path.
+func GetStateDir() string {
+	return defaultStateDir()
+}
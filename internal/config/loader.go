@@ -123,6 +123,9 @@ func (l *Loader) loadFromEnv(cfg *Config) {
 	if v := os.Getenv(l.envPrefix + "CACHE_DIR"); v != "" {
 		cfg.CacheDir = v
 	}
+	if v := os.Getenv(l.envPrefix + "STATE_DIR"); v != "" {
+		cfg.StateDir = v
+	}
 
 	// Timeouts
 	if v := os.Getenv(l.envPrefix + "TIMEOUT"); v != "" {
@@ -154,6 +157,9 @@ func (l *Loader) loadFromEnv(cfg *Config) {
 	if v := os.Getenv(l.envPrefix + "ALLOW_UNSIGNED"); v != "" {
 		cfg.AllowUnsigned = parseBool(v)
 	}
+	if v := os.Getenv(l.envPrefix + "HOLD_DRIVER"); v != "" {
+		cfg.HoldDriver = parseBool(v)
+	}
 
 	// Advanced options
 	if v := os.Getenv(l.envPrefix + "FORCE_INSTALL"); v != "" {
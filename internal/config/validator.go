@@ -126,6 +126,12 @@ func (v *Validator) Validate(cfg *Config) []error {
 			Message: "cache directory cannot be empty",
 		})
 	}
+	if cfg.StateDir == "" {
+		errs = append(errs, &ValidationError{
+			Field:   "state_dir",
+			Message: "state directory cannot be empty",
+		})
+	}
 
 	return errs
 }
@@ -0,0 +1,253 @@
+// Package dkms diagnoses failed DKMS builds of the NVIDIA kernel modules.
+//
+// DKMS only reports that make failed; the reason is in the make.log it keeps
+// in the build directory of the module. The log is matched against a catalog
+// of known failure signatures, each with a remediation.
+package dkms
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/tungetti/igor/internal/exec"
+)
+
+// DefaultTreePath is the directory where DKMS keeps the modules it builds.
+const DefaultTreePath = "/var/lib/dkms"
+
+// MakeLogPath returns the path of the make.log of the last build of the
+// module version. DKMS removes it once a build succeeds.
+func MakeLogPath(module, version string) string {
+	return path.Join(DefaultTreePath, module, version, "build", "make.log")
+}
+
+// Cause identifies a known DKMS build failure.
+type Cause string
+
+const (
+	// CauseGCCPlugin is a GCC plugin of the kernel built by another GCC version.
+	CauseGCCPlugin Cause = "gcc_plugin"
+
+	// CauseMissingHeaders is a build without the headers of the kernel.
+	CauseMissingHeaders Cause = "missing_headers"
+
+	// CauseCompilerMismatch is a compiler that does not support the options
+	// the kernel was built with.
+	CauseCompilerMismatch Cause = "compiler_mismatch"
+
+	// CauseUnsupportedKernel is a kernel API the driver branch does not know.
+	CauseUnsupportedKernel Cause = "unsupported_kernel"
+)
+
+// Build identifies a DKMS build of a module for a kernel.
+type Build struct {
+	// Module is the DKMS module name (e.g., "nvidia").
+	Module string
+	// Version is the module version (e.g., "550.54.14").
+	Version string
+	// Kernel is the kernel version the module is built for.
+	Kernel string
+}
+
+// installCommand returns the command building and installing the module again.
+func (b Build) installCommand() string {
+	return fmt.Sprintf("'sudo dkms install %s/%s -k %s'", b.Module, b.Version, b.Kernel)
+}
+
+// Diagnosis is the known cause of a failed DKMS build.
+type Diagnosis struct {
+	// Cause identifies the failure.
+	Cause Cause
+	// Summary describes the failure.
+	Summary string
+	// Remediation tells how to fix it.
+	Remediation string
+	// Evidence is the line of make.log the failure was recognized by.
+	Evidence string
+}
+
+// signature is a known failure, recognized by any of its patterns.
+type signature struct {
+	cause       Cause
+	patterns    []*regexp.Regexp
+	summary     func(b Build) string
+	remediation func(b Build, log string) string
+}
+
+// kernelCompilerPattern matches the compiler the kernel was built with, as
+// printed by the kernel build when it differs from the one in use.
+var kernelCompilerPattern = regexp.MustCompile(`(?m)The kernel was built by:\s*(.+)$`)
+
+// kernelCompiler returns the compiler the kernel was built with, or a hint
+// where to find it.
+func kernelCompiler(log string) string {
+	if match := kernelCompilerPattern.FindStringSubmatch(log); match != nil {
+		return strings.TrimSpace(match[1])
+	}
+	return "see /proc/version"
+}
+
+// signatures is the catalog of known failures, most specific first: a GCC
+// plugin error is also a compiler mismatch, and the compiler errors come
+// with the errors of the code they fail to compile.
+var signatures = []signature{
+	{
+		cause: CauseGCCPlugin,
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`incompatible gcc/plugin versions?`),
+			regexp.MustCompile(`(?:fail(?:ed)? to initialize|cannot load) plugin \S*gcc-plugins`),
+		},
+		summary: func(b Build) string {
+			return fmt.Sprintf("the GCC plugins of kernel %s were built with another GCC version", b.Kernel)
+		},
+		remediation: func(b Build, log string) string {
+			return fmt.Sprintf("Install the GCC version kernel %s was built with (%s), or reinstall its headers package after a GCC upgrade, then run %s",
+				b.Kernel, kernelCompiler(log), b.installCommand())
+		},
+	},
+	{
+		cause: CauseMissingHeaders,
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)kernel (?:headers|source)\b.*\b(?:cannot be found|not found)`),
+			regexp.MustCompile(`/lib/modules/[^/\s]+/(?:build|source)\S*: No such file or directory`),
+			regexp.MustCompile(`fatal error: (?:generated/autoconf\.h|linux/(?:kconfig|version|module)\.h): No such file or directory`),
+			regexp.MustCompile(`auto\.conf are missing`),
+		},
+		summary: func(b Build) string {
+			return fmt.Sprintf("the headers of kernel %s are not installed", b.Kernel)
+		},
+		remediation: func(b Build, log string) string {
+			return fmt.Sprintf("Install the headers package of kernel %s, then run %s", b.Kernel, b.installCommand())
+		},
+	},
+	{
+		cause: CauseCompilerMismatch,
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?:gcc|cc1|clang)(?:-[\d.]+)?: error: (?:unrecognized|unknown) (?:command[- ]line )?(?:option|argument)`),
+			regexp.MustCompile(`error: unknown warning option`),
+		},
+		summary: func(b Build) string {
+			return fmt.Sprintf("the installed compiler does not support the options kernel %s was built with", b.Kernel)
+		},
+		remediation: func(b Build, log string) string {
+			return fmt.Sprintf("Install the compiler kernel %s was built with (%s) and make it the default cc, then run %s",
+				b.Kernel, kernelCompiler(log), b.installCommand())
+		},
+	},
+	{
+		cause: CauseUnsupportedKernel,
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`error: implicit declaration of function '\w+'`),
+			regexp.MustCompile(`error: too (?:few|many) arguments to function '\w+'`),
+			regexp.MustCompile(`error: '[^']+' has no member named '\w+'`),
+			regexp.MustCompile(`error: unknown type name '\w+'`),
+			regexp.MustCompile(`error: '\w+' undeclared`),
+			regexp.MustCompile(`error: (?:passing argument \d+ of '\w+'|initialization of .*) from incompatible pointer type`),
+		},
+		summary: func(b Build) string {
+			return fmt.Sprintf("%s %s does not support the kernel API of kernel %s", b.Module, b.Version, b.Kernel)
+		},
+		remediation: func(b Build, log string) string {
+			return fmt.Sprintf("Install a newer NVIDIA driver branch supporting kernel %s, or boot a kernel older than %s supported by driver %s",
+				b.Kernel, b.Kernel, b.Version)
+		},
+	},
+}
+
+// makeLogHeaderPattern matches the first line of make.log, such as
+// "DKMS make.log for nvidia-550.54.14 for kernel 6.8.0-45-generic (x86_64)".
+var makeLogHeaderPattern = regexp.MustCompile(`(?m)^DKMS make\.log for \S+ for kernel (\S+)`)
+
+// MakeLogKernel returns the kernel version make.log was written for, or an
+// empty string if its header is missing.
+func MakeLogKernel(log string) string {
+	if match := makeLogHeaderPattern.FindStringSubmatch(log); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// Diagnose matches make.log against the known failure signatures. Returns
+// nil if none matches. The kernel of the build is taken from the header of
+// the log if not set.
+func Diagnose(log string, build Build) *Diagnosis {
+	if build.Kernel == "" {
+		build.Kernel = MakeLogKernel(log)
+	}
+
+	for _, sig := range signatures {
+		for _, pattern := range sig.patterns {
+			loc := pattern.FindStringIndex(log)
+			if loc == nil {
+				continue
+			}
+			return &Diagnosis{
+				Cause:       sig.cause,
+				Summary:     sig.summary(build),
+				Remediation: sig.remediation(build, log),
+				Evidence:    lineAt(log, loc[0]),
+			}
+		}
+	}
+	return nil
+}
+
+// lineAt returns the trimmed line of s containing the offset.
+func lineAt(s string, offset int) string {
+	start := strings.LastIndex(s[:offset], "\n") + 1
+	end := strings.Index(s[offset:], "\n")
+	if end < 0 {
+		return strings.TrimSpace(s[start:])
+	}
+	return strings.TrimSpace(s[start : offset+end])
+}
+
+// ReadMakeLog returns the make.log of the last build of the module version.
+func ReadMakeLog(ctx context.Context, executor exec.Executor, module, version string) (string, error) {
+	logPath := MakeLogPath(module, version)
+	result := executor.Execute(ctx, "cat", logPath)
+	if !result.Success() {
+		return "", fmt.Errorf("failed to read %s", logPath)
+	}
+	return result.StdoutString(), nil
+}
+
+// DiagnoseBuild reads the make.log of the build and diagnoses it. Returns nil
+// if the log cannot be read, is for another kernel, or matches no known
+// failure.
+func DiagnoseBuild(ctx context.Context, executor exec.Executor, build Build) *Diagnosis {
+	log, err := ReadMakeLog(ctx, executor, build.Module, build.Version)
+	if err != nil {
+		return nil
+	}
+	if kernel := MakeLogKernel(log); kernel != "" && build.Kernel != "" && kernel != build.Kernel {
+		return nil
+	}
+	return Diagnose(log, build)
+}
+
+// BuildError is a failed DKMS build, with its diagnosis if known.
+type BuildError struct {
+	// Build is the failed build.
+	Build Build
+	// Err is the error reported by DKMS.
+	Err error
+	// Diagnosis is the cause of the failure, or nil if not known.
+	Diagnosis *Diagnosis
+}
+
+// Error returns the DKMS error, followed by the cause of the failure if known.
+func (e *BuildError) Error() string {
+	if e.Diagnosis == nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%v: %s", e.Err, e.Diagnosis.Summary)
+}
+
+// Unwrap returns the error reported by DKMS.
+func (e *BuildError) Unwrap() error {
+	return e.Err
+}
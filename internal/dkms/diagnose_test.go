@@ -0,0 +1,146 @@
+package dkms
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/exec"
+)
+
+const makeLogHeader = "DKMS make.log for nvidia-550.54.14 for kernel 6.8.0-45-generic (x86_64)\nTue Oct  8 10:12:31 UTC 2024\n"
+
+var testBuild = Build{Module: "nvidia", Version: "550.54.14", Kernel: "6.8.0-45-generic"}
+
+func TestMakeLogPath(t *testing.T) {
+	assert.Equal(t, "/var/lib/dkms/nvidia/550.54.14/build/make.log", MakeLogPath("nvidia", "550.54.14"))
+}
+
+func TestMakeLogKernel(t *testing.T) {
+	assert.Equal(t, "6.8.0-45-generic", MakeLogKernel(makeLogHeader))
+	assert.Empty(t, MakeLogKernel("make: Entering directory\n"))
+}
+
+func TestDiagnose(t *testing.T) {
+	tests := []struct {
+		name        string
+		log         string
+		cause       Cause
+		evidence    string
+		remediation string
+	}{
+		{
+			name: "GCC plugin",
+			log: makeLogHeader +
+				"cc1: error: incompatible gcc/plugin versions\n" +
+				"cc1: error: failed to initialize plugin ./scripts/gcc-plugins/randomize_layout_plugin.so\n",
+			cause:       CauseGCCPlugin,
+			evidence:    "cc1: error: incompatible gcc/plugin versions",
+			remediation: "reinstall its headers package",
+		},
+		{
+			name: "missing headers",
+			log: makeLogHeader +
+				"make[1]: Entering directory '/var/lib/dkms/nvidia/550.54.14/build'\n" +
+				"make: *** /lib/modules/6.8.0-45-generic/build: No such file or directory.  Stop.\n",
+			cause:       CauseMissingHeaders,
+			evidence:    "make: *** /lib/modules/6.8.0-45-generic/build: No such file or directory.  Stop.",
+			remediation: "Install the headers package of kernel 6.8.0-45-generic, then run 'sudo dkms install nvidia/550.54.14 -k 6.8.0-45-generic'",
+		},
+		{
+			name: "compiler mismatch",
+			log: makeLogHeader +
+				"warning: the compiler differs from the one used to build the kernel\n" +
+				"  The kernel was built by: x86_64-linux-gnu-gcc-13 (Ubuntu 13.2.0-23ubuntu4) 13.2.0\n" +
+				"  You are using:           gcc (Ubuntu 11.4.0-1ubuntu1~22.04) 11.4.0\n" +
+				"gcc: error: unrecognized command-line option '-ftrivial-auto-var-init=zero'\n",
+			cause:       CauseCompilerMismatch,
+			evidence:    "gcc: error: unrecognized command-line option '-ftrivial-auto-var-init=zero'",
+			remediation: "(x86_64-linux-gnu-gcc-13 (Ubuntu 13.2.0-23ubuntu4) 13.2.0)",
+		},
+		{
+			name: "unsupported kernel",
+			log: makeLogHeader +
+				"/var/lib/dkms/nvidia/550.54.14/build/nvidia-drm/nvidia-drm-gem.c:55:5: error: implicit declaration of function 'drm_gem_object_put_unlocked' [-Werror=implicit-function-declaration]\n" +
+				"cc1: some warnings being treated as errors\n",
+			cause:       CauseUnsupportedKernel,
+			evidence:    "/var/lib/dkms/nvidia/550.54.14/build/nvidia-drm/nvidia-drm-gem.c:55:5: error: implicit declaration of function 'drm_gem_object_put_unlocked' [-Werror=implicit-function-declaration]",
+			remediation: "Install a newer NVIDIA driver branch supporting kernel 6.8.0-45-generic",
+		},
+		{
+			name: "no member",
+			log: makeLogHeader +
+				"nvidia/nv-mmap.c:42:12: error: 'struct vm_area_struct' has no member named 'vm_flags'\n",
+			cause:       CauseUnsupportedKernel,
+			evidence:    "nvidia/nv-mmap.c:42:12: error: 'struct vm_area_struct' has no member named 'vm_flags'",
+			remediation: "supported by driver 550.54.14",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnosis := Diagnose(tt.log, testBuild)
+
+			require.NotNil(t, diagnosis)
+			assert.Equal(t, tt.cause, diagnosis.Cause)
+			assert.Equal(t, tt.evidence, diagnosis.Evidence)
+			assert.Contains(t, diagnosis.Remediation, tt.remediation)
+			assert.NotEmpty(t, diagnosis.Summary)
+		})
+	}
+
+	t.Run("unknown failure", func(t *testing.T) {
+		assert.Nil(t, Diagnose(makeLogHeader+"make: *** [Makefile:192: __sub-make] Error 2\n", testBuild))
+	})
+
+	t.Run("kernel from the header", func(t *testing.T) {
+		diagnosis := Diagnose(makeLogHeader+"cc1: error: incompatible gcc/plugin versions\n", Build{Module: "nvidia", Version: "550.54.14"})
+
+		require.NotNil(t, diagnosis)
+		assert.Contains(t, diagnosis.Summary, "kernel 6.8.0-45-generic")
+	})
+}
+
+func TestDiagnoseBuild(t *testing.T) {
+	log := makeLogHeader + "cc1: error: incompatible gcc/plugin versions\n"
+
+	t.Run("diagnosed", func(t *testing.T) {
+		executor := exec.NewMockExecutor()
+		executor.SetResponse("cat", exec.SuccessResult(log))
+
+		diagnosis := DiagnoseBuild(context.Background(), executor, testBuild)
+
+		require.NotNil(t, diagnosis)
+		assert.Equal(t, CauseGCCPlugin, diagnosis.Cause)
+		assert.True(t, executor.WasCalledWith("cat", "/var/lib/dkms/nvidia/550.54.14/build/make.log"))
+	})
+
+	t.Run("log of another kernel", func(t *testing.T) {
+		executor := exec.NewMockExecutor()
+		executor.SetResponse("cat", exec.SuccessResult(log))
+
+		build := testBuild
+		build.Kernel = "6.8.0-47-generic"
+		assert.Nil(t, DiagnoseBuild(context.Background(), executor, build))
+	})
+
+	t.Run("no log", func(t *testing.T) {
+		executor := exec.NewMockExecutor()
+		executor.SetResponse("cat", exec.FailureResult(1, "cat: make.log: No such file or directory"))
+
+		assert.Nil(t, DiagnoseBuild(context.Background(), executor, testBuild))
+	})
+}
+
+func TestBuildError(t *testing.T) {
+	cause := errors.New("dkms build failed: Bad return status for module build")
+
+	err := &BuildError{Build: testBuild, Err: cause}
+	assert.Equal(t, cause.Error(), err.Error())
+	assert.ErrorIs(t, err, cause)
+
+	err.Diagnosis = Diagnose(makeLogHeader+"cc1: error: incompatible gcc/plugin versions\n", testBuild)
+	assert.Equal(t, "dkms build failed: Bad return status for module build: the GCC plugins of kernel 6.8.0-45-generic were built with another GCC version", err.Error())
+}
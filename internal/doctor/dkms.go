@@ -0,0 +1,200 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tungetti/igor/internal/dkms"
+	"github.com/tungetti/igor/internal/gpu/validator"
+)
+
+// DKMSEntry is a single line of "dkms status" output.
+type DKMSEntry struct {
+	// Module is the DKMS module name (e.g., "nvidia").
+	Module string
+	// Version is the module version (e.g., "550.54.14").
+	Version string
+	// Kernel is the kernel the entry applies to; empty for "added" modules.
+	Kernel string
+	// Arch is the architecture of the build, if reported.
+	Arch string
+	// Status is the DKMS state (e.g., "added", "built", "installed").
+	Status string
+}
+
+// IsInstalled returns true if the module is installed for its kernel.
+func (e DKMSEntry) IsInstalled() bool {
+	return e.Status == "installed"
+}
+
+// ParseDKMSStatus parses "dkms status" output. Both the current format
+// ("nvidia/550.54.14, 6.5.0-44-generic, x86_64: installed") and the one used
+// by DKMS 2.x ("nvidia, 550.54.14, 6.5.0-44-generic, x86_64: installed") are
+// understood. Trailing warnings after the state are ignored.
+func ParseDKMSStatus(output string) []DKMSEntry {
+	entries := make([]DKMSEntry, 0)
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		colon := strings.LastIndex(line, ":")
+		if line == "" || colon < 0 {
+			continue
+		}
+
+		status := strings.TrimSpace(line[colon+1:])
+		if fields := strings.Fields(status); len(fields) > 0 {
+			status = fields[0]
+		}
+
+		var fields []string
+		for _, f := range strings.Split(line[:colon], ",") {
+			fields = append(fields, strings.TrimSpace(f))
+		}
+
+		// Split "module/version" into separate fields.
+		if name, version, ok := strings.Cut(fields[0], "/"); ok {
+			fields = append([]string{name, version}, fields[1:]...)
+		}
+		if len(fields) < 2 || fields[0] == "" || fields[1] == "" {
+			continue
+		}
+
+		entry := DKMSEntry{Module: fields[0], Version: fields[1], Status: status}
+		if len(fields) > 2 {
+			entry.Kernel = fields[2]
+		}
+		if len(fields) > 3 {
+			entry.Arch = fields[3]
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// InstalledKernels returns the versions of the kernels installed on the
+// system, sorted. A directory under the modules path counts as a kernel when
+// it contains the kernel's own modules; directories left behind by removed
+// kernels only hold out-of-tree modules and are ignored.
+func (d *Doctor) InstalledKernels() ([]string, error) {
+	entries, err := d.fs.ReadDir(d.modulesPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", d.modulesPath, err)
+	}
+
+	kernels := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := d.fs.Stat(filepath.Join(d.modulesPath, entry.Name(), "kernel")); err != nil {
+			continue
+		}
+		kernels = append(kernels, entry.Name())
+	}
+
+	sort.Strings(kernels)
+	return kernels, nil
+}
+
+// dkmsChecks reports the DKMS status of the NVIDIA module for every
+// installed kernel. Nothing is reported when DKMS is unavailable or the
+// driver does not use DKMS (for example prebuilt kernel module packages).
+func (d *Doctor) dkmsChecks(ctx context.Context) []*validator.CheckResult {
+	result := d.executor.Execute(ctx, "dkms", "status", d.dkmsModule)
+	if !result.Success() {
+		return nil
+	}
+
+	var entries []DKMSEntry
+	for _, entry := range ParseDKMSStatus(result.StdoutString()) {
+		if entry.Module == d.dkmsModule {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	kernels, err := d.InstalledKernels()
+	if err != nil {
+		return []*validator.CheckResult{
+			validator.NewCheckResult(
+				CheckDKMSStatus,
+				false,
+				fmt.Sprintf("could not list installed kernels: %v", err),
+				validator.SeverityWarning,
+			),
+		}
+	}
+
+	running := ""
+	if d.kernelDetector != nil {
+		if info, err := d.kernelDetector.GetKernelInfo(ctx); err == nil {
+			running = info.Version
+		}
+	}
+
+	// The make.log of the last failed build tells why the module is missing
+	makeLog, _ := dkms.ReadMakeLog(ctx, d.executor, d.dkmsModule, entries[0].Version)
+
+	results := make([]*validator.CheckResult, 0, len(kernels))
+	for _, k := range kernels {
+		var installed *DKMSEntry
+		for i := range entries {
+			if entries[i].Kernel == k && entries[i].IsInstalled() {
+				installed = &entries[i]
+				break
+			}
+		}
+
+		if installed != nil {
+			results = append(results, validator.NewCheckResult(
+				CheckDKMSStatus,
+				true,
+				fmt.Sprintf("%s/%s is installed for kernel %s", installed.Module, installed.Version, k),
+				validator.SeverityInfo,
+			).WithDetail("kernel", k).
+				WithDetail("version", installed.Version))
+			continue
+		}
+
+		// The running kernel cannot load the driver; other kernels will
+		// fail after the next reboot into them.
+		severity := validator.SeverityWarning
+		message := fmt.Sprintf("%s module is not installed for kernel %s", d.dkmsModule, k)
+		if k == running {
+			severity = validator.SeverityError
+			message += " (running kernel)"
+		}
+
+		remediation := fmt.Sprintf("Install the headers for kernel %s and run 'sudo dkms autoinstall -k %s'", k, k)
+		var diagnosis *dkms.Diagnosis
+		if logKernel := dkms.MakeLogKernel(makeLog); logKernel == k {
+			diagnosis = dkms.Diagnose(makeLog, dkms.Build{Module: d.dkmsModule, Version: entries[0].Version, Kernel: k})
+		}
+		if diagnosis != nil {
+			message += ": the build failed because " + diagnosis.Summary
+			remediation = diagnosis.Remediation
+		}
+
+		check := validator.NewCheckResult(
+			CheckDKMSStatus,
+			false,
+			message,
+			severity,
+		).WithRemediation(remediation).
+			WithDetail("kernel", k).
+			WithDetail("version", entries[0].Version)
+		if diagnosis != nil {
+			check.WithDetail("cause", string(diagnosis.Cause)).
+				WithDetail("evidence", diagnosis.Evidence)
+		}
+		results = append(results, check)
+	}
+
+	return results
+}
@@ -0,0 +1,73 @@
+package doctor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDKMSStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected []DKMSEntry
+	}{
+		{
+			name:   "current format",
+			output: "nvidia/550.54.14, 6.8.0-45-generic, x86_64: installed\n",
+			expected: []DKMSEntry{
+				{Module: "nvidia", Version: "550.54.14", Kernel: "6.8.0-45-generic", Arch: "x86_64", Status: "installed"},
+			},
+		},
+		{
+			name:   "dkms 2.x format",
+			output: "nvidia, 535.161.07, 5.15.0-100-generic, x86_64: installed",
+			expected: []DKMSEntry{
+				{Module: "nvidia", Version: "535.161.07", Kernel: "5.15.0-100-generic", Arch: "x86_64", Status: "installed"},
+			},
+		},
+		{
+			name:   "added only",
+			output: "nvidia/550.54.14: added",
+			expected: []DKMSEntry{
+				{Module: "nvidia", Version: "550.54.14", Status: "added"},
+			},
+		},
+		{
+			name: "warnings and blank lines",
+			output: "\nnvidia/550.54.14, 6.8.0-40-generic, x86_64: installed (WARNING! Diff between built and installed module!)\n" +
+				"nvidia/550.54.14, 6.8.0-45-generic, x86_64: built\n",
+			expected: []DKMSEntry{
+				{Module: "nvidia", Version: "550.54.14", Kernel: "6.8.0-40-generic", Arch: "x86_64", Status: "installed"},
+				{Module: "nvidia", Version: "550.54.14", Kernel: "6.8.0-45-generic", Arch: "x86_64", Status: "built"},
+			},
+		},
+		{
+			name:     "empty",
+			output:   "",
+			expected: []DKMSEntry{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ParseDKMSStatus(tt.output))
+		})
+	}
+}
+
+func TestDoctor_InstalledKernels(t *testing.T) {
+	fsys := newKernelsFileSystem("6.8.0-45-generic", "6.8.0-40-generic")
+	// Left behind by a removed kernel: only DKMS modules remain.
+	fsys.fsys["lib/modules/6.5.0-10-generic/updates/dkms/nvidia.ko"] = nil
+
+	d := NewDoctor(WithFileSystem(fsys), WithModulesPath("lib/modules"))
+
+	kernels, err := d.InstalledKernels()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"6.8.0-40-generic", "6.8.0-45-generic"}, kernels)
+
+	_, err = NewDoctor(WithFileSystem(fsys), WithModulesPath("missing")).InstalledKernels()
+	assert.Error(t, err)
+}
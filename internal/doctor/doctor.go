@@ -0,0 +1,503 @@
+// Package doctor diagnoses an existing NVIDIA driver installation.
+// It combines the system requirement checks of the validator, the
+// post-installation verification checks, the Nouveau status, the DKMS
+// module status of every installed kernel and the enabled NVIDIA package
+// sources into a single prioritized list of problems, each with
+// remediation instructions. Problems that an installation step can repair
+// are marked with the step that fixes them.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tungetti/igor/internal/distro"
+	"github.com/tungetti/igor/internal/errors"
+	"github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/gpu/kernel"
+	"github.com/tungetti/igor/internal/gpu/nouveau"
+	"github.com/tungetti/igor/internal/gpu/validator"
+	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/install/steps"
+	"github.com/tungetti/igor/internal/pkg"
+	"github.com/tungetti/igor/internal/pkg/nvidia"
+)
+
+// Check names used for results that are not produced by the validator.
+const (
+	// CheckNvidiaSmi reports whether nvidia-smi can talk to the driver.
+	CheckNvidiaSmi validator.CheckName = "nvidia_smi"
+	// CheckModuleLoaded reports whether the nvidia kernel module is loaded.
+	CheckModuleLoaded validator.CheckName = "module_loaded"
+	// CheckGPUDetected reports whether nvidia-smi sees at least one GPU.
+	CheckGPUDetected validator.CheckName = "gpu_detected"
+	// CheckXorgConfig reports whether the X.org configuration for NVIDIA exists.
+	CheckXorgConfig validator.CheckName = "xorg_config"
+	// CheckDKMSStatus reports whether the NVIDIA DKMS module is installed for a kernel.
+	CheckDKMSStatus validator.CheckName = "dkms_status"
+	// CheckRepositoryConflicts reports whether several enabled repositories
+	// provide the NVIDIA packages.
+	CheckRepositoryConflicts validator.CheckName = "repository_conflicts"
+)
+
+// FixAction names the installation step that repairs a problem.
+type FixAction string
+
+const (
+	// FixNone means the problem cannot be repaired automatically.
+	FixNone FixAction = ""
+	// FixNouveauBlacklist runs the Nouveau blacklist step.
+	FixNouveauBlacklist FixAction = "nouveau_blacklist"
+	// FixDKMSBuild runs the DKMS build step for the affected kernel.
+	FixDKMSBuild FixAction = "dkms_build"
+	// FixModuleLoad runs the module load step.
+	FixModuleLoad FixAction = "module_load"
+	// FixXorgConfig runs the X.org configuration step.
+	FixXorgConfig FixAction = "xorg_config"
+	// FixRepositoryConflicts runs the repository conflict step.
+	FixRepositoryConflicts FixAction = "repository_conflicts"
+)
+
+// String returns the string representation of the fix action.
+func (f FixAction) String() string {
+	return string(f)
+}
+
+// Problem is a failed check together with the step that can fix it.
+type Problem struct {
+	validator.CheckResult
+
+	// Fix is the installation step that repairs the problem, if any.
+	Fix FixAction
+}
+
+// Fixable returns true if an installation step can repair the problem.
+func (p Problem) Fixable() bool {
+	return p.Fix != FixNone
+}
+
+// Kernel returns the kernel version the problem applies to, if any.
+func (p Problem) Kernel() string {
+	return p.Details["kernel"]
+}
+
+// Report contains the results of a diagnosis.
+type Report struct {
+	// Checks contains all check results in the order they were performed.
+	Checks []validator.CheckResult
+
+	// Problems contains the failed checks, most severe first.
+	Problems []Problem
+
+	// Timestamp is when the diagnosis was performed.
+	Timestamp time.Time
+
+	// Duration is how long the diagnosis took.
+	Duration time.Duration
+}
+
+// Healthy returns true if no problems were found.
+func (r *Report) Healthy() bool {
+	return len(r.Problems) == 0
+}
+
+// HasErrors returns true if any problem has error severity.
+func (r *Report) HasErrors() bool {
+	for _, p := range r.Problems {
+		if p.Severity.IsError() {
+			return true
+		}
+	}
+	return false
+}
+
+// FixableProblems returns the problems that an installation step can repair.
+func (r *Report) FixableProblems() []Problem {
+	fixable := make([]Problem, 0)
+	for _, p := range r.Problems {
+		if p.Fixable() {
+			fixable = append(fixable, p)
+		}
+	}
+	return fixable
+}
+
+// FileSystem abstracts filesystem operations for testing.
+type FileSystem interface {
+	// ReadDir reads the named directory and returns its entries.
+	ReadDir(name string) ([]fs.DirEntry, error)
+
+	// Stat returns the FileInfo structure describing file.
+	Stat(name string) (fs.FileInfo, error)
+}
+
+// RealFileSystem implements FileSystem using the actual operating system.
+type RealFileSystem struct{}
+
+// ReadDir reads the named directory and returns its entries.
+func (RealFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+// Stat returns the FileInfo structure describing file.
+func (RealFileSystem) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+// Doctor diagnoses the NVIDIA driver installation of the running system.
+type Doctor struct {
+	executor        exec.Executor
+	validator       validator.Validator
+	kernelDetector  kernel.Detector
+	nouveauDetector nouveau.Detector
+	fs              FileSystem
+	modulesPath     string
+	dkmsModule      string
+	packageManager  pkg.Manager
+	distribution    *distro.Distribution
+}
+
+// Option configures the doctor.
+type Option func(*Doctor)
+
+// WithExecutor sets the executor used to run diagnostic commands.
+func WithExecutor(executor exec.Executor) Option {
+	return func(d *Doctor) {
+		d.executor = executor
+	}
+}
+
+// WithValidator sets the system requirements validator.
+func WithValidator(v validator.Validator) Option {
+	return func(d *Doctor) {
+		d.validator = v
+	}
+}
+
+// WithKernelDetector sets the kernel detector.
+func WithKernelDetector(detector kernel.Detector) Option {
+	return func(d *Doctor) {
+		d.kernelDetector = detector
+	}
+}
+
+// WithNouveauDetector sets the Nouveau detector.
+func WithNouveauDetector(detector nouveau.Detector) Option {
+	return func(d *Doctor) {
+		d.nouveauDetector = detector
+	}
+}
+
+// WithFileSystem sets a custom filesystem implementation.
+func WithFileSystem(fs FileSystem) Option {
+	return func(d *Doctor) {
+		d.fs = fs
+	}
+}
+
+// WithModulesPath sets the directory holding the modules of installed kernels.
+func WithModulesPath(path string) Option {
+	return func(d *Doctor) {
+		d.modulesPath = path
+	}
+}
+
+// WithDKMSModule sets the name of the NVIDIA DKMS module (default: "nvidia").
+func WithDKMSModule(name string) Option {
+	return func(d *Doctor) {
+		d.dkmsModule = name
+	}
+}
+
+// WithPackageManager sets the package manager used to list the repositories.
+// The repository conflict check is skipped without it.
+func WithPackageManager(pm pkg.Manager) Option {
+	return func(d *Doctor) {
+		d.packageManager = pm
+	}
+}
+
+// WithDistribution sets the distribution of the system.
+// The repository conflict check is skipped without it.
+func WithDistribution(dist *distro.Distribution) Option {
+	return func(d *Doctor) {
+		d.distribution = dist
+	}
+}
+
+// NewDoctor creates a new doctor with the given options.
+func NewDoctor(opts ...Option) *Doctor {
+	d := &Doctor{
+		fs:          RealFileSystem{},
+		modulesPath: kernel.DefaultModulesBuildPath,
+		dkmsModule:  steps.DefaultDKMSModuleName,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Diagnose runs all checks and returns a report of the problems found.
+// Driver checks run first, followed by Nouveau, DKMS, repository and the
+// system requirement checks; problems are then ordered by severity.
+func (d *Doctor) Diagnose(ctx context.Context) (*Report, error) {
+	const op = "doctor.Diagnose"
+
+	if d.executor == nil {
+		return nil, errors.New(errors.Validation, "executor is required").WithOp(op)
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, errors.Wrap(errors.Validation, "diagnosis cancelled", ctx.Err()).WithOp(op)
+	default:
+	}
+
+	startTime := time.Now()
+	report := &Report{
+		Checks:    make([]validator.CheckResult, 0),
+		Problems:  make([]Problem, 0),
+		Timestamp: startTime,
+	}
+
+	add := func(result *validator.CheckResult, fix FixAction) {
+		report.Checks = append(report.Checks, *result)
+		if !result.Passed {
+			report.Problems = append(report.Problems, Problem{CheckResult: *result, Fix: fix})
+		}
+	}
+
+	for _, c := range d.driverChecks(ctx) {
+		add(c.result, c.fix)
+	}
+
+	if result, fix := d.nouveauCheck(ctx); result != nil {
+		add(result, fix)
+	}
+
+	for _, result := range d.dkmsChecks(ctx) {
+		add(result, FixDKMSBuild)
+	}
+
+	if result, fix := d.repositoryCheck(ctx); result != nil {
+		add(result, fix)
+	}
+
+	if d.validator != nil {
+		validation, err := d.validator.Validate(ctx)
+		if err != nil {
+			return nil, errors.Wrap(errors.Validation, "system validation failed", err).WithOp(op)
+		}
+		for i := range validation.Checks {
+			// Nouveau is checked above with post-installation semantics.
+			if validation.Checks[i].Name == validator.CheckNouveauStatus {
+				continue
+			}
+			add(&validation.Checks[i], FixNone)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, errors.Wrap(errors.Validation, "diagnosis cancelled", err).WithOp(op)
+	}
+
+	sort.SliceStable(report.Problems, func(i, j int) bool {
+		return severityRank(report.Problems[i].Severity) < severityRank(report.Problems[j].Severity)
+	})
+
+	report.Duration = time.Since(startTime)
+	return report, nil
+}
+
+// driverCheck pairs a driver check result with its fix.
+type driverCheck struct {
+	result *validator.CheckResult
+	fix    FixAction
+}
+
+// verificationChecks maps the VerificationStep check names to doctor checks.
+var verificationChecks = map[string]struct {
+	name        validator.CheckName
+	fix         FixAction
+	remediation string
+}{
+	"nvidia-smi": {
+		name:        CheckNvidiaSmi,
+		remediation: "Make sure the NVIDIA driver is installed (sudo igor install) and its kernel module is loaded",
+	},
+	"nvidia-module": {
+		name:        CheckModuleLoaded,
+		fix:         FixModuleLoad,
+		remediation: "Load the module with 'sudo modprobe nvidia'; if that fails, check 'sudo dmesg' and the DKMS status of the running kernel",
+	},
+	"gpu-detected": {
+		name:        CheckGPUDetected,
+		remediation: "Check that the GPU is listed by 'lspci' and not bound to another driver, then reboot",
+	},
+	"xorg-config": {
+		name:        CheckXorgConfig,
+		fix:         FixXorgConfig,
+		remediation: fmt.Sprintf("Create %s (not needed on Wayland-only systems)", steps.DefaultXorgConfPath),
+	},
+}
+
+// driverChecks runs the post-installation verification checks.
+func (d *Doctor) driverChecks(ctx context.Context) []driverCheck {
+	installCtx := install.NewContext(
+		install.WithExecutor(d.executor),
+		install.WithContext(ctx),
+	)
+
+	opts := []steps.VerificationStepOption{steps.WithCheckXorgConfig(true)}
+	if d.kernelDetector != nil {
+		opts = append(opts, steps.WithVerificationKernelDetector(d.kernelDetector))
+	}
+	steps.NewVerificationStep(opts...).Execute(installCtx)
+
+	raw, _ := installCtx.GetState(steps.StateVerificationChecks)
+	checks, _ := raw.([]steps.VerificationCheck)
+
+	results := make([]driverCheck, 0, len(checks))
+	for _, check := range checks {
+		mapping, ok := verificationChecks[check.Name]
+		if !ok {
+			continue
+		}
+
+		severity := validator.SeverityInfo
+		if !check.Passed {
+			severity = validator.SeverityWarning
+			if check.Critical {
+				severity = validator.SeverityError
+			}
+		}
+
+		result := validator.NewCheckResult(mapping.name, check.Passed, check.Message, severity)
+		if !check.Passed {
+			result.WithRemediation(mapping.remediation)
+		}
+		results = append(results, driverCheck{result: result, fix: mapping.fix})
+	}
+
+	return results
+}
+
+// nouveauCheck reports the Nouveau status. Once the NVIDIA driver is
+// installed a loaded Nouveau module keeps it from binding to the GPU, so
+// this is an error rather than the warning reported before installation.
+func (d *Doctor) nouveauCheck(ctx context.Context) (*validator.CheckResult, FixAction) {
+	if d.nouveauDetector == nil {
+		return nil, FixNone
+	}
+
+	status, err := d.nouveauDetector.Detect(ctx)
+	if err != nil {
+		return validator.NewCheckResult(
+			validator.CheckNouveauStatus,
+			false,
+			fmt.Sprintf("failed to check Nouveau status: %v", err),
+			validator.SeverityWarning,
+		), FixNone
+	}
+
+	switch {
+	case status.Loaded && !status.BlacklistExists:
+		return validator.NewCheckResult(
+			validator.CheckNouveauStatus,
+			false,
+			"Nouveau driver is loaded and not blacklisted",
+			validator.SeverityError,
+		).WithRemediation("Blacklist the nouveau driver, regenerate the initramfs and reboot").
+			WithDetail("loaded", "true").
+			WithDetail("blacklist_exists", "false"), FixNouveauBlacklist
+	case status.Loaded:
+		return validator.NewCheckResult(
+			validator.CheckNouveauStatus,
+			false,
+			"Nouveau driver is loaded although it is blacklisted",
+			validator.SeverityError,
+		).WithRemediation("Regenerate the initramfs (update-initramfs -u or dracut --force) and reboot").
+			WithDetail("loaded", "true").
+			WithDetail("blacklist_exists", "true"), FixNone
+	case !status.BlacklistExists:
+		return validator.NewCheckResult(
+			validator.CheckNouveauStatus,
+			false,
+			"Nouveau driver is not blacklisted and may claim the GPU on the next boot",
+			validator.SeverityWarning,
+		).WithRemediation("Blacklist the nouveau driver and regenerate the initramfs").
+			WithDetail("loaded", "false").
+			WithDetail("blacklist_exists", "false"), FixNouveauBlacklist
+	default:
+		return validator.NewCheckResult(
+			validator.CheckNouveauStatus,
+			true,
+			"Nouveau driver is not loaded and is blacklisted",
+			validator.SeverityInfo,
+		).WithDetail("loaded", "false").
+			WithDetail("blacklist_exists", "true"), FixNone
+	}
+}
+
+// repositoryCheck reports enabled repositories competing to provide the
+// NVIDIA packages, which mix driver versions built by different packagers.
+func (d *Doctor) repositoryCheck(ctx context.Context) (*validator.CheckResult, FixAction) {
+	if d.packageManager == nil || d.distribution == nil {
+		return nil, FixNone
+	}
+
+	analysis, err := nvidia.AnalyzeRepositories(ctx, d.packageManager, d.distribution)
+	if err != nil {
+		return validator.NewCheckResult(
+			CheckRepositoryConflicts,
+			false,
+			fmt.Sprintf("failed to check NVIDIA repositories: %v", err),
+			validator.SeverityWarning,
+		), FixNone
+	}
+
+	if !analysis.HasConflicts() {
+		message := "no NVIDIA repository enabled"
+		if preferred := analysis.Preferred(); preferred != nil {
+			message = fmt.Sprintf("NVIDIA packages come from %s only", preferred.Name)
+		}
+		return validator.NewCheckResult(CheckRepositoryConflicts, true, message, validator.SeverityInfo), FixNone
+	}
+
+	conflicts := make([]string, 0, len(analysis.Conflicts))
+	for _, c := range analysis.Conflicts {
+		conflicts = append(conflicts, c.String())
+	}
+	var competing []string
+	for _, repo := range analysis.CompetingRepositories() {
+		competing = append(competing, repo.Name)
+	}
+
+	return validator.NewCheckResult(
+		CheckRepositoryConflicts,
+		false,
+		fmt.Sprintf("conflicting NVIDIA repositories: %s", strings.Join(conflicts, "; ")),
+		validator.SeverityWarning,
+	).WithRemediation(fmt.Sprintf("Keep %s and disable %s",
+		analysis.Preferred().Name, strings.Join(competing, ", "))).
+		WithDetail("keep", analysis.Preferred().Name).
+		WithDetail("disable", strings.Join(competing, ",")), FixRepositoryConflicts
+}
+
+// severityRank orders severities from most to least severe.
+func severityRank(s validator.Severity) int {
+	switch s {
+	case validator.SeverityError:
+		return 0
+	case validator.SeverityWarning:
+		return 1
+	default:
+		return 2
+	}
+}
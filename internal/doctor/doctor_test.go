@@ -0,0 +1,416 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/distro"
+	"github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/gpu/kernel"
+	"github.com/tungetti/igor/internal/gpu/nouveau"
+	"github.com/tungetti/igor/internal/gpu/validator"
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// =============================================================================
+// Mocks
+// =============================================================================
+
+// mapFileSystem adapts fstest.MapFS to FileSystem.
+type mapFileSystem struct {
+	fsys fstest.MapFS
+}
+
+func (m mapFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(m.fsys, name)
+}
+
+func (m mapFileSystem) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(m.fsys, name)
+}
+
+// newKernelsFileSystem returns a filesystem with the given kernels installed
+// under "lib/modules".
+func newKernelsFileSystem(kernels ...string) mapFileSystem {
+	fsys := fstest.MapFS{}
+	for _, k := range kernels {
+		fsys["lib/modules/"+k+"/kernel/drivers/gpu/README"] = &fstest.MapFile{}
+	}
+	return mapFileSystem{fsys: fsys}
+}
+
+type mockKernelDetector struct {
+	version       string
+	loadedModules map[string]bool
+}
+
+func (m *mockKernelDetector) GetKernelInfo(ctx context.Context) (*kernel.KernelInfo, error) {
+	return &kernel.KernelInfo{Version: m.version}, nil
+}
+
+func (m *mockKernelDetector) IsModuleLoaded(ctx context.Context, name string) (bool, error) {
+	return m.loadedModules[name], nil
+}
+
+func (m *mockKernelDetector) GetLoadedModules(ctx context.Context) ([]kernel.ModuleInfo, error) {
+	return nil, nil
+}
+
+func (m *mockKernelDetector) GetModule(ctx context.Context, name string) (*kernel.ModuleInfo, error) {
+	return nil, nil
+}
+
+func (m *mockKernelDetector) AreHeadersInstalled(ctx context.Context) (bool, error) {
+	return true, nil
+}
+
+func (m *mockKernelDetector) GetHeadersPackage(ctx context.Context) (string, error) {
+	return "", nil
+}
+
+func (m *mockKernelDetector) IsSecureBootEnabled(ctx context.Context) (bool, error) {
+	return false, nil
+}
+
+type mockNouveauDetector struct {
+	status *nouveau.Status
+	err    error
+}
+
+func (m *mockNouveauDetector) Detect(ctx context.Context) (*nouveau.Status, error) {
+	return m.status, m.err
+}
+
+func (m *mockNouveauDetector) IsLoaded(ctx context.Context) (bool, error) {
+	return m.status.Loaded, m.err
+}
+
+func (m *mockNouveauDetector) IsBlacklisted(ctx context.Context) (bool, error) {
+	return m.status.BlacklistExists, m.err
+}
+
+func (m *mockNouveauDetector) GetBoundDevices(ctx context.Context) ([]string, error) {
+	return m.status.BoundDevices, m.err
+}
+
+type mockValidator struct {
+	validator.Validator
+	report *validator.ValidationReport
+	err    error
+}
+
+func (m *mockValidator) Validate(ctx context.Context) (*validator.ValidationReport, error) {
+	return m.report, m.err
+}
+
+type mockPackageManager struct {
+	pkg.Manager
+	repos []pkg.Repository
+	err   error
+}
+
+func (m *mockPackageManager) ListRepositories(ctx context.Context) ([]pkg.Repository, error) {
+	return m.repos, m.err
+}
+
+// newHealthyDoctor returns a doctor whose checks all pass, together with its
+// executor so tests can break individual checks.
+func newHealthyDoctor(opts ...Option) (*Doctor, *exec.MockExecutor) {
+	executor := exec.NewMockExecutor()
+	executor.SetResponse("nvidia-smi", exec.SuccessResult("550.54.14"))
+	executor.SetResponse("dkms", exec.SuccessResult(
+		"nvidia/550.54.14, 6.8.0-40-generic, x86_64: installed\n"+
+			"nvidia/550.54.14, 6.8.0-45-generic, x86_64: installed\n"))
+
+	report := validator.NewValidationReport()
+	report.AddCheck(validator.NewCheckResult(validator.CheckDiskSpace, true, "sufficient disk space", validator.SeverityInfo))
+	report.AddCheck(validator.NewCheckResult(validator.CheckNouveauStatus, false, "Nouveau driver is currently loaded", validator.SeverityWarning))
+
+	defaults := []Option{
+		WithExecutor(executor),
+		WithKernelDetector(&mockKernelDetector{
+			version:       "6.8.0-45-generic",
+			loadedModules: map[string]bool{"nvidia": true},
+		}),
+		WithNouveauDetector(&mockNouveauDetector{
+			status: &nouveau.Status{BlacklistExists: true},
+		}),
+		WithValidator(&mockValidator{report: report}),
+		WithFileSystem(newKernelsFileSystem("6.8.0-40-generic", "6.8.0-45-generic")),
+		WithModulesPath("lib/modules"),
+	}
+
+	return NewDoctor(append(defaults, opts...)...), executor
+}
+
+func problemNames(report *Report) []validator.CheckName {
+	names := make([]validator.CheckName, 0, len(report.Problems))
+	for _, p := range report.Problems {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// =============================================================================
+// Diagnose Tests
+// =============================================================================
+
+func TestDoctor_Diagnose_Healthy(t *testing.T) {
+	d, _ := newHealthyDoctor()
+
+	report, err := d.Diagnose(context.Background())
+	require.NoError(t, err)
+
+	assert.True(t, report.Healthy())
+	assert.False(t, report.HasErrors())
+	assert.Empty(t, report.FixableProblems())
+
+	// Driver checks, nouveau, one DKMS check per kernel and the validator
+	// checks except its nouveau check.
+	names := make([]validator.CheckName, 0, len(report.Checks))
+	for _, c := range report.Checks {
+		names = append(names, c.Name)
+	}
+	assert.Equal(t, []validator.CheckName{
+		CheckNvidiaSmi, CheckModuleLoaded, CheckGPUDetected, CheckXorgConfig,
+		validator.CheckNouveauStatus,
+		CheckDKMSStatus, CheckDKMSStatus,
+		validator.CheckDiskSpace,
+	}, names)
+}
+
+func TestDoctor_Diagnose_PrioritizesProblems(t *testing.T) {
+	d, executor := newHealthyDoctor(
+		WithKernelDetector(&mockKernelDetector{version: "6.8.0-45-generic"}),
+		WithNouveauDetector(&mockNouveauDetector{status: &nouveau.Status{}}),
+	)
+	executor.SetResponse("test", exec.FailureResult(1, ""))
+	executor.SetResponse("dkms", exec.SuccessResult("nvidia/550.54.14, 6.8.0-40-generic, x86_64: installed\n"))
+
+	report, err := d.Diagnose(context.Background())
+	require.NoError(t, err)
+
+	assert.True(t, report.HasErrors())
+	assert.Equal(t, []validator.CheckName{
+		CheckModuleLoaded,
+		CheckDKMSStatus,
+		CheckXorgConfig,
+		validator.CheckNouveauStatus,
+	}, problemNames(report))
+
+	for _, p := range report.Problems {
+		assert.NotEmpty(t, p.Remediation, "problem %s has no remediation", p.Name)
+		assert.True(t, p.Fixable(), "problem %s is not fixable", p.Name)
+	}
+
+	dkms := report.Problems[1]
+	assert.Equal(t, validator.SeverityError, dkms.Severity)
+	assert.Equal(t, "6.8.0-45-generic", dkms.Kernel())
+	assert.Contains(t, dkms.Message, "running kernel")
+	assert.Contains(t, dkms.Remediation, "dkms autoinstall -k 6.8.0-45-generic")
+}
+
+func TestDoctor_Diagnose_DKMSBuildFailure(t *testing.T) {
+	d, executor := newHealthyDoctor()
+	executor.SetResponse("dkms", exec.SuccessResult("nvidia/550.54.14, 6.8.0-40-generic, x86_64: installed\n"))
+	executor.SetResponse("cat", exec.SuccessResult(
+		"DKMS make.log for nvidia-550.54.14 for kernel 6.8.0-45-generic (x86_64)\n"+
+			"cc1: error: incompatible gcc/plugin versions\n"))
+
+	report, err := d.Diagnose(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, report.Problems, 1)
+	p := report.Problems[0]
+	assert.Equal(t, CheckDKMSStatus, p.Name)
+	assert.Equal(t, "nvidia module is not installed for kernel 6.8.0-45-generic (running kernel): the build failed because the GCC plugins of kernel 6.8.0-45-generic were built with another GCC version", p.Message)
+	assert.Contains(t, p.Remediation, "reinstall its headers package")
+	assert.Equal(t, "gcc_plugin", p.Details["cause"])
+	assert.Equal(t, "cc1: error: incompatible gcc/plugin versions", p.Details["evidence"])
+	assert.True(t, executor.WasCalledWith("cat", "/var/lib/dkms/nvidia/550.54.14/build/make.log"))
+}
+
+func TestDoctor_Diagnose_NouveauLoaded(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   *nouveau.Status
+		severity validator.Severity
+		fix      FixAction
+	}{
+		{"not blacklisted", &nouveau.Status{Loaded: true}, validator.SeverityError, FixNouveauBlacklist},
+		{"blacklisted", &nouveau.Status{Loaded: true, BlacklistExists: true}, validator.SeverityError, FixNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _ := newHealthyDoctor(WithNouveauDetector(&mockNouveauDetector{status: tt.status}))
+
+			report, err := d.Diagnose(context.Background())
+			require.NoError(t, err)
+
+			require.Len(t, report.Problems, 1)
+			p := report.Problems[0]
+			assert.Equal(t, validator.CheckNouveauStatus, p.Name)
+			assert.Equal(t, tt.severity, p.Severity)
+			assert.Equal(t, tt.fix, p.Fix)
+			assert.NotEmpty(t, p.Remediation)
+		})
+	}
+}
+
+func TestDoctor_Diagnose_NoDKMS(t *testing.T) {
+	d, executor := newHealthyDoctor()
+	executor.SetResponse("dkms", exec.FailureResult(127, "dkms: command not found"))
+
+	report, err := d.Diagnose(context.Background())
+	require.NoError(t, err)
+
+	assert.True(t, report.Healthy())
+	for _, c := range report.Checks {
+		assert.NotEqual(t, CheckDKMSStatus, c.Name)
+	}
+}
+
+func TestDoctor_Diagnose_ValidatorProblems(t *testing.T) {
+	report := validator.NewValidationReport()
+	report.AddCheck(validator.NewCheckResult(validator.CheckKernelHeaders, false, "kernel headers are not installed", validator.SeverityError).
+		WithRemediation("Install kernel headers"))
+	report.AddCheck(validator.NewCheckResult(validator.CheckSecureBoot, false, "Secure Boot is enabled", validator.SeverityWarning).
+		WithRemediation("Disable Secure Boot"))
+
+	d, _ := newHealthyDoctor(WithValidator(&mockValidator{report: report}))
+
+	result, err := d.Diagnose(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []validator.CheckName{validator.CheckKernelHeaders, validator.CheckSecureBoot}, problemNames(result))
+	assert.Equal(t, "Install kernel headers", result.Problems[0].Remediation)
+	assert.False(t, result.Problems[0].Fixable())
+}
+
+func TestDoctor_Diagnose_RepositoryConflicts(t *testing.T) {
+	fedora := &distro.Distribution{ID: "fedora", VersionID: "40", Family: constants.FamilyRHEL}
+	pm := &mockPackageManager{repos: []pkg.Repository{
+		{Name: "fedora", Enabled: true},
+		{Name: "rpmfusion-nonfree", Enabled: true},
+		{Name: "rpmfusion-nonfree-updates", Enabled: true},
+		{Name: "fedora-nvidia", Enabled: true},
+	}}
+
+	d, _ := newHealthyDoctor(WithPackageManager(pm), WithDistribution(fedora))
+
+	report, err := d.Diagnose(context.Background())
+	require.NoError(t, err)
+
+	require.Equal(t, []validator.CheckName{CheckRepositoryConflicts}, problemNames(report))
+	p := report.Problems[0]
+	assert.Equal(t, validator.SeverityWarning, p.Severity)
+	assert.Equal(t, FixRepositoryConflicts, p.Fix)
+	assert.Contains(t, p.Message, "rpmfusion-nonfree and negativo17 both provide akmod-nvidia")
+	assert.Equal(t, "Keep rpmfusion-nonfree and disable fedora-nvidia", p.Remediation)
+	assert.Equal(t, "fedora-nvidia", p.Details["disable"])
+
+	t.Run("single source", func(t *testing.T) {
+		pm := &mockPackageManager{repos: pm.repos[:3]}
+		d, _ := newHealthyDoctor(WithPackageManager(pm), WithDistribution(fedora))
+
+		report, err := d.Diagnose(context.Background())
+		require.NoError(t, err)
+
+		assert.True(t, report.Healthy())
+		assert.Contains(t, checkMessages(report), "NVIDIA packages come from rpmfusion-nonfree only")
+	})
+
+	t.Run("listing fails", func(t *testing.T) {
+		d, _ := newHealthyDoctor(WithPackageManager(&mockPackageManager{err: errors.New("boom")}), WithDistribution(fedora))
+
+		report, err := d.Diagnose(context.Background())
+		require.NoError(t, err)
+
+		require.Equal(t, []validator.CheckName{CheckRepositoryConflicts}, problemNames(report))
+		assert.False(t, report.Problems[0].Fixable())
+	})
+
+	t.Run("skipped without package manager", func(t *testing.T) {
+		d, _ := newHealthyDoctor(WithDistribution(fedora))
+
+		report, err := d.Diagnose(context.Background())
+		require.NoError(t, err)
+
+		for _, c := range report.Checks {
+			assert.NotEqual(t, CheckRepositoryConflicts, c.Name)
+		}
+	})
+}
+
+func checkMessages(report *Report) []string {
+	messages := make([]string, 0, len(report.Checks))
+	for _, c := range report.Checks {
+		messages = append(messages, c.Message)
+	}
+	return messages
+}
+
+func TestDoctor_Diagnose_Errors(t *testing.T) {
+	t.Run("no executor", func(t *testing.T) {
+		_, err := NewDoctor().Diagnose(context.Background())
+		require.Error(t, err)
+	})
+
+	t.Run("validator error", func(t *testing.T) {
+		d, _ := newHealthyDoctor(WithValidator(&mockValidator{err: errors.New("boom")}))
+		_, err := d.Diagnose(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "boom")
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		d, _ := newHealthyDoctor()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := d.Diagnose(ctx)
+		require.Error(t, err)
+	})
+}
+
+// =============================================================================
+// Fix Workflow Tests
+// =============================================================================
+
+func TestNewFixWorkflow(t *testing.T) {
+	problem := func(name validator.CheckName, fix FixAction, kernel string) Problem {
+		result := validator.NewCheckResult(name, false, "", validator.SeverityError)
+		if kernel != "" {
+			result.WithDetail("kernel", kernel).WithDetail("version", "550.54.14")
+		}
+		return Problem{CheckResult: *result, Fix: fix}
+	}
+
+	workflow := NewFixWorkflow([]Problem{
+		problem(CheckXorgConfig, FixXorgConfig, ""),
+		problem(CheckModuleLoaded, FixModuleLoad, ""),
+		problem(CheckDKMSStatus, FixDKMSBuild, "6.8.0-45-generic"),
+		problem(CheckDKMSStatus, FixDKMSBuild, "6.8.0-40-generic"),
+		problem(CheckNvidiaSmi, FixNone, ""),
+		problem(validator.CheckNouveauStatus, FixNouveauBlacklist, ""),
+		problem(CheckRepositoryConflicts, FixRepositoryConflicts, ""),
+	}, nil)
+
+	names := make([]string, 0)
+	for _, s := range workflow.Steps() {
+		names = append(names, s.Name())
+	}
+	assert.Equal(t, []string{"repository_conflicts", "nouveau_blacklist", "dkms_build", "dkms_build", "module_load", "xorg_config"}, names)
+}
+
+func TestNewFixWorkflow_NothingFixable(t *testing.T) {
+	workflow := NewFixWorkflow([]Problem{{CheckResult: *validator.NewCheckResult(CheckGPUDetected, false, "", validator.SeverityError)}}, nil)
+	assert.Empty(t, workflow.Steps())
+}
@@ -0,0 +1,78 @@
+package doctor
+
+import (
+	"sort"
+
+	"github.com/tungetti/igor/internal/gpu/kernel"
+	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/install/steps"
+)
+
+// fixOrder is the order in which fixes run. It follows the installation
+// workflow: repositories are set up first, and modules must be built before
+// they can be loaded.
+var fixOrder = []FixAction{
+	FixRepositoryConflicts,
+	FixNouveauBlacklist,
+	FixDKMSBuild,
+	FixModuleLoad,
+	FixXorgConfig,
+}
+
+// NewFixWorkflow creates a workflow running the installation steps that
+// repair the given problems. Each step is added once, except the DKMS build
+// which runs once per affected kernel. Problems that are not fixable are
+// ignored; the workflow has no steps when none of them is.
+func NewFixWorkflow(problems []Problem, kernelDetector kernel.Detector) install.Workflow {
+	needed := make(map[FixAction]bool)
+	dkms := make(map[string]string) // kernel -> module version
+	for _, p := range problems {
+		if !p.Fixable() {
+			continue
+		}
+		needed[p.Fix] = true
+		if p.Fix == FixDKMSBuild {
+			dkms[p.Kernel()] = p.Details["version"]
+		}
+	}
+
+	workflow := install.NewWorkflow("nvidia-doctor-fix")
+	for _, fix := range fixOrder {
+		if !needed[fix] {
+			continue
+		}
+
+		switch fix {
+		case FixRepositoryConflicts:
+			workflow.AddStep(steps.NewRepositoryConflictStep())
+		case FixNouveauBlacklist:
+			workflow.AddStep(steps.NewNouveauBlacklistStep())
+		case FixDKMSBuild:
+			kernels := make([]string, 0, len(dkms))
+			for k := range dkms {
+				kernels = append(kernels, k)
+			}
+			sort.Strings(kernels)
+			for _, k := range kernels {
+				opts := []steps.DKMSBuildStepOption{steps.WithKernelVersion(k)}
+				if version := dkms[k]; version != "" {
+					opts = append(opts, steps.WithModuleVersion(version))
+				}
+				if kernelDetector != nil {
+					opts = append(opts, steps.WithKernelDetector(kernelDetector))
+				}
+				workflow.AddStep(steps.NewDKMSBuildStep(opts...))
+			}
+		case FixModuleLoad:
+			var opts []steps.ModuleLoadStepOption
+			if kernelDetector != nil {
+				opts = append(opts, steps.WithModuleKernelDetector(kernelDetector))
+			}
+			workflow.AddStep(steps.NewModuleLoadStep(opts...))
+		case FixXorgConfig:
+			workflow.AddStep(steps.NewXorgConfigStep())
+		}
+	}
+
+	return workflow
+}
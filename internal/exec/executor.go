@@ -24,8 +24,14 @@ type Executor interface {
 	// ExecuteWithInput runs a command with stdin input.
 	ExecuteWithInput(ctx context.Context, input []byte, cmd string, args ...string) *Result
 
+	// ExecuteElevatedWithInput runs a command with root privileges and stdin input.
+	ExecuteElevatedWithInput(ctx context.Context, input []byte, cmd string, args ...string) *Result
+
 	// Stream runs a command and streams output to writers.
 	Stream(ctx context.Context, stdout, stderr io.Writer, cmd string, args ...string) *Result
+
+	// StreamElevated runs a command with root privileges and streams output to writers.
+	StreamElevated(ctx context.Context, stdout, stderr io.Writer, cmd string, args ...string) *Result
 }
 
 // Options configures the executor behavior.
@@ -77,6 +83,11 @@ func (e *RealExecutor) ExecuteWithInput(ctx context.Context, input []byte, cmd s
 	return e.execute(ctx, input, cmd, args, false)
 }
 
+// ExecuteElevatedWithInput runs a command with root privileges and stdin input.
+func (e *RealExecutor) ExecuteElevatedWithInput(ctx context.Context, input []byte, cmd string, args ...string) *Result {
+	return e.execute(ctx, input, cmd, args, true)
+}
+
 // Stream runs a command and streams output to writers.
 func (e *RealExecutor) Stream(ctx context.Context, stdout, stderr io.Writer, cmd string, args ...string) *Result {
 	return e.stream(ctx, stdout, stderr, cmd, args, false)
@@ -600,6 +600,19 @@ func TestMockExecutor_ExecuteWithInput(t *testing.T) {
 	assert.Equal(t, input, calls[0].Input)
 }
 
+func TestMockExecutor_ExecuteElevatedWithInput(t *testing.T) {
+	mock := NewMockExecutor()
+	ctx := context.Background()
+	input := []byte("test input")
+
+	mock.ExecuteElevatedWithInput(ctx, input, "cmd")
+
+	calls := mock.Calls()
+	require.Len(t, calls, 1)
+	assert.True(t, calls[0].Elevated)
+	assert.Equal(t, input, calls[0].Input)
+}
+
 func TestMockExecutor_SetResponse(t *testing.T) {
 	mock := NewMockExecutor()
 	ctx := context.Background()
@@ -662,6 +675,20 @@ func TestMockExecutor_Stream(t *testing.T) {
 	assert.Equal(t, "stdout content", result.StdoutString())
 }
 
+func TestMockExecutor_StreamElevated(t *testing.T) {
+	mock := NewMockExecutor()
+	ctx := context.Background()
+
+	mock.SetResponse("dnf", &Result{Stdout: []byte("Installing : cuda 1/1\n")})
+
+	var stdout bytes.Buffer
+	result := mock.StreamElevated(ctx, &stdout, nil, "dnf", "install", "-y", "cuda")
+
+	assert.Equal(t, "Installing : cuda 1/1\n", stdout.String())
+	assert.True(t, result.Success())
+	assert.True(t, mock.LastCall().Elevated)
+}
+
 func TestMockExecutor_Stream_NilWriters(t *testing.T) {
 	mock := NewMockExecutor()
 	ctx := context.Background()
@@ -139,9 +139,24 @@ func (m *MockExecutor) ExecuteWithInput(ctx context.Context, input []byte, cmd s
 	return m.record(cmd, args, false, input)
 }
 
+// ExecuteElevatedWithInput implements Executor.
+func (m *MockExecutor) ExecuteElevatedWithInput(ctx context.Context, input []byte, cmd string, args ...string) *Result {
+	return m.record(cmd, args, true, input)
+}
+
 // Stream implements Executor.
 func (m *MockExecutor) Stream(ctx context.Context, stdout, stderr io.Writer, cmd string, args ...string) *Result {
-	result := m.record(cmd, args, false, nil)
+	return m.stream(stdout, stderr, cmd, args, false)
+}
+
+// StreamElevated implements Executor.
+func (m *MockExecutor) StreamElevated(ctx context.Context, stdout, stderr io.Writer, cmd string, args ...string) *Result {
+	return m.stream(stdout, stderr, cmd, args, true)
+}
+
+// stream records a streamed command and writes its response to the writers.
+func (m *MockExecutor) stream(stdout, stderr io.Writer, cmd string, args []string, elevated bool) *Result {
+	result := m.record(cmd, args, elevated, nil)
 	if stdout != nil && result.Stdout != nil {
 		stdout.Write(result.Stdout)
 	}
@@ -0,0 +1,120 @@
+package exec
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// RecordedCommand is a command captured by a RecordingExecutor.
+type RecordedCommand struct {
+	Command  string   // The command that was called
+	Args     []string // The arguments passed
+	Elevated bool     // Whether the command would run with root privileges
+	Input    []byte   // The input provided (for ExecuteWithInput)
+	Probe    bool     // Whether the command is a read-only query that was run
+}
+
+// RecordingExecutor is an Executor that records the commands that would
+// change the system instead of running them. It is used to preview what a
+// workflow would do.
+//
+// Non-elevated Execute calls are read-only queries (which, uname, lsmod,
+// dpkg-query, ...) by convention in Igor; they are passed to the delegate so
+// steps see the real system, and are recorded as probes. Every other call,
+// including Execute calls that go through sudo, is recorded and reported as
+// successful without being run. It is safe for concurrent use.
+type RecordingExecutor struct {
+	mu       sync.Mutex
+	delegate Executor
+	commands []RecordedCommand
+}
+
+// NewRecordingExecutor creates a new recording executor. Probes are run with
+// delegate; if delegate is nil, probes are not run and succeed with no output.
+func NewRecordingExecutor(delegate Executor) *RecordingExecutor {
+	return &RecordingExecutor{delegate: delegate}
+}
+
+// Commands returns all recorded commands, probes included.
+// Returns a copy of the slice to prevent external modification.
+func (r *RecordingExecutor) Commands() []RecordedCommand {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedCommand{}, r.commands...)
+}
+
+// Reset clears all recorded commands.
+func (r *RecordingExecutor) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands = nil
+}
+
+// Execute runs read-only queries with the delegate and records calls
+// through sudo without running them.
+func (r *RecordingExecutor) Execute(ctx context.Context, cmd string, args ...string) *Result {
+	if cmd == "sudo" {
+		return r.record(cmd, args, true, nil)
+	}
+
+	r.add(RecordedCommand{Command: cmd, Args: args, Probe: true})
+
+	if r.delegate == nil {
+		return SuccessResult("")
+	}
+	return r.delegate.Execute(ctx, cmd, args...)
+}
+
+// ExecuteElevated records the command without running it.
+func (r *RecordingExecutor) ExecuteElevated(ctx context.Context, cmd string, args ...string) *Result {
+	return r.record(cmd, args, true, nil)
+}
+
+// ExecuteWithInput records the command and its input without running it.
+func (r *RecordingExecutor) ExecuteWithInput(ctx context.Context, input []byte, cmd string, args ...string) *Result {
+	return r.record(cmd, args, cmd == "sudo", input)
+}
+
+// ExecuteElevatedWithInput records the command and its input without
+// running it.
+func (r *RecordingExecutor) ExecuteElevatedWithInput(ctx context.Context, input []byte, cmd string, args ...string) *Result {
+	return r.record(cmd, args, true, input)
+}
+
+// Stream records the command without running it; nothing is written to
+// stdout or stderr.
+func (r *RecordingExecutor) Stream(ctx context.Context, stdout, stderr io.Writer, cmd string, args ...string) *Result {
+	return r.record(cmd, args, cmd == "sudo", nil)
+}
+
+// StreamElevated records the command without running it; nothing is
+// written to stdout or stderr.
+func (r *RecordingExecutor) StreamElevated(ctx context.Context, stdout, stderr io.Writer, cmd string, args ...string) *Result {
+	return r.record(cmd, args, true, nil)
+}
+
+// record records a command that is not run and returns a successful result.
+func (r *RecordingExecutor) record(cmd string, args []string, elevated bool, input []byte) *Result {
+	r.add(RecordedCommand{
+		Command:  cmd,
+		Args:     append([]string{}, args...),
+		Elevated: elevated,
+		Input:    append([]byte(nil), input...),
+	})
+
+	result := SuccessResult("")
+	result.Command = cmd
+	result.Args = args
+	return result
+}
+
+// add appends a recorded command.
+func (r *RecordingExecutor) add(c RecordedCommand) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands = append(r.commands, c)
+}
+
+// Ensure RecordingExecutor implements Executor.
+var _ Executor = (*RecordingExecutor)(nil)
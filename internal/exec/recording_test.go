@@ -0,0 +1,77 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordingExecutor_ProbesRunWithDelegate(t *testing.T) {
+	delegate := NewMockExecutor()
+	delegate.SetResponse("uname", SuccessResult("6.8.0-45-generic\n"))
+
+	r := NewRecordingExecutor(delegate)
+
+	result := r.Execute(context.Background(), "uname", "-r")
+	assert.Equal(t, "6.8.0-45-generic\n", result.StdoutString())
+	assert.True(t, delegate.WasCalledWith("uname", "-r"))
+
+	commands := r.Commands()
+	require.Len(t, commands, 1)
+	assert.Equal(t, RecordedCommand{Command: "uname", Args: []string{"-r"}, Probe: true}, commands[0])
+}
+
+func TestRecordingExecutor_ProbesWithoutDelegate(t *testing.T) {
+	r := NewRecordingExecutor(nil)
+
+	result := r.Execute(context.Background(), "lsmod")
+	assert.True(t, result.Success())
+	assert.Empty(t, result.Stdout)
+	assert.True(t, r.Commands()[0].Probe)
+}
+
+func TestRecordingExecutor_RecordsChangesWithoutRunning(t *testing.T) {
+	delegate := NewMockExecutor()
+	delegate.SetDefaultResponse(FailureResult(1, "should not run"))
+
+	r := NewRecordingExecutor(delegate)
+	ctx := context.Background()
+
+	assert.True(t, r.ExecuteElevated(ctx, "apt-get", "install", "-y", "nvidia-driver-550").Success())
+	assert.True(t, r.Execute(ctx, "sudo", "rm", "-f", "/etc/apt/sources.list.d/nvidia.list").Success())
+
+	assert.True(t, r.ExecuteWithInput(ctx, []byte("Section \"Device\"\n"), "tee", "/etc/X11/xorg.conf.d/20-nvidia.conf").Success())
+	assert.True(t, r.ExecuteWithInput(ctx, []byte("deb ..."), "sudo", "tee", "/etc/apt/sources.list.d/cuda.list").Success())
+	assert.True(t, r.ExecuteElevatedWithInput(ctx, []byte("mok_signing_key=..."), "tee", "/etc/dkms/framework.conf.d/igor.conf").Success())
+
+	var stdout, stderr bytes.Buffer
+	assert.True(t, r.Stream(ctx, &stdout, &stderr, "dkms", "build", "nvidia/550").Success())
+	assert.Empty(t, stdout.String())
+	assert.True(t, r.StreamElevated(ctx, &stdout, &stderr, "dnf", "install", "-y", "cuda").Success())
+	assert.Empty(t, stdout.String())
+
+	assert.Equal(t, 0, delegate.CallCount())
+	assert.Equal(t, []RecordedCommand{
+		{Command: "apt-get", Args: []string{"install", "-y", "nvidia-driver-550"}, Elevated: true},
+		{Command: "sudo", Args: []string{"rm", "-f", "/etc/apt/sources.list.d/nvidia.list"}, Elevated: true},
+		{Command: "tee", Args: []string{"/etc/X11/xorg.conf.d/20-nvidia.conf"}, Input: []byte("Section \"Device\"\n")},
+		{Command: "sudo", Args: []string{"tee", "/etc/apt/sources.list.d/cuda.list"}, Elevated: true, Input: []byte("deb ...")},
+		{Command: "tee", Args: []string{"/etc/dkms/framework.conf.d/igor.conf"}, Elevated: true, Input: []byte("mok_signing_key=...")},
+		{Command: "dkms", Args: []string{"build", "nvidia/550"}},
+		{Command: "dnf", Args: []string{"install", "-y", "cuda"}, Elevated: true},
+	}, r.Commands())
+}
+
+func TestRecordingExecutor_Reset(t *testing.T) {
+	r := NewRecordingExecutor(nil)
+	r.ExecuteElevated(context.Background(), "apt-get", "update")
+
+	r.Reset()
+	assert.Empty(t, r.Commands())
+
+	r.ExecuteElevated(context.Background(), "apt-get", "update")
+	assert.Len(t, r.Commands(), 1)
+}
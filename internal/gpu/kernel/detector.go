@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/tungetti/igor/internal/constants"
@@ -74,6 +75,29 @@ type Detector interface {
 	IsSecureBootEnabled(ctx context.Context) (bool, error)
 }
 
+// InstalledKernel represents a kernel installed on the system, which may not
+// be the running one.
+type InstalledKernel struct {
+	// Version is the kernel version, the name of its /lib/modules directory.
+	Version string
+
+	// HeadersPath is the path to the kernel headers directory.
+	HeadersPath string
+
+	// HeadersInstalled indicates whether kernel headers are installed.
+	HeadersInstalled bool
+
+	// HeadersPackage is the package providing the kernel headers.
+	HeadersPackage string
+}
+
+// InstalledKernelDetector is implemented by detectors that can list the
+// installed kernels, such as a newer kernel staged for the next reboot.
+type InstalledKernelDetector interface {
+	// GetInstalledKernels returns the kernels installed under /lib/modules.
+	GetInstalledKernels(ctx context.Context) ([]InstalledKernel, error)
+}
+
 // FileSystem abstracts filesystem operations for testing.
 type FileSystem interface {
 	// ReadDir reads the directory named by dirname and returns a list of directory entries.
@@ -397,12 +421,14 @@ func (d *DetectorImpl) AreHeadersInstalled(ctx context.Context) (bool, error) {
 	return installed, nil
 }
 
-// GetHeadersPackage returns the package name for kernel headers based on distribution.
-// Package names vary by distribution family:
+// GetHeadersPackage returns the package name for kernel headers based on
+// distribution and the flavor of the running kernel, from the package owning
+// the kernel image (see GetKernelPackage). Package names vary by
+// distribution family:
 //   - Debian/Ubuntu: linux-headers-$(uname -r)
-//   - Fedora/RHEL: kernel-devel-$(uname -r)
-//   - Arch: linux-headers
-//   - openSUSE: kernel-default-devel
+//   - Fedora/RHEL: kernel-devel-$(uname -r), or kernel-rt-devel, kernel-64k-devel...
+//   - Arch: linux-headers, or linux-lts-headers, linux-zen-headers...
+//   - openSUSE: kernel-default-devel, or kernel-preempt-devel...
 func (d *DetectorImpl) GetHeadersPackage(ctx context.Context) (string, error) {
 	const op = "kernel.GetHeadersPackage"
 
@@ -411,29 +437,60 @@ func (d *DetectorImpl) GetHeadersPackage(ctx context.Context) (string, error) {
 		return "", errors.Wrap(errors.GPUDetection, "failed to get kernel version for headers package", err).WithOp(op)
 	}
 
-	return d.getHeadersPackageForVersion(version), nil
+	return d.getKernelPackageForVersion(ctx, version).HeadersPackage, nil
 }
 
-// getHeadersPackageForVersion returns the headers package name for a given kernel version.
-func (d *DetectorImpl) getHeadersPackageForVersion(kernelVersion string) string {
-	switch d.distroFamily {
-	case constants.FamilyDebian:
-		return "linux-headers-" + kernelVersion
-	case constants.FamilyRHEL:
-		return "kernel-devel-" + kernelVersion
-	case constants.FamilyArch:
-		// Arch uses a generic package name, but may need linux-lts-headers for LTS kernel
-		if strings.Contains(kernelVersion, "lts") {
-			return "linux-lts-headers"
-		}
-		return "linux-headers"
-	case constants.FamilySUSE:
-		// openSUSE uses kernel-<flavor>-devel, default is most common
-		return "kernel-default-devel"
+// kernelModulesDir holds the kernel's own modules in its /lib/modules
+// directory. Directories left behind by removed kernels only hold
+// out-of-tree modules, such as those built by DKMS, and are ignored.
+const kernelModulesDir = "kernel"
+
+// GetInstalledKernels returns the kernels installed under /lib/modules,
+// sorted by name, with the availability of their headers.
+func (d *DetectorImpl) GetInstalledKernels(ctx context.Context) ([]InstalledKernel, error) {
+	const op = "kernel.GetInstalledKernels"
+
+	// Check context cancellation
+	select {
+	case <-ctx.Done():
+		return nil, errors.Wrap(errors.GPUDetection, "installed kernels detection cancelled", ctx.Err()).WithOp(op)
 	default:
-		// Generic fallback
-		return "linux-headers-" + kernelVersion
 	}
+
+	entries, err := d.fs.ReadDir(d.modulesBuildPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.Wrap(errors.NotFound, "kernel modules directory not found", err).WithOp(op)
+		}
+		return nil, errors.Wrap(errors.GPUDetection, "failed to read kernel modules directory", err).WithOp(op)
+	}
+
+	var kernels []InstalledKernel
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		version := entry.Name()
+		if _, err := d.fs.Stat(filepath.Join(d.modulesBuildPath, version, kernelModulesDir)); err != nil {
+			continue
+		}
+
+		headersInstalled, headersPath, err := d.checkHeadersInstalled(ctx, version)
+		if err != nil {
+			return nil, err
+		}
+		kernels = append(kernels, InstalledKernel{
+			Version:          version,
+			HeadersPath:      headersPath,
+			HeadersInstalled: headersInstalled,
+			HeadersPackage:   d.getKernelPackageForVersion(ctx, version).HeadersPackage,
+		})
+	}
+
+	sort.Slice(kernels, func(i, j int) bool {
+		return kernels[i].Version < kernels[j].Version
+	})
+	return kernels, nil
 }
 
 // IsSecureBootEnabled checks if Secure Boot is enabled.
@@ -539,3 +596,6 @@ func (d *DetectorImpl) checkSecureBootViaEFI(ctx context.Context) (bool, error)
 
 // Ensure DetectorImpl implements Detector interface.
 var _ Detector = (*DetectorImpl)(nil)
+
+// Ensure DetectorImpl implements InstalledKernelDetector interface.
+var _ InstalledKernelDetector = (*DetectorImpl)(nil)
@@ -758,18 +758,91 @@ func TestModuleStates(t *testing.T) {
 	assert.Equal(t, "Unloading", ModuleStateUnloading)
 }
 
-// TestGetHeadersPackageForVersion tests internal headers package resolution.
-func TestGetHeadersPackageForVersion(t *testing.T) {
+// TestGetHeadersPackage_ImageOwner tests headers package resolution from
+// the package owning the running kernel image.
+func TestGetHeadersPackage_ImageOwner(t *testing.T) {
 	t.Run("Debian with version", func(t *testing.T) {
-		detector := NewDetector(WithDistroFamily(constants.FamilyDebian))
-		pkg := detector.getHeadersPackageForVersion("5.15.0-91-generic")
+		mockExec := exec.NewMockExecutor()
+		mockExec.SetResponse("uname", exec.SuccessResult("5.15.0-91-generic\n"))
+		mockExec.SetResponse("dpkg", exec.SuccessResult("linux-image-5.15.0-91-generic: /boot/vmlinuz-5.15.0-91-generic\n"))
+		mockFS := NewMockFileSystem()
+		mockFS.AddFile("/boot/vmlinuz-5.15.0-91-generic", []byte{})
+
+		detector := NewDetector(WithExecutor(mockExec), WithFileSystem(mockFS), WithDistroFamily(constants.FamilyDebian))
+		pkg, err := detector.GetHeadersPackage(context.Background())
+
+		require.NoError(t, err)
 		assert.Equal(t, "linux-headers-5.15.0-91-generic", pkg)
+		assert.True(t, mockExec.WasCalledWith("dpkg", "-S", "/boot/vmlinuz-5.15.0-91-generic"))
 	})
 
 	t.Run("RHEL with version", func(t *testing.T) {
-		detector := NewDetector(WithDistroFamily(constants.FamilyRHEL))
-		pkg := detector.getHeadersPackageForVersion("5.14.0-284.el9.x86_64")
+		mockExec := exec.NewMockExecutor()
+		mockExec.SetResponse("uname", exec.SuccessResult("5.14.0-284.el9.x86_64\n"))
+		mockExec.SetResponse("rpm", exec.SuccessResult("kernel-core\n"))
+		mockFS := NewMockFileSystem()
+		mockFS.AddFile("/lib/modules/5.14.0-284.el9.x86_64/vmlinuz", []byte{})
+
+		detector := NewDetector(WithExecutor(mockExec), WithFileSystem(mockFS), WithDistroFamily(constants.FamilyRHEL))
+		pkg, err := detector.GetHeadersPackage(context.Background())
+
+		require.NoError(t, err)
 		assert.Equal(t, "kernel-devel-5.14.0-284.el9.x86_64", pkg)
+		assert.True(t, mockExec.WasCalledWith("rpm", "-qf", "--qf", "%{NAME}\n", "/lib/modules/5.14.0-284.el9.x86_64/vmlinuz"))
+	})
+}
+
+// TestDetectorGetInstalledKernels tests GetInstalledKernels method.
+func TestDetectorGetInstalledKernels(t *testing.T) {
+	t.Run("kernels with and without headers", func(t *testing.T) {
+		mockFS := NewMockFileSystem()
+		for _, version := range []string{"6.8.0-47-generic", "6.8.0-45-generic", "6.5.0-44-generic"} {
+			mockFS.AddDirEntry("/lib/modules", &mockDirEntry{name: version, isDir: true})
+		}
+		mockFS.AddDir("/lib/modules/6.8.0-45-generic/kernel")
+		mockFS.AddDir("/lib/modules/6.8.0-47-generic/kernel")
+		mockFS.AddDir("/usr/src/linux-headers-6.8.0-45-generic")
+		// Leftover of a removed kernel, with only the modules built by DKMS
+		mockFS.AddDir("/lib/modules/6.5.0-44-generic/updates")
+
+		detector := NewDetector(
+			WithFileSystem(mockFS),
+			WithDistroFamily(constants.FamilyDebian),
+		)
+
+		kernels, err := detector.GetInstalledKernels(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, []InstalledKernel{
+			{
+				Version:          "6.8.0-45-generic",
+				HeadersPath:      "/usr/src/linux-headers-6.8.0-45-generic",
+				HeadersInstalled: true,
+				HeadersPackage:   "linux-headers-6.8.0-45-generic",
+			},
+			{
+				Version:        "6.8.0-47-generic",
+				HeadersPackage: "linux-headers-6.8.0-47-generic",
+			},
+		}, kernels)
+	})
+
+	t.Run("no modules directory", func(t *testing.T) {
+		detector := NewDetector(WithFileSystem(NewMockFileSystem()))
+
+		kernels, err := detector.GetInstalledKernels(context.Background())
+
+		assert.Error(t, err)
+		assert.Nil(t, kernels)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := NewDetector(WithFileSystem(NewMockFileSystem())).GetInstalledKernels(ctx)
+
+		assert.Error(t, err)
 	})
 }
 
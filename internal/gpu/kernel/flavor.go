@@ -0,0 +1,217 @@
+package kernel
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/errors"
+)
+
+// KernelPackage is the package of a kernel, with the flavor of the kernel and
+// the package providing its headers.
+type KernelPackage struct {
+	// Version is the kernel version.
+	Version string
+
+	// Name is the package owning the kernel image, such as
+	// "linux-image-6.8.0-1012-azure", "kernel-rt-core", "linux-zen" or
+	// "kernel-preempt". If the owner cannot be queried, it is inferred from
+	// the kernel version (e.g., "linux-lts" for "6.6.22-1-lts").
+	Name string
+
+	// Flavor is the kernel flavor, such as "generic", "azure", "rt", "zen",
+	// "lts" or "default". It is empty for the stock kernel of RHEL and Arch.
+	Flavor string
+
+	// HeadersPackage is the package providing the kernel headers, such as
+	// "kernel-rt-devel-<version>" or "linux-zen-headers".
+	HeadersPackage string
+}
+
+// KernelPackageDetector is implemented by detectors that can tell the
+// package of the running kernel, and so its flavor.
+type KernelPackageDetector interface {
+	// GetKernelPackage returns the package owning the running kernel image.
+	GetKernelPackage(ctx context.Context) (*KernelPackage, error)
+}
+
+// GetKernelPackage returns the package owning the running kernel image, as
+// reported by dpkg -S, rpm -qf or pacman -Qo, with the flavor of the kernel
+// and the matching headers package.
+func (d *DetectorImpl) GetKernelPackage(ctx context.Context) (*KernelPackage, error) {
+	const op = "kernel.GetKernelPackage"
+
+	version, err := d.getKernelVersion(ctx)
+	if err != nil {
+		return nil, errors.Wrap(errors.GPUDetection, "failed to get kernel version for kernel package", err).WithOp(op)
+	}
+
+	return d.getKernelPackageForVersion(ctx, version), nil
+}
+
+// getKernelPackageForVersion returns the package of a kernel version, from
+// the owner of its image if known, or inferred from the version otherwise.
+func (d *DetectorImpl) getKernelPackageForVersion(ctx context.Context, kernelVersion string) *KernelPackage {
+	return resolveKernelPackage(d.distroFamily, kernelVersion, d.getKernelImageOwner(ctx, kernelVersion))
+}
+
+// kernelImagePaths returns where the image of a kernel version may be, the
+// most likely location for the distribution family first.
+func (d *DetectorImpl) kernelImagePaths(kernelVersion string) []string {
+	modulesImage := filepath.Join(d.modulesBuildPath, kernelVersion, "vmlinuz")
+	bootImage := "/boot/vmlinuz-" + kernelVersion
+	if d.distroFamily == constants.FamilyDebian {
+		return []string{bootImage, modulesImage}
+	}
+	return []string{modulesImage, bootImage}
+}
+
+// getKernelImageOwner returns the package owning the image of a kernel
+// version, or an empty string if the image or its owner is not found.
+func (d *DetectorImpl) getKernelImageOwner(ctx context.Context, kernelVersion string) string {
+	if d.executor == nil {
+		return ""
+	}
+
+	image := ""
+	for _, path := range d.kernelImagePaths(kernelVersion) {
+		if _, err := d.fs.Stat(path); err == nil {
+			image = path
+			break
+		}
+	}
+	if image == "" {
+		return ""
+	}
+
+	var args []string
+	switch d.distroFamily {
+	case constants.FamilyDebian:
+		args = []string{"dpkg", "-S", image}
+	case constants.FamilyRHEL, constants.FamilySUSE:
+		args = []string{"rpm", "-qf", "--qf", "%{NAME}\n", image}
+	case constants.FamilyArch:
+		args = []string{"pacman", "-Qoq", image}
+	default:
+		return ""
+	}
+
+	result := d.executor.Execute(ctx, args[0], args[1:]...)
+	if result.ExitCode != 0 {
+		return ""
+	}
+	return parseImageOwner(result.StdoutString())
+}
+
+// parseImageOwner returns the package from the output of dpkg -S, such as
+// "linux-image-6.8.0-45-generic: /boot/vmlinuz-6.8.0-45-generic", or of
+// rpm -qf and pacman -Qoq, which print the package name alone.
+func parseImageOwner(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "diversion by") {
+			continue
+		}
+		if i := strings.Index(line, ": "); i >= 0 {
+			line = line[:i]
+		}
+		// dpkg lists every package owning the file, with its architecture
+		// for Multi-Arch packages
+		line = strings.TrimSpace(strings.Split(line, ",")[0])
+		if i := strings.Index(line, ":"); i >= 0 {
+			line = line[:i]
+		}
+		return line
+	}
+	return ""
+}
+
+// debianFlavorPattern matches the flavor of Debian and Ubuntu kernel
+// versions, after the ABI number, such as "azure" in "6.8.0-1012-azure" or
+// "cloud-amd64" in "6.1.0-18-cloud-amd64".
+var debianFlavorPattern = regexp.MustCompile(`^[\d.]+-[\d.]+-(.+)$`)
+
+// versionFlavor returns the last alphabetic part of a kernel version, which
+// names the flavor on Arch ("6.8.1-zen1-1-zen") and openSUSE
+// ("6.4.0-150600.23.7-default"), or an empty string for a stock kernel.
+func versionFlavor(kernelVersion string) string {
+	parts := strings.FieldsFunc(kernelVersion, func(r rune) bool { return r == '-' || r == '.' })
+	for i := len(parts) - 1; i >= 0; i-- {
+		if strings.IndexFunc(parts[i], func(r rune) bool { return !unicode.IsLetter(r) }) < 0 {
+			return parts[i]
+		}
+	}
+	return ""
+}
+
+// resolveKernelPackage returns the package, flavor and headers package of a
+// kernel version, from the package owning its image if known:
+//   - Debian/Ubuntu: linux-headers-<version>, whose version names the flavor
+//   - Fedora/RHEL: <package>-devel-<version>, such as kernel-rt-devel for kernel-rt-core
+//   - Arch: <package>-headers, such as linux-zen-headers for linux-zen
+//   - openSUSE: <package>-devel, such as kernel-preempt-devel for kernel-preempt
+func resolveKernelPackage(family constants.DistroFamily, kernelVersion, owner string) *KernelPackage {
+	kp := &KernelPackage{Version: kernelVersion, Name: owner}
+
+	switch family {
+	case constants.FamilyDebian:
+		if match := debianFlavorPattern.FindStringSubmatch(kernelVersion); match != nil {
+			kp.Flavor = match[1]
+		}
+		if kp.Name == "" {
+			kp.Name = "linux-image-" + kernelVersion
+		}
+		kp.HeadersPackage = "linux-headers-" + kernelVersion
+
+	case constants.FamilyRHEL:
+		// Flavors other than the stock kernel end with "+<flavor>", such as
+		// "5.14.0-427.13.1.el9_4.x86_64+rt", which is not part of the
+		// version of their packages
+		release, suffix, hasSuffix := strings.Cut(kernelVersion, "+")
+		base := strings.TrimSuffix(owner, "-core")
+		if base == "" {
+			base = "kernel"
+			if hasSuffix && suffix != "" {
+				base = "kernel-" + suffix
+			}
+			kp.Name = base
+		}
+		kp.Flavor = strings.TrimPrefix(strings.TrimPrefix(base, "kernel"), "-")
+		kp.HeadersPackage = base + "-devel-" + release
+
+	case constants.FamilyArch:
+		if kp.Name == "" {
+			kp.Name = "linux"
+			if flavor := versionFlavor(kernelVersion); flavor != "" {
+				kp.Name = "linux-" + flavor
+			}
+		}
+		kp.Flavor = strings.TrimPrefix(strings.TrimPrefix(kp.Name, "linux"), "-")
+		kp.HeadersPackage = kp.Name + "-headers"
+
+	case constants.FamilySUSE:
+		if kp.Name == "" {
+			kp.Name = "kernel-default"
+			if flavor := versionFlavor(kernelVersion); flavor != "" {
+				kp.Name = "kernel-" + flavor
+			}
+		}
+		// kernel-default-base is a minimal kernel-default
+		base := strings.TrimSuffix(kp.Name, "-base")
+		kp.Flavor = strings.TrimPrefix(base, "kernel-")
+		kp.HeadersPackage = base + "-devel"
+
+	default:
+		// Generic fallback
+		kp.HeadersPackage = "linux-headers-" + kernelVersion
+	}
+
+	return kp
+}
+
+// Ensure DetectorImpl implements KernelPackageDetector.
+var _ KernelPackageDetector = (*DetectorImpl)(nil)
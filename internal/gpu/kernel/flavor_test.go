@@ -0,0 +1,295 @@
+package kernel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/exec"
+)
+
+// TestResolveKernelPackage tests the flavor and headers package of kernels,
+// from the package owning their image or inferred from their version.
+func TestResolveKernelPackage(t *testing.T) {
+	tests := []struct {
+		name          string
+		family        constants.DistroFamily
+		kernelVersion string
+		owner         string
+		expected      KernelPackage
+	}{
+		{
+			name:          "Ubuntu generic",
+			family:        constants.FamilyDebian,
+			kernelVersion: "6.8.0-45-generic",
+			owner:         "linux-image-6.8.0-45-generic",
+			expected:      KernelPackage{Name: "linux-image-6.8.0-45-generic", Flavor: "generic", HeadersPackage: "linux-headers-6.8.0-45-generic"},
+		},
+		{
+			name:          "Ubuntu azure",
+			family:        constants.FamilyDebian,
+			kernelVersion: "6.8.0-1012-azure",
+			expected:      KernelPackage{Name: "linux-image-6.8.0-1012-azure", Flavor: "azure", HeadersPackage: "linux-headers-6.8.0-1012-azure"},
+		},
+		{
+			name:          "Ubuntu unsigned lowlatency",
+			family:        constants.FamilyDebian,
+			kernelVersion: "6.8.0-45-lowlatency",
+			owner:         "linux-image-unsigned-6.8.0-45-lowlatency",
+			expected:      KernelPackage{Name: "linux-image-unsigned-6.8.0-45-lowlatency", Flavor: "lowlatency", HeadersPackage: "linux-headers-6.8.0-45-lowlatency"},
+		},
+		{
+			name:          "Debian cloud",
+			family:        constants.FamilyDebian,
+			kernelVersion: "6.1.0-18-cloud-amd64",
+			expected:      KernelPackage{Name: "linux-image-6.1.0-18-cloud-amd64", Flavor: "cloud-amd64", HeadersPackage: "linux-headers-6.1.0-18-cloud-amd64"},
+		},
+		{
+			name:          "Fedora stock",
+			family:        constants.FamilyRHEL,
+			kernelVersion: "6.8.5-301.fc40.x86_64",
+			owner:         "kernel-core",
+			expected:      KernelPackage{Name: "kernel-core", HeadersPackage: "kernel-devel-6.8.5-301.fc40.x86_64"},
+		},
+		{
+			name:          "RHEL realtime",
+			family:        constants.FamilyRHEL,
+			kernelVersion: "5.14.0-427.13.1.el9_4.x86_64+rt",
+			owner:         "kernel-rt-core",
+			expected:      KernelPackage{Name: "kernel-rt-core", Flavor: "rt", HeadersPackage: "kernel-rt-devel-5.14.0-427.13.1.el9_4.x86_64"},
+		},
+		{
+			name:          "RHEL 64k inferred",
+			family:        constants.FamilyRHEL,
+			kernelVersion: "5.14.0-427.13.1.el9_4.aarch64+64k",
+			expected:      KernelPackage{Name: "kernel-64k", Flavor: "64k", HeadersPackage: "kernel-64k-devel-5.14.0-427.13.1.el9_4.aarch64"},
+		},
+		{
+			name:          "Arch stock inferred",
+			family:        constants.FamilyArch,
+			kernelVersion: "6.8.2-arch2-1",
+			expected:      KernelPackage{Name: "linux", HeadersPackage: "linux-headers"},
+		},
+		{
+			name:          "Arch zen",
+			family:        constants.FamilyArch,
+			kernelVersion: "6.8.1-zen1-1-zen",
+			owner:         "linux-zen",
+			expected:      KernelPackage{Name: "linux-zen", Flavor: "zen", HeadersPackage: "linux-zen-headers"},
+		},
+		{
+			name:          "Arch LTS inferred",
+			family:        constants.FamilyArch,
+			kernelVersion: "6.6.22-1-lts",
+			expected:      KernelPackage{Name: "linux-lts", Flavor: "lts", HeadersPackage: "linux-lts-headers"},
+		},
+		{
+			name:          "Arch hardened inferred",
+			family:        constants.FamilyArch,
+			kernelVersion: "6.8.1-hardened1-1-hardened",
+			expected:      KernelPackage{Name: "linux-hardened", Flavor: "hardened", HeadersPackage: "linux-hardened-headers"},
+		},
+		{
+			name:          "openSUSE default base",
+			family:        constants.FamilySUSE,
+			kernelVersion: "6.4.0-150600.23.7-default",
+			owner:         "kernel-default-base",
+			expected:      KernelPackage{Name: "kernel-default-base", Flavor: "default", HeadersPackage: "kernel-default-devel"},
+		},
+		{
+			name:          "openSUSE preempt inferred",
+			family:        constants.FamilySUSE,
+			kernelVersion: "5.14.21-150500.55.7-preempt",
+			expected:      KernelPackage{Name: "kernel-preempt", Flavor: "preempt", HeadersPackage: "kernel-preempt-devel"},
+		},
+		{
+			name:          "Unknown family",
+			family:        constants.FamilyUnknown,
+			kernelVersion: "6.5.0-44-generic",
+			expected:      KernelPackage{HeadersPackage: "linux-headers-6.5.0-44-generic"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kp := resolveKernelPackage(tt.family, tt.kernelVersion, tt.owner)
+
+			tt.expected.Version = tt.kernelVersion
+			assert.Equal(t, tt.expected, *kp)
+		})
+	}
+}
+
+// TestParseImageOwner tests parsing the package owning the kernel image.
+func TestParseImageOwner(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected string
+	}{
+		{"dpkg", "linux-image-6.8.0-45-generic: /boot/vmlinuz-6.8.0-45-generic\n", "linux-image-6.8.0-45-generic"},
+		{"dpkg multi-arch", "linux-image-6.1.0-18-amd64:amd64: /boot/vmlinuz-6.1.0-18-amd64\n", "linux-image-6.1.0-18-amd64"},
+		{"dpkg diversion", "diversion by foo from: /boot/vmlinuz-6.8.0-45-generic\nlinux-image-6.8.0-45-generic: /boot/vmlinuz-6.8.0-45-generic\n", "linux-image-6.8.0-45-generic"},
+		{"rpm", "kernel-rt-core\n", "kernel-rt-core"},
+		{"pacman", "linux-zen\n", "linux-zen"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseImageOwner(tt.output))
+		})
+	}
+}
+
+// TestDetectorGetKernelPackage tests GetKernelPackage with the package
+// manager owning the kernel image.
+func TestDetectorGetKernelPackage(t *testing.T) {
+	tests := []struct {
+		name          string
+		family        constants.DistroFamily
+		kernelVersion string
+		image         string
+		command       string
+		args          []string
+		output        string
+		expected      KernelPackage
+	}{
+		{
+			name:          "Ubuntu",
+			family:        constants.FamilyDebian,
+			kernelVersion: "6.8.0-1012-azure",
+			image:         "/boot/vmlinuz-6.8.0-1012-azure",
+			command:       "dpkg",
+			args:          []string{"-S", "/boot/vmlinuz-6.8.0-1012-azure"},
+			output:        "linux-image-6.8.0-1012-azure: /boot/vmlinuz-6.8.0-1012-azure\n",
+			expected:      KernelPackage{Name: "linux-image-6.8.0-1012-azure", Flavor: "azure", HeadersPackage: "linux-headers-6.8.0-1012-azure"},
+		},
+		{
+			name:          "RHEL",
+			family:        constants.FamilyRHEL,
+			kernelVersion: "5.14.0-427.13.1.el9_4.x86_64+rt",
+			image:         "/lib/modules/5.14.0-427.13.1.el9_4.x86_64+rt/vmlinuz",
+			command:       "rpm",
+			args:          []string{"-qf", "--qf", "%{NAME}\n", "/lib/modules/5.14.0-427.13.1.el9_4.x86_64+rt/vmlinuz"},
+			output:        "kernel-rt-core\n",
+			expected:      KernelPackage{Name: "kernel-rt-core", Flavor: "rt", HeadersPackage: "kernel-rt-devel-5.14.0-427.13.1.el9_4.x86_64"},
+		},
+		{
+			name:          "Arch",
+			family:        constants.FamilyArch,
+			kernelVersion: "6.8.1-zen1-1-zen",
+			image:         "/lib/modules/6.8.1-zen1-1-zen/vmlinuz",
+			command:       "pacman",
+			args:          []string{"-Qoq", "/lib/modules/6.8.1-zen1-1-zen/vmlinuz"},
+			output:        "linux-zen\n",
+			expected:      KernelPackage{Name: "linux-zen", Flavor: "zen", HeadersPackage: "linux-zen-headers"},
+		},
+		{
+			name:          "openSUSE",
+			family:        constants.FamilySUSE,
+			kernelVersion: "6.4.0-150600.23.7-default",
+			image:         "/boot/vmlinuz-6.4.0-150600.23.7-default",
+			command:       "rpm",
+			args:          []string{"-qf", "--qf", "%{NAME}\n", "/boot/vmlinuz-6.4.0-150600.23.7-default"},
+			output:        "kernel-default\n",
+			expected:      KernelPackage{Name: "kernel-default", Flavor: "default", HeadersPackage: "kernel-default-devel"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockExec := exec.NewMockExecutor()
+			mockExec.SetResponse("uname", exec.SuccessResult(tt.kernelVersion+"\n"))
+			mockExec.SetResponse(tt.command, exec.SuccessResult(tt.output))
+
+			mockFS := NewMockFileSystem()
+			mockFS.AddFile(tt.image, []byte{})
+
+			detector := NewDetector(
+				WithExecutor(mockExec),
+				WithFileSystem(mockFS),
+				WithDistroFamily(tt.family),
+			)
+
+			kp, err := detector.GetKernelPackage(context.Background())
+
+			require.NoError(t, err)
+			tt.expected.Version = tt.kernelVersion
+			assert.Equal(t, tt.expected, *kp)
+			assert.True(t, mockExec.WasCalledWith(tt.command, tt.args...))
+		})
+	}
+
+	t.Run("owner not found", func(t *testing.T) {
+		mockExec := exec.NewMockExecutor()
+		mockExec.SetResponse("uname", exec.SuccessResult("6.6.22-1-lts\n"))
+		mockExec.SetResponse("pacman", exec.FailureResult(1, "error: No package owns /lib/modules/6.6.22-1-lts/vmlinuz"))
+
+		mockFS := NewMockFileSystem()
+		mockFS.AddFile("/lib/modules/6.6.22-1-lts/vmlinuz", []byte{})
+
+		detector := NewDetector(
+			WithExecutor(mockExec),
+			WithFileSystem(mockFS),
+			WithDistroFamily(constants.FamilyArch),
+		)
+
+		kp, err := detector.GetKernelPackage(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "linux-lts", kp.Name)
+		assert.Equal(t, "linux-lts-headers", kp.HeadersPackage)
+	})
+
+	t.Run("no kernel image", func(t *testing.T) {
+		mockExec := exec.NewMockExecutor()
+		mockExec.SetResponse("uname", exec.SuccessResult("6.8.0-45-generic\n"))
+
+		detector := NewDetector(
+			WithExecutor(mockExec),
+			WithFileSystem(NewMockFileSystem()),
+			WithDistroFamily(constants.FamilyDebian),
+		)
+
+		kp, err := detector.GetKernelPackage(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "linux-headers-6.8.0-45-generic", kp.HeadersPackage)
+		assert.False(t, mockExec.WasCalled("dpkg"))
+	})
+
+	t.Run("uname fails", func(t *testing.T) {
+		mockExec := exec.NewMockExecutor()
+		mockExec.SetResponse("uname", exec.FailureResult(1, "uname: error"))
+
+		detector := NewDetector(WithExecutor(mockExec), WithDistroFamily(constants.FamilyArch))
+
+		_, err := detector.GetKernelPackage(context.Background())
+		assert.Error(t, err)
+	})
+}
+
+// TestDetectorGetHeadersPackage_KernelImageOwner tests that the headers
+// package follows the package owning the kernel image.
+func TestDetectorGetHeadersPackage_KernelImageOwner(t *testing.T) {
+	mockExec := exec.NewMockExecutor()
+	mockExec.SetResponse("uname", exec.SuccessResult("6.8.1-arch1-1\n"))
+	mockExec.SetResponse("pacman", exec.SuccessResult("linux-zen\n"))
+
+	mockFS := NewMockFileSystem()
+	mockFS.AddFile("/lib/modules/6.8.1-arch1-1/vmlinuz", []byte{})
+
+	detector := NewDetector(
+		WithExecutor(mockExec),
+		WithFileSystem(mockFS),
+		WithDistroFamily(constants.FamilyArch),
+	)
+
+	pkg, err := detector.GetHeadersPackage(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, "linux-zen-headers", pkg)
+}
@@ -0,0 +1,299 @@
+package gpu
+
+import (
+	stderrors "errors"
+	"sort"
+	"time"
+
+	"github.com/tungetti/igor/internal/errors"
+	"github.com/tungetti/igor/internal/gpu/validator"
+)
+
+// ReportSchemaVersion is the version of the machine-readable detection report.
+// The version is bumped whenever a field is removed or changes meaning; new
+// optional fields may be added without a version change. Consumers should
+// check this value before parsing the rest of the document.
+const ReportSchemaVersion = "1"
+
+// Report is the stable, machine-readable representation of a GPUInfo.
+// It is the document emitted by "igor detect --json".
+//
+// Schema (version 1):
+//
+//	schema_version   string   always "1" for this layout
+//	detected_at      string   RFC 3339 timestamp of the detection run
+//	duration_ms      int      detection wall time in milliseconds
+//	gpus             array    one ReportGPU per NVIDIA GPU (may be empty)
+//	driver           object   ReportDriver, omitted if driver status is unknown
+//	kernel           object   ReportKernel, omitted if kernel detection failed
+//	nouveau          object   ReportNouveau, omitted if nouveau detection failed
+//	validation       object   ReportValidation, omitted if validation did not run
+//	warnings         array    ReportWarning for each non-fatal detector failure
+type Report struct {
+	SchemaVersion string            `json:"schema_version"`
+	DetectedAt    string            `json:"detected_at"`
+	DurationMS    int64             `json:"duration_ms"`
+	GPUs          []ReportGPU       `json:"gpus"`
+	Driver        *ReportDriver     `json:"driver,omitempty"`
+	Kernel        *ReportKernel     `json:"kernel,omitempty"`
+	Nouveau       *ReportNouveau    `json:"nouveau,omitempty"`
+	Validation    *ReportValidation `json:"validation,omitempty"`
+	Warnings      []ReportWarning   `json:"warnings"`
+}
+
+// ReportGPU describes a single NVIDIA GPU in the detection report.
+type ReportGPU struct {
+	// Index is the position of the GPU in detection order, starting at 0.
+	Index int `json:"index"`
+
+	// Name is the resolved marketing name (see NVIDIAGPUInfo.Name).
+	Name string `json:"name"`
+
+	// PCIAddress is the PCI bus address (e.g., "0000:01:00.0").
+	PCIAddress string `json:"pci_address"`
+
+	// VendorID and DeviceID are the lowercase hexadecimal PCI identifiers.
+	VendorID string `json:"vendor_id"`
+	DeviceID string `json:"device_id"`
+
+	// KernelDriver is the kernel driver currently bound to the device, if any.
+	KernelDriver string `json:"kernel_driver,omitempty"`
+
+	// Architecture is the GPU architecture, or "unknown" if not in the database.
+	Architecture string `json:"architecture"`
+
+	// ComputeCapability is the CUDA compute capability, if known.
+	ComputeCapability string `json:"compute_capability,omitempty"`
+
+	// MinDriverVersion is the minimum supported driver version, if known.
+	MinDriverVersion string `json:"min_driver_version,omitempty"`
+
+	// MemoryMiB is the total memory reported by nvidia-smi, if available.
+	MemoryMiB int64 `json:"memory_mib,omitempty"`
+
+	// UUID is the GPU UUID reported by nvidia-smi, if available.
+	UUID string `json:"uuid,omitempty"`
+
+	// DataCenter indicates a data center GPU (H100, A100, etc.).
+	DataCenter bool `json:"data_center"`
+}
+
+// ReportDriver describes the installed driver in the detection report.
+type ReportDriver struct {
+	Installed   bool   `json:"installed"`
+	Type        string `json:"type"`
+	Version     string `json:"version,omitempty"`
+	CUDAVersion string `json:"cuda_version,omitempty"`
+}
+
+// ReportKernel describes the running kernel in the detection report.
+type ReportKernel struct {
+	Version           string `json:"version"`
+	Release           string `json:"release,omitempty"`
+	Architecture      string `json:"architecture,omitempty"`
+	HeadersInstalled  bool   `json:"headers_installed"`
+	HeadersPath       string `json:"headers_path,omitempty"`
+	SecureBootEnabled bool   `json:"secure_boot_enabled"`
+}
+
+// ReportNouveau describes the Nouveau driver status in the detection report.
+type ReportNouveau struct {
+	Loaded         bool     `json:"loaded"`
+	InUse          bool     `json:"in_use"`
+	BoundDevices   []string `json:"bound_devices"`
+	Blacklisted    bool     `json:"blacklisted"`
+	BlacklistFiles []string `json:"blacklist_files"`
+}
+
+// ReportValidation summarizes the system validation in the detection report.
+type ReportValidation struct {
+	Passed   bool          `json:"passed"`
+	Errors   int           `json:"errors"`
+	Warnings int           `json:"warnings"`
+	Checks   []ReportCheck `json:"checks"`
+}
+
+// ReportCheck is a single validation check result in the detection report.
+type ReportCheck struct {
+	Name        string            `json:"name"`
+	Passed      bool              `json:"passed"`
+	Severity    string            `json:"severity"`
+	Message     string            `json:"message"`
+	Remediation string            `json:"remediation,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+}
+
+// ReportWarning is a non-fatal detector failure.
+// Detection continues when individual components fail; each failure is
+// reported here instead of aborting the run.
+type ReportWarning struct {
+	// Code is the error category (e.g., "GPUDetection", "Timeout").
+	Code string `json:"code"`
+
+	// Op is the operation that failed (e.g., "gpu.detectGPUsInternal"), if known.
+	Op string `json:"op,omitempty"`
+
+	// Message is the full error message.
+	Message string `json:"message"`
+}
+
+// NewReport builds a Report from the given GPU information.
+// A nil info produces an empty report with only the schema version set.
+func NewReport(info *GPUInfo) *Report {
+	report := &Report{
+		SchemaVersion: ReportSchemaVersion,
+		GPUs:          make([]ReportGPU, 0),
+		Warnings:      make([]ReportWarning, 0),
+	}
+
+	if info == nil {
+		return report
+	}
+
+	if !info.DetectionTime.IsZero() {
+		report.DetectedAt = info.DetectionTime.UTC().Format(time.RFC3339)
+	}
+	report.DurationMS = info.Duration.Milliseconds()
+
+	for i := range info.NVIDIAGPUs {
+		report.GPUs = append(report.GPUs, newReportGPU(i, &info.NVIDIAGPUs[i]))
+	}
+
+	if info.InstalledDriver != nil {
+		report.Driver = &ReportDriver{
+			Installed:   info.InstalledDriver.Installed,
+			Type:        info.InstalledDriver.Type.String(),
+			Version:     info.InstalledDriver.Version,
+			CUDAVersion: info.InstalledDriver.CUDAVersion,
+		}
+	}
+
+	if info.KernelInfo != nil {
+		report.Kernel = &ReportKernel{
+			Version:           info.KernelInfo.Version,
+			Release:           info.KernelInfo.Release,
+			Architecture:      info.KernelInfo.Architecture,
+			HeadersInstalled:  info.KernelInfo.HeadersInstalled,
+			HeadersPath:       info.KernelInfo.HeadersPath,
+			SecureBootEnabled: info.KernelInfo.SecureBootEnabled,
+		}
+	}
+
+	if info.NouveauStatus != nil {
+		report.Nouveau = &ReportNouveau{
+			Loaded:         info.NouveauStatus.Loaded,
+			InUse:          info.NouveauStatus.InUse,
+			BoundDevices:   nonNilStrings(info.NouveauStatus.BoundDevices),
+			Blacklisted:    info.NouveauStatus.BlacklistExists,
+			BlacklistFiles: nonNilStrings(info.NouveauStatus.BlacklistFiles),
+		}
+	}
+
+	if info.ValidationReport != nil {
+		report.Validation = newReportValidation(info.ValidationReport)
+	}
+
+	for _, err := range info.Errors {
+		if err != nil {
+			report.Warnings = append(report.Warnings, NewReportWarning(err))
+		}
+	}
+
+	// Detectors run concurrently and record their errors as they finish,
+	// so sort the warnings to keep the output stable between runs.
+	sort.Slice(report.Warnings, func(i, j int) bool {
+		a, b := report.Warnings[i], report.Warnings[j]
+		if a.Code != b.Code {
+			return a.Code < b.Code
+		}
+		if a.Op != b.Op {
+			return a.Op < b.Op
+		}
+		return a.Message < b.Message
+	})
+
+	return report
+}
+
+// newReportGPU converts an NVIDIAGPUInfo to its report representation.
+func newReportGPU(index int, g *NVIDIAGPUInfo) ReportGPU {
+	rg := ReportGPU{
+		Index:        index,
+		Name:         g.Name(),
+		PCIAddress:   g.PCIDevice.Address,
+		VendorID:     g.PCIDevice.VendorID,
+		DeviceID:     g.PCIDevice.DeviceID,
+		KernelDriver: g.PCIDevice.Driver,
+		Architecture: g.Architecture(),
+	}
+
+	if g.Model != nil {
+		rg.ComputeCapability = g.Model.ComputeCapability
+		rg.MinDriverVersion = g.Model.MinDriverVersion
+		rg.DataCenter = g.Model.IsDataCenter
+	}
+
+	if g.SMIInfo != nil {
+		rg.MemoryMiB = g.SMIInfo.MemoryTotalMiB
+		rg.UUID = g.SMIInfo.UUID
+	}
+
+	return rg
+}
+
+// newReportValidation converts a validation report to its report representation.
+// Checks are sorted by name so the output is stable across runs.
+func newReportValidation(v *validator.ValidationReport) *ReportValidation {
+	rv := &ReportValidation{
+		Passed:   v.Passed,
+		Errors:   v.ErrorCount(),
+		Warnings: v.WarningCount(),
+		Checks:   make([]ReportCheck, 0, len(v.Checks)),
+	}
+
+	for _, c := range v.Checks {
+		rc := ReportCheck{
+			Name:        c.Name.String(),
+			Passed:      c.Passed,
+			Severity:    c.Severity.String(),
+			Message:     c.Message,
+			Remediation: c.Remediation,
+		}
+		if len(c.Details) > 0 {
+			rc.Details = c.Details
+		}
+		rv.Checks = append(rv.Checks, rc)
+	}
+
+	sort.SliceStable(rv.Checks, func(i, j int) bool {
+		return rv.Checks[i].Name < rv.Checks[j].Name
+	})
+
+	return rv
+}
+
+// NewReportWarning converts a detector error into a structured warning.
+// Errors that are not *errors.Error are reported with the Unknown code.
+func NewReportWarning(err error) ReportWarning {
+	w := ReportWarning{
+		Code:    errors.Unknown.String(),
+		Message: err.Error(),
+	}
+
+	var igorErr *errors.Error
+	if stderrors.As(err, &igorErr) {
+		w.Code = igorErr.Code.String()
+		w.Op = igorErr.Op
+	}
+
+	return w
+}
+
+// nonNilStrings returns s, or an empty slice if s is nil, so that JSON
+// output always contains an array rather than null.
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,233 @@
+package gpu
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/errors"
+	"github.com/tungetti/igor/internal/gpu/kernel"
+	"github.com/tungetti/igor/internal/gpu/nouveau"
+	"github.com/tungetti/igor/internal/gpu/nvidia"
+	"github.com/tungetti/igor/internal/gpu/pci"
+	"github.com/tungetti/igor/internal/gpu/smi"
+	"github.com/tungetti/igor/internal/gpu/validator"
+)
+
+func newTestReportInfo() *GPUInfo {
+	validation := validator.NewValidationReport()
+	validation.AddCheck(validator.NewCheckResult(validator.CheckName("kernel_headers"), false,
+		"kernel headers not installed", validator.SeverityError).
+		WithRemediation("install linux-headers-6.5.0-44-generic"))
+	validation.AddCheck(validator.NewCheckResult(validator.CheckName("disk_space"), true,
+		"sufficient disk space", validator.SeverityInfo))
+
+	return &GPUInfo{
+		NVIDIAGPUs: []NVIDIAGPUInfo{
+			{
+				PCIDevice: pci.PCIDevice{
+					Address:  "0000:01:00.0",
+					VendorID: "10de",
+					DeviceID: "2684",
+					Driver:   "nouveau",
+					Name:     "NVIDIA GeForce RTX 4090",
+				},
+				Model: &nvidia.GPUModel{
+					Name:              "GeForce RTX 4090",
+					Architecture:      nvidia.ArchAdaLovelace,
+					MinDriverVersion:  "525.60",
+					ComputeCapability: "8.9",
+				},
+				SMIInfo: &smi.SMIGPUInfo{
+					UUID:           "GPU-1234",
+					MemoryTotalMiB: 24564,
+				},
+			},
+		},
+		InstalledDriver: &DriverInfo{Installed: true, Type: DriverTypeNouveau},
+		NouveauStatus:   &nouveau.Status{Loaded: true, InUse: true, BoundDevices: []string{"0000:01:00.0"}},
+		KernelInfo: &kernel.KernelInfo{
+			Version:      "6.5.0-44-generic",
+			Release:      "6.5.0",
+			Architecture: "x86_64",
+		},
+		ValidationReport: validation,
+		DetectionTime:    time.Date(2026, 1, 6, 12, 0, 0, 0, time.UTC),
+		Duration:         1500 * time.Millisecond,
+		Errors: []error{
+			errors.New(errors.GPUDetection, "lspci not found").WithOp("gpu.detectGPUsInternal"),
+			fmt.Errorf("plain failure"),
+		},
+	}
+}
+
+func TestNewReport_Nil(t *testing.T) {
+	report := NewReport(nil)
+
+	require.NotNil(t, report)
+	assert.Equal(t, ReportSchemaVersion, report.SchemaVersion)
+	assert.Empty(t, report.GPUs)
+	assert.Empty(t, report.Warnings)
+	assert.Nil(t, report.Driver)
+	assert.Nil(t, report.Kernel)
+	assert.Nil(t, report.Nouveau)
+	assert.Nil(t, report.Validation)
+}
+
+func TestNewReport_Full(t *testing.T) {
+	report := NewReport(newTestReportInfo())
+
+	assert.Equal(t, "2026-01-06T12:00:00Z", report.DetectedAt)
+	assert.Equal(t, int64(1500), report.DurationMS)
+
+	require.Len(t, report.GPUs, 1)
+	g := report.GPUs[0]
+	assert.Equal(t, 0, g.Index)
+	assert.Equal(t, "NVIDIA GeForce RTX 4090", g.Name)
+	assert.Equal(t, "0000:01:00.0", g.PCIAddress)
+	assert.Equal(t, "2684", g.DeviceID)
+	assert.Equal(t, "nouveau", g.KernelDriver)
+	assert.Equal(t, "ada", g.Architecture)
+	assert.Equal(t, "8.9", g.ComputeCapability)
+	assert.Equal(t, "525.60", g.MinDriverVersion)
+	assert.Equal(t, int64(24564), g.MemoryMiB)
+	assert.Equal(t, "GPU-1234", g.UUID)
+
+	require.NotNil(t, report.Driver)
+	assert.True(t, report.Driver.Installed)
+	assert.Equal(t, "nouveau", report.Driver.Type)
+
+	require.NotNil(t, report.Kernel)
+	assert.Equal(t, "6.5.0-44-generic", report.Kernel.Version)
+	assert.False(t, report.Kernel.HeadersInstalled)
+
+	require.NotNil(t, report.Nouveau)
+	assert.True(t, report.Nouveau.Loaded)
+	assert.Equal(t, []string{"0000:01:00.0"}, report.Nouveau.BoundDevices)
+	assert.NotNil(t, report.Nouveau.BlacklistFiles)
+
+	require.NotNil(t, report.Validation)
+	assert.False(t, report.Validation.Passed)
+	assert.Equal(t, 1, report.Validation.Errors)
+	require.Len(t, report.Validation.Checks, 2)
+	assert.Equal(t, "disk_space", report.Validation.Checks[0].Name)
+	assert.Equal(t, "kernel_headers", report.Validation.Checks[1].Name)
+	assert.Equal(t, "error", report.Validation.Checks[1].Severity)
+	assert.Equal(t, "install linux-headers-6.5.0-44-generic", report.Validation.Checks[1].Remediation)
+
+	require.Len(t, report.Warnings, 2)
+	assert.Equal(t, "GPUDetection", report.Warnings[0].Code)
+	assert.Equal(t, "gpu.detectGPUsInternal", report.Warnings[0].Op)
+	assert.Contains(t, report.Warnings[0].Message, "lspci not found")
+	assert.Equal(t, "Unknown", report.Warnings[1].Code)
+	assert.Empty(t, report.Warnings[1].Op)
+}
+
+func TestNewReport_WarningsSorted(t *testing.T) {
+	errs := []error{
+		errors.New(errors.Timeout, "smi timed out").WithOp("gpu.detectSMI"),
+		fmt.Errorf("plain failure"),
+		errors.New(errors.GPUDetection, "nouveau check failed").WithOp("nouveau.Detect"),
+		errors.New(errors.Timeout, "kernel timed out").WithOp("kernel.GetKernelInfo"),
+		errors.New(errors.GPUDetection, "lspci not found").WithOp("gpu.detectGPUsInternal"),
+		fmt.Errorf("another failure"),
+	}
+	expected := []string{
+		"GPUDetection gpu.detectGPUsInternal",
+		"GPUDetection nouveau.Detect",
+		"Timeout gpu.detectSMI",
+		"Timeout kernel.GetKernelInfo",
+		"Unknown  another failure",
+		"Unknown  plain failure",
+	}
+
+	for _, order := range [][]int{{0, 1, 2, 3, 4, 5}, {5, 4, 3, 2, 1, 0}, {2, 5, 0, 3, 1, 4}} {
+		info := &GPUInfo{}
+		for _, i := range order {
+			info.Errors = append(info.Errors, errs[i])
+		}
+
+		report := NewReport(info)
+
+		require.Len(t, report.Warnings, len(expected))
+		for i, w := range report.Warnings {
+			key := w.Code + " " + w.Op
+			if w.Op == "" {
+				key += " " + w.Message
+			}
+			assert.Equal(t, expected[i], key, "order %v", order)
+		}
+	}
+}
+
+func TestNewReport_UnknownModel(t *testing.T) {
+	info := &GPUInfo{
+		NVIDIAGPUs: []NVIDIAGPUInfo{
+			{PCIDevice: pci.PCIDevice{DeviceID: "ffff"}},
+		},
+	}
+
+	report := NewReport(info)
+
+	require.Len(t, report.GPUs, 1)
+	assert.Equal(t, "unknown", report.GPUs[0].Architecture)
+	assert.Empty(t, report.GPUs[0].MinDriverVersion)
+	assert.Contains(t, report.GPUs[0].Name, "ffff")
+	assert.Empty(t, report.DetectedAt)
+}
+
+func TestReport_JSONSchema(t *testing.T) {
+	data, err := json.Marshal(NewReport(newTestReportInfo()))
+	require.NoError(t, err)
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &doc))
+
+	for _, key := range []string{
+		"schema_version", "detected_at", "duration_ms", "gpus",
+		"driver", "kernel", "nouveau", "validation", "warnings",
+	} {
+		assert.Contains(t, doc, key)
+	}
+	assert.Equal(t, ReportSchemaVersion, doc["schema_version"])
+
+	gpus := doc["gpus"].([]interface{})
+	gpuDoc := gpus[0].(map[string]interface{})
+	for _, key := range []string{"index", "name", "pci_address", "vendor_id", "device_id", "architecture", "data_center"} {
+		assert.Contains(t, gpuDoc, key)
+	}
+}
+
+func TestReport_JSONEmptyArrays(t *testing.T) {
+	data, err := json.Marshal(NewReport(&GPUInfo{}))
+	require.NoError(t, err)
+
+	assert.Contains(t, string(data), `"gpus":[]`)
+	assert.Contains(t, string(data), `"warnings":[]`)
+	assert.NotContains(t, string(data), `"driver"`)
+}
+
+func TestNewReportWarning(t *testing.T) {
+	t.Run("igor error", func(t *testing.T) {
+		err := errors.Wrap(errors.Timeout, "kernel detection timed out", fmt.Errorf("deadline")).WithOp("kernel.GetKernelInfo")
+		w := NewReportWarning(err)
+		assert.Equal(t, "Timeout", w.Code)
+		assert.Equal(t, "kernel.GetKernelInfo", w.Op)
+		assert.Equal(t, err.Error(), w.Message)
+	})
+
+	t.Run("wrapped igor error", func(t *testing.T) {
+		err := fmt.Errorf("outer: %w", errors.New(errors.Validation, "inner"))
+		w := NewReportWarning(err)
+		assert.Equal(t, "Validation", w.Code)
+	})
+
+	t.Run("plain error", func(t *testing.T) {
+		w := NewReportWarning(fmt.Errorf("boom"))
+		assert.Equal(t, "Unknown", w.Code)
+		assert.Equal(t, "boom", w.Message)
+	})
+}
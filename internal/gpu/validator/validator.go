@@ -409,7 +409,7 @@ func (v *ValidatorImpl) ValidateSecureBoot(ctx context.Context) (*CheckResult, e
 			false,
 			"Secure Boot is enabled - unsigned kernel modules may not load",
 			SeverityWarning,
-		).WithRemediation("Either disable Secure Boot in BIOS/UEFI settings, or use pre-signed NVIDIA drivers from your distribution").
+		).WithRemediation("The NVIDIA modules are signed with a Machine Owner Key: confirm its enrollment in the MOK manager at the next reboot, or disable Secure Boot in BIOS/UEFI settings").
 			WithDetail("secure_boot", "enabled"), nil
 	}
 
@@ -10,6 +10,8 @@ import (
 	"github.com/tungetti/igor/internal/distro"
 	"github.com/tungetti/igor/internal/install"
 	"github.com/tungetti/igor/internal/install/steps"
+	"github.com/tungetti/igor/internal/pkg"
+	"github.com/tungetti/igor/internal/pkg/nvidia"
 )
 
 // BuilderConfig contains configuration options for workflow building.
@@ -20,7 +22,9 @@ type BuilderConfig struct {
 	SkipRepository bool
 	// SkipNouveau skips nouveau blacklisting
 	SkipNouveau bool
-	// SkipDKMS skips DKMS module building
+	// SkipModuleSigning skips signing the kernel modules for Secure Boot
+	SkipModuleSigning bool
+	// SkipDKMS skips kernel module building, with DKMS or akmods
 	SkipDKMS bool
 	// SkipModuleLoad skips kernel module loading
 	SkipModuleLoad bool
@@ -32,8 +36,19 @@ type BuilderConfig struct {
 	CustomSteps []install.Step
 	// ValidationChecks allows customizing which validation checks to run
 	ValidationChecks []steps.ValidationCheck
-	// RequiredDiskMB overrides default required disk space
+	// RequiredDiskMB overrides default required disk space, checked when
+	// the sizes of the packages cannot be estimated
 	RequiredDiskMB int64
+	// AdditionalPackages are installed alongside the computed driver packages
+	AdditionalPackages []string
+	// Reinstall reinstalls packages that are already installed
+	Reinstall bool
+	// AllowEssentialRemovals installs packages even if the transaction
+	// removes packages essential to the system
+	AllowEssentialRemovals bool
+	// Repository replaces the NVIDIA repository, and packages are only
+	// installed from it (nil = use the NVIDIA repository)
+	Repository *pkg.Repository
 }
 
 // WorkflowBuilder builds installation workflows for different distributions.
@@ -48,16 +63,17 @@ type WorkflowBuilderOption func(*WorkflowBuilder)
 // DefaultBuilderConfig returns the default builder configuration.
 func DefaultBuilderConfig() BuilderConfig {
 	return BuilderConfig{
-		SkipValidation:   false,
-		SkipRepository:   false,
-		SkipNouveau:      false,
-		SkipDKMS:         false,
-		SkipModuleLoad:   false,
-		SkipXorgConfig:   false,
-		SkipVerification: false,
-		CustomSteps:      nil,
-		ValidationChecks: nil,
-		RequiredDiskMB:   0, // Use default from validator
+		SkipValidation:    false,
+		SkipRepository:    false,
+		SkipNouveau:       false,
+		SkipModuleSigning: false,
+		SkipDKMS:          false,
+		SkipModuleLoad:    false,
+		SkipXorgConfig:    false,
+		SkipVerification:  false,
+		CustomSteps:       nil,
+		ValidationChecks:  nil,
+		RequiredDiskMB:    0, // Use default from validator
 	}
 }
 
@@ -96,7 +112,15 @@ func WithSkipNouveau(skip bool) WorkflowBuilderOption {
 	}
 }
 
-// WithSkipDKMS sets whether to skip the DKMS build step.
+// WithSkipModuleSigning sets whether to skip the module signing step.
+func WithSkipModuleSigning(skip bool) WorkflowBuilderOption {
+	return func(b *WorkflowBuilder) {
+		b.config.SkipModuleSigning = skip
+	}
+}
+
+// WithSkipDKMS sets whether to skip the kernel module build step, with DKMS
+// or akmods.
 func WithSkipDKMS(skip bool) WorkflowBuilderOption {
 	return func(b *WorkflowBuilder) {
 		b.config.SkipDKMS = skip
@@ -140,7 +164,8 @@ func WithValidationChecks(checks ...steps.ValidationCheck) WorkflowBuilderOption
 	}
 }
 
-// WithRequiredDiskMB sets the required disk space in megabytes.
+// WithRequiredDiskMB sets the required disk space in megabytes, checked
+// when the package manager cannot estimate the sizes of the packages.
 // If set to 0, the default from the validator is used.
 func WithRequiredDiskMB(mb int64) WorkflowBuilderOption {
 	return func(b *WorkflowBuilder) {
@@ -148,6 +173,39 @@ func WithRequiredDiskMB(mb int64) WorkflowBuilderOption {
 	}
 }
 
+// WithAdditionalPackages adds packages to the package installation step
+// beyond those computed from the driver version and components.
+func WithAdditionalPackages(packages ...string) WorkflowBuilderOption {
+	return func(b *WorkflowBuilder) {
+		b.config.AdditionalPackages = append(b.config.AdditionalPackages, packages...)
+	}
+}
+
+// WithReinstall sets whether already installed packages are reinstalled.
+func WithReinstall(reinstall bool) WorkflowBuilderOption {
+	return func(b *WorkflowBuilder) {
+		b.config.Reinstall = reinstall
+	}
+}
+
+// WithAllowEssentialRemovals sets whether packages are installed even if
+// the transaction removes packages essential to the system.
+func WithAllowEssentialRemovals(allow bool) WorkflowBuilderOption {
+	return func(b *WorkflowBuilder) {
+		b.config.AllowEssentialRemovals = allow
+	}
+}
+
+// WithRepository sets the repository to add instead of the NVIDIA repository
+// of the distribution, such as the local repository of an offline bundle.
+// Packages are only installed from that repository, and the repository step
+// is kept for the Arch family.
+func WithRepository(repo pkg.Repository) WorkflowBuilderOption {
+	return func(b *WorkflowBuilder) {
+		b.config.Repository = &repo
+	}
+}
+
 // WithBuilderConfig sets the entire builder configuration at once.
 func WithBuilderConfig(config BuilderConfig) WorkflowBuilderOption {
 	return func(b *WorkflowBuilder) {
@@ -185,20 +243,26 @@ func (b *WorkflowBuilder) Build() (install.Workflow, error) {
 func (b *WorkflowBuilder) addSteps(workflow *install.BaseWorkflow) error {
 	// Step order (as per spec):
 	// 1. ValidationStep
-	// 2. RepositoryStep (skipped for Arch)
+	// 2. RepositoryStep (skipped for Arch unless a repository is set)
 	// 3. NouveauBlacklistStep
-	// 4. PackageInstallationStep
-	// 5. DKMSBuildStep
-	// 6. ModuleLoadStep
-	// 7. XorgConfigStep
-	// 8. VerificationStep
+	// 4. ModuleSigningStep
+	// 5. PackageInstallationStep
+	// 6. DKMSBuildStep (AkmodsBuildStep for akmod packages)
+	// 7. ModuleLoadStep
+	// 8. XorgConfigStep
+	// 9. VerificationStep
+
+	// The validation step estimates the disk space of the packages the
+	// package installation step installs, and the module signing step
+	// tells DKMS from akmods by these packages
+	packageStep := b.buildPackageInstallationStep()
 
 	// 1. Validation step
 	if !b.config.SkipValidation {
-		workflow.AddStep(b.buildValidationStep())
+		workflow.AddStep(b.buildValidationStep(packageStep))
 	}
 
-	// 2. Repository step (skipped for Arch family)
+	// 2. Repository step (skipped for Arch family unless a repository is set)
 	if !b.config.SkipRepository && !b.shouldSkipRepository() {
 		workflow.AddStep(b.buildRepositoryStep())
 	}
@@ -208,25 +272,31 @@ func (b *WorkflowBuilder) addSteps(workflow *install.BaseWorkflow) error {
 		workflow.AddStep(b.buildNouveauBlacklistStep())
 	}
 
-	// 4. Package installation step
-	workflow.AddStep(b.buildPackageInstallationStep())
+	// 4. Module signing step (before the packages, whose installation
+	// already builds the modules)
+	if !b.config.SkipModuleSigning {
+		workflow.AddStep(b.buildModuleSigningStep(packageStep))
+	}
+
+	// 5. Package installation step
+	workflow.AddStep(packageStep)
 
-	// 5. DKMS build step
+	// 6. Kernel module build step
 	if !b.config.SkipDKMS {
-		workflow.AddStep(b.buildDKMSBuildStep())
+		workflow.AddStep(b.buildModuleBuildStep())
 	}
 
-	// 6. Module load step
+	// 7. Module load step
 	if !b.config.SkipModuleLoad {
 		workflow.AddStep(b.buildModuleLoadStep())
 	}
 
-	// 7. X.org config step
+	// 8. X.org config step
 	if !b.config.SkipXorgConfig {
 		workflow.AddStep(b.buildXorgConfigStep())
 	}
 
-	// 8. Verification step
+	// 9. Verification step
 	if !b.config.SkipVerification {
 		workflow.AddStep(b.buildVerificationStep())
 	}
@@ -243,12 +313,14 @@ func (b *WorkflowBuilder) addSteps(workflow *install.BaseWorkflow) error {
 // for the current distribution family.
 func (b *WorkflowBuilder) shouldSkipRepository() bool {
 	// Arch family doesn't need external repositories - NVIDIA packages are in official repos
-	return b.distro.Family == constants.FamilyArch
+	return b.config.Repository == nil && b.distro.Family == constants.FamilyArch
 }
 
 // buildValidationStep creates the validation step with appropriate options.
-func (b *WorkflowBuilder) buildValidationStep() install.Step {
-	var opts []steps.ValidationStepOption
+// The disk space check estimates the space the packages of the package
+// installation step need, and falls back to the required disk space.
+func (b *WorkflowBuilder) buildValidationStep(packages steps.PackageLister) install.Step {
+	opts := []steps.ValidationStepOption{steps.WithPackageLister(packages)}
 
 	// Add custom validation checks if specified
 	if len(b.config.ValidationChecks) > 0 {
@@ -265,6 +337,9 @@ func (b *WorkflowBuilder) buildValidationStep() install.Step {
 
 // buildRepositoryStep creates the repository configuration step.
 func (b *WorkflowBuilder) buildRepositoryStep() install.Step {
+	if b.config.Repository != nil {
+		return steps.NewRepositoryStep(steps.WithRepository(*b.config.Repository))
+	}
 	return steps.NewRepositoryStep()
 }
 
@@ -273,9 +348,55 @@ func (b *WorkflowBuilder) buildNouveauBlacklistStep() install.Step {
 	return steps.NewNouveauBlacklistStep()
 }
 
+// buildModuleSigningStep creates the module signing step, which signs with
+// akmods when the package installation step installs an akmod package.
+func (b *WorkflowBuilder) buildModuleSigningStep(packages steps.PackageLister) install.Step {
+	return steps.NewModuleSigningStep(steps.WithSigningPackageLister(packages))
+}
+
 // buildPackageInstallationStep creates the package installation step.
-func (b *WorkflowBuilder) buildPackageInstallationStep() install.Step {
-	return steps.NewPackageInstallationStep()
+func (b *WorkflowBuilder) buildPackageInstallationStep() *steps.PackageInstallationStep {
+	var opts []steps.PackageInstallationStepOption
+
+	if len(b.config.AdditionalPackages) > 0 {
+		opts = append(opts, steps.WithAdditionalPackages(b.config.AdditionalPackages...))
+	}
+
+	if b.config.Reinstall {
+		opts = append(opts, steps.WithReinstall(true))
+	}
+
+	if b.config.AllowEssentialRemovals {
+		opts = append(opts, steps.WithAllowEssentialRemovals(true))
+	}
+
+	if b.config.Repository != nil {
+		opts = append(opts, steps.WithPackageRepository(b.config.Repository.Name))
+	}
+
+	return steps.NewPackageInstallationStep(opts...)
+}
+
+// buildModuleBuildStep creates the step building the kernel modules: the
+// akmods build step when the driver packages are akmod packages, as with
+// RPM Fusion, and the DKMS build step otherwise.
+func (b *WorkflowBuilder) buildModuleBuildStep() install.Step {
+	if b.usesAkmods() {
+		return steps.NewAkmodsBuildStep()
+	}
+	return b.buildDKMSBuildStep()
+}
+
+// usesAkmods returns true if the package set of the distribution, or the
+// additional packages, install an akmod package.
+func (b *WorkflowBuilder) usesAkmods() bool {
+	for _, name := range b.config.AdditionalPackages {
+		if nvidia.IsAkmodPackage(name) {
+			return true
+		}
+	}
+	packageSet := nvidia.GetPackageSet(b.distro)
+	return packageSet != nil && packageSet.UsesAkmods()
 }
 
 // buildDKMSBuildStep creates the DKMS build step.
@@ -325,6 +446,13 @@ func (b *WorkflowBuilder) Config() BuilderConfig {
 	if b.config.ValidationChecks != nil {
 		config.ValidationChecks = append([]steps.ValidationCheck{}, b.config.ValidationChecks...)
 	}
+	if b.config.AdditionalPackages != nil {
+		config.AdditionalPackages = append([]string{}, b.config.AdditionalPackages...)
+	}
+	if b.config.Repository != nil {
+		repo := *b.config.Repository
+		config.Repository = &repo
+	}
 	return config
 }
 
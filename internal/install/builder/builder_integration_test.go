@@ -26,7 +26,7 @@ func TestBuilder_EndToEndWorkflowExecution(t *testing.T) {
 
 		// Verify workflow structure
 		assert.Equal(t, "debian-nvidia-installation", workflow.Name())
-		assert.Len(t, workflow.Steps(), 8)
+		assert.Len(t, workflow.Steps(), 9)
 
 		// Verify step order
 		stepNames := getStepNames(workflow.Steps())
@@ -34,6 +34,7 @@ func TestBuilder_EndToEndWorkflowExecution(t *testing.T) {
 			"validation",
 			"repository",
 			"nouveau_blacklist",
+			"module_signing",
 			"packages",
 			"dkms_build",
 			"module_load",
@@ -49,15 +50,16 @@ func TestBuilder_EndToEndWorkflowExecution(t *testing.T) {
 		require.NoError(t, err)
 
 		assert.Equal(t, "rhel-nvidia-installation", workflow.Name())
-		assert.Len(t, workflow.Steps(), 8)
+		assert.Len(t, workflow.Steps(), 9)
 
 		stepNames := getStepNames(workflow.Steps())
 		expectedOrder := []string{
 			"validation",
 			"repository",
 			"nouveau_blacklist",
+			"module_signing",
 			"packages",
-			"dkms_build",
+			"akmods_build",
 			"module_load",
 			"xorg_config",
 			"verification",
@@ -71,7 +73,7 @@ func TestBuilder_EndToEndWorkflowExecution(t *testing.T) {
 		require.NoError(t, err)
 
 		assert.Equal(t, "arch-nvidia-installation", workflow.Name())
-		assert.Len(t, workflow.Steps(), 7)
+		assert.Len(t, workflow.Steps(), 8)
 
 		stepNames := getStepNames(workflow.Steps())
 		assert.NotContains(t, stepNames, "repository")
@@ -79,6 +81,7 @@ func TestBuilder_EndToEndWorkflowExecution(t *testing.T) {
 		expectedOrder := []string{
 			"validation",
 			"nouveau_blacklist",
+			"module_signing",
 			"packages",
 			"dkms_build",
 			"module_load",
@@ -94,13 +97,14 @@ func TestBuilder_EndToEndWorkflowExecution(t *testing.T) {
 		require.NoError(t, err)
 
 		assert.Equal(t, "suse-nvidia-installation", workflow.Name())
-		assert.Len(t, workflow.Steps(), 8)
+		assert.Len(t, workflow.Steps(), 9)
 
 		stepNames := getStepNames(workflow.Steps())
 		expectedOrder := []string{
 			"validation",
 			"repository",
 			"nouveau_blacklist",
+			"module_signing",
 			"packages",
 			"dkms_build",
 			"module_load",
@@ -140,13 +144,13 @@ func TestBuilder_CustomStepsIntegration(t *testing.T) {
 		workflow, err := builder.Build()
 		require.NoError(t, err)
 
-		// Should have 10 steps (8 standard + 2 custom)
-		assert.Len(t, workflow.Steps(), 10)
+		// Should have 11 steps (9 standard + 2 custom)
+		assert.Len(t, workflow.Steps(), 11)
 
 		// Custom steps should be at the end
 		stepNames := getStepNames(workflow.Steps())
-		assert.Equal(t, "custom_pre_reboot", stepNames[8])
-		assert.Equal(t, "custom_final_cleanup", stepNames[9])
+		assert.Equal(t, "custom_pre_reboot", stepNames[9])
+		assert.Equal(t, "custom_final_cleanup", stepNames[10])
 	})
 
 	t.Run("custom step with rollback capability", func(t *testing.T) {
@@ -210,6 +214,7 @@ func TestBuilder_CustomStepsIntegration(t *testing.T) {
 			WithSkipValidation(true),
 			WithSkipRepository(true),
 			WithSkipNouveau(true),
+			WithSkipModuleSigning(true),
 			WithSkipDKMS(true),
 			WithSkipModuleLoad(true),
 			WithSkipXorgConfig(true),
@@ -240,6 +245,7 @@ func TestBuilder_SkipOptionsIntegration(t *testing.T) {
 			WithSkipValidation(true),
 			WithSkipRepository(true),
 			WithSkipNouveau(true),
+			WithSkipModuleSigning(true),
 			WithSkipDKMS(true),
 			WithSkipModuleLoad(true),
 			WithSkipXorgConfig(true),
@@ -257,6 +263,7 @@ func TestBuilder_SkipOptionsIntegration(t *testing.T) {
 		builder := NewWorkflowBuilder(ubuntuDistro,
 			WithSkipRepository(true),
 			WithSkipNouveau(true),
+			WithSkipModuleSigning(true),
 			WithSkipDKMS(true),
 			WithSkipModuleLoad(true),
 			WithSkipXorgConfig(true),
@@ -284,7 +291,7 @@ func TestBuilder_SkipOptionsIntegration(t *testing.T) {
 		assert.NotContains(t, stepNames, "repository")
 		assert.NotContains(t, stepNames, "dkms_build")
 		assert.NotContains(t, stepNames, "xorg_config")
-		assert.Len(t, stepNames, 5)
+		assert.Len(t, stepNames, 6)
 	})
 }
 
@@ -330,7 +337,7 @@ func TestBuilder_ValidationConfigIntegration(t *testing.T) {
 		config := builder.Config()
 		assert.Equal(t, checks, config.ValidationChecks)
 		assert.Equal(t, int64(8000), config.RequiredDiskMB)
-		assert.Len(t, workflow.Steps(), 8)
+		assert.Len(t, workflow.Steps(), 9)
 	})
 }
 
@@ -349,25 +356,25 @@ func TestBuilder_DistributionSpecificWorkflows(t *testing.T) {
 		{
 			name:          "Ubuntu",
 			distro:        ubuntuDistro,
-			expectedSteps: 8,
+			expectedSteps: 9,
 			hasRepository: true,
 		},
 		{
 			name:          "Fedora",
 			distro:        fedoraDistro,
-			expectedSteps: 8,
+			expectedSteps: 9,
 			hasRepository: true,
 		},
 		{
 			name:          "Arch",
 			distro:        archDistro,
-			expectedSteps: 7,
+			expectedSteps: 8,
 			hasRepository: false,
 		},
 		{
 			name:          "openSUSE",
 			distro:        openSUSEDistro,
-			expectedSteps: 8,
+			expectedSteps: 9,
 			hasRepository: true,
 		},
 	}
@@ -405,7 +412,7 @@ func TestBuilder_DerivativeDistributions(t *testing.T) {
 				VersionID: "21.3",
 				Family:    constants.FamilyDebian,
 			},
-			expectedSteps: 8,
+			expectedSteps: 9,
 		},
 		{
 			name: "CentOS (RHEL derivative)",
@@ -415,7 +422,7 @@ func TestBuilder_DerivativeDistributions(t *testing.T) {
 				VersionID: "9",
 				Family:    constants.FamilyRHEL,
 			},
-			expectedSteps: 8,
+			expectedSteps: 9,
 		},
 		{
 			name: "Manjaro (Arch derivative)",
@@ -425,7 +432,7 @@ func TestBuilder_DerivativeDistributions(t *testing.T) {
 				VersionID: "24.0",
 				Family:    constants.FamilyArch,
 			},
-			expectedSteps: 7,
+			expectedSteps: 8,
 		},
 		{
 			name: "openSUSE Leap (SUSE derivative)",
@@ -435,7 +442,7 @@ func TestBuilder_DerivativeDistributions(t *testing.T) {
 				VersionID: "15.5",
 				Family:    constants.FamilySUSE,
 			},
-			expectedSteps: 8,
+			expectedSteps: 9,
 		},
 	}
 
@@ -546,7 +553,7 @@ func TestBuilder_ConcurrentBuilding(t *testing.T) {
 					results <- assert.AnError
 					return
 				}
-				if len(workflow.Steps()) != 8 {
+				if len(workflow.Steps()) != 9 {
 					results <- assert.AnError
 					return
 				}
@@ -653,6 +660,7 @@ func TestBuilder_DryRunWorkflowExecution(t *testing.T) {
 			WithSkipValidation(true),
 			WithSkipRepository(true),
 			WithSkipNouveau(true),
+			WithSkipModuleSigning(true),
 			WithSkipDKMS(true),
 			WithSkipModuleLoad(true),
 			WithSkipXorgConfig(true),
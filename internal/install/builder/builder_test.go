@@ -9,6 +9,7 @@ import (
 	"github.com/tungetti/igor/internal/distro"
 	"github.com/tungetti/igor/internal/install"
 	"github.com/tungetti/igor/internal/install/steps"
+	"github.com/tungetti/igor/internal/pkg"
 )
 
 // Test distributions for different families
@@ -216,9 +217,9 @@ func TestWorkflowBuilder_Build(t *testing.T) {
 
 		assert.Equal(t, "debian-nvidia-installation", workflow.Name())
 
-		// Debian should have all 8 steps
+		// Debian should have all 9 steps
 		steps := workflow.Steps()
-		assert.Len(t, steps, 8)
+		assert.Len(t, steps, 9)
 
 		// Verify step order
 		stepNames := getStepNames(steps)
@@ -226,6 +227,7 @@ func TestWorkflowBuilder_Build(t *testing.T) {
 			"validation",
 			"repository",
 			"nouveau_blacklist",
+			"module_signing",
 			"packages",
 			"dkms_build",
 			"module_load",
@@ -244,9 +246,9 @@ func TestWorkflowBuilder_Build(t *testing.T) {
 
 		assert.Equal(t, "rhel-nvidia-installation", workflow.Name())
 
-		// RHEL should have all 8 steps
+		// RHEL should have all 9 steps
 		steps := workflow.Steps()
-		assert.Len(t, steps, 8)
+		assert.Len(t, steps, 9)
 
 		// Verify step order
 		stepNames := getStepNames(steps)
@@ -254,8 +256,9 @@ func TestWorkflowBuilder_Build(t *testing.T) {
 			"validation",
 			"repository",
 			"nouveau_blacklist",
+			"module_signing",
 			"packages",
-			"dkms_build",
+			"akmods_build",
 			"module_load",
 			"xorg_config",
 			"verification",
@@ -272,9 +275,9 @@ func TestWorkflowBuilder_Build(t *testing.T) {
 
 		assert.Equal(t, "arch-nvidia-installation", workflow.Name())
 
-		// Arch should have 7 steps (no repository step)
+		// Arch should have 8 steps (no repository step)
 		steps := workflow.Steps()
-		assert.Len(t, steps, 7)
+		assert.Len(t, steps, 8)
 
 		// Verify repository step is NOT present
 		stepNames := getStepNames(steps)
@@ -284,6 +287,7 @@ func TestWorkflowBuilder_Build(t *testing.T) {
 		expectedOrder := []string{
 			"validation",
 			"nouveau_blacklist",
+			"module_signing",
 			"packages",
 			"dkms_build",
 			"module_load",
@@ -302,9 +306,9 @@ func TestWorkflowBuilder_Build(t *testing.T) {
 
 		assert.Equal(t, "suse-nvidia-installation", workflow.Name())
 
-		// SUSE should have all 8 steps
+		// SUSE should have all 9 steps
 		steps := workflow.Steps()
-		assert.Len(t, steps, 8)
+		assert.Len(t, steps, 9)
 	})
 
 	t.Run("returns error for nil distribution", func(t *testing.T) {
@@ -336,7 +340,7 @@ func TestWorkflowBuilder_Build_SkipOptions(t *testing.T) {
 
 		stepNames := getStepNames(workflow.Steps())
 		assert.NotContains(t, stepNames, "validation")
-		assert.Len(t, stepNames, 7) // 8 - 1
+		assert.Len(t, stepNames, 8) // 9 - 1
 	})
 
 	t.Run("skip repository", func(t *testing.T) {
@@ -347,7 +351,7 @@ func TestWorkflowBuilder_Build_SkipOptions(t *testing.T) {
 
 		stepNames := getStepNames(workflow.Steps())
 		assert.NotContains(t, stepNames, "repository")
-		assert.Len(t, stepNames, 7) // 8 - 1
+		assert.Len(t, stepNames, 8) // 9 - 1
 	})
 
 	t.Run("skip nouveau", func(t *testing.T) {
@@ -358,7 +362,18 @@ func TestWorkflowBuilder_Build_SkipOptions(t *testing.T) {
 
 		stepNames := getStepNames(workflow.Steps())
 		assert.NotContains(t, stepNames, "nouveau_blacklist")
-		assert.Len(t, stepNames, 7) // 8 - 1
+		assert.Len(t, stepNames, 8) // 9 - 1
+	})
+
+	t.Run("skip module signing", func(t *testing.T) {
+		builder := NewWorkflowBuilder(ubuntuDistro, WithSkipModuleSigning(true))
+		workflow, err := builder.Build()
+
+		require.NoError(t, err)
+
+		stepNames := getStepNames(workflow.Steps())
+		assert.NotContains(t, stepNames, "module_signing")
+		assert.Len(t, stepNames, 8) // 9 - 1
 	})
 
 	t.Run("skip DKMS", func(t *testing.T) {
@@ -369,7 +384,7 @@ func TestWorkflowBuilder_Build_SkipOptions(t *testing.T) {
 
 		stepNames := getStepNames(workflow.Steps())
 		assert.NotContains(t, stepNames, "dkms_build")
-		assert.Len(t, stepNames, 7) // 8 - 1
+		assert.Len(t, stepNames, 8) // 9 - 1
 	})
 
 	t.Run("skip module load", func(t *testing.T) {
@@ -380,7 +395,7 @@ func TestWorkflowBuilder_Build_SkipOptions(t *testing.T) {
 
 		stepNames := getStepNames(workflow.Steps())
 		assert.NotContains(t, stepNames, "module_load")
-		assert.Len(t, stepNames, 7) // 8 - 1
+		assert.Len(t, stepNames, 8) // 9 - 1
 	})
 
 	t.Run("skip xorg config", func(t *testing.T) {
@@ -391,7 +406,7 @@ func TestWorkflowBuilder_Build_SkipOptions(t *testing.T) {
 
 		stepNames := getStepNames(workflow.Steps())
 		assert.NotContains(t, stepNames, "xorg_config")
-		assert.Len(t, stepNames, 7) // 8 - 1
+		assert.Len(t, stepNames, 8) // 9 - 1
 	})
 
 	t.Run("skip verification", func(t *testing.T) {
@@ -402,7 +417,7 @@ func TestWorkflowBuilder_Build_SkipOptions(t *testing.T) {
 
 		stepNames := getStepNames(workflow.Steps())
 		assert.NotContains(t, stepNames, "verification")
-		assert.Len(t, stepNames, 7) // 8 - 1
+		assert.Len(t, stepNames, 8) // 9 - 1
 	})
 
 	t.Run("skip all optional steps", func(t *testing.T) {
@@ -410,6 +425,7 @@ func TestWorkflowBuilder_Build_SkipOptions(t *testing.T) {
 			WithSkipValidation(true),
 			WithSkipRepository(true),
 			WithSkipNouveau(true),
+			WithSkipModuleSigning(true),
 			WithSkipDKMS(true),
 			WithSkipModuleLoad(true),
 			WithSkipXorgConfig(true),
@@ -434,7 +450,7 @@ func TestWorkflowBuilder_Build_SkipOptions(t *testing.T) {
 
 		stepNames := getStepNames(workflow.Steps())
 		assert.NotContains(t, stepNames, "repository")
-		assert.Len(t, stepNames, 7) // Same as default Arch
+		assert.Len(t, stepNames, 8) // Same as default Arch
 	})
 }
 
@@ -451,8 +467,8 @@ func TestWorkflowBuilder_Build_CustomSteps(t *testing.T) {
 		require.NoError(t, err)
 
 		steps := workflow.Steps()
-		assert.Len(t, steps, 9) // 8 standard + 1 custom
-		assert.Equal(t, "custom1", steps[8].Name())
+		assert.Len(t, steps, 10) // 9 standard + 1 custom
+		assert.Equal(t, "custom1", steps[9].Name())
 	})
 
 	t.Run("adds multiple custom steps", func(t *testing.T) {
@@ -469,9 +485,9 @@ func TestWorkflowBuilder_Build_CustomSteps(t *testing.T) {
 		require.NoError(t, err)
 
 		steps := workflow.Steps()
-		assert.Len(t, steps, 10) // 8 standard + 2 custom
-		assert.Equal(t, "custom1", steps[8].Name())
-		assert.Equal(t, "custom2", steps[9].Name())
+		assert.Len(t, steps, 11) // 9 standard + 2 custom
+		assert.Equal(t, "custom1", steps[9].Name())
+		assert.Equal(t, "custom2", steps[10].Name())
 	})
 
 	t.Run("custom steps added after standard steps", func(t *testing.T) {
@@ -486,9 +502,9 @@ func TestWorkflowBuilder_Build_CustomSteps(t *testing.T) {
 
 		steps := workflow.Steps()
 		// Last standard step should be verification
-		assert.Equal(t, "verification", steps[7].Name())
+		assert.Equal(t, "verification", steps[8].Name())
 		// Custom step should be after verification
-		assert.Equal(t, "custom", steps[8].Name())
+		assert.Equal(t, "custom", steps[9].Name())
 	})
 }
 
@@ -597,24 +613,24 @@ func TestWorkflowBuilder_StepCount(t *testing.T) {
 		expectedCount int
 	}{
 		{
-			name:          "Debian has 8 steps",
+			name:          "Debian has 9 steps",
 			distro:        ubuntuDistro,
-			expectedCount: 8,
+			expectedCount: 9,
 		},
 		{
-			name:          "RHEL has 8 steps",
+			name:          "RHEL has 9 steps",
 			distro:        fedoraDistro,
-			expectedCount: 8,
+			expectedCount: 9,
 		},
 		{
-			name:          "Arch has 7 steps (no repository)",
+			name:          "Arch has 8 steps (no repository)",
 			distro:        archDistro,
-			expectedCount: 7,
+			expectedCount: 8,
 		},
 		{
-			name:          "SUSE has 8 steps",
+			name:          "SUSE has 9 steps",
 			distro:        openSUSEDistro,
-			expectedCount: 8,
+			expectedCount: 9,
 		},
 	}
 
@@ -719,6 +735,44 @@ func TestWorkflowBuilder_ArchSkipsRepository(t *testing.T) {
 	})
 }
 
+// TestWorkflowBuilder_ModuleBuildStep tests the choice of the kernel module build step.
+func TestWorkflowBuilder_ModuleBuildStep(t *testing.T) {
+	t.Run("akmods for the RPM Fusion package set", func(t *testing.T) {
+		workflow, err := NewWorkflowBuilder(fedoraDistro).Build()
+		require.NoError(t, err)
+
+		stepNames := getStepNames(workflow.Steps())
+		assert.Contains(t, stepNames, "akmods_build")
+		assert.NotContains(t, stepNames, "dkms_build")
+		assert.IsType(t, &steps.AkmodsBuildStep{}, workflow.Steps()[5])
+	})
+
+	t.Run("DKMS for other package sets", func(t *testing.T) {
+		for _, dist := range []*distro.Distribution{ubuntuDistro, archDistro, openSUSEDistro} {
+			workflow, err := NewWorkflowBuilder(dist).Build()
+			require.NoError(t, err)
+
+			stepNames := getStepNames(workflow.Steps())
+			assert.Contains(t, stepNames, "dkms_build", dist.ID)
+			assert.NotContains(t, stepNames, "akmods_build", dist.ID)
+		}
+	})
+
+	t.Run("akmods for additional akmod packages", func(t *testing.T) {
+		workflow, err := NewWorkflowBuilder(openSUSEDistro, WithAdditionalPackages("akmod-nvidia")).Build()
+		require.NoError(t, err)
+
+		assert.Contains(t, getStepNames(workflow.Steps()), "akmods_build")
+	})
+
+	t.Run("skip DKMS skips akmods", func(t *testing.T) {
+		workflow, err := NewWorkflowBuilder(fedoraDistro, WithSkipDKMS(true)).Build()
+		require.NoError(t, err)
+
+		assert.NotContains(t, getStepNames(workflow.Steps()), "akmods_build")
+	})
+}
+
 // TestWorkflowBuilder_ValidationCheckConfiguration tests validation check customization.
 func TestWorkflowBuilder_ValidationCheckConfiguration(t *testing.T) {
 	t.Run("uses custom validation checks when specified", func(t *testing.T) {
@@ -808,6 +862,71 @@ func TestWorkflowBuilder_RequiredDiskMBConfiguration(t *testing.T) {
 	})
 }
 
+func TestWorkflowBuilder_PackageOptions(t *testing.T) {
+	t.Run("defaults to no additional packages and no reinstall", func(t *testing.T) {
+		config := NewWorkflowBuilder(ubuntuDistro).Config()
+
+		assert.Empty(t, config.AdditionalPackages)
+		assert.False(t, config.Reinstall)
+		assert.False(t, config.AllowEssentialRemovals)
+	})
+
+	t.Run("records additional packages and reinstall", func(t *testing.T) {
+		builder := NewWorkflowBuilder(ubuntuDistro,
+			WithAdditionalPackages("cuda-toolkit-12-4"),
+			WithAdditionalPackages("nvidia-settings"),
+			WithReinstall(true),
+			WithAllowEssentialRemovals(true),
+		)
+		config := builder.Config()
+
+		assert.Equal(t, []string{"cuda-toolkit-12-4", "nvidia-settings"}, config.AdditionalPackages)
+		assert.True(t, config.Reinstall)
+		assert.True(t, config.AllowEssentialRemovals)
+
+		workflow, err := builder.Build()
+		require.NoError(t, err)
+		assert.Contains(t, getStepNames(workflow.Steps()), "packages")
+	})
+
+	t.Run("config copy does not share additional packages", func(t *testing.T) {
+		builder := NewWorkflowBuilder(ubuntuDistro, WithAdditionalPackages("a"))
+		config := builder.Config()
+		config.AdditionalPackages[0] = "b"
+
+		assert.Equal(t, []string{"a"}, builder.Config().AdditionalPackages)
+	})
+}
+
+func TestWorkflowBuilder_WithRepository(t *testing.T) {
+	repo := pkg.Repository{Name: pkg.BundleRepositoryName, URL: "file:///srv/bundle", Enabled: true}
+
+	t.Run("records the repository", func(t *testing.T) {
+		builder := NewWorkflowBuilder(ubuntuDistro, WithRepository(repo))
+		config := builder.Config()
+
+		require.NotNil(t, config.Repository)
+		assert.Equal(t, repo, *config.Repository)
+
+		config.Repository.Name = "changed"
+		assert.Equal(t, pkg.BundleRepositoryName, builder.Config().Repository.Name)
+	})
+
+	t.Run("Arch keeps the repository step", func(t *testing.T) {
+		workflow, err := NewWorkflowBuilder(archDistro, WithRepository(repo)).Build()
+
+		require.NoError(t, err)
+		assert.Contains(t, getStepNames(workflow.Steps()), "repository")
+	})
+
+	t.Run("SkipRepository still applies", func(t *testing.T) {
+		workflow, err := NewWorkflowBuilder(ubuntuDistro, WithRepository(repo), WithSkipRepository(true)).Build()
+
+		require.NoError(t, err)
+		assert.NotContains(t, getStepNames(workflow.Steps()), "repository")
+	})
+}
+
 // TestWorkflowBuilder_ConcurrentBuilds tests that building is safe for concurrent use.
 func TestWorkflowBuilder_ConcurrentBuilds(t *testing.T) {
 	builder := NewWorkflowBuilder(ubuntuDistro)
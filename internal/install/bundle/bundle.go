@@ -0,0 +1,132 @@
+// Package bundle describes the offline package bundles of Igor.
+//
+// A bundle is a directory created by igor bundle. It holds the NVIDIA
+// packages selected for one distribution release together with their
+// dependencies, the repository metadata of the package manager, and a
+// manifest recording what the bundle was built for. igor install
+// --from-bundle serves the directory as a local repository, so the driver
+// can be installed on machines without network access.
+package bundle
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/tungetti/igor/internal/distro"
+	"github.com/tungetti/igor/internal/errors"
+)
+
+// SchemaVersion is the version of the manifest format.
+const SchemaVersion = "1"
+
+// ManifestFile is the name of the manifest file in a bundle directory.
+const ManifestFile = "igor-bundle.json"
+
+// Manifest describes the contents of a bundle.
+type Manifest struct {
+	SchemaVersion string    `json:"schema_version"`
+	Created       time.Time `json:"created"`
+	// Distribution and DistributionVersion are the ID and version ID of the
+	// distribution the bundle was built on.
+	Distribution        string `json:"distribution"`
+	DistributionVersion string `json:"distribution_version,omitempty"`
+	Architecture        string `json:"architecture"`
+	PackageManager      string `json:"package_manager"`
+	// DriverVersion, Components and AdditionalPackages are the resolved
+	// inputs of the installation, used again by igor install --from-bundle.
+	DriverVersion      string   `json:"driver_version,omitempty"`
+	Components         []string `json:"components,omitempty"`
+	AdditionalPackages []string `json:"additional_packages,omitempty"`
+	// Packages are the packages requested when downloading the bundle; the
+	// bundle also holds their dependencies.
+	Packages []string `json:"packages"`
+}
+
+// NewManifest creates the manifest of a bundle built now on the given
+// distribution with the given package manager.
+func NewManifest(dist *distro.Distribution, packageManager string) *Manifest {
+	m := &Manifest{
+		SchemaVersion:  SchemaVersion,
+		Created:        time.Now(),
+		Architecture:   runtime.GOARCH,
+		PackageManager: packageManager,
+		Packages:       make([]string, 0),
+	}
+	if dist != nil {
+		m.Distribution = dist.ID
+		m.DistributionVersion = dist.VersionID
+	}
+	return m
+}
+
+// Check returns an error if the bundle cannot be installed on the given
+// distribution with the given package manager. Packages are built for one
+// release and architecture, so both must match.
+func (m *Manifest) Check(dist *distro.Distribution, packageManager string) error {
+	const op = "bundle.Check"
+
+	if dist == nil {
+		return errors.New(errors.Validation, "distribution is unknown").WithOp(op)
+	}
+	if m.Distribution != dist.ID || m.DistributionVersion != dist.VersionID {
+		return errors.Newf(errors.Validation, "bundle was built for %s, but this system runs %s",
+			describe(m.Distribution, m.DistributionVersion), describe(dist.ID, dist.VersionID)).WithOp(op)
+	}
+	if m.Architecture != runtime.GOARCH {
+		return errors.Newf(errors.Validation, "bundle was built for %s, but this system is %s",
+			m.Architecture, runtime.GOARCH).WithOp(op)
+	}
+	if m.PackageManager != packageManager {
+		return errors.Newf(errors.Validation, "bundle was built for %s, but this system uses %s",
+			m.PackageManager, packageManager).WithOp(op)
+	}
+	return nil
+}
+
+// Write saves the manifest in the bundle directory dir.
+func Write(dir string, m *Manifest) error {
+	const op = "bundle.Write"
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return errors.Wrap(errors.Execution, "failed to encode bundle manifest", err).WithOp(op)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), append(data, '\n'), 0644); err != nil {
+		return errors.Wrap(errors.Execution, "failed to write bundle manifest", err).WithOp(op)
+	}
+	return nil
+}
+
+// Load reads the manifest of the bundle directory dir.
+func Load(dir string) (*Manifest, error) {
+	const op = "bundle.Load"
+
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.Newf(errors.NotFound, "%s is not a bundle: %s not found", dir, ManifestFile).WithOp(op)
+		}
+		return nil, errors.Wrap(errors.Execution, "failed to read bundle manifest", err).WithOp(op)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, errors.Wrapf(errors.Validation, err, "bundle manifest in %s is corrupt", dir).WithOp(op)
+	}
+	if m.SchemaVersion != SchemaVersion {
+		return nil, errors.Newf(errors.Unsupported, "bundle manifest in %s has unsupported schema version %q", dir, m.SchemaVersion).WithOp(op)
+	}
+
+	return &m, nil
+}
+
+// describe formats a distribution ID and version ID.
+func describe(id, versionID string) string {
+	if versionID == "" {
+		return id
+	}
+	return id + " " + versionID
+}
@@ -0,0 +1,99 @@
+package bundle
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tungetti/igor/internal/distro"
+	"github.com/tungetti/igor/internal/errors"
+)
+
+func ubuntu(versionID string) *distro.Distribution {
+	return &distro.Distribution{ID: "ubuntu", Name: "Ubuntu", VersionID: versionID}
+}
+
+func TestNewManifest(t *testing.T) {
+	m := NewManifest(ubuntu("24.04"), "apt")
+
+	assert.Equal(t, SchemaVersion, m.SchemaVersion)
+	assert.Equal(t, "ubuntu", m.Distribution)
+	assert.Equal(t, "24.04", m.DistributionVersion)
+	assert.Equal(t, runtime.GOARCH, m.Architecture)
+	assert.Equal(t, "apt", m.PackageManager)
+	assert.False(t, m.Created.IsZero())
+	assert.NotNil(t, m.Packages)
+}
+
+func TestManifest_Check(t *testing.T) {
+	m := NewManifest(ubuntu("24.04"), "apt")
+
+	assert.NoError(t, m.Check(ubuntu("24.04"), "apt"))
+
+	err := m.Check(ubuntu("22.04"), "apt")
+	require.Error(t, err)
+	assert.True(t, errors.IsCode(err, errors.Validation))
+	assert.Contains(t, err.Error(), "built for ubuntu 24.04, but this system runs ubuntu 22.04")
+
+	err = m.Check(&distro.Distribution{ID: "debian", VersionID: "24.04"}, "apt")
+	assert.Error(t, err)
+
+	err = m.Check(ubuntu("24.04"), "dnf")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "uses dnf")
+
+	other := *m
+	other.Architecture = "not-" + runtime.GOARCH
+	assert.Error(t, other.Check(ubuntu("24.04"), "apt"))
+
+	assert.Error(t, m.Check(nil, "apt"))
+}
+
+func TestWriteAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManifest(ubuntu("24.04"), "apt")
+	m.DriverVersion = "550"
+	m.Components = []string{"cuda"}
+	m.Packages = []string{"nvidia-driver-550", "nvidia-cuda-toolkit"}
+
+	require.NoError(t, Write(dir, m))
+
+	loaded, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, m.DriverVersion, loaded.DriverVersion)
+	assert.Equal(t, m.Components, loaded.Components)
+	assert.Equal(t, m.Packages, loaded.Packages)
+	assert.True(t, m.Created.Equal(loaded.Created))
+	assert.NoError(t, loaded.Check(ubuntu("24.04"), "apt"))
+}
+
+func TestLoad_Errors(t *testing.T) {
+	t.Run("missing manifest", func(t *testing.T) {
+		_, err := Load(t.TempDir())
+		require.Error(t, err)
+		assert.True(t, errors.IsCode(err, errors.NotFound))
+		assert.Contains(t, err.Error(), "is not a bundle")
+	})
+
+	t.Run("corrupt manifest", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ManifestFile), []byte("{"), 0644))
+
+		_, err := Load(dir)
+		require.Error(t, err)
+		assert.True(t, errors.IsCode(err, errors.Validation))
+	})
+
+	t.Run("unsupported schema", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ManifestFile), []byte(`{"schema_version":"99"}`), 0644))
+
+		_, err := Load(dir)
+		require.Error(t, err)
+		assert.True(t, errors.IsCode(err, errors.Unsupported))
+	})
+}
@@ -46,6 +46,10 @@ type Context struct {
 
 	// Dry run mode
 	DryRun bool
+
+	// Progress of the running step, set by the workflow while a step runs
+	stepProgress   func(message string, progress *pkg.Progress)
+	stepProgressMu sync.RWMutex
 }
 
 // NewContext creates a new installation context with the given options.
@@ -136,6 +140,18 @@ func (c *Context) ClearState() {
 	c.state = make(map[string]interface{})
 }
 
+// StateSnapshot returns a copy of the context state.
+// The map can be modified without affecting the context.
+func (c *Context) StateSnapshot() map[string]interface{} {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	snapshot := make(map[string]interface{}, len(c.state))
+	for key, value := range c.state {
+		snapshot[key] = value
+	}
+	return snapshot
+}
+
 // Context returns the underlying context.Context for cancellation support.
 func (c *Context) Context() context.Context {
 	return c.ctx
@@ -189,6 +205,42 @@ func (c *Context) LogError(msg string, keyvals ...interface{}) {
 	}
 }
 
+// ReportProgress reports the progress of the running step, such as what a
+// long operation is waiting for. Messages are reported as progress of the
+// step through the workflow progress callback. It does nothing when no step
+// is running.
+func (c *Context) ReportProgress(message string) {
+	c.reportStepProgress(message, nil)
+}
+
+// ReportPackageProgress reports the progress of the package manager run by
+// the running step: the package being downloaded or installed and the
+// percentage of the phase. It is reported like ReportProgress, with the
+// package fields of StepProgress set.
+func (c *Context) ReportPackageProgress(progress pkg.Progress) {
+	c.reportStepProgress(progress.String(), &progress)
+}
+
+// reportStepProgress passes the progress of the running step to the
+// function set by the workflow, if any.
+func (c *Context) reportStepProgress(message string, progress *pkg.Progress) {
+	c.stepProgressMu.RLock()
+	report := c.stepProgress
+	c.stepProgressMu.RUnlock()
+	if report != nil {
+		report(message, progress)
+	}
+}
+
+// setStepProgress sets the function receiving the progress reported by the
+// running step, with the package manager progress if any. A nil function
+// stops reporting.
+func (c *Context) setStepProgress(report func(message string, progress *pkg.Progress)) {
+	c.stepProgressMu.Lock()
+	defer c.stepProgressMu.Unlock()
+	c.stepProgress = report
+}
+
 // ContextOption is a functional option for Context.
 type ContextOption func(*Context)
 
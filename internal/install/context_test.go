@@ -11,6 +11,7 @@ import (
 	"github.com/tungetti/igor/internal/exec"
 	"github.com/tungetti/igor/internal/gpu"
 	"github.com/tungetti/igor/internal/logging"
+	"github.com/tungetti/igor/internal/pkg"
 	"github.com/tungetti/igor/internal/privilege"
 )
 
@@ -122,6 +123,21 @@ func TestContext_State(t *testing.T) {
 		assert.False(t, ok1)
 		assert.False(t, ok2)
 	})
+
+	t.Run("state snapshot", func(t *testing.T) {
+		ctx := NewContext()
+
+		ctx.SetState("key1", "value1")
+		ctx.SetState("key2", 42)
+
+		snapshot := ctx.StateSnapshot()
+		assert.Equal(t, map[string]interface{}{"key1": "value1", "key2": 42}, snapshot)
+
+		// The snapshot is a copy
+		snapshot["key3"] = true
+		_, ok := ctx.GetState("key3")
+		assert.False(t, ok)
+	})
 }
 
 func TestContext_StateTypedGetters(t *testing.T) {
@@ -239,6 +255,46 @@ func TestContext_Logging(t *testing.T) {
 	})
 }
 
+func TestContext_ReportProgress(t *testing.T) {
+	t.Run("no step running", func(t *testing.T) {
+		ctx := NewContext()
+
+		// Should not panic
+		ctx.ReportProgress("waiting")
+	})
+
+	t.Run("forwards to the running step", func(t *testing.T) {
+		ctx := NewContext()
+		var messages []string
+		ctx.setStepProgress(func(message string, progress *pkg.Progress) {
+			assert.Nil(t, progress)
+			messages = append(messages, message)
+		})
+
+		ctx.ReportProgress("waiting")
+		ctx.setStepProgress(nil)
+		ctx.ReportProgress("ignored")
+
+		assert.Equal(t, []string{"waiting"}, messages)
+	})
+}
+
+func TestContext_ReportPackageProgress(t *testing.T) {
+	ctx := NewContext()
+	var messages []string
+	var reported []pkg.Progress
+	ctx.setStepProgress(func(message string, progress *pkg.Progress) {
+		require.NotNil(t, progress)
+		messages = append(messages, message)
+		reported = append(reported, *progress)
+	})
+
+	ctx.ReportPackageProgress(pkg.Progress{Phase: pkg.ProgressInstall, Package: "nvidia-driver-550", Percent: 42})
+
+	assert.Equal(t, []string{"Installing nvidia-driver-550 (42%)"}, messages)
+	assert.Equal(t, []pkg.Progress{{Phase: pkg.ProgressInstall, Package: "nvidia-driver-550", Percent: 42}}, reported)
+}
+
 func TestContextOptions(t *testing.T) {
 	t.Run("WithGPUInfo", func(t *testing.T) {
 		gpuInfo := &gpu.GPUInfo{}
@@ -0,0 +1,187 @@
+// Package journal persists the runs of installation workflows for Igor.
+//
+// Each run is saved as a JSON entry holding its execution report and the
+// state its steps stored in the install.Context, such as the installed
+// packages or the path of a configuration backup. Step rollbacks only depend
+// on that state, so a later process can rebuild the steps of a run, restore
+// the state and roll the run back after the process that executed it is gone.
+package journal
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tungetti/igor/internal/install"
+)
+
+// SchemaVersion is the version of the journal entry format.
+const SchemaVersion = "1"
+
+// StatusRunning is the status of a run that has not finished. A run keeps
+// this status if the process executing it was interrupted.
+const StatusRunning = "running"
+
+// Entry is the journal entry of a single run.
+type Entry struct {
+	SchemaVersion string `json:"schema_version"`
+	ID            string `json:"id"`
+	// Command is the igor command that executed the run.
+	Command       string `json:"command"`
+	Workflow      string `json:"workflow"`
+	Distribution  string `json:"distribution,omitempty"`
+	DriverVersion string `json:"driver_version,omitempty"`
+	// Status is StatusRunning or the status of the finished workflow.
+	Status string `json:"status"`
+	// CompletedSteps holds the names of the steps that completed, in order.
+	CompletedSteps []string `json:"completed_steps"`
+	Report         *Report  `json:"report,omitempty"`
+	// State holds the context state of the run that can be persisted.
+	State map[string]StateValue `json:"state"`
+	// RolledBack is set once the run was rolled back with igor rollback.
+	RolledBack *Rollback `json:"rolled_back,omitempty"`
+}
+
+// NewEntry creates a journal entry for a run that starts now.
+func NewEntry(id, command, workflow string) *Entry {
+	return &Entry{
+		SchemaVersion:  SchemaVersion,
+		ID:             id,
+		Command:        command,
+		Workflow:       workflow,
+		Status:         StatusRunning,
+		CompletedSteps: make([]string, 0),
+		Report:         &Report{StartTime: time.Now()},
+		State:          make(map[string]StateValue),
+	}
+}
+
+// StartTime returns the time the run started.
+func (e *Entry) StartTime() time.Time {
+	if e.Report == nil {
+		return time.Time{}
+	}
+	return e.Report.StartTime
+}
+
+// SetReport records the execution report of the finished run.
+func (e *Entry) SetReport(report install.ExecutionReport) {
+	e.Status = report.Status.String()
+	e.CompletedSteps = append([]string{}, report.CompletedSteps...)
+	e.Report = NewReport(report)
+}
+
+// RollbackNeeded reports whether the run changed the system and was not
+// rolled back yet, automatically or with igor rollback. If not, it returns
+// the reason.
+func (e *Entry) RollbackNeeded() (bool, string) {
+	switch {
+	case e.RolledBack != nil && e.RolledBack.Success:
+		return false, fmt.Sprintf("run %s was already rolled back on %s", e.ID, e.RolledBack.Time.Format(time.RFC1123))
+	case e.Report != nil && e.Report.RollbackPerformed && e.Report.RollbackSuccess:
+		return false, fmt.Sprintf("run %s was rolled back automatically when it failed", e.ID)
+	case len(e.CompletedSteps) == 0:
+		return false, fmt.Sprintf("run %s completed no steps", e.ID)
+	default:
+		return true, ""
+	}
+}
+
+// Report is the persisted form of an install.ExecutionReport.
+type Report struct {
+	StartTime         time.Time     `json:"start_time"`
+	EndTime           time.Time     `json:"end_time"`
+	TotalDuration     time.Duration `json:"total_duration"`
+	StepsExecuted     int           `json:"steps_executed"`
+	StepsCompleted    int           `json:"steps_completed"`
+	StepsSkipped      int           `json:"steps_skipped"`
+	StepsFailed       int           `json:"steps_failed"`
+	RollbackPerformed bool          `json:"rollback_performed"`
+	RollbackSuccess   bool          `json:"rollback_success"`
+	FailedStep        string        `json:"failed_step,omitempty"`
+	Error             string        `json:"error,omitempty"`
+	Log               []LogEntry    `json:"log,omitempty"`
+}
+
+// NewReport converts an execution report to its persisted form.
+func NewReport(report install.ExecutionReport) *Report {
+	r := &Report{
+		StartTime:         report.StartTime,
+		EndTime:           report.EndTime,
+		TotalDuration:     report.TotalDuration,
+		StepsExecuted:     report.StepsExecuted,
+		StepsCompleted:    report.StepsCompleted,
+		StepsSkipped:      report.StepsSkipped,
+		StepsFailed:       report.StepsFailed,
+		RollbackPerformed: report.RollbackPerformed,
+		RollbackSuccess:   report.RollbackSuccess,
+		FailedStep:        report.FailedStep,
+	}
+	if report.Error != nil {
+		r.Error = report.Error.Error()
+	}
+	for _, entry := range report.ExecutionLog {
+		logEntry := LogEntry{
+			Timestamp: entry.Timestamp,
+			StepName:  entry.StepName,
+			Event:     entry.EventType.String(),
+			Message:   entry.Message,
+			Duration:  entry.Duration,
+		}
+		if entry.Error != nil {
+			logEntry.Error = entry.Error.Error()
+		}
+		r.Log = append(r.Log, logEntry)
+	}
+	return r
+}
+
+// LogEntry is the persisted form of an install.ExecutionEntry.
+type LogEntry struct {
+	Timestamp time.Time     `json:"timestamp"`
+	StepName  string        `json:"step,omitempty"`
+	Event     string        `json:"event"`
+	Message   string        `json:"message,omitempty"`
+	Duration  time.Duration `json:"duration,omitempty"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// Rollback records a rollback of a run with igor rollback.
+type Rollback struct {
+	Time    time.Time `json:"time"`
+	Success bool      `json:"success"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// NewID returns a new run ID for a run starting at t. IDs sort by time.
+func NewID(t time.Time) string {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		// Fall back to the sub-second part of the time
+		return fmt.Sprintf("%s-%06x", t.Format("20060102-150405"), t.Nanosecond()&0xffffff)
+	}
+	return t.Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// sortNewestFirst sorts entries from the most recent run to the oldest.
+func sortNewestFirst(entries []*Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		ti, tj := entries[i].StartTime(), entries[j].StartTime()
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return entries[i].ID > entries[j].ID
+	})
+}
+
+// marshalEntry encodes an entry as indented JSON.
+func marshalEntry(e *Entry) ([]byte, error) {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
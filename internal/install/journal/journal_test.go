@@ -0,0 +1,151 @@
+package journal
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tungetti/igor/internal/install"
+)
+
+func TestNewID(t *testing.T) {
+	start := time.Date(2026, 10, 16, 10, 38, 34, 0, time.UTC)
+
+	id := NewID(start)
+	assert.Regexp(t, regexp.MustCompile(`^20261016-103834-[0-9a-f]{6}$`), id)
+	assert.True(t, validID.MatchString(id))
+	assert.NotEqual(t, id, NewID(start))
+}
+
+func TestNewEntry(t *testing.T) {
+	e := NewEntry("run1", "install", "ubuntu-nvidia-installation")
+
+	assert.Equal(t, SchemaVersion, e.SchemaVersion)
+	assert.Equal(t, "run1", e.ID)
+	assert.Equal(t, "install", e.Command)
+	assert.Equal(t, StatusRunning, e.Status)
+	assert.NotNil(t, e.CompletedSteps)
+	assert.NotNil(t, e.State)
+	assert.False(t, e.StartTime().IsZero())
+}
+
+func TestEntry_SetReport(t *testing.T) {
+	start := time.Now().Add(-time.Minute)
+	report := install.ExecutionReport{
+		WorkflowName:      "test",
+		Status:            install.WorkflowStatusFailed,
+		StartTime:         start,
+		EndTime:           start.Add(time.Minute),
+		TotalDuration:     time.Minute,
+		StepsExecuted:     3,
+		StepsCompleted:    2,
+		StepsFailed:       1,
+		RollbackPerformed: true,
+		CompletedSteps:    []string{"repository", "packages"},
+		FailedStep:        "dkms_build",
+		Error:             errors.New("build failed"),
+		ExecutionLog: []install.ExecutionEntry{
+			{Timestamp: start, StepName: "dkms_build", EventType: install.EventStepFailed, Message: "failed", Error: errors.New("build failed")},
+		},
+	}
+
+	e := NewEntry("run1", "install", "test")
+	e.SetReport(report)
+
+	assert.Equal(t, "failed", e.Status)
+	assert.Equal(t, []string{"repository", "packages"}, e.CompletedSteps)
+	require.NotNil(t, e.Report)
+	assert.Equal(t, start, e.StartTime())
+	assert.Equal(t, 2, e.Report.StepsCompleted)
+	assert.True(t, e.Report.RollbackPerformed)
+	assert.Equal(t, "dkms_build", e.Report.FailedStep)
+	assert.Equal(t, "build failed", e.Report.Error)
+	require.Len(t, e.Report.Log, 1)
+	assert.Equal(t, install.EventStepFailed.String(), e.Report.Log[0].Event)
+	assert.Equal(t, "build failed", e.Report.Log[0].Error)
+}
+
+func TestEntry_RollbackNeeded(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(e *Entry)
+		needed bool
+		reason string
+	}{
+		{
+			name:   "completed steps",
+			modify: func(e *Entry) {},
+			needed: true,
+		},
+		{
+			name:   "no completed steps",
+			modify: func(e *Entry) { e.CompletedSteps = nil },
+			reason: "completed no steps",
+		},
+		{
+			name: "automatic rollback succeeded",
+			modify: func(e *Entry) {
+				e.Report.RollbackPerformed = true
+				e.Report.RollbackSuccess = true
+			},
+			reason: "rolled back automatically",
+		},
+		{
+			name: "automatic rollback failed",
+			modify: func(e *Entry) {
+				e.Report.RollbackPerformed = true
+			},
+			needed: true,
+		},
+		{
+			name:   "rolled back",
+			modify: func(e *Entry) { e.RolledBack = &Rollback{Time: time.Now(), Success: true} },
+			reason: "already rolled back",
+		},
+		{
+			name:   "rollback failed",
+			modify: func(e *Entry) { e.RolledBack = &Rollback{Time: time.Now(), Error: "failed"} },
+			needed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEntry("run1", "install", "test")
+			e.CompletedSteps = []string{"packages"}
+			tt.modify(e)
+
+			needed, reason := e.RollbackNeeded()
+			assert.Equal(t, tt.needed, needed)
+			if tt.needed {
+				assert.Empty(t, reason)
+			} else {
+				assert.Contains(t, reason, tt.reason)
+			}
+		})
+	}
+}
+
+func TestSortNewestFirst(t *testing.T) {
+	now := time.Now()
+	newEntryAt := func(id string, start time.Time) *Entry {
+		e := NewEntry(id, "install", "test")
+		e.Report.StartTime = start
+		return e
+	}
+
+	entries := []*Entry{
+		newEntryAt("a", now.Add(-time.Hour)),
+		newEntryAt("c", now),
+		newEntryAt("b", now),
+	}
+	sortNewestFirst(entries)
+
+	assert.Equal(t, "c", entries[0].ID)
+	assert.Equal(t, "b", entries[1].ID)
+	assert.Equal(t, "a", entries[2].ID)
+}
@@ -0,0 +1,81 @@
+package journal
+
+import (
+	"sync"
+
+	"github.com/tungetti/igor/internal/install"
+)
+
+// Recorder keeps the journal entry of a run up to date while the run
+// executes. The entry is saved after every step, so a run interrupted by a
+// crash or a reboot can still be rolled back.
+//
+// Saving is best effort: a failure to save does not stop the run. The last
+// error is available from Err.
+type Recorder struct {
+	mu    sync.Mutex
+	store *Store
+	entry *Entry
+	err   error
+}
+
+// NewRecorder creates a recorder that saves entry to store.
+func NewRecorder(store *Store, entry *Entry) *Recorder {
+	return &Recorder{store: store, entry: entry}
+}
+
+// Entry returns the journal entry of the run.
+func (r *Recorder) Entry() *Entry {
+	return r.entry
+}
+
+// Start saves the entry before the first step runs.
+func (r *Recorder) Start() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.save()
+}
+
+// PostStepHook returns a step hook, for install.WithPostStepHook, that
+// records the completed steps and the context state after every step.
+// The hook never fails.
+func (r *Recorder) PostStepHook() install.StepHook {
+	return func(ctx *install.Context, step install.Step, result *install.StepResult) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		if result != nil && result.Status == install.StepStatusCompleted {
+			r.entry.CompletedSteps = append(r.entry.CompletedSteps, step.Name())
+		}
+		r.entry.CaptureState(ctx)
+		_ = r.save()
+		return nil
+	}
+}
+
+// Finish records the execution report and the final context state, and
+// saves the entry.
+func (r *Recorder) Finish(ctx *install.Context, report install.ExecutionReport) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entry.SetReport(report)
+	r.entry.CaptureState(ctx)
+	return r.save()
+}
+
+// Err returns the last error that occurred while saving the entry.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// save saves the entry. The caller must hold the lock.
+func (r *Recorder) save() error {
+	if err := r.store.Save(r.entry); err != nil {
+		r.err = err
+		return err
+	}
+	return nil
+}
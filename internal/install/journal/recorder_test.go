@@ -0,0 +1,102 @@
+package journal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tungetti/igor/internal/install"
+)
+
+func newStateStep(name, key string, status install.StepStatus) install.Step {
+	return install.NewFuncStep(name, "Step "+name, func(ctx *install.Context) install.StepResult {
+		if status == install.StepStatusFailed {
+			return install.FailStep("failed", errors.New(name+" failed"))
+		}
+		ctx.SetState(key, true)
+		return install.NewStepResult(status, "done")
+	}, install.WithRollbackFunc(func(ctx *install.Context) error {
+		ctx.DeleteState(key)
+		return nil
+	}))
+}
+
+func TestRecorder(t *testing.T) {
+	store := NewStore(t.TempDir())
+	recorder := NewRecorder(store, NewEntry("run1", "install", "test"))
+	require.NoError(t, recorder.Start())
+
+	var savedAfterFirstStep *Entry
+	workflow := install.NewWorkflow("test")
+	workflow.AddStep(newStateStep("step1", "step1_done", install.StepStatusCompleted))
+	workflow.AddStep(install.NewFuncStep("inspect", "Inspect journal", func(ctx *install.Context) install.StepResult {
+		savedAfterFirstStep, _ = store.Load("run1")
+		return install.SkipStep("nothing to do")
+	}))
+	workflow.AddStep(newStateStep("step2", "step2_done", install.StepStatusCompleted))
+
+	ctx := install.NewContext()
+	report := install.NewOrchestrator(workflow, install.WithPostStepHook(recorder.PostStepHook())).Execute(ctx)
+	require.Equal(t, install.WorkflowStatusCompleted, report.Status)
+	require.NoError(t, recorder.Finish(ctx, report))
+	assert.NoError(t, recorder.Err())
+
+	// The entry was saved after the first step
+	require.NotNil(t, savedAfterFirstStep)
+	assert.Equal(t, StatusRunning, savedAfterFirstStep.Status)
+	assert.Equal(t, []string{"step1"}, savedAfterFirstStep.CompletedSteps)
+	assert.Contains(t, savedAfterFirstStep.State, "step1_done")
+
+	saved, err := store.Load("run1")
+	require.NoError(t, err)
+	assert.Equal(t, "completed", saved.Status)
+	assert.Equal(t, []string{"step1", "step2"}, saved.CompletedSteps)
+	assert.Contains(t, saved.State, "step2_done")
+	require.NotNil(t, saved.Report)
+	assert.Equal(t, 2, saved.Report.StepsCompleted)
+}
+
+func TestRecorder_FailedRun(t *testing.T) {
+	store := NewStore(t.TempDir())
+	recorder := NewRecorder(store, NewEntry("run1", "install", "test"))
+
+	workflow := install.NewWorkflow("test")
+	workflow.AddStep(newStateStep("step1", "step1_done", install.StepStatusCompleted))
+	workflow.AddStep(newStateStep("step2", "step2_done", install.StepStatusFailed))
+
+	ctx := install.NewContext()
+	report := install.NewOrchestrator(workflow, install.WithPostStepHook(recorder.PostStepHook())).Execute(ctx)
+	require.Equal(t, install.WorkflowStatusFailed, report.Status)
+	require.NoError(t, recorder.Finish(ctx, report))
+
+	saved, err := store.Load("run1")
+	require.NoError(t, err)
+	assert.Equal(t, "failed", saved.Status)
+	assert.Equal(t, []string{"step1"}, saved.CompletedSteps)
+	assert.Equal(t, "step2", saved.Report.FailedStep)
+
+	needed, _ := saved.RollbackNeeded()
+	assert.True(t, needed)
+}
+
+func TestRecorder_SaveErrorDoesNotFailRun(t *testing.T) {
+	// The store directory cannot be created below a regular file
+	blocker := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(blocker, nil, 0600))
+	store := NewStore(filepath.Join(blocker, "runs"))
+
+	recorder := NewRecorder(store, NewEntry("run1", "install", "test"))
+
+	workflow := install.NewWorkflow("test")
+	workflow.AddStep(newStateStep("step1", "step1_done", install.StepStatusCompleted))
+
+	ctx := install.NewContext()
+	report := install.NewOrchestrator(workflow, install.WithPostStepHook(recorder.PostStepHook())).Execute(ctx)
+	assert.Equal(t, install.WorkflowStatusCompleted, report.Status)
+	assert.Error(t, recorder.Err())
+	assert.Error(t, recorder.Finish(ctx, report))
+}
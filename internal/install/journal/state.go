@@ -0,0 +1,118 @@
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tungetti/igor/internal/install"
+)
+
+// Types of persisted state values.
+const (
+	StateTypeBool     = "bool"
+	StateTypeString   = "string"
+	StateTypeStrings  = "strings"
+	StateTypeInt      = "int"
+	StateTypeDuration = "duration"
+)
+
+// StateValue is a persisted context state value. The type is kept so the
+// value is restored with the Go type the steps expect.
+type StateValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// NewStateValue converts a context state value to its persisted form.
+// Only booleans, strings, string slices, integers and durations are
+// persisted, which is what steps store for their rollback; it returns false
+// for any other value, such as reports.
+func NewStateValue(value interface{}) (StateValue, bool) {
+	var typ string
+	switch v := value.(type) {
+	case bool:
+		typ = StateTypeBool
+	case string:
+		typ = StateTypeString
+	case []string:
+		typ = StateTypeStrings
+		if v == nil {
+			value = []string{}
+		}
+	case int:
+		typ = StateTypeInt
+	case time.Duration:
+		typ = StateTypeDuration
+		value = v.String()
+	default:
+		return StateValue{}, false
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return StateValue{}, false
+	}
+	return StateValue{Type: typ, Value: data}, true
+}
+
+// Decode returns the value with its original Go type.
+func (v StateValue) Decode() (interface{}, error) {
+	switch v.Type {
+	case StateTypeBool:
+		var b bool
+		err := json.Unmarshal(v.Value, &b)
+		return b, err
+	case StateTypeString:
+		var s string
+		err := json.Unmarshal(v.Value, &s)
+		return s, err
+	case StateTypeStrings:
+		var s []string
+		err := json.Unmarshal(v.Value, &s)
+		return s, err
+	case StateTypeInt:
+		var i int
+		err := json.Unmarshal(v.Value, &i)
+		return i, err
+	case StateTypeDuration:
+		var s string
+		if err := json.Unmarshal(v.Value, &s); err != nil {
+			return nil, err
+		}
+		return time.ParseDuration(s)
+	default:
+		return nil, fmt.Errorf("unknown state type %q", v.Type)
+	}
+}
+
+// CaptureState records the persistable state of the context, replacing the
+// state recorded before.
+func (e *Entry) CaptureState(ctx *install.Context) {
+	state := make(map[string]StateValue)
+	for key, value := range ctx.StateSnapshot() {
+		if v, ok := NewStateValue(value); ok {
+			state[key] = v
+		}
+	}
+	e.State = state
+}
+
+// RestoreState stores the recorded state in the context.
+func (e *Entry) RestoreState(ctx *install.Context) error {
+	keys := make([]string, 0, len(e.State))
+	for key := range e.State {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value, err := e.State[key].Decode()
+		if err != nil {
+			return fmt.Errorf("invalid state %q: %w", key, err)
+		}
+		ctx.SetState(key, value)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package journal
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tungetti/igor/internal/install"
+)
+
+func TestStateValue_RoundTrip(t *testing.T) {
+	values := []interface{}{
+		true,
+		"/etc/X11/xorg.conf.d/20-nvidia.conf",
+		[]string{"nvidia-driver-550", "nvidia-settings"},
+		42,
+		90 * time.Second,
+	}
+
+	for _, value := range values {
+		v, ok := NewStateValue(value)
+		require.True(t, ok, "%T", value)
+
+		// Values survive encoding as JSON
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+		var decoded StateValue
+		require.NoError(t, json.Unmarshal(data, &decoded))
+
+		got, err := decoded.Decode()
+		require.NoError(t, err)
+		assert.Equal(t, value, got)
+	}
+}
+
+func TestNewStateValue_Unsupported(t *testing.T) {
+	_, ok := NewStateValue(struct{ Passed bool }{true})
+	assert.False(t, ok)
+
+	_, ok = NewStateValue(nil)
+	assert.False(t, ok)
+}
+
+func TestStateValue_Decode_Errors(t *testing.T) {
+	_, err := StateValue{Type: "map", Value: json.RawMessage(`{}`)}.Decode()
+	assert.Error(t, err)
+
+	_, err = StateValue{Type: StateTypeBool, Value: json.RawMessage(`"yes"`)}.Decode()
+	assert.Error(t, err)
+
+	_, err = StateValue{Type: StateTypeDuration, Value: json.RawMessage(`"soon"`)}.Decode()
+	assert.Error(t, err)
+}
+
+func TestEntry_CaptureAndRestoreState(t *testing.T) {
+	ctx := install.NewContext()
+	ctx.SetState("packages_installed", true)
+	ctx.SetState("installed_packages", []string{"nvidia-driver-550"})
+	ctx.SetState("xorg_backup_path", "/etc/X11/xorg.conf.backup")
+	ctx.SetState("package_install_time", 2*time.Minute)
+	ctx.SetState("validation_report", struct{}{})
+
+	e := NewEntry("run1", "install", "test")
+	e.CaptureState(ctx)
+	assert.Len(t, e.State, 4)
+	assert.NotContains(t, e.State, "validation_report")
+
+	restored := install.NewContext()
+	require.NoError(t, e.RestoreState(restored))
+	assert.True(t, restored.GetStateBool("packages_installed"))
+	assert.Equal(t, "/etc/X11/xorg.conf.backup", restored.GetStateString("xorg_backup_path"))
+	packages, _ := restored.GetState("installed_packages")
+	assert.Equal(t, []string{"nvidia-driver-550"}, packages)
+	duration, _ := restored.GetState("package_install_time")
+	assert.Equal(t, 2*time.Minute, duration)
+
+	e.State["broken"] = StateValue{Type: "map"}
+	assert.Error(t, e.RestoreState(install.NewContext()))
+}
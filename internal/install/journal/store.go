@@ -0,0 +1,141 @@
+package journal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/tungetti/igor/internal/errors"
+)
+
+// entryExt is the extension of journal entry files.
+const entryExt = ".json"
+
+// validID matches run IDs. It keeps IDs from escaping the store directory.
+var validID = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// Store saves journal entries as one JSON file per run in a directory.
+// The directory is created on the first save.
+type Store struct {
+	dir string
+}
+
+// NewStore creates a store for the given directory.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Dir returns the directory of the store.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Path returns the path of the file of a run.
+func (s *Store) Path(id string) string {
+	return filepath.Join(s.dir, id+entryExt)
+}
+
+// Save writes an entry, replacing the previous version of the same run.
+// The file is replaced atomically, so an interrupted save leaves the
+// previous version intact.
+func (s *Store) Save(e *Entry) error {
+	const op = "journal.Save"
+
+	if e == nil || !validID.MatchString(e.ID) {
+		return errors.New(errors.Validation, "journal entry has no valid ID").WithOp(op)
+	}
+
+	data, err := marshalEntry(e)
+	if err != nil {
+		return errors.Wrap(errors.Execution, "failed to encode journal entry", err).WithOp(op)
+	}
+
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return errors.Wrap(errors.Execution, "failed to create journal directory", err).WithOp(op)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, "."+e.ID+"-*.tmp")
+	if err != nil {
+		return errors.Wrap(errors.Execution, "failed to create journal file", err).WithOp(op)
+	}
+	tmpPath := tmp.Name()
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, 0600)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, s.Path(e.ID))
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return errors.Wrap(errors.Execution, "failed to write journal file", err).WithOp(op)
+	}
+
+	return nil
+}
+
+// Load reads the entry of a run.
+func (s *Store) Load(id string) (*Entry, error) {
+	const op = "journal.Load"
+
+	if !validID.MatchString(id) {
+		return nil, errors.Newf(errors.Validation, "invalid run ID %q", id).WithOp(op)
+	}
+
+	data, err := os.ReadFile(s.Path(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.Newf(errors.NotFound, "run %s not found", id).WithOp(op)
+		}
+		return nil, errors.Wrap(errors.Execution, "failed to read journal file", err).WithOp(op)
+	}
+
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, errors.Wrapf(errors.Validation, err, "journal file of run %s is corrupt", id).WithOp(op)
+	}
+	if e.SchemaVersion != SchemaVersion {
+		return nil, errors.Newf(errors.Unsupported, "journal file of run %s has unsupported schema version %q", id, e.SchemaVersion).WithOp(op)
+	}
+	if e.ID != id {
+		return nil, errors.Newf(errors.Validation, "journal file of run %s holds run %q", id, e.ID).WithOp(op)
+	}
+
+	return &e, nil
+}
+
+// List returns the entries of all runs, newest first. Files that cannot be
+// read as journal entries are ignored. A missing directory holds no runs.
+func (s *Store) List() ([]*Entry, error) {
+	const op = "journal.List"
+
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*Entry{}, nil
+		}
+		return nil, errors.Wrap(errors.Execution, "failed to read journal directory", err).WithOp(op)
+	}
+
+	entries := make([]*Entry, 0, len(files))
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, entryExt) {
+			continue
+		}
+		e, err := s.Load(strings.TrimSuffix(name, entryExt))
+		if err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	sortNewestFirst(entries)
+	return entries, nil
+}
@@ -0,0 +1,93 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tungetti/igor/internal/errors"
+)
+
+func TestStore_SaveAndLoad(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "runs"))
+
+	e := NewEntry("20261016-103834-abcdef", "install", "ubuntu-nvidia-installation")
+	e.CompletedSteps = append(e.CompletedSteps, "packages")
+	v, _ := NewStateValue([]string{"nvidia-driver-550"})
+	e.State["installed_packages"] = v
+	require.NoError(t, store.Save(e))
+
+	info, err := os.Stat(store.Path(e.ID))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded, err := store.Load(e.ID)
+	require.NoError(t, err)
+	assert.Equal(t, e.ID, loaded.ID)
+	assert.Equal(t, []string{"packages"}, loaded.CompletedSteps)
+	assert.Equal(t, e.State["installed_packages"].Type, loaded.State["installed_packages"].Type)
+
+	// Saving again replaces the entry and leaves no temporary files
+	e.Status = "completed"
+	require.NoError(t, store.Save(e))
+	loaded, err = store.Load(e.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "completed", loaded.Status)
+
+	files, err := os.ReadDir(store.Dir())
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+}
+
+func TestStore_Load_Errors(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	_, err := store.Load("missing")
+	assert.True(t, errors.IsCode(err, errors.NotFound))
+
+	_, err = store.Load("../config")
+	assert.True(t, errors.IsCode(err, errors.Validation))
+
+	require.NoError(t, os.WriteFile(store.Path("corrupt"), []byte("{"), 0600))
+	_, err = store.Load("corrupt")
+	assert.True(t, errors.IsCode(err, errors.Validation))
+
+	require.NoError(t, os.WriteFile(store.Path("future"), []byte(`{"schema_version":"99","id":"future"}`), 0600))
+	_, err = store.Load("future")
+	assert.True(t, errors.IsCode(err, errors.Unsupported))
+}
+
+func TestStore_Save_InvalidID(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	assert.Error(t, store.Save(nil))
+	assert.Error(t, store.Save(NewEntry("../escape", "install", "test")))
+}
+
+func TestStore_List(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	entries, err := NewStore(filepath.Join(t.TempDir(), "missing")).List()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	now := time.Now()
+	for i, id := range []string{"old", "new", "middle"} {
+		e := NewEntry(id, "install", "test")
+		e.Report.StartTime = now.Add(-time.Duration([]int{3, 1, 2}[i]) * time.Hour)
+		require.NoError(t, store.Save(e))
+	}
+	require.NoError(t, os.WriteFile(store.Path("corrupt"), []byte("{"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(store.Dir(), "notes.txt"), []byte("x"), 0600))
+
+	entries, err = store.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "new", entries[0].ID)
+	assert.Equal(t, "middle", entries[1].ID)
+	assert.Equal(t, "old", entries[2].ID)
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/tungetti/igor/internal/pkg"
 )
 
 // ExecutionHook is called before/after workflow execution.
@@ -86,6 +88,8 @@ type ExecutionReport struct {
 	RollbackPerformed bool
 	RollbackSuccess   bool
 	ExecutionLog      []ExecutionEntry
+	CompletedSteps    []string // Names of the steps that completed, in order
+	FailedStep        string   // Name of the step that caused the failure, if any
 	Error             error
 }
 
@@ -305,6 +309,7 @@ func (o *Orchestrator) executeWithHooks(ctx *Context) WorkflowResult {
 			EventType: EventStepStarted,
 			Message:   step.Description(),
 		})
+		o.reportStepProgress(step, i, len(steps), fmt.Sprintf("Starting: %s", step.Description()))
 
 		// Call preStepHook if set
 		if o.preStepHook != nil {
@@ -342,8 +347,16 @@ func (o *Orchestrator) executeWithHooks(ctx *Context) WorkflowResult {
 			return result
 		}
 
-		// Execute step
+		// Execute step, forwarding the progress it reports
+		if ctx != nil {
+			ctx.setStepProgress(func(message string, progress *pkg.Progress) {
+				o.reportPackageProgress(step, i, len(steps), message, progress)
+			})
+		}
 		stepResult := step.Execute(ctx)
+		if ctx != nil {
+			ctx.setStepProgress(nil)
+		}
 		stepDuration := time.Since(stepStartTime)
 
 		// Log step result
@@ -361,6 +374,7 @@ func (o *Orchestrator) executeWithHooks(ctx *Context) WorkflowResult {
 				Message:   stepResult.Message,
 				Duration:  stepDuration,
 			})
+			o.reportStepProgress(step, i, len(steps), fmt.Sprintf("Completed: %s", stepResult.Message))
 
 		case StepStatusSkipped:
 			o.logEvent(ExecutionEntry{
@@ -370,6 +384,7 @@ func (o *Orchestrator) executeWithHooks(ctx *Context) WorkflowResult {
 				Message:   stepResult.Message,
 				Duration:  stepDuration,
 			})
+			o.reportStepProgress(step, i, len(steps), fmt.Sprintf("Skipped: %s", stepResult.Message))
 
 		case StepStatusFailed:
 			result.Status = WorkflowStatusFailed
@@ -383,6 +398,7 @@ func (o *Orchestrator) executeWithHooks(ctx *Context) WorkflowResult {
 				Duration:  stepDuration,
 				Error:     stepResult.Error,
 			})
+			o.reportStepProgress(step, i, len(steps), fmt.Sprintf("Failed: %s", stepResult.Message))
 
 			// Call postStepHook before returning on failure
 			if o.postStepHook != nil {
@@ -430,11 +446,6 @@ func (o *Orchestrator) executeWithHooks(ctx *Context) WorkflowResult {
 				return result
 			}
 		}
-
-		// Report progress
-		if o.progressCallback != nil {
-			o.progressCallback(NewStepProgress(step.Name(), i+1, len(steps), stepResult.Message))
-		}
 	}
 
 	// All steps completed (or none failed fatally with stopOnFirstError=false)
@@ -443,9 +454,34 @@ func (o *Orchestrator) executeWithHooks(ctx *Context) WorkflowResult {
 	}
 	result.TotalDuration = time.Since(startTime)
 
+	if result.Status == WorkflowStatusCompleted && o.progressCallback != nil {
+		o.progressCallback(NewStepProgress("", len(steps), len(steps), "Workflow completed successfully"))
+	}
+
 	return result
 }
 
+// reportStepProgress reports the progress of a step executed with hooks.
+// Indices and messages match those reported by BaseWorkflow.Execute.
+func (o *Orchestrator) reportStepProgress(step Step, index, total int, message string) {
+	if o.progressCallback != nil {
+		o.progressCallback(NewStepProgress(step.Name(), index, total, message))
+	}
+}
+
+// reportPackageProgress reports the progress reported by a running step,
+// with the package manager progress if any.
+func (o *Orchestrator) reportPackageProgress(step Step, index, total int, message string, progress *pkg.Progress) {
+	if o.progressCallback == nil {
+		return
+	}
+	p := NewStepProgress(step.Name(), index, total, message)
+	if progress != nil {
+		p = p.WithPackageProgress(*progress)
+	}
+	o.progressCallback(p)
+}
+
 // ExecuteWithRollback runs the workflow and automatically rolls back on failure.
 func (o *Orchestrator) ExecuteWithRollback(ctx *Context) ExecutionReport {
 	o.mu.Lock()
@@ -620,6 +656,8 @@ func (o *Orchestrator) generateReport(startTime time.Time, result WorkflowResult
 		RollbackPerformed: rollbackPerformed,
 		RollbackSuccess:   rollbackSuccess,
 		ExecutionLog:      executionLog,
+		CompletedSteps:    append([]string{}, result.CompletedSteps...),
+		FailedStep:        result.FailedStep,
 		Error:             result.Error,
 	}
 }
@@ -8,6 +8,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/pkg"
 )
 
 // newMockStep creates a mock step with the given name and status.
@@ -160,6 +161,7 @@ func TestOrchestrator_Execute_StepFailure(t *testing.T) {
 	report := o.Execute(ctx)
 
 	assert.Equal(t, WorkflowStatusFailed, report.Status)
+	assert.Equal(t, "step2", report.FailedStep)
 	assert.Error(t, report.Error)
 	assert.False(t, report.RollbackPerformed)
 
@@ -772,6 +774,94 @@ func TestOrchestrator_Execute_WithProgressCallback_IntegrationWithStepHooks(t *t
 	assert.True(t, len(progressUpdates) >= 2)
 }
 
+func TestOrchestrator_Execute_WithStepHooks_ProgressMatchesWorkflow(t *testing.T) {
+	newSteps := func() []Step {
+		return []Step{
+			NewFuncStep("step1", "Step 1", func(ctx *Context) StepResult {
+				return CompleteStep("done")
+			}),
+			NewFuncStep("step2", "Step 2", func(ctx *Context) StepResult {
+				return SkipStep("not needed")
+			}),
+		}
+	}
+
+	run := func(opts ...OrchestratorOption) []StepProgress {
+		updates := make([]StepProgress, 0)
+		callback := func(p StepProgress) {
+			updates = append(updates, p)
+		}
+
+		o := NewOrchestrator(newMockWorkflow(newSteps()...), append(opts, WithOrchestratorProgress(callback))...)
+		report := o.Execute(NewContext())
+		require.Equal(t, WorkflowStatusCompleted, report.Status)
+		return updates
+	}
+
+	withoutHooks := run()
+	withHooks := run(WithPostStepHook(func(ctx *Context, s Step, r *StepResult) error {
+		return nil
+	}))
+
+	assert.Equal(t, withoutHooks, withHooks)
+	require.Len(t, withHooks, 5)
+	assert.Equal(t, "Starting: Step 1", withHooks[0].Message)
+	assert.Equal(t, "Completed: done", withHooks[1].Message)
+	assert.Equal(t, 1, withHooks[3].StepIndex)
+	assert.Equal(t, "Skipped: not needed", withHooks[3].Message)
+}
+
+func TestOrchestrator_Execute_WithStepHooks_StepReportsProgress(t *testing.T) {
+	updates := make([]StepProgress, 0)
+	step := NewFuncStep("step1", "Step 1", func(ctx *Context) StepResult {
+		ctx.ReportProgress("Waiting for the lock")
+		ctx.ReportPackageProgress(pkg.Progress{Phase: pkg.ProgressDownload, Package: "nvidia-driver-550", Percent: 50})
+		return CompleteStep("done")
+	})
+
+	o := NewOrchestrator(newMockWorkflow(step),
+		WithOrchestratorProgress(func(p StepProgress) {
+			updates = append(updates, p)
+		}),
+		WithPostStepHook(func(ctx *Context, s Step, r *StepResult) error {
+			return nil
+		}),
+	)
+	report := o.Execute(NewContext())
+	require.Equal(t, WorkflowStatusCompleted, report.Status)
+
+	require.GreaterOrEqual(t, len(updates), 4)
+	assert.Equal(t, "Starting: Step 1", updates[0].Message)
+	assert.Equal(t, "Waiting for the lock", updates[1].Message)
+	assert.Equal(t, "step1", updates[1].StepName)
+	assert.Equal(t, "Downloading nvidia-driver-550 (50%)", updates[2].Message)
+	assert.Equal(t, "nvidia-driver-550", updates[2].Package)
+	assert.Equal(t, 25.0, updates[2].Percent)
+	assert.Equal(t, "Completed: done", updates[3].Message)
+}
+
+func TestOrchestrator_Execute_ReportCompletedSteps(t *testing.T) {
+	step1 := newMockStep("step1", StepStatusCompleted)
+	step2 := newMockStep("step2", StepStatusSkipped)
+	step3 := newMockStep("step3", StepStatusCompleted)
+
+	t.Run("without step hooks", func(t *testing.T) {
+		o := NewOrchestrator(newMockWorkflow(step1, step2, step3))
+		report := o.Execute(NewContext())
+		assert.Equal(t, []string{"step1", "step3"}, report.CompletedSteps)
+	})
+
+	t.Run("with step hooks", func(t *testing.T) {
+		o := NewOrchestrator(newMockWorkflow(step1, step2, step3),
+			WithPostStepHook(func(ctx *Context, s Step, r *StepResult) error {
+				return nil
+			}),
+		)
+		report := o.Execute(NewContext())
+		assert.Equal(t, []string{"step1", "step3"}, report.CompletedSteps)
+	})
+}
+
 func TestOrchestrator_Execute_PostExecuteHook_NotOverrideFailure(t *testing.T) {
 	// When workflow fails and post-execute hook also fails,
 	// the original error should be preserved
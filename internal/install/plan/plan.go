@@ -0,0 +1,189 @@
+// Package plan previews installation workflows for Igor.
+// It runs the steps of a workflow against an exec.RecordingExecutor and
+// reports, for each step, the commands it would run, the packages it would
+// install and the files it would write, without changing the system.
+package plan
+
+import (
+	"strings"
+	"time"
+
+	"github.com/tungetti/igor/internal/errors"
+	"github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/install"
+)
+
+// PackageLister is implemented by steps that install packages, such as
+// steps.PackageInstallationStep.
+type PackageLister interface {
+	// Packages returns the packages the step would install.
+	Packages(ctx *install.Context) ([]string, error)
+}
+
+// Command is a command of a plan.
+type Command struct {
+	Command  string   `json:"command"`
+	Args     []string `json:"args"`
+	Elevated bool     `json:"elevated"`
+	// Probe is true for read-only queries, which were run while planning
+	// to inspect the system. All other commands were only recorded.
+	Probe bool `json:"probe"`
+	// Input is written to the standard input of the command.
+	Input string `json:"input,omitempty"`
+}
+
+// String returns the command line of the command.
+func (c Command) String() string {
+	return strings.Join(append([]string{c.Command}, c.Args...), " ")
+}
+
+// File is a file a step would write.
+type File struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	Append  bool   `json:"append,omitempty"`
+}
+
+// Step is the plan of a single workflow step.
+type Step struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Status is the status the step reached while planning.
+	Status   string    `json:"status"`
+	Message  string    `json:"message,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Packages []string  `json:"packages,omitempty"`
+	Files    []File    `json:"files,omitempty"`
+	Commands []Command `json:"commands"`
+}
+
+// Changes returns the commands of the step that change the system, that is
+// every command except the probes.
+func (s Step) Changes() []Command {
+	changes := make([]Command, 0, len(s.Commands))
+	for _, c := range s.Commands {
+		if !c.Probe {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// Plan is the preview of an installation workflow.
+type Plan struct {
+	Workflow    string    `json:"workflow"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Steps       []Step    `json:"steps"`
+}
+
+// Packages returns the packages of all steps, in order.
+func (p *Plan) Packages() []string {
+	packages := make([]string, 0)
+	for _, s := range p.Steps {
+		packages = append(packages, s.Packages...)
+	}
+	return packages
+}
+
+// Generate runs every step of the workflow against the recorder and returns
+// the plan. The context must use the recorder as its executor, and its
+// package manager must have been created with the recorder; otherwise the
+// steps would change the system.
+//
+// Steps run in order, as during installation, but planning continues after
+// a step fails so the whole workflow is shown. Probes see the system as it
+// is, so steps that depend on the changes of earlier steps, such as the DKMS
+// build after the packages are installed, may be skipped or fail here.
+func Generate(ctx *install.Context, workflow install.Workflow, recorder *exec.RecordingExecutor) (*Plan, error) {
+	const op = "plan.Generate"
+
+	if workflow == nil {
+		return nil, errors.New(errors.Validation, "workflow is required").WithOp(op)
+	}
+	if recorder == nil || ctx == nil || ctx.Executor != exec.Executor(recorder) {
+		return nil, errors.New(errors.Validation, "context must use the recording executor").WithOp(op)
+	}
+	if ctx.DryRun {
+		return nil, errors.New(errors.Validation, "context must not be in dry-run mode").WithOp(op)
+	}
+
+	plan := &Plan{
+		Workflow:    workflow.Name(),
+		GeneratedAt: time.Now(),
+		Steps:       make([]Step, 0, len(workflow.Steps())),
+	}
+
+	for _, step := range workflow.Steps() {
+		if ctx.IsCancelled() {
+			return nil, errors.Wrap(errors.Validation, "planning cancelled", ctx.Context().Err()).WithOp(op)
+		}
+
+		recorded := len(recorder.Commands())
+		planned := Step{
+			Name:        step.Name(),
+			Description: step.Description(),
+		}
+
+		if lister, ok := step.(PackageLister); ok {
+			packages, err := lister.Packages(ctx)
+			if err == nil {
+				planned.Packages = packages
+			}
+		}
+
+		var result install.StepResult
+		if err := step.Validate(ctx); err != nil {
+			result = install.FailStep("validation failed", err)
+		} else {
+			result = step.Execute(ctx)
+		}
+
+		planned.Status = result.Status.String()
+		planned.Message = result.Message
+		if result.Error != nil {
+			planned.Error = result.Error.Error()
+		}
+
+		planned.Commands = make([]Command, 0)
+		for _, rc := range recorder.Commands()[recorded:] {
+			c := Command{
+				Command:  rc.Command,
+				Args:     rc.Args,
+				Elevated: rc.Elevated,
+				Probe:    rc.Probe,
+				Input:    string(rc.Input),
+			}
+			planned.Commands = append(planned.Commands, c)
+			if file, ok := writtenFile(c); ok {
+				planned.Files = append(planned.Files, file)
+			}
+		}
+
+		plan.Steps = append(plan.Steps, planned)
+	}
+
+	return plan, nil
+}
+
+// writtenFile returns the file written by a command. Igor writes files as
+// root by piping their content to tee, optionally through sudo.
+func writtenFile(c Command) (File, bool) {
+	args := c.Args
+	if c.Command == "sudo" {
+		if len(args) == 0 {
+			return File{}, false
+		}
+		c.Command, args = args[0], args[1:]
+	}
+	if c.Command != "tee" || len(args) == 0 {
+		return File{}, false
+	}
+
+	file := File{Path: args[len(args)-1], Content: c.Input}
+	for _, arg := range args[:len(args)-1] {
+		if arg == "-a" || arg == "--append" {
+			file.Append = true
+		}
+	}
+	return file, true
+}
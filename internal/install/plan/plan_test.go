@@ -0,0 +1,233 @@
+package plan
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/distro"
+	"github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/gpu/nouveau"
+	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/install/steps"
+	"github.com/tungetti/igor/internal/pkg/apt"
+)
+
+// =============================================================================
+// Mocks
+// =============================================================================
+
+type mockNouveauDetector struct {
+	blacklisted bool
+}
+
+func (m *mockNouveauDetector) Detect(ctx context.Context) (*nouveau.Status, error) {
+	return &nouveau.Status{BlacklistExists: m.blacklisted}, nil
+}
+
+func (m *mockNouveauDetector) IsLoaded(ctx context.Context) (bool, error) {
+	return false, nil
+}
+
+func (m *mockNouveauDetector) IsBlacklisted(ctx context.Context) (bool, error) {
+	return m.blacklisted, nil
+}
+
+func (m *mockNouveauDetector) GetBoundDevices(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+
+// emptyFileWriter is an XorgFileWriter for a system without X.org
+// configuration.
+type emptyFileWriter struct{}
+
+func (emptyFileWriter) WriteFile(path string, content []byte, perm os.FileMode) error {
+	return errors.New("unexpected write")
+}
+
+func (emptyFileWriter) MkdirAll(path string, perm os.FileMode) error {
+	return errors.New("unexpected mkdir")
+}
+
+func (emptyFileWriter) Stat(path string) (os.FileInfo, error) {
+	return nil, os.ErrNotExist
+}
+
+func (emptyFileWriter) Rename(oldpath, newpath string) error {
+	return errors.New("unexpected rename")
+}
+
+func (emptyFileWriter) Remove(path string) error {
+	return errors.New("unexpected remove")
+}
+
+func (emptyFileWriter) ReadFile(path string) ([]byte, error) {
+	return nil, os.ErrNotExist
+}
+
+type mockDisplayDetector struct{}
+
+func (mockDisplayDetector) DetectDisplayServer(ctx context.Context) (string, error) {
+	return "xorg", nil
+}
+
+func (mockDisplayDetector) IsWaylandSession() bool {
+	return false
+}
+
+// newTestPlanContext returns an install context for Ubuntu whose executor
+// and package manager record commands, together with the recorder and the
+// executor that answers probes.
+func newTestPlanContext() (*install.Context, *exec.RecordingExecutor, *exec.MockExecutor) {
+	probes := exec.NewMockExecutor()
+	probes.SetResponse("dpkg-query", exec.FailureResult(1, "no packages found"))
+
+	recorder := exec.NewRecordingExecutor(probes)
+	ctx := install.NewContext(
+		install.WithDistroInfo(&distro.Distribution{ID: "ubuntu", VersionID: "24.04", Family: constants.FamilyDebian}),
+		install.WithExecutor(recorder),
+		install.WithPackageManager(apt.NewManager(recorder, nil)),
+		install.WithDriverVersion("550"),
+	)
+	return ctx, recorder, probes
+}
+
+func newTestWorkflow() install.Workflow {
+	workflow := install.NewWorkflow("test-install")
+	workflow.AddStep(steps.NewNouveauBlacklistStep(steps.WithNouveauDetector(&mockNouveauDetector{})))
+	workflow.AddStep(steps.NewPackageInstallationStep())
+	workflow.AddStep(steps.NewXorgConfigStep(
+		steps.WithXorgFileWriter(emptyFileWriter{}),
+		steps.WithDisplayDetector(mockDisplayDetector{}),
+	))
+	return workflow
+}
+
+// =============================================================================
+// Generate Tests
+// =============================================================================
+
+func TestGenerate(t *testing.T) {
+	ctx, recorder, probes := newTestPlanContext()
+
+	plan, err := Generate(ctx, newTestWorkflow(), recorder)
+	require.NoError(t, err)
+
+	assert.Equal(t, "test-install", plan.Workflow)
+	require.Len(t, plan.Steps, 3)
+
+	nouveauStep := plan.Steps[0]
+	assert.Equal(t, "nouveau_blacklist", nouveauStep.Name)
+	assert.Equal(t, "completed", nouveauStep.Status)
+	require.Len(t, nouveauStep.Files, 1)
+	assert.Equal(t, "/etc/modprobe.d/blacklist-nouveau.conf", nouveauStep.Files[0].Path)
+	assert.Contains(t, nouveauStep.Files[0].Content, "blacklist nouveau")
+	assert.Equal(t, []string{"tee /etc/modprobe.d/blacklist-nouveau.conf", "update-initramfs -u"},
+		commandLines(nouveauStep.Changes()))
+
+	packagesStep := plan.Steps[1]
+	assert.Equal(t, "packages", packagesStep.Name)
+	assert.Contains(t, packagesStep.Packages, "nvidia-driver-550")
+	assert.Equal(t, plan.Packages(), packagesStep.Packages)
+	changes := packagesStep.Changes()
+	require.NotEmpty(t, changes)
+	installCmd := changes[len(changes)-1]
+	assert.True(t, installCmd.Elevated)
+	assert.Contains(t, installCmd.Args, "install")
+	assert.Contains(t, installCmd.Args, "nvidia-driver-550")
+
+	xorgStep := plan.Steps[2]
+	assert.Equal(t, "completed", xorgStep.Status)
+	require.Len(t, xorgStep.Files, 1)
+	assert.Equal(t, "/etc/X11/xorg.conf.d/20-nvidia.conf", xorgStep.Files[0].Path)
+	assert.Contains(t, xorgStep.Files[0].Content, "nvidia")
+
+	// Only probes reached the system.
+	for _, call := range probes.Calls() {
+		assert.False(t, call.Elevated)
+		assert.Nil(t, call.Input)
+	}
+}
+
+func TestGenerate_ContinuesAfterFailure(t *testing.T) {
+	ctx, recorder, _ := newTestPlanContext()
+
+	workflow := install.NewWorkflow("test-install")
+	workflow.AddStep(install.NewFuncStep("failing", "Fails", func(ctx *install.Context) install.StepResult {
+		return install.FailStep("check failed", errors.New("not enough disk space"))
+	}))
+	workflow.AddStep(steps.NewPackageInstallationStep())
+
+	plan, err := Generate(ctx, workflow, recorder)
+	require.NoError(t, err)
+
+	require.Len(t, plan.Steps, 2)
+	assert.Equal(t, "failed", plan.Steps[0].Status)
+	assert.Equal(t, "not enough disk space", plan.Steps[0].Error)
+	assert.Empty(t, plan.Steps[0].Commands)
+	assert.Equal(t, "completed", plan.Steps[1].Status)
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	t.Run("nil workflow", func(t *testing.T) {
+		ctx, recorder, _ := newTestPlanContext()
+		_, err := Generate(ctx, nil, recorder)
+		assert.Error(t, err)
+	})
+
+	t.Run("real executor", func(t *testing.T) {
+		ctx := install.NewContext(install.WithExecutor(exec.NewMockExecutor()))
+		_, err := Generate(ctx, newTestWorkflow(), exec.NewRecordingExecutor(nil))
+		assert.Error(t, err)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		ctx, recorder, _ := newTestPlanContext()
+		ctx.DryRun = true
+		_, err := Generate(ctx, newTestWorkflow(), recorder)
+		assert.Error(t, err)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, recorder, _ := newTestPlanContext()
+		ctx.Cancel()
+		_, err := Generate(ctx, newTestWorkflow(), recorder)
+		assert.Error(t, err)
+	})
+}
+
+func TestWrittenFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		command Command
+		file    File
+		ok      bool
+	}{
+		{"tee", Command{Command: "tee", Args: []string{"/etc/a.conf"}, Input: "x"}, File{Path: "/etc/a.conf", Content: "x"}, true},
+		{"sudo tee", Command{Command: "sudo", Args: []string{"tee", "/etc/b.list"}, Input: "y"}, File{Path: "/etc/b.list", Content: "y"}, true},
+		{"append", Command{Command: "tee", Args: []string{"-a", "/etc/c.conf"}}, File{Path: "/etc/c.conf", Append: true}, true},
+		{"other command", Command{Command: "rm", Args: []string{"-f", "/etc/a.conf"}}, File{}, false},
+		{"bare sudo", Command{Command: "sudo"}, File{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, ok := writtenFile(tt.command)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.file, file)
+		})
+	}
+}
+
+func commandLines(commands []Command) []string {
+	lines := make([]string, 0, len(commands))
+	for _, c := range commands {
+		lines = append(lines, c.String())
+	}
+	return lines
+}
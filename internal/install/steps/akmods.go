@@ -0,0 +1,436 @@
+package steps
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/tungetti/igor/internal/gpu/kernel"
+	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// State keys for akmods module build.
+const (
+	// StateAkmodsBuilt indicates whether the kmod package was built by this step.
+	StateAkmodsBuilt = "akmods_built"
+	// StateAkmodsKernelVersion stores the kernel version the kmod package was built for.
+	StateAkmodsKernelVersion = "akmods_kernel_version"
+	// StateAkmodsKmodPackage stores the kmod package installed by this step,
+	// if it was not installed before.
+	StateAkmodsKmodPackage = "akmods_kmod_package"
+	// StateAkmodsBuildTime stores the duration of the akmods build process.
+	StateAkmodsBuildTime = "akmods_build_time"
+)
+
+// Default values for akmods build step.
+const (
+	// DefaultAkmodsModuleName is the default NVIDIA akmod name.
+	DefaultAkmodsModuleName = "nvidia"
+	// DefaultAkmodsLogDir is the directory where akmods keeps its build logs.
+	DefaultAkmodsLogDir = "/var/cache/akmods"
+	// DefaultAkmodsTimeout is the default timeout for building and installing
+	// the kmod package (10 minutes).
+	DefaultAkmodsTimeout = 10 * time.Minute
+	// DefaultAkmodsPollInterval is the default interval between checks of the
+	// kmod package while waiting for it to be installed.
+	DefaultAkmodsPollInterval = 5 * time.Second
+)
+
+// akmodsLogTailLines is the number of lines of the build log reported when
+// the build fails.
+const akmodsLogTailLines = 20
+
+// AkmodsBuildError is a failed akmods build, with the end of its build log.
+type AkmodsBuildError struct {
+	// Kernel is the kernel version the module was built for.
+	Kernel string
+	// Err is the error of the build.
+	Err error
+	// LogPath is the build log in /var/cache/akmods, empty if not found.
+	LogPath string
+	// LogTail is the last lines of the build log.
+	LogTail []string
+}
+
+// Error returns the build error, followed by the build log if found.
+func (e *AkmodsBuildError) Error() string {
+	if e.LogPath == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%v (build log: %s)", e.Err, e.LogPath)
+}
+
+// Unwrap returns the error of the build.
+func (e *AkmodsBuildError) Unwrap() error {
+	return e.Err
+}
+
+// AkmodsBuildStep builds the NVIDIA kernel module with akmods, as with the
+// akmod-nvidia package of RPM Fusion. akmods builds a kmod-nvidia package for
+// the kernel and installs it; the step waits until it is installed, so that
+// the module exists before the reboot.
+type AkmodsBuildStep struct {
+	install.BaseStep
+	moduleName     string          // akmod name (default: "nvidia")
+	kernelVersion  string          // Specific kernel version (optional, uses the running kernel if empty)
+	kernelDetector kernel.Detector // For getting kernel info
+	logDir         string          // akmods build log directory (default: /var/cache/akmods)
+	timeout        time.Duration   // Build timeout (default: 10 minutes)
+	pollInterval   time.Duration   // Interval between checks of the kmod package
+}
+
+// AkmodsBuildStepOption configures the AkmodsBuildStep.
+type AkmodsBuildStepOption func(*AkmodsBuildStep)
+
+// WithAkmodName sets the akmod name (default: "nvidia").
+func WithAkmodName(name string) AkmodsBuildStepOption {
+	return func(s *AkmodsBuildStep) {
+		s.moduleName = name
+	}
+}
+
+// WithAkmodsKernelVersion sets the specific kernel version to build for.
+// If not set, the running kernel is used.
+func WithAkmodsKernelVersion(version string) AkmodsBuildStepOption {
+	return func(s *AkmodsBuildStep) {
+		s.kernelVersion = version
+	}
+}
+
+// WithAkmodsKernelDetector sets a custom kernel detector.
+// This is primarily used for testing.
+func WithAkmodsKernelDetector(detector kernel.Detector) AkmodsBuildStepOption {
+	return func(s *AkmodsBuildStep) {
+		s.kernelDetector = detector
+	}
+}
+
+// WithAkmodsLogDir sets the directory where akmods keeps its build logs.
+func WithAkmodsLogDir(dir string) AkmodsBuildStepOption {
+	return func(s *AkmodsBuildStep) {
+		s.logDir = dir
+	}
+}
+
+// WithAkmodsTimeout sets the timeout for building and installing the kmod package.
+func WithAkmodsTimeout(timeout time.Duration) AkmodsBuildStepOption {
+	return func(s *AkmodsBuildStep) {
+		s.timeout = timeout
+	}
+}
+
+// WithAkmodsPollInterval sets the interval between checks of the kmod
+// package while waiting for it to be installed.
+func WithAkmodsPollInterval(interval time.Duration) AkmodsBuildStepOption {
+	return func(s *AkmodsBuildStep) {
+		s.pollInterval = interval
+	}
+}
+
+// NewAkmodsBuildStep creates a new AkmodsBuildStep with the given options.
+func NewAkmodsBuildStep(opts ...AkmodsBuildStepOption) *AkmodsBuildStep {
+	s := &AkmodsBuildStep{
+		BaseStep:     install.NewBaseStep("akmods_build", "Build NVIDIA kernel modules with akmods", true),
+		moduleName:   DefaultAkmodsModuleName,
+		logDir:       DefaultAkmodsLogDir,
+		timeout:      DefaultAkmodsTimeout,
+		pollInterval: DefaultAkmodsPollInterval,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Execute builds the NVIDIA kernel module with akmods.
+// It performs the following steps:
+//  1. Checks for cancellation
+//  2. Validates prerequisites (executor available)
+//  3. Checks if akmods is available
+//  4. Gets the kernel version, from kernel.Detector or uname -r
+//  5. Gets the version of the installed akmod-nvidia package
+//  6. Skips the build if kmod-nvidia-<kernel_version> of that version is installed
+//  7. In dry-run mode, logs what would be built
+//  8. Runs akmods --force --kernels <kernel_version> --akmod nvidia
+//  9. Waits for kmod-nvidia-<kernel_version> to be installed, as akmods
+//     leaves the build to an instance already running, such as the one
+//     started by the installation of akmod-nvidia
+//  10. Stores state for rollback
+//
+// On failure, the end of the build log in /var/cache/akmods is reported.
+func (s *AkmodsBuildStep) Execute(ctx *install.Context) install.StepResult {
+	startTime := time.Now()
+
+	// Check for cancellation
+	if ctx.IsCancelled() {
+		return install.FailStep("step cancelled", context.Canceled)
+	}
+
+	ctx.LogDebug("starting akmods module build")
+
+	// Validate prerequisites
+	if err := s.Validate(ctx); err != nil {
+		return install.FailStep("validation failed", err).WithDuration(time.Since(startTime))
+	}
+
+	// Check if akmods is available
+	if !s.isAkmodsAvailable(ctx) {
+		ctx.Log("akmods is not available, skipping module build")
+		return install.SkipStep("akmods is not available").WithDuration(time.Since(startTime))
+	}
+
+	// Get the kernel to build for
+	kernelVersion := s.kernelVersion
+	if kernelVersion == "" {
+		var err error
+		kernelVersion, err = getRunningKernelVersion(ctx, s.kernelDetector)
+		if err != nil {
+			ctx.LogError("failed to get kernel version", "error", err)
+			return install.FailStep("failed to get kernel version", err).WithDuration(time.Since(startTime))
+		}
+	}
+	ctx.LogDebug("using kernel version", "kernel", kernelVersion)
+
+	// Get the version of the akmod package
+	akmodPackage := "akmod-" + s.moduleName
+	version := s.getPackageVersion(ctx, akmodPackage)
+	if version == "" {
+		ctx.Log("akmod package is not installed, skipping build", "package", akmodPackage)
+		return install.SkipStep(fmt.Sprintf("%s is not installed", akmodPackage)).WithDuration(time.Since(startTime))
+	}
+	ctx.LogDebug("using akmod version", "version", version)
+
+	// Skip the build if the kmod package of that version is installed
+	kmodPackage := s.kmodPackage(kernelVersion)
+	installedVersion := s.getPackageVersion(ctx, kmodPackage)
+	if installedVersion == version {
+		ctx.Log("NVIDIA kmod package is already installed", "package", kmodPackage, "version", version)
+		return install.SkipStep(fmt.Sprintf("%s %s is already installed", kmodPackage, version)).WithDuration(time.Since(startTime))
+	}
+
+	// Check for cancellation
+	if ctx.IsCancelled() {
+		return install.FailStep("step cancelled", context.Canceled).WithDuration(time.Since(startTime))
+	}
+
+	// Dry run mode
+	if ctx.DryRun {
+		ctx.Log("dry run: would run akmods", "kernel", kernelVersion, "akmod", s.moduleName)
+		ctx.Log("dry run: would wait for package to be installed", "package", kmodPackage, "version", version)
+		return install.CompleteStep(fmt.Sprintf("dry run: %s %s would be built with akmods", kmodPackage, version)).
+			WithDuration(time.Since(startTime))
+	}
+
+	// Build with timeout context, including the wait for the package
+	buildCtx, cancel := context.WithTimeout(ctx.Context(), s.timeout)
+	defer cancel()
+
+	ctx.Log("building NVIDIA kernel module with akmods", "akmod", s.moduleName, "version", version, "kernel", kernelVersion)
+	ctx.ReportProgress(fmt.Sprintf("Building %s %s with akmods", kmodPackage, version))
+	if err := s.runAkmods(ctx, buildCtx, kernelVersion); err != nil {
+		return s.buildFailed(ctx, kernelVersion, kmodPackage, err).WithDuration(time.Since(startTime))
+	}
+
+	if err := s.waitForKmod(ctx, buildCtx, kmodPackage, version); err != nil {
+		return s.buildFailed(ctx, kernelVersion, kmodPackage, err).WithDuration(time.Since(startTime))
+	}
+
+	// Store state for rollback: a kmod package installed before is upgraded,
+	// and is left in place
+	ctx.SetState(StateAkmodsBuilt, true)
+	ctx.SetState(StateAkmodsKernelVersion, kernelVersion)
+	if installedVersion == "" {
+		ctx.SetState(StateAkmodsKmodPackage, kmodPackage)
+	}
+
+	buildDuration := time.Since(startTime)
+	ctx.SetState(StateAkmodsBuildTime, buildDuration)
+
+	ctx.Log("NVIDIA kmod package built and installed with akmods",
+		"package", kmodPackage,
+		"version", version,
+		"kernel", kernelVersion,
+		"duration", buildDuration)
+
+	return install.CompleteStep(fmt.Sprintf("%s %s built and installed with akmods for kernel %s", kmodPackage, version, kernelVersion)).
+		WithDuration(buildDuration).
+		WithCanRollback(installedVersion == "")
+}
+
+// Rollback removes the kmod package installed during execution.
+// If no kmod package was installed by this step, this is a no-op.
+func (s *AkmodsBuildStep) Rollback(ctx *install.Context) error {
+	kmodPackage := ctx.GetStateString(StateAkmodsKmodPackage)
+	if !ctx.GetStateBool(StateAkmodsBuilt) || kmodPackage == "" {
+		ctx.LogDebug("no kmod package was installed, nothing to rollback")
+		return nil
+	}
+
+	if ctx.PackageManager == nil {
+		return fmt.Errorf("package manager not available for rollback")
+	}
+
+	ctx.Log("rolling back akmods module build", "package", kmodPackage)
+	if err := ctx.PackageManager.Remove(ctx.Context(), pkg.RemoveOptions{NoConfirm: true}, kmodPackage); err != nil {
+		ctx.LogError("failed to remove kmod package during rollback", "package", kmodPackage, "error", err)
+		return fmt.Errorf("failed to remove kmod package '%s': %w", kmodPackage, err)
+	}
+
+	// Clear state
+	ctx.DeleteState(StateAkmodsBuilt)
+	ctx.DeleteState(StateAkmodsKernelVersion)
+	ctx.DeleteState(StateAkmodsKmodPackage)
+	ctx.DeleteState(StateAkmodsBuildTime)
+
+	ctx.LogDebug("akmods module rollback completed")
+	return nil
+}
+
+// Validate checks if the step can be executed with the given context.
+// It ensures the Executor is available for running commands and validates
+// that the akmod name and kernel version contain only safe characters.
+func (s *AkmodsBuildStep) Validate(ctx *install.Context) error {
+	if ctx.Executor == nil {
+		return fmt.Errorf("executor is required for akmods module build")
+	}
+	if !isValidDKMSModuleName(s.moduleName) {
+		return fmt.Errorf("invalid akmod name: %q", s.moduleName)
+	}
+	if s.kernelVersion != "" && !isValidKernelVersion(s.kernelVersion) {
+		return fmt.Errorf("invalid kernel version: %q", s.kernelVersion)
+	}
+	return nil
+}
+
+// CanRollback returns true since the akmods module build can be rolled back.
+func (s *AkmodsBuildStep) CanRollback() bool {
+	return true
+}
+
+// isAkmodsAvailable checks if the akmods command is available on the system.
+func (s *AkmodsBuildStep) isAkmodsAvailable(ctx *install.Context) bool {
+	result := ctx.Executor.Execute(ctx.Context(), "which", "akmods")
+	return result.ExitCode == 0
+}
+
+// kmodPackage returns the name of the kmod package akmods builds for the
+// kernel, such as "kmod-nvidia-6.8.5-301.fc40.x86_64".
+func (s *AkmodsBuildStep) kmodPackage(kernelVersion string) string {
+	return fmt.Sprintf("kmod-%s-%s", s.moduleName, kernelVersion)
+}
+
+// getPackageVersion returns the version of an installed package, or an
+// empty string if it is not installed.
+func (s *AkmodsBuildStep) getPackageVersion(ctx *install.Context, name string) string {
+	result := ctx.Executor.Execute(ctx.Context(), "rpm", "-q", "--qf", "%{VERSION}", name)
+	if result.ExitCode != 0 {
+		return ""
+	}
+	return strings.TrimSpace(string(result.Stdout))
+}
+
+// runAkmods runs akmods for the kernel, which builds the kmod package and
+// installs it.
+func (s *AkmodsBuildStep) runAkmods(ctx *install.Context, buildCtx context.Context, kernelVersion string) error {
+	result := ctx.Executor.ExecuteElevated(buildCtx, "akmods", "--force", "--kernels", kernelVersion, "--akmod", s.moduleName)
+
+	if result.ExitCode != 0 {
+		errMsg := strings.TrimSpace(string(result.Stderr))
+		if errMsg == "" {
+			errMsg = strings.TrimSpace(string(result.Stdout))
+		}
+		if errMsg == "" {
+			errMsg = "unknown error"
+		}
+		return fmt.Errorf("akmods failed: %s", errMsg)
+	}
+
+	return nil
+}
+
+// waitForKmod waits until the kmod package of the version is installed,
+// reporting progress while it waits.
+func (s *AkmodsBuildStep) waitForKmod(ctx *install.Context, buildCtx context.Context, kmodPackage, version string) error {
+	for {
+		if s.getPackageVersion(ctx, kmodPackage) == version {
+			return nil
+		}
+
+		ctx.ReportProgress(fmt.Sprintf("Waiting for %s %s to be installed", kmodPackage, version))
+		select {
+		case <-buildCtx.Done():
+			if ctx.IsCancelled() {
+				return context.Canceled
+			}
+			return fmt.Errorf("%s %s was not installed within %s", kmodPackage, version, s.timeout)
+		case <-time.After(s.pollInterval):
+		}
+	}
+}
+
+// buildFailed returns the result of a failed build, with the end of the
+// build log if found.
+func (s *AkmodsBuildStep) buildFailed(ctx *install.Context, kernelVersion, kmodPackage string, err error) install.StepResult {
+	if ctx.IsCancelled() {
+		return install.FailStep("step cancelled", context.Canceled)
+	}
+
+	ctx.LogError("failed to build kernel module with akmods", "kernel", kernelVersion, "error", err)
+	buildErr := &AkmodsBuildError{Kernel: kernelVersion, Err: err}
+	buildErr.LogPath, buildErr.LogTail = s.readBuildLog(ctx, kernelVersion)
+	if buildErr.LogPath == "" {
+		return install.FailStep(fmt.Sprintf("failed to build %s with akmods", kmodPackage), buildErr)
+	}
+
+	ctx.LogError("akmods build log", "path", buildErr.LogPath, "tail", strings.Join(buildErr.LogTail, "\n"))
+	return install.FailStep(fmt.Sprintf("failed to build %s with akmods, see %s", kmodPackage, buildErr.LogPath), buildErr)
+}
+
+// readBuildLog returns the path and last lines of the newest build log of
+// the kernel, such as
+// /var/cache/akmods/nvidia/550.54.14-1.fc40-for-6.8.5-301.fc40.x86_64.failed.log.
+// A failed log is preferred to the log of a build still running. Returns an
+// empty path if no log is found.
+func (s *AkmodsBuildStep) readBuildLog(ctx *install.Context, kernelVersion string) (string, []string) {
+	dir := path.Join(s.logDir, s.moduleName)
+	result := ctx.Executor.Execute(ctx.Context(), "ls", "-1t", dir)
+	if result.ExitCode != 0 {
+		return "", nil
+	}
+
+	var logName string
+	for _, suffix := range []string{"-for-" + kernelVersion + ".failed.log", "-for-" + kernelVersion + ".log"} {
+		for _, name := range strings.Split(string(result.Stdout), "\n") {
+			if name = strings.TrimSpace(name); strings.HasSuffix(name, suffix) {
+				logName = name
+				break
+			}
+		}
+		if logName != "" {
+			break
+		}
+	}
+	if logName == "" {
+		return "", nil
+	}
+
+	logPath := path.Join(dir, logName)
+	result = ctx.Executor.Execute(ctx.Context(), "cat", logPath)
+	if result.ExitCode != 0 {
+		return logPath, nil
+	}
+
+	lines := strings.Split(strings.TrimRight(string(result.Stdout), "\n"), "\n")
+	if len(lines) > akmodsLogTailLines {
+		lines = lines[len(lines)-akmodsLogTailLines:]
+	}
+	return logPath, lines
+}
+
+// Ensure AkmodsBuildStep implements the Step interface.
+var _ install.Step = (*AkmodsBuildStep)(nil)
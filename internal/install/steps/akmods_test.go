@@ -0,0 +1,410 @@
+package steps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/install"
+)
+
+const (
+	testAkmodsKernel  = "6.8.5-301.fc40.x86_64"
+	testAkmodsVersion = "550.54.14"
+	testKmodPackage   = "kmod-nvidia-6.8.5-301.fc40.x86_64"
+)
+
+// =============================================================================
+// Mock Executor
+// =============================================================================
+
+// akmodsExecutor is a mock executor answering rpm -q with the installed
+// packages, and installing the kmod package when akmods runs.
+type akmodsExecutor struct {
+	*exec.MockExecutor
+	packages     map[string]string // installed package -> version
+	akmodsResult *exec.Result
+	builds       bool // akmods installs the kmod package
+	installAfter int  // the kmod package is installed after this many checks
+	kmodChecks   int
+	logs         []string // files in /var/cache/akmods/nvidia, newest first
+	log          string
+}
+
+func newAkmodsExecutor() *akmodsExecutor {
+	m := &akmodsExecutor{
+		MockExecutor: exec.NewMockExecutor(),
+		packages:     map[string]string{"akmod-nvidia": testAkmodsVersion},
+		akmodsResult: exec.SuccessResult(""),
+		builds:       true,
+	}
+	m.SetDefaultResponse(exec.SuccessResult(""))
+	m.SetResponse("which", exec.SuccessResult("/usr/sbin/akmods"))
+	return m
+}
+
+func (m *akmodsExecutor) Execute(ctx context.Context, cmd string, args ...string) *exec.Result {
+	result := m.MockExecutor.Execute(ctx, cmd, args...)
+	switch cmd {
+	case "rpm":
+		name := args[len(args)-1]
+		if name == testKmodPackage && m.installAfter > 0 {
+			m.kmodChecks++
+			if m.kmodChecks > m.installAfter {
+				m.packages[name] = testAkmodsVersion
+			}
+		}
+		if version, ok := m.packages[name]; ok {
+			return exec.SuccessResult(version)
+		}
+		return exec.FailureResult(1, "package "+name+" is not installed")
+	case "ls":
+		if m.logs == nil {
+			return exec.FailureResult(2, "ls: cannot access '/var/cache/akmods/nvidia': No such file or directory")
+		}
+		return exec.SuccessResult(strings.Join(m.logs, "\n") + "\n")
+	case "cat":
+		return exec.SuccessResult(m.log)
+	}
+	return result
+}
+
+func (m *akmodsExecutor) ExecuteElevated(ctx context.Context, cmd string, args ...string) *exec.Result {
+	result := m.MockExecutor.ExecuteElevated(ctx, cmd, args...)
+	if cmd != "akmods" {
+		return result
+	}
+	if m.akmodsResult.ExitCode == 0 && m.builds {
+		m.packages[testKmodPackage] = testAkmodsVersion
+	}
+	return m.akmodsResult
+}
+
+func newAkmodsTestContext() (*install.Context, *akmodsExecutor) {
+	executor := newAkmodsExecutor()
+	ctx := install.NewContext(install.WithExecutor(executor))
+	return ctx, executor
+}
+
+func newAkmodsTestStep(opts ...AkmodsBuildStepOption) *AkmodsBuildStep {
+	detector := NewMockKernelDetector()
+	detector.SetKernelVersion(testAkmodsKernel)
+	opts = append([]AkmodsBuildStepOption{
+		WithAkmodsKernelDetector(detector),
+		WithAkmodsPollInterval(time.Millisecond),
+	}, opts...)
+	return NewAkmodsBuildStep(opts...)
+}
+
+// =============================================================================
+// AkmodsBuildStep Constructor Tests
+// =============================================================================
+
+func TestNewAkmodsBuildStep_DefaultOptions(t *testing.T) {
+	step := NewAkmodsBuildStep()
+
+	assert.Equal(t, "akmods_build", step.Name())
+	assert.Equal(t, "Build NVIDIA kernel modules with akmods", step.Description())
+	assert.True(t, step.CanRollback())
+	assert.Equal(t, DefaultAkmodsModuleName, step.moduleName)
+	assert.Empty(t, step.kernelVersion)
+	assert.Nil(t, step.kernelDetector)
+	assert.Equal(t, DefaultAkmodsLogDir, step.logDir)
+	assert.Equal(t, DefaultAkmodsTimeout, step.timeout)
+	assert.Equal(t, DefaultAkmodsPollInterval, step.pollInterval)
+}
+
+func TestNewAkmodsBuildStep_WithOptions(t *testing.T) {
+	detector := NewMockKernelDetector()
+
+	step := NewAkmodsBuildStep(
+		WithAkmodName("nvidia-open"),
+		WithAkmodsKernelVersion(testAkmodsKernel),
+		WithAkmodsKernelDetector(detector),
+		WithAkmodsLogDir("/tmp/akmods"),
+		WithAkmodsTimeout(5*time.Minute),
+		WithAkmodsPollInterval(time.Second),
+	)
+
+	assert.Equal(t, "nvidia-open", step.moduleName)
+	assert.Equal(t, testAkmodsKernel, step.kernelVersion)
+	assert.Equal(t, detector, step.kernelDetector)
+	assert.Equal(t, "/tmp/akmods", step.logDir)
+	assert.Equal(t, 5*time.Minute, step.timeout)
+	assert.Equal(t, time.Second, step.pollInterval)
+}
+
+// =============================================================================
+// AkmodsBuildStep Execute Tests
+// =============================================================================
+
+func TestAkmodsBuildStep_Execute_Success(t *testing.T) {
+	ctx, executor := newAkmodsTestContext()
+
+	result := newAkmodsTestStep().Execute(ctx)
+
+	assert.Equal(t, install.StepStatusCompleted, result.Status)
+	assert.Equal(t, "kmod-nvidia-6.8.5-301.fc40.x86_64 550.54.14 built and installed with akmods for kernel 6.8.5-301.fc40.x86_64", result.Message)
+	assert.True(t, result.CanRollback)
+	assert.True(t, executor.WasCalledWith("akmods", "--force", "--kernels", testAkmodsKernel, "--akmod", "nvidia"))
+
+	assert.True(t, ctx.GetStateBool(StateAkmodsBuilt))
+	assert.Equal(t, testAkmodsKernel, ctx.GetStateString(StateAkmodsKernelVersion))
+	assert.Equal(t, testKmodPackage, ctx.GetStateString(StateAkmodsKmodPackage))
+	_, ok := ctx.GetState(StateAkmodsBuildTime)
+	assert.True(t, ok)
+}
+
+func TestAkmodsBuildStep_Execute_WaitsForKmod(t *testing.T) {
+	ctx, executor := newAkmodsTestContext()
+	// An akmods instance started by the installation of akmod-nvidia
+	// is still building the package when akmods returns
+	executor.builds = false
+	executor.installAfter = 3
+
+	w := install.NewWorkflow("test")
+	w.AddStep(newAkmodsTestStep())
+	var progress []string
+	w.OnProgress(func(p install.StepProgress) {
+		progress = append(progress, p.Message)
+	})
+
+	result := w.Execute(ctx)
+
+	assert.Equal(t, install.WorkflowStatusCompleted, result.Status)
+	assert.Contains(t, progress, "Waiting for kmod-nvidia-6.8.5-301.fc40.x86_64 550.54.14 to be installed")
+	assert.Equal(t, 4, executor.kmodChecks)
+}
+
+func TestAkmodsBuildStep_Execute_UpgradesKmod(t *testing.T) {
+	ctx, executor := newAkmodsTestContext()
+	executor.packages[testKmodPackage] = "545.29.06"
+
+	result := newAkmodsTestStep().Execute(ctx)
+
+	assert.Equal(t, install.StepStatusCompleted, result.Status)
+	assert.False(t, result.CanRollback)
+	assert.True(t, executor.WasCalled("akmods"))
+	assert.Empty(t, ctx.GetStateString(StateAkmodsKmodPackage))
+}
+
+func TestAkmodsBuildStep_Execute_AlreadyInstalled(t *testing.T) {
+	ctx, executor := newAkmodsTestContext()
+	executor.packages[testKmodPackage] = testAkmodsVersion
+
+	result := newAkmodsTestStep().Execute(ctx)
+
+	assert.Equal(t, install.StepStatusSkipped, result.Status)
+	assert.Equal(t, "kmod-nvidia-6.8.5-301.fc40.x86_64 550.54.14 is already installed", result.Message)
+	assert.False(t, executor.WasCalled("akmods"))
+}
+
+func TestAkmodsBuildStep_Execute_AkmodsNotAvailable(t *testing.T) {
+	ctx, executor := newAkmodsTestContext()
+	executor.SetResponse("which", exec.FailureResult(1, ""))
+
+	result := newAkmodsTestStep().Execute(ctx)
+
+	assert.Equal(t, install.StepStatusSkipped, result.Status)
+	assert.Equal(t, "akmods is not available", result.Message)
+}
+
+func TestAkmodsBuildStep_Execute_AkmodNotInstalled(t *testing.T) {
+	ctx, executor := newAkmodsTestContext()
+	delete(executor.packages, "akmod-nvidia")
+
+	result := newAkmodsTestStep().Execute(ctx)
+
+	assert.Equal(t, install.StepStatusSkipped, result.Status)
+	assert.Equal(t, "akmod-nvidia is not installed", result.Message)
+	assert.False(t, executor.WasCalled("akmods"))
+}
+
+func TestAkmodsBuildStep_Execute_WithKernelVersion(t *testing.T) {
+	ctx, executor := newAkmodsTestContext()
+	executor.SetResponse("uname", exec.SuccessResult("6.8.4-200.fc40.x86_64"))
+
+	result := NewAkmodsBuildStep(WithAkmodsKernelVersion(testAkmodsKernel)).Execute(ctx)
+
+	assert.Equal(t, install.StepStatusCompleted, result.Status)
+	assert.False(t, executor.WasCalled("uname"))
+	assert.True(t, executor.WasCalledWith("akmods", "--force", "--kernels", testAkmodsKernel, "--akmod", "nvidia"))
+}
+
+func TestAkmodsBuildStep_Execute_DryRun(t *testing.T) {
+	ctx, executor := newAkmodsTestContext()
+	ctx.DryRun = true
+
+	result := newAkmodsTestStep().Execute(ctx)
+
+	assert.Equal(t, install.StepStatusCompleted, result.Status)
+	assert.Equal(t, "dry run: kmod-nvidia-6.8.5-301.fc40.x86_64 550.54.14 would be built with akmods", result.Message)
+	assert.False(t, executor.WasCalled("akmods"))
+	assert.False(t, ctx.GetStateBool(StateAkmodsBuilt))
+}
+
+func TestAkmodsBuildStep_Execute_BuildFails(t *testing.T) {
+	var log strings.Builder
+	for i := 1; i <= 30; i++ {
+		fmt.Fprintf(&log, "line %d\n", i)
+	}
+
+	t.Run("with build log", func(t *testing.T) {
+		ctx, executor := newAkmodsTestContext()
+		executor.akmodsResult = exec.FailureResult(1, "Building and installing nvidia-kmod [FAILED]")
+		executor.logs = []string{
+			"550.54.14-1.fc40-for-6.8.4-200.fc40.x86_64.failed.log",
+			"550.54.14-1.fc40-for-6.8.5-301.fc40.x86_64.log",
+			"550.54.14-1.fc40-for-6.8.5-301.fc40.x86_64.failed.log",
+		}
+		executor.log = log.String()
+
+		result := newAkmodsTestStep().Execute(ctx)
+
+		assert.Equal(t, install.StepStatusFailed, result.Status)
+		logPath := "/var/cache/akmods/nvidia/550.54.14-1.fc40-for-6.8.5-301.fc40.x86_64.failed.log"
+		assert.Equal(t, "failed to build kmod-nvidia-6.8.5-301.fc40.x86_64 with akmods, see "+logPath, result.Message)
+		assert.True(t, executor.WasCalledWith("cat", logPath))
+
+		var buildErr *AkmodsBuildError
+		require.True(t, errors.As(result.Error, &buildErr))
+		assert.Equal(t, testAkmodsKernel, buildErr.Kernel)
+		assert.Equal(t, logPath, buildErr.LogPath)
+		require.Len(t, buildErr.LogTail, 20)
+		assert.Equal(t, "line 11", buildErr.LogTail[0])
+		assert.Equal(t, "line 30", buildErr.LogTail[19])
+		assert.Equal(t, "akmods failed: Building and installing nvidia-kmod [FAILED] (build log: "+logPath+")", buildErr.Error())
+		assert.False(t, ctx.GetStateBool(StateAkmodsBuilt))
+	})
+
+	t.Run("without build log", func(t *testing.T) {
+		ctx, executor := newAkmodsTestContext()
+		executor.akmodsResult = exec.FailureResult(1, "")
+
+		result := newAkmodsTestStep().Execute(ctx)
+
+		assert.Equal(t, install.StepStatusFailed, result.Status)
+		assert.Equal(t, "failed to build kmod-nvidia-6.8.5-301.fc40.x86_64 with akmods", result.Message)
+		assert.EqualError(t, result.Error, "akmods failed: unknown error")
+	})
+}
+
+func TestAkmodsBuildStep_Execute_KmodNotInstalled(t *testing.T) {
+	ctx, executor := newAkmodsTestContext()
+	executor.builds = false
+
+	result := newAkmodsTestStep(WithAkmodsTimeout(20 * time.Millisecond)).Execute(ctx)
+
+	assert.Equal(t, install.StepStatusFailed, result.Status)
+	assert.Contains(t, result.Error.Error(), "kmod-nvidia-6.8.5-301.fc40.x86_64 550.54.14 was not installed within 20ms")
+}
+
+func TestAkmodsBuildStep_Execute_Cancelled(t *testing.T) {
+	ctx, executor := newAkmodsTestContext()
+	ctx.Cancel()
+
+	result := newAkmodsTestStep().Execute(ctx)
+
+	assert.Equal(t, install.StepStatusFailed, result.Status)
+	assert.ErrorIs(t, result.Error, context.Canceled)
+	assert.False(t, executor.WasCalled("akmods"))
+}
+
+func TestAkmodsBuildStep_Execute_NoExecutor(t *testing.T) {
+	result := NewAkmodsBuildStep().Execute(install.NewContext())
+
+	assert.Equal(t, install.StepStatusFailed, result.Status)
+	assert.Contains(t, result.Error.Error(), "executor is required")
+}
+
+// =============================================================================
+// AkmodsBuildStep Rollback Tests
+// =============================================================================
+
+func TestAkmodsBuildStep_Rollback(t *testing.T) {
+	t.Run("removes the kmod package", func(t *testing.T) {
+		ctx, _ := newAkmodsTestContext()
+		pm := NewPackageMockManager()
+		ctx.PackageManager = pm
+		ctx.SetState(StateAkmodsBuilt, true)
+		ctx.SetState(StateAkmodsKernelVersion, testAkmodsKernel)
+		ctx.SetState(StateAkmodsKmodPackage, testKmodPackage)
+
+		err := NewAkmodsBuildStep().Rollback(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{testKmodPackage}, pm.removePackages)
+		assert.False(t, ctx.GetStateBool(StateAkmodsBuilt))
+		assert.Empty(t, ctx.GetStateString(StateAkmodsKmodPackage))
+	})
+
+	t.Run("upgraded kmod package is left in place", func(t *testing.T) {
+		ctx, _ := newAkmodsTestContext()
+		pm := NewPackageMockManager()
+		ctx.PackageManager = pm
+		ctx.SetState(StateAkmodsBuilt, true)
+
+		require.NoError(t, NewAkmodsBuildStep().Rollback(ctx))
+		assert.False(t, pm.removeCalled)
+	})
+
+	t.Run("remove fails", func(t *testing.T) {
+		ctx, _ := newAkmodsTestContext()
+		pm := NewPackageMockManager()
+		pm.SetRemoveError(errors.New("package is in use"))
+		ctx.PackageManager = pm
+		ctx.SetState(StateAkmodsBuilt, true)
+		ctx.SetState(StateAkmodsKmodPackage, testKmodPackage)
+
+		err := NewAkmodsBuildStep().Rollback(ctx)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "package is in use")
+		assert.True(t, ctx.GetStateBool(StateAkmodsBuilt))
+	})
+
+	t.Run("no package manager", func(t *testing.T) {
+		ctx, _ := newAkmodsTestContext()
+		ctx.SetState(StateAkmodsBuilt, true)
+		ctx.SetState(StateAkmodsKmodPackage, testKmodPackage)
+
+		assert.Error(t, NewAkmodsBuildStep().Rollback(ctx))
+	})
+}
+
+func TestAkmodsBuildStep_FullWorkflow_ExecuteAndRollback(t *testing.T) {
+	ctx, _ := newAkmodsTestContext()
+	pm := NewPackageMockManager()
+	ctx.PackageManager = pm
+	step := newAkmodsTestStep()
+
+	result := step.Execute(ctx)
+	require.Equal(t, install.StepStatusCompleted, result.Status)
+
+	require.NoError(t, step.Rollback(ctx))
+	assert.Equal(t, []string{testKmodPackage}, pm.removePackages)
+}
+
+// =============================================================================
+// AkmodsBuildStep Validate Tests
+// =============================================================================
+
+func TestAkmodsBuildStep_Validate(t *testing.T) {
+	ctx, _ := newAkmodsTestContext()
+
+	assert.NoError(t, NewAkmodsBuildStep().Validate(ctx))
+	assert.Error(t, NewAkmodsBuildStep().Validate(install.NewContext()))
+	assert.Error(t, NewAkmodsBuildStep(WithAkmodName("nvidia; rm -rf /")).Validate(ctx))
+	assert.Error(t, NewAkmodsBuildStep(WithAkmodsKernelVersion("6.8.5 --force")).Validate(ctx))
+}
+
+func TestAkmodsBuildStep_InterfaceCompliance(t *testing.T) {
+	var _ install.Step = (*AkmodsBuildStep)(nil)
+	var _ install.Step = NewAkmodsBuildStep()
+}
@@ -0,0 +1,164 @@
+package steps
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/pkg"
+	"github.com/tungetti/igor/internal/pkg/nvidia"
+)
+
+// State keys for repository conflict resolution.
+const (
+	// StateRepositoryAnalysis stores the *nvidia.RepositoryAnalysis of the
+	// enabled repositories, set by the repository conflict validation check.
+	StateRepositoryAnalysis = "repository_analysis"
+	// StateDisabledRepositories stores the names of the repositories
+	// disabled to resolve conflicts.
+	StateDisabledRepositories = "disabled_repositories"
+)
+
+// RepositoryConflictStep disables the repositories competing with the
+// preferred source of NVIDIA packages, so that all the driver packages come
+// from a single source.
+type RepositoryConflictStep struct {
+	install.BaseStep
+}
+
+// NewRepositoryConflictStep creates a new repository conflict resolution step.
+func NewRepositoryConflictStep() *RepositoryConflictStep {
+	return &RepositoryConflictStep{
+		BaseStep: install.NewBaseStep("repository_conflicts", "Disable conflicting NVIDIA repositories", true),
+	}
+}
+
+// Execute disables the repositories competing with the preferred NVIDIA
+// package source. It performs the following steps:
+//  1. Analyzes the enabled repositories for competing NVIDIA sources
+//  2. In dry-run mode, logs the repositories it would disable
+//  3. Disables the repositories of the competing sources, re-enabling the
+//     ones already disabled if one fails
+//  4. Updates the package lists, so the disabled repositories are no longer
+//     used
+//  5. Stores the disabled repositories for potential rollback
+func (s *RepositoryConflictStep) Execute(ctx *install.Context) install.StepResult {
+	startTime := time.Now()
+
+	if ctx.IsCancelled() {
+		return install.FailStep("step cancelled", context.Canceled)
+	}
+
+	if err := s.Validate(ctx); err != nil {
+		return install.FailStep("validation failed", err).WithDuration(time.Since(startTime))
+	}
+
+	analysis, err := nvidia.AnalyzeRepositories(ctx.Context(), ctx.PackageManager, ctx.DistroInfo)
+	if err != nil {
+		ctx.LogError("failed to analyze repositories", "error", err)
+		return install.FailStep("failed to analyze repositories", err).WithDuration(time.Since(startTime))
+	}
+	ctx.SetState(StateRepositoryAnalysis, analysis)
+
+	repos := analysis.CompetingRepositories()
+	if len(repos) == 0 {
+		ctx.LogDebug("no conflicting repositories")
+		return install.CompleteStep("no conflicting repositories").WithDuration(time.Since(startTime))
+	}
+
+	keep := analysis.Preferred().Name
+	if ctx.DryRun {
+		for _, repo := range repos {
+			ctx.Log("dry run: would disable repository", "name", repo.Name, "keep", keep)
+		}
+		return install.CompleteStep(fmt.Sprintf("dry run: %d conflicting repositories would be disabled", len(repos))).
+			WithDuration(time.Since(startTime))
+	}
+
+	disabled := make([]string, 0, len(repos))
+	for _, repo := range repos {
+		if ctx.IsCancelled() {
+			s.enableRepositories(ctx, disabled)
+			return install.FailStep("step cancelled", context.Canceled).WithDuration(time.Since(startTime))
+		}
+
+		ctx.Log("disabling conflicting repository", "name", repo.Name, "keep", keep)
+		if err := ctx.PackageManager.DisableRepository(ctx.Context(), repo.Name); err != nil {
+			ctx.LogError("failed to disable repository", "name", repo.Name, "error", err)
+			s.enableRepositories(ctx, disabled)
+			return install.FailStep(fmt.Sprintf("failed to disable repository %s", repo.Name), err).
+				WithDuration(time.Since(startTime))
+		}
+		disabled = append(disabled, repo.Name)
+	}
+
+	// The repositories are disabled either way; stale package lists only
+	// delay the change until the next update.
+	if err := ctx.PackageManager.Update(ctx.Context(), pkg.DefaultUpdateOptions()); err != nil {
+		ctx.LogWarn("failed to update package lists", "error", err)
+	}
+
+	ctx.SetState(StateDisabledRepositories, disabled)
+
+	ctx.Log("conflicting repositories disabled", "repositories", strings.Join(disabled, ", "), "keep", keep)
+	return install.CompleteStep(fmt.Sprintf("disabled %s", strings.Join(disabled, ", "))).
+		WithDuration(time.Since(startTime)).
+		WithCanRollback(true)
+}
+
+// enableRepositories re-enables the given repositories, logging failures.
+func (s *RepositoryConflictStep) enableRepositories(ctx *install.Context, names []string) []string {
+	var failed []string
+	for _, name := range names {
+		if err := ctx.PackageManager.EnableRepository(ctx.Context(), name); err != nil {
+			ctx.LogError("failed to re-enable repository", "name", name, "error", err)
+			failed = append(failed, name)
+		}
+	}
+	return failed
+}
+
+// Rollback re-enables the repositories disabled during execution.
+// If no repository was disabled, this is a no-op.
+func (s *RepositoryConflictStep) Rollback(ctx *install.Context) error {
+	disabled, _ := ctx.GetState(StateDisabledRepositories)
+	names, ok := disabled.([]string)
+	if !ok || len(names) == 0 {
+		ctx.LogDebug("no repository was disabled, nothing to rollback")
+		return nil
+	}
+
+	if ctx.PackageManager == nil {
+		return fmt.Errorf("package manager not available for rollback")
+	}
+
+	ctx.Log("re-enabling disabled repositories", "repositories", strings.Join(names, ", "))
+	if failed := s.enableRepositories(ctx, names); len(failed) > 0 {
+		return fmt.Errorf("failed to re-enable repositories: %s", strings.Join(failed, ", "))
+	}
+
+	ctx.DeleteState(StateDisabledRepositories)
+	return nil
+}
+
+// Validate checks if the step can be executed with the given context.
+// It ensures both PackageManager and DistroInfo are available.
+func (s *RepositoryConflictStep) Validate(ctx *install.Context) error {
+	if ctx.PackageManager == nil {
+		return fmt.Errorf("package manager is required for repository conflict resolution")
+	}
+	if ctx.DistroInfo == nil {
+		return fmt.Errorf("distribution info is required for repository conflict resolution")
+	}
+	return nil
+}
+
+// CanRollback returns true since disabled repositories can be re-enabled.
+func (s *RepositoryConflictStep) CanRollback() bool {
+	return true
+}
+
+// Ensure RepositoryConflictStep implements the Step interface.
+var _ install.Step = (*RepositoryConflictStep)(nil)
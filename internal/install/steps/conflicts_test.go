@@ -0,0 +1,161 @@
+package steps
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// newConflictingPackageManager returns a package manager with the
+// graphics-drivers PPA and the CUDA repository enabled.
+func newConflictingPackageManager() *MockPackageManager {
+	pm := NewMockPackageManager()
+	pm.repos = []pkg.Repository{
+		{Name: "ubuntu", Enabled: true},
+		{Name: "graphics-drivers-ubuntu-ppa-jammy", Enabled: true},
+		{Name: "cuda-ubuntu2204-x86_64", Enabled: true},
+	}
+	return pm
+}
+
+func TestNewRepositoryConflictStep(t *testing.T) {
+	step := NewRepositoryConflictStep()
+
+	assert.Equal(t, "repository_conflicts", step.Name())
+	assert.Equal(t, "Disable conflicting NVIDIA repositories", step.Description())
+	assert.True(t, step.CanRollback())
+}
+
+func TestRepositoryConflictStep_Execute_Success(t *testing.T) {
+	pm := newConflictingPackageManager()
+	step := NewRepositoryConflictStep()
+	ctx := install.NewContext(install.WithPackageManager(pm), install.WithDistroInfo(newUbuntuDistro()))
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusCompleted, result.Status)
+	assert.Equal(t, "disabled cuda-ubuntu2204-x86_64", result.Message)
+	assert.True(t, result.CanRollback)
+	assert.Equal(t, []string{"cuda-ubuntu2204-x86_64"}, pm.disabledRepos)
+	assert.True(t, pm.updateCalled)
+	assert.Equal(t, []string{"cuda-ubuntu2204-x86_64"}, getStringSliceFromState(ctx, StateDisabledRepositories))
+}
+
+func TestRepositoryConflictStep_Execute_NoConflicts(t *testing.T) {
+	pm := NewMockPackageManager()
+	pm.repos = []pkg.Repository{{Name: "graphics-drivers-ubuntu-ppa-jammy", Enabled: true}}
+	step := NewRepositoryConflictStep()
+	ctx := install.NewContext(install.WithPackageManager(pm), install.WithDistroInfo(newUbuntuDistro()))
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusCompleted, result.Status)
+	assert.Equal(t, "no conflicting repositories", result.Message)
+	assert.Empty(t, pm.disabledRepos)
+	assert.False(t, pm.updateCalled)
+}
+
+func TestRepositoryConflictStep_Execute_DryRun(t *testing.T) {
+	pm := newConflictingPackageManager()
+	step := NewRepositoryConflictStep()
+	ctx := install.NewContext(
+		install.WithPackageManager(pm),
+		install.WithDistroInfo(newUbuntuDistro()),
+		install.WithDryRun(true),
+	)
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusCompleted, result.Status)
+	assert.Contains(t, result.Message, "dry run")
+	assert.Empty(t, pm.disabledRepos)
+}
+
+func TestRepositoryConflictStep_Execute_DisableFails(t *testing.T) {
+	pm := newConflictingPackageManager()
+	pm.repos = append(pm.repos, pkg.Repository{Name: "cuda-ubuntu2204-sbsa", Enabled: true})
+	pm.disableRepoErr = map[string]error{"cuda-ubuntu2204-sbsa": errors.New("permission denied")}
+	step := NewRepositoryConflictStep()
+	ctx := install.NewContext(install.WithPackageManager(pm), install.WithDistroInfo(newUbuntuDistro()))
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusFailed, result.Status)
+	assert.Contains(t, result.Message, "failed to disable repository cuda-ubuntu2204-sbsa")
+	assert.Equal(t, []string{"cuda-ubuntu2204-x86_64"}, pm.enabledRepos)
+}
+
+func TestRepositoryConflictStep_Execute_ListFails(t *testing.T) {
+	pm := NewMockPackageManager()
+	pm.listReposErr = errors.New("permission denied")
+	step := NewRepositoryConflictStep()
+	ctx := install.NewContext(install.WithPackageManager(pm), install.WithDistroInfo(newUbuntuDistro()))
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusFailed, result.Status)
+	assert.Equal(t, "failed to analyze repositories", result.Message)
+}
+
+func TestRepositoryConflictStep_Execute_UpdateFails(t *testing.T) {
+	pm := newConflictingPackageManager()
+	pm.SetUpdateError(errors.New("network unreachable"))
+	step := NewRepositoryConflictStep()
+	ctx := install.NewContext(install.WithPackageManager(pm), install.WithDistroInfo(newUbuntuDistro()))
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusCompleted, result.Status)
+	assert.Equal(t, []string{"cuda-ubuntu2204-x86_64"}, pm.disabledRepos)
+}
+
+func TestRepositoryConflictStep_Execute_MissingPrerequisites(t *testing.T) {
+	step := NewRepositoryConflictStep()
+
+	result := step.Execute(install.NewContext(install.WithDistroInfo(newUbuntuDistro())))
+	assert.Equal(t, install.StepStatusFailed, result.Status)
+
+	result = step.Execute(install.NewContext(install.WithPackageManager(NewMockPackageManager())))
+	assert.Equal(t, install.StepStatusFailed, result.Status)
+}
+
+func TestRepositoryConflictStep_Rollback(t *testing.T) {
+	pm := newConflictingPackageManager()
+	step := NewRepositoryConflictStep()
+	ctx := install.NewContext(install.WithPackageManager(pm), install.WithDistroInfo(newUbuntuDistro()))
+
+	result := step.Execute(ctx)
+	require.Equal(t, install.StepStatusCompleted, result.Status)
+
+	require.NoError(t, step.Rollback(ctx))
+	assert.Equal(t, []string{"cuda-ubuntu2204-x86_64"}, pm.enabledRepos)
+	_, ok := ctx.GetState(StateDisabledRepositories)
+	assert.False(t, ok)
+}
+
+func TestRepositoryConflictStep_Rollback_NothingDisabled(t *testing.T) {
+	pm := NewMockPackageManager()
+	step := NewRepositoryConflictStep()
+	ctx := install.NewContext(install.WithPackageManager(pm))
+
+	assert.NoError(t, step.Rollback(ctx))
+	assert.Empty(t, pm.enabledRepos)
+}
+
+func TestRepositoryConflictStep_Rollback_EnableFails(t *testing.T) {
+	pm := NewMockPackageManager()
+	pm.enableRepoErr = errors.New("permission denied")
+	step := NewRepositoryConflictStep()
+	ctx := install.NewContext(install.WithPackageManager(pm))
+	ctx.SetState(StateDisabledRepositories, []string{"cuda-ubuntu2204-x86_64"})
+
+	err := step.Rollback(ctx)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cuda-ubuntu2204-x86_64")
+	assert.Equal(t, []string{"cuda-ubuntu2204-x86_64"}, getStringSliceFromState(ctx, StateDisabledRepositories))
+}
@@ -0,0 +1,147 @@
+package steps
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/gpu/validator"
+	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/pkg"
+	"github.com/tungetti/igor/internal/pkg/nvidia"
+)
+
+// StateDiskUsage stores the []pkg.DiskUsage of the installation
+// transaction, with the free space of the filesystems receiving the files.
+const StateDiskUsage = "disk_usage"
+
+// PackageLister is implemented by steps that install packages, such as
+// PackageInstallationStep.
+type PackageLister interface {
+	// Packages returns the packages the step would install.
+	Packages(ctx *install.Context) ([]string, error)
+}
+
+// EstimateDiskUsage returns the disk space a simulated transaction needs
+// in each directory on the distribution family, with the free space of the
+// filesystems holding them. Directories that do not exist yet are measured
+// on the filesystem of their closest existing parent; directories whose
+// free space cannot be read are left unmeasured.
+func EstimateDiskUsage(ctx context.Context, executor exec.Executor, family constants.DistroFamily, sim *pkg.Simulation) []pkg.DiskUsage {
+	usage := sim.DiskUsage(func(name string) bool {
+		return nvidia.InstallsInOpt(family, name)
+	})
+	for i := range usage {
+		usage[i].MountPoint, usage[i].Available = measureFreeSpace(ctx, executor, usage[i].Dir)
+	}
+	return usage
+}
+
+// measureFreeSpace returns the mount point and the free space in bytes of
+// the filesystem holding dir, or an empty mount point if df cannot tell.
+func measureFreeSpace(ctx context.Context, executor exec.Executor, dir string) (string, int64) {
+	for {
+		result := executor.Execute(ctx, "df", "-P", "-B1", dir)
+		if result.Success() {
+			return parseDfFreeSpace(result.StdoutString())
+		}
+		if dir == "/" {
+			return "", 0
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// parseDfFreeSpace parses the output of df -P -B1 for a single path and
+// returns the mount point and the available space in bytes.
+//
+// Example:
+//
+//	Filesystem        1-blocks        Used   Available Capacity Mounted on
+//	/dev/nvme0n1p2 510405828608 98463387648 386035126272      21% /
+func parseDfFreeSpace(output string) (string, int64) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) < 2 {
+		return "", 0
+	}
+	fields := strings.Fields(lines[1])
+	if len(fields) < 6 {
+		return "", 0
+	}
+	available, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return "", 0
+	}
+	return strings.Join(fields[5:], " "), available
+}
+
+// estimateTransactionDiskUsage simulates the installation of the packages
+// and estimates its disk usage. It returns nil if the package manager cannot
+// simulate the transaction, or no free space could be measured.
+func estimateTransactionDiskUsage(ctx *install.Context, opts pkg.InstallOptions, packages []string) []pkg.DiskUsage {
+	simulator, ok := ctx.PackageManager.(pkg.SimulationManager)
+	if !ok || ctx.Executor == nil || ctx.DistroInfo == nil || len(packages) == 0 {
+		return nil
+	}
+
+	sim, err := simulator.SimulateInstall(ctx.Context(), opts, packages...)
+	if err != nil {
+		ctx.LogDebug("failed to simulate package installation", "error", err)
+		return nil
+	}
+	return measuredDiskUsage(ctx, sim)
+}
+
+// measuredDiskUsage estimates the disk usage of a simulated transaction and
+// stores it in the context. It returns nil if no free space was measured.
+func measuredDiskUsage(ctx *install.Context, sim *pkg.Simulation) []pkg.DiskUsage {
+	if ctx.Executor == nil || ctx.DistroInfo == nil {
+		return nil
+	}
+
+	usage := EstimateDiskUsage(ctx.Context(), ctx.Executor, ctx.DistroInfo.Family, sim)
+	for _, u := range usage {
+		if u.Measured() {
+			ctx.SetState(StateDiskUsage, usage)
+			return usage
+		}
+	}
+	return nil
+}
+
+// diskUsageCheckResult returns the result of checking the disk usage of the
+// installation transaction against the free space of the filesystems.
+func diskUsageCheckResult(usage []pkg.DiskUsage) *validator.CheckResult {
+	needed := make([]string, 0, len(usage))
+	for _, u := range usage {
+		needed = append(needed, u.String())
+	}
+
+	shortages := pkg.DiskShortages(usage)
+	if len(shortages) == 0 {
+		return validator.NewCheckResult(
+			validator.CheckDiskSpace,
+			true,
+			fmt.Sprintf("sufficient disk space: %s", strings.Join(needed, "; ")),
+			validator.SeverityInfo,
+		)
+	}
+
+	messages := make([]string, 0, len(shortages))
+	remediations := make([]string, 0, len(shortages))
+	for _, shortage := range shortages {
+		messages = append(messages, shortage.String())
+		remediations = append(remediations, fmt.Sprintf("%s on %s",
+			pkg.FormatSize(shortage.Required-shortage.Available), shortage.MountPoint))
+	}
+	return validator.NewCheckResult(
+		validator.CheckDiskSpace,
+		false,
+		fmt.Sprintf("insufficient disk space: %s", strings.Join(messages, "; ")),
+		validator.SeverityError,
+	).WithRemediation(fmt.Sprintf("Free up at least %s", strings.Join(remediations, " and ")))
+}
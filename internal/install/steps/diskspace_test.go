@@ -0,0 +1,220 @@
+package steps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/gpu/validator"
+	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// dfExecutor is a mock executor answering df according to the path it is
+// asked about. Other paths fail, as df does for missing directories.
+type dfExecutor struct {
+	*exec.MockExecutor
+	paths map[string]string
+}
+
+func newDfExecutor(paths map[string]string) *dfExecutor {
+	return &dfExecutor{MockExecutor: exec.NewMockExecutor(), paths: paths}
+}
+
+func (e *dfExecutor) Execute(ctx context.Context, cmd string, args ...string) *exec.Result {
+	result := e.MockExecutor.Execute(ctx, cmd, args...)
+	if cmd != "df" || len(args) == 0 {
+		return result
+	}
+	if output, ok := e.paths[args[len(args)-1]]; ok {
+		return exec.SuccessResult(output)
+	}
+	return exec.FailureResult(1, "df: "+args[len(args)-1]+": No such file or directory")
+}
+
+// dfOutput returns the output of df -P -B1 for a filesystem.
+func dfOutput(mountPoint string, available int64) string {
+	return fmt.Sprintf("Filesystem 1-blocks Used Available Capacity Mounted on\n/dev/sda1 1 1 %d 1%% %s\n", available, mountPoint)
+}
+
+func TestParseDfFreeSpace(t *testing.T) {
+	mountPoint, available := parseDfFreeSpace(`Filesystem        1-blocks        Used   Available Capacity Mounted on
+/dev/nvme0n1p2 510405828608 98463387648 386035126272      21% /
+`)
+	assert.Equal(t, "/", mountPoint)
+	assert.Equal(t, int64(386035126272), available)
+
+	mountPoint, _ = parseDfFreeSpace("Filesystem 1-blocks Used Available Capacity Mounted on\n/dev/sdb1 1 1 1 1% /mnt/data disk\n")
+	assert.Equal(t, "/mnt/data disk", mountPoint)
+
+	mountPoint, _ = parseDfFreeSpace("df: /opt: No such file or directory\n")
+	assert.Empty(t, mountPoint)
+}
+
+func TestEstimateDiskUsage(t *testing.T) {
+	sim := pkg.NewSimulation()
+	sim.Install = []pkg.PackageChange{
+		{Name: "nvidia-utils", InstalledSize: 300 << 20},
+		{Name: "cuda", InstalledSize: 4 << 30},
+	}
+	sim.DownloadSize = 2 << 30
+	sim.InstallSize = 300<<20 + 4<<30
+
+	// /opt does not exist yet and is measured on the root filesystem
+	executor := newDfExecutor(map[string]string{
+		"/var/cache": dfOutput("/var", 10<<30),
+		"/usr":       dfOutput("/", 3<<30),
+		"/":          dfOutput("/", 3<<30),
+	})
+
+	usage := EstimateDiskUsage(context.Background(), executor, constants.FamilyArch, sim)
+
+	assert.Equal(t, []pkg.DiskUsage{
+		{Dir: pkg.PackageCacheDir, Required: 2 << 30, MountPoint: "/var", Available: 10 << 30},
+		{Dir: pkg.SystemDir, Required: 300 << 20, MountPoint: "/", Available: 3 << 30},
+		{Dir: pkg.OptDir, Required: 4 << 30, MountPoint: "/", Available: 3 << 30},
+	}, usage)
+	assert.True(t, executor.WasCalledWith("df", "-P", "-B1", "/opt"))
+	assert.True(t, executor.WasCalledWith("df", "-P", "-B1", "/"))
+
+	// Other families install the CUDA toolkit in /usr
+	usage = EstimateDiskUsage(context.Background(), executor, constants.FamilyDebian, sim)
+	require.Len(t, usage, 2)
+	assert.Equal(t, pkg.SystemDir, usage[1].Dir)
+}
+
+func TestEstimateDiskUsage_Unmeasured(t *testing.T) {
+	sim := pkg.NewSimulation()
+	sim.Install = []pkg.PackageChange{{Name: "nvidia-driver-550"}}
+	sim.DownloadSize = 100 << 20
+
+	usage := EstimateDiskUsage(context.Background(), newDfExecutor(nil), constants.FamilyDebian, sim)
+
+	require.Len(t, usage, 1)
+	assert.False(t, usage[0].Measured())
+}
+
+// newDiskUsageTestSimulation returns a simulation needing 1 GiB in the
+// package cache and 2 GiB in /usr.
+func newDiskUsageTestSimulation() *pkg.Simulation {
+	sim := pkg.NewSimulation()
+	sim.Install = []pkg.PackageChange{{Name: "nvidia-driver-550", InstalledSize: 2 << 30}}
+	sim.DownloadSize = 1 << 30
+	sim.InstallSize = 2 << 30
+	return sim
+}
+
+func newDiskUsageTestContext(pm pkg.Manager, executor exec.Executor) *install.Context {
+	return install.NewContext(
+		install.WithPackageManager(pm),
+		install.WithExecutor(executor),
+		install.WithDistroInfo(newTestUbuntuDistro()),
+		install.WithDriverVersion("550"),
+	)
+}
+
+func TestValidationStep_Execute_DiskUsage(t *testing.T) {
+	fixed := validator.NewCheckResult(validator.CheckDiskSpace, true, "fixed requirement met", validator.SeverityInfo)
+
+	t.Run("enough space", func(t *testing.T) {
+		mockPM := NewSimulatingMockManager(newDiskUsageTestSimulation())
+		mockValidator := NewMockValidator()
+		mockValidator.diskSpaceResult = fixed
+		executor := newDfExecutor(map[string]string{
+			"/var/cache": dfOutput("/", 4<<30),
+			"/usr":       dfOutput("/", 4<<30),
+		})
+		ctx := newDiskUsageTestContext(mockPM, executor)
+		step := NewValidationStep(WithValidator(mockValidator), WithChecks(CheckDiskSpace),
+			WithPackageLister(NewPackageInstallationStep()))
+
+		result := step.Execute(ctx)
+
+		assert.Equal(t, install.StepStatusCompleted, result.Status)
+		assert.Equal(t, []string{"nvidia-driver-550"}, mockPM.simulated)
+		stored, ok := ctx.GetState(StateDiskUsage)
+		require.True(t, ok)
+		assert.Len(t, stored, 2)
+	})
+
+	t.Run("not enough space", func(t *testing.T) {
+		mockPM := NewSimulatingMockManager(newDiskUsageTestSimulation())
+		mockValidator := NewMockValidator()
+		mockValidator.diskSpaceResult = fixed
+		executor := newDfExecutor(map[string]string{
+			"/var/cache": dfOutput("/", 2<<30),
+			"/usr":       dfOutput("/", 2<<30),
+		})
+		step := NewValidationStep(WithValidator(mockValidator), WithChecks(CheckDiskSpace),
+			WithPackageLister(NewPackageInstallationStep()))
+
+		result := step.Execute(newDiskUsageTestContext(mockPM, executor))
+
+		assert.Equal(t, install.StepStatusFailed, result.Status)
+		assert.Contains(t, result.Message, "insufficient disk space: / has 2.0 GiB free, 3.0 GiB needed for /var/cache, /usr")
+	})
+
+	t.Run("simulation fails", func(t *testing.T) {
+		mockPM := NewSimulatingMockManager(nil)
+		mockPM.simErr = pkg.Wrap(pkg.ErrPackageNotFound, errors.New("nvidia-driver-550 not found"))
+		mockValidator := NewMockValidator()
+		mockValidator.diskSpaceResult = validator.NewCheckResult(validator.CheckDiskSpace, false, "fixed requirement not met", validator.SeverityError)
+		step := NewValidationStep(WithValidator(mockValidator), WithChecks(CheckDiskSpace),
+			WithPackageLister(NewPackageInstallationStep()))
+
+		result := step.Execute(newDiskUsageTestContext(mockPM, newDfExecutor(nil)))
+
+		// The fixed requirement is checked instead
+		assert.Equal(t, install.StepStatusFailed, result.Status)
+		assert.Contains(t, result.Message, "fixed requirement not met")
+	})
+
+	t.Run("without package lister", func(t *testing.T) {
+		mockPM := NewSimulatingMockManager(newDiskUsageTestSimulation())
+		mockValidator := NewMockValidator()
+		mockValidator.diskSpaceResult = fixed
+		step := NewValidationStep(WithValidator(mockValidator), WithChecks(CheckDiskSpace))
+
+		result := step.Execute(newDiskUsageTestContext(mockPM, newDfExecutor(nil)))
+
+		assert.Equal(t, install.StepStatusCompleted, result.Status)
+		assert.Empty(t, mockPM.simulated)
+	})
+}
+
+func TestPackageInstallationStep_Execute_DiskUsage(t *testing.T) {
+	t.Run("not enough space", func(t *testing.T) {
+		mockPM := NewSimulatingMockManager(newDiskUsageTestSimulation())
+		executor := newDfExecutor(map[string]string{
+			"/var/cache": dfOutput("/var", 512<<20),
+			"/usr":       dfOutput("/", 10<<30),
+		})
+
+		result := NewPackageInstallationStep().Execute(newDiskUsageTestContext(mockPM, executor))
+
+		assert.Equal(t, install.StepStatusFailed, result.Status)
+		assert.Contains(t, result.Error.Error(), "not enough disk space: /var has 512.0 MiB free, 1.0 GiB needed for /var/cache")
+		assert.False(t, mockPM.installCalled)
+	})
+
+	t.Run("enough space", func(t *testing.T) {
+		mockPM := NewSimulatingMockManager(newDiskUsageTestSimulation())
+		executor := newDfExecutor(map[string]string{
+			"/var/cache": dfOutput("/var", 2<<30),
+			"/usr":       dfOutput("/", 10<<30),
+		})
+		ctx := newDiskUsageTestContext(mockPM, executor)
+
+		result := NewPackageInstallationStep().Execute(ctx)
+
+		assert.Equal(t, install.StepStatusCompleted, result.Status)
+		assert.True(t, mockPM.installCalled)
+		_, ok := ctx.GetState(StateDiskUsage)
+		assert.True(t, ok)
+	})
+}
@@ -9,8 +9,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/tungetti/igor/internal/dkms"
 	"github.com/tungetti/igor/internal/gpu/kernel"
 	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/pkg"
 )
 
 // State keys for DKMS module build configuration.
@@ -21,8 +23,12 @@ const (
 	StateDKMSModuleName = "dkms_module_name"
 	// StateDKMSModuleVersion stores the version of the DKMS module that was built.
 	StateDKMSModuleVersion = "dkms_module_version"
-	// StateDKMSKernelVersion stores the kernel version the module was built for.
+	// StateDKMSKernelVersion stores the first kernel version the module was built for.
 	StateDKMSKernelVersion = "dkms_kernel_version"
+	// StateDKMSKernelVersions stores all the kernel versions the module was built for.
+	StateDKMSKernelVersions = "dkms_kernel_versions"
+	// StateDKMSHeadersPackages stores the kernel headers packages installed to build the module.
+	StateDKMSHeadersPackages = "dkms_headers_packages"
 	// StateDKMSBuildTime stores the duration of the DKMS build process.
 	StateDKMSBuildTime = "dkms_build_time"
 )
@@ -36,12 +42,13 @@ const (
 )
 
 // DKMSBuildStep builds NVIDIA kernel modules using DKMS (Dynamic Kernel Module Support).
-// It checks if the module is already built for the current kernel and builds it if not.
+// It builds the module for every installed kernel it is not already built for,
+// so that a newer kernel staged for the next reboot also gets the module.
 type DKMSBuildStep struct {
 	install.BaseStep
 	moduleName      string          // NVIDIA module name (default: "nvidia")
 	moduleVersion   string          // Specific version (optional, auto-detected if empty)
-	kernelVersion   string          // Specific kernel version (optional, uses current if empty)
+	kernelVersion   string          // Specific kernel version (optional, uses installed kernels if empty)
 	skipStatusCheck bool            // Skip checking if module is already built
 	kernelDetector  kernel.Detector // For getting kernel info
 	timeout         time.Duration   // Build timeout (default: 10 minutes)
@@ -66,7 +73,7 @@ func WithModuleVersion(version string) DKMSBuildStepOption {
 }
 
 // WithKernelVersion sets the specific kernel version to build for.
-// If not set, the current running kernel version is used.
+// If not set, the running kernel and the other installed kernels are used.
 func WithKernelVersion(version string) DKMSBuildStepOption {
 	return func(s *DKMSBuildStep) {
 		s.kernelVersion = version
@@ -81,6 +88,7 @@ func WithSkipStatusCheck(skip bool) DKMSBuildStepOption {
 }
 
 // WithKernelDetector sets a custom kernel detector.
+// The installed kernels are listed when it implements kernel.InstalledKernelDetector.
 // This is primarily used for testing.
 func WithKernelDetector(detector kernel.Detector) DKMSBuildStepOption {
 	return func(s *DKMSBuildStep) {
@@ -115,16 +123,20 @@ func NewDKMSBuildStep(opts ...DKMSBuildStepOption) *DKMSBuildStep {
 // It performs the following steps:
 //  1. Checks for cancellation
 //  2. Validates prerequisites (executor available)
-//  3. Gets kernel version from kernel.Detector or context
-//  4. Checks if DKMS is available
-//  5. Gets NVIDIA module version from dkms status (if not specified)
-//  6. Checks if module is already built for current kernel (unless skipStatusCheck)
-//  7. If already built, skip with success message
+//  3. Checks if DKMS is available
+//  4. Gets NVIDIA module version from dkms status (if not specified)
+//  5. Gets the kernels to build for: the running kernel, from kernel.Detector
+//     or uname -r, and the other installed kernels, or the specified kernel
+//  6. Skips the kernels the module is already built for (unless skipStatusCheck)
+//  7. If already built for all kernels, skip with success message
 //  8. In dry-run mode, logs what would be built
-//  9. Runs dkms build nvidia/<version> -k <kernel_version>
-//  10. Runs dkms install nvidia/<version> -k <kernel_version>
-//  11. Verifies installation via dkms status
+//  9. Installs the missing headers packages of the other kernels
+//  10. Runs dkms build nvidia/<version> -k <kernel_version> for each kernel
+//  11. Runs dkms install nvidia/<version> -k <kernel_version> for each kernel
 //  12. Stores state for rollback
+//
+// A failure for the running kernel fails the step, while a failure for
+// another kernel is reported in the result message.
 func (s *DKMSBuildStep) Execute(ctx *install.Context) install.StepResult {
 	startTime := time.Now()
 
@@ -151,13 +163,13 @@ func (s *DKMSBuildStep) Execute(ctx *install.Context) install.StepResult {
 		return install.FailStep("step cancelled", context.Canceled).WithDuration(time.Since(startTime))
 	}
 
-	// Get kernel version
-	kernelVersion, err := s.getKernelVersion(ctx)
+	// Get the kernels to build for
+	kernels, err := s.getKernels(ctx)
 	if err != nil {
 		ctx.LogError("failed to get kernel version", "error", err)
 		return install.FailStep("failed to get kernel version", err).WithDuration(time.Since(startTime))
 	}
-	ctx.LogDebug("using kernel version", "kernel", kernelVersion)
+	ctx.LogDebug("using kernel versions", "kernels", kernelVersions(kernels))
 
 	// Get module version
 	moduleVersion := s.moduleVersion
@@ -179,89 +191,126 @@ func (s *DKMSBuildStep) Execute(ctx *install.Context) install.StepResult {
 		return install.FailStep("step cancelled", context.Canceled).WithDuration(time.Since(startTime))
 	}
 
-	// Check if module is already built (unless skipStatusCheck)
-	if !s.skipStatusCheck {
-		isBuilt, err := s.isModuleBuilt(ctx, moduleVersion, kernelVersion)
-		if err != nil {
-			ctx.LogWarn("failed to check if module is built, proceeding anyway", "error", err)
-		} else if isBuilt {
-			ctx.Log("NVIDIA module is already built for kernel", "version", moduleVersion, "kernel", kernelVersion)
-			return install.SkipStep(fmt.Sprintf("module %s/%s is already built for kernel %s", s.moduleName, moduleVersion, kernelVersion)).
-				WithDuration(time.Since(startTime))
+	// Skip the kernels the module is already built for (unless skipStatusCheck)
+	var pending []dkmsKernel
+	var alreadyBuilt []string
+	for _, k := range kernels {
+		if !s.skipStatusCheck {
+			isBuilt, err := s.isModuleBuilt(ctx, moduleVersion, k.version)
+			if err != nil {
+				ctx.LogWarn("failed to check if module is built, proceeding anyway", "kernel", k.version, "error", err)
+			} else if isBuilt {
+				ctx.Log("NVIDIA module is already built for kernel", "version", moduleVersion, "kernel", k.version)
+				alreadyBuilt = append(alreadyBuilt, k.version)
+				continue
+			}
 		}
+		pending = append(pending, k)
+	}
+	if len(pending) == 0 {
+		return install.SkipStep(fmt.Sprintf("module %s/%s is already built for %s", s.moduleName, moduleVersion, kernelsLabel(alreadyBuilt))).
+			WithDuration(time.Since(startTime))
 	}
 
 	// Dry run mode
 	if ctx.DryRun {
-		ctx.Log("dry run: would build DKMS module", "module", s.moduleName, "version", moduleVersion, "kernel", kernelVersion)
-		ctx.Log("dry run: would run: dkms build %s/%s -k %s", s.moduleName, moduleVersion, kernelVersion)
-		ctx.Log("dry run: would run: dkms install %s/%s -k %s", s.moduleName, moduleVersion, kernelVersion)
-		return install.CompleteStep("dry run: DKMS module would be built").WithDuration(time.Since(startTime))
+		for _, k := range pending {
+			ctx.Log("dry run: would build DKMS module", "module", s.moduleName, "version", moduleVersion, "kernel", k.version)
+			if !k.headersInstalled {
+				ctx.Log("dry run: would install kernel headers", "package", k.headersPackage, "kernel", k.version)
+			}
+			ctx.Log("dry run: would run dkms build", "module", s.moduleName, "version", moduleVersion, "kernel", k.version)
+			ctx.Log("dry run: would run dkms install", "module", s.moduleName, "version", moduleVersion, "kernel", k.version)
+		}
+		return install.CompleteStep(fmt.Sprintf("dry run: DKMS module would be built for %s", kernelsLabel(kernelVersions(pending)))).
+			WithDuration(time.Since(startTime))
 	}
 
-	// Check for cancellation before build
-	if ctx.IsCancelled() {
-		return install.FailStep("step cancelled", context.Canceled).WithDuration(time.Since(startTime))
-	}
+	ctx.SetState(StateDKMSModuleName, s.moduleName)
+	ctx.SetState(StateDKMSModuleVersion, moduleVersion)
 
-	// Build the module
-	ctx.Log("building NVIDIA DKMS module", "module", s.moduleName, "version", moduleVersion, "kernel", kernelVersion)
-	if err := s.buildModule(ctx, moduleVersion, kernelVersion); err != nil {
-		ctx.LogError("failed to build DKMS module", "error", err)
-		return install.FailStep("failed to build DKMS module", err).WithDuration(time.Since(startTime))
-	}
+	var built, headersPackages, failures []string
+	for _, k := range pending {
+		// Check for cancellation before each build
+		if ctx.IsCancelled() {
+			s.cleanup(ctx)
+			return install.FailStep("step cancelled", context.Canceled).WithDuration(time.Since(startTime))
+		}
 
-	// Check for cancellation after build
-	if ctx.IsCancelled() {
-		// Try to clean up the built module
-		ctx.LogDebug("cleaning up after cancellation")
-		_ = s.removeModule(ctx, moduleVersion, kernelVersion)
-		return install.FailStep("step cancelled", context.Canceled).WithDuration(time.Since(startTime))
-	}
+		// Install the headers of a kernel other than the running one
+		if !k.headersInstalled {
+			ctx.Log("installing kernel headers", "package", k.headersPackage, "kernel", k.version)
+			if err := s.installHeaders(ctx, k.headersPackage); err != nil {
+				ctx.LogWarn("failed to install kernel headers, skipping kernel", "kernel", k.version, "error", err)
+				failures = append(failures, fmt.Sprintf("kernel %s: %v", k.version, err))
+				continue
+			}
+			headersPackages = append(headersPackages, k.headersPackage)
+			ctx.SetState(StateDKMSHeadersPackages, headersPackages)
+		}
 
-	// Install the module
-	ctx.Log("installing NVIDIA DKMS module", "module", s.moduleName, "version", moduleVersion, "kernel", kernelVersion)
-	if err := s.installModule(ctx, moduleVersion, kernelVersion); err != nil {
-		ctx.LogError("failed to install DKMS module", "error", err)
-		// Try to rollback the build
-		if rollbackErr := s.removeModule(ctx, moduleVersion, kernelVersion); rollbackErr != nil {
-			ctx.LogWarn("failed to rollback module build", "error", rollbackErr)
+		if message, err := s.buildAndInstallModule(ctx, moduleVersion, k.version); err != nil {
+			if k.required {
+				s.cleanup(ctx)
+				return install.FailStep(message, err).WithDuration(time.Since(startTime))
+			}
+			ctx.LogWarn(message, "kernel", k.version, "error", err)
+			failures = append(failures, fmt.Sprintf("kernel %s: %v", k.version, err))
+			continue
 		}
-		return install.FailStep("failed to install DKMS module", err).WithDuration(time.Since(startTime))
+
+		// Store state for rollback
+		built = append(built, k.version)
+		ctx.SetState(StateDKMSBuilt, true)
+		ctx.SetState(StateDKMSKernelVersion, built[0])
+		ctx.SetState(StateDKMSKernelVersions, built)
 	}
 
-	// Store state for rollback
 	buildDuration := time.Since(startTime)
-	ctx.SetState(StateDKMSBuilt, true)
-	ctx.SetState(StateDKMSModuleName, s.moduleName)
-	ctx.SetState(StateDKMSModuleVersion, moduleVersion)
-	ctx.SetState(StateDKMSKernelVersion, kernelVersion)
 	ctx.SetState(StateDKMSBuildTime, buildDuration)
 
-	ctx.Log("NVIDIA DKMS module built and installed successfully",
+	ctx.Log("NVIDIA DKMS module build finished",
 		"module", s.moduleName,
 		"version", moduleVersion,
-		"kernel", kernelVersion,
+		"built", built,
+		"already_built", alreadyBuilt,
+		"failed", len(failures),
 		"duration", buildDuration)
 
-	return install.CompleteStep("NVIDIA DKMS module built and installed successfully").
+	// Per-kernel results
+	var results []string
+	if len(built) > 0 {
+		results = append(results, "NVIDIA DKMS module built and installed successfully for "+kernelsLabel(built))
+	} else {
+		results = append(results, "NVIDIA DKMS module not built for any kernel")
+	}
+	if len(alreadyBuilt) > 0 {
+		results = append(results, "already built for "+kernelsLabel(alreadyBuilt))
+	}
+	if len(failures) > 0 {
+		results = append(results, "failed for "+strings.Join(failures, ", "))
+	}
+
+	return install.CompleteStep(strings.Join(results, "; ")).
 		WithDuration(buildDuration).
-		WithCanRollback(true)
+		WithCanRollback(len(built) > 0 || len(headersPackages) > 0)
 }
 
-// Rollback removes the DKMS module that was built during execution.
-// If no module was built by this step, this is a no-op.
+// Rollback removes the DKMS modules that were built during execution and
+// the kernel headers packages that were installed for them.
+// If nothing was done by this step, this is a no-op.
 func (s *DKMSBuildStep) Rollback(ctx *install.Context) error {
+	kernels := s.builtKernels(ctx)
+	headersPackages, _ := getStateStrings(ctx, StateDKMSHeadersPackages)
+
 	// Check if we actually built a module
-	if !ctx.GetStateBool(StateDKMSBuilt) {
+	if len(kernels) == 0 && len(headersPackages) == 0 {
 		ctx.LogDebug("no DKMS module was built, nothing to rollback")
 		return nil
 	}
 
 	moduleVersion := ctx.GetStateString(StateDKMSModuleVersion)
-	kernelVersion := ctx.GetStateString(StateDKMSKernelVersion)
-
-	if moduleVersion == "" {
+	if moduleVersion == "" && len(headersPackages) == 0 {
 		ctx.LogDebug("module version not found in state, nothing to rollback")
 		return nil
 	}
@@ -271,13 +320,33 @@ func (s *DKMSBuildStep) Rollback(ctx *install.Context) error {
 		return fmt.Errorf("executor not available for rollback")
 	}
 
-	ctx.Log("rolling back DKMS module build", "module", s.moduleName, "version", moduleVersion, "kernel", kernelVersion)
+	var errs []string
+
+	// Remove the modules
+	if moduleVersion != "" {
+		for _, kernelVersion := range kernels {
+			ctx.Log("rolling back DKMS module build", "module", s.moduleName, "version", moduleVersion, "kernel", kernelVersion)
+			if err := s.removeModule(ctx, moduleVersion, kernelVersion); err != nil {
+				ctx.LogError("failed to remove DKMS module during rollback", "kernel", kernelVersion, "error", err)
+				errs = append(errs, fmt.Sprintf("failed to remove DKMS module '%s/%s' for kernel '%s': %v",
+					s.moduleName, moduleVersion, kernelVersion, err))
+			}
+		}
+	}
+
+	// Remove the headers packages
+	if len(headersPackages) > 0 {
+		ctx.Log("removing kernel headers packages", "packages", headersPackages)
+		if ctx.PackageManager == nil {
+			errs = append(errs, "package manager not available to remove kernel headers packages")
+		} else if err := ctx.PackageManager.Remove(ctx.Context(), pkg.RemoveOptions{NoConfirm: true}, headersPackages...); err != nil {
+			ctx.LogError("failed to remove kernel headers packages during rollback", "error", err)
+			errs = append(errs, fmt.Sprintf("failed to remove kernel headers packages: %v", err))
+		}
+	}
 
-	// Remove the module
-	if err := s.removeModule(ctx, moduleVersion, kernelVersion); err != nil {
-		ctx.LogError("failed to remove DKMS module during rollback", "error", err)
-		return fmt.Errorf("failed to remove DKMS module '%s/%s' for kernel '%s': %w",
-			s.moduleName, moduleVersion, kernelVersion, err)
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
 	}
 
 	// Clear state
@@ -285,12 +354,47 @@ func (s *DKMSBuildStep) Rollback(ctx *install.Context) error {
 	ctx.DeleteState(StateDKMSModuleName)
 	ctx.DeleteState(StateDKMSModuleVersion)
 	ctx.DeleteState(StateDKMSKernelVersion)
+	ctx.DeleteState(StateDKMSKernelVersions)
+	ctx.DeleteState(StateDKMSHeadersPackages)
 	ctx.DeleteState(StateDKMSBuildTime)
 
 	ctx.LogDebug("DKMS module rollback completed")
 	return nil
 }
 
+// cleanup rolls back what a failed execution did.
+func (s *DKMSBuildStep) cleanup(ctx *install.Context) {
+	ctx.LogDebug("cleaning up after failed DKMS module build")
+	if err := s.Rollback(ctx); err != nil {
+		ctx.LogWarn("failed to rollback module build", "error", err)
+	}
+	ctx.DeleteState(StateDKMSModuleName)
+	ctx.DeleteState(StateDKMSModuleVersion)
+	ctx.DeleteState(StateDKMSBuildTime)
+}
+
+// builtKernels returns the kernels the module was built for by this step.
+func (s *DKMSBuildStep) builtKernels(ctx *install.Context) []string {
+	if !ctx.GetStateBool(StateDKMSBuilt) {
+		return nil
+	}
+	if kernels, ok := getStateStrings(ctx, StateDKMSKernelVersions); ok && len(kernels) > 0 {
+		return kernels
+	}
+	// State written before the module was built for several kernels
+	return []string{ctx.GetStateString(StateDKMSKernelVersion)}
+}
+
+// getStateStrings returns a string slice stored in the context state.
+func getStateStrings(ctx *install.Context, key string) ([]string, bool) {
+	value, ok := ctx.GetState(key)
+	if !ok {
+		return nil, false
+	}
+	values, ok := value.([]string)
+	return values, ok
+}
+
 // Validate checks if the step can be executed with the given context.
 // It ensures the Executor is available for running commands and validates
 // that module names and versions contain only safe characters.
@@ -383,10 +487,15 @@ func (s *DKMSBuildStep) getKernelVersion(ctx *install.Context) (string, error) {
 	if s.kernelVersion != "" {
 		return s.kernelVersion, nil
 	}
+	return getRunningKernelVersion(ctx, s.kernelDetector)
+}
 
+// getRunningKernelVersion returns the version of the running kernel from the
+// kernel detector if set, falling back to uname -r.
+func getRunningKernelVersion(ctx *install.Context, detector kernel.Detector) (string, error) {
 	// Try kernel detector if available
-	if s.kernelDetector != nil {
-		info, err := s.kernelDetector.GetKernelInfo(ctx.Context())
+	if detector != nil {
+		info, err := detector.GetKernelInfo(ctx.Context())
 		if err == nil && info.Version != "" {
 			return info.Version, nil
 		}
@@ -410,6 +519,130 @@ func (s *DKMSBuildStep) getKernelVersion(ctx *install.Context) (string, error) {
 	return version, nil
 }
 
+// dkmsKernel is a kernel the module is built for.
+type dkmsKernel struct {
+	version          string
+	required         bool // the running or specified kernel, whose build failure fails the step
+	headersInstalled bool
+	headersPackage   string
+}
+
+// getKernels returns the kernels to build for: the specified kernel if set,
+// or the running kernel followed by the other installed kernels.
+func (s *DKMSBuildStep) getKernels(ctx *install.Context) ([]dkmsKernel, error) {
+	// Kernel headers of the running or specified kernel are installed
+	// with the packages
+	if s.kernelVersion != "" {
+		return []dkmsKernel{{version: s.kernelVersion, required: true, headersInstalled: true}}, nil
+	}
+
+	running, err := s.getKernelVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	kernels := []dkmsKernel{{version: running, required: true, headersInstalled: true}}
+
+	installed, err := s.getInstalledKernels(ctx)
+	if err != nil {
+		ctx.LogWarn("failed to list installed kernels, building for the running kernel only", "error", err)
+		return kernels, nil
+	}
+	for _, k := range installed {
+		if k.Version == running || !isValidKernelVersion(k.Version) {
+			continue
+		}
+		kernels = append(kernels, dkmsKernel{
+			version:          k.Version,
+			headersInstalled: k.HeadersInstalled,
+			headersPackage:   k.HeadersPackage,
+		})
+	}
+	return kernels, nil
+}
+
+// getInstalledKernels lists the installed kernels with the kernel detector,
+// or with a kernel.Detector for the distribution if none is set.
+func (s *DKMSBuildStep) getInstalledKernels(ctx *install.Context) ([]kernel.InstalledKernel, error) {
+	var detector kernel.InstalledKernelDetector
+	if s.kernelDetector != nil {
+		d, ok := s.kernelDetector.(kernel.InstalledKernelDetector)
+		if !ok {
+			return nil, nil
+		}
+		detector = d
+	} else {
+		opts := []kernel.DetectorOption{kernel.WithExecutor(ctx.Executor)}
+		if ctx.DistroInfo != nil {
+			opts = append(opts, kernel.WithDistroFamily(ctx.DistroInfo.Family))
+		}
+		detector = kernel.NewDetector(opts...)
+	}
+	return detector.GetInstalledKernels(ctx.Context())
+}
+
+// kernelVersions returns the versions of the kernels.
+func kernelVersions(kernels []dkmsKernel) []string {
+	versions := make([]string, 0, len(kernels))
+	for _, k := range kernels {
+		versions = append(versions, k.version)
+	}
+	return versions
+}
+
+// kernelsLabel describes kernel versions, such as "kernel 6.8.0-45-generic"
+// or "kernels 6.8.0-45-generic, 6.8.0-47-generic".
+func kernelsLabel(versions []string) string {
+	if len(versions) == 1 {
+		return "kernel " + versions[0]
+	}
+	return "kernels " + strings.Join(versions, ", ")
+}
+
+// installHeaders installs the kernel headers package of a kernel other than
+// the running one.
+func (s *DKMSBuildStep) installHeaders(ctx *install.Context, headersPackage string) error {
+	if headersPackage == "" {
+		return fmt.Errorf("kernel headers are not installed")
+	}
+	if ctx.PackageManager == nil {
+		return fmt.Errorf("package manager not available to install %s", headersPackage)
+	}
+	if err := ctx.PackageManager.Install(ctx.Context(), pkg.NonInteractiveInstallOptions(), headersPackage); err != nil {
+		return fmt.Errorf("failed to install %s: %w", headersPackage, err)
+	}
+	return nil
+}
+
+// buildAndInstallModule builds and installs the module for the kernel. On
+// failure, it returns the step result message with the error.
+func (s *DKMSBuildStep) buildAndInstallModule(ctx *install.Context, version, kernelVersion string) (string, error) {
+	ctx.Log("building NVIDIA DKMS module", "module", s.moduleName, "version", version, "kernel", kernelVersion)
+	if err := s.buildModule(ctx, version, kernelVersion); err != nil {
+		ctx.LogError("failed to build DKMS module", "kernel", kernelVersion, "error", err)
+		buildErr := s.diagnoseBuild(ctx, version, kernelVersion, err)
+		if buildErr.Diagnosis == nil {
+			return "failed to build DKMS module", buildErr
+		}
+		ctx.LogError("DKMS build failure diagnosed",
+			"cause", buildErr.Diagnosis.Cause,
+			"evidence", buildErr.Diagnosis.Evidence,
+			"remediation", buildErr.Diagnosis.Remediation)
+		return fmt.Sprintf("failed to build DKMS module: %s. %s", buildErr.Diagnosis.Summary, buildErr.Diagnosis.Remediation), buildErr
+	}
+
+	ctx.Log("installing NVIDIA DKMS module", "module", s.moduleName, "version", version, "kernel", kernelVersion)
+	if err := s.installModule(ctx, version, kernelVersion); err != nil {
+		ctx.LogError("failed to install DKMS module", "kernel", kernelVersion, "error", err)
+		// Try to rollback the build
+		if rollbackErr := s.removeModule(ctx, version, kernelVersion); rollbackErr != nil {
+			ctx.LogWarn("failed to rollback module build", "error", rollbackErr)
+		}
+		return "failed to install DKMS module", err
+	}
+
+	return "", nil
+}
+
 // getModuleVersion extracts the NVIDIA module version from dkms status.
 // It parses the output to find the nvidia module version.
 func (s *DKMSBuildStep) getModuleVersion(ctx *install.Context) (string, error) {
@@ -488,6 +721,17 @@ func (s *DKMSBuildStep) parseIsModuleBuilt(output, version, kernelVersion string
 	return false
 }
 
+// diagnoseBuild returns the build error with the diagnosis of the make.log
+// of the failed build, if it matches a known failure.
+func (s *DKMSBuildStep) diagnoseBuild(ctx *install.Context, version, kernelVersion string, err error) *dkms.BuildError {
+	build := dkms.Build{Module: s.moduleName, Version: version, Kernel: kernelVersion}
+	return &dkms.BuildError{
+		Build:     build,
+		Err:       err,
+		Diagnosis: dkms.DiagnoseBuild(ctx.Context(), ctx.Executor, build),
+	}
+}
+
 // buildModule runs dkms build for the specified module version and kernel.
 func (s *DKMSBuildStep) buildModule(ctx *install.Context, version, kernelVersion string) error {
 	moduleSpec := fmt.Sprintf("%s/%s", s.moduleName, version)
@@ -549,8 +793,9 @@ func (s *DKMSBuildStep) removeModule(ctx *install.Context, version, kernelVersio
 	args := []string{"remove", moduleSpec}
 	if kernelVersion != "" {
 		args = append(args, "-k", kernelVersion)
+	} else {
+		args = append(args, "--all") // Remove all instances for this version
 	}
-	args = append(args, "--all") // Remove all instances for this version
 
 	result := ctx.Executor.ExecuteElevated(ctx.Context(), "dkms", args...)
 
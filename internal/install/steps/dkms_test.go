@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/tungetti/igor/internal/dkms"
 	"github.com/tungetti/igor/internal/exec"
 	"github.com/tungetti/igor/internal/gpu/kernel"
 	"github.com/tungetti/igor/internal/install"
@@ -23,6 +24,8 @@ type MockKernelDetector struct {
 	headersInstalled  bool
 	headersPackage    string
 	secureBootEnabled bool
+	installedKernels  []kernel.InstalledKernel
+	kernelPackage     *kernel.KernelPackage
 
 	// Error injection
 	getKernelInfoErr       error
@@ -32,6 +35,8 @@ type MockKernelDetector struct {
 	areHeadersInstalledErr error
 	getHeadersPackageErr   error
 	isSecureBootEnabledErr error
+	getInstalledKernelsErr error
+	getKernelPackageErr    error
 
 	// Call tracking
 	getKernelInfoCalled bool
@@ -129,9 +134,31 @@ func (m *MockKernelDetector) IsSecureBootEnabled(ctx context.Context) (bool, err
 	return m.secureBootEnabled, nil
 }
 
+// GetInstalledKernels implements kernel.InstalledKernelDetector.
+func (m *MockKernelDetector) GetInstalledKernels(ctx context.Context) ([]kernel.InstalledKernel, error) {
+	if m.getInstalledKernelsErr != nil {
+		return nil, m.getInstalledKernelsErr
+	}
+	return m.installedKernels, nil
+}
+
+// GetKernelPackage implements kernel.KernelPackageDetector.
+func (m *MockKernelDetector) GetKernelPackage(ctx context.Context) (*kernel.KernelPackage, error) {
+	if m.getKernelPackageErr != nil {
+		return nil, m.getKernelPackageErr
+	}
+	return m.kernelPackage, nil
+}
+
 // Ensure MockKernelDetector implements kernel.Detector.
 var _ kernel.Detector = (*MockKernelDetector)(nil)
 
+// Ensure MockKernelDetector implements kernel.InstalledKernelDetector.
+var _ kernel.InstalledKernelDetector = (*MockKernelDetector)(nil)
+
+// Ensure MockKernelDetector implements kernel.KernelPackageDetector.
+var _ kernel.KernelPackageDetector = (*MockKernelDetector)(nil)
+
 // =============================================================================
 // Test Helpers
 // =============================================================================
@@ -423,6 +450,7 @@ func TestDKMSBuildStep_Execute_WithSpecificVersion(t *testing.T) {
 
 	step := NewDKMSBuildStep(
 		WithModuleVersion("535.154.05"),
+		WithKernelDetector(NewMockKernelDetector()),
 	)
 
 	result := step.Execute(ctx)
@@ -451,6 +479,211 @@ func TestDKMSBuildStep_Execute_WithKernelVersion(t *testing.T) {
 	assert.Equal(t, "5.15.0-100-generic", ctx.GetStateString(StateDKMSKernelVersion))
 }
 
+// failingBuildExecutor is a mock executor failing dkms build for some kernels.
+type failingBuildExecutor struct {
+	*exec.MockExecutor
+	failing map[string]bool
+}
+
+func (e *failingBuildExecutor) ExecuteElevated(ctx context.Context, cmd string, args ...string) *exec.Result {
+	result := e.MockExecutor.ExecuteElevated(ctx, cmd, args...)
+	if cmd == "dkms" && len(args) == 4 && args[0] == "build" && e.failing[args[3]] {
+		return exec.FailureResult(10, "Bad return status for module build on kernel: "+args[3])
+	}
+	return result
+}
+
+// newInstalledKernelsDetector returns a detector running 6.8.0-45-generic,
+// with 6.8.0-47-generic staged without headers.
+func newInstalledKernelsDetector() *MockKernelDetector {
+	detector := NewMockKernelDetector()
+	detector.SetKernelVersion("6.8.0-45-generic")
+	detector.installedKernels = []kernel.InstalledKernel{
+		{Version: "6.8.0-45-generic", HeadersInstalled: true, HeadersPackage: "linux-headers-6.8.0-45-generic"},
+		{Version: "6.8.0-47-generic", HeadersPackage: "linux-headers-6.8.0-47-generic"},
+	}
+	return detector
+}
+
+func TestDKMSBuildStep_Execute_InstalledKernels(t *testing.T) {
+	ctx, mockExec := newDKMSTestContext()
+	pm := NewPackageMockManager()
+	ctx.PackageManager = pm
+	mockExec.SetResponse("dkms", exec.SuccessResult("nvidia/550.54.14: added\n"))
+
+	step := NewDKMSBuildStep(WithKernelDetector(newInstalledKernelsDetector()))
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusCompleted, result.Status)
+	assert.Equal(t, "NVIDIA DKMS module built and installed successfully for kernels 6.8.0-45-generic, 6.8.0-47-generic", result.Message)
+	assert.True(t, result.CanRollback)
+
+	// The headers of the staged kernel are installed, not those of the running one
+	assert.Equal(t, []string{"linux-headers-6.8.0-47-generic"}, pm.installPackages)
+	for _, kernelVersion := range []string{"6.8.0-45-generic", "6.8.0-47-generic"} {
+		assert.True(t, mockExec.WasCalledWith("dkms", "build", "nvidia/550.54.14", "-k", kernelVersion))
+		assert.True(t, mockExec.WasCalledWith("dkms", "install", "nvidia/550.54.14", "-k", kernelVersion))
+	}
+
+	assert.Equal(t, "6.8.0-45-generic", ctx.GetStateString(StateDKMSKernelVersion))
+	kernels, _ := ctx.GetState(StateDKMSKernelVersions)
+	assert.Equal(t, []string{"6.8.0-45-generic", "6.8.0-47-generic"}, kernels)
+	headers, _ := ctx.GetState(StateDKMSHeadersPackages)
+	assert.Equal(t, []string{"linux-headers-6.8.0-47-generic"}, headers)
+
+	// Rollback removes the module of each kernel and the headers installed
+	mockExec.Reset()
+	assert.NoError(t, step.Rollback(ctx))
+	assert.True(t, mockExec.WasCalledWith("dkms", "remove", "nvidia/550.54.14", "-k", "6.8.0-45-generic"))
+	assert.True(t, mockExec.WasCalledWith("dkms", "remove", "nvidia/550.54.14", "-k", "6.8.0-47-generic"))
+	assert.Equal(t, []string{"linux-headers-6.8.0-47-generic"}, pm.removePackages)
+	_, ok := ctx.GetState(StateDKMSKernelVersions)
+	assert.False(t, ok)
+}
+
+func TestDKMSBuildStep_Execute_InstalledKernelAlreadyBuilt(t *testing.T) {
+	ctx, mockExec := newDKMSTestContext()
+	ctx.PackageManager = NewPackageMockManager()
+	mockExec.SetResponse("dkms", exec.SuccessResult("nvidia/550.54.14, 6.8.0-45-generic, x86_64: installed\n"))
+
+	step := NewDKMSBuildStep(WithKernelDetector(newInstalledKernelsDetector()))
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusCompleted, result.Status)
+	assert.Equal(t, "NVIDIA DKMS module built and installed successfully for kernel 6.8.0-47-generic; already built for kernel 6.8.0-45-generic", result.Message)
+	assert.False(t, mockExec.WasCalledWith("dkms", "build", "nvidia/550.54.14", "-k", "6.8.0-45-generic"))
+	assert.True(t, mockExec.WasCalledWith("dkms", "build", "nvidia/550.54.14", "-k", "6.8.0-47-generic"))
+}
+
+func TestDKMSBuildStep_Execute_InstalledKernelFails(t *testing.T) {
+	t.Run("headers not available", func(t *testing.T) {
+		ctx, mockExec := newDKMSTestContext()
+		pm := NewPackageMockManager()
+		pm.SetInstallError(errors.New("unable to locate package linux-headers-6.8.0-47-generic"))
+		ctx.PackageManager = pm
+		mockExec.SetResponse("dkms", exec.SuccessResult("nvidia/550.54.14: added\n"))
+
+		step := NewDKMSBuildStep(WithKernelDetector(newInstalledKernelsDetector()))
+
+		result := step.Execute(ctx)
+
+		assert.Equal(t, install.StepStatusCompleted, result.Status)
+		assert.Contains(t, result.Message, "successfully for kernel 6.8.0-45-generic")
+		assert.Contains(t, result.Message, "failed for kernel 6.8.0-47-generic: failed to install linux-headers-6.8.0-47-generic")
+		assert.False(t, mockExec.WasCalledWith("dkms", "build", "nvidia/550.54.14", "-k", "6.8.0-47-generic"))
+	})
+
+	t.Run("build fails for the staged kernel", func(t *testing.T) {
+		ctx, mockExec := newDKMSTestContext()
+		ctx.PackageManager = NewPackageMockManager()
+		mockExec.SetResponse("dkms", exec.SuccessResult("nvidia/550.54.14: added\n"))
+		ctx.Executor = &failingBuildExecutor{MockExecutor: mockExec, failing: map[string]bool{"6.8.0-47-generic": true}}
+
+		step := NewDKMSBuildStep(WithKernelDetector(newInstalledKernelsDetector()))
+
+		result := step.Execute(ctx)
+
+		assert.Equal(t, install.StepStatusCompleted, result.Status)
+		assert.Contains(t, result.Message, "failed for kernel 6.8.0-47-generic: dkms build failed")
+		kernels, _ := ctx.GetState(StateDKMSKernelVersions)
+		assert.Equal(t, []string{"6.8.0-45-generic"}, kernels)
+	})
+
+	t.Run("build fails for the running kernel", func(t *testing.T) {
+		ctx, mockExec := newDKMSTestContext()
+		pm := NewPackageMockManager()
+		ctx.PackageManager = pm
+		mockExec.SetResponse("dkms", exec.SuccessResult("nvidia/550.54.14: added\n"))
+		ctx.Executor = &failingBuildExecutor{MockExecutor: mockExec, failing: map[string]bool{"6.8.0-45-generic": true}}
+
+		step := NewDKMSBuildStep(WithKernelDetector(newInstalledKernelsDetector()))
+
+		result := step.Execute(ctx)
+
+		assert.Equal(t, install.StepStatusFailed, result.Status)
+		assert.Contains(t, result.Message, "failed to build DKMS module")
+		// The running kernel is built first, the staged kernel is not built
+		assert.False(t, mockExec.WasCalledWith("dkms", "build", "nvidia/550.54.14", "-k", "6.8.0-47-generic"))
+		assert.False(t, pm.installCalled)
+		assert.False(t, ctx.GetStateBool(StateDKMSBuilt))
+		assert.Empty(t, ctx.GetStateString(StateDKMSModuleVersion))
+	})
+}
+
+func TestDKMSBuildStep_Execute_BuildFailureDiagnosed(t *testing.T) {
+	ctx, mockExec := newDKMSTestContext()
+	mockExec.SetResponse("dkms", exec.SuccessResult("nvidia/470.256.02: added\n"))
+	mockExec.SetResponse("cat", exec.SuccessResult(
+		"DKMS make.log for nvidia-470.256.02 for kernel 6.8.0-45-generic (x86_64)\n"+
+			"nvidia-drm/nvidia-drm-gem.c:55:5: error: implicit declaration of function 'drm_gem_object_put_unlocked'\n"))
+	ctx.Executor = &failingBuildExecutor{MockExecutor: mockExec, failing: map[string]bool{"6.8.0-45-generic": true}}
+
+	step := NewDKMSBuildStep(WithKernelDetector(newInstalledKernelsDetector()))
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusFailed, result.Status)
+	assert.Equal(t, "failed to build DKMS module: nvidia 470.256.02 does not support the kernel API of kernel 6.8.0-45-generic. "+
+		"Install a newer NVIDIA driver branch supporting kernel 6.8.0-45-generic, or boot a kernel older than 6.8.0-45-generic supported by driver 470.256.02",
+		result.Message)
+	assert.True(t, mockExec.WasCalledWith("cat", "/var/lib/dkms/nvidia/470.256.02/build/make.log"))
+
+	var buildErr *dkms.BuildError
+	if assert.ErrorAs(t, result.Error, &buildErr) {
+		assert.Equal(t, dkms.CauseUnsupportedKernel, buildErr.Diagnosis.Cause)
+		assert.Equal(t, "6.8.0-45-generic", buildErr.Build.Kernel)
+	}
+}
+
+func TestDKMSBuildStep_Execute_InstalledKernelsDryRun(t *testing.T) {
+	ctx, mockExec := newDKMSTestContext()
+	ctx.DryRun = true
+	pm := NewPackageMockManager()
+	ctx.PackageManager = pm
+	mockExec.SetResponse("dkms", exec.SuccessResult("nvidia/550.54.14: added\n"))
+
+	step := NewDKMSBuildStep(WithKernelDetector(newInstalledKernelsDetector()))
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusCompleted, result.Status)
+	assert.Equal(t, "dry run: DKMS module would be built for kernels 6.8.0-45-generic, 6.8.0-47-generic", result.Message)
+	assert.False(t, pm.installCalled)
+}
+
+func TestDKMSBuildStep_Execute_WithKernelVersionIgnoresInstalledKernels(t *testing.T) {
+	ctx, mockExec := newDKMSTestContext()
+	mockExec.SetResponse("dkms", exec.SuccessResult("nvidia/550.54.14: added\n"))
+
+	step := NewDKMSBuildStep(
+		WithKernelVersion("6.8.0-47-generic"),
+		WithKernelDetector(newInstalledKernelsDetector()),
+	)
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusCompleted, result.Status)
+	assert.True(t, mockExec.WasCalledWith("dkms", "build", "nvidia/550.54.14", "-k", "6.8.0-47-generic"))
+	assert.False(t, mockExec.WasCalledWith("dkms", "build", "nvidia/550.54.14", "-k", "6.8.0-45-generic"))
+}
+
+func TestDKMSBuildStep_Execute_InstalledKernelsError(t *testing.T) {
+	ctx, mockExec := newDKMSTestContext()
+	mockExec.SetResponse("dkms", exec.SuccessResult("nvidia/550.54.14: added\n"))
+	detector := newInstalledKernelsDetector()
+	detector.getInstalledKernelsErr = errors.New("kernel modules directory not found")
+
+	step := NewDKMSBuildStep(WithKernelDetector(detector))
+
+	result := step.Execute(ctx)
+
+	// The module is still built for the running kernel
+	assert.Equal(t, install.StepStatusCompleted, result.Status)
+	assert.Equal(t, "NVIDIA DKMS module built and installed successfully for kernel 6.8.0-45-generic", result.Message)
+}
+
 func TestDKMSBuildStep_Execute_NoExecutor(t *testing.T) {
 	ctx := install.NewContext()
 
@@ -1039,6 +1272,8 @@ func TestDKMSBuildStep_StateKeys(t *testing.T) {
 	assert.Equal(t, "dkms_module_name", StateDKMSModuleName)
 	assert.Equal(t, "dkms_module_version", StateDKMSModuleVersion)
 	assert.Equal(t, "dkms_kernel_version", StateDKMSKernelVersion)
+	assert.Equal(t, "dkms_kernel_versions", StateDKMSKernelVersions)
+	assert.Equal(t, "dkms_headers_packages", StateDKMSHeadersPackages)
 	assert.Equal(t, "dkms_build_time", StateDKMSBuildTime)
 }
 
@@ -1093,7 +1328,7 @@ func TestDKMSBuildStep_Duration(t *testing.T) {
 	mockExec.SetResponse("dkms", exec.SuccessResult("nvidia/550.54.14: added\n"))
 	mockExec.SetResponse("uname", exec.SuccessResult("6.5.0-44-generic"))
 
-	step := NewDKMSBuildStep()
+	step := NewDKMSBuildStep(WithKernelDetector(NewMockKernelDetector()))
 
 	result := step.Execute(ctx)
 
@@ -1184,13 +1419,19 @@ func TestDKMSBuildStep_RemoveModule(t *testing.T) {
 		assert.NoError(t, err)
 		assert.True(t, mockExec.WasCalled("dkms"))
 
-		// Verify --all flag is used
-		calls := mockExec.Calls()
-		for _, call := range calls {
-			if call.Command == "dkms" && len(call.Args) > 0 && call.Args[0] == "remove" {
-				assert.Contains(t, call.Args, "--all")
-			}
-		}
+		// Only the instance of the kernel is removed
+		assert.True(t, mockExec.WasCalledWith("dkms", "remove", "nvidia/550.54.14", "-k", "6.5.0-44-generic"))
+	})
+
+	t.Run("all kernels", func(t *testing.T) {
+		ctx, mockExec := newDKMSTestContext()
+
+		step := NewDKMSBuildStep()
+
+		err := step.removeModule(ctx, "550.54.14", "")
+
+		assert.NoError(t, err)
+		assert.True(t, mockExec.WasCalledWith("dkms", "remove", "nvidia/550.54.14", "--all"))
 	})
 
 	t.Run("failure", func(t *testing.T) {
@@ -5,8 +5,10 @@ package steps
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/tungetti/igor/internal/gpu/kernel"
 	"github.com/tungetti/igor/internal/install"
 	"github.com/tungetti/igor/internal/pkg"
 	"github.com/tungetti/igor/internal/pkg/nvidia"
@@ -20,6 +22,19 @@ const (
 	StateInstalledPackages = "installed_packages"
 	// StatePackageInstallTime stores the duration of the installation.
 	StatePackageInstallTime = "package_install_time"
+	// StatePackageSimulation stores the *pkg.Simulation of the installation
+	// transaction, if the package manager could simulate it.
+	StatePackageSimulation = "package_simulation"
+)
+
+// Defaults for waiting on the package manager lock.
+const (
+	// DefaultPackageLockTimeout is how long to wait for another process to
+	// release the package manager lock before giving up.
+	DefaultPackageLockTimeout = 5 * time.Minute
+	// DefaultPackageLockProgressInterval is how often progress is reported
+	// while waiting for the package manager lock.
+	DefaultPackageLockProgressInterval = 10 * time.Second
 )
 
 // PackageInstallationStep installs NVIDIA packages using the package manager.
@@ -30,6 +45,12 @@ type PackageInstallationStep struct {
 	additionalPackages []string                     // Extra packages to install beyond computed ones
 	skipDependencies   bool                         // TODO: Implement to pass --nodeps or equivalent to package manager
 	batchSize          int                          // How many packages to install at once (0 = all)
+	reinstall          bool                         // Reinstall packages that are already installed
+	repository         string                       // Only install from this repository ("" = all)
+	allowEssential     bool                         // Install even if essential packages would be removed
+	lockTimeout        time.Duration                // How long to wait for the package manager lock (0 = do not wait)
+	lockProgress       time.Duration                // How often to report progress while waiting for the lock
+	kernelDetector     kernel.Detector              // For finding the package of the running kernel
 	preInstallHook     func(*install.Context) error // Hook before installation
 	postInstallHook    func(*install.Context) error // Hook after installation
 }
@@ -64,6 +85,61 @@ func WithBatchSize(size int) PackageInstallationStepOption {
 	}
 }
 
+// WithReinstall configures whether already installed packages are reinstalled.
+func WithReinstall(reinstall bool) PackageInstallationStepOption {
+	return func(s *PackageInstallationStep) {
+		s.reinstall = reinstall
+	}
+}
+
+// WithPackageRepository restricts package resolution to the named
+// repository, such as the local repository of an offline bundle.
+func WithPackageRepository(name string) PackageInstallationStepOption {
+	return func(s *PackageInstallationStep) {
+		s.repository = name
+	}
+}
+
+// WithAllowEssentialRemovals configures whether the installation proceeds
+// when it would remove packages essential to the system, such as the kernel
+// or the C library. By default, the step fails before installing anything
+// if the simulated transaction removes any of them.
+func WithAllowEssentialRemovals(allow bool) PackageInstallationStepOption {
+	return func(s *PackageInstallationStep) {
+		s.allowEssential = allow
+	}
+}
+
+// WithPackageLockTimeout sets how long to wait for another process, such as
+// unattended-upgrades or PackageKit, to release the package manager lock.
+// The step fails, naming the process holding the lock, when it is still
+// held after the timeout. If set to 0, the lock is not waited for.
+func WithPackageLockTimeout(timeout time.Duration) PackageInstallationStepOption {
+	return func(s *PackageInstallationStep) {
+		s.lockTimeout = timeout
+	}
+}
+
+// WithPackageLockProgressInterval sets how often progress is reported while
+// waiting for the package manager lock.
+func WithPackageLockProgressInterval(interval time.Duration) PackageInstallationStepOption {
+	return func(s *PackageInstallationStep) {
+		if interval > 0 {
+			s.lockProgress = interval
+		}
+	}
+}
+
+// WithPackageKernelDetector sets a custom kernel detector. The driver
+// packages are matched to the running kernel, such as nvidia-lts for
+// linux-lts on Arch, when it implements kernel.KernelPackageDetector.
+// This is primarily used for testing.
+func WithPackageKernelDetector(detector kernel.Detector) PackageInstallationStepOption {
+	return func(s *PackageInstallationStep) {
+		s.kernelDetector = detector
+	}
+}
+
 // WithPreInstallHook sets a function to be called before package installation.
 // If the hook returns an error, installation is aborted.
 func WithPreInstallHook(fn func(*install.Context) error) PackageInstallationStepOption {
@@ -87,6 +163,8 @@ func NewPackageInstallationStep(opts ...PackageInstallationStepOption) *PackageI
 		additionalPackages: make([]string, 0),
 		skipDependencies:   false,
 		batchSize:          0,
+		lockTimeout:        DefaultPackageLockTimeout,
+		lockProgress:       DefaultPackageLockProgressInterval,
 	}
 
 	for _, opt := range opts {
@@ -100,11 +178,15 @@ func NewPackageInstallationStep(opts ...PackageInstallationStepOption) *PackageI
 // It performs the following steps:
 //  1. Validates prerequisites (package manager, distro info)
 //  2. Computes the packages to install based on driver version and components
-//  3. In dry-run mode, logs what would be installed
-//  4. Runs pre-install hook if configured
-//  5. Installs packages (in batches if configured)
-//  6. Runs post-install hook if configured
-//  7. Stores state for potential rollback
+//  3. Simulates the transaction if the package manager supports it, and
+//     fails if essential packages would be removed
+//  4. In dry-run mode, logs what would be installed
+//  5. Waits for other processes to release the package manager lock
+//  6. Runs pre-install hook if configured
+//  7. Installs packages (in batches if configured), in a transaction if
+//     the package manager supports them
+//  8. Runs post-install hook if configured
+//  9. Stores state for potential rollback
 func (s *PackageInstallationStep) Execute(ctx *install.Context) install.StepResult {
 	startTime := time.Now()
 
@@ -134,6 +216,13 @@ func (s *PackageInstallationStep) Execute(ctx *install.Context) install.StepResu
 
 	ctx.LogDebug("packages to install", "count", len(packages), "packages", packages)
 
+	// Preview the transaction, so that packages the installation would
+	// remove, and a lack of disk space, are known before anything changes
+	if err := s.checkTransaction(ctx, packages); err != nil {
+		ctx.LogError("package transaction check failed", "error", err)
+		return install.FailStep("package transaction check failed", err).WithDuration(time.Since(startTime))
+	}
+
 	// Dry run mode
 	if ctx.DryRun {
 		ctx.Log("dry run: would install packages", "packages", packages)
@@ -145,6 +234,15 @@ func (s *PackageInstallationStep) Execute(ctx *install.Context) install.StepResu
 		return install.FailStep("step cancelled", context.Canceled).WithDuration(time.Since(startTime))
 	}
 
+	// Wait for other package managers to finish
+	if err := s.waitForPackageLock(ctx); err != nil {
+		if ctx.IsCancelled() {
+			return install.FailStep("step cancelled", context.Canceled).WithDuration(time.Since(startTime))
+		}
+		ctx.LogError("package manager lock not released", "error", err)
+		return install.FailStep("package manager is locked by another process", err).WithDuration(time.Since(startTime))
+	}
+
 	// Run pre-install hook if configured
 	if s.preInstallHook != nil {
 		ctx.LogDebug("running pre-install hook")
@@ -160,12 +258,25 @@ func (s *PackageInstallationStep) Execute(ctx *install.Context) install.StepResu
 		return install.FailStep("step cancelled", context.Canceled).WithDuration(time.Since(startTime))
 	}
 
-	// Install packages
+	// Install packages, in a transaction when the package manager supports
+	// them, so that a failure does not leave a partially installed driver
+	tx, transactional := ctx.PackageManager.(pkg.TransactionalManager)
+	if transactional {
+		if err := tx.BeginTransaction(ctx.Context()); err != nil {
+			ctx.LogWarn("failed to begin package transaction, installing without it", "error", err)
+			transactional = false
+		}
+	}
+
 	installedPackages, err := s.installPackages(ctx, packages)
+	if transactional {
+		err = s.endTransaction(ctx, tx, err)
+	}
 	if err != nil {
 		ctx.LogError("package installation failed", "error", err)
-		// If some packages were installed, try to roll them back
-		if len(installedPackages) > 0 {
+		// If some packages were installed outside of a transaction, try to
+		// roll them back
+		if !transactional && len(installedPackages) > 0 {
 			ctx.SetState(StateInstalledPackages, installedPackages)
 			if rollbackErr := s.removePackages(ctx, installedPackages); rollbackErr != nil {
 				ctx.LogWarn("failed to rollback partially installed packages", "error", rollbackErr)
@@ -279,6 +390,131 @@ func (s *PackageInstallationStep) CanRollback() bool {
 	return true
 }
 
+// Packages returns the packages the step would install for the given context
+// without installing them. Packages that are already installed are included;
+// the package manager decides whether to reinstall them.
+func (s *PackageInstallationStep) Packages(ctx *install.Context) ([]string, error) {
+	return s.computePackages(ctx)
+}
+
+// waitForPackageLock waits until no other process holds the package manager
+// lock, reporting progress while it waits. It returns an error naming the
+// process holding the lock when the lock timeout expires. Package managers
+// that cannot report their lock are not waited for.
+func (s *PackageInstallationStep) waitForPackageLock(ctx *install.Context) error {
+	locker, ok := ctx.PackageManager.(pkg.LockableManager)
+	if !ok || s.lockTimeout <= 0 {
+		return nil
+	}
+
+	holder, err := locker.LockHolder(ctx.Context())
+	if err != nil {
+		// Let the package manager report the lock if it is held
+		ctx.LogWarn("failed to check the package manager lock", "error", err)
+		return nil
+	}
+	if holder == nil {
+		return nil
+	}
+
+	ctx.Log("waiting for the package manager lock", "holder", holder.String(), "timeout", s.lockTimeout)
+	ctx.ReportProgress(fmt.Sprintf("Waiting for the package manager lock held by %s", holder))
+
+	waitStart := time.Now()
+	deadline := waitStart.Add(s.lockTimeout)
+	for {
+		wait := time.Until(deadline)
+		if wait > s.lockProgress {
+			wait = s.lockProgress
+		}
+
+		waitCtx, cancel := context.WithTimeout(ctx.Context(), wait)
+		err := locker.WaitForLock(waitCtx)
+		expired := waitCtx.Err() != nil
+		cancel()
+
+		switch {
+		case err == nil:
+			ctx.Log("package manager lock released", "waited", time.Since(waitStart).Round(time.Second))
+			return nil
+		case ctx.IsCancelled():
+			return context.Canceled
+		case !expired:
+			return err
+		case !time.Now().Before(deadline):
+			return fmt.Errorf("timed out after %s: %w", s.lockTimeout, err)
+		}
+
+		ctx.ReportProgress(fmt.Sprintf("Still waiting for the package manager lock (%s elapsed)",
+			time.Since(waitStart).Round(time.Second)))
+	}
+}
+
+// checkTransaction simulates the installation of the packages and stores
+// the simulation and its disk usage in the context. It returns an error
+// naming the essential packages the installation would remove, unless they
+// are allowed, or the filesystems without enough free space for it. The
+// preview is best effort: package managers that cannot simulate, or fail to,
+// are not checked.
+func (s *PackageInstallationStep) checkTransaction(ctx *install.Context, packages []string) error {
+	simulator, ok := ctx.PackageManager.(pkg.SimulationManager)
+	if !ok {
+		return nil
+	}
+
+	sim, err := simulator.SimulateInstall(ctx.Context(), s.installOptions(), packages...)
+	if err != nil {
+		ctx.LogWarn("failed to simulate package installation", "error", err)
+		return nil
+	}
+	ctx.SetState(StatePackageSimulation, sim)
+	ctx.Log("package transaction", "changes", sim.Summary())
+
+	for _, change := range sim.Remove {
+		ctx.Log("package would be removed", "package", change.Name, "replaced_by", change.ReplacedBy)
+	}
+
+	if err := s.checkDiskUsage(ctx, sim); err != nil {
+		return err
+	}
+
+	essential := sim.EssentialRemovals()
+	if len(essential) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(essential))
+	for _, change := range essential {
+		names = append(names, change.Name)
+	}
+	if s.allowEssential {
+		ctx.LogWarn("installation removes essential packages", "packages", names)
+		return nil
+	}
+	return fmt.Errorf("installing would remove %s (use --force to install anyway)", strings.Join(names, ", "))
+}
+
+// checkDiskUsage checks the free space of the filesystems receiving the
+// packages against the sizes of the simulated transaction. Unlike the
+// validation step, it runs once the NVIDIA repository is configured, so the
+// sizes of its packages are known.
+func (s *PackageInstallationStep) checkDiskUsage(ctx *install.Context, sim *pkg.Simulation) error {
+	usage := measuredDiskUsage(ctx, sim)
+	for _, u := range usage {
+		ctx.LogDebug("package disk usage", "dir", u.Dir, "required", u.Required, "available", u.Available, "mount_point", u.MountPoint)
+	}
+
+	shortages := pkg.DiskShortages(usage)
+	if len(shortages) == 0 {
+		return nil
+	}
+	messages := make([]string, 0, len(shortages))
+	for _, shortage := range shortages {
+		messages = append(messages, shortage.String())
+	}
+	return fmt.Errorf("not enough disk space: %s", strings.Join(messages, "; "))
+}
+
 // computePackages determines which packages to install based on the context.
 // It uses nvidia.GetPackageSet to get distribution-specific package names,
 // then adds packages for the specified driver version and components.
@@ -332,14 +568,89 @@ func (s *PackageInstallationStep) computePackages(ctx *install.Context) ([]strin
 		addPackages(s.additionalPackages)
 	}
 
+	if packageSet.HasPrebuiltDrivers() {
+		packages = s.matchKernelDrivers(ctx, packageSet, packages)
+	}
+
 	return packages, nil
 }
 
+// matchKernelDrivers replaces the driver packages with those matching the
+// flavor of the running kernel, when the driver is prebuilt for specific
+// kernels: nvidia-lts for linux-lts on Arch, or nvidia-dkms and the kernel
+// headers for linux-zen or linux-hardened. The packages are left unchanged
+// if the kernel package cannot be found.
+func (s *PackageInstallationStep) matchKernelDrivers(ctx *install.Context, packageSet *nvidia.PackageSet, packages []string) []string {
+	kp, err := s.getKernelPackage(ctx)
+	if err != nil || kp == nil {
+		ctx.LogDebug("cannot find the kernel package, keeping driver packages", "error", err)
+		return packages
+	}
+
+	drivers, dkms := packageSet.DriverPackagesForKernel(kp.Name)
+	if dkms {
+		drivers = append(append([]string{}, drivers...), kp.HeadersPackage)
+	}
+
+	isDriver := make(map[string]bool, len(packageSet.Driver))
+	for _, p := range packageSet.Driver {
+		isDriver[p] = true
+	}
+
+	seen := make(map[string]bool)
+	matched := make([]string, 0, len(packages)+len(drivers))
+	replaced := false
+	for _, p := range packages {
+		candidates := []string{p}
+		if isDriver[p] {
+			if replaced {
+				continue
+			}
+			candidates = drivers
+			replaced = true
+		}
+		for _, c := range candidates {
+			if c != "" && !seen[c] {
+				seen[c] = true
+				matched = append(matched, c)
+			}
+		}
+	}
+
+	if replaced {
+		ctx.Log("matched driver packages to the running kernel",
+			"kernel_package", kp.Name, "flavor", kp.Flavor, "packages", drivers)
+	}
+	return matched
+}
+
+// getKernelPackage returns the package of the running kernel with the kernel
+// detector, or with a kernel.Detector for the distribution if none is set.
+func (s *PackageInstallationStep) getKernelPackage(ctx *install.Context) (*kernel.KernelPackage, error) {
+	var detector kernel.KernelPackageDetector
+	if s.kernelDetector != nil {
+		d, ok := s.kernelDetector.(kernel.KernelPackageDetector)
+		if !ok {
+			return nil, nil
+		}
+		detector = d
+	} else {
+		detector = kernel.NewDetector(
+			kernel.WithExecutor(ctx.Executor),
+			kernel.WithDistroFamily(ctx.DistroInfo.Family),
+		)
+	}
+	return detector.GetKernelPackage(ctx.Context())
+}
+
 // installPackages installs the specified packages using the package manager.
 // If batchSize is set, packages are installed in batches.
+// The download and installation progress of the package manager is
+// reported as progress of the step, batch after batch.
 // Returns the list of packages that were successfully installed.
 func (s *PackageInstallationStep) installPackages(ctx *install.Context, packages []string) ([]string, error) {
-	opts := pkg.NonInteractiveInstallOptions()
+	opts := s.installOptions()
+	opts.Progress = ctx.ReportPackageProgress
 
 	// If we have no batch size, install all at once
 	if s.batchSize <= 0 {
@@ -375,6 +686,34 @@ func (s *PackageInstallationStep) installPackages(ctx *install.Context, packages
 	return installedPackages, nil
 }
 
+// installOptions returns the options packages are installed with.
+func (s *PackageInstallationStep) installOptions() pkg.InstallOptions {
+	opts := pkg.NonInteractiveInstallOptions()
+	opts.Reinstall = s.reinstall
+	opts.Repository = s.repository
+	return opts
+}
+
+// endTransaction commits the package transaction if the installation
+// succeeded, and rolls it back otherwise, so that no package of a failed
+// installation is left installed. Returns the installation error, or the
+// commit error.
+func (s *PackageInstallationStep) endTransaction(ctx *install.Context, tx pkg.TransactionalManager, installErr error) error {
+	if installErr != nil {
+		ctx.Log("rolling back package transaction")
+		if err := tx.RollbackTransaction(ctx.Context()); err != nil {
+			ctx.LogWarn("failed to roll back package transaction", "error", err)
+		}
+		return installErr
+	}
+
+	ctx.LogDebug("committing package transaction")
+	if err := tx.CommitTransaction(ctx.Context()); err != nil {
+		return fmt.Errorf("failed to commit package transaction: %w", err)
+	}
+	return nil
+}
+
 // removePackages removes the specified packages using the package manager.
 func (s *PackageInstallationStep) removePackages(ctx *install.Context, packages []string) error {
 	if ctx.PackageManager == nil {
@@ -3,6 +3,7 @@ package steps
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/tungetti/igor/internal/constants"
 	"github.com/tungetti/igor/internal/distro"
+	"github.com/tungetti/igor/internal/gpu/kernel"
 	"github.com/tungetti/igor/internal/install"
 	"github.com/tungetti/igor/internal/pkg"
 	"github.com/tungetti/igor/internal/pkg/nvidia"
@@ -200,8 +202,16 @@ func (m *PackageMockManager) IsAvailable() bool {
 	return true
 }
 
-// Ensure PackageMockManager implements pkg.Manager.
-var _ pkg.Manager = (*PackageMockManager)(nil)
+// ImportPinnedGPGKey implements pkg.KeyPinningManager.
+func (m *PackageMockManager) ImportPinnedGPGKey(ctx context.Context, keyURL string, pin pkg.KeyPin) (*pkg.KeyImport, error) {
+	return &pkg.KeyImport{Fingerprints: pin.Fingerprints}, nil
+}
+
+// Ensure PackageMockManager implements pkg.Manager and pkg.KeyPinningManager.
+var (
+	_ pkg.Manager           = (*PackageMockManager)(nil)
+	_ pkg.KeyPinningManager = (*PackageMockManager)(nil)
+)
 
 // =============================================================================
 // Test Distribution Helpers
@@ -503,6 +513,75 @@ func TestPackageInstallationStep_Execute_WithAdditionalPackages(t *testing.T) {
 	assert.Contains(t, mockPM.installPackages, "another-pkg")
 }
 
+func TestPackageInstallationStep_Execute_WithReinstall(t *testing.T) {
+	mockPM := NewPackageMockManager()
+	step := NewPackageInstallationStep(WithReinstall(true))
+
+	ctx := install.NewContext(
+		install.WithPackageManager(mockPM),
+		install.WithDistroInfo(newTestUbuntuDistro()),
+		install.WithDriverVersion("550"),
+	)
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusCompleted, result.Status)
+	assert.True(t, mockPM.lastInstallOpts.Reinstall)
+	assert.True(t, mockPM.lastInstallOpts.NoConfirm)
+}
+
+func TestPackageInstallationStep_Execute_WithPackageRepository(t *testing.T) {
+	mockPM := NewPackageMockManager()
+	step := NewPackageInstallationStep(WithPackageRepository(pkg.BundleRepositoryName))
+
+	ctx := install.NewContext(
+		install.WithPackageManager(mockPM),
+		install.WithDistroInfo(newTestUbuntuDistro()),
+		install.WithDriverVersion("550"),
+	)
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusCompleted, result.Status)
+	assert.Equal(t, pkg.BundleRepositoryName, mockPM.lastInstallOpts.Repository)
+}
+
+func TestPackageInstallationStep_Execute_ReportsPackageProgress(t *testing.T) {
+	mockPM := NewPackageMockManager()
+	mockPM.SetInstallCallback(func(ctx context.Context, opts pkg.InstallOptions, packages ...string) error {
+		require.NotNil(t, opts.Progress)
+		opts.Progress(pkg.Progress{Phase: pkg.ProgressDownload, Package: packages[0], Percent: 100})
+		opts.Progress(pkg.Progress{Phase: pkg.ProgressInstall, Package: packages[0], Percent: 50})
+		return nil
+	})
+
+	ctx := install.NewContext(
+		install.WithPackageManager(mockPM),
+		install.WithDistroInfo(newTestUbuntuDistro()),
+		install.WithDriverVersion("550"),
+	)
+
+	w := install.NewWorkflow("test")
+	w.AddStep(NewPackageInstallationStep())
+
+	var reported []install.StepProgress
+	w.OnProgress(func(p install.StepProgress) {
+		if p.PackagePhase != "" {
+			reported = append(reported, p)
+		}
+	})
+
+	result := w.Execute(ctx)
+	require.Equal(t, install.WorkflowStatusCompleted, result.Status)
+
+	require.Len(t, reported, 2)
+	assert.Equal(t, "Downloading nvidia-driver-550 (100%)", reported[0].Message)
+	assert.Equal(t, 50.0, reported[0].StepPercent)
+	assert.Equal(t, pkg.ProgressInstall, reported[1].PackagePhase)
+	assert.Equal(t, "nvidia-driver-550", reported[1].Package)
+	assert.Equal(t, 75.0, reported[1].StepPercent)
+}
+
 func TestPackageInstallationStep_Execute_WithPreInstallHook(t *testing.T) {
 	mockPM := NewPackageMockManager()
 	hookCalled := false
@@ -1025,6 +1104,24 @@ func TestPackageInstallationStep_ComputePackages(t *testing.T) {
 	assert.Contains(t, packages, "nvidia-driver-550")
 }
 
+func TestPackageInstallationStep_Packages(t *testing.T) {
+	mockPM := NewPackageMockManager()
+	step := NewPackageInstallationStep(WithAdditionalPackages("cuda-toolkit-12-4"))
+
+	ctx := install.NewContext(
+		install.WithPackageManager(mockPM),
+		install.WithDistroInfo(newTestUbuntuDistro()),
+		install.WithDriverVersion("550"),
+	)
+
+	packages, err := step.Packages(ctx)
+
+	require.NoError(t, err)
+	assert.Contains(t, packages, "nvidia-driver-550")
+	assert.Equal(t, "cuda-toolkit-12-4", packages[len(packages)-1])
+	assert.False(t, mockPM.installCalled, "Packages must not install anything")
+}
+
 func TestPackageInstallationStep_ComputePackages_WithVersion(t *testing.T) {
 	mockPM := NewPackageMockManager()
 	step := NewPackageInstallationStep()
@@ -1571,3 +1668,518 @@ func TestPackageInstallationStep_StateInstallTime(t *testing.T) {
 	assert.True(t, ok)
 	assert.Greater(t, installTime.Nanoseconds(), int64(0))
 }
+
+// =============================================================================
+// PackageInstallationStep Lock Tests
+// =============================================================================
+
+// LockableMockManager is a PackageMockManager whose lock is held until
+// releaseAfter calls to WaitForLock have returned.
+type LockableMockManager struct {
+	*PackageMockManager
+	holder       *pkg.LockHolder
+	holderErr    error
+	releaseAfter int
+	waitCalls    int
+}
+
+func NewLockableMockManager(holder *pkg.LockHolder, releaseAfter int) *LockableMockManager {
+	return &LockableMockManager{
+		PackageMockManager: NewPackageMockManager(),
+		holder:             holder,
+		releaseAfter:       releaseAfter,
+	}
+}
+
+func (m *LockableMockManager) AcquireLock(ctx context.Context) error { return nil }
+func (m *LockableMockManager) ReleaseLock(ctx context.Context) error { return nil }
+
+func (m *LockableMockManager) IsLocked(ctx context.Context) (bool, error) {
+	holder, err := m.LockHolder(ctx)
+	return holder != nil, err
+}
+
+func (m *LockableMockManager) LockHolder(ctx context.Context) (*pkg.LockHolder, error) {
+	return m.holder, m.holderErr
+}
+
+func (m *LockableMockManager) WaitForLock(ctx context.Context) error {
+	m.waitCalls++
+	if m.releaseAfter >= 0 && m.waitCalls > m.releaseAfter {
+		m.holder = nil
+		return nil
+	}
+	<-ctx.Done()
+	return pkg.Wrap(pkg.ErrLockAcquireFailed, ctx.Err())
+}
+
+// lockProgressMessages runs the step in a workflow and returns the progress
+// messages the step reported while waiting for the lock.
+func lockProgressMessages(t *testing.T, step install.Step, ctx *install.Context) (install.WorkflowResult, []string) {
+	t.Helper()
+
+	w := install.NewWorkflow("test")
+	w.AddStep(step)
+
+	var messages []string
+	w.OnProgress(func(p install.StepProgress) {
+		if strings.Contains(p.Message, "package manager lock") {
+			messages = append(messages, p.Message)
+		}
+	})
+
+	return w.Execute(ctx), messages
+}
+
+func TestNewPackageInstallationStep_LockDefaults(t *testing.T) {
+	step := NewPackageInstallationStep()
+	assert.Equal(t, DefaultPackageLockTimeout, step.lockTimeout)
+	assert.Equal(t, DefaultPackageLockProgressInterval, step.lockProgress)
+
+	step = NewPackageInstallationStep(
+		WithPackageLockTimeout(time.Minute),
+		WithPackageLockProgressInterval(0),
+	)
+	assert.Equal(t, time.Minute, step.lockTimeout)
+	assert.Equal(t, DefaultPackageLockProgressInterval, step.lockProgress)
+}
+
+func TestPackageInstallationStep_Execute_LockFree(t *testing.T) {
+	mockPM := NewLockableMockManager(nil, 0)
+	step := NewPackageInstallationStep()
+
+	ctx := install.NewContext(
+		install.WithPackageManager(mockPM),
+		install.WithDistroInfo(newTestUbuntuDistro()),
+		install.WithDriverVersion("550"),
+	)
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusCompleted, result.Status)
+	assert.Zero(t, mockPM.waitCalls)
+	assert.True(t, mockPM.installCalled)
+}
+
+func TestPackageInstallationStep_Execute_WaitsForLock(t *testing.T) {
+	mockPM := NewLockableMockManager(&pkg.LockHolder{PID: 812, Command: "unattended-upgr"}, 2)
+	step := NewPackageInstallationStep(
+		WithPackageLockTimeout(time.Second),
+		WithPackageLockProgressInterval(5*time.Millisecond),
+	)
+
+	ctx := install.NewContext(
+		install.WithPackageManager(mockPM),
+		install.WithDistroInfo(newTestUbuntuDistro()),
+		install.WithDriverVersion("550"),
+	)
+
+	result, messages := lockProgressMessages(t, step, ctx)
+
+	assert.Equal(t, install.WorkflowStatusCompleted, result.Status)
+	assert.Equal(t, 3, mockPM.waitCalls)
+	assert.True(t, mockPM.installCalled)
+	require.Len(t, messages, 3)
+	assert.Equal(t, "Waiting for the package manager lock held by unattended-upgr (pid 812)", messages[0])
+	assert.Contains(t, messages[1], "Still waiting for the package manager lock")
+}
+
+func TestPackageInstallationStep_Execute_LockTimeout(t *testing.T) {
+	mockPM := NewLockableMockManager(&pkg.LockHolder{PID: 812, Command: "unattended-upgr"}, -1)
+	step := NewPackageInstallationStep(
+		WithPackageLockTimeout(30*time.Millisecond),
+		WithPackageLockProgressInterval(10*time.Millisecond),
+	)
+
+	ctx := install.NewContext(
+		install.WithPackageManager(mockPM),
+		install.WithDistroInfo(newTestUbuntuDistro()),
+		install.WithDriverVersion("550"),
+	)
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusFailed, result.Status)
+	assert.Contains(t, result.Message, "package manager is locked")
+	require.Error(t, result.Error)
+	assert.ErrorIs(t, result.Error, pkg.ErrLockAcquireFailed)
+	assert.Contains(t, result.Error.Error(), "timed out after 30ms")
+	assert.False(t, mockPM.installCalled)
+}
+
+func TestPackageInstallationStep_Execute_LockNotWaitedFor(t *testing.T) {
+	t.Run("timeout disabled", func(t *testing.T) {
+		mockPM := NewLockableMockManager(&pkg.LockHolder{PID: 812}, -1)
+		step := NewPackageInstallationStep(WithPackageLockTimeout(0))
+
+		ctx := install.NewContext(
+			install.WithPackageManager(mockPM),
+			install.WithDistroInfo(newTestUbuntuDistro()),
+			install.WithDriverVersion("550"),
+		)
+
+		result := step.Execute(ctx)
+
+		assert.Equal(t, install.StepStatusCompleted, result.Status)
+		assert.Zero(t, mockPM.waitCalls)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		mockPM := NewLockableMockManager(&pkg.LockHolder{PID: 812}, -1)
+		step := NewPackageInstallationStep()
+
+		ctx := install.NewContext(
+			install.WithPackageManager(mockPM),
+			install.WithDistroInfo(newTestUbuntuDistro()),
+			install.WithDriverVersion("550"),
+			install.WithDryRun(true),
+		)
+
+		result := step.Execute(ctx)
+
+		assert.Equal(t, install.StepStatusCompleted, result.Status)
+		assert.Zero(t, mockPM.waitCalls)
+	})
+
+	t.Run("lock check fails", func(t *testing.T) {
+		mockPM := NewLockableMockManager(nil, -1)
+		mockPM.holderErr = errors.New("permission denied")
+		step := NewPackageInstallationStep()
+
+		ctx := install.NewContext(
+			install.WithPackageManager(mockPM),
+			install.WithDistroInfo(newTestUbuntuDistro()),
+			install.WithDriverVersion("550"),
+		)
+
+		result := step.Execute(ctx)
+
+		assert.Equal(t, install.StepStatusCompleted, result.Status)
+		assert.Zero(t, mockPM.waitCalls)
+	})
+}
+
+func TestPackageInstallationStep_Execute_CancelledWhileWaitingForLock(t *testing.T) {
+	mockPM := NewLockableMockManager(&pkg.LockHolder{PID: 812}, -1)
+	step := NewPackageInstallationStep(WithPackageLockProgressInterval(5 * time.Millisecond))
+
+	ctx := install.NewContext(
+		install.WithPackageManager(mockPM),
+		install.WithDistroInfo(newTestUbuntuDistro()),
+		install.WithDriverVersion("550"),
+	)
+	time.AfterFunc(20*time.Millisecond, ctx.Cancel)
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusFailed, result.Status)
+	assert.ErrorIs(t, result.Error, context.Canceled)
+	assert.False(t, mockPM.installCalled)
+}
+
+// =============================================================================
+// PackageInstallationStep Transaction Tests
+// =============================================================================
+
+// TransactionalMockManager is a PackageMockManager that records the
+// transaction calls made by the step.
+type TransactionalMockManager struct {
+	*PackageMockManager
+	beginErr  error
+	commitErr error
+	active    bool
+	calls     []string
+}
+
+func NewTransactionalMockManager() *TransactionalMockManager {
+	return &TransactionalMockManager{PackageMockManager: NewPackageMockManager()}
+}
+
+func (m *TransactionalMockManager) BeginTransaction(ctx context.Context) error {
+	m.calls = append(m.calls, "begin")
+	if m.beginErr != nil {
+		return m.beginErr
+	}
+	m.active = true
+	return nil
+}
+
+func (m *TransactionalMockManager) CommitTransaction(ctx context.Context) error {
+	m.calls = append(m.calls, "commit")
+	m.active = false
+	return m.commitErr
+}
+
+func (m *TransactionalMockManager) RollbackTransaction(ctx context.Context) error {
+	m.calls = append(m.calls, "rollback")
+	m.active = false
+	return nil
+}
+
+func (m *TransactionalMockManager) InTransaction() bool {
+	return m.active
+}
+
+func newTransactionTestContext(mockPM pkg.Manager) *install.Context {
+	return install.NewContext(
+		install.WithPackageManager(mockPM),
+		install.WithDistroInfo(newTestUbuntuDistro()),
+		install.WithDriverVersion("550"),
+	)
+}
+
+func TestPackageInstallationStep_Execute_Transaction(t *testing.T) {
+	mockPM := NewTransactionalMockManager()
+	step := NewPackageInstallationStep(WithBatchSize(1), WithAdditionalPackages("pkg1", "pkg2"))
+	ctx := newTransactionTestContext(mockPM)
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusCompleted, result.Status)
+	assert.Equal(t, []string{"begin", "commit"}, mockPM.calls)
+	assert.Greater(t, mockPM.installCount, 1)
+	assert.True(t, ctx.GetStateBool(StatePackagesInstalled))
+}
+
+func TestPackageInstallationStep_Execute_TransactionRolledBack(t *testing.T) {
+	mockPM := NewTransactionalMockManager()
+	mockPM.SetInstallCallback(func(ctx context.Context, opts pkg.InstallOptions, packages ...string) error {
+		if mockPM.installCount == 2 {
+			return errors.New("batch failed")
+		}
+		return nil
+	})
+	step := NewPackageInstallationStep(WithBatchSize(1), WithAdditionalPackages("pkg1", "pkg2"))
+	ctx := newTransactionTestContext(mockPM)
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusFailed, result.Status)
+	assert.Contains(t, result.Error.Error(), "batch failed")
+	assert.Equal(t, []string{"begin", "rollback"}, mockPM.calls)
+	// The transaction reverts the first batch, so nothing is removed
+	assert.False(t, mockPM.removeCalled)
+	assert.False(t, ctx.GetStateBool(StatePackagesInstalled))
+}
+
+func TestPackageInstallationStep_Execute_TransactionCommitFails(t *testing.T) {
+	mockPM := NewTransactionalMockManager()
+	mockPM.commitErr = pkg.Wrap(pkg.ErrTransactionFailed, errors.New("dnf shell failed"))
+	step := NewPackageInstallationStep()
+	ctx := newTransactionTestContext(mockPM)
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusFailed, result.Status)
+	assert.ErrorIs(t, result.Error, pkg.ErrTransactionFailed)
+	assert.Contains(t, result.Error.Error(), "failed to commit package transaction")
+	assert.Equal(t, []string{"begin", "commit"}, mockPM.calls)
+	assert.False(t, mockPM.removeCalled)
+}
+
+func TestPackageInstallationStep_Execute_TransactionBeginFails(t *testing.T) {
+	mockPM := NewTransactionalMockManager()
+	mockPM.beginErr = pkg.Wrap(pkg.ErrTransactionFailed, errors.New("dpkg-query failed"))
+	step := NewPackageInstallationStep()
+	ctx := newTransactionTestContext(mockPM)
+
+	result := step.Execute(ctx)
+
+	// Packages are installed without a transaction
+	assert.Equal(t, install.StepStatusCompleted, result.Status)
+	assert.Equal(t, []string{"begin"}, mockPM.calls)
+	assert.True(t, mockPM.installCalled)
+}
+
+// =============================================================================
+// PackageInstallationStep Simulation Tests
+// =============================================================================
+
+// SimulatingMockManager is a PackageMockManager that returns a fixed
+// simulation of the installation transaction.
+type SimulatingMockManager struct {
+	*PackageMockManager
+	sim         *pkg.Simulation
+	simErr      error
+	simulated   []string
+	lastSimOpts pkg.InstallOptions
+}
+
+func NewSimulatingMockManager(sim *pkg.Simulation) *SimulatingMockManager {
+	return &SimulatingMockManager{PackageMockManager: NewPackageMockManager(), sim: sim}
+}
+
+func (m *SimulatingMockManager) SimulateInstall(ctx context.Context, opts pkg.InstallOptions, packages ...string) (*pkg.Simulation, error) {
+	m.simulated = append(m.simulated, packages...)
+	m.lastSimOpts = opts
+	return m.sim, m.simErr
+}
+
+func TestPackageInstallationStep_Execute_Simulation(t *testing.T) {
+	sim := pkg.NewSimulation()
+	sim.Install = []pkg.PackageChange{{Name: "nvidia-driver-550"}}
+	sim.Remove = []pkg.PackageChange{{Name: "xserver-xorg-video-nouveau"}}
+	mockPM := NewSimulatingMockManager(sim)
+	step := NewPackageInstallationStep(WithReinstall(true))
+	ctx := newTransactionTestContext(mockPM)
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusCompleted, result.Status)
+	assert.Equal(t, mockPM.installPackages, mockPM.simulated)
+	assert.True(t, mockPM.lastSimOpts.Reinstall)
+	stored, ok := ctx.GetState(StatePackageSimulation)
+	require.True(t, ok)
+	assert.Same(t, sim, stored)
+}
+
+func TestPackageInstallationStep_Execute_EssentialRemovals(t *testing.T) {
+	sim := pkg.NewSimulation()
+	sim.Remove = []pkg.PackageChange{
+		{Name: "xserver-xorg-video-nouveau"},
+		{Name: "xserver-xorg-core", ReplacedBy: "nvidia-driver-550"},
+	}
+
+	t.Run("blocked", func(t *testing.T) {
+		mockPM := NewSimulatingMockManager(sim)
+		step := NewPackageInstallationStep()
+
+		result := step.Execute(newTransactionTestContext(mockPM))
+
+		assert.Equal(t, install.StepStatusFailed, result.Status)
+		assert.Contains(t, result.Error.Error(), "would remove xserver-xorg-core (use --force to install anyway)")
+		assert.False(t, mockPM.installCalled)
+	})
+
+	t.Run("blocked in dry run", func(t *testing.T) {
+		mockPM := NewSimulatingMockManager(sim)
+		ctx := newTransactionTestContext(mockPM)
+		ctx.DryRun = true
+
+		result := NewPackageInstallationStep().Execute(ctx)
+
+		assert.Equal(t, install.StepStatusFailed, result.Status)
+	})
+
+	t.Run("allowed", func(t *testing.T) {
+		mockPM := NewSimulatingMockManager(sim)
+		step := NewPackageInstallationStep(WithAllowEssentialRemovals(true))
+
+		result := step.Execute(newTransactionTestContext(mockPM))
+
+		assert.Equal(t, install.StepStatusCompleted, result.Status)
+		assert.True(t, mockPM.installCalled)
+	})
+}
+
+func TestPackageInstallationStep_Execute_SimulationFails(t *testing.T) {
+	mockPM := NewSimulatingMockManager(nil)
+	mockPM.simErr = pkg.Wrap(pkg.ErrSimulationFailed, errors.New("root privileges are required"))
+	ctx := newTransactionTestContext(mockPM)
+
+	result := NewPackageInstallationStep().Execute(ctx)
+
+	// The preview is best effort
+	assert.Equal(t, install.StepStatusCompleted, result.Status)
+	assert.True(t, mockPM.installCalled)
+	_, ok := ctx.GetState(StatePackageSimulation)
+	assert.False(t, ok)
+}
+
+// =============================================================================
+// PackageInstallationStep Kernel Flavor Tests
+// =============================================================================
+
+// newKernelFlavorTestContext creates a context installing the driver and
+// utilities on the given distribution.
+func newKernelFlavorTestContext(mockPM *PackageMockManager, dist *distro.Distribution) *install.Context {
+	return install.NewContext(
+		install.WithPackageManager(mockPM),
+		install.WithDistroInfo(dist),
+		install.WithComponents([]string{string(nvidia.ComponentDriver), string(nvidia.ComponentUtils)}),
+	)
+}
+
+func TestPackageInstallationStep_Execute_KernelFlavor(t *testing.T) {
+	tests := []struct {
+		name          string
+		kernelPackage *kernel.KernelPackage
+		contains      []string
+		excludes      []string
+	}{
+		{
+			name:          "stock kernel",
+			kernelPackage: &kernel.KernelPackage{Name: "linux", HeadersPackage: "linux-headers"},
+			contains:      []string{"nvidia", "nvidia-utils"},
+			excludes:      []string{"nvidia-dkms", "linux-headers"},
+		},
+		{
+			name:          "LTS kernel",
+			kernelPackage: &kernel.KernelPackage{Name: "linux-lts", Flavor: "lts", HeadersPackage: "linux-lts-headers"},
+			contains:      []string{"nvidia-lts", "nvidia-utils"},
+			excludes:      []string{"nvidia", "nvidia-dkms"},
+		},
+		{
+			name:          "zen kernel",
+			kernelPackage: &kernel.KernelPackage{Name: "linux-zen", Flavor: "zen", HeadersPackage: "linux-zen-headers"},
+			contains:      []string{"nvidia-dkms", "linux-zen-headers", "nvidia-utils"},
+			excludes:      []string{"nvidia"},
+		},
+		{
+			name:     "kernel package unknown",
+			contains: []string{"nvidia", "nvidia-utils"},
+			excludes: []string{"nvidia-dkms"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockPM := NewPackageMockManager()
+			mockPM.SetFamily(constants.FamilyArch)
+			detector := NewMockKernelDetector()
+			detector.kernelPackage = tt.kernelPackage
+			step := NewPackageInstallationStep(WithPackageKernelDetector(detector))
+
+			result := step.Execute(newKernelFlavorTestContext(mockPM, newTestArchDistro()))
+
+			assert.Equal(t, install.StepStatusCompleted, result.Status)
+			for _, p := range tt.contains {
+				assert.Contains(t, mockPM.installPackages, p)
+			}
+			for _, p := range tt.excludes {
+				assert.NotContains(t, mockPM.installPackages, p)
+			}
+		})
+	}
+}
+
+func TestPackageInstallationStep_Execute_KernelFlavorError(t *testing.T) {
+	mockPM := NewPackageMockManager()
+	mockPM.SetFamily(constants.FamilyArch)
+	detector := NewMockKernelDetector()
+	detector.getKernelPackageErr = errors.New("uname failed")
+	step := NewPackageInstallationStep(WithPackageKernelDetector(detector))
+
+	result := step.Execute(newKernelFlavorTestContext(mockPM, newTestArchDistro()))
+
+	assert.Equal(t, install.StepStatusCompleted, result.Status)
+	assert.Contains(t, mockPM.installPackages, "nvidia")
+}
+
+func TestPackageInstallationStep_Packages_KernelFlavorNoPrebuiltDrivers(t *testing.T) {
+	detector := NewMockKernelDetector()
+	detector.kernelPackage = &kernel.KernelPackage{
+		Name:           "linux-image-6.8.0-1012-azure",
+		Flavor:         "azure",
+		HeadersPackage: "linux-headers-6.8.0-1012-azure",
+	}
+	ctx := newKernelFlavorTestContext(NewPackageMockManager(), newTestUbuntuDistro())
+
+	packages, err := NewPackageInstallationStep(WithPackageKernelDetector(detector)).Packages(ctx)
+	require.NoError(t, err)
+	expected, err := NewPackageInstallationStep().Packages(ctx)
+	require.NoError(t, err)
+
+	// Debian drivers are not prebuilt for a kernel flavor
+	assert.Equal(t, expected, packages)
+}
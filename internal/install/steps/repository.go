@@ -5,6 +5,7 @@ package steps
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/tungetti/igor/internal/install"
@@ -18,6 +19,12 @@ const (
 	StateRepositoryConfigured = "repository_configured"
 	// StateRepositoryName stores the name of the configured repository.
 	StateRepositoryName = "repository_name"
+	// StateRepositoryPreferred indicates whether the repository was given
+	// priority for the NVIDIA packages.
+	StateRepositoryPreferred = "repository_preferred"
+	// StateRepositoryKeyFingerprints stores the fingerprints of the verified
+	// signing key of the repository.
+	StateRepositoryKeyFingerprints = "repository_key_fingerprints"
 )
 
 // RepositoryStep configures NVIDIA repositories for the detected Linux distribution.
@@ -26,6 +33,7 @@ const (
 type RepositoryStep struct {
 	install.BaseStep
 	skipUpdate bool
+	repository *pkg.Repository
 }
 
 // RepositoryStepOption configures the repository step.
@@ -40,6 +48,16 @@ func WithSkipUpdate(skip bool) RepositoryStepOption {
 	}
 }
 
+// WithRepository configures the step to add the given repository instead of
+// the NVIDIA repository of the distribution, such as the local repository of
+// an offline bundle. Only that repository is refreshed after it is added, so
+// the step works without network access.
+func WithRepository(repo pkg.Repository) RepositoryStepOption {
+	return func(s *RepositoryStep) {
+		s.repository = &repo
+	}
+}
+
 // NewRepositoryStep creates a new repository configuration step with the given options.
 func NewRepositoryStep(opts ...RepositoryStepOption) *RepositoryStep {
 	s := &RepositoryStep{
@@ -56,12 +74,16 @@ func NewRepositoryStep(opts ...RepositoryStepOption) *RepositoryStep {
 
 // Execute configures the NVIDIA repository for the current distribution.
 // It performs the following steps:
-//  1. Gets the appropriate repository for the distribution
+//  1. Gets the appropriate repository for the distribution, unless one was
+//     configured with WithRepository
 //  2. For Arch Linux, skips as no external repository is needed
 //  3. In dry-run mode, logs the action without making changes
-//  4. Adds the repository using the package manager
-//  5. Updates package lists (unless skipUpdate is set)
-//  6. Stores state for potential rollback
+//  4. Imports the signing key of the repository, verified against its
+//     pinned fingerprints
+//  5. Adds the repository using the package manager
+//  6. Gives the NVIDIA repository priority for the NVIDIA packages
+//  7. Updates package lists (unless skipUpdate is set)
+//  8. Stores state for potential rollback
 func (s *RepositoryStep) Execute(ctx *install.Context) install.StepResult {
 	startTime := time.Now()
 
@@ -78,10 +100,17 @@ func (s *RepositoryStep) Execute(ctx *install.Context) install.StepResult {
 	}
 
 	// Get repository for this distribution
-	repo, err := nvidia.GetRepository(ctx.DistroInfo)
-	if err != nil {
-		ctx.LogError("failed to get repository info", "error", err)
-		return install.FailStep("failed to get repository info", err).WithDuration(time.Since(startTime))
+	repo := s.repository
+	updateOpts := pkg.DefaultUpdateOptions()
+	if repo != nil {
+		updateOpts.Repository = repo.Name
+	} else {
+		var err error
+		repo, err = nvidia.GetRepository(ctx.DistroInfo)
+		if err != nil {
+			ctx.LogError("failed to get repository info", "error", err)
+			return install.FailStep("failed to get repository info", err).WithDuration(time.Since(startTime))
+		}
 	}
 
 	// Arch Linux doesn't need an external repository
@@ -94,7 +123,13 @@ func (s *RepositoryStep) Execute(ctx *install.Context) install.StepResult {
 
 	// Dry run mode
 	if ctx.DryRun {
+		if isKeyURL(repo.GPGKey) {
+			ctx.Log("dry run: would import the signing key verified against its pinned fingerprints", "url", repo.GPGKey)
+		}
 		ctx.Log("dry run: would add repository", "name", repo.Name, "url", repo.URL)
+		if s.repository == nil {
+			ctx.Log("dry run: would prefer repository for the NVIDIA packages", "name", repo.Name)
+		}
 		if !s.skipUpdate {
 			ctx.Log("dry run: would update package lists")
 		}
@@ -106,6 +141,20 @@ func (s *RepositoryStep) Execute(ctx *install.Context) install.StepResult {
 		return install.FailStep("step cancelled", context.Canceled).WithDuration(time.Since(startTime))
 	}
 
+	// Import the signing key before the repository is used
+	if isKeyURL(repo.GPGKey) {
+		keyring, err := s.importSigningKey(ctx, repo)
+		if err != nil {
+			ctx.LogError("failed to import repository signing key", "name", repo.Name, "error", err)
+			return install.FailStep("failed to import repository signing key", err).WithDuration(time.Since(startTime))
+		}
+		if keyring != "" {
+			signed := *repo
+			signed.GPGKey = keyring
+			repo = &signed
+		}
+	}
+
 	// Add repository
 	ctx.Log("adding repository", "name", repo.Name)
 	if err := ctx.PackageManager.AddRepository(ctx.Context(), *repo); err != nil {
@@ -115,23 +164,35 @@ func (s *RepositoryStep) Execute(ctx *install.Context) install.StepResult {
 
 	ctx.LogDebug("repository added successfully", "name", repo.Name)
 
+	// Give the NVIDIA repository priority, so that the NVIDIA packages are
+	// not mixed with those of the distribution. Repositories given with
+	// WithRepository, such as the one of an offline bundle, are used on
+	// their own.
+	preferred := false
+	if s.repository == nil {
+		var err error
+		preferred, err = s.preferRepository(ctx, *repo)
+		if err != nil {
+			ctx.LogError("failed to prefer repository", "name", repo.Name, "error", err)
+			s.removeRepository(ctx, repo.Name, false)
+			return install.FailStep("failed to prefer repository", err).WithDuration(time.Since(startTime))
+		}
+	}
+
 	// Update package lists unless skipped
 	if !s.skipUpdate {
 		// Check for cancellation before updating
 		if ctx.IsCancelled() {
 			// Try to rollback the repository we just added
-			_ = ctx.PackageManager.RemoveRepository(ctx.Context(), repo.Name)
+			s.removeRepository(ctx, repo.Name, preferred)
 			return install.FailStep("step cancelled", context.Canceled).WithDuration(time.Since(startTime))
 		}
 
 		ctx.Log("updating package lists")
-		if err := ctx.PackageManager.Update(ctx.Context(), pkg.DefaultUpdateOptions()); err != nil {
+		if err := ctx.PackageManager.Update(ctx.Context(), updateOpts); err != nil {
 			ctx.LogError("failed to update package lists", "error", err)
 			// Try to rollback the repository we just added
-			rollbackErr := ctx.PackageManager.RemoveRepository(ctx.Context(), repo.Name)
-			if rollbackErr != nil {
-				ctx.LogError("failed to rollback repository", "name", repo.Name, "error", rollbackErr)
-			}
+			s.removeRepository(ctx, repo.Name, preferred)
 			return install.FailStep("failed to update package lists", err).WithDuration(time.Since(startTime))
 		}
 		ctx.LogDebug("package lists updated successfully")
@@ -140,6 +201,7 @@ func (s *RepositoryStep) Execute(ctx *install.Context) install.StepResult {
 	// Store state for rollback
 	ctx.SetState(StateRepositoryConfigured, true)
 	ctx.SetState(StateRepositoryName, repo.Name)
+	ctx.SetState(StateRepositoryPreferred, preferred)
 
 	ctx.Log("repository configured successfully", "name", repo.Name)
 	return install.CompleteStep("repository configured successfully").
@@ -147,6 +209,78 @@ func (s *RepositoryStep) Execute(ctx *install.Context) install.StepResult {
 		WithCanRollback(true)
 }
 
+// importSigningKey downloads the signing key of the repository and imports
+// it once verified against its pinned fingerprints. It returns the keyring
+// the repository must reference, on package managers using a keyring per
+// repository. A key without a pin, or a package manager unable to verify
+// it, fails the step: the key would be trusted for every package of the
+// repository without being checked.
+func (s *RepositoryStep) importSigningKey(ctx *install.Context, repo *pkg.Repository) (string, error) {
+	pin, ok := nvidia.GetKeyPin(repo.GPGKey)
+	if !ok {
+		return "", fmt.Errorf("no fingerprint is pinned for the signing key %s", repo.GPGKey)
+	}
+
+	pm, ok := ctx.PackageManager.(pkg.KeyPinningManager)
+	if !ok {
+		return "", fmt.Errorf("package manager %s cannot verify pinned signing keys", ctx.PackageManager.Name())
+	}
+
+	ctx.Log("importing repository signing key", "name", pin.Name, "url", repo.GPGKey)
+	imported, err := pm.ImportPinnedGPGKey(ctx.Context(), repo.GPGKey, pin)
+	if err != nil {
+		return "", err
+	}
+
+	ctx.LogDebug("repository signing key verified", "name", pin.Name, "fingerprints", strings.Join(imported.Fingerprints, ", "))
+	ctx.SetState(StateRepositoryKeyFingerprints, imported.Fingerprints)
+
+	// The repository rotated its key; the old one should no longer be trusted
+	for _, key := range imported.Retired {
+		ctx.LogWarn("a retired signing key of the repository is still trusted, remove it",
+			"name", pin.Name, "key", key.String())
+	}
+
+	return imported.Keyring, nil
+}
+
+// preferRepository gives the repository priority for the NVIDIA packages,
+// on package managers supporting preferences. It returns whether a
+// preference was set.
+func (s *RepositoryStep) preferRepository(ctx *install.Context, repo pkg.Repository) (bool, error) {
+	pm, ok := ctx.PackageManager.(pkg.PreferenceManager)
+	if !ok {
+		ctx.LogDebug("package manager does not support repository preferences", "name", ctx.PackageManager.Name())
+		return false, nil
+	}
+
+	ctx.Log("preferring repository for the NVIDIA packages", "name", repo.Name)
+	if err := pm.PreferRepository(ctx.Context(), repo, nvidia.PreferredPackages); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// removeRepository removes a repository added by a failed execution, and
+// its preference if one was set. Errors are logged, as the step is already
+// failing.
+func (s *RepositoryStep) removeRepository(ctx *install.Context, name string, preferred bool) {
+	if preferred {
+		if err := ctx.PackageManager.(pkg.PreferenceManager).RemoveRepositoryPreference(ctx.Context(), name); err != nil {
+			ctx.LogError("failed to remove repository preference", "name", name, "error", err)
+		}
+	}
+	if err := ctx.PackageManager.RemoveRepository(ctx.Context(), name); err != nil {
+		ctx.LogError("failed to rollback repository", "name", name, "error", err)
+	}
+}
+
+// isKeyURL reports whether a repository GPG key is a URL to download the
+// key from, rather than a key already on the system.
+func isKeyURL(key string) bool {
+	return strings.HasPrefix(key, "https://") || strings.HasPrefix(key, "http://")
+}
+
 // Rollback removes the repository that was added during execution.
 // If no repository was configured, this is a no-op.
 func (s *RepositoryStep) Rollback(ctx *install.Context) error {
@@ -169,6 +303,17 @@ func (s *RepositoryStep) Rollback(ctx *install.Context) error {
 
 	ctx.Log("rolling back repository configuration", "name", repoName)
 
+	// Remove the preference before the repository it refers to
+	if ctx.GetStateBool(StateRepositoryPreferred) {
+		if pm, ok := ctx.PackageManager.(pkg.PreferenceManager); ok {
+			if err := pm.RemoveRepositoryPreference(ctx.Context(), repoName); err != nil {
+				ctx.LogError("failed to remove repository preference during rollback", "name", repoName, "error", err)
+				return fmt.Errorf("failed to remove preference of repository '%s': %w", repoName, err)
+			}
+		}
+		ctx.DeleteState(StateRepositoryPreferred)
+	}
+
 	// Remove the repository
 	if err := ctx.PackageManager.RemoveRepository(ctx.Context(), repoName); err != nil {
 		ctx.LogError("failed to remove repository during rollback", "name", repoName, "error", err)
@@ -178,6 +323,7 @@ func (s *RepositoryStep) Rollback(ctx *install.Context) error {
 	// Clear state
 	ctx.DeleteState(StateRepositoryConfigured)
 	ctx.DeleteState(StateRepositoryName)
+	ctx.DeleteState(StateRepositoryKeyFingerprints)
 
 	ctx.LogDebug("repository rollback completed", "name", repoName)
 	return nil
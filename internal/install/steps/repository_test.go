@@ -11,6 +11,7 @@ import (
 	"github.com/tungetti/igor/internal/distro"
 	"github.com/tungetti/igor/internal/install"
 	"github.com/tungetti/igor/internal/pkg"
+	"github.com/tungetti/igor/internal/pkg/nvidia"
 )
 
 // =============================================================================
@@ -22,10 +23,23 @@ type MockPackageManager struct {
 	name   string
 	family constants.DistroFamily
 
+	// Configured repositories
+	repos []pkg.Repository
+
 	// Error injection
-	addRepoErr    error
-	removeRepoErr error
-	updateErr     error
+	addRepoErr     error
+	removeRepoErr  error
+	updateErr      error
+	listReposErr   error
+	disableRepoErr map[string]error
+	enableRepoErr  error
+	importKeyErr   error
+	preferErr      error
+	removePrefErr  error
+
+	// Key import results
+	importKeyring string
+	importRetired []pkg.RetiredKey
 
 	// Tracking calls
 	addRepoCalled    bool
@@ -33,6 +47,14 @@ type MockPackageManager struct {
 	updateCalled     bool
 	lastAddedRepo    *pkg.Repository
 	lastRemovedRepo  string
+	lastUpdateOpts   pkg.UpdateOptions
+	disabledRepos    []string
+	enabledRepos     []string
+	importedKeys     []string
+	importedPins     []pkg.KeyPin
+	preferredRepos   []string
+	preferredGlobs   []string
+	removedPrefs     []string
 }
 
 // NewMockPackageManager creates a new mock package manager for testing.
@@ -75,6 +97,7 @@ func (m *MockPackageManager) RemoveRepository(ctx context.Context, name string)
 // Update implements pkg.Manager.
 func (m *MockPackageManager) Update(ctx context.Context, opts pkg.UpdateOptions) error {
 	m.updateCalled = true
+	m.lastUpdateOpts = opts
 	return m.updateErr
 }
 
@@ -120,16 +143,21 @@ func (m *MockPackageManager) ListUpgradable(ctx context.Context) ([]pkg.Package,
 
 // ListRepositories implements pkg.Manager.
 func (m *MockPackageManager) ListRepositories(ctx context.Context) ([]pkg.Repository, error) {
-	return nil, nil
+	return m.repos, m.listReposErr
 }
 
 // EnableRepository implements pkg.Manager.
 func (m *MockPackageManager) EnableRepository(ctx context.Context, name string) error {
-	return nil
+	m.enabledRepos = append(m.enabledRepos, name)
+	return m.enableRepoErr
 }
 
 // DisableRepository implements pkg.Manager.
 func (m *MockPackageManager) DisableRepository(ctx context.Context, name string) error {
+	if err := m.disableRepoErr[name]; err != nil {
+		return err
+	}
+	m.disabledRepos = append(m.disabledRepos, name)
 	return nil
 }
 
@@ -168,8 +196,46 @@ func (m *MockPackageManager) IsAvailable() bool {
 	return true
 }
 
-// Ensure MockPackageManager implements pkg.Manager.
-var _ pkg.Manager = (*MockPackageManager)(nil)
+// ImportPinnedGPGKey implements pkg.KeyPinningManager.
+func (m *MockPackageManager) ImportPinnedGPGKey(ctx context.Context, keyURL string, pin pkg.KeyPin) (*pkg.KeyImport, error) {
+	if m.importKeyErr != nil {
+		return nil, m.importKeyErr
+	}
+	m.importedKeys = append(m.importedKeys, keyURL)
+	m.importedPins = append(m.importedPins, pin)
+	return &pkg.KeyImport{
+		Fingerprints: pin.Fingerprints,
+		Keyring:      m.importKeyring,
+		Retired:      m.importRetired,
+	}, nil
+}
+
+// PreferRepository implements pkg.PreferenceManager.
+func (m *MockPackageManager) PreferRepository(ctx context.Context, repo pkg.Repository, packages []string) error {
+	if m.preferErr != nil {
+		return m.preferErr
+	}
+	m.preferredRepos = append(m.preferredRepos, repo.Name)
+	m.preferredGlobs = packages
+	return nil
+}
+
+// RemoveRepositoryPreference implements pkg.PreferenceManager.
+func (m *MockPackageManager) RemoveRepositoryPreference(ctx context.Context, name string) error {
+	if m.removePrefErr != nil {
+		return m.removePrefErr
+	}
+	m.removedPrefs = append(m.removedPrefs, name)
+	return nil
+}
+
+// Ensure MockPackageManager implements pkg.Manager and its optional
+// capabilities.
+var (
+	_ pkg.Manager           = (*MockPackageManager)(nil)
+	_ pkg.KeyPinningManager = (*MockPackageManager)(nil)
+	_ pkg.PreferenceManager = (*MockPackageManager)(nil)
+)
 
 // =============================================================================
 // Test Helpers
@@ -291,6 +357,201 @@ func TestRepositoryStep_Execute_SuccessWithFedora(t *testing.T) {
 	assert.True(t, mockPM.updateCalled)
 }
 
+func TestRepositoryStep_Execute_ImportsPinnedKey(t *testing.T) {
+	mockPM := NewMockPackageManager()
+	mockPM.importKeyring = "/etc/apt/keyrings/nvidia-cuda.gpg"
+	step := NewRepositoryStep()
+
+	dist := newDebianDistro()
+	ctx := install.NewContext(
+		install.WithPackageManager(mockPM),
+		install.WithDistroInfo(dist),
+	)
+
+	result := step.Execute(ctx)
+
+	require.Equal(t, install.StepStatusCompleted, result.Status)
+	require.Len(t, mockPM.importedKeys, 1)
+	assert.Contains(t, mockPM.importedKeys[0], "3bf863cc.pub")
+	assert.Equal(t, "nvidia-cuda", mockPM.importedPins[0].Name)
+
+	// The repository references the keyring instead of the key URL
+	require.NotNil(t, mockPM.lastAddedRepo)
+	assert.Equal(t, "/etc/apt/keyrings/nvidia-cuda.gpg", mockPM.lastAddedRepo.GPGKey)
+	fingerprints, ok := ctx.GetState(StateRepositoryKeyFingerprints)
+	require.True(t, ok)
+	assert.Equal(t, []string{nvidia.CUDAKeyFingerprint}, fingerprints)
+}
+
+func TestRepositoryStep_Execute_PinnedKeyMismatch(t *testing.T) {
+	mockPM := NewMockPackageManager()
+	mockPM.importKeyErr = pkg.Wrap(pkg.ErrGPGKeyMismatch, errors.New("key for nvidia-cuda has fingerprint X"))
+	step := NewRepositoryStep()
+
+	ctx := install.NewContext(
+		install.WithPackageManager(mockPM),
+		install.WithDistroInfo(newDebianDistro()),
+	)
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusFailed, result.Status)
+	assert.ErrorIs(t, result.Error, pkg.ErrGPGKeyMismatch)
+	assert.False(t, mockPM.addRepoCalled)
+	assert.False(t, ctx.GetStateBool(StateRepositoryConfigured))
+}
+
+func TestRepositoryStep_Execute_RetiredKeyStillTrusted(t *testing.T) {
+	mockPM := NewMockPackageManager()
+	mockPM.importRetired = []pkg.RetiredKey{
+		{Fingerprint: nvidia.CUDARetiredKeyFingerprint, ReplacedBy: nvidia.CUDAKeyFingerprint, Date: "2022-04-27"},
+	}
+	step := NewRepositoryStep()
+
+	ctx := install.NewContext(
+		install.WithPackageManager(mockPM),
+		install.WithDistroInfo(newDebianDistro()),
+	)
+
+	// A retired key is reported, not fatal
+	result := step.Execute(ctx)
+	assert.Equal(t, install.StepStatusCompleted, result.Status)
+	assert.True(t, mockPM.addRepoCalled)
+}
+
+func TestRepositoryStep_Execute_UnpinnedKey(t *testing.T) {
+	mockPM := NewMockPackageManager()
+	step := NewRepositoryStep(WithRepository(pkg.Repository{
+		Name:   "custom",
+		URL:    "https://example.com/repo",
+		GPGKey: "https://example.com/key.pub",
+	}))
+
+	ctx := install.NewContext(
+		install.WithPackageManager(mockPM),
+		install.WithDistroInfo(newUbuntuDistro()),
+	)
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusFailed, result.Status)
+	assert.Contains(t, result.Error.Error(), "no fingerprint is pinned")
+	assert.Empty(t, mockPM.importedKeys)
+	assert.False(t, mockPM.addRepoCalled)
+}
+
+func TestRepositoryStep_Execute_KeyPinningUnsupported(t *testing.T) {
+	mockPM := NewMockPackageManager()
+	step := NewRepositoryStep()
+
+	// A package manager without pinned key imports cannot add the repository
+	ctx := install.NewContext(
+		install.WithPackageManager(struct{ pkg.Manager }{mockPM}),
+		install.WithDistroInfo(newDebianDistro()),
+	)
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusFailed, result.Status)
+	assert.Contains(t, result.Error.Error(), "cannot verify pinned signing keys")
+	assert.False(t, mockPM.addRepoCalled)
+}
+
+func TestRepositoryStep_Execute_PrefersRepository(t *testing.T) {
+	mockPM := NewMockPackageManager()
+	step := NewRepositoryStep()
+
+	ctx := install.NewContext(
+		install.WithPackageManager(mockPM),
+		install.WithDistroInfo(newDebianDistro()),
+	)
+
+	result := step.Execute(ctx)
+
+	require.Equal(t, install.StepStatusCompleted, result.Status)
+	assert.Equal(t, []string{"nvidia-cuda"}, mockPM.preferredRepos)
+	assert.Equal(t, nvidia.PreferredPackages, mockPM.preferredGlobs)
+	assert.True(t, ctx.GetStateBool(StateRepositoryPreferred))
+
+	// Rollback removes the preference along with the repository
+	require.NoError(t, step.Rollback(ctx))
+	assert.Equal(t, []string{"nvidia-cuda"}, mockPM.removedPrefs)
+	assert.True(t, mockPM.removeRepoCalled)
+	assert.False(t, ctx.GetStateBool(StateRepositoryPreferred))
+}
+
+func TestRepositoryStep_Execute_PreferError(t *testing.T) {
+	mockPM := NewMockPackageManager()
+	mockPM.preferErr = errors.New("permission denied")
+	step := NewRepositoryStep()
+
+	ctx := install.NewContext(
+		install.WithPackageManager(mockPM),
+		install.WithDistroInfo(newDebianDistro()),
+	)
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusFailed, result.Status)
+	assert.Contains(t, result.Message, "failed to prefer repository")
+	// The repository added is removed again
+	assert.True(t, mockPM.removeRepoCalled)
+	assert.False(t, mockPM.updateCalled)
+}
+
+func TestRepositoryStep_Execute_UpdateError_RemovesPreference(t *testing.T) {
+	mockPM := NewMockPackageManager()
+	mockPM.SetUpdateError(errors.New("network error"))
+	step := NewRepositoryStep()
+
+	ctx := install.NewContext(
+		install.WithPackageManager(mockPM),
+		install.WithDistroInfo(newDebianDistro()),
+	)
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusFailed, result.Status)
+	assert.Equal(t, []string{"nvidia-cuda"}, mockPM.removedPrefs)
+	assert.True(t, mockPM.removeRepoCalled)
+}
+
+func TestRepositoryStep_Execute_WithRepository_NotPreferred(t *testing.T) {
+	mockPM := NewMockPackageManager()
+	step := NewRepositoryStep(WithRepository(pkg.Repository{
+		Name:    "igor-bundle",
+		URL:     "file:///var/lib/igor/bundle",
+		Trusted: true,
+	}))
+
+	ctx := install.NewContext(
+		install.WithPackageManager(mockPM),
+		install.WithDistroInfo(newUbuntuDistro()),
+	)
+
+	result := step.Execute(ctx)
+
+	require.Equal(t, install.StepStatusCompleted, result.Status)
+	assert.Empty(t, mockPM.preferredRepos)
+	assert.False(t, ctx.GetStateBool(StateRepositoryPreferred))
+}
+
+func TestRepositoryStep_Rollback_RemovePreferenceError(t *testing.T) {
+	mockPM := NewMockPackageManager()
+	mockPM.removePrefErr = errors.New("permission denied")
+	step := NewRepositoryStep()
+
+	ctx := install.NewContext(install.WithPackageManager(mockPM))
+	ctx.SetState(StateRepositoryConfigured, true)
+	ctx.SetState(StateRepositoryName, "nvidia-cuda")
+	ctx.SetState(StateRepositoryPreferred, true)
+
+	err := step.Rollback(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to remove preference")
+	assert.False(t, mockPM.removeRepoCalled)
+}
+
 func TestRepositoryStep_Execute_ArchLinux_Skip(t *testing.T) {
 	mockPM := NewMockPackageManager()
 	mockPM.family = constants.FamilyArch
@@ -310,6 +571,27 @@ func TestRepositoryStep_Execute_ArchLinux_Skip(t *testing.T) {
 	assert.False(t, mockPM.updateCalled)
 }
 
+func TestRepositoryStep_Execute_WithRepository(t *testing.T) {
+	mockPM := NewMockPackageManager()
+	mockPM.family = constants.FamilyArch
+	repo := pkg.Repository{Name: pkg.BundleRepositoryName, URL: "file:///srv/bundle", Enabled: true, Trusted: true}
+	step := NewRepositoryStep(WithRepository(repo))
+
+	// The repository is added even on Arch Linux
+	ctx := install.NewContext(
+		install.WithPackageManager(mockPM),
+		install.WithDistroInfo(newArchDistro()),
+	)
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusCompleted, result.Status)
+	require.NotNil(t, mockPM.lastAddedRepo)
+	assert.Equal(t, repo, *mockPM.lastAddedRepo)
+	assert.Equal(t, pkg.BundleRepositoryName, mockPM.lastUpdateOpts.Repository)
+	assert.Equal(t, pkg.BundleRepositoryName, ctx.GetStateString(StateRepositoryName))
+}
+
 func TestRepositoryStep_Execute_DryRun(t *testing.T) {
 	mockPM := NewMockPackageManager()
 	step := NewRepositoryStep()
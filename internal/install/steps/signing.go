@@ -0,0 +1,332 @@
+package steps
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/gpu/kernel"
+	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/pkg/nvidia"
+	"github.com/tungetti/igor/internal/secureboot"
+)
+
+// State keys for Secure Boot module signing.
+const (
+	// StateMOKKeyGenerated indicates whether this step created the signing key.
+	StateMOKKeyGenerated = "mok_key_generated"
+	// StateMOKPrivateKey stores the path of the private signing key.
+	StateMOKPrivateKey = "mok_private_key"
+	// StateMOKCertificate stores the path of the signing certificate.
+	StateMOKCertificate = "mok_certificate"
+	// StateMOKDKMSConfigured indicates whether this step wrote the DKMS signing configuration.
+	StateMOKDKMSConfigured = "mok_dkms_configured"
+	// StateMOKEnrollmentQueued indicates whether this step queued the key for enrollment.
+	StateMOKEnrollmentQueued = "mok_enrollment_queued"
+	// StateMOKEnrollment stores the *secureboot.Enrollment the user has to
+	// confirm at the next reboot. It is not kept in the run journal.
+	StateMOKEnrollment = "mok_enrollment"
+)
+
+// ModuleSigningStep prepares the signing of the NVIDIA kernel modules when
+// Secure Boot is enabled. It reuses or creates a Machine Owner Key, has DKMS
+// or akmods sign the modules with it, and queues the key for enrollment.
+// It runs before the packages are installed, as their installation already
+// builds the modules.
+type ModuleSigningStep struct {
+	install.BaseStep
+	kernelDetector kernel.Detector // For detecting Secure Boot
+	packages       PackageLister   // Tells DKMS from akmods
+	password       string          // Enrollment password (generated if empty)
+}
+
+// ModuleSigningStepOption configures the ModuleSigningStep.
+type ModuleSigningStepOption func(*ModuleSigningStep)
+
+// WithSigningKernelDetector sets a custom kernel detector.
+// This is primarily used for testing.
+func WithSigningKernelDetector(detector kernel.Detector) ModuleSigningStepOption {
+	return func(s *ModuleSigningStep) {
+		s.kernelDetector = detector
+	}
+}
+
+// WithSigningPackageLister sets the step installing the packages. The
+// modules are signed by akmods when it installs an akmod package, and by
+// DKMS otherwise.
+func WithSigningPackageLister(lister PackageLister) ModuleSigningStepOption {
+	return func(s *ModuleSigningStep) {
+		s.packages = lister
+	}
+}
+
+// WithEnrollmentPassword sets the one-time password the MOK manager asks
+// for at the next reboot. If not set, a random password is generated.
+func WithEnrollmentPassword(password string) ModuleSigningStepOption {
+	return func(s *ModuleSigningStep) {
+		s.password = password
+	}
+}
+
+// NewModuleSigningStep creates a new ModuleSigningStep with the given options.
+func NewModuleSigningStep(opts ...ModuleSigningStepOption) *ModuleSigningStep {
+	s := &ModuleSigningStep{
+		BaseStep: install.NewBaseStep("module_signing", "Sign NVIDIA kernel modules for Secure Boot", true),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Execute prepares the signing of the NVIDIA kernel modules.
+// It performs the following steps:
+//  1. Skips the step if Secure Boot is disabled
+//  2. Reuses the key of DKMS or akmods, or creates one
+//  3. Writes the DKMS signing configuration (akmods signs with its key as is)
+//  4. Checks the enrolled keys with mokutil --list-enrolled and --list-new
+//  5. Queues the key for enrollment with mokutil --import if needed
+//  6. Stores the enrollment the user has to confirm at the next reboot
+func (s *ModuleSigningStep) Execute(ctx *install.Context) install.StepResult {
+	startTime := time.Now()
+
+	// Check for cancellation
+	if ctx.IsCancelled() {
+		return install.FailStep("step cancelled", context.Canceled)
+	}
+
+	// Validate prerequisites
+	if err := s.Validate(ctx); err != nil {
+		return install.FailStep("validation failed", err).WithDuration(time.Since(startTime))
+	}
+
+	enabled, err := s.getKernelDetector(ctx).IsSecureBootEnabled(ctx.Context())
+	if err != nil {
+		ctx.LogWarn("could not determine Secure Boot status, modules will not be signed", "error", err)
+		return install.SkipStep("could not determine Secure Boot status").WithDuration(time.Since(startTime))
+	}
+	if !enabled {
+		ctx.LogDebug("Secure Boot is disabled, modules need no signing")
+		return install.SkipStep("Secure Boot is disabled").WithDuration(time.Since(startTime))
+	}
+
+	tool := s.getSigningTool(ctx)
+	manager := secureboot.NewManager(ctx.Executor)
+	key, found := manager.FindKey(ctx.Context(), tool)
+
+	// Dry run mode
+	if ctx.DryRun {
+		if found {
+			ctx.Log("dry run: would reuse signing key", "tool", tool, "certificate", key.Certificate)
+		} else {
+			ctx.Log("dry run: would create signing key", "tool", tool, "certificate", key.Certificate)
+		}
+		if tool == secureboot.ToolDKMS {
+			ctx.Log("dry run: would write DKMS signing configuration", "path", secureboot.DKMSConfigPath)
+		}
+		ctx.Log("dry run: would queue the key for enrollment unless enrolled", "command", "mokutil --import "+key.Certificate)
+		return install.CompleteStep("dry run: modules would be signed for Secure Boot").WithDuration(time.Since(startTime))
+	}
+
+	if found {
+		ctx.Log("reusing signing key", "tool", tool, "certificate", key.Certificate)
+	} else {
+		ctx.Log("creating signing key", "tool", tool, "certificate", key.Certificate)
+		if err := manager.GenerateKey(ctx.Context(), tool, key); err != nil {
+			ctx.LogError("failed to create signing key", "error", err)
+			return install.FailStep("failed to create signing key", err).WithDuration(time.Since(startTime))
+		}
+		ctx.SetState(StateMOKKeyGenerated, true)
+	}
+	ctx.SetState(StateMOKPrivateKey, key.PrivateKey)
+	ctx.SetState(StateMOKCertificate, key.Certificate)
+
+	if tool == secureboot.ToolDKMS {
+		ctx.Log("writing DKMS signing configuration", "path", secureboot.DKMSConfigPath)
+		if err := manager.ConfigureDKMS(ctx.Context(), key); err != nil {
+			ctx.LogError("failed to configure DKMS module signing", "error", err)
+			s.rollbackKey(ctx)
+			return install.FailStep("failed to configure DKMS module signing", err).WithDuration(time.Since(startTime))
+		}
+		ctx.SetState(StateMOKDKMSConfigured, true)
+	}
+
+	// Check for cancellation before enrollment
+	if ctx.IsCancelled() {
+		_ = s.Rollback(ctx)
+		return install.FailStep("step cancelled", context.Canceled).WithDuration(time.Since(startTime))
+	}
+
+	status, err := manager.EnrollmentStatus(ctx.Context(), key)
+	if err != nil {
+		ctx.LogError("failed to check enrolled keys", "error", err)
+		_ = s.Rollback(ctx)
+		return install.FailStep("failed to check enrolled keys", err).WithDuration(time.Since(startTime))
+	}
+
+	switch status {
+	case secureboot.Enrolled:
+		ctx.Log("signing key is already enrolled", "certificate", key.Certificate)
+		return install.CompleteStep("modules will be signed with the enrolled key " + key.Certificate).
+			WithDuration(time.Since(startTime)).
+			WithCanRollback(true)
+	case secureboot.EnrollmentPending:
+		ctx.Log("signing key is already queued for enrollment", "certificate", key.Certificate)
+		ctx.SetState(StateMOKEnrollment, &secureboot.Enrollment{Certificate: key.Certificate})
+		return install.CompleteStep("modules will be signed; confirm the queued key enrollment at the next reboot").
+			WithDuration(time.Since(startTime)).
+			WithCanRollback(true)
+	}
+
+	password := s.password
+	if password == "" {
+		password, err = secureboot.GeneratePassword()
+		if err != nil {
+			_ = s.Rollback(ctx)
+			return install.FailStep("failed to queue key enrollment", err).WithDuration(time.Since(startTime))
+		}
+	}
+
+	ctx.Log("queueing signing key for enrollment", "certificate", key.Certificate)
+	if err := manager.QueueEnrollment(ctx.Context(), key, password); err != nil {
+		ctx.LogError("failed to queue key enrollment", "error", err)
+		_ = s.Rollback(ctx)
+		return install.FailStep("failed to queue key enrollment", err).WithDuration(time.Since(startTime))
+	}
+	ctx.SetState(StateMOKEnrollmentQueued, true)
+	ctx.SetState(StateMOKEnrollment, &secureboot.Enrollment{Certificate: key.Certificate, Password: password})
+
+	return install.CompleteStep("modules will be signed; confirm the key enrollment at the next reboot").
+		WithDuration(time.Since(startTime)).
+		WithCanRollback(true)
+}
+
+// Rollback cancels the enrollment request, and removes the DKMS signing
+// configuration and the key created by this step. Keys that were reused are
+// kept.
+func (s *ModuleSigningStep) Rollback(ctx *install.Context) error {
+	queued := ctx.GetStateBool(StateMOKEnrollmentQueued)
+	configured := ctx.GetStateBool(StateMOKDKMSConfigured)
+	generated := ctx.GetStateBool(StateMOKKeyGenerated)
+	if !queued && !configured && !generated {
+		ctx.LogDebug("no module signing was configured, nothing to rollback")
+		return nil
+	}
+
+	// Validate executor
+	if ctx.Executor == nil {
+		return fmt.Errorf("executor not available for rollback")
+	}
+
+	manager := secureboot.NewManager(ctx.Executor)
+	var errs []string
+
+	if queued {
+		ctx.Log("cancelling signing key enrollment")
+		if err := manager.CancelEnrollment(ctx.Context()); err != nil {
+			ctx.LogError("failed to cancel key enrollment", "error", err)
+			errs = append(errs, err.Error())
+		} else {
+			ctx.DeleteState(StateMOKEnrollmentQueued)
+			ctx.DeleteState(StateMOKEnrollment)
+		}
+	}
+
+	if configured {
+		ctx.Log("removing DKMS signing configuration", "path", secureboot.DKMSConfigPath)
+		if err := manager.RemoveDKMSConfig(ctx.Context()); err != nil {
+			ctx.LogError("failed to remove DKMS signing configuration", "error", err)
+			errs = append(errs, err.Error())
+		} else {
+			ctx.DeleteState(StateMOKDKMSConfigured)
+		}
+	}
+
+	if generated {
+		if err := s.rollbackKey(ctx); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to rollback module signing: %s", strings.Join(errs, "; "))
+	}
+
+	ctx.LogDebug("module signing rollback completed")
+	return nil
+}
+
+// rollbackKey removes the key created by this step.
+func (s *ModuleSigningStep) rollbackKey(ctx *install.Context) error {
+	if !ctx.GetStateBool(StateMOKKeyGenerated) {
+		return nil
+	}
+
+	key := secureboot.Key{
+		PrivateKey:  ctx.GetStateString(StateMOKPrivateKey),
+		Certificate: ctx.GetStateString(StateMOKCertificate),
+	}
+	ctx.Log("removing signing key", "certificate", key.Certificate)
+	if err := secureboot.NewManager(ctx.Executor).RemoveKey(ctx.Context(), key); err != nil {
+		ctx.LogError("failed to remove signing key", "error", err)
+		return err
+	}
+
+	ctx.DeleteState(StateMOKKeyGenerated)
+	ctx.DeleteState(StateMOKPrivateKey)
+	ctx.DeleteState(StateMOKCertificate)
+	return nil
+}
+
+// Validate checks if the step can be executed with the given context.
+// It ensures the Executor is available for running commands.
+func (s *ModuleSigningStep) Validate(ctx *install.Context) error {
+	if ctx.Executor == nil {
+		return fmt.Errorf("executor is required for module signing")
+	}
+	return nil
+}
+
+// CanRollback returns true since module signing can be rolled back.
+func (s *ModuleSigningStep) CanRollback() bool {
+	return true
+}
+
+// getKernelDetector returns the configured kernel detector or creates one
+// running commands with the executor of the context.
+func (s *ModuleSigningStep) getKernelDetector(ctx *install.Context) kernel.Detector {
+	if s.kernelDetector != nil {
+		return s.kernelDetector
+	}
+	return kernel.NewDetector(kernel.WithExecutor(ctx.Executor))
+}
+
+// getSigningTool returns the tool building the modules: akmods if an akmod
+// package is installed, DKMS otherwise. Without a package lister, akmods is
+// assumed for the RHEL family.
+func (s *ModuleSigningStep) getSigningTool(ctx *install.Context) secureboot.Tool {
+	if s.packages != nil {
+		packages, err := s.packages.Packages(ctx)
+		if err == nil {
+			for _, name := range packages {
+				if nvidia.IsAkmodPackage(name) {
+					return secureboot.ToolAkmods
+				}
+			}
+			return secureboot.ToolDKMS
+		}
+		ctx.LogDebug("failed to list packages", "error", err)
+	}
+
+	if ctx.DistroInfo != nil && ctx.DistroInfo.Family == constants.FamilyRHEL {
+		return secureboot.ToolAkmods
+	}
+	return secureboot.ToolDKMS
+}
+
+// Ensure ModuleSigningStep implements the Step interface.
+var _ install.Step = (*ModuleSigningStep)(nil)
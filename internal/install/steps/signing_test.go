@@ -0,0 +1,232 @@
+package steps
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/secureboot"
+)
+
+// =============================================================================
+// Test Helpers
+// =============================================================================
+
+const signingTestFingerprint = "76:a0:92:06:58:00:bf:37:69:01:c3:72:cd:55:a9:0e:1f:de:d2:e0"
+
+// signingExecutor is a mock executor answering test -f for existing files
+// and mokutil --list-enrolled with the enrolled keys.
+type signingExecutor struct {
+	*exec.MockExecutor
+	files    map[string]bool
+	enrolled bool
+}
+
+func newSigningExecutor() *signingExecutor {
+	e := &signingExecutor{MockExecutor: exec.NewMockExecutor(), files: make(map[string]bool)}
+	e.SetResponse("openssl", exec.SuccessResult("sha1 Fingerprint=76:A0:92:06:58:00:BF:37:69:01:C3:72:CD:55:A9:0E:1F:DE:D2:E0\n"))
+	return e
+}
+
+func (e *signingExecutor) Execute(ctx context.Context, cmd string, args ...string) *exec.Result {
+	result := e.MockExecutor.Execute(ctx, cmd, args...)
+	if cmd == "test" && len(args) == 2 && !e.files[args[1]] {
+		return exec.FailureResult(1, "")
+	}
+	if cmd == "mokutil" && len(args) == 1 && args[0] == "--list-enrolled" && e.enrolled {
+		return exec.SuccessResult("[key 1]\nSHA1 Fingerprint: " + signingTestFingerprint + "\n")
+	}
+	return result
+}
+
+// staticPackageLister lists a fixed set of packages.
+type staticPackageLister []string
+
+func (l staticPackageLister) Packages(ctx *install.Context) ([]string, error) {
+	return l, nil
+}
+
+func newSecureBootDetector(enabled bool) *MockKernelDetector {
+	detector := NewMockKernelDetector()
+	detector.secureBootEnabled = enabled
+	return detector
+}
+
+func newSigningTestContext(executor exec.Executor) *install.Context {
+	return install.NewContext(
+		install.WithExecutor(executor),
+		install.WithDistroInfo(newTestUbuntuDistro()),
+	)
+}
+
+// =============================================================================
+// ModuleSigningStep Tests
+// =============================================================================
+
+func TestModuleSigningStep_Execute_SecureBootDisabled(t *testing.T) {
+	executor := newSigningExecutor()
+	step := NewModuleSigningStep(WithSigningKernelDetector(newSecureBootDetector(false)))
+
+	result := step.Execute(newSigningTestContext(executor))
+
+	assert.Equal(t, install.StepStatusSkipped, result.Status)
+	assert.Contains(t, result.Message, "Secure Boot is disabled")
+	assert.Equal(t, 0, executor.CallCount())
+}
+
+func TestModuleSigningStep_Execute_SecureBootUnknown(t *testing.T) {
+	detector := newSecureBootDetector(true)
+	detector.isSecureBootEnabledErr = errors.New("mokutil not found")
+	step := NewModuleSigningStep(WithSigningKernelDetector(detector))
+
+	result := step.Execute(newSigningTestContext(newSigningExecutor()))
+
+	assert.Equal(t, install.StepStatusSkipped, result.Status)
+}
+
+func TestModuleSigningStep_Execute_NewKey(t *testing.T) {
+	executor := newSigningExecutor()
+	ctx := newSigningTestContext(executor)
+	step := NewModuleSigningStep(
+		WithSigningKernelDetector(newSecureBootDetector(true)),
+		WithSigningPackageLister(staticPackageLister{"nvidia-driver-550", "nvidia-dkms-550"}),
+		WithEnrollmentPassword("12345678"),
+	)
+
+	result := step.Execute(ctx)
+
+	require.Equal(t, install.StepStatusCompleted, result.Status, result.Message)
+	assert.True(t, result.CanRollback)
+	assert.True(t, executor.WasCalled("openssl"))
+	assert.True(t, executor.WasCalledWith("tee", secureboot.DKMSConfigPath))
+	assert.True(t, executor.WasCalledWith("mokutil", "--import", secureboot.DKMSKey.Certificate))
+
+	assert.True(t, ctx.GetStateBool(StateMOKKeyGenerated))
+	assert.True(t, ctx.GetStateBool(StateMOKDKMSConfigured))
+	assert.True(t, ctx.GetStateBool(StateMOKEnrollmentQueued))
+	value, ok := ctx.GetState(StateMOKEnrollment)
+	require.True(t, ok)
+	assert.Equal(t, &secureboot.Enrollment{Certificate: secureboot.DKMSKey.Certificate, Password: "12345678"}, value)
+}
+
+func TestModuleSigningStep_Execute_EnrolledKey(t *testing.T) {
+	executor := newSigningExecutor()
+	executor.files[secureboot.UbuntuKey.PrivateKey] = true
+	executor.files[secureboot.UbuntuKey.Certificate] = true
+	executor.enrolled = true
+	ctx := newSigningTestContext(executor)
+	step := NewModuleSigningStep(WithSigningKernelDetector(newSecureBootDetector(true)))
+
+	result := step.Execute(ctx)
+
+	require.Equal(t, install.StepStatusCompleted, result.Status, result.Message)
+	assert.Contains(t, result.Message, "enrolled key "+secureboot.UbuntuKey.Certificate)
+	assert.False(t, executor.WasCalled("chmod"))
+	assert.False(t, executor.WasCalledWith("mokutil", "--import", secureboot.UbuntuKey.Certificate))
+	assert.False(t, ctx.GetStateBool(StateMOKKeyGenerated))
+	_, ok := ctx.GetState(StateMOKEnrollment)
+	assert.False(t, ok)
+}
+
+func TestModuleSigningStep_Execute_Akmods(t *testing.T) {
+	executor := newSigningExecutor()
+	ctx := newSigningTestContext(executor)
+	ctx.DistroInfo = newTestFedoraDistro()
+	step := NewModuleSigningStep(
+		WithSigningKernelDetector(newSecureBootDetector(true)),
+		WithSigningPackageLister(staticPackageLister{"akmod-nvidia", "xorg-x11-drv-nvidia"}),
+	)
+
+	result := step.Execute(ctx)
+
+	require.Equal(t, install.StepStatusCompleted, result.Status, result.Message)
+	assert.True(t, executor.WasCalledWith("kmodgenca", "-a"))
+	assert.False(t, executor.WasCalledWith("tee", secureboot.DKMSConfigPath))
+	assert.True(t, executor.WasCalledWith("mokutil", "--import", secureboot.AkmodsKey.Certificate))
+
+	value, _ := ctx.GetState(StateMOKEnrollment)
+	enrollment := value.(*secureboot.Enrollment)
+	assert.Len(t, enrollment.Password, secureboot.PasswordLength)
+}
+
+func TestModuleSigningStep_Execute_DryRun(t *testing.T) {
+	executor := newSigningExecutor()
+	ctx := newSigningTestContext(executor)
+	ctx.DryRun = true
+	step := NewModuleSigningStep(WithSigningKernelDetector(newSecureBootDetector(true)))
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusCompleted, result.Status)
+	assert.Contains(t, result.Message, "dry run")
+	assert.False(t, executor.WasCalled("openssl"))
+	assert.False(t, executor.WasCalled("mokutil"))
+}
+
+func TestModuleSigningStep_Execute_EnrollmentFails(t *testing.T) {
+	executor := newSigningExecutor()
+	executor.SetResponse("mokutil", exec.FailureResult(1, "Failed to enroll new keys"))
+	ctx := newSigningTestContext(executor)
+	step := NewModuleSigningStep(WithSigningKernelDetector(newSecureBootDetector(true)))
+
+	result := step.Execute(ctx)
+
+	assert.Equal(t, install.StepStatusFailed, result.Status)
+	assert.Contains(t, result.Error.Error(), "Failed to enroll new keys")
+	// The key and the DKMS configuration created are removed
+	assert.True(t, executor.WasCalledWith("rm", "-f", secureboot.DKMSConfigPath, secureboot.DKMSSignToolPath))
+	assert.True(t, executor.WasCalledWith("rm", "-f", secureboot.DKMSKey.PrivateKey, secureboot.DKMSKey.Certificate))
+	assert.False(t, ctx.GetStateBool(StateMOKKeyGenerated))
+}
+
+func TestModuleSigningStep_Rollback(t *testing.T) {
+	t.Run("nothing to rollback", func(t *testing.T) {
+		executor := newSigningExecutor()
+
+		err := NewModuleSigningStep().Rollback(newSigningTestContext(executor))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, executor.CallCount())
+	})
+
+	t.Run("reused key is kept", func(t *testing.T) {
+		executor := newSigningExecutor()
+		ctx := newSigningTestContext(executor)
+		ctx.SetState(StateMOKDKMSConfigured, true)
+		ctx.SetState(StateMOKEnrollmentQueued, true)
+		ctx.SetState(StateMOKPrivateKey, secureboot.UbuntuKey.PrivateKey)
+		ctx.SetState(StateMOKCertificate, secureboot.UbuntuKey.Certificate)
+
+		err := NewModuleSigningStep().Rollback(ctx)
+
+		require.NoError(t, err)
+		assert.True(t, executor.WasCalledWith("mokutil", "--revoke-import"))
+		assert.True(t, executor.WasCalledWith("rm", "-f", secureboot.DKMSConfigPath, secureboot.DKMSSignToolPath))
+		assert.False(t, executor.WasCalledWith("rm", "-f", secureboot.UbuntuKey.PrivateKey, secureboot.UbuntuKey.Certificate))
+		assert.False(t, ctx.GetStateBool(StateMOKEnrollmentQueued))
+	})
+
+	t.Run("failure is reported", func(t *testing.T) {
+		executor := newSigningExecutor()
+		executor.SetResponse("mokutil", exec.FailureResult(1, "Failed to delete the import request"))
+		ctx := newSigningTestContext(executor)
+		ctx.SetState(StateMOKEnrollmentQueued, true)
+
+		err := NewModuleSigningStep().Rollback(ctx)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Failed to delete the import request")
+	})
+}
+
+func TestModuleSigningStep_Validate(t *testing.T) {
+	step := NewModuleSigningStep()
+	assert.Error(t, step.Validate(install.NewContext()))
+	assert.NoError(t, step.Validate(newSigningTestContext(newSigningExecutor())))
+	assert.Equal(t, "module_signing", step.Name())
+	assert.True(t, step.CanRollback())
+}
@@ -10,6 +10,8 @@ import (
 
 	"github.com/tungetti/igor/internal/gpu/validator"
 	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/pkg"
+	"github.com/tungetti/igor/internal/pkg/nvidia"
 )
 
 // ValidationCheck represents a single validation check to perform.
@@ -30,6 +32,9 @@ const (
 	CheckNouveauStatus
 	// CheckNVIDIAGPU validates that an NVIDIA GPU is present.
 	CheckNVIDIAGPU
+	// CheckRepositoryConflicts validates that a single source of NVIDIA
+	// packages is enabled.
+	CheckRepositoryConflicts
 )
 
 // String returns the string representation of a ValidationCheck.
@@ -49,6 +54,8 @@ func (c ValidationCheck) String() string {
 		return "nouveau_status"
 	case CheckNVIDIAGPU:
 		return "nvidia_gpu"
+	case CheckRepositoryConflicts:
+		return "repository_conflicts"
 	default:
 		return fmt.Sprintf("unknown(%d)", int(c))
 	}
@@ -59,6 +66,7 @@ type ValidationStep struct {
 	install.BaseStep
 	validator      validator.Validator
 	requiredDiskMB int64
+	packages       PackageLister
 	checks         []ValidationCheck
 }
 
@@ -72,13 +80,24 @@ func WithValidator(v validator.Validator) ValidationStepOption {
 	}
 }
 
-// WithRequiredDiskMB sets the required disk space in megabytes.
+// WithRequiredDiskMB sets the required disk space in megabytes, checked
+// when the disk usage of the packages cannot be estimated.
 func WithRequiredDiskMB(mb int64) ValidationStepOption {
 	return func(s *ValidationStep) {
 		s.requiredDiskMB = mb
 	}
 }
 
+// WithPackageLister sets the step listing the packages of the installation.
+// The disk space check then estimates the space the packages need from
+// the package manager, and falls back to the required disk space when it
+// cannot simulate the installation.
+func WithPackageLister(lister PackageLister) ValidationStepOption {
+	return func(s *ValidationStep) {
+		s.packages = lister
+	}
+}
+
 // WithChecks sets the specific validation checks to perform.
 func WithChecks(checks ...ValidationCheck) ValidationStepOption {
 	return func(s *ValidationStep) {
@@ -94,6 +113,7 @@ func defaultChecks() []ValidationCheck {
 		CheckDiskSpace,
 		CheckBuildTools,
 		CheckNouveauStatus,
+		CheckRepositoryConflicts,
 	}
 }
 
@@ -223,6 +243,9 @@ func (s *ValidationStep) runCheck(ctx context.Context, v validator.Validator, ch
 	case CheckKernelHeaders:
 		return v.ValidateKernelHeaders(ctx)
 	case CheckDiskSpace:
+		if result := s.checkDiskUsage(installCtx); result != nil {
+			return result, nil
+		}
 		return v.ValidateDiskSpace(ctx, s.requiredDiskMB)
 	case CheckSecureBoot:
 		return v.ValidateSecureBoot(ctx)
@@ -232,11 +255,37 @@ func (s *ValidationStep) runCheck(ctx context.Context, v validator.Validator, ch
 		return v.ValidateNouveauStatus(ctx)
 	case CheckNVIDIAGPU:
 		return s.checkNVIDIAGPU(installCtx)
+	case CheckRepositoryConflicts:
+		return s.checkRepositoryConflicts(installCtx)
 	default:
 		return nil, fmt.Errorf("unknown check: %s", check.String())
 	}
 }
 
+// checkDiskUsage checks the free space of the filesystems receiving the
+// packages against the download and installed sizes of the simulated
+// installation. It returns nil, for the required disk space to be checked
+// instead, without a package lister, when the package manager cannot
+// simulate the installation, such as before the NVIDIA repository is
+// configured, and when the free space cannot be measured.
+func (s *ValidationStep) checkDiskUsage(ctx *install.Context) *validator.CheckResult {
+	if s.packages == nil {
+		return nil
+	}
+
+	packages, err := s.packages.Packages(ctx)
+	if err != nil {
+		ctx.LogDebug("failed to list packages for the disk space estimate", "error", err)
+		return nil
+	}
+
+	usage := estimateTransactionDiskUsage(ctx, pkg.NonInteractiveInstallOptions(), packages)
+	if usage == nil {
+		return nil
+	}
+	return diskUsageCheckResult(usage)
+}
+
 // checkNVIDIAGPU validates that at least one NVIDIA GPU is present in the system.
 func (s *ValidationStep) checkNVIDIAGPU(ctx *install.Context) (*validator.CheckResult, error) {
 	if ctx.GPUInfo == nil {
@@ -271,6 +320,51 @@ func (s *ValidationStep) checkNVIDIAGPU(ctx *install.Context) (*validator.CheckR
 	).WithDetail("gpu_count", fmt.Sprintf("%d", gpuCount)), nil
 }
 
+// checkRepositoryConflicts validates that the enabled repositories do not
+// provide the NVIDIA packages from competing sources. The check is skipped
+// without a package manager or distribution info, and when the repositories
+// cannot be listed.
+func (s *ValidationStep) checkRepositoryConflicts(ctx *install.Context) (*validator.CheckResult, error) {
+	if ctx.PackageManager == nil || ctx.DistroInfo == nil {
+		return nil, nil
+	}
+
+	analysis, err := nvidia.AnalyzeRepositories(ctx.Context(), ctx.PackageManager, ctx.DistroInfo)
+	if err != nil {
+		ctx.LogWarn("skipping repository conflict check", "error", err)
+		return nil, nil
+	}
+	ctx.SetState(StateRepositoryAnalysis, analysis)
+
+	if !analysis.HasConflicts() {
+		return validator.NewCheckResult(
+			"repository_conflicts",
+			true,
+			"no conflicting NVIDIA repositories",
+			validator.SeverityInfo,
+		), nil
+	}
+
+	conflicts := make([]string, 0, len(analysis.Conflicts))
+	for _, c := range analysis.Conflicts {
+		conflicts = append(conflicts, c.String())
+	}
+
+	var competing []string
+	for _, repo := range analysis.CompetingRepositories() {
+		competing = append(competing, repo.Name)
+	}
+
+	return validator.NewCheckResult(
+		"repository_conflicts",
+		false,
+		fmt.Sprintf("conflicting NVIDIA repositories: %s", strings.Join(conflicts, "; ")),
+		validator.SeverityWarning,
+	).WithRemediation(fmt.Sprintf("Keep %s and disable %s with 'sudo igor doctor --fix'",
+		analysis.Preferred().Name, strings.Join(competing, ", "))).
+		WithDetail("competing_repositories", strings.Join(competing, ",")), nil
+}
+
 // storeResults stores validation results in the context state.
 func (s *ValidationStep) storeResults(ctx *install.Context, passed bool, warnings, errors []string, needsHeaders, needsNouveau bool) {
 	ctx.SetState("validation_passed", passed)
@@ -2,6 +2,7 @@ package steps
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -11,6 +12,8 @@ import (
 	"github.com/tungetti/igor/internal/gpu/pci"
 	"github.com/tungetti/igor/internal/gpu/validator"
 	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/pkg"
+	"github.com/tungetti/igor/internal/pkg/nvidia"
 )
 
 // MockValidator implements validator.Validator for testing.
@@ -145,6 +148,7 @@ func TestValidationCheck_String(t *testing.T) {
 		{CheckBuildTools, "build_tools"},
 		{CheckNouveauStatus, "nouveau_status"},
 		{CheckNVIDIAGPU, "nvidia_gpu"},
+		{CheckRepositoryConflicts, "repository_conflicts"},
 		{ValidationCheck(99), "unknown(99)"},
 	}
 
@@ -393,6 +397,66 @@ func TestValidationStep_Execute_WithGPU(t *testing.T) {
 	assert.True(t, ctx.GetStateBool("validation_passed"))
 }
 
+// TestValidationStep_Execute_RepositoryConflicts tests the repository conflict check.
+func TestValidationStep_Execute_RepositoryConflicts(t *testing.T) {
+	step := NewValidationStep(
+		WithValidator(NewMockValidator()),
+		WithChecks(CheckRepositoryConflicts),
+	)
+
+	t.Run("warns about competing repositories", func(t *testing.T) {
+		pm := NewMockPackageManager()
+		pm.repos = []pkg.Repository{
+			{Name: "graphics-drivers-ubuntu-ppa-jammy", Enabled: true},
+			{Name: "cuda-ubuntu2204-x86_64", Enabled: true},
+		}
+		ctx := install.NewContext(install.WithPackageManager(pm), install.WithDistroInfo(newUbuntuDistro()))
+
+		result := step.Execute(ctx)
+
+		assert.Equal(t, install.StepStatusCompleted, result.Status)
+		warnings := getStringSliceFromState(ctx, "validation_warnings")
+		require.Len(t, warnings, 1)
+		assert.Contains(t, warnings[0], "graphics-drivers-ppa and nvidia-cuda both provide")
+
+		analysis, ok := ctx.GetState(StateRepositoryAnalysis)
+		require.True(t, ok)
+		assert.True(t, analysis.(*nvidia.RepositoryAnalysis).HasConflicts())
+	})
+
+	t.Run("passes with a single source", func(t *testing.T) {
+		pm := NewMockPackageManager()
+		pm.repos = []pkg.Repository{{Name: "graphics-drivers-ubuntu-ppa-jammy", Enabled: true}}
+		ctx := install.NewContext(install.WithPackageManager(pm), install.WithDistroInfo(newUbuntuDistro()))
+
+		result := step.Execute(ctx)
+
+		assert.Equal(t, install.StepStatusCompleted, result.Status)
+		assert.Empty(t, getStringSliceFromState(ctx, "validation_warnings"))
+	})
+
+	t.Run("skipped when repositories cannot be listed", func(t *testing.T) {
+		pm := NewMockPackageManager()
+		pm.listReposErr = errors.New("permission denied")
+		ctx := install.NewContext(install.WithPackageManager(pm), install.WithDistroInfo(newUbuntuDistro()))
+
+		result := step.Execute(ctx)
+
+		assert.Equal(t, install.StepStatusCompleted, result.Status)
+		assert.Empty(t, getStringSliceFromState(ctx, "validation_warnings"))
+	})
+
+	t.Run("skipped without package manager", func(t *testing.T) {
+		ctx := install.NewContext()
+
+		result := step.Execute(ctx)
+
+		assert.Equal(t, install.StepStatusCompleted, result.Status)
+		_, ok := ctx.GetState(StateRepositoryAnalysis)
+		assert.False(t, ok)
+	})
+}
+
 // TestValidationStep_Execute_KernelHeadersFails tests kernel headers failure tracking.
 func TestValidationStep_Execute_KernelHeadersFails(t *testing.T) {
 	mockValidator := NewMockValidator()
@@ -669,6 +733,7 @@ func TestDefaultChecks(t *testing.T) {
 	assert.Contains(t, checks, CheckDiskSpace)
 	assert.Contains(t, checks, CheckBuildTools)
 	assert.Contains(t, checks, CheckNouveauStatus)
+	assert.Contains(t, checks, CheckRepositoryConflicts)
 	assert.NotContains(t, checks, CheckSecureBoot)
 	assert.NotContains(t, checks, CheckNVIDIAGPU)
 }
@@ -26,6 +26,8 @@ const (
 	StateGPUDetected = "gpu_detected"
 	// StateVerificationErrors stores a list of verification failure messages.
 	StateVerificationErrors = "verification_errors"
+	// StateVerificationChecks stores the individual check results as []VerificationCheck.
+	StateVerificationChecks = "verification_checks"
 )
 
 // VerificationCheck represents a single verification check result.
@@ -323,6 +325,7 @@ func (s *VerificationStep) calculateOverallResult(results []VerificationCheck) b
 func (s *VerificationStep) storeResults(ctx *install.Context, results []VerificationCheck, errors []string, passed bool) {
 	ctx.SetState(StateVerificationPassed, passed)
 	ctx.SetState(StateVerificationErrors, errors)
+	ctx.SetState(StateVerificationChecks, results)
 
 	// Store individual check results in state
 	for _, check := range results {
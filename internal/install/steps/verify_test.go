@@ -824,6 +824,7 @@ func TestVerificationStep_StateKeys(t *testing.T) {
 	assert.Equal(t, "module_loaded", StateModuleLoaded)
 	assert.Equal(t, "gpu_detected", StateGPUDetected)
 	assert.Equal(t, "verification_errors", StateVerificationErrors)
+	assert.Equal(t, "verification_checks", StateVerificationChecks)
 }
 
 // =============================================================================
@@ -950,6 +951,31 @@ func TestVerificationStep_StoresVerificationErrors(t *testing.T) {
 	assert.NotEmpty(t, errs)
 }
 
+func TestVerificationStep_StoresVerificationChecks(t *testing.T) {
+	ctx, mockExec := newVerificationTestContext()
+
+	mockExec.SetResponse("nvidia-smi", exec.FailureResult(1, "command failed"))
+
+	step := NewVerificationStep(
+		WithCheckModuleLoaded(false),
+		WithCheckGPUDetected(false),
+		WithCheckXorgConfig(true),
+	)
+
+	step.Execute(ctx)
+
+	checksRaw, ok := ctx.GetState(StateVerificationChecks)
+	require.True(t, ok)
+	checks, ok := checksRaw.([]VerificationCheck)
+	require.True(t, ok)
+	require.Len(t, checks, 2)
+	assert.Equal(t, "nvidia-smi", checks[0].Name)
+	assert.False(t, checks[0].Passed)
+	assert.True(t, checks[0].Critical)
+	assert.Equal(t, "xorg-config", checks[1].Name)
+	assert.False(t, checks[1].Critical)
+}
+
 func TestVerificationStep_StoresDriverVersion(t *testing.T) {
 	ctx, mockExec := newVerificationTestContext()
 	mockDetector := newMockVerificationKernelDetector()
@@ -6,6 +6,8 @@ package install
 import (
 	"fmt"
 	"time"
+
+	"github.com/tungetti/igor/internal/pkg"
 )
 
 // StepStatus represents the status of an installation step.
@@ -139,6 +141,20 @@ type StepProgress struct {
 
 	// Message is a human-readable progress message.
 	Message string
+
+	// StepPercent is the progress percentage of the current step (0-100),
+	// when the step reports one.
+	StepPercent float64
+
+	// PackagePhase is the phase of the package manager run by the step,
+	// or empty if the step reports no package progress.
+	PackagePhase pkg.ProgressPhase
+
+	// Package is the package being downloaded or installed, if known.
+	Package string
+
+	// PackagePercent is the progress percentage of the package phase (0-100).
+	PackagePercent float64
 }
 
 // NewStepProgress creates a new step progress instance.
@@ -156,6 +172,25 @@ func NewStepProgress(name string, index, total int, message string) StepProgress
 	}
 }
 
+// WithPackageProgress returns the step progress with the progress of the
+// package manager run by the step. Downloads make up the first half of the
+// step and the installation the second half, and the overall percentage
+// includes the progress of the step.
+func (p StepProgress) WithPackageProgress(progress pkg.Progress) StepProgress {
+	p.PackagePhase = progress.Phase
+	p.Package = progress.Package
+	p.PackagePercent = progress.Percent
+
+	p.StepPercent = progress.Percent / 2
+	if progress.Phase == pkg.ProgressInstall {
+		p.StepPercent += 50
+	}
+	if p.TotalSteps > 0 {
+		p.Percent = (float64(p.StepIndex) + p.StepPercent/100) / float64(p.TotalSteps) * 100
+	}
+	return p
+}
+
 // String returns a human-readable representation of the progress.
 func (p StepProgress) String() string {
 	return fmt.Sprintf("[%d/%d] %s: %s (%.1f%%)",
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/tungetti/igor/internal/pkg"
 )
 
 func TestStepStatus_String(t *testing.T) {
@@ -169,6 +170,23 @@ func TestStepProgress_String(t *testing.T) {
 	assert.Contains(t, str, "Installing")
 }
 
+func TestStepProgress_WithPackageProgress(t *testing.T) {
+	base := NewStepProgress("install", 1, 4, "Downloading nvidia-driver-550 (50%)")
+
+	download := base.WithPackageProgress(pkg.Progress{Phase: pkg.ProgressDownload, Package: "nvidia-driver-550", Percent: 50})
+	assert.Equal(t, pkg.ProgressDownload, download.PackagePhase)
+	assert.Equal(t, "nvidia-driver-550", download.Package)
+	assert.Equal(t, 50.0, download.PackagePercent)
+	assert.Equal(t, 25.0, download.StepPercent)
+	assert.Equal(t, 31.25, download.Percent) // (1 + 0.25) / 4 * 100
+
+	install := base.WithPackageProgress(pkg.Progress{Phase: pkg.ProgressInstall, Percent: 100})
+	assert.Equal(t, "", install.Package)
+	assert.Equal(t, 100.0, install.StepPercent)
+	assert.Equal(t, 50.0, install.Percent) // (1 + 1) / 4 * 100
+	assert.Equal(t, base.Message, install.Message)
+}
+
 func TestWorkflowStatus_String(t *testing.T) {
 	tests := []struct {
 		status   WorkflowStatus
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/tungetti/igor/internal/pkg"
 )
 
 // Workflow represents an installation workflow composed of multiple steps.
@@ -110,8 +112,16 @@ func (w *BaseWorkflow) Execute(ctx *Context) WorkflowResult {
 			return result
 		}
 
-		// Execute step
+		// Execute step, forwarding the progress it reports
+		if ctx != nil {
+			ctx.setStepProgress(func(message string, progress *pkg.Progress) {
+				w.reportStepProgress(step.Name(), i, len(steps), message, progress)
+			})
+		}
 		stepResult := step.Execute(ctx)
+		if ctx != nil {
+			ctx.setStepProgress(nil)
+		}
 
 		// Handle step result
 		switch stepResult.Status {
@@ -159,7 +169,17 @@ func (w *BaseWorkflow) Rollback(ctx *Context) error {
 	completed := append([]Step{}, w.completedSteps...)
 	w.mu.Unlock()
 
-	// Rollback in reverse order
+	return RollbackSteps(ctx, completed, func(p StepProgress) {
+		w.reportProgress(p.StepName, p.StepIndex, p.TotalSteps, p.Message)
+	})
+}
+
+// RollbackSteps rolls back the given completed steps in reverse order.
+// Steps that cannot be rolled back are ignored, and a failed rollback does
+// not stop the others. It lets a run be rolled back by a later process,
+// which rebuilds the steps and restores their state in the context.
+// progress may be nil.
+func RollbackSteps(ctx *Context, completed []Step, progress func(StepProgress)) error {
 	var rollbackErrors []error
 	for i := len(completed) - 1; i >= 0; i-- {
 		step := completed[i]
@@ -167,7 +187,9 @@ func (w *BaseWorkflow) Rollback(ctx *Context) error {
 			continue
 		}
 
-		w.reportProgress(step.Name(), i, len(completed), fmt.Sprintf("Rolling back: %s", step.Description()))
+		if progress != nil {
+			progress(NewStepProgress(step.Name(), i, len(completed), fmt.Sprintf("Rolling back: %s", step.Description())))
+		}
 
 		if err := step.Rollback(ctx); err != nil {
 			rollbackErrors = append(rollbackErrors, fmt.Errorf("rollback of '%s' failed: %w", step.Name(), err))
@@ -204,12 +226,22 @@ func (w *BaseWorkflow) isCancelled() bool {
 
 // reportProgress sends a progress update to the callback if set.
 func (w *BaseWorkflow) reportProgress(stepName string, index, total int, message string) {
+	w.reportStepProgress(stepName, index, total, message, nil)
+}
+
+// reportStepProgress sends a progress update reported by a running step to
+// the callback if set, with the package manager progress if any.
+func (w *BaseWorkflow) reportStepProgress(stepName string, index, total int, message string, progress *pkg.Progress) {
 	w.mu.RLock()
 	cb := w.progressCb
 	w.mu.RUnlock()
 
 	if cb != nil {
-		cb(NewStepProgress(stepName, index, total, message))
+		p := NewStepProgress(stepName, index, total, message)
+		if progress != nil {
+			p = p.WithPackageProgress(*progress)
+		}
+		cb(p)
 	}
 }
 
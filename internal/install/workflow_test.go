@@ -8,6 +8,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/pkg"
 )
 
 func TestNewWorkflow(t *testing.T) {
@@ -339,6 +340,64 @@ func TestBaseWorkflow_OnProgress(t *testing.T) {
 	assert.Contains(t, names, "step2")
 }
 
+func TestBaseWorkflow_Execute_StepReportsProgress(t *testing.T) {
+	w := NewWorkflow("test")
+	w.AddStep(NewMockStep("step1", false))
+	w.AddStep(NewFuncStep("step2", "Step 2", func(ctx *Context) StepResult {
+		ctx.ReportProgress("Waiting for the lock")
+		return CompleteStep("done")
+	}))
+
+	progressUpdates := make([]StepProgress, 0)
+	w.OnProgress(func(p StepProgress) {
+		progressUpdates = append(progressUpdates, p)
+	})
+
+	ctx := NewContext()
+	result := w.Execute(ctx)
+	require.Equal(t, WorkflowStatusCompleted, result.Status)
+
+	var reported []StepProgress
+	for _, p := range progressUpdates {
+		if p.Message == "Waiting for the lock" {
+			reported = append(reported, p)
+		}
+	}
+	require.Len(t, reported, 1)
+	assert.Equal(t, "step2", reported[0].StepName)
+	assert.Equal(t, 1, reported[0].StepIndex)
+	assert.Equal(t, 2, reported[0].TotalSteps)
+
+	// Progress reported outside of a step is dropped
+	ctx.ReportProgress("after the workflow")
+	assert.Equal(t, "Workflow completed successfully", progressUpdates[len(progressUpdates)-1].Message)
+}
+
+func TestBaseWorkflow_Execute_StepReportsPackageProgress(t *testing.T) {
+	w := NewWorkflow("test")
+	w.AddStep(NewFuncStep("packages", "Install packages", func(ctx *Context) StepResult {
+		ctx.ReportPackageProgress(pkg.Progress{Phase: pkg.ProgressInstall, Package: "nvidia-driver-550", Percent: 50})
+		return CompleteStep("done")
+	}))
+
+	var reported []StepProgress
+	w.OnProgress(func(p StepProgress) {
+		if p.Package != "" {
+			reported = append(reported, p)
+		}
+	})
+
+	result := w.Execute(NewContext())
+	require.Equal(t, WorkflowStatusCompleted, result.Status)
+
+	require.Len(t, reported, 1)
+	assert.Equal(t, "packages", reported[0].StepName)
+	assert.Equal(t, "Installing nvidia-driver-550 (50%)", reported[0].Message)
+	assert.Equal(t, pkg.ProgressInstall, reported[0].PackagePhase)
+	assert.Equal(t, 75.0, reported[0].StepPercent)
+	assert.Equal(t, 75.0, reported[0].Percent)
+}
+
 func TestBaseWorkflow_Reset(t *testing.T) {
 	w := NewWorkflow("test")
 
@@ -487,3 +546,37 @@ func TestBaseWorkflow_Execute_RollbackOnFailure(t *testing.T) {
 	// Verify rollback order (reverse of completion order)
 	assert.Equal(t, []string{"install", "setup"}, rollbackOrder)
 }
+
+func TestRollbackSteps(t *testing.T) {
+	rollbackOrder := make([]string, 0)
+	newStep := func(name string, err error) Step {
+		return NewFuncStep(name, "Step "+name, func(ctx *Context) StepResult {
+			return CompleteStep("done")
+		}, WithRollbackFunc(func(ctx *Context) error {
+			rollbackOrder = append(rollbackOrder, name)
+			return err
+		}))
+	}
+	noRollback := NewFuncStep("plain", "Plain step", func(ctx *Context) StepResult {
+		return CompleteStep("done")
+	})
+
+	progress := make([]string, 0)
+	err := RollbackSteps(NewContext(), []Step{
+		newStep("step1", nil),
+		noRollback,
+		newStep("step2", errors.New("rollback failed")),
+		newStep("step3", nil),
+	}, func(p StepProgress) {
+		progress = append(progress, p.Message)
+	})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "rollback completed with 1 errors")
+	assert.Contains(t, err.Error(), "step2")
+	// A failed rollback does not stop the others
+	assert.Equal(t, []string{"step3", "step2", "step1"}, rollbackOrder)
+	assert.Equal(t, []string{"Rolling back: Step step3", "Rolling back: Step step2", "Rolling back: Step step1"}, progress)
+
+	assert.NoError(t, RollbackSteps(NewContext(), nil, nil))
+}
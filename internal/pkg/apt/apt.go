@@ -6,6 +6,7 @@ import (
 	"context"
 	"fmt"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"github.com/tungetti/igor/internal/constants"
@@ -26,6 +27,8 @@ var nonInteractiveEnv = []string{
 type Manager struct {
 	executor  igorexec.Executor
 	privilege *privilege.Manager
+	locks     *pkg.LockChecker
+	tx        pkg.Transaction
 }
 
 // NewManager creates a new APT package manager.
@@ -35,6 +38,7 @@ func NewManager(executor igorexec.Executor, priv *privilege.Manager) *Manager {
 	return &Manager{
 		executor:  executor,
 		privilege: priv,
+		locks:     pkg.NewLockChecker(executor, "dpkg", aptLockFiles...),
 	}
 }
 
@@ -56,13 +60,20 @@ func (m *Manager) IsAvailable() bool {
 
 // Install installs one or more packages using apt-get install.
 // Uses DEBIAN_FRONTEND=noninteractive for unattended operation.
+// If opts.Progress is set, the download and installation progress is read
+// from the status lines of apt-get as it runs.
 func (m *Manager) Install(ctx context.Context, opts pkg.InstallOptions, packages ...string) error {
 	if len(packages) == 0 {
 		return nil
 	}
 
 	args := m.buildInstallArgs(opts, packages)
-	result := m.executeElevatedWithEnv(ctx, "apt-get", args...)
+	var result *igorexec.Result
+	if opts.Progress != nil {
+		result = m.executeElevatedWithProgress(ctx, opts.Progress, args...)
+	} else {
+		result = m.executeElevatedWithEnv(ctx, "apt-get", args...)
+	}
 
 	if result.Failed() {
 		// Check for common error patterns
@@ -104,11 +115,26 @@ func (m *Manager) buildInstallArgs(opts pkg.InstallOptions, packages []string) [
 	if opts.SkipVerify {
 		args = append(args, "--allow-unauthenticated")
 	}
+	if opts.Repository != "" {
+		args = append(args, repositoryArgs(opts.Repository)...)
+	}
 
 	args = append(args, packages...)
 	return args
 }
 
+// repositoryArgs returns the apt-get options limiting the sources to the
+// .list file of the named repository. List-Cleanup is turned off so the
+// package lists of the other repositories are kept.
+func repositoryArgs(name string) []string {
+	sourceList := filepath.Join(sourcesListDir, sanitizeFilename(name)+".list")
+	return []string{
+		"-o", "Dir::Etc::sourcelist=" + sourceList,
+		"-o", "Dir::Etc::sourceparts=-",
+		"-o", "APT::Get::List-Cleanup=0",
+	}
+}
+
 // Remove removes one or more packages from the system.
 // If opts.Purge is true, also removes configuration files.
 func (m *Manager) Remove(ctx context.Context, opts pkg.RemoveOptions, packages ...string) error {
@@ -152,12 +178,16 @@ func (m *Manager) Remove(ctx context.Context, opts pkg.RemoveOptions, packages .
 }
 
 // Update updates the package database using apt-get update.
+// If opts.Repository is set, only that repository is refreshed.
 func (m *Manager) Update(ctx context.Context, opts pkg.UpdateOptions) error {
 	args := []string{"update"}
 
 	if opts.Quiet {
 		args = append(args, "-qq")
 	}
+	if opts.Repository != "" {
+		args = append(args, repositoryArgs(opts.Repository)...)
+	}
 
 	result := m.executeElevatedWithEnv(ctx, "apt-get", args...)
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -653,6 +654,15 @@ deb [arch=amd64 signed-by=/etc/apt/keyrings/nvidia.gpg] https://developer.downlo
 	assert.False(t, repos[3].Enabled)
 }
 
+func TestParseSourcesList_DisabledByIgor(t *testing.T) {
+	repos, err := parseSourcesList("# igor-disabled: deb https://developer.download.nvidia.com/compute/cuda/repos/ubuntu2204/x86_64/ /")
+	require.NoError(t, err)
+	require.Len(t, repos, 1)
+
+	assert.Equal(t, "https://developer.download.nvidia.com/compute/cuda/repos/ubuntu2204/x86_64/", repos[0].URL)
+	assert.False(t, repos[0].Enabled)
+}
+
 func TestParseAptListUpgradable(t *testing.T) {
 	output := `Listing...
 nginx/jammy-updates 1.22.0-1ubuntu1.1 amd64 [upgradable from: 1.22.0-1ubuntu1]
@@ -846,6 +856,30 @@ func TestManager_AddRepository_DirectExists(t *testing.T) {
 	assert.ErrorIs(t, err, pkg.ErrRepositoryExists)
 }
 
+func TestManager_AddRepository_DirectKeyURL(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetResponse("test", exec.FailureResult(1, ""))
+	mockExec.SetDefaultResponse(exec.SuccessResult(""))
+
+	repo := pkg.Repository{
+		Name:         "nvidia-cuda",
+		URL:          "https://developer.download.nvidia.com/compute/cuda/repos/ubuntu2204/x86_64",
+		Distribution: "/",
+		GPGKey:       "https://developer.download.nvidia.com/compute/cuda/repos/ubuntu2204/x86_64/3bf863cc.pub",
+	}
+
+	err := mgr.AddRepository(context.Background(), repo)
+	require.NoError(t, err)
+
+	// The key is downloaded to the keyring of the repository, which the
+	// entry references instead of the URL
+	assert.True(t, mockExec.WasCalled("sh"))
+	lastCall := mockExec.LastCall()
+	assert.Contains(t, string(lastCall.Input), "[signed-by=/etc/apt/keyrings/nvidia-cuda.gpg]")
+	assert.NotContains(t, string(lastCall.Input), "3bf863cc.pub")
+}
+
 func TestManager_RemoveRepository_DirectRepo(t *testing.T) {
 	mgr, mockExec := setupTest()
 
@@ -947,6 +981,364 @@ func TestManager_ListRepositories_WithSourcesListD(t *testing.T) {
 	assert.GreaterOrEqual(t, len(repos), 1)
 }
 
+func TestManager_ListRepositories_NamedAfterFile(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetResponse("cat", exec.SuccessResult("deb https://developer.download.nvidia.com/compute/cuda/repos/ubuntu2204/x86_64/ /"))
+	mockExec.SetResponse("ls", exec.SuccessResult("cuda-ubuntu2204-x86_64.list\nREADME"))
+
+	repos, err := mgr.ListRepositories(context.Background())
+	require.NoError(t, err)
+	require.Len(t, repos, 2)
+	assert.Equal(t, "cuda-ubuntu2204-x86_64", repos[1].Name)
+	assert.True(t, repos[1].Enabled)
+}
+
+func TestManager_DisableRepository_OwnFile(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetResponse("test", exec.SuccessResult(""))
+	mockExec.SetResponse("cat", exec.SuccessResult(
+		"deb https://developer.download.nvidia.com/compute/cuda/repos/ubuntu2204/x86_64/ /\n"+
+			"deb-src https://developer.download.nvidia.com/compute/cuda/repos/ubuntu2204/x86_64/ /"))
+	mockExec.SetDefaultResponse(exec.SuccessResult(""))
+
+	err := mgr.DisableRepository(context.Background(), "cuda-ubuntu2204-x86_64")
+	require.NoError(t, err)
+
+	call := mockExec.LastCall()
+	assert.Equal(t, []string{"tee", "/etc/apt/sources.list.d/cuda-ubuntu2204-x86_64.list"}, call.Args)
+	assert.Equal(t,
+		"# igor-disabled: deb https://developer.download.nvidia.com/compute/cuda/repos/ubuntu2204/x86_64/ /\n"+
+			"# igor-disabled: deb-src https://developer.download.nvidia.com/compute/cuda/repos/ubuntu2204/x86_64/ /",
+		string(call.Input))
+}
+
+func TestManager_EnableRepository_OwnFile(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	// The deb-src entry was commented out by the user, not by igor
+	mockExec.SetResponse("test", exec.SuccessResult(""))
+	mockExec.SetResponse("cat", exec.SuccessResult(
+		"# igor-disabled: deb https://developer.download.nvidia.com/compute/cuda/repos/ubuntu2204/x86_64/ /\n"+
+			"# deb-src https://developer.download.nvidia.com/compute/cuda/repos/ubuntu2204/x86_64/ /"))
+	mockExec.SetDefaultResponse(exec.SuccessResult(""))
+
+	err := mgr.EnableRepository(context.Background(), "cuda-ubuntu2204-x86_64")
+	require.NoError(t, err)
+
+	call := mockExec.LastCall()
+	assert.Equal(t, []string{"tee", "/etc/apt/sources.list.d/cuda-ubuntu2204-x86_64.list"}, call.Args)
+	assert.Equal(t,
+		"deb https://developer.download.nvidia.com/compute/cuda/repos/ubuntu2204/x86_64/ /\n"+
+			"# deb-src https://developer.download.nvidia.com/compute/cuda/repos/ubuntu2204/x86_64/ /",
+		string(call.Input))
+}
+
+func TestManager_EnableRepository_OwnFileDisabledByUser(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetResponse("test", exec.SuccessResult(""))
+	mockExec.SetResponse("cat", exec.SuccessResult(
+		"# deb https://developer.download.nvidia.com/compute/cuda/repos/ubuntu2204/x86_64/ /"))
+
+	err := mgr.EnableRepository(context.Background(), "cuda-ubuntu2204-x86_64")
+	assert.ErrorIs(t, err, pkg.ErrRepositoryNotFound)
+	assert.False(t, mockExec.WasCalled("sudo"))
+}
+
+const testUbuntuSources = `# Ubuntu sources have moved to this file.
+Types: deb
+URIs: http://archive.ubuntu.com/ubuntu/
+Suites: noble noble-updates
+Components: main restricted universe
+Signed-By: /usr/share/keyrings/ubuntu-archive-keyring.gpg
+
+Types: deb
+URIs: http://security.ubuntu.com/ubuntu/
+Suites: noble-security
+Components: main restricted universe
+Enabled: no
+Signed-By: /usr/share/keyrings/ubuntu-archive-keyring.gpg
+`
+
+func TestParseDeb822Sources(t *testing.T) {
+	repos := parseDeb822Sources(testUbuntuSources)
+	require.Len(t, repos, 3)
+
+	assert.Equal(t, "http://archive.ubuntu.com/ubuntu/", repos[0].URL)
+	assert.Equal(t, "deb", repos[0].Type)
+	assert.Equal(t, "noble", repos[0].Distribution)
+	assert.Equal(t, []string{"main", "restricted", "universe"}, repos[0].Components)
+	assert.Equal(t, "/usr/share/keyrings/ubuntu-archive-keyring.gpg", repos[0].GPGKey)
+	assert.True(t, repos[0].Enabled)
+
+	assert.Equal(t, "noble-updates", repos[1].Distribution)
+
+	assert.Equal(t, "noble-security", repos[2].Distribution)
+	assert.False(t, repos[2].Enabled)
+}
+
+func TestParseDeb822Sources_TypesAndInlineKey(t *testing.T) {
+	content := `types: deb deb-src
+uris: https://example.com/a https://example.com/b
+suites: stable
+trusted: yes
+signed-by:
+ -----BEGIN PGP PUBLIC KEY BLOCK-----
+ .
+ mQINBGTest
+ -----END PGP PUBLIC KEY BLOCK-----
+`
+	repos := parseDeb822Sources(content)
+	require.Len(t, repos, 4)
+	assert.Equal(t, "deb", repos[0].Type)
+	assert.Equal(t, "https://example.com/b", repos[1].URL)
+	assert.Equal(t, "deb-src", repos[2].Type)
+	for _, repo := range repos {
+		assert.Empty(t, repo.GPGKey)
+		assert.True(t, repo.Trusted)
+		assert.Empty(t, repo.Components)
+	}
+}
+
+func TestParseDeb822Sources_Empty(t *testing.T) {
+	assert.Empty(t, parseDeb822Sources(""))
+	assert.Empty(t, parseDeb822Sources("# only a comment\n"))
+}
+
+func TestBuildDeb822Entry(t *testing.T) {
+	entry := buildDeb822Entry(pkg.Repository{
+		Name:         "nvidia-cuda",
+		URL:          "https://developer.download.nvidia.com/compute/cuda/repos/ubuntu2404/x86_64",
+		Distribution: "/",
+		Components:   []string{"main"},
+		GPGKey:       "/etc/apt/keyrings/nvidia-cuda.gpg",
+	})
+	assert.Equal(t, "Types: deb\n"+
+		"URIs: https://developer.download.nvidia.com/compute/cuda/repos/ubuntu2404/x86_64\n"+
+		"Suites: /\n"+
+		"Signed-By: /etc/apt/keyrings/nvidia-cuda.gpg\n", entry)
+
+	entry = buildDeb822Entry(pkg.Repository{
+		URL:          "file:///opt/bundle",
+		Distribution: "stable",
+		Components:   []string{"main", "contrib"},
+		Trusted:      true,
+	})
+	assert.Contains(t, entry, "Suites: stable\nComponents: main contrib\n")
+	assert.Contains(t, entry, "Trusted: yes\n")
+	assert.NotContains(t, entry, "Signed-By")
+
+	// The entry reads back as the repository
+	repos := parseDeb822Sources(entry)
+	require.Len(t, repos, 1)
+	assert.Equal(t, "file:///opt/bundle", repos[0].URL)
+	assert.True(t, repos[0].Enabled)
+}
+
+func TestSetDeb822Enabled(t *testing.T) {
+	// Disabling adds the field to the enabled stanza and sets the other one
+	content, modified := setDeb822Enabled(testUbuntuSources, false)
+	require.True(t, modified)
+	for _, repo := range parseDeb822Sources(content) {
+		assert.False(t, repo.Enabled)
+	}
+	assert.True(t, strings.HasPrefix(content, "# Ubuntu sources have moved to this file.\nEnabled: no\nTypes: deb\n"))
+	assert.Equal(t, 2, strings.Count(content, "Enabled: no"))
+
+	// Disabling again changes nothing
+	_, modified = setDeb822Enabled(content, false)
+	assert.False(t, modified)
+
+	// Enabling sets the field in every stanza
+	content, modified = setDeb822Enabled(content, true)
+	require.True(t, modified)
+	for _, repo := range parseDeb822Sources(content) {
+		assert.True(t, repo.Enabled)
+	}
+	assert.NotContains(t, content, "Enabled: no")
+}
+
+func TestManager_ListRepositories_Deb822(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetResponse("cat", exec.SuccessResult(testUbuntuSources))
+	mockExec.SetResponse("ls", exec.SuccessResult("ubuntu.sources\nREADME"))
+
+	repos, err := mgr.ListRepositories(context.Background())
+	require.NoError(t, err)
+	require.Len(t, repos, 3)
+	assert.Equal(t, "ubuntu", repos[0].Name)
+	assert.False(t, repos[2].Enabled)
+}
+
+func TestManager_AddRepository_Deb822(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetResponse("test", exec.FailureResult(1, ""))
+	// The system sources are in ubuntu.sources; sources.list has only a comment
+	mockExec.SetResponse("cat", exec.SuccessResult("# Ubuntu sources have moved to /etc/apt/sources.list.d/ubuntu.sources\n"))
+	mockExec.SetResponse("ls", exec.SuccessResult("ubuntu.sources\n"))
+	mockExec.SetDefaultResponse(exec.SuccessResult(""))
+
+	err := mgr.AddRepository(context.Background(), pkg.Repository{
+		Name:         "nvidia-cuda",
+		URL:          "https://developer.download.nvidia.com/compute/cuda/repos/ubuntu2404/x86_64",
+		Distribution: "/",
+		GPGKey:       "/etc/apt/keyrings/nvidia-cuda.gpg",
+	})
+	require.NoError(t, err)
+
+	call := mockExec.LastCall()
+	assert.Equal(t, []string{"tee", "/etc/apt/sources.list.d/nvidia-cuda.sources"}, call.Args)
+	assert.Contains(t, string(call.Input), "Signed-By: /etc/apt/keyrings/nvidia-cuda.gpg\n")
+}
+
+func TestManager_AddRepository_ListWhenSourcesListUsed(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	// A system upgraded from an older release keeps its sources.list
+	mockExec.SetResponse("test", exec.FailureResult(1, ""))
+	mockExec.SetResponse("cat", exec.SuccessResult("deb http://deb.debian.org/debian bookworm main\n"))
+	mockExec.SetResponse("ls", exec.SuccessResult("other.sources\n"))
+	mockExec.SetDefaultResponse(exec.SuccessResult(""))
+
+	err := mgr.AddRepository(context.Background(), pkg.Repository{
+		Name:         "nvidia-cuda",
+		URL:          "https://developer.download.nvidia.com/compute/cuda/repos/debian12/x86_64",
+		Distribution: "/",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tee", "/etc/apt/sources.list.d/nvidia-cuda.list"}, mockExec.LastCall().Args)
+}
+
+func TestManager_DisableRepository_Deb822(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetResponse("cat", exec.SuccessResult("Types: deb\nURIs: https://example.com/cuda\nSuites: /\n"))
+	mockExec.SetDefaultResponse(exec.SuccessResult(""))
+
+	// Only the .sources file exists
+	mgr.executor = &sourcesFileExecutor{MockExecutor: mockExec, path: "/etc/apt/sources.list.d/nvidia-cuda.sources"}
+
+	err := mgr.DisableRepository(context.Background(), "nvidia-cuda")
+	require.NoError(t, err)
+
+	call := mockExec.LastCall()
+	assert.Equal(t, []string{"tee", "/etc/apt/sources.list.d/nvidia-cuda.sources"}, call.Args)
+	assert.Equal(t, "Enabled: no\nTypes: deb\nURIs: https://example.com/cuda\nSuites: /\n", string(call.Input))
+
+	// An already disabled repository is not found
+	mockExec.SetResponse("cat", exec.SuccessResult("Enabled: no\nTypes: deb\n"))
+	err = mgr.DisableRepository(context.Background(), "nvidia-cuda")
+	assert.ErrorIs(t, err, pkg.ErrRepositoryNotFound)
+}
+
+func TestManager_RemoveRepository_Deb822(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetDefaultResponse(exec.SuccessResult(""))
+	mgr.executor = &sourcesFileExecutor{MockExecutor: mockExec, path: "/etc/apt/sources.list.d/nvidia-cuda.sources"}
+
+	err := mgr.RemoveRepository(context.Background(), "nvidia-cuda")
+	require.NoError(t, err)
+	assert.True(t, mockExec.WasCalledWith("rm", "-f", "/etc/apt/sources.list.d/nvidia-cuda.sources"))
+}
+
+// sourcesFileExecutor is a MockExecutor on which "test -f" succeeds for a
+// single path only.
+type sourcesFileExecutor struct {
+	*exec.MockExecutor
+	path string
+}
+
+func (e *sourcesFileExecutor) Execute(ctx context.Context, cmd string, args ...string) *exec.Result {
+	if cmd == "test" {
+		e.MockExecutor.Execute(ctx, cmd, args...)
+		if args[len(args)-1] == e.path {
+			return exec.SuccessResult("")
+		}
+		return exec.FailureResult(1, "")
+	}
+	return e.MockExecutor.Execute(ctx, cmd, args...)
+}
+
+func TestManager_PreferRepository(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetDefaultResponse(exec.SuccessResult(""))
+
+	repo := pkg.Repository{
+		Name: "nvidia-cuda",
+		URL:  "https://developer.download.nvidia.com/compute/cuda/repos/ubuntu2404/x86_64",
+	}
+	err := mgr.PreferRepository(context.Background(), repo, []string{"nvidia-*", "cuda-*"})
+	require.NoError(t, err)
+
+	call := mockExec.LastCall()
+	assert.Equal(t, []string{"tee", "/etc/apt/preferences.d/igor-nvidia-cuda"}, call.Args)
+	assert.Equal(t, "# Written by igor: prefer nvidia-cuda for the NVIDIA packages\n"+
+		"Package: nvidia-* cuda-*\n"+
+		"Pin: origin \"developer.download.nvidia.com\"\n"+
+		"Pin-Priority: 600\n", string(call.Input))
+}
+
+func TestManager_PreferRepository_PPA(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetDefaultResponse(exec.SuccessResult(""))
+
+	err := mgr.PreferRepository(context.Background(),
+		pkg.Repository{Name: "graphics-drivers-ppa", URL: "ppa:graphics-drivers/ppa"}, []string{"nvidia-*"})
+	require.NoError(t, err)
+	assert.Contains(t, string(mockExec.LastCall().Input), "Pin: release o=LP-PPA-graphics-drivers\n")
+}
+
+func TestRepositoryPin(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{url: "ppa:graphics-drivers/ppa", want: "release o=LP-PPA-graphics-drivers"},
+		{url: "ppa:kisak/kisak-mesa", want: "release o=LP-PPA-kisak-kisak-mesa"},
+		{url: "https://developer.download.nvidia.com/compute/cuda/repos/debian12/x86_64", want: `origin "developer.download.nvidia.com"`},
+		{url: "ppa:", wantErr: true},
+		{url: "/srv/repo", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			pin, err := repositoryPin(pkg.Repository{Name: "test", URL: tt.url})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, pin)
+		})
+	}
+}
+
+func TestManager_PreferRepository_NoPackages(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	err := mgr.PreferRepository(context.Background(), pkg.Repository{Name: "nvidia-cuda", URL: "https://example.com"}, nil)
+	require.Error(t, err)
+	assert.Equal(t, 0, mockExec.CallCount())
+}
+
+func TestManager_RemoveRepositoryPreference(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetDefaultResponse(exec.SuccessResult(""))
+
+	err := mgr.RemoveRepositoryPreference(context.Background(), "nvidia-cuda")
+	require.NoError(t, err)
+	assert.True(t, mockExec.WasCalledWith("rm", "-f", "/etc/apt/preferences.d/igor-nvidia-cuda"))
+}
+
 func TestManager_GetGPGKeyPath(t *testing.T) {
 	mgr, _ := setupTest()
 
@@ -1142,29 +1534,107 @@ func TestManager_RemoveRepository_PPANotFound(t *testing.T) {
 	assert.ErrorIs(t, err, pkg.ErrRepositoryNotFound)
 }
 
-func TestManager_AddGPGKey_LegacyFallback(t *testing.T) {
+const (
+	testCUDAFingerprint        = "EB693B3035CD5710E231E123A4B469963BF863CC"
+	testRetiredCUDAFingerprint = "AE09FE4BBD223A84B2CCFCE3F60F4B3D7FA2AF80"
+)
+
+func testCUDAKeyPin() pkg.KeyPin {
+	return pkg.KeyPin{
+		Name:         "nvidia-cuda",
+		Fingerprints: []string{testCUDAFingerprint},
+		Retired: []pkg.RetiredKey{
+			{Fingerprint: testRetiredCUDAFingerprint, ReplacedBy: testCUDAFingerprint, Date: "2022-04-27"},
+		},
+	}
+}
+
+func testKeyColons(fingerprints ...string) string {
+	var sb strings.Builder
+	for _, fpr := range fingerprints {
+		sb.WriteString("pub:-:4096:1:" + fpr[24:] + ":1650000000:::-:::scSC::::::23::0:\n")
+		sb.WriteString("fpr:::::::::" + fpr + ":\n")
+	}
+	return sb.String()
+}
+
+func TestManager_ImportPinnedGPGKey(t *testing.T) {
 	mgr, mockExec := setupTest()
 
-	// mkdir fails (fall back to legacy)
-	mockExec.SetResponse("mkdir", exec.FailureResult(1, "Permission denied"))
-	// Legacy succeeds
-	mockExec.SetResponse("sh", exec.SuccessResult(""))
+	mockExec.SetDefaultResponse(exec.SuccessResult(""))
+	mockExec.SetResponse("mktemp", exec.SuccessResult("/tmp/igor-key.abc\n"))
+	mockExec.SetResponse("gpg", exec.SuccessResult(testKeyColons(testCUDAFingerprint)))
+	mockExec.SetResponse("sh", exec.FailureResult(2, ""))
 
-	err := mgr.AddGPGKey(context.Background(), "nvidia", "https://nvidia.com/key.gpg")
+	keyURL := "https://developer.download.nvidia.com/compute/cuda/repos/ubuntu2204/x86_64/3bf863cc.pub"
+	imp, err := mgr.ImportPinnedGPGKey(context.Background(), keyURL, testCUDAKeyPin())
 	require.NoError(t, err)
+
+	assert.Equal(t, "/etc/apt/keyrings/nvidia-cuda.gpg", imp.Keyring)
+	assert.Equal(t, []string{testCUDAFingerprint}, imp.Fingerprints)
+	assert.Empty(t, imp.Retired)
+
+	assert.True(t, mockExec.WasCalledWith("curl", "-fsSL", "-o", "/tmp/igor-key.abc", keyURL))
+	assert.True(t, mockExec.WasCalledWith("gpg", "--batch", "--yes", "--dearmor", "--output", "/etc/apt/keyrings/nvidia-cuda.gpg", "/tmp/igor-key.abc"))
+	assert.True(t, mockExec.WasCalledWith("rm", "-f", "/tmp/igor-key.abc"))
+	for _, call := range mockExec.Calls() {
+		assert.NotContains(t, strings.Join(call.Args, " "), "apt-key")
+	}
 }
 
-func TestManager_AddGPGKey_LegacyFailure(t *testing.T) {
+func TestManager_ImportPinnedGPGKey_Mismatch(t *testing.T) {
 	mgr, mockExec := setupTest()
 
-	// mkdir fails (fall back to legacy)
+	mockExec.SetDefaultResponse(exec.SuccessResult(""))
+	mockExec.SetResponse("mktemp", exec.SuccessResult("/tmp/igor-key.abc\n"))
+	mockExec.SetResponse("gpg", exec.SuccessResult(testKeyColons("0123456789ABCDEF0123456789ABCDEF01234567")))
+
+	_, err := mgr.ImportPinnedGPGKey(context.Background(), "https://example.com/key.pub", testCUDAKeyPin())
+	require.Error(t, err)
+	assert.ErrorIs(t, err, pkg.ErrGPGKeyMismatch)
+
+	// Nothing is written to the keyrings
+	assert.False(t, mockExec.WasCalled("mkdir"))
+	assert.True(t, mockExec.WasCalledWith("rm", "-f", "/tmp/igor-key.abc"))
+}
+
+func TestManager_ImportPinnedGPGKey_ReportsRetiredKey(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetDefaultResponse(exec.SuccessResult(""))
+	mockExec.SetResponse("mktemp", exec.SuccessResult("/tmp/igor-key.abc\n"))
+	mockExec.SetResponse("gpg", exec.SuccessResult(testKeyColons(testCUDAFingerprint)))
+	// The retired key is still in the global trust store
+	mockExec.SetResponse("sh", exec.SuccessResult(testKeyColons(testRetiredCUDAFingerprint, testCUDAFingerprint)))
+
+	imp, err := mgr.ImportPinnedGPGKey(context.Background(), "https://example.com/key.pub", testCUDAKeyPin())
+	require.NoError(t, err)
+	require.Len(t, imp.Retired, 1)
+	assert.Equal(t, testRetiredCUDAFingerprint, imp.Retired[0].Fingerprint)
+}
+
+func TestManager_ImportPinnedGPGKey_InvalidName(t *testing.T) {
+	mgr, _ := setupTest()
+
+	pin := testCUDAKeyPin()
+	pin.Name = "nvidia; rm -rf /"
+	_, err := mgr.ImportPinnedGPGKey(context.Background(), "https://example.com/key.pub", pin)
+	require.Error(t, err)
+}
+
+func TestManager_AddGPGKey_KeyringsDirFailure(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	// mkdir fails; the key must not go to the global trust store instead
 	mockExec.SetResponse("mkdir", exec.FailureResult(1, "Permission denied"))
-	// Legacy also fails
-	mockExec.SetResponse("sh", exec.FailureResult(1, "Connection refused"))
+	mockExec.SetResponse("sh", exec.SuccessResult(""))
 
 	err := mgr.AddGPGKey(context.Background(), "nvidia", "https://nvidia.com/key.gpg")
 	require.Error(t, err)
-	assert.ErrorIs(t, err, pkg.ErrNetworkUnavailable)
+	assert.Contains(t, err.Error(), "/etc/apt/keyrings")
+	for _, call := range mockExec.Calls() {
+		assert.NotContains(t, strings.Join(call.Args, " "), "apt-key")
+	}
 }
 
 func TestManager_Install_DownloadOnly(t *testing.T) {
@@ -1358,3 +1828,564 @@ func TestManager_Install_DpkgInterrupted(t *testing.T) {
 	require.Error(t, err)
 	assert.ErrorIs(t, err, pkg.ErrLockAcquireFailed)
 }
+
+// =============================================================================
+// Hold Tests
+// =============================================================================
+
+func TestManager_Hold(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	err := mgr.Hold(context.Background(), "nvidia-driver-550", "nvidia-dkms-550")
+	require.NoError(t, err)
+
+	assert.True(t, mockExec.WasCalledWith("apt-mark", "hold", "nvidia-driver-550", "nvidia-dkms-550"))
+	assert.True(t, mockExec.LastCall().Elevated)
+}
+
+func TestManager_Hold_Failure(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("apt-mark", exec.FailureResult(100, "E: Unable to locate package foo"))
+
+	err := mgr.Hold(context.Background(), "foo")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, pkg.ErrHoldFailed)
+	assert.Contains(t, err.Error(), "Unable to locate package")
+}
+
+func TestManager_Hold_EmptyPackages(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	require.NoError(t, mgr.Hold(context.Background()))
+	require.NoError(t, mgr.Unhold(context.Background()))
+	assert.Equal(t, 0, mockExec.CallCount())
+}
+
+func TestManager_Unhold(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	err := mgr.Unhold(context.Background(), "nvidia-driver-550")
+	require.NoError(t, err)
+
+	assert.True(t, mockExec.WasCalledWith("apt-mark", "unhold", "nvidia-driver-550"))
+	assert.True(t, mockExec.LastCall().Elevated)
+}
+
+func TestManager_ListHeld(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("apt-mark", exec.SuccessResult("nvidia-driver-550\nlibnvidia-gl-550:i386\n\n"))
+
+	held, err := mgr.ListHeld(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nvidia-driver-550", "libnvidia-gl-550:i386"}, held)
+	assert.True(t, mockExec.WasCalledWith("apt-mark", "showhold"))
+	assert.False(t, mockExec.LastCall().Elevated)
+}
+
+func TestManager_ListHeld_Empty(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("apt-mark", exec.SuccessResult(""))
+
+	held, err := mgr.ListHeld(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, held)
+}
+
+// =============================================================================
+// Lock Tests
+// =============================================================================
+
+func TestManager_LockHolder(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("find", exec.SuccessResult("/proc/812/fd/5 /var/lib/dpkg/lock-frontend\n"))
+	mockExec.SetResponse("cat", exec.SuccessResult("unattended-upgr\n"))
+
+	holder, err := mgr.LockHolder(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, holder)
+	assert.Equal(t, 812, holder.PID)
+	assert.Equal(t, "unattended-upgr", holder.Command)
+	assert.Equal(t, "/var/lib/dpkg/lock-frontend", holder.Path)
+	assert.True(t, mockExec.WasCalledWith("cat", "/proc/812/comm"))
+
+	err = mgr.AcquireLock(context.Background())
+	require.Error(t, err)
+	assert.ErrorIs(t, err, pkg.ErrLockAcquireFailed)
+	assert.Contains(t, err.Error(), "unattended-upgr (pid 812)")
+}
+
+func TestManager_IsLocked_Free(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("find", exec.SuccessResult(""))
+
+	locked, err := mgr.IsLocked(context.Background())
+	require.NoError(t, err)
+	assert.False(t, locked)
+
+	calls := mockExec.Calls()
+	require.Len(t, calls, 1)
+	for _, path := range []string{"/var/lib/dpkg/lock-frontend", "/var/lib/dpkg/lock", "/var/lib/apt/lists/lock", "/var/cache/apt/archives/lock"} {
+		assert.Contains(t, calls[0].Args, path)
+	}
+	assert.NoError(t, mgr.WaitForLock(context.Background()))
+	assert.NoError(t, mgr.ReleaseLock(context.Background()))
+}
+
+// =============================================================================
+// History Tests
+// =============================================================================
+
+const testAptHistory = `
+Start-Date: 2024-01-10  09:00:12
+Commandline: apt-get upgrade -y
+Upgrade: nvidia-driver-545:amd64 (545.29.06-0ubuntu1, 545.29.06-0ubuntu2), libnvidia-gl-545:i386 (545.29.06-0ubuntu1, 545.29.06-0ubuntu2)
+End-Date: 2024-01-10  09:03:40
+
+Start-Date: 2024-01-15  10:23:45
+Commandline: apt-get install -y nvidia-driver-550
+Requested-By: admin (1000)
+Install: nvidia-driver-550:amd64 (550.54.14-0ubuntu1), libnvidia-gl-550:amd64 (550.54.14-0ubuntu1, automatic)
+Remove: nvidia-driver-545:amd64 (545.29.06-0ubuntu2)
+Error: Sub-process /usr/bin/dpkg returned an error code (1)
+End-Date: 2024-01-15  10:24:10
+`
+
+func TestParseAptHistory(t *testing.T) {
+	entries := parseAptHistory(testAptHistory)
+	require.Len(t, entries, 2)
+
+	assert.True(t, entries[0].Success)
+	assert.Equal(t, "apt-get upgrade -y", entries[0].Details)
+	assert.Equal(t, "upgrade", entries[0].Operation)
+	assert.Equal(t, []string{"nvidia-driver-545", "libnvidia-gl-545"}, entries[0].Packages)
+	assert.Equal(t, time.Date(2024, 1, 10, 9, 0, 12, 0, time.Local).Unix(), entries[0].Timestamp)
+
+	assert.False(t, entries[1].Success)
+	assert.Equal(t, "install, remove", entries[1].Operation)
+	assert.Equal(t, []string{"nvidia-driver-550", "libnvidia-gl-550", "nvidia-driver-545"}, entries[1].Packages)
+}
+
+func TestParseAptHistory_Truncated(t *testing.T) {
+	// A log starting in the middle of an entry, as after a manual edit
+	entries := parseAptHistory("Install: foo:amd64 (1.0)\nEnd-Date: 2024-01-10  09:03:40\n")
+	assert.Empty(t, entries)
+}
+
+func TestManager_History(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("find", exec.SuccessResult("/var/log/apt/history.log.1.gz\n/var/log/apt/history.log.10.gz\n/var/log/apt/history.log.2.gz\n"))
+	mockExec.SetResponse("zcat", exec.SuccessResult(testAptHistory))
+
+	entries, err := mgr.History(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "2", entries[0].ID)
+	assert.Equal(t, "apt-get install -y nvidia-driver-550", entries[0].Details)
+
+	assert.True(t, mockExec.WasCalledWith("zcat", "-f",
+		"/var/log/apt/history.log.10.gz",
+		"/var/log/apt/history.log.2.gz",
+		"/var/log/apt/history.log.1.gz",
+		"/var/log/apt/history.log",
+	))
+}
+
+func TestManager_History_NoLog(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("find", exec.SuccessResult(""))
+	mockExec.SetResponse("zcat", exec.FailureResult(1, "gzip: /var/log/apt/history.log: No such file or directory"))
+
+	entries, err := mgr.History(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	assert.True(t, mockExec.WasCalledWith("zcat", "-f", "/var/log/apt/history.log"))
+}
+
+func TestManager_History_ReadFailure(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("find", exec.FailureResult(1, ""))
+	mockExec.SetResponse("zcat", exec.FailureResult(1, "gzip: /var/log/apt/history.log: Permission denied"))
+
+	_, err := mgr.History(context.Background(), 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Permission denied")
+}
+
+func TestManager_Undo_NotSupported(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	assert.ErrorIs(t, mgr.Undo(context.Background(), "1"), pkg.ErrUnsupportedOperation)
+	assert.ErrorIs(t, mgr.Redo(context.Background(), "1"), pkg.ErrUnsupportedOperation)
+	assert.Equal(t, 0, mockExec.CallCount())
+}
+
+// =============================================================================
+// Transaction Tests
+// =============================================================================
+
+const testDpkgBeforeTransaction = "vim\t2:9.1.0016-1ubuntu7\tinstall ok installed\n" +
+	"xserver-xorg-video-nouveau\t1:1.0.17-2build1\tinstall ok installed\n" +
+	"nvidia-kernel-common-535\t535.171.04-0ubuntu1\tinstall ok installed\n"
+
+const testDpkgAfterTransaction = "vim\t2:9.1.0016-1ubuntu7\tinstall ok installed\n" +
+	"nvidia-kernel-common-535\t550.54.14-0ubuntu1\tinstall ok installed\n" +
+	"nvidia-driver-550\t550.54.14-0ubuntu1\tinstall ok installed\n" +
+	"libnvidia-gl-550\t550.54.14-0ubuntu1\tinstall ok installed\n"
+
+// aptGetArgs returns the arguments of env running apt-get with args.
+func aptGetArgs(args ...string) []string {
+	return append(append(append([]string{}, nonInteractiveEnv...), "apt-get"), args...)
+}
+
+func TestManager_Transaction_Commit(t *testing.T) {
+	mgr, mockExec := setupTest()
+	ctx := context.Background()
+	mockExec.SetResponse("dpkg-query", exec.SuccessResult(testDpkgBeforeTransaction))
+
+	require.NoError(t, mgr.BeginTransaction(ctx))
+	assert.True(t, mgr.InTransaction())
+
+	// Operations run right away
+	require.NoError(t, mgr.Install(ctx, pkg.NonInteractiveInstallOptions(), "nvidia-driver-550"))
+	assert.True(t, mockExec.WasCalledWith("env", aptGetArgs("install", "-y", "nvidia-driver-550")...))
+
+	require.NoError(t, mgr.CommitTransaction(ctx))
+	assert.False(t, mgr.InTransaction())
+}
+
+func TestManager_Transaction_Rollback(t *testing.T) {
+	mgr, mockExec := setupTest()
+	ctx := context.Background()
+	mockExec.SetResponse("dpkg-query", exec.SuccessResult(testDpkgBeforeTransaction))
+
+	require.NoError(t, mgr.BeginTransaction(ctx))
+	mockExec.SetResponse("dpkg-query", exec.SuccessResult(testDpkgAfterTransaction))
+	require.NoError(t, mgr.RollbackTransaction(ctx))
+
+	assert.False(t, mgr.InTransaction())
+	assert.True(t, mockExec.WasCalledWith("env", aptGetArgs("install", "-y", "--allow-downgrades",
+		"nvidia-kernel-common-535=535.171.04-0ubuntu1", "xserver-xorg-video-nouveau=1:1.0.17-2build1")...))
+	assert.True(t, mockExec.WasCalledWith("env", aptGetArgs("remove", "-y", "libnvidia-gl-550", "nvidia-driver-550")...))
+
+	// Packages are restored before the added ones are removed
+	calls := mockExec.Calls()
+	require.GreaterOrEqual(t, len(calls), 2)
+	assert.Contains(t, calls[len(calls)-2].Args, "install")
+	assert.Contains(t, calls[len(calls)-1].Args, "remove")
+}
+
+func TestManager_Transaction_RollbackNoChanges(t *testing.T) {
+	mgr, mockExec := setupTest()
+	ctx := context.Background()
+	mockExec.SetResponse("dpkg-query", exec.SuccessResult(testDpkgBeforeTransaction))
+
+	require.NoError(t, mgr.BeginTransaction(ctx))
+	require.NoError(t, mgr.RollbackTransaction(ctx))
+
+	assert.False(t, mockExec.WasCalled("env"))
+}
+
+func TestManager_Transaction_RollbackFailure(t *testing.T) {
+	mgr, mockExec := setupTest()
+	ctx := context.Background()
+	mockExec.SetResponse("dpkg-query", exec.SuccessResult(testDpkgBeforeTransaction))
+
+	require.NoError(t, mgr.BeginTransaction(ctx))
+	mockExec.SetResponse("dpkg-query", exec.SuccessResult(testDpkgAfterTransaction))
+	mockExec.SetResponse("env", exec.FailureResult(100, "E: Version '535.171.04-0ubuntu1' for 'nvidia-kernel-common-535' was not found"))
+	err := mgr.RollbackTransaction(ctx)
+
+	assert.ErrorIs(t, err, pkg.ErrTransactionFailed)
+	assert.Contains(t, err.Error(), "failed to restore packages")
+	assert.False(t, mgr.InTransaction())
+}
+
+func TestManager_Transaction_BeginFailure(t *testing.T) {
+	mgr, mockExec := setupTest()
+	ctx := context.Background()
+	mockExec.SetResponse("dpkg-query", exec.FailureResult(2, "dpkg-query: error: failed to open package info file"))
+
+	assert.ErrorIs(t, mgr.BeginTransaction(ctx), pkg.ErrTransactionFailed)
+	assert.False(t, mgr.InTransaction())
+}
+
+func TestManager_Transaction_NotInProgress(t *testing.T) {
+	mgr, _ := setupTest()
+	ctx := context.Background()
+
+	assert.ErrorIs(t, mgr.CommitTransaction(ctx), pkg.ErrTransactionFailed)
+	assert.ErrorIs(t, mgr.RollbackTransaction(ctx), pkg.ErrTransactionFailed)
+}
+
+// =============================================================================
+// Bundle Tests
+// =============================================================================
+
+const testAptCacheDependsRecurse = `nvidia-driver-550
+  Depends: libnvidia-gl-550
+  Depends: nvidia-kernel-common-550
+ |Depends: xserver-xorg-video-nvidia-550
+  Depends: <libnvidia-compute>
+libnvidia-gl-550
+  Depends: libc6
+nvidia-kernel-common-550
+<libnvidia-compute>
+libc6
+libnvidia-gl-550
+`
+
+func TestParseAptCacheDependsRecurse(t *testing.T) {
+	packages := parseAptCacheDependsRecurse(testAptCacheDependsRecurse)
+	assert.Equal(t, []string{"nvidia-driver-550", "libnvidia-gl-550", "nvidia-kernel-common-550", "libc6"}, packages)
+	assert.Empty(t, parseAptCacheDependsRecurse(""))
+}
+
+func TestManager_DownloadBundle(t *testing.T) {
+	mgr, mockExec := setupTest()
+	ctx := context.Background()
+	mockExec.SetResponse("apt-cache", exec.SuccessResult(testAptCacheDependsRecurse))
+
+	err := mgr.DownloadBundle(ctx, "/srv/bundle", "nvidia-driver-550")
+	require.NoError(t, err)
+
+	calls := mockExec.Calls()
+	require.Len(t, calls, 3)
+	assert.Equal(t, "apt-cache", calls[0].Command)
+	assert.Equal(t, []string{"depends", "--recurse"}, calls[0].Args[:2])
+	assert.Equal(t, "nvidia-driver-550", calls[0].Args[len(calls[0].Args)-1])
+
+	// The directory and packages are passed as arguments to the script
+	assert.Equal(t, "sh", calls[1].Command)
+	assert.Equal(t, []string{
+		"-c", `cd "$1" && shift && exec apt-get download "$@"`, "sh", "/srv/bundle",
+		"nvidia-driver-550", "libnvidia-gl-550", "nvidia-kernel-common-550", "libc6",
+	}, calls[1].Args)
+
+	assert.Equal(t, "sh", calls[2].Command)
+	assert.Contains(t, calls[2].Args[1], "apt-ftparchive packages . > Packages")
+	assert.Equal(t, "/srv/bundle", calls[2].Args[3])
+}
+
+func TestManager_DownloadBundle_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("no packages", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		require.NoError(t, mgr.DownloadBundle(ctx, "/srv/bundle"))
+		assert.Equal(t, 0, mockExec.CallCount())
+	})
+
+	t.Run("relative directory", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		err := mgr.DownloadBundle(ctx, "bundle", "nvidia-driver-550")
+		assert.ErrorIs(t, err, pkg.ErrBundleFailed)
+		assert.Equal(t, 0, mockExec.CallCount())
+	})
+
+	t.Run("dependency resolution fails", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		mockExec.SetResponse("apt-cache", exec.FailureResult(100, "E: No packages found"))
+		err := mgr.DownloadBundle(ctx, "/srv/bundle", "nvidia-driver-999")
+		assert.ErrorIs(t, err, pkg.ErrBundleFailed)
+	})
+
+	t.Run("download fails", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		mockExec.SetResponse("apt-cache", exec.SuccessResult(testAptCacheDependsRecurse))
+		mockExec.SetResponse("sh", exec.FailureResult(100, "E: Failed to fetch"))
+		err := mgr.DownloadBundle(ctx, "/srv/bundle", "nvidia-driver-550")
+		assert.ErrorIs(t, err, pkg.ErrBundleFailed)
+		assert.Contains(t, err.Error(), "apt-get download failed")
+	})
+}
+
+func TestManager_BundleRepository(t *testing.T) {
+	mgr, _ := setupTest()
+
+	repo := mgr.BundleRepository("/srv/bundle")
+	assert.Equal(t, pkg.BundleRepositoryName, repo.Name)
+	assert.Equal(t, "deb [trusted=yes] file:///srv/bundle ./\n", buildSourcesListEntry(repo))
+
+	parsed, err := parseSourcesListLine(strings.TrimSpace(buildSourcesListEntry(repo)))
+	require.NoError(t, err)
+	assert.True(t, parsed.Trusted)
+}
+
+func TestManager_Repository_Restricted(t *testing.T) {
+	mgr, mockExec := setupTest()
+	ctx := context.Background()
+	restrict := []string{
+		"-o", "Dir::Etc::sourcelist=/etc/apt/sources.list.d/igor-bundle.list",
+		"-o", "Dir::Etc::sourceparts=-",
+		"-o", "APT::Get::List-Cleanup=0",
+	}
+
+	require.NoError(t, mgr.Update(ctx, pkg.UpdateOptions{Repository: "igor-bundle"}))
+	assert.Equal(t, aptGetArgs(append([]string{"update"}, restrict...)...), mockExec.LastCall().Args)
+
+	require.NoError(t, mgr.Install(ctx, pkg.InstallOptions{Repository: "igor-bundle"}, "nvidia-driver-550"))
+	expected := append(append([]string{"install", "-y"}, restrict...), "nvidia-driver-550")
+	assert.Equal(t, aptGetArgs(expected...), mockExec.LastCall().Args)
+}
+
+// =============================================================================
+// Simulation Tests
+// =============================================================================
+
+const testAptGetSimulate = `NOTE: This is only a simulation!
+      apt-get needs root privileges for real execution.
+      Keep also in mind that locking is deactivated,
+      so don't depend on the relevance to the real current situation!
+Reading package lists...
+Building dependency tree...
+The following packages will be REMOVED:
+  e2fsprogs xserver-xorg-video-nouveau
+The following NEW packages will be installed:
+  nvidia-driver-550
+The following packages will be upgraded:
+  libnvidia-gl-535
+The following packages will be DOWNGRADED:
+  libnvidia-common
+WARNING: The following essential packages will be removed.
+This should NOT be done unless you know exactly what you are doing!
+  e2fsprogs
+1 upgraded, 1 newly installed, 1 downgraded, 2 to remove and 12 not upgraded.
+Remv e2fsprogs [1.47.0-2.4]
+Remv xserver-xorg-video-nouveau [1:1.0.17-2build1]
+Inst nvidia-driver-550 (550.120-0ubuntu1 Ubuntu:24.04/noble-updates [amd64])
+Inst libnvidia-gl-535 [535.183-0ubuntu1] (535.216-0ubuntu1 Ubuntu:24.04/noble-updates [amd64])
+Inst libnvidia-common [560.35-0ubuntu1] (550.120-0ubuntu1 Ubuntu:24.04/noble-updates [all])
+Inst libc-bin [2.39-0ubuntu8] (2.39-0ubuntu8 Ubuntu:24.04/noble [amd64])
+Conf nvidia-driver-550 (550.120-0ubuntu1 Ubuntu:24.04/noble-updates [amd64])
+`
+
+const testAptCacheShowSizes = `Package: nvidia-driver-550
+Version: 550.120-0ubuntu1
+Installed-Size: 100
+Size: 4096
+
+Package: libnvidia-gl-535
+Version: 535.216-0ubuntu1
+Installed-Size: 200
+Size: 8192
+`
+
+func TestParseAptGetSimulate(t *testing.T) {
+	sim := parseAptGetSimulate(testAptGetSimulate)
+
+	assert.Equal(t, []pkg.PackageChange{
+		{Name: "nvidia-driver-550", Version: "550.120-0ubuntu1"},
+	}, sim.Install)
+	assert.Equal(t, []pkg.PackageChange{
+		{Name: "libnvidia-gl-535", Version: "535.216-0ubuntu1", OldVersion: "535.183-0ubuntu1"},
+	}, sim.Upgrade)
+	assert.Equal(t, []pkg.PackageChange{
+		{Name: "libnvidia-common", Version: "550.120-0ubuntu1", OldVersion: "560.35-0ubuntu1"},
+	}, sim.Downgrade)
+	assert.Equal(t, []pkg.PackageChange{
+		{Name: "e2fsprogs", Version: "1.47.0-2.4", Essential: true},
+		{Name: "xserver-xorg-video-nouveau", Version: "1:1.0.17-2build1"},
+	}, sim.Remove)
+
+	assert.True(t, parseAptGetSimulate("").IsEmpty())
+}
+
+func TestParseAptCacheShowSizes(t *testing.T) {
+	sizes := parseAptCacheShowSizes(testAptCacheShowSizes)
+	assert.Equal(t, map[string]aptPackageSizes{
+		"nvidia-driver-550=550.120-0ubuntu1": {download: 4096, installed: 100 * 1024},
+		"libnvidia-gl-535=535.216-0ubuntu1":  {download: 8192, installed: 200 * 1024},
+	}, sizes)
+}
+
+func TestManager_SimulateInstall(t *testing.T) {
+	mgr, mockExec := setupTest()
+	ctx := context.Background()
+	mockExec.SetResponse("apt-get", exec.SuccessResult(testAptGetSimulate))
+	mockExec.SetResponse("apt-cache", exec.SuccessResult(testAptCacheShowSizes))
+	mockExec.SetResponse("dpkg-query", exec.SuccessResult("libnvidia-gl-535\t150\ne2fsprogs\t10\n"))
+
+	sim, err := mgr.SimulateInstall(ctx, pkg.NonInteractiveInstallOptions(), "nvidia-driver-550")
+	require.NoError(t, err)
+
+	calls := mockExec.Calls()
+	require.Len(t, calls, 3)
+	assert.Equal(t, "apt-get", calls[0].Command)
+	assert.Equal(t, []string{"-s", "install", "-y", "nvidia-driver-550"}, calls[0].Args)
+	assert.Equal(t, "apt-cache", calls[1].Command)
+	assert.Contains(t, calls[1].Args, "nvidia-driver-550=550.120-0ubuntu1")
+	assert.Equal(t, "dpkg-query", calls[2].Command)
+	assert.Contains(t, calls[2].Args, "xserver-xorg-video-nouveau")
+
+	assert.Equal(t, int64(4096), sim.Install[0].Size)
+	assert.Equal(t, int64(100*1024), sim.Install[0].InstalledSize)
+	assert.Equal(t, int64(10*1024), sim.Remove[0].InstalledSize)
+	assert.Equal(t, int64(4096+8192), sim.DownloadSize)
+	assert.Equal(t, int64((100+200-150-10)*1024), sim.InstallSize)
+	require.Len(t, sim.EssentialRemovals(), 1)
+	assert.Equal(t, "e2fsprogs", sim.EssentialRemovals()[0].Name)
+}
+
+func TestManager_SimulateInstall_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("no packages", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		sim, err := mgr.SimulateInstall(ctx, pkg.NonInteractiveInstallOptions())
+		require.NoError(t, err)
+		assert.True(t, sim.IsEmpty())
+		assert.Equal(t, 0, mockExec.CallCount())
+	})
+
+	t.Run("package not found", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		mockExec.SetResponse("apt-get", exec.FailureResult(100, "E: Unable to locate package nvidia-driver-999"))
+		_, err := mgr.SimulateInstall(ctx, pkg.NonInteractiveInstallOptions(), "nvidia-driver-999")
+		assert.ErrorIs(t, err, pkg.ErrPackageNotFound)
+	})
+
+	t.Run("unresolvable", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		mockExec.SetResponse("apt-get", exec.FailureResult(100, "E: Unable to correct problems, you have held broken packages."))
+		_, err := mgr.SimulateInstall(ctx, pkg.NonInteractiveInstallOptions(), "nvidia-driver-550")
+		assert.ErrorIs(t, err, pkg.ErrSimulationFailed)
+	})
+}
+
+func TestManager_Install_Progress(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("env", exec.SuccessResult(
+		"Reading package lists...\n"+
+			"dlstatus:1:0:Retrieving file 1 of 2\n"+
+			"dlstatus:2:50.5:Retrieving file 2 of 2\n"+
+			"pmstatus:dpkg-exec:0:Running dpkg\n"+
+			"pmstatus:nvidia-driver-550:amd64:42.8571:Unpacking nvidia-driver-550 (amd64)\n"+
+			"pmstatus:nvidia-utils-550:85.7:Setting up nvidia-utils-550 (550.54.14-0ubuntu1)\n"+
+			"pmerror:broken:0:oops"))
+
+	var reported []pkg.Progress
+	opts := pkg.NonInteractiveInstallOptions()
+	opts.Progress = func(p pkg.Progress) { reported = append(reported, p) }
+	require.NoError(t, mgr.Install(context.Background(), opts, "nvidia-driver-550"))
+
+	call := mockExec.LastCall()
+	assert.True(t, call.Elevated)
+	assert.Equal(t, aptGetArgs("-o", "APT::Status-Fd=1", "install", "-y", "nvidia-driver-550"), call.Args)
+	assert.Equal(t, []pkg.Progress{
+		{Phase: pkg.ProgressDownload, Percent: 0},
+		{Phase: pkg.ProgressDownload, Percent: 50.5},
+		{Phase: pkg.ProgressInstall, Percent: 0},
+		{Phase: pkg.ProgressInstall, Package: "nvidia-driver-550", Percent: 42.8571},
+		{Phase: pkg.ProgressInstall, Package: "nvidia-utils-550", Percent: 85.7},
+	}, reported)
+}
+
+func TestManager_Install_ProgressFailure(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("env", exec.FailureResult(100, "E: Could not get lock /var/lib/dpkg/lock"))
+
+	opts := pkg.NonInteractiveInstallOptions()
+	opts.Progress = func(pkg.Progress) {}
+	err := mgr.Install(context.Background(), opts, "nvidia-driver-550")
+	assert.ErrorIs(t, err, pkg.ErrLockAcquireFailed)
+}
@@ -0,0 +1,68 @@
+package apt
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// DownloadBundle downloads the packages and their dependencies into dir and
+// writes a flat repository index for it.
+// The dependency closure is resolved with apt-cache depends and includes the
+// packages already installed, so the bundle can be installed on a minimal
+// system of the same release. apt-get download and apt-ftparchive work on
+// the current directory, so they run from a shell that changes into dir;
+// the directory and the package names are passed as arguments and never
+// interpolated into the script. apt-ftparchive is part of apt-utils.
+// The files are written with elevated privileges, like the other package
+// manager operations.
+func (m *Manager) DownloadBundle(ctx context.Context, dir string, packages ...string) error {
+	if len(packages) == 0 {
+		return nil
+	}
+	if !filepath.IsAbs(dir) {
+		return pkg.Wrap(pkg.ErrBundleFailed, fmt.Errorf("bundle directory must be an absolute path: %s", dir))
+	}
+
+	args := []string{
+		"depends", "--recurse", "--no-recommends", "--no-suggests",
+		"--no-conflicts", "--no-breaks", "--no-replaces", "--no-enhances",
+	}
+	args = append(args, packages...)
+	result := m.executor.Execute(ctx, "apt-cache", args...)
+	if result.Failed() {
+		return pkg.Wrap(pkg.ErrBundleFailed, fmt.Errorf("apt-cache depends failed (exit code %d): %s", result.ExitCode, result.StderrString()))
+	}
+	closure := parseAptCacheDependsRecurse(result.StdoutString())
+
+	args = append([]string{"-c", `cd "$1" && shift && exec apt-get download "$@"`, "sh", dir}, closure...)
+	result = m.executor.ExecuteElevated(ctx, "sh", args...)
+	if result.Failed() {
+		return pkg.Wrap(pkg.ErrBundleFailed, fmt.Errorf("apt-get download failed (exit code %d): %s", result.ExitCode, result.StderrString()))
+	}
+
+	result = m.executor.ExecuteElevated(ctx, "sh", "-c", `cd "$1" && apt-ftparchive packages . > Packages && apt-ftparchive release . > Release`, "sh", dir)
+	if result.Failed() {
+		return pkg.Wrap(pkg.ErrBundleFailed, fmt.Errorf("failed to index the bundle (is apt-utils installed?): %s", result.StderrString()))
+	}
+
+	return nil
+}
+
+// BundleRepository returns the flat repository serving the bundle in dir.
+// The packages are not signed, so the repository is trusted.
+func (m *Manager) BundleRepository(dir string) pkg.Repository {
+	return pkg.Repository{
+		Name:         pkg.BundleRepositoryName,
+		URL:          "file://" + dir,
+		Enabled:      true,
+		Type:         "deb",
+		Distribution: "./",
+		Trusted:      true,
+	}
+}
+
+// Ensure Manager implements pkg.BundleManager interface.
+var _ pkg.BundleManager = (*Manager)(nil)
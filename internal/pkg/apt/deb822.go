@@ -0,0 +1,218 @@
+package apt
+
+import (
+	"strings"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// deb822 is the format of the .sources files in /etc/apt/sources.list.d,
+// which replaced the one-line .list format on Ubuntu 24.04 and Debian 12.
+// A file holds stanzas separated by blank lines, each of "Field: value"
+// lines; a value continues on the following lines starting with a space.
+//
+//	Types: deb
+//	URIs: https://developer.download.nvidia.com/compute/cuda/repos/ubuntu2404/x86_64
+//	Suites: /
+//	Signed-By: /etc/apt/keyrings/nvidia-cuda.gpg
+
+// Extensions of the repository files in sources.list.d.
+const (
+	listExtension    = ".list"
+	sourcesExtension = ".sources"
+)
+
+// deb822Field is a field of a deb822 stanza.
+type deb822Field struct {
+	name  string
+	value string
+}
+
+// parseDeb822Stanzas parses deb822 content into its stanzas. Field names
+// are case-insensitive and returned in lower case; multi-line values are
+// joined with newlines. Comments are skipped.
+func parseDeb822Stanzas(content string) [][]deb822Field {
+	var stanzas [][]deb822Field
+	var stanza []deb822Field
+
+	for _, line := range strings.Split(content, "\n") {
+		switch {
+		case strings.TrimSpace(line) == "":
+			if len(stanza) > 0 {
+				stanzas = append(stanzas, stanza)
+				stanza = nil
+			}
+		case strings.HasPrefix(line, "#"):
+			continue
+		case line[0] == ' ' || line[0] == '\t':
+			// Continuation of the previous field
+			if len(stanza) > 0 {
+				stanza[len(stanza)-1].value += "\n" + strings.TrimSpace(line)
+			}
+		default:
+			name, value, ok := strings.Cut(line, ":")
+			if !ok {
+				continue
+			}
+			stanza = append(stanza, deb822Field{
+				name:  strings.ToLower(strings.TrimSpace(name)),
+				value: strings.TrimSpace(value),
+			})
+		}
+	}
+	if len(stanza) > 0 {
+		stanzas = append(stanzas, stanza)
+	}
+
+	return stanzas
+}
+
+// deb822Value returns the value of a field of the stanza, or "".
+func deb822Value(stanza []deb822Field, name string) string {
+	for _, f := range stanza {
+		if f.name == name {
+			return f.value
+		}
+	}
+	return ""
+}
+
+// parseDeb822Sources parses the content of a .sources file. A stanza lists
+// several types, URIs and suites; a repository is returned for each
+// combination, as for the equivalent .list lines. A stanza is disabled with
+// "Enabled: no". Signed-By is a keyring path, or the key itself, embedded
+// over several lines, in which case GPGKey is left empty.
+func parseDeb822Sources(content string) []pkg.Repository {
+	var repos []pkg.Repository
+
+	for _, stanza := range parseDeb822Stanzas(content) {
+		types := strings.Fields(deb822Value(stanza, "types"))
+		uris := strings.Fields(deb822Value(stanza, "uris"))
+		suites := strings.Fields(deb822Value(stanza, "suites"))
+		components := strings.Fields(deb822Value(stanza, "components"))
+		enabled := !strings.EqualFold(deb822Value(stanza, "enabled"), "no")
+		trusted := strings.EqualFold(deb822Value(stanza, "trusted"), "yes")
+
+		gpgKey := deb822Value(stanza, "signed-by")
+		if strings.Contains(gpgKey, "\n") || strings.HasPrefix(gpgKey, "-----BEGIN") {
+			gpgKey = ""
+		}
+
+		if len(suites) == 0 {
+			suites = []string{""}
+		}
+		for _, repoType := range types {
+			if repoType != "deb" && repoType != "deb-src" {
+				continue
+			}
+			for _, uri := range uris {
+				for _, suite := range suites {
+					repos = append(repos, pkg.Repository{
+						Name:         generateRepoName(uri, suite),
+						URL:          uri,
+						Enabled:      enabled,
+						GPGKey:       gpgKey,
+						Type:         repoType,
+						Distribution: suite,
+						Components:   append([]string(nil), components...),
+						Trusted:      trusted,
+					})
+				}
+			}
+		}
+	}
+
+	return repos
+}
+
+// buildDeb822Entry creates a deb822 stanza from a Repository.
+func buildDeb822Entry(repo pkg.Repository) string {
+	var sb strings.Builder
+
+	repoType := repo.Type
+	if repoType == "" {
+		repoType = "deb"
+	}
+	suite := repo.Distribution
+	if suite == "" {
+		suite = "/"
+	}
+
+	sb.WriteString("Types: " + repoType + "\n")
+	sb.WriteString("URIs: " + repo.URL + "\n")
+	sb.WriteString("Suites: " + suite + "\n")
+	// Components must be omitted for flat repositories, whose suite is a path
+	if len(repo.Components) > 0 && !strings.HasSuffix(suite, "/") {
+		sb.WriteString("Components: " + strings.Join(repo.Components, " ") + "\n")
+	}
+	if repo.GPGKey != "" {
+		sb.WriteString("Signed-By: " + repo.GPGKey + "\n")
+	}
+	if repo.Trusted {
+		sb.WriteString("Trusted: yes\n")
+	}
+
+	return sb.String()
+}
+
+// setDeb822Enabled enables or disables every stanza of a .sources file
+// through its Enabled field, adding the field to stanzas that lack it.
+// Comments and formatting are preserved. It returns the new content and
+// whether any stanza changed.
+func setDeb822Enabled(content string, enable bool) (string, bool) {
+	value := "no"
+	if enable {
+		value = "yes"
+	}
+
+	lines := strings.Split(content, "\n")
+	var out []string
+	modified := false
+
+	for start := 0; start < len(lines); {
+		// A stanza runs up to the next blank line
+		end := start
+		for end < len(lines) && strings.TrimSpace(lines[end]) != "" {
+			end++
+		}
+
+		firstField, enabledLine := -1, -1
+		for i := start; i < end; i++ {
+			line := lines[i]
+			if strings.HasPrefix(line, "#") || line[0] == ' ' || line[0] == '\t' {
+				continue
+			}
+			if firstField < 0 {
+				firstField = i
+			}
+			if name, _, ok := strings.Cut(line, ":"); ok && strings.EqualFold(strings.TrimSpace(name), "enabled") {
+				enabledLine = i
+			}
+		}
+
+		for i := start; i < end; i++ {
+			switch {
+			case i == enabledLine:
+				_, current, _ := strings.Cut(lines[i], ":")
+				if strings.EqualFold(strings.TrimSpace(current), "no") == enable {
+					modified = true
+				}
+				out = append(out, "Enabled: "+value)
+				continue
+			case i == firstField && enabledLine < 0 && !enable:
+				// A stanza without the field is enabled
+				out = append(out, "Enabled: no")
+				modified = true
+			}
+			out = append(out, lines[i])
+		}
+
+		// Keep the blank line separating the stanzas
+		if end < len(lines) {
+			out = append(out, lines[end])
+		}
+		start = end + 1
+	}
+
+	return strings.Join(out, "\n"), modified
+}
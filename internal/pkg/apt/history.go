@@ -0,0 +1,91 @@
+package apt
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// aptHistoryDir is the directory of the APT history log and its rotations.
+const aptHistoryDir = "/var/log/apt"
+
+// History returns the APT transaction history, newest first.
+// It is read from /var/log/apt/history.log and its compressed rotations.
+// APT has no transaction IDs, so entries are numbered in log order, oldest
+// first; numbers shift when the oldest rotation is deleted.
+func (m *Manager) History(ctx context.Context, limit int) ([]pkg.HistoryEntry, error) {
+	logs := m.historyLogs(ctx)
+
+	// zcat -f also prints the uncompressed current log. It fails if one of
+	// the logs cannot be read, so whatever it printed is used.
+	args := append([]string{"-f"}, logs...)
+	result := m.executor.Execute(ctx, "zcat", args...)
+	if result.Failed() && len(result.Stdout) == 0 {
+		if !strings.Contains(result.StderrString(), "No such file") {
+			return nil, fmt.Errorf("failed to read the APT history: %s", result.StderrString())
+		}
+		return []pkg.HistoryEntry{}, nil
+	}
+
+	entries := parseAptHistory(result.StdoutString())
+	for i := range entries {
+		entries[i].ID = strconv.Itoa(i + 1)
+	}
+	return pkg.NewestHistory(entries, limit), nil
+}
+
+// Undo is not supported: APT cannot undo transactions.
+func (m *Manager) Undo(ctx context.Context, id string) error {
+	return pkg.Wrap(pkg.ErrUnsupportedOperation, fmt.Errorf("apt cannot undo transactions"))
+}
+
+// Redo is not supported: APT cannot redo transactions.
+func (m *Manager) Redo(ctx context.Context, id string) error {
+	return pkg.Wrap(pkg.ErrUnsupportedOperation, fmt.Errorf("apt cannot redo transactions"))
+}
+
+// historyLogs returns the APT history logs, oldest first: the rotations,
+// such as history.log.2.gz and history.log.1.gz, then history.log.
+func (m *Manager) historyLogs(ctx context.Context) []string {
+	current := filepath.Join(aptHistoryDir, "history.log")
+
+	result := m.executor.Execute(ctx, "find", aptHistoryDir, "-maxdepth", "1", "-type", "f", "-name", "history.log.*")
+	if result.Failed() {
+		return []string{current}
+	}
+
+	type rotation struct {
+		path   string
+		number int
+	}
+	var rotations []rotation
+	for _, line := range strings.Split(result.StdoutString(), "\n") {
+		path := strings.TrimSpace(line)
+		if path == "" {
+			continue
+		}
+		suffix := strings.TrimPrefix(filepath.Base(path), "history.log.")
+		number, err := strconv.Atoi(strings.TrimSuffix(suffix, ".gz"))
+		if err != nil {
+			continue
+		}
+		rotations = append(rotations, rotation{path: path, number: number})
+	}
+	sort.Slice(rotations, func(i, j int) bool {
+		return rotations[i].number > rotations[j].number
+	})
+
+	logs := make([]string, 0, len(rotations)+1)
+	for _, r := range rotations {
+		logs = append(logs, r.path)
+	}
+	return append(logs, current)
+}
+
+// Ensure Manager implements pkg.HistoryManager interface.
+var _ pkg.HistoryManager = (*Manager)(nil)
@@ -0,0 +1,67 @@
+package apt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// Hold prevents packages from being upgraded using apt-mark hold.
+func (m *Manager) Hold(ctx context.Context, packages ...string) error {
+	if len(packages) == 0 {
+		return nil
+	}
+
+	args := append([]string{"hold"}, packages...)
+	result := m.executor.ExecuteElevated(ctx, "apt-mark", args...)
+
+	if result.Failed() {
+		return pkg.Wrap(pkg.ErrHoldFailed, fmt.Errorf("apt-mark hold failed: %s", result.StderrString()))
+	}
+
+	return nil
+}
+
+// Unhold releases package holds using apt-mark unhold.
+func (m *Manager) Unhold(ctx context.Context, packages ...string) error {
+	if len(packages) == 0 {
+		return nil
+	}
+
+	args := append([]string{"unhold"}, packages...)
+	result := m.executor.ExecuteElevated(ctx, "apt-mark", args...)
+
+	if result.Failed() {
+		return pkg.Wrap(pkg.ErrHoldFailed, fmt.Errorf("apt-mark unhold failed: %s", result.StderrString()))
+	}
+
+	return nil
+}
+
+// ListHeld returns the held packages using apt-mark showhold.
+func (m *Manager) ListHeld(ctx context.Context) ([]string, error) {
+	result := m.executor.Execute(ctx, "apt-mark", "showhold")
+
+	if result.Failed() {
+		return nil, fmt.Errorf("apt-mark showhold failed: %s", result.StderrString())
+	}
+
+	return parseAptMarkShowhold(result.StdoutString()), nil
+}
+
+// parseAptMarkShowhold parses the output of apt-mark showhold, one package
+// per line. Architecture qualifiers of foreign packages are kept.
+func parseAptMarkShowhold(output string) []string {
+	held := make([]string, 0)
+	for _, line := range strings.Split(output, "\n") {
+		if name := strings.TrimSpace(line); name != "" {
+			held = append(held, name)
+		}
+	}
+	return held
+}
+
+// Ensure Manager implements pkg.HoldManager interface.
+var _ pkg.HoldManager = (*Manager)(nil)
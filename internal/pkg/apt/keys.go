@@ -0,0 +1,69 @@
+package apt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// trustedKeyFiles are the keys APT trusts for every repository, and the
+// keyrings repositories reference with signed-by.
+const trustedKeyFiles = "/etc/apt/trusted.gpg " + trustedGPGDir + "/* " + aptKeyringsDir + "/*"
+
+// ImportPinnedGPGKey downloads the key at keyURL, verifies it against the
+// pin and writes it to its own keyring in /etc/apt/keyrings, named after
+// the pin. Repositories reference the keyring with signed-by, so the key is
+// trusted for them only instead of for every repository, as keys in the
+// global trust store are. The retired keys of the pin APT still trusts are
+// reported.
+// Returns ErrGPGKeyMismatch if the key does not match the pin.
+func (m *Manager) ImportPinnedGPGKey(ctx context.Context, keyURL string, pin pkg.KeyPin) (*pkg.KeyImport, error) {
+	if err := validateGPGKeyInputs(pin.Name, keyURL); err != nil {
+		return nil, err
+	}
+
+	file, err := pkg.FetchPinnedGPGKey(ctx, m.executor, keyURL, pin)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Remove(ctx)
+
+	result := m.executor.ExecuteElevated(ctx, "mkdir", "-p", aptKeyringsDir)
+	if result.Failed() {
+		return nil, fmt.Errorf("failed to create %s: %s", aptKeyringsDir, result.StderrString())
+	}
+
+	// gpg --dearmor passes binary keys through unchanged, so armored and
+	// binary keys both end up as binary keyrings, as APT expects.
+	keyPath := m.GetGPGKeyPath(pin.Name)
+	result = m.executor.ExecuteElevated(ctx, "gpg", "--batch", "--yes", "--dearmor", "--output", keyPath, file.Path)
+	if result.Failed() {
+		return nil, fmt.Errorf("failed to write keyring %s: %s", keyPath, result.StderrString())
+	}
+
+	return &pkg.KeyImport{
+		Fingerprints: file.Fingerprints,
+		Keyring:      keyPath,
+		Retired:      pin.RetiredKeys(m.trustedKeyFingerprints(ctx)),
+	}, nil
+}
+
+// trustedKeyFingerprints returns the fingerprints of the keys in the global
+// trust store and the keyrings. gpg exits with an error when a glob matches
+// nothing, so its output is parsed whatever the exit code.
+func (m *Manager) trustedKeyFingerprints(ctx context.Context) []string {
+	shellCmd := fmt.Sprintf("gpg --show-keys --with-colons %s 2>/dev/null", trustedKeyFiles)
+	result := m.executor.Execute(ctx, "sh", "-c", shellCmd)
+	return pkg.ParseGPGFingerprints(result.StdoutString())
+}
+
+// isKeyURL reports whether the GPGKey of a repository is a URL to download
+// the key from rather than the path of a keyring.
+func isKeyURL(key string) bool {
+	return strings.HasPrefix(key, "https://") || strings.HasPrefix(key, "http://")
+}
+
+// Ensure Manager implements pkg.KeyPinningManager interface.
+var _ pkg.KeyPinningManager = (*Manager)(nil)
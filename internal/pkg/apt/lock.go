@@ -0,0 +1,48 @@
+package apt
+
+import (
+	"context"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// aptLockFiles are the lock files of dpkg and APT, in the order APT takes
+// them. unattended-upgrades and PackageKit take the same locks.
+var aptLockFiles = []pkg.LockFile{
+	{Path: "/var/lib/dpkg/lock-frontend", Kind: pkg.LockKindOpenFile},
+	{Path: "/var/lib/dpkg/lock", Kind: pkg.LockKindOpenFile},
+	{Path: "/var/lib/apt/lists/lock", Kind: pkg.LockKindOpenFile},
+	{Path: "/var/cache/apt/archives/lock", Kind: pkg.LockKindOpenFile},
+}
+
+// AcquireLock verifies that the dpkg lock is free.
+// APT commands take the lock themselves, so it is not held afterwards.
+// Returns ErrLockAcquireFailed, naming the process holding the lock, if it
+// is held.
+func (m *Manager) AcquireLock(ctx context.Context) error {
+	return m.locks.AcquireLock(ctx)
+}
+
+// ReleaseLock does nothing, since AcquireLock does not hold the lock.
+func (m *Manager) ReleaseLock(ctx context.Context) error {
+	return m.locks.ReleaseLock(ctx)
+}
+
+// IsLocked checks if another process holds the dpkg lock.
+func (m *Manager) IsLocked(ctx context.Context) (bool, error) {
+	return m.locks.IsLocked(ctx)
+}
+
+// WaitForLock waits until the dpkg lock is free or ctx is done.
+func (m *Manager) WaitForLock(ctx context.Context) error {
+	return m.locks.WaitForLock(ctx)
+}
+
+// LockHolder returns the process holding the dpkg lock, or nil if the
+// lock is free.
+func (m *Manager) LockHolder(ctx context.Context) (*pkg.LockHolder, error) {
+	return m.locks.LockHolder(ctx)
+}
+
+// Ensure Manager implements pkg.LockableManager interface.
+var _ pkg.LockableManager = (*Manager)(nil)
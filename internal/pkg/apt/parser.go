@@ -4,6 +4,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/tungetti/igor/internal/pkg"
 )
@@ -182,9 +183,7 @@ func parseSourcesList(content string) ([]pkg.Repository, error) {
 		// Disabled repos look like: #deb ... or # deb ...
 		if strings.HasPrefix(line, "#") {
 			// Check if this is a disabled repo (commented out deb line)
-			trimmed := strings.TrimPrefix(line, "#")
-			trimmed = strings.TrimSpace(trimmed)
-			if !strings.HasPrefix(trimmed, "deb") {
+			if !strings.HasPrefix(uncommentEntry(line), "deb") {
 				// This is a regular comment, skip it
 				continue
 			}
@@ -203,14 +202,23 @@ func parseSourcesList(content string) ([]pkg.Repository, error) {
 	return repos, nil
 }
 
+// uncommentEntry returns a commented-out sources.list line without its
+// leading #, or without the marker of the entries disabled by igor.
+func uncommentEntry(line string) string {
+	line = strings.TrimSpace(line)
+	if entry, ok := strings.CutPrefix(line, disabledEntryPrefix); ok {
+		return strings.TrimSpace(entry)
+	}
+	return strings.TrimSpace(strings.TrimPrefix(line, "#"))
+}
+
 // parseSourcesListLine parses a single sources.list line.
 func parseSourcesListLine(line string) (*pkg.Repository, error) {
 	// Check for disabled lines (commented out but with # followed by deb)
 	enabled := true
 	if strings.HasPrefix(line, "#") {
 		enabled = false
-		line = strings.TrimPrefix(line, "#")
-		line = strings.TrimSpace(line)
+		line = uncommentEntry(line)
 	}
 
 	// Must start with deb or deb-src
@@ -228,6 +236,7 @@ func parseSourcesListLine(line string) (*pkg.Repository, error) {
 
 	// Check for options in brackets [arch=amd64 signed-by=/path/to/key.gpg]
 	var gpgKey string
+	var trusted bool
 	if strings.HasPrefix(parts[idx], "[") {
 		// Find the closing bracket
 		optStr := ""
@@ -240,6 +249,7 @@ func parseSourcesListLine(line string) (*pkg.Repository, error) {
 		}
 		// Extract signed-by option if present
 		gpgKey = extractOption(optStr, "signed-by")
+		trusted = extractOption(optStr, "trusted") == "yes"
 	}
 
 	if idx >= len(parts) {
@@ -271,6 +281,7 @@ func parseSourcesListLine(line string) (*pkg.Repository, error) {
 		Type:         repoType,
 		Distribution: dist,
 		Components:   components,
+		Trusted:      trusted,
 	}, nil
 }
 
@@ -358,3 +369,256 @@ func parseAptListUpgradable(output string) ([]pkg.Package, error) {
 
 	return packages, nil
 }
+
+// aptHistoryOperations maps the package fields of an APT history log entry
+// to history operations.
+var aptHistoryOperations = map[string]string{
+	"Install":   pkg.HistoryInstall,
+	"Upgrade":   pkg.HistoryUpgrade,
+	"Downgrade": pkg.HistoryDowngrade,
+	"Reinstall": pkg.HistoryReinstall,
+	"Remove":    pkg.HistoryRemove,
+	"Purge":     pkg.HistoryRemove,
+}
+
+// parseAptHistory parses the APT history log into history entries, in log
+// order. Entries are blocks of "Field: value" lines from Start-Date to
+// End-Date; an Error field marks a failed transaction. IDs are not set.
+func parseAptHistory(content string) []pkg.HistoryEntry {
+	entries := make([]pkg.HistoryEntry, 0)
+	var current *pkg.HistoryEntry
+
+	for _, line := range strings.Split(content, "\n") {
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		if key == "Start-Date" {
+			entries = append(entries, pkg.HistoryEntry{
+				Timestamp: parseAptHistoryTime(value),
+				Success:   true,
+			})
+			current = &entries[len(entries)-1]
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		switch key {
+		case "Commandline":
+			current.Details = value
+		case "Error":
+			current.Success = false
+		case "End-Date":
+			current = nil
+		default:
+			if operation, ok := aptHistoryOperations[key]; ok {
+				current.AddOperation(operation)
+				for _, name := range parseAptHistoryPackages(value) {
+					current.AddPackage(name)
+				}
+			}
+		}
+	}
+
+	return entries
+}
+
+// parseAptHistoryPackages returns the package names of a package field of
+// the APT history log, such as
+// "nvidia-driver-550:amd64 (550.54.14-0ubuntu1), libnvidia-gl-550:i386 (550.54.14-0ubuntu1, automatic)".
+// Architecture qualifiers are dropped.
+func parseAptHistoryPackages(value string) []string {
+	names := make([]string, 0)
+	for _, item := range strings.Split(value, "), ") {
+		name, _, _ := strings.Cut(strings.TrimSpace(item), " ")
+		name, _, _ = strings.Cut(name, ":")
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// parseAptHistoryTime parses a date of the APT history log, such as
+// "2024-01-15  10:23:45", in the local time zone. It returns 0 if the date
+// cannot be parsed.
+func parseAptHistoryTime(value string) int64 {
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", strings.Join(strings.Fields(value), " "), time.Local)
+	if err != nil {
+		return 0
+	}
+	return t.Unix()
+}
+
+// parseAptCacheDependsRecurse parses the output of apt-cache depends --recurse
+// and returns the packages of the dependency closure, in order of appearance.
+// Package names start at the beginning of a line; dependency lines are
+// indented, and virtual packages are shown in angle brackets.
+func parseAptCacheDependsRecurse(output string) []string {
+	var packages []string
+	seen := make(map[string]bool)
+
+	for _, line := range strings.Split(output, "\n") {
+		if line == "" || line[0] == ' ' || line[0] == '\t' || line[0] == '|' {
+			continue
+		}
+		name := strings.TrimSpace(line)
+		if strings.HasPrefix(name, "<") || seen[name] {
+			continue
+		}
+		seen[name] = true
+		packages = append(packages, name)
+	}
+
+	return packages
+}
+
+// parseAptGetSimulate parses the output of apt-get -s install. The changes
+// are read from the Inst and Remv lines; the DOWNGRADED and essential
+// package sections of the summary, printed before them, tell downgrades and
+// essential removals.
+//
+// Example lines:
+//
+//	Remv xserver-xorg-video-nouveau [1:1.0.17-2build1]
+//	Inst nvidia-driver-550 (550.120-0ubuntu1 Ubuntu:24.04/noble-updates [amd64])
+//	Inst libnvidia-gl-535 [535.183-0ubuntu1] (535.216-0ubuntu1 Ubuntu:24.04/noble-updates [amd64])
+func parseAptGetSimulate(output string) *pkg.Simulation {
+	sim := pkg.NewSimulation()
+	downgraded := make(map[string]bool)
+	essential := make(map[string]bool)
+	section := ""
+
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, " "):
+			for _, name := range strings.Fields(line) {
+				switch section {
+				case "downgrade":
+					downgraded[name] = true
+				case "essential":
+					essential[name] = true
+				}
+			}
+			continue
+		case strings.HasPrefix(line, "The following packages will be DOWNGRADED"):
+			section = "downgrade"
+			continue
+		case strings.HasPrefix(line, "WARNING: The following essential packages will be removed"):
+			section = "essential"
+			continue
+		case strings.HasPrefix(line, "This should NOT be done"):
+			continue
+		}
+		section = ""
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 || (fields[0] != "Inst" && fields[0] != "Remv") {
+			continue
+		}
+
+		change := pkg.PackageChange{Name: fields[1]}
+		rest := strings.Join(fields[2:], " ")
+		if strings.HasPrefix(rest, "[") {
+			if end := strings.Index(rest, "]"); end > 0 {
+				change.OldVersion = rest[1:end]
+				rest = strings.TrimSpace(rest[end+1:])
+			}
+		}
+
+		if fields[0] == "Remv" {
+			change.Version = change.OldVersion
+			change.OldVersion = ""
+			change.Essential = essential[change.Name]
+			sim.Remove = append(sim.Remove, change)
+			continue
+		}
+
+		if strings.HasPrefix(rest, "(") {
+			if version := strings.Fields(rest[1:]); len(version) > 0 {
+				change.Version = version[0]
+			}
+		}
+		switch {
+		case change.OldVersion == "":
+			sim.Install = append(sim.Install, change)
+		case change.OldVersion == change.Version:
+			// Reinstalls change nothing
+		case downgraded[change.Name]:
+			sim.Downgrade = append(sim.Downgrade, change)
+		default:
+			sim.Upgrade = append(sim.Upgrade, change)
+		}
+	}
+
+	return sim
+}
+
+// aptPackageSizes holds the sizes of a package version in bytes.
+type aptPackageSizes struct {
+	download  int64
+	installed int64
+}
+
+// parseAptCacheShowSizes parses the stanzas printed by apt-cache show and
+// returns the download and installed sizes of each package version, keyed
+// by "name=version". Installed-Size is in KiB.
+func parseAptCacheShowSizes(output string) map[string]aptPackageSizes {
+	sizes := make(map[string]aptPackageSizes)
+	var name, version string
+	var current aptPackageSizes
+
+	flush := func() {
+		if name != "" && version != "" {
+			sizes[name+"="+version] = current
+		}
+		name, version, current = "", "", aptPackageSizes{}
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			if strings.TrimSpace(line) == "" {
+				flush()
+			}
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Package":
+			name = value
+		case "Version":
+			version = value
+		case "Size":
+			current.download, _ = strconv.ParseInt(value, 10, 64)
+		case "Installed-Size":
+			if size, err := strconv.ParseInt(value, 10, 64); err == nil {
+				current.installed = size * 1024
+			}
+		}
+	}
+	flush()
+
+	return sizes
+}
+
+// parseDpkgQueryInstalledSizes parses the output of
+// dpkg-query -W -f='${Package}\t${Installed-Size}\n' and returns the
+// installed size of each package in bytes. Installed-Size is in KiB.
+func parseDpkgQueryInstalledSizes(output string) map[string]int64 {
+	sizes := make(map[string]int64)
+	for _, line := range strings.Split(output, "\n") {
+		name, value, found := strings.Cut(strings.TrimSpace(line), "\t")
+		if !found {
+			continue
+		}
+		if size, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err == nil {
+			sizes[name] = size * 1024
+		}
+	}
+	return sizes
+}
@@ -0,0 +1,100 @@
+package apt
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// APT preferences for preferred repositories.
+const (
+	preferencesDir = "/etc/apt/preferences.d"
+
+	// preferredPinPriority is above the 500 of the other repositories, so
+	// the preferred repository wins whatever the versions, without
+	// downgrading installed packages. NVIDIA ships the same priority in the
+	// pin file of the CUDA repository.
+	preferredPinPriority = 600
+)
+
+// PreferRepository writes a pin file in /etc/apt/preferences.d giving the
+// origin of the repository priority for the packages matching the globs.
+// The file is named after the repository and replaced if it exists.
+func (m *Manager) PreferRepository(ctx context.Context, repo pkg.Repository, packages []string) error {
+	if len(packages) == 0 {
+		return fmt.Errorf("no packages to prefer repository %s for", repo.Name)
+	}
+
+	pin, err := repositoryPin(repo)
+	if err != nil {
+		return err
+	}
+
+	content := buildPreferences(repo.Name, pin, packages)
+	path := m.GetPreferencesPath(repo.Name)
+	result := m.executor.ExecuteWithInput(ctx, []byte(content), "sudo", "tee", path)
+	if result.Failed() {
+		return fmt.Errorf("failed to write preferences file %s: %s", path, result.StderrString())
+	}
+
+	return nil
+}
+
+// RemoveRepositoryPreference removes the pin file of the repository.
+func (m *Manager) RemoveRepositoryPreference(ctx context.Context, name string) error {
+	path := m.GetPreferencesPath(name)
+	result := m.executor.ExecuteElevated(ctx, "rm", "-f", path)
+	if result.Failed() {
+		return fmt.Errorf("failed to remove preferences file %s: %s", path, result.StderrString())
+	}
+
+	return nil
+}
+
+// GetPreferencesPath returns the path of the pin file of a repository.
+// APT ignores files in preferences.d with dots in their name, other than a
+// .pref extension; sanitizeFilename replaces them.
+func (m *Manager) GetPreferencesPath(name string) string {
+	return filepath.Join(preferencesDir, "igor-"+sanitizeFilename(name))
+}
+
+// repositoryPin returns the Pin line value selecting the packages of a
+// repository: its origin, the host name of its URL, or for a PPA the
+// Origin of its Release file, which is LP-PPA-owner for the PPAs named
+// "ppa" and LP-PPA-owner-name otherwise.
+func repositoryPin(repo pkg.Repository) (string, error) {
+	if ppa, ok := strings.CutPrefix(repo.URL, "ppa:"); ok {
+		owner, name, _ := strings.Cut(ppa, "/")
+		if owner == "" {
+			return "", fmt.Errorf("invalid PPA: %s", repo.URL)
+		}
+		origin := "LP-PPA-" + owner
+		if name != "" && name != "ppa" {
+			origin += "-" + name
+		}
+		return "release o=" + origin, nil
+	}
+
+	u, err := url.Parse(repo.URL)
+	if err != nil || u.Hostname() == "" {
+		return "", fmt.Errorf("cannot pin repository %s: no host in URL %q", repo.Name, repo.URL)
+	}
+	return fmt.Sprintf("origin %q", u.Hostname()), nil
+}
+
+// buildPreferences creates the content of a pin file.
+func buildPreferences(name, pin string, packages []string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Written by igor: prefer %s for the NVIDIA packages\n", name)
+	fmt.Fprintf(&sb, "Package: %s\n", strings.Join(packages, " "))
+	fmt.Fprintf(&sb, "Pin: %s\n", pin)
+	fmt.Fprintf(&sb, "Pin-Priority: %d\n", preferredPinPriority)
+	return sb.String()
+}
+
+// Ensure Manager implements pkg.PreferenceManager interface.
+var _ pkg.PreferenceManager = (*Manager)(nil)
@@ -0,0 +1,76 @@
+package apt
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	igorexec "github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// statusFdArgs makes apt-get report its progress on the standard output,
+// as machine-readable dlstatus and pmstatus lines.
+var statusFdArgs = []string{"-o", "APT::Status-Fd=1"}
+
+// executeElevatedWithProgress runs apt-get like executeElevatedWithEnv,
+// with its status lines parsed into progress passed to report.
+func (m *Manager) executeElevatedWithProgress(ctx context.Context, report func(pkg.Progress), args ...string) *igorexec.Result {
+	envArgs := append(append([]string{}, nonInteractiveEnv...), "apt-get")
+	envArgs = append(envArgs, statusFdArgs...)
+	envArgs = append(envArgs, args...)
+
+	w := pkg.NewProgressWriter(parseStatusLine, report)
+	result := m.executor.StreamElevated(ctx, w, nil, "env", envArgs...)
+	w.Flush()
+	return result
+}
+
+// parseStatusLine parses a line written to APT::Status-Fd:
+//
+//	dlstatus:1:9.0566:Retrieving file 1 of 3
+//	pmstatus:nvidia-driver-550:amd64:42.8571:Unpacking nvidia-driver-550 (amd64)
+//
+// Recent versions of apt qualify the package names of pmstatus lines with
+// their architecture, which older ones leave out. The pseudo package
+// dpkg-exec stands for dpkg as a whole.
+func parseStatusLine(line string) (pkg.Progress, bool) {
+	kind, rest, ok := strings.Cut(line, ":")
+	if !ok {
+		return pkg.Progress{}, false
+	}
+
+	switch kind {
+	case "dlstatus":
+		fields := strings.SplitN(rest, ":", 3)
+		if len(fields) < 2 {
+			return pkg.Progress{}, false
+		}
+		percent, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return pkg.Progress{}, false
+		}
+		return pkg.Progress{Phase: pkg.ProgressDownload, Percent: percent}, true
+
+	case "pmstatus":
+		fields := strings.SplitN(rest, ":", 4)
+		if len(fields) < 2 {
+			return pkg.Progress{}, false
+		}
+		name, fields := fields[0], fields[1:]
+		percent, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil && len(fields) > 1 {
+			// The field after the name is its architecture
+			percent, err = strconv.ParseFloat(fields[1], 64)
+		}
+		if err != nil {
+			return pkg.Progress{}, false
+		}
+		if name == "dpkg-exec" {
+			name = ""
+		}
+		return pkg.Progress{Phase: pkg.ProgressInstall, Package: name, Percent: percent}, true
+	}
+
+	return pkg.Progress{}, false
+}
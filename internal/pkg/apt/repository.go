@@ -20,7 +20,10 @@ const (
 
 // AddRepository adds a new package repository.
 // For PPAs: uses add-apt-repository -y ppa:user/ppa
-// For direct URLs: creates a .list file in /etc/apt/sources.list.d/
+// For direct URLs: creates a .list file in /etc/apt/sources.list.d/, or a
+// deb822 .sources file when the system sources use that format
+// A GPGKey URL is downloaded to a keyring in /etc/apt/keyrings, which the
+// repository references with signed-by.
 func (m *Manager) AddRepository(ctx context.Context, repo pkg.Repository) error {
 	// Check if it's a PPA
 	if strings.HasPrefix(repo.URL, "ppa:") {
@@ -46,23 +49,34 @@ func (m *Manager) addPPA(ctx context.Context, ppa string) error {
 	return nil
 }
 
-// addDirectRepository adds a repository by creating a .list file.
+// addDirectRepository adds a repository by creating a .list file, or a
+// .sources file on systems whose sources are in the deb822 format.
 func (m *Manager) addDirectRepository(ctx context.Context, repo pkg.Repository) error {
-	// Generate filename from repo name
-	filename := sanitizeFilename(repo.Name) + ".list"
-	repoFilePath := filepath.Join(sourcesListDir, filename)
-
-	// Check if file already exists
-	result := m.executor.Execute(ctx, "test", "-f", repoFilePath)
-	if result.ExitCode == 0 {
+	// Check if a file of the repository already exists, in either format
+	if repoFilePath, ok := m.findRepositoryFile(ctx, repo.Name); ok {
 		return pkg.Wrap(pkg.ErrRepositoryExists, fmt.Errorf("repository file already exists: %s", repoFilePath))
 	}
 
-	// Build the sources.list entry
-	entry := buildSourcesListEntry(repo)
+	// Keys given by URL go to the keyring of the repository
+	if isKeyURL(repo.GPGKey) {
+		if err := m.AddGPGKey(ctx, repo.Name, repo.GPGKey); err != nil {
+			return err
+		}
+		repo.GPGKey = m.GetGPGKeyPath(repo.Name)
+	}
+
+	// Build the entry in the format of the system sources
+	var entry, repoFilePath string
+	if m.usesDeb822(ctx) {
+		entry = buildDeb822Entry(repo)
+		repoFilePath = filepath.Join(sourcesListDir, sanitizeFilename(repo.Name)+sourcesExtension)
+	} else {
+		entry = buildSourcesListEntry(repo)
+		repoFilePath = filepath.Join(sourcesListDir, sanitizeFilename(repo.Name)+listExtension)
+	}
 
 	// Write the file using tee with elevated privileges
-	result = m.executor.ExecuteWithInput(ctx, []byte(entry), "sudo", "tee", repoFilePath)
+	result := m.executor.ExecuteWithInput(ctx, []byte(entry), "sudo", "tee", repoFilePath)
 	if result.Failed() {
 		return fmt.Errorf("failed to create repository file: %s", result.StderrString())
 	}
@@ -70,6 +84,59 @@ func (m *Manager) addDirectRepository(ctx context.Context, repo pkg.Repository)
 	return nil
 }
 
+// usesDeb822 reports whether the system sources are in deb822 .sources
+// files, as on fresh installs of Ubuntu 24.04 and Debian 12, rather than in
+// sources.list. New repositories are written in the same format.
+func (m *Manager) usesDeb822(ctx context.Context) bool {
+	result := m.executor.Execute(ctx, "cat", sourcesListPath)
+	if result.Success() {
+		repos, _ := parseSourcesList(result.StdoutString())
+		for _, repo := range repos {
+			if repo.Enabled {
+				return false
+			}
+		}
+	}
+
+	for _, file := range m.listRepositoryFiles(ctx) {
+		if strings.HasSuffix(file, sourcesExtension) {
+			return true
+		}
+	}
+	return false
+}
+
+// listRepositoryFiles returns the .list and .sources files in
+// sources.list.d.
+func (m *Manager) listRepositoryFiles(ctx context.Context) []string {
+	result := m.executor.Execute(ctx, "ls", "-1", sourcesListDir)
+	if result.Failed() {
+		return nil
+	}
+
+	var files []string
+	for _, file := range strings.Split(strings.TrimSpace(result.StdoutString()), "\n") {
+		if strings.HasSuffix(file, listExtension) || strings.HasSuffix(file, sourcesExtension) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// findRepositoryFile returns the .list or .sources file of a repository in
+// sources.list.d, trying the sanitized name first.
+func (m *Manager) findRepositoryFile(ctx context.Context, name string) (string, bool) {
+	for _, base := range []string{sanitizeFilename(name), name} {
+		for _, ext := range []string{listExtension, sourcesExtension} {
+			path := filepath.Join(sourcesListDir, base+ext)
+			if result := m.executor.Execute(ctx, "test", "-f", path); result.ExitCode == 0 {
+				return path, true
+			}
+		}
+	}
+	return "", false
+}
+
 // buildSourcesListEntry creates a sources.list format entry from a Repository.
 func buildSourcesListEntry(repo pkg.Repository) string {
 	var sb strings.Builder
@@ -82,10 +149,17 @@ func buildSourcesListEntry(repo pkg.Repository) string {
 	sb.WriteString(repoType)
 	sb.WriteString(" ")
 
-	// Options (GPG key signing)
+	// Options (GPG key signing, trusted local repositories)
+	var options []string
+	if repo.Trusted {
+		options = append(options, "trusted=yes")
+	}
 	if repo.GPGKey != "" {
+		options = append(options, fmt.Sprintf("signed-by=%s", repo.GPGKey))
+	}
+	if len(options) > 0 {
 		sb.WriteString("[")
-		sb.WriteString(fmt.Sprintf("signed-by=%s", repo.GPGKey))
+		sb.WriteString(strings.Join(options, " "))
 		sb.WriteString("] ")
 	}
 
@@ -117,23 +191,14 @@ func (m *Manager) RemoveRepository(ctx context.Context, name string) error {
 		return m.removePPA(ctx, name)
 	}
 
-	// Try to find and remove the .list file
-	filename := sanitizeFilename(name) + ".list"
-	repoFilePath := filepath.Join(sourcesListDir, filename)
-
-	// Check if file exists
-	result := m.executor.Execute(ctx, "test", "-f", repoFilePath)
-	if result.ExitCode != 0 {
-		// Try without sanitization
-		repoFilePath = filepath.Join(sourcesListDir, name+".list")
-		result = m.executor.Execute(ctx, "test", "-f", repoFilePath)
-		if result.ExitCode != 0 {
-			return pkg.Wrap(pkg.ErrRepositoryNotFound, fmt.Errorf("repository not found: %s", name))
-		}
+	// Find the .list or .sources file
+	repoFilePath, ok := m.findRepositoryFile(ctx, name)
+	if !ok {
+		return pkg.Wrap(pkg.ErrRepositoryNotFound, fmt.Errorf("repository not found: %s", name))
 	}
 
 	// Remove the file
-	result = m.executor.ExecuteElevated(ctx, "rm", "-f", repoFilePath)
+	result := m.executor.ExecuteElevated(ctx, "rm", "-f", repoFilePath)
 	if result.Failed() {
 		return fmt.Errorf("failed to remove repository file: %s", result.StderrString())
 	}
@@ -157,7 +222,10 @@ func (m *Manager) removePPA(ctx context.Context, ppa string) error {
 }
 
 // ListRepositories returns a list of configured repositories.
-// Parses /etc/apt/sources.list and /etc/apt/sources.list.d/*.list files.
+// Parses /etc/apt/sources.list and the /etc/apt/sources.list.d/*.list and
+// deb822 *.sources files. Repositories of a file in sources.list.d are named
+// after the file, so they can be passed to EnableRepository and
+// DisableRepository.
 func (m *Manager) ListRepositories(ctx context.Context) ([]pkg.Repository, error) {
 	var allRepos []pkg.Repository
 
@@ -168,53 +236,74 @@ func (m *Manager) ListRepositories(ctx context.Context) ([]pkg.Repository, error
 		allRepos = append(allRepos, repos...)
 	}
 
-	// Read all .list files in sources.list.d
-	result = m.executor.Execute(ctx, "ls", "-1", sourcesListDir)
-	if result.Success() {
-		files := strings.Split(strings.TrimSpace(result.StdoutString()), "\n")
-		for _, file := range files {
-			if !strings.HasSuffix(file, ".list") {
-				continue
-			}
-			path := filepath.Join(sourcesListDir, file)
-			result := m.executor.Execute(ctx, "cat", path)
-			if result.Success() {
-				repos, _ := parseSourcesList(result.StdoutString())
-				allRepos = append(allRepos, repos...)
-			}
+	// Read all .list and .sources files in sources.list.d
+	for _, file := range m.listRepositoryFiles(ctx) {
+		path := filepath.Join(sourcesListDir, file)
+		result := m.executor.Execute(ctx, "cat", path)
+		if !result.Success() {
+			continue
+		}
+
+		var repos []pkg.Repository
+		ext := filepath.Ext(file)
+		if ext == sourcesExtension {
+			repos = parseDeb822Sources(result.StdoutString())
+		} else {
+			repos, _ = parseSourcesList(result.StdoutString())
 		}
+		for i := range repos {
+			repos[i].Name = strings.TrimSuffix(file, ext)
+		}
+		allRepos = append(allRepos, repos...)
 	}
 
 	return allRepos, nil
 }
 
 // EnableRepository enables a disabled repository.
-// This uncomments the repository line in its configuration file.
+// This uncomments the repository line in its configuration file, or sets
+// "Enabled: yes" in its .sources file.
 func (m *Manager) EnableRepository(ctx context.Context, name string) error {
 	return m.toggleRepository(ctx, name, true)
 }
 
 // DisableRepository disables an enabled repository.
-// This comments out the repository line in its configuration file.
+// This comments out the repository line in its configuration file, or sets
+// "Enabled: no" in its .sources file.
 func (m *Manager) DisableRepository(ctx context.Context, name string) error {
 	return m.toggleRepository(ctx, name, false)
 }
 
+// disabledEntryPrefix marks the entries of a .list file disabled by igor,
+// so that only those are enabled again, and not the entries the user
+// commented out.
+const disabledEntryPrefix = "# igor-disabled: "
+
 // toggleRepository enables or disables a repository by commenting/uncommenting its entry.
+// Every entry of the repository's own .list file is toggled; in the main
+// sources.list, only the entries containing the name are. Entries are
+// disabled with disabledEntryPrefix, and only those, or commented entries
+// containing the name, are enabled again. The stanzas of a .sources file
+// are toggled through their Enabled field.
 func (m *Manager) toggleRepository(ctx context.Context, name string, enable bool) error {
 	// Try to find the repository file
-	filename := sanitizeFilename(name) + ".list"
-	repoPath := filepath.Join(sourcesListDir, filename)
-
-	// Check if file exists
-	result := m.executor.Execute(ctx, "test", "-f", repoPath)
-	if result.ExitCode != 0 {
+	repoPath, ownFile := m.findRepositoryFile(ctx, name)
+	if !ownFile {
 		// Try the main sources.list
 		repoPath = sourcesListPath
 	}
+	if strings.HasSuffix(repoPath, sourcesExtension) {
+		return m.toggleDeb822Repository(ctx, name, repoPath, enable)
+	}
+	isEntry := func(line string) bool {
+		if ownFile {
+			return strings.HasPrefix(strings.TrimSpace(line), "deb")
+		}
+		return containsRepoEntry(line, name)
+	}
 
 	// Read the current content
-	result = m.executor.Execute(ctx, "cat", repoPath)
+	result := m.executor.Execute(ctx, "cat", repoPath)
 	if result.Failed() {
 		return pkg.Wrap(pkg.ErrRepositoryNotFound, fmt.Errorf("cannot read repository file: %s", repoPath))
 	}
@@ -232,16 +321,20 @@ func (m *Manager) toggleRepository(ctx context.Context, name string, enable bool
 		}
 
 		if enable {
-			// Enable: remove leading #
-			if strings.HasPrefix(trimmed, "#") && containsRepoEntry(trimmed[1:], name) {
+			// Enable: remove the marker of igor, or the leading # of an
+			// entry naming the repository
+			if entry, ok := strings.CutPrefix(trimmed, disabledEntryPrefix); ok && isEntry(entry) {
+				lines[i] = entry
+				modified = true
+			} else if strings.HasPrefix(trimmed, "#") && containsRepoEntry(trimmed[1:], name) {
 				lines[i] = strings.TrimPrefix(line, "#")
 				lines[i] = strings.TrimPrefix(lines[i], " ")
 				modified = true
 			}
 		} else {
-			// Disable: add leading #
-			if !strings.HasPrefix(trimmed, "#") && containsRepoEntry(trimmed, name) {
-				lines[i] = "# " + line
+			// Disable: add the marker of igor
+			if !strings.HasPrefix(trimmed, "#") && isEntry(trimmed) {
+				lines[i] = disabledEntryPrefix + line
 				modified = true
 			}
 		}
@@ -264,6 +357,30 @@ func (m *Manager) toggleRepository(ctx context.Context, name string, enable bool
 	return nil
 }
 
+// toggleDeb822Repository enables or disables every stanza of a .sources
+// file.
+func (m *Manager) toggleDeb822Repository(ctx context.Context, name, repoPath string, enable bool) error {
+	result := m.executor.Execute(ctx, "cat", repoPath)
+	if result.Failed() {
+		return pkg.Wrap(pkg.ErrRepositoryNotFound, fmt.Errorf("cannot read repository file: %s", repoPath))
+	}
+
+	newContent, modified := setDeb822Enabled(result.StdoutString(), enable)
+	if !modified {
+		if enable {
+			return pkg.Wrap(pkg.ErrRepositoryNotFound, fmt.Errorf("repository not found or already enabled: %s", name))
+		}
+		return pkg.Wrap(pkg.ErrRepositoryNotFound, fmt.Errorf("repository not found or already disabled: %s", name))
+	}
+
+	result = m.executor.ExecuteWithInput(ctx, []byte(newContent), "sudo", "tee", repoPath)
+	if result.Failed() {
+		return fmt.Errorf("failed to update repository file: %s", result.StderrString())
+	}
+
+	return nil
+}
+
 // containsRepoEntry checks if a line contains a repository entry matching the name.
 func containsRepoEntry(line, name string) bool {
 	line = strings.TrimSpace(line)
@@ -280,8 +397,9 @@ func (m *Manager) RefreshRepositories(ctx context.Context) error {
 }
 
 // AddGPGKey imports a GPG key for repository verification.
-// Uses the modern approach: curl -fsSL URL | gpg --dearmor -o /etc/apt/keyrings/name.gpg
-// Falls back to apt-key add if keyrings directory doesn't exist.
+// Uses the keyring approach: curl -fsSL URL | gpg --dearmor -o /etc/apt/keyrings/name.gpg
+// The key is trusted only by the repositories referencing the keyring with
+// signed-by; the global trust store of apt-key is never used.
 func (m *Manager) AddGPGKey(ctx context.Context, name string, keyURL string) error {
 	// Validate inputs to prevent shell injection
 	if err := validateGPGKeyInputs(name, keyURL); err != nil {
@@ -291,17 +409,16 @@ func (m *Manager) AddGPGKey(ctx context.Context, name string, keyURL string) err
 	// Ensure keyrings directory exists
 	result := m.executor.ExecuteElevated(ctx, "mkdir", "-p", aptKeyringsDir)
 	if result.Failed() {
-		// Fall back to trusted.gpg.d
-		return m.addGPGKeyLegacy(ctx, name, keyURL)
+		return fmt.Errorf("failed to create %s: %s", aptKeyringsDir, result.StderrString())
 	}
 
 	// Download and dearmor the key
-	keyPath := filepath.Join(aptKeyringsDir, sanitizeFilename(name)+".gpg")
+	keyPath := m.GetGPGKeyPath(name)
 
-	// Use a pipeline: curl | gpg --dearmor | sudo tee
+	// Use a pipeline: curl | gpg --dearmor
 	// Since we can't do pipes directly, we'll use a shell command
 	// Inputs have been validated above to prevent injection
-	shellCmd := fmt.Sprintf("curl -fsSL '%s' | gpg --dearmor -o '%s'", keyURL, keyPath)
+	shellCmd := fmt.Sprintf("curl -fsSL '%s' | gpg --batch --yes --dearmor -o '%s'", keyURL, keyPath)
 	result = m.executor.ExecuteElevated(ctx, "sh", "-c", shellCmd)
 
 	if result.Failed() {
@@ -335,24 +452,6 @@ func validateGPGKeyInputs(name, keyURL string) error {
 	return nil
 }
 
-// addGPGKeyLegacy adds a GPG key using the legacy apt-key method.
-func (m *Manager) addGPGKeyLegacy(ctx context.Context, name string, keyURL string) error {
-	// Inputs already validated by AddGPGKey caller
-	// Download and add with apt-key (deprecated but works everywhere)
-	shellCmd := fmt.Sprintf("curl -fsSL '%s' | apt-key add -", keyURL)
-	result := m.executor.ExecuteElevated(ctx, "sh", "-c", shellCmd)
-
-	if result.Failed() {
-		stderr := result.StderrString()
-		if strings.Contains(stderr, "Could not resolve") || strings.Contains(stderr, "Connection refused") {
-			return pkg.Wrap(pkg.ErrNetworkUnavailable, fmt.Errorf("failed to download GPG key: %s", stderr))
-		}
-		return fmt.Errorf("failed to add GPG key: %s", stderr)
-	}
-
-	return nil
-}
-
 // GetGPGKeyPath returns the path where a GPG key should be stored.
 // Useful when adding a repository that needs to reference the key.
 func (m *Manager) GetGPGKeyPath(name string) string {
@@ -0,0 +1,82 @@
+package apt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// SimulateInstall resolves the installation of the packages with
+// apt-get -s, which needs no root privileges. apt-get does not print sizes
+// when simulating, so they are read from apt-cache show for the installed
+// versions and from dpkg-query for the replaced ones; sizes that cannot be
+// read are left unknown.
+func (m *Manager) SimulateInstall(ctx context.Context, opts pkg.InstallOptions, packages ...string) (*pkg.Simulation, error) {
+	if len(packages) == 0 {
+		return pkg.NewSimulation(), nil
+	}
+
+	args := append([]string{"-s"}, m.buildInstallArgs(opts, packages)...)
+	result := m.executor.Execute(ctx, "apt-get", args...)
+	if result.Failed() {
+		stderr := result.StderrString()
+		if strings.Contains(stderr, "Unable to locate package") {
+			return nil, pkg.Wrap(pkg.ErrPackageNotFound, fmt.Errorf("apt-get -s install failed: %s", stderr))
+		}
+		return nil, pkg.Wrap(pkg.ErrSimulationFailed, fmt.Errorf("apt-get -s install failed (exit code %d): %s", result.ExitCode, stderr))
+	}
+
+	sim := parseAptGetSimulate(result.StdoutString())
+	m.addSimulationSizes(ctx, sim)
+	return sim, nil
+}
+
+// addSimulationSizes fills in the package and total sizes of a simulation.
+func (m *Manager) addSimulationSizes(ctx context.Context, sim *pkg.Simulation) {
+	var versions, replaced []string
+	for _, changes := range [][]pkg.PackageChange{sim.Install, sim.Upgrade, sim.Downgrade} {
+		for _, change := range changes {
+			if change.Version != "" {
+				versions = append(versions, change.Name+"="+change.Version)
+			}
+		}
+	}
+	for _, changes := range [][]pkg.PackageChange{sim.Upgrade, sim.Downgrade, sim.Remove} {
+		for _, change := range changes {
+			replaced = append(replaced, change.Name)
+		}
+	}
+
+	if len(versions) > 0 {
+		args := append([]string{"show", "--no-all-versions"}, versions...)
+		result := m.executor.Execute(ctx, "apt-cache", args...)
+		sizes := parseAptCacheShowSizes(result.StdoutString())
+		for _, changes := range [][]pkg.PackageChange{sim.Install, sim.Upgrade, sim.Downgrade} {
+			for i := range changes {
+				size := sizes[strings.SplitN(changes[i].Name, ":", 2)[0]+"="+changes[i].Version]
+				changes[i].Size = size.download
+				changes[i].InstalledSize = size.installed
+				sim.DownloadSize += size.download
+				sim.InstallSize += size.installed
+			}
+		}
+	}
+
+	if len(replaced) > 0 {
+		// dpkg-query fails if a package is unknown, but still prints the others
+		args := append([]string{"-W", "-f=${Package}\t${Installed-Size}\n"}, replaced...)
+		result := m.executor.Execute(ctx, "dpkg-query", args...)
+		sizes := parseDpkgQueryInstalledSizes(result.StdoutString())
+		for _, name := range replaced {
+			sim.InstallSize -= sizes[strings.SplitN(name, ":", 2)[0]]
+		}
+		for i := range sim.Remove {
+			sim.Remove[i].InstalledSize = sizes[strings.SplitN(sim.Remove[i].Name, ":", 2)[0]]
+		}
+	}
+}
+
+// Ensure Manager implements pkg.SimulationManager interface.
+var _ pkg.SimulationManager = (*Manager)(nil)
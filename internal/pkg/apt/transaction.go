@@ -0,0 +1,71 @@
+package apt
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// BeginTransaction starts a transaction.
+// APT has no transactions, so they are emulated: the installed packages and
+// their versions are recorded when the transaction begins, Install and
+// Remove run right away, and a rollback restores the recorded packages.
+func (m *Manager) BeginTransaction(ctx context.Context) error {
+	installed, err := m.ListInstalled(ctx)
+	if err != nil {
+		return pkg.Wrap(pkg.ErrTransactionFailed, fmt.Errorf("failed to record the installed packages: %w", err))
+	}
+	return m.tx.Begin(pkg.InstalledVersions(installed))
+}
+
+// CommitTransaction keeps the changes made during the transaction.
+func (m *Manager) CommitTransaction(ctx context.Context) error {
+	_, err := m.tx.End()
+	return err
+}
+
+// RollbackTransaction reverts the changes made during the transaction.
+// Packages that were removed or changed version are installed again at
+// their recorded version, allowing downgrades, then the packages that were
+// added are removed. Previous versions must still be available from the
+// repositories or the APT cache.
+func (m *Manager) RollbackTransaction(ctx context.Context) error {
+	state, err := m.tx.End()
+	if err != nil {
+		return err
+	}
+
+	installed, err := m.ListInstalled(ctx)
+	if err != nil {
+		return pkg.Wrap(pkg.ErrTransactionFailed, fmt.Errorf("failed to list the installed packages: %w", err))
+	}
+	remove, restore := pkg.RevertPlan(state.Snapshot, pkg.InstalledVersions(installed))
+
+	if len(restore) > 0 {
+		packages := make([]string, 0, len(restore))
+		for _, p := range restore {
+			packages = append(packages, p.Name+"="+p.Version)
+		}
+
+		opts := pkg.NonInteractiveInstallOptions()
+		opts.AllowDowngrade = true
+		if err := m.Install(ctx, opts, packages...); err != nil {
+			return pkg.Wrap(pkg.ErrTransactionFailed, fmt.Errorf("failed to restore packages: %w", err))
+		}
+	}
+
+	if err := m.Remove(ctx, pkg.RemoveOptions{NoConfirm: true}, remove...); err != nil {
+		return pkg.Wrap(pkg.ErrTransactionFailed, fmt.Errorf("failed to remove added packages: %w", err))
+	}
+
+	return nil
+}
+
+// InTransaction returns true if a transaction is in progress.
+func (m *Manager) InTransaction() bool {
+	return m.tx.Active()
+}
+
+// Ensure Manager implements pkg.TransactionalManager interface.
+var _ pkg.TransactionalManager = (*Manager)(nil)
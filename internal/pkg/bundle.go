@@ -0,0 +1,38 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tungetti/igor/internal/exec"
+)
+
+// BundleRoot is an empty temporary directory in which the dependencies of
+// a bundle are resolved, as the package database or root directory of the
+// package manager. Nothing is installed there, so the dependency closure
+// includes the packages already installed on the system creating the
+// bundle, and the bundle can be installed on a minimal system.
+type BundleRoot struct {
+	// Path is the path of the directory.
+	Path string
+
+	executor exec.Executor
+}
+
+// NewBundleRoot creates an empty bundle root with mktemp. The caller
+// removes it with Remove once the bundle is downloaded.
+func NewBundleRoot(ctx context.Context, executor exec.Executor) (*BundleRoot, error) {
+	result := executor.Execute(ctx, "mktemp", "-d", "-t", "igor-bundle.XXXXXXXXXX")
+	path := strings.TrimSpace(result.StdoutString())
+	if result.Failed() || path == "" {
+		return nil, Wrap(ErrBundleFailed, fmt.Errorf("failed to create temporary bundle root: %s", result.StderrString()))
+	}
+	return &BundleRoot{Path: path, executor: executor}, nil
+}
+
+// Remove deletes the bundle root. The package manager writes in it with
+// elevated privileges, so it is removed with them.
+func (r *BundleRoot) Remove(ctx context.Context) {
+	r.executor.ExecuteElevated(ctx, "rm", "-rf", r.Path)
+}
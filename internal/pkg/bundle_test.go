@@ -0,0 +1,35 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/exec"
+)
+
+func TestBundleRoot(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("created and removed", func(t *testing.T) {
+		executor := exec.NewMockExecutor()
+		executor.SetResponse("mktemp", exec.SuccessResult("/tmp/igor-bundle.Xy12Ab34Cd\n"))
+
+		root, err := NewBundleRoot(ctx, executor)
+		require.NoError(t, err)
+		assert.Equal(t, "/tmp/igor-bundle.Xy12Ab34Cd", root.Path)
+		assert.True(t, executor.WasCalledWith("mktemp", "-d", "-t", "igor-bundle.XXXXXXXXXX"))
+
+		root.Remove(ctx)
+		assert.True(t, executor.WasCalledWith("rm", "-rf", "/tmp/igor-bundle.Xy12Ab34Cd"))
+	})
+
+	t.Run("mktemp fails", func(t *testing.T) {
+		executor := exec.NewMockExecutor()
+		executor.SetResponse("mktemp", exec.FailureResult(1, "mktemp: failed to create directory"))
+
+		_, err := NewBundleRoot(ctx, executor)
+		assert.ErrorIs(t, err, ErrBundleFailed)
+	})
+}
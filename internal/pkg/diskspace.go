@@ -0,0 +1,131 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Directories receiving the files of a package transaction.
+const (
+	// PackageCacheDir receives the downloaded package files.
+	PackageCacheDir = "/var/cache"
+
+	// SystemDir receives the files of most packages.
+	SystemDir = "/usr"
+
+	// OptDir receives the files of the packages installed in /opt, such as
+	// the CUDA toolkit of Arch Linux.
+	OptDir = "/opt"
+)
+
+// DiskUsage is the disk space a transaction needs in a directory.
+type DiskUsage struct {
+	// Dir is the directory receiving the files.
+	Dir string `json:"dir"`
+
+	// Required is the disk space needed in bytes.
+	Required int64 `json:"required"`
+
+	// MountPoint is the mount point of the filesystem holding Dir, or empty
+	// if the free space has not been measured.
+	MountPoint string `json:"mount_point,omitempty"`
+
+	// Available is the free space of the filesystem in bytes, when measured.
+	Available int64 `json:"available,omitempty"`
+}
+
+// Measured returns true if the free space of the filesystem is known.
+func (u DiskUsage) Measured() bool {
+	return u.MountPoint != ""
+}
+
+// String returns a description of the usage, such as
+// "/usr: 1.2 GiB needed, 20.0 GiB free on /".
+func (u DiskUsage) String() string {
+	if !u.Measured() {
+		return fmt.Sprintf("%s: %s needed", u.Dir, FormatSize(u.Required))
+	}
+	return fmt.Sprintf("%s: %s needed, %s free on %s", u.Dir, FormatSize(u.Required), FormatSize(u.Available), u.MountPoint)
+}
+
+// DiskUsage returns the disk space the transaction needs in each directory:
+// the download size in PackageCacheDir, and the installed size in OptDir for
+// the packages inOpt returns true for and in SystemDir for the others. The
+// packages in /opt are only told apart when the package manager reports the
+// installed size of each package. Directories that need no space are left
+// out.
+func (s *Simulation) DiskUsage(inOpt func(name string) bool) []DiskUsage {
+	var opt int64
+	if inOpt != nil {
+		for _, changes := range [][]PackageChange{s.Install, s.Upgrade, s.Downgrade} {
+			for _, change := range changes {
+				if inOpt(change.Name) {
+					opt += change.InstalledSize
+				}
+			}
+		}
+	}
+
+	var usage []DiskUsage
+	for _, u := range []DiskUsage{
+		{Dir: PackageCacheDir, Required: s.DownloadSize},
+		{Dir: SystemDir, Required: s.InstallSize - opt},
+		{Dir: OptDir, Required: opt},
+	} {
+		if u.Required > 0 {
+			usage = append(usage, u)
+		}
+	}
+	return usage
+}
+
+// DiskShortage is a filesystem without enough free space for a transaction.
+type DiskShortage struct {
+	// MountPoint is the mount point of the filesystem.
+	MountPoint string
+
+	// Dirs are the directories of the transaction on the filesystem.
+	Dirs []string
+
+	// Required is the disk space needed on the filesystem in bytes.
+	Required int64
+
+	// Available is the free space of the filesystem in bytes.
+	Available int64
+}
+
+// String returns a description of the shortage, such as
+// "/ has 1.0 GiB free, 3.2 GiB needed for /var/cache, /usr".
+func (s DiskShortage) String() string {
+	return fmt.Sprintf("%s has %s free, %s needed for %s",
+		s.MountPoint, FormatSize(s.Available), FormatSize(s.Required), strings.Join(s.Dirs, ", "))
+}
+
+// DiskShortages adds up the measured disk usage of the directories on the
+// same filesystem, and returns the filesystems without enough free space.
+// Directories whose free space was not measured are not checked.
+func DiskShortages(usage []DiskUsage) []DiskShortage {
+	var filesystems []*DiskShortage
+	byMountPoint := make(map[string]*DiskShortage)
+	for _, u := range usage {
+		if !u.Measured() {
+			continue
+		}
+		fs, ok := byMountPoint[u.MountPoint]
+		if !ok {
+			fs = &DiskShortage{MountPoint: u.MountPoint, Available: u.Available}
+			byMountPoint[u.MountPoint] = fs
+			filesystems = append(filesystems, fs)
+		}
+		fs.Dirs = append(fs.Dirs, u.Dir)
+		fs.Required += u.Required
+	}
+
+	var shortages []DiskShortage
+	for _, fs := range filesystems {
+		if fs.Required > fs.Available {
+			shortages = append(shortages, *fs)
+		}
+	}
+	return shortages
+}
@@ -0,0 +1,56 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimulation_DiskUsage(t *testing.T) {
+	sim := NewSimulation()
+	sim.Install = []PackageChange{
+		{Name: "nvidia-utils", InstalledSize: 300 << 20},
+		{Name: "cuda", InstalledSize: 4 << 30},
+	}
+	sim.DownloadSize = 2 << 30
+	sim.InstallSize = 300<<20 + 4<<30
+
+	inOpt := func(name string) bool { return name == "cuda" }
+	assert.Equal(t, []DiskUsage{
+		{Dir: PackageCacheDir, Required: 2 << 30},
+		{Dir: SystemDir, Required: 300 << 20},
+		{Dir: OptDir, Required: 4 << 30},
+	}, sim.DiskUsage(inOpt))
+
+	// Without a way to tell the packages in /opt, all goes to /usr
+	assert.Equal(t, []DiskUsage{
+		{Dir: PackageCacheDir, Required: 2 << 30},
+		{Dir: SystemDir, Required: 300<<20 + 4<<30},
+	}, sim.DiskUsage(nil))
+
+	// Transactions freeing space need none
+	assert.Empty(t, (&Simulation{InstallSize: -1 << 20}).DiskUsage(nil))
+}
+
+func TestDiskShortages(t *testing.T) {
+	usage := []DiskUsage{
+		{Dir: PackageCacheDir, Required: 2 << 30, MountPoint: "/", Available: 3 << 30},
+		{Dir: SystemDir, Required: 2 << 30, MountPoint: "/", Available: 3 << 30},
+		{Dir: OptDir, Required: 4 << 30, MountPoint: "/opt", Available: 5 << 30},
+		{Dir: "/srv", Required: 1 << 40},
+	}
+
+	shortages := DiskShortages(usage)
+	assert.Equal(t, []DiskShortage{
+		{MountPoint: "/", Dirs: []string{PackageCacheDir, SystemDir}, Required: 4 << 30, Available: 3 << 30},
+	}, shortages)
+	assert.Equal(t, "/ has 3.0 GiB free, 4.0 GiB needed for /var/cache, /usr", shortages[0].String())
+
+	assert.Empty(t, DiskShortages(usage[2:]))
+}
+
+func TestDiskUsage_String(t *testing.T) {
+	assert.Equal(t, "/usr: 1.5 GiB needed", DiskUsage{Dir: SystemDir, Required: 3 << 29}.String())
+	assert.Equal(t, "/opt: 4.0 GiB needed, 20.0 GiB free on /",
+		DiskUsage{Dir: OptDir, Required: 4 << 30, MountPoint: "/", Available: 20 << 30}.String())
+}
@@ -0,0 +1,54 @@
+package dnf
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// DownloadBundle downloads the packages and their dependencies into dir and
+// creates the repository metadata with createrepo_c.
+// dnf download --alldeps includes the dependencies that are already
+// installed, so the bundle can be installed on a minimal system of the same
+// release. createrepo_c is provided by the createrepo_c package.
+func (m *Manager) DownloadBundle(ctx context.Context, dir string, packages ...string) error {
+	if len(packages) == 0 {
+		return nil
+	}
+
+	args := []string{"download", "--resolve", "--alldeps", "--destdir", dir}
+	args = append(args, packages...)
+	result := m.executor.ExecuteElevated(ctx, "dnf", args...)
+	if result.Failed() {
+		stderr := result.StderrString()
+		if strings.Contains(stderr, "Failed to download") || strings.Contains(stderr, "Cannot download") {
+			return pkg.Wrap(pkg.ErrNetworkUnavailable, fmt.Errorf("dnf download failed: %s", stderr))
+		}
+		return pkg.Wrap(pkg.ErrBundleFailed, fmt.Errorf("dnf download failed (exit code %d): %s", result.ExitCode, stderr))
+	}
+
+	result = m.executor.ExecuteElevated(ctx, "createrepo_c", dir)
+	if result.Failed() {
+		return pkg.Wrap(pkg.ErrBundleFailed, fmt.Errorf("createrepo_c failed (exit code %d): %s", result.ExitCode, result.StderrString()))
+	}
+
+	return nil
+}
+
+// BundleRepository returns the local repository serving the bundle in dir.
+// The packages keep their signatures, but the key may not be imported on
+// the target system, so GPG checks are disabled.
+func (m *Manager) BundleRepository(dir string) pkg.Repository {
+	return pkg.Repository{
+		Name:    pkg.BundleRepositoryName,
+		URL:     "file://" + dir,
+		Enabled: true,
+		Type:    "rpm",
+		Trusted: true,
+	}
+}
+
+// Ensure Manager implements pkg.BundleManager interface.
+var _ pkg.BundleManager = (*Manager)(nil)
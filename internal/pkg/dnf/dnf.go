@@ -19,6 +19,8 @@ import (
 type Manager struct {
 	executor  igorexec.Executor
 	privilege *privilege.Manager
+	locks     *pkg.LockChecker
+	tx        pkg.Transaction
 }
 
 // NewManager creates a new DNF package manager.
@@ -28,6 +30,7 @@ func NewManager(executor igorexec.Executor, priv *privilege.Manager) *Manager {
 	return &Manager{
 		executor:  executor,
 		privilege: priv,
+		locks:     pkg.NewLockChecker(executor, "dnf", dnfLockFiles...),
 	}
 }
 
@@ -48,14 +51,25 @@ func (m *Manager) IsAvailable() bool {
 }
 
 // Install installs one or more packages using dnf install.
-// Uses -y for non-interactive operation.
+// Uses -y for non-interactive operation. During a transaction the
+// packages are queued until it is committed. If opts.Progress is set, the
+// download and installation progress is read from the output of dnf as it
+// runs.
 func (m *Manager) Install(ctx context.Context, opts pkg.InstallOptions, packages ...string) error {
 	if len(packages) == 0 {
 		return nil
 	}
+	if m.tx.QueueInstall(opts, packages...) {
+		return nil
+	}
 
 	args := m.buildInstallArgs(opts, packages)
-	result := m.executor.ExecuteElevated(ctx, "dnf", args...)
+	var result *igorexec.Result
+	if opts.Progress != nil {
+		result = m.executeElevatedWithProgress(ctx, opts.Progress, args...)
+	} else {
+		result = m.executor.ExecuteElevated(ctx, "dnf", args...)
+	}
 
 	if result.Failed() {
 		stderr := result.StderrString()
@@ -97,17 +111,26 @@ func (m *Manager) buildInstallArgs(opts pkg.InstallOptions, packages []string) [
 	if opts.SkipVerify {
 		args = append(args, "--nogpgcheck")
 	}
+	if opts.Repository != "" {
+		args = append(args, "--repo="+sanitizeRepoID(opts.Repository))
+	}
 
 	args = append(args, packages...)
 	return args
 }
 
 // Remove removes one or more packages from the system.
-// Uses -y for non-interactive operation.
+// Uses -y for non-interactive operation. During a transaction the
+// packages are queued until it is committed. If opts.Progress is set, the
+// download and installation progress is read from the output of dnf as it
+// runs.
 func (m *Manager) Remove(ctx context.Context, opts pkg.RemoveOptions, packages ...string) error {
 	if len(packages) == 0 {
 		return nil
 	}
+	if m.tx.QueueRemove(packages...) {
+		return nil
+	}
 
 	args := []string{"remove", "-y"}
 	args = append(args, packages...)
@@ -141,12 +164,16 @@ func (m *Manager) Remove(ctx context.Context, opts pkg.RemoveOptions, packages .
 
 // Update updates the package database using dnf check-update.
 // Note: dnf check-update returns exit code 100 when updates are available (not an error).
+// If opts.Repository is set, only that repository is refreshed.
 func (m *Manager) Update(ctx context.Context, opts pkg.UpdateOptions) error {
 	args := []string{"check-update"}
 
 	if opts.Quiet {
 		args = append(args, "-q")
 	}
+	if opts.Repository != "" {
+		args = append(args, "--repo="+sanitizeRepoID(opts.Repository))
+	}
 
 	result := m.executor.ExecuteElevated(ctx, "dnf", args...)
 
@@ -3,6 +3,7 @@ package dnf
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -1234,3 +1235,952 @@ func TestManager_AddRPMFusionEL_NoRepos(t *testing.T) {
 
 	// Should not call any command for RPM Fusion (only EPEL might be called but it's okay if it fails)
 }
+
+// =============================================================================
+// Hold Tests
+// =============================================================================
+
+const testDnfConf = `[main]
+gpgcheck=1
+installonly_limit=3
+exclude=kernel*
+`
+
+func TestManager_Hold_Versionlock(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("dnf", exec.SuccessResult(""))
+
+	err := mgr.Hold(context.Background(), "akmod-nvidia", "xorg-x11-drv-nvidia")
+	require.NoError(t, err)
+
+	assert.True(t, mockExec.WasCalledWith("dnf", "versionlock", "list"))
+	assert.True(t, mockExec.WasCalledWith("dnf", "versionlock", "add", "akmod-nvidia", "xorg-x11-drv-nvidia"))
+	assert.True(t, mockExec.LastCall().Elevated)
+	assert.False(t, mockExec.WasCalled("tee"))
+}
+
+func TestManager_Hold_ExcludeFallback_WriteFailure(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("dnf", exec.FailureResult(1, "No such command: versionlock."))
+	mockExec.SetResponse("cat", exec.SuccessResult(testDnfConf))
+	mockExec.SetResponse("sudo", exec.FailureResult(1, "read-only file system"))
+
+	err := mgr.Hold(context.Background(), "akmod-nvidia")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, pkg.ErrHoldFailed)
+	assert.Contains(t, err.Error(), "read-only file system")
+}
+
+func TestManager_Hold_ExcludeFallback(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("dnf", exec.FailureResult(1, "No such command: versionlock."))
+	mockExec.SetResponse("cat", exec.SuccessResult(testDnfConf))
+
+	err := mgr.Hold(context.Background(), "akmod-nvidia", "xorg-x11-drv-nvidia")
+	require.NoError(t, err)
+
+	call := mockExec.LastCall()
+	assert.Equal(t, "sudo", call.Command)
+	assert.Equal(t, []string{"tee", dnfConfPath}, call.Args)
+	assert.Contains(t, string(call.Input), "exclude=kernel* akmod-nvidia xorg-x11-drv-nvidia\n")
+	assert.Contains(t, string(call.Input), "installonly_limit=3\n")
+}
+
+func TestManager_Hold_ExcludeFallback_AlreadyHeld(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("dnf", exec.FailureResult(1, "No such command: versionlock."))
+	mockExec.SetResponse("cat", exec.SuccessResult(testDnfConf))
+
+	err := mgr.Hold(context.Background(), "kernel*")
+	require.NoError(t, err)
+	assert.False(t, mockExec.WasCalled("sudo"))
+}
+
+func TestManager_Hold_ExcludeFallback_ReadFailure(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("dnf", exec.FailureResult(1, "No such command: versionlock."))
+	mockExec.SetResponse("cat", exec.FailureResult(1, "Permission denied"))
+
+	err := mgr.Hold(context.Background(), "akmod-nvidia")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, pkg.ErrHoldFailed)
+}
+
+func TestManager_Unhold_Versionlock(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("dnf", exec.SuccessResult("akmod-nvidia-3:550.54.14-1.fc39.*\n"))
+	mockExec.SetResponse("cat", exec.SuccessResult(testDnfConf))
+
+	err := mgr.Unhold(context.Background(), "akmod-nvidia", "xorg-x11-drv-nvidia")
+	require.NoError(t, err)
+
+	assert.True(t, mockExec.WasCalledWith("dnf", "versionlock", "delete", "akmod-nvidia"))
+	assert.False(t, mockExec.WasCalled("sudo"))
+}
+
+func TestManager_Unhold_Exclude(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("dnf", exec.FailureResult(1, "No such command: versionlock."))
+	mockExec.SetResponse("cat", exec.SuccessResult("[main]\nexclude=akmod-nvidia\ngpgcheck=1\n"))
+
+	err := mgr.Unhold(context.Background(), "akmod-nvidia")
+	require.NoError(t, err)
+
+	call := mockExec.LastCall()
+	assert.Equal(t, "sudo", call.Command)
+	assert.Equal(t, "[main]\ngpgcheck=1\n", string(call.Input))
+}
+
+func TestManager_ListHeld_Dnf(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("dnf", exec.SuccessResult("Last metadata expiration check: 0:10:00 ago.\nakmod-nvidia-3:550.54.14-1.fc39.*\n"))
+	mockExec.SetResponse("cat", exec.SuccessResult("[main]\nexclude=kernel*, akmod-nvidia\n"))
+
+	held, err := mgr.ListHeld(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"akmod-nvidia", "kernel*"}, held)
+}
+
+func TestParseVersionlockList(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected []string
+	}{
+		{"empty", "", []string{}},
+		{"dnf4", "akmod-nvidia-3:550.54.14-1.fc39.*\nxorg-x11-drv-nvidia-cuda-libs-3:550.54.14-1.fc39.x86_64\n", []string{"akmod-nvidia", "xorg-x11-drv-nvidia-cuda-libs"}},
+		{"yum", "Loaded plugins: fastestmirror, versionlock\n0:nvidia-driver-550.54.14-1.el7.*\nversionlock list done\n", []string{"nvidia-driver"}},
+		{"dnf5", "# Added by 'versionlock add' command on 2024-03-01 10:00:00\nPackage name: akmod-nvidia\nevr = 3:550.54.14-1.fc39\n", []string{"akmod-nvidia"}},
+		{"exclusion", "!kernel-6.7.5-200.fc39.*\n", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseVersionlockList(tt.output))
+		})
+	}
+}
+
+func TestSetExcludes(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		add      []string
+		remove   []string
+		expected string
+		changed  bool
+	}{
+		{
+			name:     "add to existing line",
+			content:  "[main]\nexclude=kernel*\n",
+			add:      []string{"akmod-nvidia"},
+			expected: "[main]\nexclude=kernel* akmod-nvidia\n",
+			changed:  true,
+		},
+		{
+			name:     "add new line",
+			content:  "[main]\ngpgcheck=1\n\n[updates]\nenabled=1\n",
+			add:      []string{"akmod-nvidia"},
+			expected: "[main]\ngpgcheck=1\nexclude=akmod-nvidia\n\n[updates]\nenabled=1\n",
+			changed:  true,
+		},
+		{
+			name:     "add main section",
+			content:  "",
+			add:      []string{"akmod-nvidia"},
+			expected: "[main]\nexclude=akmod-nvidia\n",
+			changed:  true,
+		},
+		{
+			name:     "add main section after others",
+			content:  "[updates]\nenabled=1\n",
+			add:      []string{"akmod-nvidia"},
+			expected: "[updates]\nenabled=1\n\n[main]\nexclude=akmod-nvidia\n",
+			changed:  true,
+		},
+		{
+			name:     "already excluded",
+			content:  "[main]\nexcludepkgs=akmod-nvidia\n",
+			add:      []string{"akmod-nvidia"},
+			expected: "[main]\nexcludepkgs=akmod-nvidia\n",
+			changed:  false,
+		},
+		{
+			name:     "remove keeps other entries",
+			content:  "[main]\nexclude=kernel*,akmod-nvidia\n",
+			remove:   []string{"akmod-nvidia"},
+			expected: "[main]\nexclude=kernel*\n",
+			changed:  true,
+		},
+		{
+			name:     "remove ignores other sections",
+			content:  "[main]\n[updates]\nexclude=akmod-nvidia\n",
+			remove:   []string{"akmod-nvidia"},
+			expected: "[main]\n[updates]\nexclude=akmod-nvidia\n",
+			changed:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, changed := setExcludes(tt.content, tt.add, tt.remove)
+			assert.Equal(t, tt.expected, content)
+			assert.Equal(t, tt.changed, changed)
+		})
+	}
+}
+
+// =============================================================================
+// Lock Tests
+// =============================================================================
+
+func TestManager_LockHolder(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("find", exec.SuccessResult("/proc/1500/fd/7 /usr/lib/sysimage/rpm/.rpm.lock\n"))
+	mockExec.SetResponse("cat", exec.SuccessResult("packagekitd\n"))
+
+	holder, err := mgr.LockHolder(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, holder)
+	assert.Equal(t, "packagekitd (pid 1500)", holder.String())
+
+	locked, err := mgr.IsLocked(context.Background())
+	require.NoError(t, err)
+	assert.True(t, locked)
+
+	err = mgr.AcquireLock(context.Background())
+	assert.ErrorIs(t, err, pkg.ErrLockAcquireFailed)
+}
+
+func TestManager_IsLocked_Free(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("find", exec.SuccessResult(""))
+
+	locked, err := mgr.IsLocked(context.Background())
+	require.NoError(t, err)
+	assert.False(t, locked)
+
+	calls := mockExec.Calls()
+	require.Len(t, calls, 1)
+	for _, path := range []string{"/var/lib/dnf/rpmdb_lock.pid", "/usr/lib/sysimage/rpm/.rpm.lock", "/var/lib/rpm/.rpm.lock"} {
+		assert.Contains(t, calls[0].Args, path)
+	}
+	assert.NoError(t, mgr.AcquireLock(context.Background()))
+}
+
+// =============================================================================
+// History Tests
+// =============================================================================
+
+const testDnfHistoryList = `ID     | Command line                 | Date and time    | Action(s)      | Altered
+-------------------------------------------------------------------------------------
+    12 | install akmod-nvidia         | 2024-01-15 10:23 | Install        |   45 EE
+    11 | upgrade                      | 2024-01-10 09:00 | I, U           |  120
+`
+
+const testDnfHistoryInfo = `Transaction ID : 11
+Begin time     : Wed 10 Jan 2024 09:00:12 AM UTC
+Begin rpmdb    : 1432:aa
+End time       : Wed 10 Jan 2024 09:03:40 AM UTC (208 seconds)
+User           : root <root>
+Return-Code    : Success
+Releasever     : 39
+Command Line   : upgrade
+Comment        :
+Transaction performed with:
+    Installed     dnf-4.18.2-1.fc39.noarch       @updates
+Packages Altered:
+    Upgrade  xorg-x11-drv-nvidia-3:545.29.06-2.fc39.x86_64 @rpmfusion-nonfree-nvidia-driver
+    Upgraded xorg-x11-drv-nvidia-3:545.29.06-1.fc39.x86_64 @@System
+    Install  kernel-6.6.9-200.fc39.x86_64                  @updates
+-------------------------------------------------------------------------------
+Transaction ID : 12
+Begin time     : Mon 15 Jan 2024 10:23:45 AM UTC
+End time       : Mon 15 Jan 2024 10:24:10 AM UTC (25 seconds)
+User           : root <root>
+Return-Code    : Failure: 1
+Command Line   : install akmod-nvidia
+Packages Altered:
+ ** Install akmod-nvidia-3:550.54.14-1.fc39.x86_64 @rpmfusion-nonfree-nvidia-driver
+    Dep-Install kmodtool-1.1-7.fc39.noarch         @fedora
+Scriptlet output:
+   1 akmods: building modules
+`
+
+func TestParseHistoryIDs(t *testing.T) {
+	assert.Equal(t, []string{"12", "11"}, parseHistoryIDs(testDnfHistoryList))
+
+	dnf5 := "ID Command line             Date and time       Action(s) Altered\n" +
+		" 3 dnf5 install akmod-nvidia 2024-01-15 10:23:45                 4\n"
+	assert.Equal(t, []string{"3"}, parseHistoryIDs(dnf5))
+	assert.Empty(t, parseHistoryIDs("No transactions\n"))
+}
+
+func TestParseHistoryInfo(t *testing.T) {
+	entries := parseHistoryInfo(testDnfHistoryInfo)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "11", entries[0].ID)
+	assert.True(t, entries[0].Success)
+	assert.Equal(t, "upgrade", entries[0].Details)
+	assert.Equal(t, "upgrade, install", entries[0].Operation)
+	assert.Equal(t, []string{"xorg-x11-drv-nvidia", "kernel"}, entries[0].Packages)
+	assert.NotZero(t, entries[0].Timestamp)
+
+	assert.Equal(t, "12", entries[1].ID)
+	assert.False(t, entries[1].Success)
+	assert.Equal(t, "install", entries[1].Operation)
+	assert.Equal(t, []string{"akmod-nvidia", "kmodtool"}, entries[1].Packages)
+	assert.Greater(t, entries[1].Timestamp, entries[0].Timestamp)
+}
+
+func TestParseHistoryInfo_DNF5(t *testing.T) {
+	output := `Transaction ID : 3
+Start time     : 2024-01-15 10:23:45
+End time       : 2024-01-15 10:24:10
+User           : 0 root
+Status         : Ok
+Description    : dnf5 install akmod-nvidia
+Packages altered:
+  Action   Package                                Reason     Repository
+  Install  akmod-nvidia-3:550.54.14-1.fc40.x86_64 User       rpmfusion-nonfree
+  Replaced xorg-x11-drv-nvidia-3:550.40.07-1.fc40.x86_64 User @System
+`
+	entries := parseHistoryInfo(output)
+	require.Len(t, entries, 1)
+	assert.True(t, entries[0].Success)
+	assert.Equal(t, "dnf5 install akmod-nvidia", entries[0].Details)
+	assert.Equal(t, "install", entries[0].Operation)
+	assert.Equal(t, []string{"akmod-nvidia", "xorg-x11-drv-nvidia"}, entries[0].Packages)
+
+	expected := time.Date(2024, 1, 15, 10, 23, 45, 0, time.Local)
+	assert.Equal(t, expected.Unix(), entries[0].Timestamp)
+}
+
+func TestManager_History(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("dnf", exec.SuccessResult(testDnfHistoryList))
+
+	// The mock answers dnf history list and info with the same output, so
+	// only check the commands and that entries come newest first.
+	_, err := mgr.History(context.Background(), 1)
+	require.NoError(t, err)
+	assert.True(t, mockExec.WasCalledWith("dnf", "history", "list"))
+	assert.True(t, mockExec.WasCalledWith("dnf", "history", "info", "12"))
+
+	mockExec.SetResponse("dnf", exec.SuccessResult(testDnfHistoryList+testDnfHistoryInfo))
+	entries, err := mgr.History(context.Background(), 2)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "12", entries[0].ID)
+	assert.Equal(t, "11", entries[1].ID)
+	assert.True(t, mockExec.WasCalledWith("dnf", "history", "info", "12", "11"))
+}
+
+func TestManager_History_Empty(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("dnf", exec.SuccessResult("No transactions\n"))
+
+	entries, err := mgr.History(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	assert.Equal(t, 1, mockExec.CallCount())
+}
+
+func TestManager_History_Failure(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("dnf", exec.FailureResult(1, "history database is locked"))
+
+	_, err := mgr.History(context.Background(), 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "history database is locked")
+}
+
+func TestManager_Undo(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	require.NoError(t, mgr.Undo(context.Background(), "12"))
+	assert.True(t, mockExec.WasCalledWith("dnf", "history", "undo", "-y", "12"))
+	assert.True(t, mockExec.LastCall().Elevated)
+
+	require.NoError(t, mgr.Redo(context.Background(), "12"))
+	assert.True(t, mockExec.WasCalledWith("dnf", "history", "redo", "-y", "12"))
+}
+
+func TestManager_Undo_Errors(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	err := mgr.Undo(context.Background(), "--help")
+	assert.ErrorIs(t, err, pkg.ErrHistoryFailed)
+	assert.Equal(t, 0, mockExec.CallCount())
+
+	mockExec.SetResponse("dnf", exec.FailureResult(1, "no package to undo"))
+	err = mgr.Undo(context.Background(), "12")
+	assert.ErrorIs(t, err, pkg.ErrHistoryFailed)
+	assert.Contains(t, err.Error(), "no package to undo")
+}
+
+// =============================================================================
+// Transaction Tests
+// =============================================================================
+
+func TestManager_Transaction_Commit(t *testing.T) {
+	mgr, mockExec := setupTest()
+	ctx := context.Background()
+
+	require.NoError(t, mgr.BeginTransaction(ctx))
+	assert.True(t, mgr.InTransaction())
+
+	opts := pkg.NonInteractiveInstallOptions()
+	require.NoError(t, mgr.Install(ctx, opts, "akmod-nvidia", "xorg-x11-drv-nvidia"))
+	require.NoError(t, mgr.Install(ctx, opts, "xorg-x11-drv-nvidia-cuda"))
+	require.NoError(t, mgr.Remove(ctx, pkg.DefaultRemoveOptions(), "nvidia-settings"))
+	// Nothing runs until the transaction is committed
+	assert.Equal(t, 0, mockExec.CallCount())
+
+	require.NoError(t, mgr.CommitTransaction(ctx))
+	assert.False(t, mgr.InTransaction())
+
+	require.Equal(t, 1, mockExec.CallCount())
+	call := mockExec.LastCall()
+	assert.Equal(t, "dnf", call.Command)
+	assert.Equal(t, []string{"shell", "-y", "--setopt=strict=True"}, call.Args)
+	assert.Equal(t, "install akmod-nvidia xorg-x11-drv-nvidia xorg-x11-drv-nvidia-cuda\nremove nvidia-settings\nrun\n", string(call.Input))
+}
+
+func TestManager_Transaction_CommitEmpty(t *testing.T) {
+	mgr, mockExec := setupTest()
+	ctx := context.Background()
+
+	require.NoError(t, mgr.BeginTransaction(ctx))
+	require.NoError(t, mgr.CommitTransaction(ctx))
+	assert.Equal(t, 0, mockExec.CallCount())
+}
+
+func TestManager_Transaction_CommitOptions(t *testing.T) {
+	mgr, mockExec := setupTest()
+	ctx := context.Background()
+
+	require.NoError(t, mgr.BeginTransaction(ctx))
+	require.NoError(t, mgr.Install(ctx, pkg.InstallOptions{AllowDowngrade: true, SkipVerify: true}, "akmod-nvidia"))
+	require.NoError(t, mgr.CommitTransaction(ctx))
+
+	assert.Equal(t, []string{"shell", "-y", "--setopt=strict=True", "--allowerasing", "--nogpgcheck"}, mockExec.LastCall().Args)
+}
+
+func TestManager_Transaction_CommitFailure(t *testing.T) {
+	t.Run("exit code", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		ctx := context.Background()
+		mockExec.SetResponse("dnf", exec.FailureResult(1, "Error: Failed to download metadata"))
+
+		require.NoError(t, mgr.BeginTransaction(ctx))
+		require.NoError(t, mgr.Install(ctx, pkg.InstallOptions{}, "akmod-nvidia"))
+		err := mgr.CommitTransaction(ctx)
+
+		assert.ErrorIs(t, err, pkg.ErrTransactionFailed)
+		assert.False(t, mgr.InTransaction())
+	})
+
+	t.Run("error in output", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		ctx := context.Background()
+		mockExec.SetResponse("dnf", exec.SuccessResultWithStderr("", "Error: Unable to find a match: akmod-nvidia-cuda"))
+
+		require.NoError(t, mgr.BeginTransaction(ctx))
+		require.NoError(t, mgr.Install(ctx, pkg.InstallOptions{}, "akmod-nvidia", "akmod-nvidia-cuda"))
+		err := mgr.CommitTransaction(ctx)
+
+		assert.ErrorIs(t, err, pkg.ErrTransactionFailed)
+		assert.Contains(t, err.Error(), "Unable to find a match")
+	})
+
+	t.Run("lock", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		ctx := context.Background()
+		mockExec.SetResponse("dnf", exec.FailureResult(1, "Waiting for process with pid 812 to finish... another copy is running"))
+
+		require.NoError(t, mgr.BeginTransaction(ctx))
+		require.NoError(t, mgr.Install(ctx, pkg.InstallOptions{}, "akmod-nvidia"))
+		assert.ErrorIs(t, mgr.CommitTransaction(ctx), pkg.ErrLockAcquireFailed)
+	})
+}
+
+func TestManager_Transaction_Rollback(t *testing.T) {
+	mgr, mockExec := setupTest()
+	ctx := context.Background()
+
+	require.NoError(t, mgr.BeginTransaction(ctx))
+	require.NoError(t, mgr.Install(ctx, pkg.InstallOptions{}, "akmod-nvidia"))
+	require.NoError(t, mgr.RollbackTransaction(ctx))
+
+	assert.False(t, mgr.InTransaction())
+	assert.Equal(t, 0, mockExec.CallCount())
+
+	// Operations run right away once the transaction has ended
+	require.NoError(t, mgr.Install(ctx, pkg.InstallOptions{}, "akmod-nvidia"))
+	assert.True(t, mockExec.WasCalledWith("dnf", "install", "-y", "akmod-nvidia"))
+}
+
+func TestManager_Transaction_NotInProgress(t *testing.T) {
+	mgr, _ := setupTest()
+	ctx := context.Background()
+
+	assert.ErrorIs(t, mgr.CommitTransaction(ctx), pkg.ErrTransactionFailed)
+	assert.ErrorIs(t, mgr.RollbackTransaction(ctx), pkg.ErrTransactionFailed)
+
+	require.NoError(t, mgr.BeginTransaction(ctx))
+	assert.ErrorIs(t, mgr.BeginTransaction(ctx), pkg.ErrTransactionFailed)
+}
+
+// =============================================================================
+// Bundle Tests
+// =============================================================================
+
+func TestManager_DownloadBundle(t *testing.T) {
+	mgr, mockExec := setupTest()
+	ctx := context.Background()
+
+	err := mgr.DownloadBundle(ctx, "/srv/bundle", "akmod-nvidia", "xorg-x11-drv-nvidia")
+	require.NoError(t, err)
+
+	calls := mockExec.Calls()
+	require.Len(t, calls, 2)
+	assert.Equal(t, "dnf", calls[0].Command)
+	assert.Equal(t, []string{"download", "--resolve", "--alldeps", "--destdir", "/srv/bundle", "akmod-nvidia", "xorg-x11-drv-nvidia"}, calls[0].Args)
+	assert.Equal(t, "createrepo_c", calls[1].Command)
+	assert.Equal(t, []string{"/srv/bundle"}, calls[1].Args)
+}
+
+func TestManager_DownloadBundle_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("no packages", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		require.NoError(t, mgr.DownloadBundle(ctx, "/srv/bundle"))
+		assert.Equal(t, 0, mockExec.CallCount())
+	})
+
+	t.Run("network", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		mockExec.SetResponse("dnf", exec.FailureResult(1, "Error: Failed to download metadata for repo 'fedora'"))
+		err := mgr.DownloadBundle(ctx, "/srv/bundle", "akmod-nvidia")
+		assert.ErrorIs(t, err, pkg.ErrNetworkUnavailable)
+	})
+
+	t.Run("createrepo fails", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		mockExec.SetResponse("createrepo_c", exec.FailureResult(127, "createrepo_c: command not found"))
+		err := mgr.DownloadBundle(ctx, "/srv/bundle", "akmod-nvidia")
+		assert.ErrorIs(t, err, pkg.ErrBundleFailed)
+	})
+}
+
+func TestManager_BundleRepository(t *testing.T) {
+	mgr, _ := setupTest()
+
+	repo := mgr.BundleRepository("/srv/bundle")
+	assert.Equal(t, pkg.BundleRepositoryName, repo.Name)
+	content := buildRepoFileContent(repo)
+	assert.Contains(t, content, "[igor-bundle]\n")
+	assert.Contains(t, content, "baseurl=file:///srv/bundle\n")
+	assert.Contains(t, content, "gpgcheck=0\n")
+}
+
+func TestManager_Repository_Restricted(t *testing.T) {
+	mgr, mockExec := setupTest()
+	ctx := context.Background()
+
+	require.NoError(t, mgr.Update(ctx, pkg.UpdateOptions{Repository: "igor-bundle"}))
+	assert.Equal(t, []string{"check-update", "--repo=igor-bundle"}, mockExec.LastCall().Args)
+
+	require.NoError(t, mgr.Install(ctx, pkg.InstallOptions{Repository: "igor-bundle"}, "akmod-nvidia"))
+	assert.Equal(t, []string{"install", "-y", "--repo=igor-bundle", "akmod-nvidia"}, mockExec.LastCall().Args)
+}
+
+// =============================================================================
+// Simulation Tests
+// =============================================================================
+
+const testDnfAssumeno = `Last metadata expiration check: 0:12:01 ago on Mon 15 Jan 2024 10:23:45 AM CET.
+Dependencies resolved.
+================================================================================
+ Package                      Arch    Version           Repository         Size
+================================================================================
+Installing:
+ akmod-nvidia                 x86_64  3:550.120-1.fc40  rpmfusion-nonfree  40 k
+ xorg-x11-drv-nvidia          x86_64  3:550.120-1.fc40  rpmfusion-nonfree 2.9 M
+     replacing  xorg-x11-drv-nouveau.x86_64 1:1.0.17-8.fc40
+Upgrading:
+ mesa-libGL                   x86_64  24.1.7-1.fc40     updates           167 k
+Installing dependencies:
+ xorg-x11-drv-nvidia-kmodsrc-with-a-very-long-name
+                              x86_64  3:550.120-1.fc40  rpmfusion-nonfree  70 M
+Removing dependent packages:
+ nvidia-settings-legacy       x86_64  470.256-1.fc40    @rpmfusion        1.2 M
+Downgrading:
+ libglvnd                     x86_64  1:1.7.0-4.fc40    fedora            133 k
+
+Transaction Summary
+================================================================================
+Install    3 Packages
+Upgrade    1 Package
+Remove     1 Package
+Downgrade  1 Package
+
+Total download size: 73 M
+Installed size: 250 M
+Operation aborted.
+`
+
+const testDnf5Assumeno = `Updating and loading repositories:
+Repositories loaded.
+Package                 Arch   Version           Repository      Size
+Installing:
+ nvidia-driver          x86_64 3:550.120-1.fc41  rpmfusion  40.0 KiB
+Upgrading:
+ mesa-libGL             x86_64 24.2.4-1.fc41     updates   400.0 KiB
+   replacing mesa-libGL x86_64 24.2.3-1.fc41     updates   390.0 KiB
+
+Transaction Summary:
+ Installing:         1 package
+ Upgrading:          1 package
+ Replacing:          1 package
+
+Total size of inbound packages is 2 MiB. Need to download 2 MiB.
+After this operation, 10 KiB extra will be used (install 440 KiB, remove 430 KiB).
+Operation aborted by the user.
+`
+
+func TestParseDnfTransaction(t *testing.T) {
+	sim := parseDnfTransaction(testDnfAssumeno)
+
+	assert.Equal(t, []pkg.PackageChange{
+		{Name: "akmod-nvidia", Version: "3:550.120-1.fc40", Size: 40 << 10},
+		{Name: "xorg-x11-drv-nvidia", Version: "3:550.120-1.fc40", Size: 3040870},
+		{Name: "xorg-x11-drv-nvidia-kmodsrc-with-a-very-long-name", Version: "3:550.120-1.fc40", Size: 70 << 20},
+	}, sim.Install)
+	assert.Equal(t, []pkg.PackageChange{
+		{Name: "mesa-libGL", Version: "24.1.7-1.fc40", Size: 167 << 10},
+	}, sim.Upgrade)
+	assert.Equal(t, []pkg.PackageChange{
+		{Name: "xorg-x11-drv-nouveau", Version: "1:1.0.17-8.fc40", ReplacedBy: "xorg-x11-drv-nvidia"},
+		{Name: "nvidia-settings-legacy", Version: "470.256-1.fc40", Size: 1258291},
+	}, sim.Remove)
+	assert.Equal(t, []pkg.PackageChange{
+		{Name: "libglvnd", Version: "1:1.7.0-4.fc40", Size: 133 << 10},
+	}, sim.Downgrade)
+	assert.Equal(t, int64(73<<20), sim.DownloadSize)
+	assert.Equal(t, int64(250<<20), sim.InstallSize)
+}
+
+func TestParseDnfTransaction_Dnf5(t *testing.T) {
+	sim := parseDnfTransaction(testDnf5Assumeno)
+
+	assert.Equal(t, []pkg.PackageChange{
+		{Name: "nvidia-driver", Version: "3:550.120-1.fc41", Size: 40 << 10},
+	}, sim.Install)
+	assert.Equal(t, []pkg.PackageChange{
+		{Name: "mesa-libGL", Version: "24.2.4-1.fc41", OldVersion: "24.2.3-1.fc41", Size: 400 << 10},
+	}, sim.Upgrade)
+	assert.Empty(t, sim.Remove)
+	assert.Equal(t, int64(2<<20), sim.DownloadSize)
+	assert.Equal(t, int64(10<<10), sim.InstallSize)
+}
+
+func TestParseDnfTransaction_FreedSpace(t *testing.T) {
+	sim := parseDnfTransaction("Removing:\n foo x86_64 1.0-1 @fedora 12 k\n\nTransaction Summary\nFreed space: 12 k\n")
+	assert.Equal(t, int64(-12<<10), sim.InstallSize)
+	assert.True(t, parseDnfTransaction("Nothing to do.\n").IsEmpty())
+}
+
+func TestManager_SimulateInstall(t *testing.T) {
+	mgr, mockExec := setupTest()
+	ctx := context.Background()
+	mockExec.SetResponse("dnf", &exec.Result{ExitCode: 1, Stdout: []byte(testDnfAssumeno), Stderr: []byte("Operation aborted.")})
+
+	sim, err := mgr.SimulateInstall(ctx, pkg.NonInteractiveInstallOptions(), "akmod-nvidia", "xorg-x11-drv-nvidia")
+	require.NoError(t, err)
+
+	call := mockExec.Calls()[0]
+	assert.Equal(t, "dnf", call.Command)
+	assert.Equal(t, []string{"install", "--assumeno", "akmod-nvidia", "xorg-x11-drv-nvidia"}, call.Args)
+	assert.Len(t, sim.Install, 3)
+	assert.Len(t, sim.Remove, 2)
+}
+
+func TestParseDnfInstalledSizes(t *testing.T) {
+	sizes := parseDnfInstalledSizes("akmod-nvidia 120000\nxorg-x11-drv-nvidia 9000000\nakmod-nvidia 130000\n\nLast metadata expiration check: 0:01:02 ago\n")
+	assert.Equal(t, map[string]int64{"akmod-nvidia": 120000, "xorg-x11-drv-nvidia": 9000000}, sizes)
+}
+
+// subcommandExecutor is a mock executor answering dnf according to its
+// subcommand, such as "repoquery".
+type subcommandExecutor struct {
+	*exec.MockExecutor
+	responses map[string]*exec.Result
+}
+
+func (e *subcommandExecutor) Execute(ctx context.Context, cmd string, args ...string) *exec.Result {
+	result := e.MockExecutor.Execute(ctx, cmd, args...)
+	if cmd == "dnf" && len(args) > 0 {
+		if r, ok := e.responses[args[0]]; ok {
+			return r
+		}
+	}
+	return result
+}
+
+func TestManager_SimulateInstall_InstalledSizes(t *testing.T) {
+	mockExec := exec.NewMockExecutor()
+	mockExec.SetResponse("rpm", exec.SuccessResult("nvidia-settings-legacy 4000000\n"))
+	executor := &subcommandExecutor{
+		MockExecutor: mockExec,
+		responses: map[string]*exec.Result{
+			"install":   {ExitCode: 1, Stdout: []byte(testDnfAssumeno)},
+			"repoquery": exec.SuccessResult("akmod-nvidia 120000\nxorg-x11-drv-nvidia 9000000\nmesa-libGL 500000\n"),
+		},
+	}
+	priv := privilege.NewManager()
+	priv.SetRoot(true)
+	mgr := NewManager(executor, priv)
+
+	sim, err := mgr.SimulateInstall(context.Background(), pkg.NonInteractiveInstallOptions(), "akmod-nvidia", "xorg-x11-drv-nvidia")
+	require.NoError(t, err)
+
+	assert.True(t, mockExec.WasCalledWith("dnf", "repoquery", "--quiet", "--queryformat", "%{name} %{installsize}\n",
+		"akmod-nvidia-3:550.120-1.fc40", "xorg-x11-drv-nvidia-3:550.120-1.fc40",
+		"xorg-x11-drv-nvidia-kmodsrc-with-a-very-long-name-3:550.120-1.fc40",
+		"mesa-libGL-24.1.7-1.fc40", "libglvnd-1:1.7.0-4.fc40"))
+	assert.Equal(t, int64(120000), sim.Install[0].InstalledSize)
+	assert.Equal(t, int64(9000000), sim.Install[1].InstalledSize)
+	assert.Zero(t, sim.Install[2].InstalledSize)
+	assert.Equal(t, int64(500000), sim.Upgrade[0].InstalledSize)
+	assert.Equal(t, int64(4000000), sim.Remove[1].InstalledSize)
+
+	// The totals printed by dnf are kept
+	assert.Equal(t, int64(73<<20), sim.DownloadSize)
+	assert.Equal(t, int64(250<<20), sim.InstallSize)
+}
+
+func TestManager_SimulateInstall_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("package not found", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		mockExec.SetResponse("dnf", exec.FailureResult(1, "No match for argument: nvidia-driver-999"))
+		_, err := mgr.SimulateInstall(ctx, pkg.NonInteractiveInstallOptions(), "nvidia-driver-999")
+		assert.ErrorIs(t, err, pkg.ErrPackageNotFound)
+	})
+
+	t.Run("not root", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		mockExec.SetResponse("dnf", exec.FailureResult(1, "Error: This command has to be run with superuser privileges"))
+		_, err := mgr.SimulateInstall(ctx, pkg.NonInteractiveInstallOptions(), "akmod-nvidia")
+		assert.ErrorIs(t, err, pkg.ErrSimulationFailed)
+	})
+}
+
+func testCUDAKeyPin() pkg.KeyPin {
+	return pkg.KeyPin{
+		Name:         "nvidia-cuda",
+		Fingerprints: []string{"EB693B3035CD5710E231E123A4B469963BF863CC"},
+		Retired: []pkg.RetiredKey{
+			{Fingerprint: "AE09FE4BBD223A84B2CCFCE3F60F4B3D7FA2AF80", ReplacedBy: "EB693B3035CD5710E231E123A4B469963BF863CC", Date: "2022-04-27"},
+		},
+	}
+}
+
+func TestManager_ImportPinnedGPGKey(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetDefaultResponse(exec.SuccessResult(""))
+	mockExec.SetResponse("mktemp", exec.SuccessResult("/tmp/igor-key.abc\n"))
+	mockExec.SetResponse("gpg", exec.SuccessResult("pub:-:4096:1:A4B469963BF863CC:1650000000:::-:::scSC:\nfpr:::::::::EB693B3035CD5710E231E123A4B469963BF863CC:\n"))
+	// The retired key is still in the RPM database
+	mockExec.SetResponse("rpm", exec.SuccessResult("3bf863cc\n7fa2af80\n"))
+
+	keyURL := "https://developer.download.nvidia.com/compute/cuda/repos/rhel9/x86_64/3bf863cc.pub"
+	imp, err := mgr.ImportPinnedGPGKey(context.Background(), keyURL, testCUDAKeyPin())
+	require.NoError(t, err)
+
+	assert.Empty(t, imp.Keyring)
+	assert.Equal(t, []string{"EB693B3035CD5710E231E123A4B469963BF863CC"}, imp.Fingerprints)
+	require.Len(t, imp.Retired, 1)
+	assert.Equal(t, "AE09FE4BBD223A84B2CCFCE3F60F4B3D7FA2AF80", imp.Retired[0].Fingerprint)
+
+	// The verified file is imported, not the URL
+	assert.True(t, mockExec.WasCalledWith("rpm", "--import", "/tmp/igor-key.abc"))
+	assert.False(t, mockExec.WasCalledWith("rpm", "--import", keyURL))
+	assert.True(t, mockExec.WasCalledWith("rm", "-f", "/tmp/igor-key.abc"))
+}
+
+func TestManager_ImportPinnedGPGKey_Mismatch(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetDefaultResponse(exec.SuccessResult(""))
+	mockExec.SetResponse("mktemp", exec.SuccessResult("/tmp/igor-key.abc\n"))
+	mockExec.SetResponse("gpg", exec.SuccessResult("pub:-:4096:1:0123456789ABCDEF:1650000000:::-:::scSC:\nfpr:::::::::00000000000000000000000000000000DEADBEEF:\n"))
+
+	_, err := mgr.ImportPinnedGPGKey(context.Background(), "https://example.com/key.pub", testCUDAKeyPin())
+	require.Error(t, err)
+	assert.ErrorIs(t, err, pkg.ErrGPGKeyMismatch)
+	assert.False(t, mockExec.WasCalled("rpm"))
+}
+
+func TestManager_PreferRepository(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetResponse("dnf", exec.SuccessResult(`repo id                           repo name                                 status
+fedora                            Fedora 40 - x86_64                        enabled
+rpmfusion-nonfree                 RPM Fusion Nonfree                        enabled
+rpmfusion-nonfree-updates         RPM Fusion Nonfree - Updates              enabled`))
+
+	err := mgr.PreferRepository(context.Background(), pkg.Repository{Name: "rpmfusion-nonfree"}, []string{"nvidia-*"})
+	require.NoError(t, err)
+
+	// The updates repository gets the same priority
+	assert.True(t, mockExec.WasCalledWith("dnf", "config-manager", "--save",
+		"--setopt=rpmfusion-nonfree.priority=90", "--setopt=rpmfusion-nonfree-updates.priority=90"))
+}
+
+func TestManager_RemoveRepositoryPreference(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetResponse("dnf", exec.SuccessResult(""))
+
+	err := mgr.RemoveRepositoryPreference(context.Background(), "rpmfusion-nonfree")
+	require.NoError(t, err)
+	assert.True(t, mockExec.WasCalledWith("dnf", "config-manager", "--save", "--setopt=rpmfusion-nonfree.priority=99"))
+}
+
+func TestManager_RemoveRepositoryPreference_NotFound(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetResponse("dnf", exec.FailureResult(1, "Error: No matching repo to modify: rpmfusion-nonfree."))
+
+	err := mgr.RemoveRepositoryPreference(context.Background(), "rpmfusion-nonfree")
+	assert.NoError(t, err)
+
+	err = mgr.PreferRepository(context.Background(), pkg.Repository{Name: "rpmfusion-nonfree"}, nil)
+	assert.ErrorIs(t, err, pkg.ErrRepositoryNotFound)
+}
+
+// =============================================================================
+// Progress Tests
+// =============================================================================
+
+const testDnfInstallOutput = `Dependencies resolved.
+Downloading Packages:
+(1/2): xorg-x11-drv-nvidia-cuda-libs-550.54.14-1.fc40.x86_64.rpm  12 MB/s |  45 MB     00:03
+(2/2): akmod-nvidia-550.54.14-1.fc40.x86_64.rpm                  1.2 MB/s |  40 kB     00:00
+Running transaction
+  Preparing        :                                                        1/1
+  Installing       : xorg-x11-drv-nvidia-cuda-libs-3:550.54.14-1.fc40.x86_64    1/2
+  Running scriptlet: akmod-nvidia-3:550.54.14-1.fc40.x86_64                     2/2
+  Installing       : akmod-nvidia-3:550.54.14-1.fc40.x86_64                     2/2
+  Verifying        : akmod-nvidia-3:550.54.14-1.fc40.x86_64                     1/2
+Complete!
+`
+
+func TestParseProgressLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected pkg.Progress
+		ok       bool
+	}{
+		{"(1/4): akmod-nvidia-550.54.14-1.fc40.x86_64.rpm  1.2 MB/s |  40 kB  00:00",
+			pkg.Progress{Phase: pkg.ProgressDownload, Package: "akmod-nvidia", Percent: 25}, true},
+		{"Upgrading        : nvidia-settings-3:550.54.14-1.fc40.x86_64    2/4",
+			pkg.Progress{Phase: pkg.ProgressInstall, Package: "nvidia-settings", Percent: 50}, true},
+		{"[2/4] akmod-nvidia-3:550.54.14-1.fc40.x86_64  100% |   1.2 MiB/s |  40.0 KiB |  00m00s",
+			pkg.Progress{Phase: pkg.ProgressDownload, Package: "akmod-nvidia", Percent: 50}, true},
+		{"[ 6/8] Installing akmod-nvidia-3:550.54.14-1.fc40.x86_64  100% |   2.0 MiB/s |  40.0 KiB |  00m00s",
+			pkg.Progress{Phase: pkg.ProgressInstall, Package: "akmod-nvidia", Percent: 75}, true},
+		{"[1/8] Verify package files  100% | 500.0   B/s |   4.0   B |  00m00s", pkg.Progress{}, false},
+		{"Running scriptlet: akmod-nvidia-3:550.54.14-1.fc40.x86_64    2/2", pkg.Progress{}, false},
+		{"Verifying        : akmod-nvidia-3:550.54.14-1.fc40.x86_64    1/2", pkg.Progress{}, false},
+		{"Complete!", pkg.Progress{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			progress, ok := parseProgressLine(tt.line)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, progress)
+		})
+	}
+}
+
+func TestManager_Install_Progress(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("dnf", exec.SuccessResult(testDnfInstallOutput))
+
+	var reported []pkg.Progress
+	opts := pkg.NonInteractiveInstallOptions()
+	opts.Progress = func(p pkg.Progress) { reported = append(reported, p) }
+	require.NoError(t, mgr.Install(context.Background(), opts, "akmod-nvidia"))
+
+	call := mockExec.LastCall()
+	assert.True(t, call.Elevated)
+	assert.Equal(t, []string{"install", "-y", "akmod-nvidia"}, call.Args)
+	assert.Equal(t, []pkg.Progress{
+		{Phase: pkg.ProgressDownload, Package: "xorg-x11-drv-nvidia-cuda-libs", Percent: 50},
+		{Phase: pkg.ProgressDownload, Package: "akmod-nvidia", Percent: 100},
+		{Phase: pkg.ProgressInstall, Package: "xorg-x11-drv-nvidia-cuda-libs", Percent: 50},
+		{Phase: pkg.ProgressInstall, Package: "akmod-nvidia", Percent: 100},
+	}, reported)
+}
+
+func TestManager_Transaction_CommitProgress(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("install only", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		mockExec.SetResponse("dnf", exec.SuccessResult(testDnfInstallOutput))
+
+		var reported []pkg.Progress
+		opts := pkg.NonInteractiveInstallOptions()
+		opts.Progress = func(p pkg.Progress) { reported = append(reported, p) }
+
+		require.NoError(t, mgr.BeginTransaction(ctx))
+		require.NoError(t, mgr.Install(ctx, opts, "akmod-nvidia", "xorg-x11-drv-nvidia-cuda-libs"))
+		require.NoError(t, mgr.CommitTransaction(ctx))
+
+		call := mockExec.LastCall()
+		assert.True(t, call.Elevated)
+		assert.Equal(t, []string{"--setopt=strict=True", "install", "-y", "akmod-nvidia", "xorg-x11-drv-nvidia-cuda-libs"}, call.Args)
+		assert.Len(t, reported, 4)
+	})
+
+	t.Run("install failure", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		mockExec.SetResponse("dnf", exec.FailureResult(1, "Error: Unable to find a match: akmod-nvidia-cuda"))
+
+		opts := pkg.NonInteractiveInstallOptions()
+		opts.Progress = func(pkg.Progress) {}
+
+		require.NoError(t, mgr.BeginTransaction(ctx))
+		require.NoError(t, mgr.Install(ctx, opts, "akmod-nvidia-cuda"))
+		err := mgr.CommitTransaction(ctx)
+		assert.ErrorIs(t, err, pkg.ErrTransactionFailed)
+		assert.Contains(t, err.Error(), "dnf install failed")
+	})
+
+	t.Run("with removals", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+
+		opts := pkg.NonInteractiveInstallOptions()
+		opts.Progress = func(pkg.Progress) {}
+
+		require.NoError(t, mgr.BeginTransaction(ctx))
+		require.NoError(t, mgr.Install(ctx, opts, "akmod-nvidia"))
+		require.NoError(t, mgr.Remove(ctx, pkg.DefaultRemoveOptions(), "nvidia-settings"))
+		require.NoError(t, mgr.CommitTransaction(ctx))
+
+		// dnf shell runs the transaction, without progress
+		call := mockExec.LastCall()
+		assert.Equal(t, []string{"shell", "-y", "--setopt=strict=True"}, call.Args)
+		assert.NotEmpty(t, call.Input)
+	})
+}
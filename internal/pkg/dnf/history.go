@@ -0,0 +1,68 @@
+package dnf
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// History returns the DNF transaction history, newest first.
+// Transactions are listed with dnf history list and their details are read
+// with a single dnf history info call for the listed IDs.
+func (m *Manager) History(ctx context.Context, limit int) ([]pkg.HistoryEntry, error) {
+	result := m.executor.Execute(ctx, "dnf", "history", "list")
+	if result.Failed() {
+		return nil, fmt.Errorf("dnf history list failed: %s", result.StderrString())
+	}
+
+	ids := parseHistoryIDs(result.StdoutString())
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+	if len(ids) == 0 {
+		return []pkg.HistoryEntry{}, nil
+	}
+
+	args := append([]string{"history", "info"}, ids...)
+	result = m.executor.Execute(ctx, "dnf", args...)
+	if result.Failed() {
+		return nil, fmt.Errorf("dnf history info failed: %s", result.StderrString())
+	}
+
+	entries := parseHistoryInfo(result.StdoutString())
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, _ := strconv.Atoi(entries[i].ID)
+		b, _ := strconv.Atoi(entries[j].ID)
+		return a > b
+	})
+	return entries, nil
+}
+
+// Undo undoes a transaction with dnf history undo.
+func (m *Manager) Undo(ctx context.Context, id string) error {
+	return m.historyCommand(ctx, "undo", id)
+}
+
+// Redo repeats a transaction with dnf history redo.
+func (m *Manager) Redo(ctx context.Context, id string) error {
+	return m.historyCommand(ctx, "redo", id)
+}
+
+// historyCommand runs dnf history undo or redo on a transaction.
+func (m *Manager) historyCommand(ctx context.Context, command, id string) error {
+	if n, err := strconv.Atoi(id); err != nil || n <= 0 {
+		return pkg.Wrap(pkg.ErrHistoryFailed, fmt.Errorf("invalid transaction ID %q", id))
+	}
+
+	result := m.executor.ExecuteElevated(ctx, "dnf", "history", command, "-y", id)
+	if result.Failed() {
+		return pkg.Wrap(pkg.ErrHistoryFailed, fmt.Errorf("dnf history %s %s failed: %s", command, id, result.StderrString()))
+	}
+	return nil
+}
+
+// Ensure Manager implements pkg.HistoryManager interface.
+var _ pkg.HistoryManager = (*Manager)(nil)
@@ -0,0 +1,129 @@
+package dnf
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// dnfConfPath is the main DNF configuration file.
+const dnfConfPath = "/etc/dnf/dnf.conf"
+
+// Hold prevents packages from being upgraded.
+// Uses dnf versionlock add when the versionlock plugin is available, which
+// locks the installed versions. Otherwise the packages are added to the
+// exclude= option of /etc/dnf/dnf.conf, which hides all their updates.
+func (m *Manager) Hold(ctx context.Context, packages ...string) error {
+	if len(packages) == 0 {
+		return nil
+	}
+
+	if _, ok := m.versionlockList(ctx); ok {
+		args := append([]string{"versionlock", "add"}, packages...)
+		result := m.executor.ExecuteElevated(ctx, "dnf", args...)
+
+		if result.Failed() {
+			return pkg.Wrap(pkg.ErrHoldFailed, fmt.Errorf("dnf versionlock add failed: %s", result.StderrString()))
+		}
+		return nil
+	}
+
+	return m.updateExcludes(ctx, packages, nil)
+}
+
+// Unhold releases package holds.
+// Removes the packages from the versionlock list and from the exclude=
+// option of /etc/dnf/dnf.conf, wherever they were held.
+func (m *Manager) Unhold(ctx context.Context, packages ...string) error {
+	if len(packages) == 0 {
+		return nil
+	}
+
+	if locked, ok := m.versionlockList(ctx); ok {
+		if toDelete := intersect(packages, locked); len(toDelete) > 0 {
+			args := append([]string{"versionlock", "delete"}, toDelete...)
+			result := m.executor.ExecuteElevated(ctx, "dnf", args...)
+
+			if result.Failed() {
+				return pkg.Wrap(pkg.ErrHoldFailed, fmt.Errorf("dnf versionlock delete failed: %s", result.StderrString()))
+			}
+		}
+	}
+
+	return m.updateExcludes(ctx, nil, packages)
+}
+
+// ListHeld returns the packages locked with versionlock or excluded in
+// /etc/dnf/dnf.conf.
+func (m *Manager) ListHeld(ctx context.Context) ([]string, error) {
+	held, _ := m.versionlockList(ctx)
+
+	result := m.executor.Execute(ctx, "cat", dnfConfPath)
+	if result.Failed() {
+		return nil, fmt.Errorf("failed to read %s: %s", dnfConfPath, result.StderrString())
+	}
+
+	for _, name := range parseExcludes(result.StdoutString()) {
+		if !contains(held, name) {
+			held = append(held, name)
+		}
+	}
+
+	return held, nil
+}
+
+// versionlockList returns the packages locked with the versionlock plugin.
+// Returns false if the plugin is not available.
+func (m *Manager) versionlockList(ctx context.Context) ([]string, bool) {
+	result := m.executor.Execute(ctx, "dnf", "versionlock", "list")
+	if result.Failed() {
+		return nil, false
+	}
+	return parseVersionlockList(result.StdoutString()), true
+}
+
+// updateExcludes adds and removes packages in the exclude= option of
+// /etc/dnf/dnf.conf. The file is only written if it changes.
+func (m *Manager) updateExcludes(ctx context.Context, add, remove []string) error {
+	result := m.executor.Execute(ctx, "cat", dnfConfPath)
+	if result.Failed() {
+		return pkg.Wrap(pkg.ErrHoldFailed, fmt.Errorf("failed to read %s: %s", dnfConfPath, result.StderrString()))
+	}
+
+	content, changed := setExcludes(result.StdoutString(), add, remove)
+	if !changed {
+		return nil
+	}
+
+	result = m.executor.ExecuteWithInput(ctx, []byte(content), "sudo", "tee", dnfConfPath)
+	if result.Failed() {
+		return pkg.Wrap(pkg.ErrHoldFailed, fmt.Errorf("failed to update %s: %s", dnfConfPath, result.StderrString()))
+	}
+
+	return nil
+}
+
+// contains reports whether list includes s.
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// intersect returns the items of a that are also in b, in the order of a.
+func intersect(a, b []string) []string {
+	var result []string
+	for _, item := range a {
+		if contains(b, item) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// Ensure Manager implements pkg.HoldManager interface.
+var _ pkg.HoldManager = (*Manager)(nil)
@@ -0,0 +1,50 @@
+package dnf
+
+import (
+	"context"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// dnfLockFiles are the lock files of DNF and of the RPM database, which
+// PackageKit also locks. Paths are the ones shown in /proc, so the RPM
+// database is listed both in its Fedora location and in /var/lib/rpm.
+var dnfLockFiles = []pkg.LockFile{
+	{Path: "/var/lib/dnf/rpmdb_lock.pid", Kind: pkg.LockKindOpenFile},
+	{Path: "/var/cache/dnf/metadata_lock.pid", Kind: pkg.LockKindOpenFile},
+	{Path: "/var/cache/dnf/download_lock.pid", Kind: pkg.LockKindOpenFile},
+	{Path: "/usr/lib/sysimage/rpm/.rpm.lock", Kind: pkg.LockKindOpenFile},
+	{Path: "/var/lib/rpm/.rpm.lock", Kind: pkg.LockKindOpenFile},
+}
+
+// AcquireLock verifies that the dnf lock is free.
+// DNF commands take the lock themselves, so it is not held afterwards.
+// Returns ErrLockAcquireFailed, naming the process holding the lock, if it
+// is held.
+func (m *Manager) AcquireLock(ctx context.Context) error {
+	return m.locks.AcquireLock(ctx)
+}
+
+// ReleaseLock does nothing, since AcquireLock does not hold the lock.
+func (m *Manager) ReleaseLock(ctx context.Context) error {
+	return m.locks.ReleaseLock(ctx)
+}
+
+// IsLocked checks if another process holds the dnf lock.
+func (m *Manager) IsLocked(ctx context.Context) (bool, error) {
+	return m.locks.IsLocked(ctx)
+}
+
+// WaitForLock waits until the dnf lock is free or ctx is done.
+func (m *Manager) WaitForLock(ctx context.Context) error {
+	return m.locks.WaitForLock(ctx)
+}
+
+// LockHolder returns the process holding the dnf lock, or nil if the
+// lock is free.
+func (m *Manager) LockHolder(ctx context.Context) (*pkg.LockHolder, error) {
+	return m.locks.LockHolder(ctx)
+}
+
+// Ensure Manager implements pkg.LockableManager interface.
+var _ pkg.LockableManager = (*Manager)(nil)
@@ -4,6 +4,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/tungetti/igor/internal/pkg"
 )
@@ -492,7 +493,7 @@ func buildRepoFileContent(repo pkg.Repository) string {
 	}
 
 	// GPG settings
-	if repo.GPGKey != "" {
+	if repo.GPGKey != "" && !repo.Trusted {
 		sb.WriteString("gpgcheck=1\n")
 		sb.WriteString("gpgkey=")
 		sb.WriteString(repo.GPGKey)
@@ -525,3 +526,484 @@ func sanitizeRepoID(name string) string {
 
 	return name
 }
+
+// parseVersionlockList parses the output of dnf versionlock list and returns
+// the names of the locked packages.
+//
+// DNF 4 prints one NEVRA pattern per line (name-[epoch:]version-release.arch,
+// the arch often being "*"); DNF 5 prints "Package name: <name>" lines. Other
+// lines, such as metadata messages and exclusions starting with "!", are
+// ignored.
+func parseVersionlockList(output string) []string {
+	locked := make([]string, 0)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+
+		var name string
+		switch {
+		case strings.HasPrefix(line, "Package name:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "Package name:"))
+		case line == "", strings.ContainsAny(line, " \t#!"):
+			continue
+		default:
+			name = nevraName(line)
+		}
+
+		if name != "" && !contains(locked, name) {
+			locked = append(locked, name)
+		}
+	}
+	return locked
+}
+
+// nevraName returns the package name of a [epoch:]name-[epoch:]version-release.arch
+// string, or "" if s has no version and release.
+func nevraName(s string) string {
+	// Strip a leading epoch, as printed by yum ("0:name-version-release.arch")
+	if idx := strings.Index(s, ":"); idx > 0 && !strings.Contains(s[:idx], "-") {
+		s = s[idx+1:]
+	}
+
+	parts := strings.Split(s, "-")
+	if len(parts) < 3 {
+		return ""
+	}
+	return strings.Join(parts[:len(parts)-2], "-")
+}
+
+// excludeKeys are the options of the [main] section that exclude packages.
+var excludeKeys = map[string]bool{"exclude": true, "excludepkgs": true}
+
+// parseExcludes returns the packages excluded in the [main] section of a
+// DNF or YUM configuration file. Values are separated by spaces or commas.
+func parseExcludes(content string) []string {
+	excluded := make([]string, 0)
+	inMain := false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			inMain = trimmed == "[main]"
+			continue
+		}
+		if !inMain {
+			continue
+		}
+		if key, values, ok := splitExcludeLine(trimmed); ok && excludeKeys[key] {
+			for _, name := range values {
+				if !contains(excluded, name) {
+					excluded = append(excluded, name)
+				}
+			}
+		}
+	}
+	return excluded
+}
+
+// setExcludes adds and removes packages in the exclude= option of the [main]
+// section of a DNF or YUM configuration file. The first exclude line is
+// updated, or a new one is added after the [main] header; lines left empty
+// are removed. Returns the new content and whether it changed.
+func setExcludes(content string, add, remove []string) (string, bool) {
+	lines := strings.Split(content, "\n")
+	out := make([]string, 0, len(lines)+2)
+	changed := false
+	inMain, mainFound, added := false, false, len(add) == 0
+
+	// addTo appends the packages to add that are not excluded yet.
+	addTo := func(values []string) []string {
+		for _, name := range add {
+			if !contains(values, name) && !contains(parseExcludes(content), name) {
+				values = append(values, name)
+				changed = true
+			}
+		}
+		added = true
+		return values
+	}
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			if inMain && !added {
+				out = appendExcludeLine(out, addTo(nil))
+			}
+			inMain = trimmed == "[main]"
+			out = append(out, line)
+			if inMain {
+				mainFound = true
+			}
+			continue
+		}
+
+		key, values, ok := splitExcludeLine(trimmed)
+		if !inMain || !ok || !excludeKeys[key] {
+			out = append(out, line)
+			continue
+		}
+
+		kept := make([]string, 0, len(values))
+		for _, name := range values {
+			if contains(remove, name) {
+				changed = true
+				continue
+			}
+			kept = append(kept, name)
+		}
+		if !added {
+			kept = addTo(kept)
+		}
+		if len(kept) == 0 {
+			changed = true
+			continue
+		}
+		if len(kept) != len(values) {
+			line = key + "=" + strings.Join(kept, " ")
+		}
+		out = append(out, line)
+	}
+
+	if !added {
+		values := addTo(nil)
+		if !mainFound && len(values) > 0 {
+			// Add a [main] section at the end, separated by an empty line
+			for len(out) > 0 && strings.TrimSpace(out[len(out)-1]) == "" {
+				out = out[:len(out)-1]
+			}
+			if len(out) > 0 {
+				out = append(out, "")
+			}
+			out = append(out, "[main]", "")
+		}
+		out = appendExcludeLine(out, values)
+	}
+
+	if !changed {
+		return content, false
+	}
+	return strings.Join(out, "\n"), true
+}
+
+// appendExcludeLine appends an exclude= line for values, if any, keeping
+// the trailing empty lines of a section after it.
+func appendExcludeLine(lines []string, values []string) []string {
+	if len(values) == 0 {
+		return lines
+	}
+	end := len(lines)
+	for end > 0 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	result := append(append([]string{}, lines[:end]...), "exclude="+strings.Join(values, " "))
+	return append(result, lines[end:]...)
+}
+
+// splitExcludeLine splits a key=value line of a configuration file into the
+// key and the values of a space or comma separated list.
+func splitExcludeLine(line string) (string, []string, bool) {
+	if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+		return "", nil, false
+	}
+	eqIdx := strings.Index(line, "=")
+	if eqIdx == -1 {
+		return "", nil, false
+	}
+	key := strings.ToLower(strings.TrimSpace(line[:eqIdx]))
+	values := strings.FieldsFunc(line[eqIdx+1:], func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ','
+	})
+	return key, values, true
+}
+
+// parseHistoryIDs returns the transaction IDs listed by dnf history list, in
+// the listed order. Lines that do not start with an ID, such as headers and
+// plugin messages, are ignored.
+func parseHistoryIDs(output string) []string {
+	ids := make([]string, 0)
+	for _, line := range strings.Split(output, "\n") {
+		field := line
+		if idx := strings.Index(field, "|"); idx != -1 {
+			field = field[:idx]
+		}
+		fields := strings.Fields(field)
+		if len(fields) == 0 {
+			continue
+		}
+		if id, err := strconv.Atoi(fields[0]); err == nil && id > 0 {
+			ids = append(ids, fields[0])
+		}
+	}
+	return ids
+}
+
+// historyActions maps the actions listed under "Packages Altered" by
+// dnf history info to history operations. Actions naming the replaced side
+// of a change, such as "Upgraded", affect the package but add no operation.
+var historyActions = map[string]string{
+	"install":     pkg.HistoryInstall,
+	"dep-install": pkg.HistoryInstall,
+	"obsoleting":  pkg.HistoryInstall,
+	"upgrade":     pkg.HistoryUpgrade,
+	"update":      pkg.HistoryUpgrade,
+	"upgraded":    "",
+	"updated":     "",
+	"replaced":    "",
+	"downgrade":   pkg.HistoryDowngrade,
+	"downgraded":  "",
+	"reinstall":   pkg.HistoryReinstall,
+	"reinstalled": "",
+	"removed":     pkg.HistoryRemove,
+	"remove":      pkg.HistoryRemove,
+	"erase":       pkg.HistoryRemove,
+	"obsoleted":   pkg.HistoryRemove,
+}
+
+// historyTimeLayouts are the formats of the begin time of a transaction.
+var historyTimeLayouts = []string{
+	"2006-01-02 15:04:05",             // DNF 5
+	"Mon 02 Jan 2006 03:04:05 PM MST", // DNF 4, 12-hour clock
+	"Mon 02 Jan 2006 15:04:05 MST",    // DNF 4, 24-hour clock
+	"Mon Jan 2 15:04:05 2006",         // DNF 4 without a locale
+}
+
+// parseHistoryInfo parses the output of dnf history info for one or more
+// transactions into history entries, in the printed order.
+func parseHistoryInfo(output string) []pkg.HistoryEntry {
+	entries := make([]pkg.HistoryEntry, 0)
+	var current *pkg.HistoryEntry
+	inPackages := false
+
+	for _, line := range strings.Split(output, "\n") {
+		if inPackages && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			addHistoryPackage(current, line)
+			continue
+		}
+		inPackages = false
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if key == "transaction id" {
+			entries = append(entries, pkg.HistoryEntry{ID: value})
+			current = &entries[len(entries)-1]
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		switch key {
+		case "begin time", "start time":
+			current.Timestamp = parseHistoryTime(value)
+		case "return-code", "status":
+			current.Success = value == "Success" || value == "Ok"
+		case "command line", "description":
+			current.Details = value
+		case "packages altered":
+			inPackages = true
+		}
+	}
+
+	return entries
+}
+
+// addHistoryPackage adds a package line listed under "Packages Altered",
+// such as "    Install akmod-nvidia-3:550.54.14-1.fc39.x86_64 @rpmfusion",
+// to an entry.
+func addHistoryPackage(entry *pkg.HistoryEntry, line string) {
+	fields := strings.Fields(line)
+	// Problems are flagged with a leading "**"
+	for len(fields) > 0 && strings.Trim(fields[0], "*") == "" {
+		fields = fields[1:]
+	}
+	if len(fields) < 2 {
+		return
+	}
+
+	operation, ok := historyActions[strings.ToLower(fields[0])]
+	if !ok {
+		return
+	}
+	name := nevraName(fields[1])
+	if name == "" {
+		return
+	}
+	entry.AddOperation(operation)
+	entry.AddPackage(name)
+}
+
+// parseHistoryTime parses the begin time of a transaction in the local time
+// zone. It returns 0 for times it cannot parse, such as localized ones.
+func parseHistoryTime(value string) int64 {
+	value = strings.Join(strings.Fields(value), " ")
+	for _, layout := range historyTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t.Unix()
+		}
+	}
+	return 0
+}
+
+// parseDnfTransaction parses the transaction table printed by
+// dnf install --assumeno, for both dnf and dnf5. Long package names are
+// printed on a line of their own, followed by the rest of the row.
+// Packages obsoleted by an installed package are listed under it on a
+// "replacing" line and are reported as removed; dnf5 also lists the version
+// replaced by an upgrade that way.
+//
+// Example:
+//
+//	Installing:
+//	 xorg-x11-drv-nvidia  x86_64  3:550.120-1.fc40  rpmfusion-nonfree-updates  2.9 M
+//	     replacing  xorg-x11-drv-nouveau.x86_64 1:1.0.17-8.fc40
+//	Upgrading:
+//	 mesa-libGL           x86_64  24.1.7-1.fc40     updates                    167 k
+//	...
+//	Total download size: 120 M
+//	Installed size: 400 M
+func parseDnfTransaction(output string) *pkg.Simulation {
+	sim := pkg.NewSimulation()
+	var section *[]pkg.PackageChange
+	var last *pkg.PackageChange
+	pending := ""
+
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		fields := strings.Fields(trimmed)
+
+		switch {
+		case trimmed == "":
+			continue
+		case strings.HasPrefix(trimmed, "Transaction Summary"):
+			section, last = nil, nil
+			continue
+		case strings.HasPrefix(trimmed, "Total download size:"):
+			sim.DownloadSize, _ = pkg.ParseSize(strings.TrimPrefix(trimmed, "Total download size:"))
+			continue
+		case strings.HasPrefix(trimmed, "Installed size:"):
+			sim.InstallSize, _ = pkg.ParseSize(strings.TrimPrefix(trimmed, "Installed size:"))
+			continue
+		case strings.HasPrefix(trimmed, "Freed space:"):
+			freed, _ := pkg.ParseSize(strings.TrimPrefix(trimmed, "Freed space:"))
+			sim.InstallSize = -freed
+			continue
+		case strings.Contains(trimmed, "Need to download "):
+			_, size, _ := strings.Cut(trimmed, "Need to download ")
+			sim.DownloadSize, _ = pkg.ParseSize(strings.TrimSuffix(size, "."))
+			continue
+		case strings.HasPrefix(trimmed, "After this operation, "):
+			sim.InstallSize = parseDnf5SpaceChange(strings.TrimPrefix(trimmed, "After this operation, "))
+			continue
+		case strings.HasSuffix(trimmed, ":") && !strings.HasPrefix(line, " "):
+			section, last, pending = dnfTransactionSection(sim, trimmed), nil, ""
+			continue
+		case section == nil:
+			continue
+		case fields[0] == "replacing" && len(fields) >= 3:
+			addDnfReplaced(sim, last, fields)
+			continue
+		case len(fields) == 1:
+			pending = fields[0]
+			continue
+		}
+
+		if pending != "" {
+			fields = append([]string{pending}, fields...)
+			pending = ""
+		}
+		if len(fields) < 4 {
+			continue
+		}
+
+		change := pkg.PackageChange{Name: fields[0], Version: fields[2]}
+		if n := len(fields); n >= 6 {
+			change.Size, _ = pkg.ParseSize(fields[n-2] + " " + fields[n-1])
+		}
+		*section = append(*section, change)
+		last = &(*section)[len(*section)-1]
+	}
+
+	return sim
+}
+
+// parseDnfInstalledSizes parses the "name size" lines printed with
+// repoquerySizeFormat or rpmSizeFormat and returns the installed size of
+// each package in bytes, keyed by name. The first line of a package wins.
+func parseDnfInstalledSizes(output string) map[string]int64 {
+	sizes := make(map[string]int64)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		if _, seen := sizes[fields[0]]; !seen {
+			sizes[fields[0]] = size
+		}
+	}
+	return sizes
+}
+
+// dnfTransactionSection returns the change list of the simulation a section
+// of the transaction table adds to, or nil for sections that are skipped,
+// such as reinstalls and skipped packages.
+func dnfTransactionSection(sim *pkg.Simulation, header string) *[]pkg.PackageChange {
+	switch {
+	case strings.HasPrefix(header, "Installing"):
+		return &sim.Install
+	case strings.HasPrefix(header, "Upgrading"):
+		return &sim.Upgrade
+	case strings.HasPrefix(header, "Removing"):
+		return &sim.Remove
+	case strings.HasPrefix(header, "Downgrading"):
+		return &sim.Downgrade
+	default:
+		return nil
+	}
+}
+
+// addDnfReplaced handles a "replacing" line of the transaction table: the
+// installed version of an upgraded or downgraded package for dnf5, or a
+// package obsoleted by the last package. dnf prints the replaced package as
+// "name.arch version", dnf5 as "name arch version repository size".
+func addDnfReplaced(sim *pkg.Simulation, last *pkg.PackageChange, fields []string) {
+	name, version := fields[1], fields[2]
+	if len(fields) >= 4 && isArchitecture(fields[2]) {
+		version = fields[3]
+	} else if idx := strings.LastIndex(name, "."); idx > 0 && isArchitecture(name[idx+1:]) {
+		name = name[:idx]
+	}
+
+	if last != nil && last.Name == name {
+		last.OldVersion = version
+		return
+	}
+
+	change := pkg.PackageChange{Name: name, Version: version}
+	if last != nil {
+		change.ReplacedBy = last.Name
+	}
+	sim.Remove = append(sim.Remove, change)
+}
+
+// parseDnf5SpaceChange parses the disk space change of a dnf5 transaction
+// summary, such as "400 MiB extra will be used (install 400 MiB, remove 0 B)."
+// or "12 MiB will be freed (install 0 B, remove 12 MiB).".
+func parseDnf5SpaceChange(s string) int64 {
+	for _, marker := range []string{" extra will be used", " will be freed"} {
+		if idx := strings.Index(s, marker); idx > 0 {
+			size, _ := pkg.ParseSize(s[:idx])
+			if marker == " will be freed" {
+				return -size
+			}
+			return size
+		}
+	}
+	return 0
+}
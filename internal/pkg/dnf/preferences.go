@@ -0,0 +1,75 @@
+package dnf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// Repository priorities. Lower values win, and repositories without a
+// priority have 99.
+const (
+	preferredPriority = 90
+	defaultPriority   = 99
+)
+
+// PreferRepository gives the repository, and the repositories of the same
+// source such as its updates repository, priority 90 with
+// dnf config-manager --setopt=<repo>.priority. Priorities apply to whole
+// repositories, so the package globs are not used.
+func (m *Manager) PreferRepository(ctx context.Context, repo pkg.Repository, packages []string) error {
+	return m.setPriority(ctx, repo.Name, preferredPriority)
+}
+
+// RemoveRepositoryPreference restores the default priority of the
+// repository and of the repositories of the same source.
+func (m *Manager) RemoveRepositoryPreference(ctx context.Context, name string) error {
+	err := m.setPriority(ctx, name, defaultPriority)
+	if errors.Is(err, pkg.ErrRepositoryNotFound) {
+		return nil
+	}
+	return err
+}
+
+// setPriority sets the priority of the repository and its companions.
+func (m *Manager) setPriority(ctx context.Context, name string, priority int) error {
+	args := []string{"config-manager", "--save"}
+	for _, id := range m.sourceRepositoryIDs(ctx, name) {
+		args = append(args, fmt.Sprintf("--setopt=%s.priority=%d", id, priority))
+	}
+
+	result := m.executor.ExecuteElevated(ctx, "dnf", args...)
+	if result.Failed() {
+		stderr := result.StderrString()
+		if strings.Contains(stderr, "No matching repo") {
+			return pkg.Wrap(pkg.ErrRepositoryNotFound, fmt.Errorf("repository not found: %s", name))
+		}
+		return fmt.Errorf("dnf config-manager --setopt failed: %s", stderr)
+	}
+
+	return nil
+}
+
+// sourceRepositoryIDs returns the repository and the repositories named
+// after it, like rpmfusion-nonfree-updates for rpmfusion-nonfree. They carry
+// the newer versions of the packages of the repository, so giving the
+// repository alone priority would hold its packages back.
+func (m *Manager) sourceRepositoryIDs(ctx context.Context, name string) []string {
+	ids := []string{name}
+	repos, err := m.ListRepositories(ctx)
+	if err != nil {
+		return ids
+	}
+	for _, repo := range repos {
+		if strings.HasPrefix(repo.Name, name+"-") {
+			ids = append(ids, repo.Name)
+		}
+	}
+	return ids
+}
+
+// Ensure Manager implements pkg.PreferenceManager interface.
+var _ pkg.PreferenceManager = (*Manager)(nil)
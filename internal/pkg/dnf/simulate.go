@@ -0,0 +1,87 @@
+package dnf
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// SimulateInstall resolves the installation of the packages with
+// dnf install --assumeno, which prints the transaction and aborts it.
+// dnf resolves install transactions only when run as root. The transaction
+// table prints one size per package, so the installed size of each package
+// is read with dnf repoquery, and that of removed packages from the RPM
+// database.
+func (m *Manager) SimulateInstall(ctx context.Context, opts pkg.InstallOptions, packages ...string) (*pkg.Simulation, error) {
+	if len(packages) == 0 {
+		return pkg.NewSimulation(), nil
+	}
+
+	args := m.buildInstallArgs(opts, packages)
+	args[1] = "--assumeno"
+	result := m.executor.Execute(ctx, "dnf", args...)
+
+	// Aborting the transaction makes dnf exit with an error
+	stdout := result.StdoutString()
+	if result.Failed() && !strings.Contains(stdout, "Transaction Summary") {
+		stderr := result.StderrString()
+		if strings.Contains(stderr, "No match for argument") || strings.Contains(stderr, "No package") {
+			return nil, pkg.Wrap(pkg.ErrPackageNotFound, fmt.Errorf("dnf install --assumeno failed: %s", stderr))
+		}
+		return nil, pkg.Wrap(pkg.ErrSimulationFailed, fmt.Errorf("dnf install --assumeno failed (exit code %d): %s", result.ExitCode, stderr))
+	}
+
+	sim := parseDnfTransaction(stdout)
+	m.addInstalledSizes(ctx, sim)
+	return sim, nil
+}
+
+// Query formats printing the name and installed size of packages, for
+// dnf repoquery and rpm -q.
+const (
+	repoquerySizeFormat = "%{name} %{installsize}\n"
+	rpmSizeFormat       = "%{NAME} %{SIZE}\n"
+)
+
+// addInstalledSizes fills in the installed size of each package of a
+// simulation. The totals printed by dnf are kept; packages whose size cannot
+// be read are left unknown.
+func (m *Manager) addInstalledSizes(ctx context.Context, sim *pkg.Simulation) {
+	var specs []string
+	for _, changes := range [][]pkg.PackageChange{sim.Install, sim.Upgrade, sim.Downgrade} {
+		for _, change := range changes {
+			if change.Version != "" {
+				specs = append(specs, change.Name+"-"+change.Version)
+			}
+		}
+	}
+	if len(specs) > 0 {
+		args := append([]string{"repoquery", "--quiet", "--queryformat", repoquerySizeFormat}, specs...)
+		result := m.executor.Execute(ctx, "dnf", args...)
+		sizes := parseDnfInstalledSizes(result.StdoutString())
+		for _, changes := range [][]pkg.PackageChange{sim.Install, sim.Upgrade, sim.Downgrade} {
+			for i := range changes {
+				changes[i].InstalledSize = sizes[changes[i].Name]
+			}
+		}
+	}
+
+	if len(sim.Remove) > 0 {
+		names := make([]string, 0, len(sim.Remove))
+		for _, change := range sim.Remove {
+			names = append(names, change.Name)
+		}
+		// rpm -q fails if a package is not installed, but still prints the others
+		args := append([]string{"-q", "--queryformat", rpmSizeFormat}, names...)
+		result := m.executor.Execute(ctx, "rpm", args...)
+		sizes := parseDnfInstalledSizes(result.StdoutString())
+		for i := range sim.Remove {
+			sim.Remove[i].InstalledSize = sizes[sim.Remove[i].Name]
+		}
+	}
+}
+
+// Ensure Manager implements pkg.SimulationManager interface.
+var _ pkg.SimulationManager = (*Manager)(nil)
@@ -0,0 +1,117 @@
+package dnf
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	igorexec "github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// BeginTransaction starts a transaction.
+// Until it is committed or rolled back, Install and Remove only queue the
+// packages, which are then installed and removed together by a single
+// dnf shell transaction.
+func (m *Manager) BeginTransaction(ctx context.Context) error {
+	return m.tx.Begin(nil)
+}
+
+// CommitTransaction runs the queued operations as one dnf transaction.
+// The script passed to dnf shell queues all the packages to install on a
+// single install line, with strict mode on, so a package that cannot be
+// installed fails the whole line and nothing is installed. dnf shell goes on
+// after a failed command, so its output is checked for errors as well.
+//
+// The script of dnf shell is read from the standard input, which leaves no
+// way to stream its output, so a transaction that only installs packages
+// and whose options ask for progress runs as a single strict dnf install
+// instead.
+func (m *Manager) CommitTransaction(ctx context.Context) error {
+	state, err := m.tx.End()
+	if err != nil {
+		return err
+	}
+	if len(state.Install) == 0 && len(state.Remove) == 0 {
+		return nil
+	}
+
+	command := "dnf shell"
+	var result *igorexec.Result
+	if state.Options.Progress != nil && len(state.Remove) == 0 {
+		command = "dnf install"
+		args := append([]string{"--setopt=strict=True"}, m.buildInstallArgs(state.Options, state.Install)...)
+		result = m.executeElevatedWithProgress(ctx, state.Options.Progress, args...)
+	} else {
+		args := m.buildShellArgs(state.Options)
+		cmd := "dnf"
+		if m.privilege != nil {
+			cmd, args = m.privilege.ElevatedCommand(cmd, args...)
+		}
+		result = m.executor.ExecuteWithInput(ctx, []byte(buildShellScript(state)), cmd, args...)
+	}
+
+	output := result.StdoutString() + result.StderrString()
+	if result.Failed() {
+		if strings.Contains(output, "lock") || strings.Contains(output, "another copy is running") {
+			return pkg.Wrap(pkg.ErrLockAcquireFailed, fmt.Errorf("%s failed: %s", command, output))
+		}
+		return pkg.Wrap(pkg.ErrTransactionFailed, fmt.Errorf("%s failed (exit code %d): %s", command, result.ExitCode, output))
+	}
+	if strings.Contains(output, "Error:") {
+		return pkg.Wrap(pkg.ErrTransactionFailed, fmt.Errorf("%s transaction failed: %s", command, output))
+	}
+
+	return nil
+}
+
+// RollbackTransaction discards the queued operations.
+// Nothing has been installed or removed before the commit, so there is
+// nothing to revert.
+func (m *Manager) RollbackTransaction(ctx context.Context) error {
+	_, err := m.tx.End()
+	return err
+}
+
+// InTransaction returns true if a transaction is in progress.
+func (m *Manager) InTransaction() bool {
+	return m.tx.Active()
+}
+
+// buildShellArgs constructs the dnf shell command arguments.
+// dnf shell has no per-command options, so the install options apply to
+// the whole transaction. Reinstallation is not supported.
+func (m *Manager) buildShellArgs(opts pkg.InstallOptions) []string {
+	args := []string{"shell", "-y", "--setopt=strict=True"}
+
+	if opts.Force || opts.AllowDowngrade {
+		args = append(args, "--allowerasing")
+	}
+	if opts.DownloadOnly {
+		args = append(args, "--downloadonly")
+	}
+	if opts.SkipVerify {
+		args = append(args, "--nogpgcheck")
+	}
+	if opts.Repository != "" {
+		args = append(args, "--repo="+sanitizeRepoID(opts.Repository))
+	}
+
+	return args
+}
+
+// buildShellScript returns the dnf shell script running a transaction.
+func buildShellScript(state pkg.TransactionState) string {
+	var b strings.Builder
+	if len(state.Install) > 0 {
+		b.WriteString("install " + strings.Join(state.Install, " ") + "\n")
+	}
+	if len(state.Remove) > 0 {
+		b.WriteString("remove " + strings.Join(state.Remove, " ") + "\n")
+	}
+	b.WriteString("run\n")
+	return b.String()
+}
+
+// Ensure Manager implements pkg.TransactionalManager interface.
+var _ pkg.TransactionalManager = (*Manager)(nil)
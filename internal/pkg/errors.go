@@ -59,6 +59,40 @@ var (
 		message: "package removal failed",
 	}
 
+	// ErrHoldFailed indicates a package version hold could not be set or released.
+	ErrHoldFailed = &PackageError{
+		code:    igorerrors.PackageManager,
+		message: "package hold failed",
+	}
+
+	// ErrHistoryFailed indicates a transaction from the package manager history
+	// could not be undone or redone.
+	ErrHistoryFailed = &PackageError{
+		code:    igorerrors.PackageManager,
+		message: "history operation failed",
+	}
+
+	// ErrTransactionFailed indicates a package transaction could not be
+	// started, committed or rolled back.
+	ErrTransactionFailed = &PackageError{
+		code:    igorerrors.Installation,
+		message: "package transaction failed",
+	}
+
+	// ErrBundleFailed indicates the packages of an offline bundle could not
+	// be downloaded or indexed.
+	ErrBundleFailed = &PackageError{
+		code:    igorerrors.PackageManager,
+		message: "package bundle failed",
+	}
+
+	// ErrSimulationFailed indicates the package manager could not resolve a
+	// simulated transaction.
+	ErrSimulationFailed = &PackageError{
+		code:    igorerrors.PackageManager,
+		message: "package simulation failed",
+	}
+
 	// ErrLockAcquireFailed indicates the package manager lock could not be acquired.
 	// This typically happens when another package manager instance is running.
 	ErrLockAcquireFailed = &PackageError{
@@ -78,6 +112,13 @@ var (
 		message: "GPG verification failed",
 	}
 
+	// ErrGPGKeyMismatch indicates a repository signing key does not have
+	// the fingerprint pinned for it.
+	ErrGPGKeyMismatch = &PackageError{
+		code:    igorerrors.Validation,
+		message: "GPG key fingerprint mismatch",
+	}
+
 	// ErrUnsupportedOperation indicates the operation is not supported by this package manager.
 	ErrUnsupportedOperation = &PackageError{
 		code:    igorerrors.Unsupported,
@@ -0,0 +1,206 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tungetti/igor/internal/exec"
+)
+
+// KeyPin is the set of fingerprints a repository signing key is expected to
+// have. Keys downloaded for the repository are verified against it before
+// they are trusted, so a key replaced on the server or in transit is
+// rejected instead of imported.
+type KeyPin struct {
+	// Name identifies the key (e.g., "nvidia-cuda"). On package managers
+	// using a keyring per repository, it names the keyring file.
+	Name string
+
+	// Fingerprints are the accepted fingerprints of the primary key.
+	Fingerprints []string
+
+	// Retired are the keys the repository was signed with before a key
+	// rotation. They are no longer accepted.
+	Retired []RetiredKey
+}
+
+// RetiredKey is a repository signing key that was rotated out.
+type RetiredKey struct {
+	// Fingerprint is the fingerprint of the retired key.
+	Fingerprint string
+
+	// ReplacedBy is the fingerprint of the key that replaced it.
+	ReplacedBy string
+
+	// Date is when the key was retired (YYYY-MM-DD).
+	Date string
+}
+
+// String returns a human-readable representation of the rotation.
+func (k RetiredKey) String() string {
+	return fmt.Sprintf("%s (retired on %s, replaced by %s)", k.Fingerprint, k.Date, k.ReplacedBy)
+}
+
+// Accepts reports whether the fingerprint is one of the pinned fingerprints.
+func (p KeyPin) Accepts(fingerprint string) bool {
+	fingerprint = NormalizeFingerprint(fingerprint)
+	for _, f := range p.Fingerprints {
+		if NormalizeFingerprint(f) == fingerprint {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify checks the fingerprints of the primary keys of a key file against
+// the pin. Every key of the file must be pinned, so that no extra key is
+// trusted along with the expected one. It returns ErrGPGKeyMismatch if the
+// file has no key or a key is not pinned, telling when the key is one that
+// was rotated out.
+func (p KeyPin) Verify(fingerprints []string) error {
+	if len(fingerprints) == 0 {
+		return Wrap(ErrGPGKeyMismatch, fmt.Errorf("no key found for %s", p.Name))
+	}
+
+	for _, fingerprint := range fingerprints {
+		if p.Accepts(fingerprint) {
+			continue
+		}
+		if retired := p.RetiredKeys([]string{fingerprint}); len(retired) > 0 {
+			return Wrap(ErrGPGKeyMismatch, fmt.Errorf("key for %s is the retired key %s", p.Name, retired[0]))
+		}
+		return Wrap(ErrGPGKeyMismatch, fmt.Errorf("key for %s has fingerprint %s, expected %s; if the repository rotated its key, update igor",
+			p.Name, NormalizeFingerprint(fingerprint), strings.Join(p.Fingerprints, " or ")))
+	}
+	return nil
+}
+
+// RetiredKeys returns the retired keys of the pin among the given
+// fingerprints or key IDs. Key IDs, such as the 8-digit IDs rpm uses, match
+// the end of a fingerprint.
+func (p KeyPin) RetiredKeys(fingerprints []string) []RetiredKey {
+	var retired []RetiredKey
+	for _, key := range p.Retired {
+		for _, fingerprint := range fingerprints {
+			fingerprint = NormalizeFingerprint(fingerprint)
+			if fingerprint != "" && strings.HasSuffix(NormalizeFingerprint(key.Fingerprint), fingerprint) {
+				retired = append(retired, key)
+				break
+			}
+		}
+	}
+	return retired
+}
+
+// KeyImport is the result of importing a pinned key.
+type KeyImport struct {
+	// Fingerprints are the fingerprints of the imported key.
+	Fingerprints []string
+
+	// Keyring is the keyring file the key was written to, on package
+	// managers using a keyring per repository; repositories reference it
+	// with their GPGKey. It is empty when the key was added to the trust
+	// store of the package manager.
+	Keyring string
+
+	// Retired are the retired keys of the pin the package manager still
+	// trusts. They should be removed, as the repository no longer uses
+	// them.
+	Retired []RetiredKey
+}
+
+// NormalizeFingerprint returns the fingerprint in upper case without
+// spaces or a "0x" prefix, as printed by "gpg --with-colons".
+func NormalizeFingerprint(fingerprint string) string {
+	fingerprint = strings.ToUpper(strings.ReplaceAll(fingerprint, " ", ""))
+	return strings.TrimPrefix(fingerprint, "0X")
+}
+
+// ParseGPGFingerprints parses the output of
+// "gpg --show-keys --with-colons" into the fingerprints of the primary keys.
+// Subkey fingerprints are skipped.
+func ParseGPGFingerprints(output string) []string {
+	var fingerprints []string
+	primary := false
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(strings.TrimSpace(line), ":")
+		switch fields[0] {
+		case "pub", "sec":
+			primary = true
+		case "sub", "ssb":
+			primary = false
+		case "fpr":
+			if primary && len(fields) > 9 && fields[9] != "" {
+				fingerprints = append(fingerprints, NormalizeFingerprint(fields[9]))
+				primary = false
+			}
+		}
+	}
+	return fingerprints
+}
+
+// GPGKeyFile is a downloaded key, verified against its pin.
+type GPGKeyFile struct {
+	// Path is the temporary file holding the key.
+	Path string
+
+	// Fingerprints are the fingerprints of the primary keys of the file.
+	Fingerprints []string
+
+	executor exec.Executor
+}
+
+// Remove deletes the temporary key file.
+func (f *GPGKeyFile) Remove(ctx context.Context) {
+	f.executor.Execute(ctx, "rm", "-f", f.Path)
+}
+
+// FetchPinnedGPGKey downloads the key at keyURL into a temporary file and
+// verifies it against the pin. The caller imports the file and then removes
+// it. The download runs unprivileged and only touches the temporary file,
+// so previews of a workflow verify the key as well. Returns
+// ErrNetworkUnavailable if the key cannot be downloaded, and
+// ErrGPGKeyMismatch if it does not match the pin.
+func FetchPinnedGPGKey(ctx context.Context, executor exec.Executor, keyURL string, pin KeyPin) (*GPGKeyFile, error) {
+	if !strings.HasPrefix(keyURL, "https://") && !strings.HasPrefix(keyURL, "http://") {
+		return nil, fmt.Errorf("invalid GPG key URL: must start with http:// or https://")
+	}
+
+	result := executor.Execute(ctx, "mktemp", "-t", "igor-key.XXXXXXXXXX")
+	path := strings.TrimSpace(result.StdoutString())
+	if result.Failed() || path == "" {
+		return nil, fmt.Errorf("failed to create temporary key file: %s", result.StderrString())
+	}
+	file := &GPGKeyFile{Path: path, executor: executor}
+
+	result = executor.Execute(ctx, "curl", "-fsSL", "-o", path, keyURL)
+	if result.Failed() {
+		file.Remove(ctx)
+		return nil, Wrap(ErrNetworkUnavailable, fmt.Errorf("failed to download GPG key %s: %s", keyURL, result.StderrString()))
+	}
+
+	result = executor.Execute(ctx, "gpg", "--show-keys", "--with-colons", "--with-fingerprint", path)
+	if result.Failed() {
+		file.Remove(ctx)
+		return nil, Wrap(ErrGPGKeyMismatch, fmt.Errorf("%s is not a GPG key: %s", keyURL, result.StderrString()))
+	}
+
+	file.Fingerprints = ParseGPGFingerprints(result.StdoutString())
+	if err := pin.Verify(file.Fingerprints); err != nil {
+		file.Remove(ctx)
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// InstalledRPMKeyIDs returns the IDs of the keys in the rpm database, the
+// last 8 hex digits of their fingerprints.
+func InstalledRPMKeyIDs(ctx context.Context, executor exec.Executor) []string {
+	result := executor.Execute(ctx, "rpm", "-q", "gpg-pubkey", "--qf", "%{VERSION}\\n")
+	if result.Failed() {
+		return nil
+	}
+	return strings.Fields(result.StdoutString())
+}
@@ -0,0 +1,151 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/exec"
+)
+
+const (
+	testCurrentFingerprint = "EB693B3035CD5710E231E123A4B469963BF863CC"
+	testRetiredFingerprint = "AE09FE4BBD223A84B2CCFCE3F60F4B3D7FA2AF80"
+)
+
+// testGPGOutput is "gpg --show-keys --with-colons" output for a key with a
+// subkey.
+const testGPGOutput = `pub:-:4096:1:A4B469963BF863CC:1649875437:::-:::scESC::::::23::0:
+fpr:::::::::EB693B3035CD5710E231E123A4B469963BF863CC:
+uid:-::::1649875437::ED8D1D9F8AF59D4C54DE3EE36D8F1F6B0F6C04C3::cudatools <cudatools@nvidia.com>::::::::::0:
+sub:-:4096:1:1A3E1A35C1B1F08B:1649875437::::::e::::::23:
+fpr:::::::::0DB4C1E3A7F2B1D3C3A66A9F1A3E1A35C1B1F08B:
+`
+
+func newTestKeyPin() KeyPin {
+	return KeyPin{
+		Name:         "nvidia-cuda",
+		Fingerprints: []string{testCurrentFingerprint},
+		Retired: []RetiredKey{
+			{Fingerprint: testRetiredFingerprint, ReplacedBy: testCurrentFingerprint, Date: "2022-04-27"},
+		},
+	}
+}
+
+func TestNormalizeFingerprint(t *testing.T) {
+	assert.Equal(t, testCurrentFingerprint, NormalizeFingerprint("eb69 3b30 35cd 5710 e231  e123 a4b4 6996 3bf8 63cc"))
+	assert.Equal(t, "3BF863CC", NormalizeFingerprint("0x3bf863cc"))
+}
+
+func TestParseGPGFingerprints(t *testing.T) {
+	assert.Equal(t, []string{testCurrentFingerprint}, ParseGPGFingerprints(testGPGOutput))
+	assert.Empty(t, ParseGPGFingerprints(""))
+	assert.Empty(t, ParseGPGFingerprints("gpg: no valid OpenPGP data found."))
+}
+
+func TestKeyPin_Verify(t *testing.T) {
+	pin := newTestKeyPin()
+
+	assert.NoError(t, pin.Verify([]string{testCurrentFingerprint}))
+	assert.NoError(t, pin.Verify([]string{"eb693b3035cd5710e231e123a4b469963bf863cc"}))
+
+	err := pin.Verify(nil)
+	assert.ErrorIs(t, err, ErrGPGKeyMismatch)
+
+	err = pin.Verify([]string{"0123456789ABCDEF0123456789ABCDEF01234567"})
+	require.ErrorIs(t, err, ErrGPGKeyMismatch)
+	assert.Contains(t, err.Error(), "has fingerprint 0123456789ABCDEF0123456789ABCDEF01234567, expected "+testCurrentFingerprint)
+
+	err = pin.Verify([]string{testRetiredFingerprint})
+	require.ErrorIs(t, err, ErrGPGKeyMismatch)
+	assert.Contains(t, err.Error(), "is the retired key "+testRetiredFingerprint+" (retired on 2022-04-27")
+
+	// An extra key in the file is rejected too.
+	err = pin.Verify([]string{testCurrentFingerprint, "0123456789ABCDEF0123456789ABCDEF01234567"})
+	assert.ErrorIs(t, err, ErrGPGKeyMismatch)
+}
+
+func TestKeyPin_RetiredKeys(t *testing.T) {
+	pin := newTestKeyPin()
+
+	assert.Len(t, pin.RetiredKeys([]string{testRetiredFingerprint}), 1)
+	assert.Len(t, pin.RetiredKeys([]string{"7fa2af80", "3bf863cc"}), 1)
+	assert.Empty(t, pin.RetiredKeys([]string{"3bf863cc", ""}))
+}
+
+func TestFetchPinnedGPGKey(t *testing.T) {
+	ctx := context.Background()
+	pin := newTestKeyPin()
+
+	newExecutor := func() *exec.MockExecutor {
+		executor := exec.NewMockExecutor()
+		executor.SetResponse("mktemp", exec.SuccessResult("/tmp/igor-key.abc\n"))
+		executor.SetResponse("curl", exec.SuccessResult(""))
+		executor.SetResponse("gpg", exec.SuccessResult(testGPGOutput))
+		executor.SetResponse("rm", exec.SuccessResult(""))
+		return executor
+	}
+
+	t.Run("verified", func(t *testing.T) {
+		executor := newExecutor()
+
+		file, err := FetchPinnedGPGKey(ctx, executor, "https://example.com/3bf863cc.pub", pin)
+		require.NoError(t, err)
+
+		assert.Equal(t, "/tmp/igor-key.abc", file.Path)
+		assert.Equal(t, []string{testCurrentFingerprint}, file.Fingerprints)
+		assert.True(t, executor.WasCalledWith("curl", "-fsSL", "-o", "/tmp/igor-key.abc", "https://example.com/3bf863cc.pub"))
+		assert.False(t, executor.WasCalled("rm"))
+
+		file.Remove(ctx)
+		assert.True(t, executor.WasCalledWith("rm", "-f", "/tmp/igor-key.abc"))
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		executor := newExecutor()
+		executor.SetResponse("gpg", exec.SuccessResult("pub:-:4096:1:F60F4B3D7FA2AF80:1:::-:::scSC:\nfpr:::::::::"+testRetiredFingerprint+":\n"))
+
+		_, err := FetchPinnedGPGKey(ctx, executor, "https://example.com/7fa2af80.pub", pin)
+		require.ErrorIs(t, err, ErrGPGKeyMismatch)
+		assert.Contains(t, err.Error(), "retired")
+		assert.True(t, executor.WasCalledWith("rm", "-f", "/tmp/igor-key.abc"))
+	})
+
+	t.Run("not a key", func(t *testing.T) {
+		executor := newExecutor()
+		executor.SetResponse("gpg", exec.FailureResult(2, "gpg: no valid OpenPGP data found."))
+
+		_, err := FetchPinnedGPGKey(ctx, executor, "https://example.com/key", pin)
+		assert.ErrorIs(t, err, ErrGPGKeyMismatch)
+	})
+
+	t.Run("download fails", func(t *testing.T) {
+		executor := newExecutor()
+		executor.SetResponse("curl", exec.FailureResult(6, "curl: (6) Could not resolve host: example.com"))
+
+		_, err := FetchPinnedGPGKey(ctx, executor, "https://example.com/key", pin)
+		assert.ErrorIs(t, err, ErrNetworkUnavailable)
+		assert.True(t, executor.WasCalled("rm"))
+	})
+
+	t.Run("invalid URL", func(t *testing.T) {
+		executor := newExecutor()
+
+		_, err := FetchPinnedGPGKey(ctx, executor, "file:///etc/passwd", pin)
+		require.Error(t, err)
+		assert.False(t, errors.Is(err, ErrGPGKeyMismatch))
+		assert.Equal(t, 0, executor.CallCount())
+	})
+}
+
+func TestInstalledRPMKeyIDs(t *testing.T) {
+	executor := exec.NewMockExecutor()
+	executor.SetResponse("rpm", exec.SuccessResult("7fa2af80\n3bf863cc\n"))
+
+	assert.Equal(t, []string{"7fa2af80", "3bf863cc"}, InstalledRPMKeyIDs(context.Background(), executor))
+
+	executor.SetResponse("rpm", exec.FailureResult(1, "package gpg-pubkey is not installed"))
+	assert.Empty(t, InstalledRPMKeyIDs(context.Background(), executor))
+}
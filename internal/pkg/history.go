@@ -0,0 +1,69 @@
+package pkg
+
+import (
+	"strings"
+	"time"
+)
+
+// Operations of history entries, shared by the backends.
+const (
+	HistoryInstall   = "install"
+	HistoryUpgrade   = "upgrade"
+	HistoryDowngrade = "downgrade"
+	HistoryReinstall = "reinstall"
+	HistoryRemove    = "remove"
+)
+
+// Time returns the time of the operation, or the zero time if unknown.
+func (h HistoryEntry) Time() time.Time {
+	if h.Timestamp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(h.Timestamp, 0)
+}
+
+// AddOperation adds an operation to the entry, unless it is listed already.
+func (h *HistoryEntry) AddOperation(operation string) {
+	if operation == "" {
+		return
+	}
+	for _, op := range strings.Split(h.Operation, ", ") {
+		if op == operation {
+			return
+		}
+	}
+	if h.Operation == "" {
+		h.Operation = operation
+	} else {
+		h.Operation += ", " + operation
+	}
+}
+
+// AddPackage adds a package to the entry, unless it is listed already.
+func (h *HistoryEntry) AddPackage(name string) {
+	if name == "" {
+		return
+	}
+	for _, p := range h.Packages {
+		if p == name {
+			return
+		}
+	}
+	h.Packages = append(h.Packages, name)
+}
+
+// NewestHistory returns the newest limit entries of a history read from a
+// log, newest first. entries must be in log order, oldest first. A limit of
+// 0 returns all the entries.
+func NewestHistory(entries []HistoryEntry, limit int) []HistoryEntry {
+	count := len(entries)
+	if limit > 0 && limit < count {
+		count = limit
+	}
+
+	newest := make([]HistoryEntry, 0, count)
+	for i := len(entries) - 1; i >= 0 && len(newest) < count; i-- {
+		newest = append(newest, entries[i])
+	}
+	return newest
+}
@@ -0,0 +1,56 @@
+package pkg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryEntry_Time(t *testing.T) {
+	assert.True(t, HistoryEntry{}.Time().IsZero())
+
+	entry := HistoryEntry{Timestamp: 1704067200}
+	assert.True(t, entry.Time().Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestHistoryEntry_AddOperation(t *testing.T) {
+	var entry HistoryEntry
+
+	entry.AddOperation(HistoryInstall)
+	assert.Equal(t, "install", entry.Operation)
+
+	entry.AddOperation(HistoryUpgrade)
+	entry.AddOperation(HistoryInstall)
+	entry.AddOperation("")
+	assert.Equal(t, "install, upgrade", entry.Operation)
+}
+
+func TestHistoryEntry_AddPackage(t *testing.T) {
+	var entry HistoryEntry
+
+	entry.AddPackage("nvidia-driver-550")
+	entry.AddPackage("libnvidia-gl-550")
+	entry.AddPackage("nvidia-driver-550")
+	entry.AddPackage("")
+
+	assert.Equal(t, []string{"nvidia-driver-550", "libnvidia-gl-550"}, entry.Packages)
+}
+
+func TestNewestHistory(t *testing.T) {
+	entries := []HistoryEntry{{ID: "1"}, {ID: "2"}, {ID: "3"}}
+
+	ids := func(entries []HistoryEntry) []string {
+		result := make([]string, 0, len(entries))
+		for _, e := range entries {
+			result = append(result, e.ID)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"3", "2", "1"}, ids(NewestHistory(entries, 0)))
+	assert.Equal(t, []string{"3", "2"}, ids(NewestHistory(entries, 2)))
+	assert.Equal(t, []string{"3", "2", "1"}, ids(NewestHistory(entries, 10)))
+	assert.Empty(t, NewestHistory(nil, 5))
+	assert.NotNil(t, NewestHistory(nil, 0))
+}
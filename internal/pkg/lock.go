@@ -0,0 +1,295 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tungetti/igor/internal/exec"
+)
+
+// DefaultLockPollInterval is how often WaitForLock checks the lock.
+const DefaultLockPollInterval = 2 * time.Second
+
+// LockKind describes how a package manager marks its lock as held.
+type LockKind int
+
+const (
+	// LockKindOpenFile is a lock held while a process keeps the lock file
+	// open, as dpkg, APT, DNF and RPM do with their fcntl locks.
+	LockKindOpenFile LockKind = iota
+
+	// LockKindPIDFile is a lock file holding the PID of the process holding
+	// the lock, as used by YUM and libzypp. A file left behind by a process
+	// that is gone does not lock.
+	LockKindPIDFile
+
+	// LockKindExistence is a lock held while the lock file exists, as used
+	// by pacman. A file left behind by a crashed process still locks.
+	LockKindExistence
+)
+
+// LockFile describes a lock file of a package manager.
+type LockFile struct {
+	Path string
+	Kind LockKind
+}
+
+// LockHolder describes the process holding a package manager lock.
+type LockHolder struct {
+	// PID is the process ID, or 0 if the process is unknown.
+	PID int
+	// Command is the process name, or empty if unknown.
+	Command string
+	// Path is the lock file that is held.
+	Path string
+}
+
+// String returns a description of the holder, such as
+// "unattended-upgr (pid 812)".
+func (h *LockHolder) String() string {
+	switch {
+	case h.PID == 0:
+		return "an unknown process (" + h.Path + " exists)"
+	case h.Command == "":
+		return fmt.Sprintf("pid %d", h.PID)
+	default:
+		return fmt.Sprintf("%s (pid %d)", h.Command, h.PID)
+	}
+}
+
+// LockChecker finds the process holding the lock of a package manager.
+// It implements the lock methods of LockableManager for the backends.
+//
+// Package managers take their lock themselves when igor runs them, so the
+// lock cannot be held on their behalf: AcquireLock only verifies that the
+// lock is free and ReleaseLock has nothing to release. What matters is
+// waiting for other package managers, such as unattended-upgrades or
+// PackageKit, to finish before starting an operation.
+type LockChecker struct {
+	executor     exec.Executor
+	name         string
+	files        []LockFile
+	pollInterval time.Duration
+}
+
+// NewLockChecker creates a lock checker for the package manager called
+// name, using the given lock files.
+func NewLockChecker(executor exec.Executor, name string, files ...LockFile) *LockChecker {
+	return &LockChecker{
+		executor:     executor,
+		name:         name,
+		files:        files,
+		pollInterval: DefaultLockPollInterval,
+	}
+}
+
+// SetPollInterval sets how often WaitForLock checks the lock.
+func (c *LockChecker) SetPollInterval(interval time.Duration) {
+	if interval > 0 {
+		c.pollInterval = interval
+	}
+}
+
+// Files returns the lock files that are checked.
+func (c *LockChecker) Files() []LockFile {
+	return append([]LockFile{}, c.files...)
+}
+
+// AcquireLock returns ErrLockAcquireFailed, naming the holder, if the lock
+// is held by another process.
+func (c *LockChecker) AcquireLock(ctx context.Context) error {
+	holder, err := c.LockHolder(ctx)
+	if err != nil {
+		return Wrap(ErrLockAcquireFailed, err)
+	}
+	if holder != nil {
+		return Wrap(ErrLockAcquireFailed, fmt.Errorf("%s lock is held by %s", c.name, holder))
+	}
+	return nil
+}
+
+// ReleaseLock does nothing: the lock is released by the package manager
+// commands that took it.
+func (c *LockChecker) ReleaseLock(ctx context.Context) error {
+	return nil
+}
+
+// IsLocked reports whether the lock is held by another process.
+func (c *LockChecker) IsLocked(ctx context.Context) (bool, error) {
+	holder, err := c.LockHolder(ctx)
+	if err != nil {
+		return false, err
+	}
+	return holder != nil, nil
+}
+
+// WaitForLock waits until the lock is free. It returns ErrLockAcquireFailed,
+// naming the holder, when ctx is done first.
+func (c *LockChecker) WaitForLock(ctx context.Context) error {
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	var holder *LockHolder
+	for {
+		current, err := c.LockHolder(ctx)
+		if err != nil {
+			// The context may end while the holder is looked up
+			if holder != nil && ctx.Err() != nil {
+				return c.gaveUp(ctx, holder)
+			}
+			return Wrap(ErrLockAcquireFailed, err)
+		}
+		if current == nil {
+			return nil
+		}
+		holder = current
+
+		select {
+		case <-ctx.Done():
+			return c.gaveUp(ctx, holder)
+		case <-ticker.C:
+		}
+	}
+}
+
+// gaveUp returns the error of WaitForLock when the context ends while the
+// lock is held.
+func (c *LockChecker) gaveUp(ctx context.Context, holder *LockHolder) error {
+	return Wrap(ErrLockAcquireFailed, fmt.Errorf("gave up waiting for the %s lock held by %s: %w", c.name, holder, ctx.Err()))
+}
+
+// LockHolder returns the process holding the lock, or nil if the lock is
+// free. Lock files are checked in order and the first holder is returned.
+//
+// Processes keeping a lock file open can only be seen with root
+// privileges; without them such locks appear free.
+func (c *LockChecker) LockHolder(ctx context.Context) (*LockHolder, error) {
+	var openFiles []string
+	for _, file := range c.files {
+		switch file.Kind {
+		case LockKindOpenFile:
+			openFiles = append(openFiles, file.Path)
+		case LockKindPIDFile:
+			if holder := c.pidFileHolder(ctx, file.Path); holder != nil {
+				return holder, nil
+			}
+		case LockKindExistence:
+			if result := c.executor.Execute(ctx, "test", "-e", file.Path); !result.Failed() {
+				holders := c.openFileHolders(ctx, []string{file.Path})
+				if len(holders) > 0 {
+					return holders[0], nil
+				}
+				return &LockHolder{Path: file.Path}, nil
+			}
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if holders := c.openFileHolders(ctx, openFiles); len(holders) > 0 {
+		// Report the holder of the first lock file in order
+		for _, path := range openFiles {
+			for _, holder := range holders {
+				if holder.Path == path {
+					return holder, nil
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// pidFileHolder returns the running process whose PID a lock file holds.
+func (c *LockChecker) pidFileHolder(ctx context.Context, path string) *LockHolder {
+	result := c.executor.Execute(ctx, "cat", path)
+	if result.Failed() {
+		return nil
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(result.StdoutString()))
+	if err != nil || pid <= 0 {
+		return nil
+	}
+
+	command, running := c.processName(ctx, pid)
+	if !running {
+		return nil
+	}
+	return &LockHolder{PID: pid, Command: command, Path: path}
+}
+
+// openFileHolders returns the processes that have one of the given files
+// open, found through the file descriptors listed in /proc.
+func (c *LockChecker) openFileHolders(ctx context.Context, paths []string) []*LockHolder {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	args := []string{"/proc", "-mindepth", "3", "-maxdepth", "3", "-path", "/proc/[0-9]*/fd/*", "("}
+	for i, path := range paths {
+		if i > 0 {
+			args = append(args, "-o")
+		}
+		args = append(args, "-lname", path)
+	}
+	args = append(args, ")", "-printf", "%p %l\\n")
+
+	// find reports processes it cannot inspect as errors, so its exit code
+	// is ignored and only the matches are used.
+	result := c.executor.Execute(ctx, "find", args...)
+
+	var holders []*LockHolder
+	for _, match := range parseProcFDMatches(result.StdoutString()) {
+		command, _ := c.processName(ctx, match.PID)
+		holders = append(holders, &LockHolder{PID: match.PID, Command: command, Path: match.Path})
+	}
+	return holders
+}
+
+// processName returns the name of a process and whether it is running.
+func (c *LockChecker) processName(ctx context.Context, pid int) (string, bool) {
+	result := c.executor.Execute(ctx, "cat", fmt.Sprintf("/proc/%d/comm", pid))
+	if result.Failed() {
+		return "", false
+	}
+	return strings.TrimSpace(result.StdoutString()), true
+}
+
+// parseProcFDMatches parses lines of "/proc/<pid>/fd/<fd> <target>", as
+// printed by find for the file descriptors of processes, into the process
+// and target of each line. Duplicate process and target pairs are dropped.
+func parseProcFDMatches(output string) []LockHolder {
+	var matches []LockHolder
+	seen := make(map[string]bool)
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		fdPath, target, ok := strings.Cut(line, " ")
+		if !ok || !strings.HasPrefix(fdPath, "/proc/") {
+			continue
+		}
+
+		parts := strings.Split(strings.TrimPrefix(fdPath, "/proc/"), "/")
+		if len(parts) != 3 || parts[1] != "fd" {
+			continue
+		}
+		pid, err := strconv.Atoi(parts[0])
+		if err != nil || pid <= 0 {
+			continue
+		}
+
+		key := parts[0] + " " + target
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		matches = append(matches, LockHolder{PID: pid, Path: target})
+	}
+
+	return matches
+}
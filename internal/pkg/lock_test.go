@@ -0,0 +1,323 @@
+package pkg
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/exec"
+)
+
+// lockExecutor is a mock executor that answers Execute by the full command
+// line, since the lock checker runs cat both on lock files and on /proc.
+// Unknown command lines fail. If set, intercept answers a command line
+// before the responses when it returns a result.
+type lockExecutor struct {
+	*exec.MockExecutor
+	responses map[string]*exec.Result
+	intercept func(line string) *exec.Result
+	checks    int
+	freeAfter int
+}
+
+func newLockExecutor() *lockExecutor {
+	return &lockExecutor{
+		MockExecutor: exec.NewMockExecutor(),
+		responses:    make(map[string]*exec.Result),
+	}
+}
+
+func (e *lockExecutor) Execute(ctx context.Context, cmd string, args ...string) *exec.Result {
+	e.MockExecutor.Execute(ctx, cmd, args...)
+	if cmd == "find" {
+		e.checks++
+		if e.freeAfter > 0 && e.checks > e.freeAfter {
+			return exec.SuccessResult("")
+		}
+	}
+	line := strings.Join(append([]string{cmd}, args...), " ")
+	if cmd == "find" {
+		line = "find"
+	}
+	if e.intercept != nil {
+		if result := e.intercept(line); result != nil {
+			return result
+		}
+	}
+	if result, ok := e.responses[line]; ok {
+		return result
+	}
+	return exec.FailureResult(1, "")
+}
+
+// =============================================================================
+// Lock Holder Tests
+// =============================================================================
+
+func TestLockHolder_String(t *testing.T) {
+	tests := []struct {
+		name     string
+		holder   LockHolder
+		expected string
+	}{
+		{"command and pid", LockHolder{PID: 812, Command: "unattended-upgr", Path: "/lock"}, "unattended-upgr (pid 812)"},
+		{"pid only", LockHolder{PID: 812, Path: "/lock"}, "pid 812"},
+		{"unknown process", LockHolder{Path: "/var/lib/pacman/db.lck"}, "an unknown process (/var/lib/pacman/db.lck exists)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.holder.String())
+		})
+	}
+}
+
+func TestParseProcFDMatches(t *testing.T) {
+	output := "/proc/812/fd/5 /var/lib/dpkg/lock-frontend\n" +
+		"/proc/812/fd/6 /var/lib/dpkg/lock\n" +
+		"/proc/812/fd/9 /var/lib/dpkg/lock\n" +
+		"/proc/self/fd/3 /var/lib/dpkg/lock\n" +
+		"/proc/900/task/fd /var/lib/dpkg/lock\n" +
+		"find: '/proc/1/fd': Permission denied\n" +
+		"\n"
+
+	matches := parseProcFDMatches(output)
+
+	assert.Equal(t, []LockHolder{
+		{PID: 812, Path: "/var/lib/dpkg/lock-frontend"},
+		{PID: 812, Path: "/var/lib/dpkg/lock"},
+	}, matches)
+	assert.Empty(t, parseProcFDMatches(""))
+}
+
+// =============================================================================
+// Lock Checker Tests
+// =============================================================================
+
+func TestLockChecker_OpenFile(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("free", func(t *testing.T) {
+		executor := newLockExecutor()
+		executor.responses["find"] = exec.SuccessResult("")
+		checker := NewLockChecker(executor, "dpkg",
+			LockFile{Path: "/var/lib/dpkg/lock-frontend", Kind: LockKindOpenFile},
+			LockFile{Path: "/var/lib/dpkg/lock", Kind: LockKindOpenFile},
+		)
+
+		holder, err := checker.LockHolder(ctx)
+		require.NoError(t, err)
+		assert.Nil(t, holder)
+
+		locked, err := checker.IsLocked(ctx)
+		require.NoError(t, err)
+		assert.False(t, locked)
+		assert.NoError(t, checker.AcquireLock(ctx))
+		assert.NoError(t, checker.ReleaseLock(ctx))
+
+		assert.True(t, executor.WasCalledWith("find", "/proc", "-mindepth", "3", "-maxdepth", "3",
+			"-path", "/proc/[0-9]*/fd/*", "(",
+			"-lname", "/var/lib/dpkg/lock-frontend", "-o", "-lname", "/var/lib/dpkg/lock",
+			")", "-printf", "%p %l\\n"))
+	})
+
+	t.Run("held", func(t *testing.T) {
+		executor := newLockExecutor()
+		executor.responses["find"] = exec.FailureResult(1, "find: '/proc/1/fd': Permission denied")
+		executor.responses["find"].Stdout = []byte("/proc/77/fd/4 /var/lib/dpkg/lock\n/proc/812/fd/5 /var/lib/dpkg/lock-frontend\n")
+		executor.responses["cat /proc/812/comm"] = exec.SuccessResult("unattended-upgr\n")
+		checker := NewLockChecker(executor, "dpkg",
+			LockFile{Path: "/var/lib/dpkg/lock-frontend", Kind: LockKindOpenFile},
+			LockFile{Path: "/var/lib/dpkg/lock", Kind: LockKindOpenFile},
+		)
+
+		holder, err := checker.LockHolder(ctx)
+		require.NoError(t, err)
+		require.NotNil(t, holder)
+		assert.Equal(t, 812, holder.PID)
+		assert.Equal(t, "unattended-upgr", holder.Command)
+		assert.Equal(t, "/var/lib/dpkg/lock-frontend", holder.Path)
+
+		err = checker.AcquireLock(ctx)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrLockAcquireFailed)
+		assert.Contains(t, err.Error(), "dpkg lock is held by unattended-upgr (pid 812)")
+	})
+}
+
+func TestLockChecker_PIDFile(t *testing.T) {
+	ctx := context.Background()
+	files := []LockFile{{Path: "/var/run/zypp.pid", Kind: LockKindPIDFile}}
+
+	t.Run("running process", func(t *testing.T) {
+		executor := newLockExecutor()
+		executor.responses["cat /var/run/zypp.pid"] = exec.SuccessResult("4242\n")
+		executor.responses["cat /proc/4242/comm"] = exec.SuccessResult("packagekitd\n")
+		checker := NewLockChecker(executor, "zypp", files...)
+
+		holder, err := checker.LockHolder(ctx)
+		require.NoError(t, err)
+		require.NotNil(t, holder)
+		assert.Equal(t, "packagekitd (pid 4242)", holder.String())
+		assert.False(t, executor.WasCalled("find"))
+	})
+
+	t.Run("stale pid file", func(t *testing.T) {
+		executor := newLockExecutor()
+		executor.responses["cat /var/run/zypp.pid"] = exec.SuccessResult("4242\n")
+		checker := NewLockChecker(executor, "zypp", files...)
+
+		locked, err := checker.IsLocked(ctx)
+		require.NoError(t, err)
+		assert.False(t, locked)
+	})
+
+	t.Run("no pid file", func(t *testing.T) {
+		executor := newLockExecutor()
+		checker := NewLockChecker(executor, "zypp", files...)
+
+		locked, err := checker.IsLocked(ctx)
+		require.NoError(t, err)
+		assert.False(t, locked)
+	})
+
+	t.Run("invalid pid", func(t *testing.T) {
+		executor := newLockExecutor()
+		executor.responses["cat /var/run/zypp.pid"] = exec.SuccessResult("\n")
+		checker := NewLockChecker(executor, "zypp", files...)
+
+		locked, err := checker.IsLocked(ctx)
+		require.NoError(t, err)
+		assert.False(t, locked)
+	})
+}
+
+func TestLockChecker_Existence(t *testing.T) {
+	ctx := context.Background()
+	files := []LockFile{{Path: "/var/lib/pacman/db.lck", Kind: LockKindExistence}}
+
+	t.Run("missing", func(t *testing.T) {
+		executor := newLockExecutor()
+		checker := NewLockChecker(executor, "pacman", files...)
+
+		holder, err := checker.LockHolder(ctx)
+		require.NoError(t, err)
+		assert.Nil(t, holder)
+	})
+
+	t.Run("held by a running process", func(t *testing.T) {
+		executor := newLockExecutor()
+		executor.responses["test -e /var/lib/pacman/db.lck"] = exec.SuccessResult("")
+		executor.responses["find"] = exec.SuccessResult("/proc/300/fd/3 /var/lib/pacman/db.lck\n")
+		executor.responses["cat /proc/300/comm"] = exec.SuccessResult("pacman\n")
+		checker := NewLockChecker(executor, "pacman", files...)
+
+		holder, err := checker.LockHolder(ctx)
+		require.NoError(t, err)
+		require.NotNil(t, holder)
+		assert.Equal(t, "pacman (pid 300)", holder.String())
+	})
+
+	t.Run("left behind", func(t *testing.T) {
+		executor := newLockExecutor()
+		executor.responses["test -e /var/lib/pacman/db.lck"] = exec.SuccessResult("")
+		executor.responses["find"] = exec.SuccessResult("")
+		checker := NewLockChecker(executor, "pacman", files...)
+
+		holder, err := checker.LockHolder(ctx)
+		require.NoError(t, err)
+		require.NotNil(t, holder)
+		assert.Zero(t, holder.PID)
+		assert.Equal(t, "/var/lib/pacman/db.lck", holder.Path)
+	})
+}
+
+func TestLockChecker_WaitForLock(t *testing.T) {
+	files := []LockFile{{Path: "/var/lib/dpkg/lock-frontend", Kind: LockKindOpenFile}}
+
+	t.Run("lock released", func(t *testing.T) {
+		executor := newLockExecutor()
+		executor.responses["find"] = exec.SuccessResult("/proc/812/fd/5 /var/lib/dpkg/lock-frontend\n")
+		executor.responses["cat /proc/812/comm"] = exec.SuccessResult("apt-get\n")
+		executor.freeAfter = 2
+		checker := NewLockChecker(executor, "dpkg", files...)
+		checker.SetPollInterval(time.Millisecond)
+
+		err := checker.WaitForLock(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 3, executor.checks)
+	})
+
+	t.Run("context done", func(t *testing.T) {
+		executor := newLockExecutor()
+		executor.responses["find"] = exec.SuccessResult("/proc/812/fd/5 /var/lib/dpkg/lock-frontend\n")
+		executor.responses["cat /proc/812/comm"] = exec.SuccessResult("apt-get\n")
+		checker := NewLockChecker(executor, "dpkg", files...)
+		checker.SetPollInterval(time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		err := checker.WaitForLock(ctx)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrLockAcquireFailed)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Contains(t, err.Error(), "held by apt-get (pid 812)")
+	})
+
+	t.Run("context done during a lock check", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		executor := newLockExecutor()
+		executor.responses["cat /var/run/zypp.pid"] = exec.SuccessResult("4242\n")
+		executor.responses["cat /proc/4242/comm"] = exec.SuccessResult("packagekitd\n")
+		reads := 0
+		executor.intercept = func(line string) *exec.Result {
+			if line != "cat /var/run/zypp.pid" {
+				return nil
+			}
+			reads++
+			if reads < 2 {
+				return nil
+			}
+			// The context ends while the lock is checked again
+			cancel()
+			return exec.FailureResult(1, "")
+		}
+		checker := NewLockChecker(executor, "zypp", LockFile{Path: "/var/run/zypp.pid", Kind: LockKindPIDFile})
+		checker.SetPollInterval(time.Millisecond)
+
+		err := checker.WaitForLock(ctx)
+		require.Error(t, err)
+		assert.Equal(t, 2, reads)
+		assert.ErrorIs(t, err, ErrLockAcquireFailed)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Contains(t, err.Error(), "held by packagekitd (pid 4242)")
+	})
+}
+
+func TestLockChecker_SetPollInterval(t *testing.T) {
+	checker := NewLockChecker(exec.NewMockExecutor(), "dpkg")
+	assert.Equal(t, DefaultLockPollInterval, checker.pollInterval)
+
+	checker.SetPollInterval(0)
+	assert.Equal(t, DefaultLockPollInterval, checker.pollInterval)
+
+	checker.SetPollInterval(time.Second)
+	assert.Equal(t, time.Second, checker.pollInterval)
+}
+
+func TestLockChecker_Files(t *testing.T) {
+	files := []LockFile{{Path: "/var/run/zypp.pid", Kind: LockKindPIDFile}}
+	checker := NewLockChecker(exec.NewMockExecutor(), "zypp", files...)
+
+	got := checker.Files()
+	assert.Equal(t, files, got)
+
+	got[0].Path = "/changed"
+	assert.Equal(t, "/var/run/zypp.pid", checker.Files()[0].Path)
+}
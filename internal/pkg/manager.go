@@ -139,8 +139,46 @@ type RepositoryManager interface {
 	ListGPGKeys(ctx context.Context) ([]string, error)
 }
 
+// KeyPinningManager provides repository signing key imports verified
+// against pinned fingerprints. Keys are downloaded and checked before the
+// package manager trusts them, so a key replaced on the server or in
+// transit is rejected.
+type KeyPinningManager interface {
+	Manager
+
+	// ImportPinnedGPGKey downloads the key at keyURL, verifies it against
+	// the pin and imports it. Package managers supporting a keyring per
+	// repository write the key to its own keyring, named after the pin,
+	// instead of trusting it for every repository.
+	// Returns ErrGPGKeyMismatch if the key does not match the pin.
+	ImportPinnedGPGKey(ctx context.Context, keyURL string, pin KeyPin) (*KeyImport, error)
+}
+
+// PreferenceManager provides repository preferences. Package managers pick
+// each package from the repository with its newest version, so a driver
+// installed from a third-party repository can mix its packages with those
+// of the distribution. A preference makes the repository win for the
+// packages of the driver.
+type PreferenceManager interface {
+	Manager
+
+	// PreferRepository gives the repository priority over the others for
+	// the packages matching the globs (e.g., "nvidia-*"). Package managers
+	// with priorities per repository rather than per package give the
+	// whole repository priority.
+	PreferRepository(ctx context.Context, repo Repository, packages []string) error
+
+	// RemoveRepositoryPreference restores the default priority of the
+	// repository. Removing a preference that is not set, or of a repository
+	// that no longer exists, is not an error.
+	RemoveRepositoryPreference(ctx context.Context, name string) error
+}
+
 // LockableManager provides package manager lock management.
-// Some package managers (like APT) use locks to prevent concurrent operations.
+// Package managers use locks to prevent concurrent operations, so an
+// operation started while another package manager (such as
+// unattended-upgrades or PackageKit) is running fails; callers can wait for
+// the lock first.
 type LockableManager interface {
 	Manager
 
@@ -157,37 +195,114 @@ type LockableManager interface {
 	// WaitForLock waits until the package manager lock becomes available.
 	// The context can be used to set a timeout for waiting.
 	WaitForLock(ctx context.Context) error
+
+	// LockHolder returns the process holding the package manager lock,
+	// or nil if the lock is free.
+	LockHolder(ctx context.Context) (*LockHolder, error)
+}
+
+// HoldManager provides package version holds.
+// A held package stays at its installed version: routine upgrades such as
+// "apt upgrade" or "dnf update" leave it alone until the hold is released.
+type HoldManager interface {
+	Manager
+
+	// Hold prevents the packages from being upgraded.
+	// Holding a package that is already held is not an error.
+	// Returns ErrHoldFailed if the hold cannot be set.
+	Hold(ctx context.Context, packages ...string) error
+
+	// Unhold releases the holds on the packages.
+	// Releasing a package that is not held is not an error.
+	// Returns ErrHoldFailed if the hold cannot be released.
+	Unhold(ctx context.Context, packages ...string) error
+
+	// ListHeld returns the names of the held packages.
+	ListHeld(ctx context.Context) ([]string, error)
 }
 
 // TransactionalManager provides transaction support for package operations.
 // Implementations that support atomic/reversible operations should implement this.
+//
+// While a transaction is in progress, Install and Remove are part of it.
+// Package managers with native transactions queue them and run them as one
+// transaction on commit, so a failure changes nothing. The others run them
+// at once and revert to the packages installed when the transaction began
+// on rollback.
 type TransactionalManager interface {
 	Manager
 
 	// BeginTransaction starts a new transaction.
+	// Returns ErrTransactionFailed if a transaction is already in progress.
 	BeginTransaction(ctx context.Context) error
 
 	// CommitTransaction commits the current transaction.
+	// The transaction ends even if the commit fails.
 	CommitTransaction(ctx context.Context) error
 
 	// RollbackTransaction rolls back the current transaction.
+	// Returns ErrTransactionFailed if the changes cannot be reverted.
 	RollbackTransaction(ctx context.Context) error
 
 	// InTransaction returns true if a transaction is currently active.
 	InTransaction() bool
 }
 
+// BundleRepositoryName is the name of the local repository serving an
+// offline package bundle.
+const BundleRepositoryName = "igor-bundle"
+
+// BundleManager provides offline package bundles.
+// A bundle is a directory holding packages and their dependencies, with
+// the metadata needed to serve it as a local repository, so the packages
+// can be installed on a machine without network access.
+type BundleManager interface {
+	Manager
+
+	// DownloadBundle downloads the packages and their dependencies into dir
+	// and writes the repository metadata of the directory.
+	// Returns ErrBundleFailed if the bundle cannot be created.
+	DownloadBundle(ctx context.Context, dir string, packages ...string) error
+
+	// BundleRepository returns the local repository serving the bundle in
+	// dir, named BundleRepositoryName, to register with AddRepository.
+	BundleRepository(dir string) Repository
+}
+
+// SimulationManager provides transaction previews.
+// The package manager resolves the transaction without running it, so the
+// packages that would be removed or replaced are known before installing.
+type SimulationManager interface {
+	Manager
+
+	// SimulateInstall resolves the installation of the packages, as Install
+	// would with the same options, without changing the system.
+	// Simulations are read-only, but some package managers resolve
+	// transactions only when run as root.
+	// Returns ErrPackageNotFound if a package doesn't exist, or
+	// ErrSimulationFailed if the transaction cannot be resolved.
+	SimulateInstall(ctx context.Context, opts InstallOptions, packages ...string) (*Simulation, error)
+}
+
 // HistoryManager provides package operation history.
+// The history is read from the transaction history of the package manager,
+// or from its log on package managers without one, so it includes
+// operations made outside of igor.
 type HistoryManager interface {
 	Manager
 
-	// History returns the package manager operation history.
+	// History returns the package manager operation history, newest first.
+	// At most limit entries are returned; a limit of 0 returns all of them.
 	History(ctx context.Context, limit int) ([]HistoryEntry, error)
 
 	// Undo undoes a previous operation by ID.
+	// Returns ErrUnsupportedOperation if the package manager cannot undo
+	// operations, and ErrHistoryFailed if the undo fails.
 	Undo(ctx context.Context, id string) error
 
 	// Redo redoes a previously undone operation by ID.
+	// Returns ErrUnsupportedOperation if the package manager cannot redo
+	// operations, and ErrHistoryFailed if the redo fails.
 	Redo(ctx context.Context, id string) error
 }
 
@@ -196,10 +311,13 @@ type HistoryEntry struct {
 	// ID is the unique identifier for this history entry.
 	ID string
 
-	// Timestamp is when the operation occurred.
+	// Timestamp is when the operation occurred, in seconds since the Unix
+	// epoch, or 0 if unknown.
 	Timestamp int64
 
 	// Operation is the type of operation (install, remove, upgrade, etc.).
+	// Operations touching packages in several ways list each of them,
+	// separated by commas, such as "install, upgrade".
 	Operation string
 
 	// Packages lists the packages affected by this operation.
@@ -411,9 +411,15 @@ func TestPackageError_Code(t *testing.T) {
 		{"ErrUpdateFailed", ErrUpdateFailed, igorerrors.PackageManager},
 		{"ErrInstallFailed", ErrInstallFailed, igorerrors.Installation},
 		{"ErrRemoveFailed", ErrRemoveFailed, igorerrors.PackageManager},
+		{"ErrHoldFailed", ErrHoldFailed, igorerrors.PackageManager},
+		{"ErrHistoryFailed", ErrHistoryFailed, igorerrors.PackageManager},
+		{"ErrTransactionFailed", ErrTransactionFailed, igorerrors.Installation},
+		{"ErrBundleFailed", ErrBundleFailed, igorerrors.PackageManager},
+		{"ErrSimulationFailed", ErrSimulationFailed, igorerrors.PackageManager},
 		{"ErrLockAcquireFailed", ErrLockAcquireFailed, igorerrors.PackageManager},
 		{"ErrDependencyConflict", ErrDependencyConflict, igorerrors.PackageManager},
 		{"ErrGPGVerificationFailed", ErrGPGVerificationFailed, igorerrors.Validation},
+		{"ErrGPGKeyMismatch", ErrGPGKeyMismatch, igorerrors.Validation},
 		{"ErrUnsupportedOperation", ErrUnsupportedOperation, igorerrors.Unsupported},
 		{"ErrNetworkUnavailable", ErrNetworkUnavailable, igorerrors.Network},
 		{"ErrInsufficientSpace", ErrInsufficientSpace, igorerrors.PackageManager},
@@ -543,9 +549,15 @@ func TestSentinelErrors_Existence(t *testing.T) {
 		ErrUpdateFailed,
 		ErrInstallFailed,
 		ErrRemoveFailed,
+		ErrHoldFailed,
+		ErrHistoryFailed,
+		ErrTransactionFailed,
+		ErrBundleFailed,
+		ErrSimulationFailed,
 		ErrLockAcquireFailed,
 		ErrDependencyConflict,
 		ErrGPGVerificationFailed,
+		ErrGPGKeyMismatch,
 		ErrUnsupportedOperation,
 		ErrNetworkUnavailable,
 		ErrInsufficientSpace,
@@ -567,9 +579,15 @@ func TestSentinelErrors_AreDistinct(t *testing.T) {
 		ErrUpdateFailed,
 		ErrInstallFailed,
 		ErrRemoveFailed,
+		ErrHoldFailed,
+		ErrHistoryFailed,
+		ErrTransactionFailed,
+		ErrBundleFailed,
+		ErrSimulationFailed,
 		ErrLockAcquireFailed,
 		ErrDependencyConflict,
 		ErrGPGVerificationFailed,
+		ErrGPGKeyMismatch,
 		ErrUnsupportedOperation,
 		ErrNetworkUnavailable,
 		ErrInsufficientSpace,
@@ -0,0 +1,272 @@
+package nvidia
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// Driver branch classifications.
+const (
+	BranchLatest     = "Latest"
+	BranchProduction = "Production"
+	BranchLTS        = "LTS"
+	BranchLegacy     = "Legacy"
+)
+
+// DriverBranch describes a single NVIDIA driver branch (major version)
+// as seen by the package manager, combining repository availability
+// with the locally installed packages.
+type DriverBranch struct {
+	// Version is the major driver version (e.g., "550").
+	Version string `json:"version"`
+
+	// Branch is the release classification (Latest, Production, LTS, Legacy).
+	Branch string `json:"branch"`
+
+	// AvailableVersion is the newest full version offered by the repositories.
+	AvailableVersion string `json:"available_version,omitempty"`
+
+	// InstalledVersion is the full version currently installed, if any.
+	InstalledVersion string `json:"installed_version,omitempty"`
+
+	// Packages are the driver packages offered by the repositories for this branch.
+	Packages []string `json:"packages"`
+
+	// InstalledPackages are the driver packages installed for this branch.
+	InstalledPackages []string `json:"installed_packages"`
+
+	// Available indicates the branch can be installed from the configured repositories.
+	Available bool `json:"available"`
+
+	// Installed indicates a driver package from this branch is installed.
+	Installed bool `json:"installed"`
+
+	// Supported indicates the branch meets the GPU's minimum driver version.
+	// Always true when no minimum version is known.
+	Supported bool `json:"supported"`
+
+	// Recommended indicates this is the branch Igor recommends for the detected GPU.
+	Recommended bool `json:"recommended"`
+}
+
+// ClassifyDriverBranch classifies a major driver version into a release branch.
+func ClassifyDriverBranch(version string) string {
+	major := DriverMajorVersion(version)
+	switch {
+	case major >= 560:
+		return BranchLatest
+	case major >= 550:
+		return BranchProduction
+	case major >= 535:
+		return BranchLTS
+	default:
+		return BranchLegacy
+	}
+}
+
+// DriverMajorVersion returns the major component of a driver version string.
+// It accepts full versions ("550.54.14"), package versions with an epoch or
+// release ("3:550.78-1.fc40") and bare majors ("550"). Returns 0 if no major
+// version can be parsed.
+func DriverMajorVersion(version string) int {
+	version = strings.TrimSpace(version)
+	if idx := strings.Index(version, ":"); idx != -1 {
+		version = version[idx+1:]
+	}
+	end := 0
+	for end < len(version) && version[end] >= '0' && version[end] <= '9' {
+		end++
+	}
+	if end == 0 {
+		return 0
+	}
+	major, err := strconv.Atoi(version[:end])
+	if err != nil {
+		return 0
+	}
+	return major
+}
+
+// BuildDriverBranches groups driver packages into branches by major version.
+//
+// available are the packages returned by the package manager search and
+// installed are the packages currently installed; both may include non-driver
+// packages, which are ignored. minDriverVersion is the minimum driver version
+// for the detected GPU (see Architecture.MinDriverVersion); branches older
+// than it are marked unsupported. The newest available supported branch is
+// marked recommended. The result is sorted by version, newest first.
+func BuildDriverBranches(ps *PackageSet, available, installed []pkg.Package, minDriverVersion string) []DriverBranch {
+	branches := make(map[int]*DriverBranch)
+
+	get := func(major int) *DriverBranch {
+		b, ok := branches[major]
+		if !ok {
+			version := strconv.Itoa(major)
+			b = &DriverBranch{
+				Version:           version,
+				Branch:            ClassifyDriverBranch(version),
+				Packages:          make([]string, 0),
+				InstalledPackages: make([]string, 0),
+			}
+			branches[major] = b
+		}
+		return b
+	}
+
+	for _, p := range available {
+		major := driverPackageMajor(ps, p)
+		if major == 0 {
+			continue
+		}
+		b := get(major)
+		b.Available = true
+		b.Packages = appendUnique(b.Packages, p.Name)
+		if isNewerVersion(p.Version, b.AvailableVersion) {
+			b.AvailableVersion = p.Version
+		}
+	}
+
+	for _, p := range installed {
+		major := driverPackageMajor(ps, p)
+		if major == 0 {
+			continue
+		}
+		b := get(major)
+		b.Installed = true
+		b.InstalledPackages = appendUnique(b.InstalledPackages, p.Name)
+		if isNewerVersion(p.Version, b.InstalledVersion) {
+			b.InstalledVersion = p.Version
+		}
+	}
+
+	minMajor := DriverMajorVersion(minDriverVersion)
+
+	result := make([]DriverBranch, 0, len(branches))
+	for major, b := range branches {
+		b.Supported = minMajor == 0 || major >= minMajor
+		sort.Strings(b.Packages)
+		sort.Strings(b.InstalledPackages)
+		result = append(result, *b)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return DriverMajorVersion(result[i].Version) > DriverMajorVersion(result[j].Version)
+	})
+
+	for i := range result {
+		if result[i].Available && result[i].Supported {
+			result[i].Recommended = true
+			break
+		}
+	}
+
+	return result
+}
+
+// driverPackageMajor returns the driver major version a package belongs to,
+// or 0 if the package is not a driver package for this package set.
+// Versioned package names (e.g., "nvidia-driver-550") take precedence over
+// the package version field, which is used for unversioned names such as
+// Arch's "nvidia" or Fedora's "akmod-nvidia".
+func driverPackageMajor(ps *PackageSet, p pkg.Package) int {
+	if ps == nil {
+		return 0
+	}
+
+	for _, pattern := range []string{ps.DriverVersionPattern, ps.DKMSVersionPattern} {
+		if re := versionPatternRegexp(pattern); re != nil {
+			if m := re.FindStringSubmatch(p.Name); len(m) == 2 {
+				return DriverMajorVersion(m[1])
+			}
+		}
+	}
+
+	if containsString(ps.Driver, p.Name) || containsString(ps.DriverDKMS, p.Name) {
+		major := DriverMajorVersion(p.Version)
+		// Guard against packages whose version is not a driver version
+		// (e.g., meta packages versioned 1.0).
+		if major >= 100 {
+			return major
+		}
+	}
+
+	return 0
+}
+
+// versionPatternRegexp converts a printf-style package pattern such as
+// "nvidia-driver-%s" into an anchored regexp capturing the version.
+func versionPatternRegexp(pattern string) *regexp.Regexp {
+	if pattern == "" || !strings.Contains(pattern, "%s") {
+		return nil
+	}
+	parts := strings.SplitN(pattern, "%s", 2)
+	return regexp.MustCompile("^" + regexp.QuoteMeta(parts[0]) + `(\d{3})` + regexp.QuoteMeta(parts[1]) + "$")
+}
+
+// isNewerVersion reports whether candidate should replace current.
+// Versions are compared numerically component by component.
+func isNewerVersion(candidate, current string) bool {
+	if candidate == "" {
+		return false
+	}
+	if current == "" {
+		return true
+	}
+	return compareDriverVersions(candidate, current) > 0
+}
+
+// compareDriverVersions compares two driver version strings numerically.
+// Returns a negative number if a < b, zero if equal, and a positive number if a > b.
+func compareDriverVersions(a, b string) int {
+	split := func(v string) []int {
+		if idx := strings.Index(v, ":"); idx != -1 {
+			v = v[idx+1:]
+		}
+		if idx := strings.IndexAny(v, "-+~"); idx != -1 {
+			v = v[:idx]
+		}
+		var nums []int
+		for _, part := range strings.Split(v, ".") {
+			n, _ := strconv.Atoi(part)
+			nums = append(nums, n)
+		}
+		return nums
+	}
+
+	av, bv := split(a), split(b)
+	for i := 0; i < len(av) || i < len(bv); i++ {
+		var x, y int
+		if i < len(av) {
+			x = av[i]
+		}
+		if i < len(bv) {
+			y = bv[i]
+		}
+		if x != y {
+			return x - y
+		}
+	}
+	return 0
+}
+
+// appendUnique appends s to list if it is not already present.
+func appendUnique(list []string, s string) []string {
+	if containsString(list, s) {
+		return list
+	}
+	return append(list, s)
+}
+
+// containsString reports whether list contains s.
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package nvidia
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+func TestClassifyDriverBranch(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected string
+	}{
+		{"570", BranchLatest},
+		{"560.35.03", BranchLatest},
+		{"550", BranchProduction},
+		{"545", BranchLTS},
+		{"535", BranchLTS},
+		{"470", BranchLegacy},
+		{"", BranchLegacy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ClassifyDriverBranch(tt.version))
+		})
+	}
+}
+
+func TestDriverMajorVersion(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected int
+	}{
+		{"550", 550},
+		{"550.54.14", 550},
+		{"3:550.78-1.fc40", 550},
+		{"550.78-1", 550},
+		{"  535.183.01 ", 535},
+		{"", 0},
+		{"abc", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			assert.Equal(t, tt.expected, DriverMajorVersion(tt.version))
+		})
+	}
+}
+
+func TestCompareDriverVersions(t *testing.T) {
+	assert.Equal(t, 0, compareDriverVersions("550.54.14", "550.54.14"))
+	assert.Positive(t, compareDriverVersions("550.120", "550.54.14"))
+	assert.Negative(t, compareDriverVersions("535.183", "550.54"))
+	assert.Positive(t, compareDriverVersions("3:550.78-1", "550.67-1"))
+}
+
+func TestBuildDriverBranches_Debian(t *testing.T) {
+	ps := GetPackageSetForFamily(constants.FamilyDebian)
+	require.NotNil(t, ps)
+
+	available := []pkg.Package{
+		{Name: "nvidia-driver-550", Version: "550.120-0ubuntu1"},
+		{Name: "nvidia-driver-550", Version: "550.107-0ubuntu1"},
+		{Name: "nvidia-dkms-550", Version: "550.120-0ubuntu1"},
+		{Name: "nvidia-driver-535", Version: "535.183-0ubuntu1"},
+		{Name: "nvidia-driver-470", Version: "470.256-0ubuntu1"},
+		{Name: "nvidia-settings", Version: "510.47-0ubuntu1"},
+		{Name: "nvidia-driver-550-open"},
+	}
+	installed := []pkg.Package{
+		{Name: "nvidia-driver-535", Version: "535.183-0ubuntu1", Installed: true},
+		{Name: "nvidia-utils-535", Version: "535.183-0ubuntu1", Installed: true},
+	}
+
+	branches := BuildDriverBranches(ps, available, installed, "525.60")
+	require.Len(t, branches, 3)
+
+	assert.Equal(t, "550", branches[0].Version)
+	assert.Equal(t, BranchProduction, branches[0].Branch)
+	assert.Equal(t, "550.120-0ubuntu1", branches[0].AvailableVersion)
+	assert.Equal(t, []string{"nvidia-dkms-550", "nvidia-driver-550"}, branches[0].Packages)
+	assert.True(t, branches[0].Available)
+	assert.False(t, branches[0].Installed)
+	assert.True(t, branches[0].Recommended)
+
+	assert.Equal(t, "535", branches[1].Version)
+	assert.True(t, branches[1].Installed)
+	assert.Equal(t, "535.183-0ubuntu1", branches[1].InstalledVersion)
+	assert.Equal(t, []string{"nvidia-driver-535"}, branches[1].InstalledPackages)
+	assert.False(t, branches[1].Recommended)
+	assert.True(t, branches[1].Supported)
+
+	assert.Equal(t, "470", branches[2].Version)
+	assert.False(t, branches[2].Supported)
+}
+
+func TestBuildDriverBranches_Unversioned(t *testing.T) {
+	ps := GetPackageSetForFamily(constants.FamilyArch)
+	require.NotNil(t, ps)
+
+	available := []pkg.Package{
+		{Name: "nvidia", Version: "560.35.03-5"},
+		{Name: "nvidia-dkms", Version: "560.35.03-5"},
+		{Name: "nvidia-utils", Version: "560.35.03-1"},
+	}
+	installed := []pkg.Package{
+		{Name: "nvidia", Version: "555.58.02-1", Installed: true},
+	}
+
+	branches := BuildDriverBranches(ps, available, installed, "")
+	require.Len(t, branches, 2)
+
+	assert.Equal(t, "560", branches[0].Version)
+	assert.Equal(t, []string{"nvidia", "nvidia-dkms"}, branches[0].Packages)
+	assert.True(t, branches[0].Recommended)
+
+	assert.Equal(t, "555", branches[1].Version)
+	assert.True(t, branches[1].Installed)
+	assert.False(t, branches[1].Available)
+}
+
+func TestBuildDriverBranches_NoSupportedBranch(t *testing.T) {
+	ps := GetPackageSetForFamily(constants.FamilyDebian)
+
+	available := []pkg.Package{{Name: "nvidia-driver-535"}}
+	branches := BuildDriverBranches(ps, available, nil, "560.00")
+
+	require.Len(t, branches, 1)
+	assert.False(t, branches[0].Supported)
+	assert.False(t, branches[0].Recommended)
+}
+
+func TestBuildDriverBranches_NilPackageSet(t *testing.T) {
+	branches := BuildDriverBranches(nil, []pkg.Package{{Name: "nvidia-driver-550"}}, nil, "")
+	assert.Empty(t, branches)
+	assert.NotNil(t, branches)
+}
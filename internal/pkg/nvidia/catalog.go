@@ -0,0 +1,471 @@
+package nvidia
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/distro"
+)
+
+// CatalogSchemaVersion is the version of the package catalog schema.
+const CatalogSchemaVersion = 1
+
+// Locations of the package catalog override files.
+const (
+	// SystemCatalogDir is the directory of the system-wide override files.
+	SystemCatalogDir = "/etc/igor/packages.d"
+
+	// CatalogDirName is the name of the override directory in the user
+	// configuration directory.
+	CatalogDirName = "packages.d"
+
+	// BuiltinCatalogSource is the source name of the catalog embedded in igor.
+	BuiltinCatalogSource = "builtin"
+)
+
+// ErrInvalidCatalog is returned when a catalog file does not match the schema.
+var ErrInvalidCatalog = errors.New("invalid package catalog")
+
+//go:embed packages.yaml
+var builtinCatalog []byte
+
+// catalogFile is the schema of the catalog files: the embedded packages.yaml
+// and the override files.
+type catalogFile struct {
+	Version       int                      `yaml:"version" json:"version"`
+	Families      map[string]*catalogEntry `yaml:"families" json:"families"`
+	Distributions map[string]*catalogEntry `yaml:"distributions" json:"distributions"`
+}
+
+// catalogEntry is a package set in a catalog file. Fields left out, or
+// null, keep the value of the catalog being overridden; a list replaces
+// the whole list.
+type catalogEntry struct {
+	Family               *string  `yaml:"family" json:"family"`
+	Distribution         *string  `yaml:"distribution" json:"distribution"`
+	Driver               []string `yaml:"driver" json:"driver"`
+	DriverDKMS           []string `yaml:"driver_dkms" json:"driver_dkms"`
+	Utils                []string `yaml:"utils" json:"utils"`
+	Settings             []string `yaml:"settings" json:"settings"`
+	CUDA                 []string `yaml:"cuda" json:"cuda"`
+	CUDACompiler         []string `yaml:"cuda_compiler" json:"cuda_compiler"`
+	CUDALibs             []string `yaml:"cuda_libs" json:"cuda_libs"`
+	CUDnn                []string `yaml:"cudnn" json:"cudnn"`
+	OpenCL               []string `yaml:"opencl" json:"opencl"`
+	Vulkan               []string `yaml:"vulkan" json:"vulkan"`
+	DriverVersionPattern *string  `yaml:"driver_version_pattern" json:"driver_version_pattern"`
+	DKMSVersionPattern   *string  `yaml:"dkms_version_pattern" json:"dkms_version_pattern"`
+	Notes                *string  `yaml:"notes" json:"notes"`
+}
+
+// lists returns the package lists of the entry by their field name.
+func (e *catalogEntry) lists() []struct {
+	name     string
+	packages []string
+} {
+	return []struct {
+		name     string
+		packages []string
+	}{
+		{"driver", e.Driver},
+		{"driver_dkms", e.DriverDKMS},
+		{"utils", e.Utils},
+		{"settings", e.Settings},
+		{"cuda", e.CUDA},
+		{"cuda_compiler", e.CUDACompiler},
+		{"cuda_libs", e.CUDALibs},
+		{"cudnn", e.CUDnn},
+		{"opencl", e.OpenCL},
+		{"vulkan", e.Vulkan},
+	}
+}
+
+// applyTo copies the fields set in the entry to the package set.
+func (e *catalogEntry) applyTo(ps *PackageSet) {
+	setList := func(dst *[]string, src []string) {
+		if src != nil {
+			*dst = append([]string{}, src...)
+		}
+	}
+	setString := func(dst *string, src *string) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+
+	if e.Family != nil {
+		ps.Family = constants.DistroFamily(*e.Family)
+	}
+	setString(&ps.Distribution, e.Distribution)
+	setList(&ps.Driver, e.Driver)
+	setList(&ps.DriverDKMS, e.DriverDKMS)
+	setList(&ps.Utils, e.Utils)
+	setList(&ps.Settings, e.Settings)
+	setList(&ps.CUDA, e.CUDA)
+	setList(&ps.CUDACompiler, e.CUDACompiler)
+	setList(&ps.CUDALibs, e.CUDALibs)
+	setList(&ps.CUDnn, e.CUDnn)
+	setList(&ps.OpenCL, e.OpenCL)
+	setList(&ps.Vulkan, e.Vulkan)
+	setString(&ps.DriverVersionPattern, e.DriverVersionPattern)
+	setString(&ps.DKMSVersionPattern, e.DKMSVersionPattern)
+	setString(&ps.Notes, e.Notes)
+}
+
+// Catalog holds the package sets of the distribution families and of the
+// distributions whose packaging differs from their family.
+type Catalog struct {
+	families      map[constants.DistroFamily]*PackageSet
+	distributions map[string]*PackageSet
+	sources       []string
+}
+
+// BuiltinCatalog returns the catalog embedded in igor, without overrides.
+func BuiltinCatalog() *Catalog {
+	c := &Catalog{
+		families:      make(map[constants.DistroFamily]*PackageSet),
+		distributions: make(map[string]*PackageSet),
+	}
+	// The embedded catalog is checked by the tests, so it cannot fail here
+	if err := c.apply(BuiltinCatalogSource, builtinCatalog, false); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// LoadCatalog returns the embedded catalog with the override files of the
+// directories applied in order. In each directory, the .yaml, .yml and
+// .json files are applied in lexical order; other files are ignored, as
+// are directories that do not exist. A file that does not match the schema
+// fails the whole load, so a typo never silently installs the wrong
+// packages.
+func LoadCatalog(dirs ...string) (*Catalog, error) {
+	c := BuiltinCatalog()
+
+	for _, dir := range dirs {
+		files, err := catalogFiles(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range files {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read package catalog %s: %w", path, err)
+			}
+			if err := c.apply(path, data, filepath.Ext(path) == ".json"); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return c, nil
+}
+
+// CatalogDirs returns the override directories, in the order they are
+// applied: the system directory, then the packages.d directory of the user
+// configuration directory, so user overrides win.
+func CatalogDirs(configDir string) []string {
+	dirs := []string{SystemCatalogDir}
+	if configDir != "" {
+		dirs = append(dirs, filepath.Join(configDir, CatalogDirName))
+	}
+	return dirs
+}
+
+// catalogFiles returns the override files of a directory in lexical order.
+func catalogFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read package catalog directory %s: %w", dir, err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		switch filepath.Ext(name) {
+		case ".yaml", ".yml", ".json":
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	// os.ReadDir sorts by file name
+	return files, nil
+}
+
+// apply validates a catalog file and merges it into the catalog.
+// Nothing is merged if the file is invalid.
+func (c *Catalog) apply(source string, data []byte, isJSON bool) error {
+	var file catalogFile
+	if err := decodeCatalogFile(data, isJSON, &file); err != nil {
+		return fmt.Errorf("%w %s: %v", ErrInvalidCatalog, source, err)
+	}
+
+	if problems := c.validate(&file); len(problems) > 0 {
+		return fmt.Errorf("%w %s: %s", ErrInvalidCatalog, source, strings.Join(problems, "; "))
+	}
+
+	for _, name := range sortedKeys(file.Families) {
+		family := constants.DistroFamily(name)
+		ps, ok := c.families[family]
+		if !ok {
+			ps = &PackageSet{Family: family}
+			c.families[family] = ps
+		}
+		file.Families[name].applyTo(ps)
+		ps.Sources = append(ps.Sources, source)
+	}
+
+	for _, id := range sortedKeys(file.Distributions) {
+		ps, ok := c.distributions[id]
+		if !ok {
+			ps = &PackageSet{Distribution: id}
+			c.distributions[id] = ps
+		}
+		file.Distributions[id].applyTo(ps)
+		ps.Sources = append(ps.Sources, source)
+	}
+
+	c.sources = append(c.sources, source)
+	return nil
+}
+
+// decodeCatalogFile decodes a catalog file, rejecting unknown fields.
+func decodeCatalogFile(data []byte, isJSON bool, file *catalogFile) error {
+	if isJSON {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		return dec.Decode(file)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(file); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// Patterns of the names in catalog files. Package names may not start with
+// a dash, so that they are never taken for options of the package manager.
+var (
+	catalogIDPattern      = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+	catalogPackagePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+:-]*$`)
+)
+
+// validate checks a decoded catalog file against the schema and returns
+// the problems found.
+func (c *Catalog) validate(file *catalogFile) []string {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if file.Version != CatalogSchemaVersion {
+		addf("unsupported version %d (expected %d)", file.Version, CatalogSchemaVersion)
+		return problems
+	}
+
+	for _, name := range sortedKeys(file.Families) {
+		entry := file.Families[name]
+		path := "families." + name
+		if entry == nil {
+			addf("%s: empty entry", path)
+			continue
+		}
+		if !isSupportedFamily(name) {
+			addf("%s: unknown family (expected one of %s)", path, supportedFamilyNames())
+			continue
+		}
+		if entry.Family != nil {
+			addf("%s.family: only allowed for distributions", path)
+		}
+		_, exists := c.families[constants.DistroFamily(name)]
+		problems = append(problems, validateCatalogEntry(path, entry, exists)...)
+	}
+
+	for _, id := range sortedKeys(file.Distributions) {
+		entry := file.Distributions[id]
+		path := "distributions." + id
+		if entry == nil {
+			addf("%s: empty entry", path)
+			continue
+		}
+		if !catalogIDPattern.MatchString(id) {
+			addf("%s: invalid distribution ID (use the lower-case ID of /etc/os-release)", path)
+			continue
+		}
+		_, exists := c.distributions[id]
+		switch {
+		case entry.Family != nil && !isSupportedFamily(*entry.Family):
+			addf("%s.family: unknown family %q (expected one of %s)", path, *entry.Family, supportedFamilyNames())
+		case entry.Family == nil && !exists:
+			addf("%s.family: required for a new distribution", path)
+		}
+		problems = append(problems, validateCatalogEntry(path, entry, exists)...)
+	}
+
+	return problems
+}
+
+// validateCatalogEntry checks the package names and patterns of an entry.
+// A new entry must list its driver packages.
+func validateCatalogEntry(path string, entry *catalogEntry, exists bool) []string {
+	var problems []string
+
+	for _, list := range entry.lists() {
+		for i, name := range list.packages {
+			if !catalogPackagePattern.MatchString(name) {
+				problems = append(problems, fmt.Sprintf("%s.%s[%d]: invalid package name %q", path, list.name, i, name))
+			}
+		}
+	}
+
+	switch {
+	case entry.Driver == nil && !exists:
+		problems = append(problems, path+".driver: required for a new package set")
+	case entry.Driver != nil && len(entry.Driver) == 0:
+		problems = append(problems, path+".driver: must list at least one package")
+	}
+
+	for _, p := range []struct {
+		name    string
+		pattern *string
+	}{
+		{"driver_version_pattern", entry.DriverVersionPattern},
+		{"dkms_version_pattern", entry.DKMSVersionPattern},
+	} {
+		if p.pattern == nil || *p.pattern == "" {
+			continue
+		}
+		if strings.Count(*p.pattern, "%") != 1 || strings.Count(*p.pattern, "%s") != 1 ||
+			!catalogPackagePattern.MatchString(fmt.Sprintf(*p.pattern, GetRecommendedDriverVersion())) {
+			problems = append(problems, fmt.Sprintf("%s.%s: invalid pattern %q (a package name with a single %%s for the version)", path, p.name, *p.pattern))
+		}
+	}
+
+	return problems
+}
+
+// isSupportedFamily returns true if name is a supported distribution family.
+func isSupportedFamily(name string) bool {
+	for _, f := range SupportedFamilies() {
+		if string(f) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// supportedFamilyNames returns the supported families for error messages.
+func supportedFamilyNames() string {
+	names := make([]string, 0, len(SupportedFamilies()))
+	for _, f := range SupportedFamilies() {
+		names = append(names, string(f))
+	}
+	return strings.Join(names, ", ")
+}
+
+// sortedKeys returns the keys of a catalog file section in order, so that
+// problems are reported in a stable order.
+func sortedKeys(m map[string]*catalogEntry) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// PackageSet returns the package set of a distribution: its own entry if
+// the catalog has one, otherwise the entry of its family.
+func (c *Catalog) PackageSet(dist *distro.Distribution) *PackageSet {
+	if dist == nil {
+		return nil
+	}
+	if ps, ok := c.distributions[dist.ID]; ok {
+		return ps
+	}
+	return c.ForFamily(dist.Family)
+}
+
+// ForFamily returns the package set of a distribution family.
+func (c *Catalog) ForFamily(family constants.DistroFamily) *PackageSet {
+	return c.families[family]
+}
+
+// ByID returns the package set of a distribution ID, or nil if the
+// distribution uses the package set of its family.
+func (c *Catalog) ByID(distroID string) *PackageSet {
+	return c.distributions[strings.ToLower(distroID)]
+}
+
+// Families returns the families with a package set.
+func (c *Catalog) Families() []constants.DistroFamily {
+	var families []constants.DistroFamily
+	for _, f := range SupportedFamilies() {
+		if _, ok := c.families[f]; ok {
+			families = append(families, f)
+		}
+	}
+	return families
+}
+
+// Distributions returns the IDs of the distributions with their own
+// package set, sorted.
+func (c *Catalog) Distributions() []string {
+	ids := make([]string, 0, len(c.distributions))
+	for id := range c.distributions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Sources returns the files the catalog was loaded from, in the order they
+// were applied, starting with BuiltinCatalogSource.
+func (c *Catalog) Sources() []string {
+	return append([]string(nil), c.sources...)
+}
+
+// activeCatalog is the catalog used by GetPackageSet and the related
+// functions.
+var activeCatalog = struct {
+	sync.RWMutex
+	catalog *Catalog
+}{catalog: BuiltinCatalog()}
+
+// CurrentCatalog returns the catalog in use.
+func CurrentCatalog() *Catalog {
+	activeCatalog.RLock()
+	defer activeCatalog.RUnlock()
+	return activeCatalog.catalog
+}
+
+// SetCatalog replaces the catalog in use, typically with the result of
+// LoadCatalog at startup. A nil catalog restores the embedded one.
+func SetCatalog(c *Catalog) {
+	if c == nil {
+		c = BuiltinCatalog()
+	}
+	activeCatalog.Lock()
+	defer activeCatalog.Unlock()
+	activeCatalog.catalog = c
+}
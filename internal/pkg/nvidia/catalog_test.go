@@ -0,0 +1,300 @@
+package nvidia
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/distro"
+)
+
+// writeCatalogFile writes an override file into dir.
+func writeCatalogFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+}
+
+func TestBuiltinCatalog(t *testing.T) {
+	c := BuiltinCatalog()
+
+	assert.Equal(t, SupportedFamilies(), c.Families())
+	assert.Equal(t, []string{"fedora", "manjaro", "opensuse-leap", "opensuse-tumbleweed", "pop", "ubuntu"}, c.Distributions())
+	assert.Equal(t, []string{BuiltinCatalogSource}, c.Sources())
+
+	ubuntu := c.ByID("ubuntu")
+	require.NotNil(t, ubuntu)
+	assert.Equal(t, constants.FamilyDebian, ubuntu.Family)
+	assert.Equal(t, "ubuntu", ubuntu.Distribution)
+	assert.Equal(t, "nvidia-driver-%s", ubuntu.DriverVersionPattern)
+	assert.Equal(t, []string{BuiltinCatalogSource}, ubuntu.Sources)
+
+	// Comments in the catalog are not part of the names
+	assert.Equal(t, []string{"linux-nvidia", "nvidia"}, c.ByID("manjaro").Driver)
+	assert.Equal(t, []string{"nvidia-utils"}, c.ForFamily(constants.FamilyArch).Vulkan)
+}
+
+func TestLoadCatalog_NoOverrides(t *testing.T) {
+	c, err := LoadCatalog(filepath.Join(t.TempDir(), "missing"))
+	require.NoError(t, err)
+	assert.Equal(t, BuiltinCatalog(), c)
+}
+
+func TestLoadCatalog_Override(t *testing.T) {
+	dir := t.TempDir()
+	writeCatalogFile(t, dir, "10-mirror.yaml", `
+version: 1
+distributions:
+  ubuntu:
+    driver: [mirror-nvidia-driver-550]
+    driver_version_pattern: mirror-nvidia-driver-%s
+`)
+
+	c, err := LoadCatalog(dir)
+	require.NoError(t, err)
+
+	ubuntu := c.PackageSet(&distro.Distribution{ID: "ubuntu", Family: constants.FamilyDebian})
+	require.NotNil(t, ubuntu)
+	assert.Equal(t, []string{"mirror-nvidia-driver-550"}, ubuntu.Driver)
+	assert.Equal(t, []string{"mirror-nvidia-driver-535"}, ubuntu.GetPackagesForVersion("535"))
+	// Fields left out keep their value
+	assert.Equal(t, "nvidia-dkms-%s", ubuntu.DKMSVersionPattern)
+	assert.Equal(t, []string{"nvidia-settings"}, ubuntu.Settings)
+
+	path := filepath.Join(dir, "10-mirror.yaml")
+	assert.Equal(t, []string{BuiltinCatalogSource, path}, ubuntu.Sources)
+	assert.Equal(t, []string{BuiltinCatalogSource, path}, c.Sources())
+
+	// Other package sets are unchanged
+	assert.Equal(t, BuiltinCatalog().ByID("pop"), c.ByID("pop"))
+}
+
+func TestLoadCatalog_ClearPattern(t *testing.T) {
+	dir := t.TempDir()
+	writeCatalogFile(t, dir, "pattern.yaml", `
+version: 1
+families:
+  debian:
+    driver_version_pattern: ""
+`)
+
+	c, err := LoadCatalog(dir)
+	require.NoError(t, err)
+	debian := c.ForFamily(constants.FamilyDebian)
+	assert.Empty(t, debian.DriverVersionPattern)
+	assert.Equal(t, debian.Driver, debian.GetPackagesForVersion("550"))
+}
+
+func TestLoadCatalog_NewDistribution(t *testing.T) {
+	dir := t.TempDir()
+	writeCatalogFile(t, dir, "mint.yml", `
+version: 1
+distributions:
+  linuxmint:
+    family: debian
+    driver: [nvidia-driver-550]
+    utils: [nvidia-utils-550]
+`)
+
+	c, err := LoadCatalog(dir)
+	require.NoError(t, err)
+
+	mint := c.PackageSet(&distro.Distribution{ID: "linuxmint", Family: constants.FamilyDebian})
+	require.NotNil(t, mint)
+	assert.Equal(t, constants.FamilyDebian, mint.Family)
+	assert.Equal(t, "linuxmint", mint.Distribution)
+	assert.Equal(t, []string{"nvidia-driver-550", "nvidia-utils-550"}, mint.GetMinimalPackages())
+	assert.Empty(t, mint.CUDA)
+}
+
+func TestLoadCatalog_JSON(t *testing.T) {
+	dir := t.TempDir()
+	writeCatalogFile(t, dir, "fedora.json", `{
+	"version": 1,
+	"distributions": {
+		"fedora": {"cuda": ["cuda-12-4"]}
+	}
+}`)
+
+	c, err := LoadCatalog(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cuda-12-4"}, c.ByID("fedora").CUDA)
+}
+
+func TestLoadCatalog_Order(t *testing.T) {
+	system := t.TempDir()
+	user := t.TempDir()
+	writeCatalogFile(t, system, "20-b.yaml", "version: 1\nfamilies:\n  arch:\n    driver: [nvidia-b]\n")
+	writeCatalogFile(t, system, "10-a.yaml", "version: 1\nfamilies:\n  arch:\n    driver: [nvidia-a]\n    settings: [settings-a]\n")
+	writeCatalogFile(t, user, "00-user.yaml", "version: 1\nfamilies:\n  arch:\n    settings: [settings-user]\n")
+
+	c, err := LoadCatalog(system, user)
+	require.NoError(t, err)
+
+	arch := c.ForFamily(constants.FamilyArch)
+	assert.Equal(t, []string{"nvidia-b"}, arch.Driver)
+	assert.Equal(t, []string{"settings-user"}, arch.Settings)
+	assert.Equal(t, []string{
+		BuiltinCatalogSource,
+		filepath.Join(system, "10-a.yaml"),
+		filepath.Join(system, "20-b.yaml"),
+		filepath.Join(user, "00-user.yaml"),
+	}, c.Sources())
+}
+
+func TestLoadCatalog_IgnoresOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeCatalogFile(t, dir, "README", "not a catalog")
+	writeCatalogFile(t, dir, "mirror.yaml.rpmsave", "not a catalog")
+	writeCatalogFile(t, dir, ".mirror.yaml.swp", "not a catalog")
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "old.yaml"), 0755))
+
+	c, err := LoadCatalog(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{BuiltinCatalogSource}, c.Sources())
+}
+
+func TestLoadCatalog_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		errMsg  string
+	}{
+		{
+			name:    "missing version",
+			file:    "a.yaml",
+			content: "families:\n  arch:\n    driver: [nvidia]\n",
+			errMsg:  "unsupported version 0",
+		},
+		{
+			name:    "unknown field",
+			file:    "a.yaml",
+			content: "version: 1\nfamilies:\n  arch:\n    drivers: [nvidia]\n",
+			errMsg:  "field drivers not found",
+		},
+		{
+			name:    "unknown JSON field",
+			file:    "a.json",
+			content: `{"version": 1, "distros": {}}`,
+			errMsg:  `unknown field "distros"`,
+		},
+		{
+			name:    "malformed",
+			file:    "a.yaml",
+			content: "version: 1\nfamilies: [arch\n",
+			errMsg:  "invalid package catalog",
+		},
+		{
+			name:    "unknown family",
+			file:    "a.yaml",
+			content: "version: 1\nfamilies:\n  gentoo:\n    driver: [nvidia-drivers]\n",
+			errMsg:  "families.gentoo: unknown family",
+		},
+		{
+			name:    "family in family entry",
+			file:    "a.yaml",
+			content: "version: 1\nfamilies:\n  arch:\n    family: debian\n",
+			errMsg:  "families.arch.family: only allowed for distributions",
+		},
+		{
+			name:    "new distribution without family",
+			file:    "a.yaml",
+			content: "version: 1\ndistributions:\n  linuxmint:\n    driver: [nvidia-driver-550]\n",
+			errMsg:  "distributions.linuxmint.family: required for a new distribution",
+		},
+		{
+			name:    "new distribution without driver",
+			file:    "a.yaml",
+			content: "version: 1\ndistributions:\n  linuxmint:\n    family: debian\n",
+			errMsg:  "distributions.linuxmint.driver: required for a new package set",
+		},
+		{
+			name:    "empty driver",
+			file:    "a.yaml",
+			content: "version: 1\ndistributions:\n  ubuntu:\n    driver: []\n",
+			errMsg:  "distributions.ubuntu.driver: must list at least one package",
+		},
+		{
+			name:    "invalid distribution ID",
+			file:    "a.yaml",
+			content: "version: 1\ndistributions:\n  Linux Mint:\n    family: debian\n    driver: [nvidia]\n",
+			errMsg:  "invalid distribution ID",
+		},
+		{
+			name:    "option as package name",
+			file:    "a.yaml",
+			content: "version: 1\nfamilies:\n  debian:\n    utils: [nvidia-utils-550, --allow-unauthenticated]\n",
+			errMsg:  `families.debian.utils[1]: invalid package name "--allow-unauthenticated"`,
+		},
+		{
+			name:    "pattern without version",
+			file:    "a.yaml",
+			content: "version: 1\nfamilies:\n  debian:\n    driver_version_pattern: nvidia-driver\n",
+			errMsg:  "families.debian.driver_version_pattern: invalid pattern",
+		},
+		{
+			name:    "pattern with another verb",
+			file:    "a.yaml",
+			content: "version: 1\nfamilies:\n  debian:\n    dkms_version_pattern: nvidia-dkms-%s-%d\n",
+			errMsg:  "families.debian.dkms_version_pattern: invalid pattern",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeCatalogFile(t, dir, tt.file, tt.content)
+
+			c, err := LoadCatalog(dir)
+			require.Error(t, err)
+			assert.Nil(t, c)
+			assert.ErrorIs(t, err, ErrInvalidCatalog)
+			assert.Contains(t, err.Error(), filepath.Join(dir, tt.file))
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestLoadCatalog_ReportsAllProblems(t *testing.T) {
+	dir := t.TempDir()
+	writeCatalogFile(t, dir, "a.yaml", `
+version: 1
+distributions:
+  ubuntu:
+    driver: [-bad]
+  linuxmint:
+    driver: [nvidia-driver-550]
+`)
+
+	_, err := LoadCatalog(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "distributions.linuxmint.family")
+	assert.Contains(t, err.Error(), "distributions.ubuntu.driver[0]")
+}
+
+func TestCatalogDirs(t *testing.T) {
+	assert.Equal(t, []string{SystemCatalogDir, "/home/user/.config/igor/packages.d"}, CatalogDirs("/home/user/.config/igor"))
+	assert.Equal(t, []string{SystemCatalogDir}, CatalogDirs(""))
+}
+
+func TestSetCatalog(t *testing.T) {
+	t.Cleanup(func() { SetCatalog(nil) })
+
+	dir := t.TempDir()
+	writeCatalogFile(t, dir, "arch.yaml", "version: 1\nfamilies:\n  arch:\n    driver: [nvidia-open]\n")
+	c, err := LoadCatalog(dir)
+	require.NoError(t, err)
+
+	SetCatalog(c)
+	assert.Same(t, c, CurrentCatalog())
+	assert.Equal(t, []string{"nvidia-open"}, GetPackageSetForFamily(constants.FamilyArch).Driver)
+	assert.Equal(t, []string{"nvidia-open"}, GetPackageSet(&distro.Distribution{ID: "arch", Family: constants.FamilyArch}).Driver)
+
+	SetCatalog(nil)
+	assert.Equal(t, []string{"nvidia"}, GetPackageSetForFamily(constants.FamilyArch).Driver)
+}
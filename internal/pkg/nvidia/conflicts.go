@@ -0,0 +1,355 @@
+package nvidia
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/distro"
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// RepositorySource is a source of NVIDIA driver packages, such as the
+// graphics-drivers PPA or RPM Fusion. A source may span several
+// repositories, like the release and updates repositories of RPM Fusion.
+type RepositorySource struct {
+	// Name identifies the source (e.g., "graphics-drivers-ppa", "negativo17").
+	Name string
+
+	// Description is a human-readable description of the source.
+	Description string
+
+	// Packages are the NVIDIA packages the source provides. A "*" stands
+	// for the driver branch, as in "nvidia-driver-*".
+	Packages []string
+
+	// Repositories are the enabled repositories of the source.
+	Repositories []pkg.Repository
+
+	// Preferred marks the source igor installs the driver from on this
+	// distribution, as returned by GetRepositoryInfo.
+	Preferred bool
+}
+
+// RepositoryNames returns the names of the repositories of the source.
+func (s RepositorySource) RepositoryNames() []string {
+	names := make([]string, 0, len(s.Repositories))
+	for _, repo := range s.Repositories {
+		names = append(names, repo.Name)
+	}
+	return names
+}
+
+// RepositoryConflict is a pair of enabled sources providing the same
+// NVIDIA packages. The package manager picks each package from whichever
+// source has the newest version, so the driver ends up mixing versions
+// built by different packagers.
+type RepositoryConflict struct {
+	// Source and Other are the names of the conflicting sources.
+	Source string
+	Other  string
+
+	// Packages are the packages both sources provide.
+	Packages []string
+}
+
+// String returns a human-readable representation of the conflict.
+func (c RepositoryConflict) String() string {
+	return fmt.Sprintf("%s and %s both provide %s", c.Source, c.Other, strings.Join(c.Packages, ", "))
+}
+
+// RepositoryAnalysis is the result of analyzing the enabled repositories
+// for competing NVIDIA package sources.
+type RepositoryAnalysis struct {
+	// Sources are the enabled NVIDIA package sources, the preferred one
+	// first.
+	Sources []RepositorySource
+
+	// Conflicts are the pairs of sources providing the same packages.
+	Conflicts []RepositoryConflict
+}
+
+// HasConflicts returns true if enabled sources provide the same packages.
+func (a *RepositoryAnalysis) HasConflicts() bool {
+	return len(a.Conflicts) > 0
+}
+
+// Preferred returns the source to keep when resolving conflicts: the
+// preferred source of the distribution if it is enabled, and otherwise the
+// first source found, in the order of their recommendation. It returns nil
+// if no NVIDIA source is enabled.
+func (a *RepositoryAnalysis) Preferred() *RepositorySource {
+	if len(a.Sources) == 0 {
+		return nil
+	}
+	return &a.Sources[0]
+}
+
+// Competing returns the sources conflicting with the preferred source,
+// which must be disabled to resolve the conflicts.
+func (a *RepositoryAnalysis) Competing() []RepositorySource {
+	preferred := a.Preferred()
+	if preferred == nil {
+		return nil
+	}
+
+	conflicting := make(map[string]bool)
+	for _, c := range a.Conflicts {
+		switch preferred.Name {
+		case c.Source:
+			conflicting[c.Other] = true
+		case c.Other:
+			conflicting[c.Source] = true
+		}
+	}
+
+	var competing []RepositorySource
+	for _, source := range a.Sources[1:] {
+		if conflicting[source.Name] {
+			competing = append(competing, source)
+		}
+	}
+	return competing
+}
+
+// CompetingRepositories returns the repositories of the competing sources.
+func (a *RepositoryAnalysis) CompetingRepositories() []pkg.Repository {
+	var repos []pkg.Repository
+	for _, source := range a.Competing() {
+		repos = append(repos, source.Repositories...)
+	}
+	return repos
+}
+
+// knownSource describes a source of NVIDIA packages and how to recognize
+// its repositories.
+type knownSource struct {
+	name        string
+	description string
+	family      constants.DistroFamily
+	// names are substrings of the repository name (repo ID, alias or
+	// sources.list.d file name), matched case-insensitively.
+	names []string
+	// urls are substrings of the repository URL.
+	urls     []string
+	packages []string
+}
+
+// matchesURL reports whether the repository URL belongs to the source.
+func (s knownSource) matchesURL(url string) bool {
+	url = strings.ToLower(url)
+	for _, u := range s.urls {
+		if url != "" && strings.Contains(url, u) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesName reports whether the repository name belongs to the source.
+func (s knownSource) matchesName(name string) bool {
+	name = strings.ToLower(name)
+	for _, n := range s.names {
+		if strings.Contains(name, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// knownSources lists the known NVIDIA package sources of each family, in
+// the order they are preferred when the source igor uses is not enabled.
+// The packages are those the sources are known to ship; repositories carry
+// far more packages than that, but these are the ones whose versions get
+// mixed.
+var knownSources = []knownSource{
+	// Debian family
+	{
+		name:        "graphics-drivers-ppa",
+		description: "Ubuntu graphics-drivers PPA",
+		family:      constants.FamilyDebian,
+		names:       []string{"graphics-drivers"},
+		urls:        []string{"launchpad.net/graphics-drivers", "launchpadcontent.net/graphics-drivers", "ppa:graphics-drivers"},
+		packages:    []string{"libnvidia-compute-*", "libnvidia-gl-*", "nvidia-dkms-*", "nvidia-driver-*", "nvidia-utils-*"},
+	},
+	{
+		name:        "nvidia-cuda",
+		description: "NVIDIA CUDA repository",
+		family:      constants.FamilyDebian,
+		names:       []string{"cuda"},
+		urls:        []string{"developer.download.nvidia.com/compute/cuda"},
+		packages:    []string{"cuda-drivers", "libnvidia-compute-*", "libnvidia-gl-*", "nvidia-dkms-*", "nvidia-driver-*", "nvidia-utils-*"},
+	},
+	// RHEL family
+	{
+		name:        "rpmfusion-nonfree",
+		description: "RPM Fusion nonfree repository",
+		family:      constants.FamilyRHEL,
+		names:       []string{"rpmfusion-nonfree"},
+		urls:        []string{"rpmfusion.org/nonfree"},
+		packages:    []string{"akmod-nvidia", "kmod-nvidia", "nvidia-settings", "xorg-x11-drv-nvidia", "xorg-x11-drv-nvidia-cuda"},
+	},
+	{
+		name:        "negativo17",
+		description: "negativo17 NVIDIA repository",
+		family:      constants.FamilyRHEL,
+		names:       []string{"fedora-nvidia", "epel-nvidia", "negativo17"},
+		urls:        []string{"negativo17.org"},
+		packages:    []string{"akmod-nvidia", "dkms-nvidia", "nvidia-driver", "nvidia-driver-cuda", "nvidia-settings"},
+	},
+	{
+		name:        "nvidia-cuda",
+		description: "NVIDIA CUDA repository",
+		family:      constants.FamilyRHEL,
+		names:       []string{"cuda"},
+		urls:        []string{"developer.download.nvidia.com/compute/cuda"},
+		packages:    []string{"cuda-drivers", "kmod-nvidia-latest-dkms", "nvidia-driver", "nvidia-driver-cuda", "nvidia-settings"},
+	},
+	// SUSE family. The CUDA repository comes first, as the name of the
+	// openSUSE repository only tells it is an NVIDIA one.
+	{
+		name:        "nvidia-cuda",
+		description: "NVIDIA CUDA repository",
+		family:      constants.FamilySUSE,
+		names:       []string{"cuda"},
+		urls:        []string{"developer.download.nvidia.com/compute/cuda"},
+		packages:    []string{"cuda-drivers", "nvidia-compute-G06", "nvidia-driver-G06-kmp-default", "nvidia-gl-G06"},
+	},
+	{
+		name:        "nvidia-opensuse",
+		description: "NVIDIA repository for openSUSE",
+		family:      constants.FamilySUSE,
+		names:       []string{"nvidia"},
+		urls:        []string{"download.nvidia.com/opensuse"},
+		packages:    []string{"nvidia-compute-G06", "nvidia-driver-G06-kmp-default", "nvidia-gl-G06", "nvidia-video-G06"},
+	},
+}
+
+// sourcesForFamily returns the known sources of a distribution family.
+func sourcesForFamily(family constants.DistroFamily) []knownSource {
+	var sources []knownSource
+	for _, s := range knownSources {
+		if s.family == family {
+			sources = append(sources, s)
+		}
+	}
+	return sources
+}
+
+// matchSource returns the index of the source the repository belongs to,
+// or -1. Repository URLs are more reliable than names, so all sources are
+// tried by URL before any is tried by name.
+func matchSource(sources []knownSource, repo pkg.Repository) int {
+	for i, s := range sources {
+		if s.matchesURL(repo.URL) {
+			return i
+		}
+	}
+	for i, s := range sources {
+		if s.matchesName(repo.Name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// AnalyzeRepositories finds the enabled repositories providing NVIDIA
+// packages and the sources competing for the same packages.
+func AnalyzeRepositories(ctx context.Context, pm pkg.Manager, dist *distro.Distribution) (*RepositoryAnalysis, error) {
+	if pm == nil {
+		return nil, fmt.Errorf("package manager cannot be nil")
+	}
+	if dist == nil {
+		return nil, fmt.Errorf("distribution cannot be nil")
+	}
+
+	repos, err := pm.ListRepositories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories: %w", err)
+	}
+	return AnalyzeRepositoryList(repos, dist), nil
+}
+
+// AnalyzeRepositoryList is AnalyzeRepositories for a list of repositories.
+// Disabled repositories are ignored.
+func AnalyzeRepositoryList(repos []pkg.Repository, dist *distro.Distribution) *RepositoryAnalysis {
+	analysis := &RepositoryAnalysis{
+		Sources:   make([]RepositorySource, 0),
+		Conflicts: make([]RepositoryConflict, 0),
+	}
+	if dist == nil {
+		return analysis
+	}
+
+	known := sourcesForFamily(dist.Family)
+	preferred := -1
+	if info, err := GetRepositoryInfo(dist); err == nil {
+		preferred = matchSource(known, pkg.Repository{Name: info.Name, URL: info.URL})
+	}
+
+	found := make(map[int]*RepositorySource)
+	for _, repo := range repos {
+		if !repo.Enabled {
+			continue
+		}
+		i := matchSource(known, repo)
+		if i < 0 {
+			continue
+		}
+		if found[i] == nil {
+			found[i] = &RepositorySource{
+				Name:        known[i].name,
+				Description: known[i].description,
+				Packages:    append([]string{}, known[i].packages...),
+				Preferred:   i == preferred,
+			}
+		}
+		found[i].Repositories = append(found[i].Repositories, repo)
+	}
+
+	order := make([]int, 0, len(found))
+	for i := range found {
+		order = append(order, i)
+	}
+	sort.Slice(order, func(a, b int) bool {
+		if (order[a] == preferred) != (order[b] == preferred) {
+			return order[a] == preferred
+		}
+		return order[a] < order[b]
+	})
+	for _, i := range order {
+		analysis.Sources = append(analysis.Sources, *found[i])
+	}
+
+	for i, source := range analysis.Sources {
+		for _, other := range analysis.Sources[i+1:] {
+			if shared := sharedPackages(source.Packages, other.Packages); len(shared) > 0 {
+				analysis.Conflicts = append(analysis.Conflicts, RepositoryConflict{
+					Source:   source.Name,
+					Other:    other.Name,
+					Packages: shared,
+				})
+			}
+		}
+	}
+
+	return analysis
+}
+
+// sharedPackages returns the packages present in both lists, sorted.
+func sharedPackages(a, b []string) []string {
+	in := make(map[string]bool, len(a))
+	for _, p := range a {
+		in[p] = true
+	}
+	var shared []string
+	for _, p := range b {
+		if in[p] {
+			shared = append(shared, p)
+		}
+	}
+	sort.Strings(shared)
+	return shared
+}
@@ -0,0 +1,159 @@
+package nvidia
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/distro"
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// repoListManager is a package manager listing fixed repositories.
+type repoListManager struct {
+	pkg.Manager
+	repos []pkg.Repository
+	err   error
+}
+
+func (m *repoListManager) ListRepositories(ctx context.Context) ([]pkg.Repository, error) {
+	return m.repos, m.err
+}
+
+func sourceNames(sources []RepositorySource) []string {
+	names := make([]string, 0, len(sources))
+	for _, s := range sources {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func TestAnalyzeRepositoryList_Ubuntu(t *testing.T) {
+	dist := &distro.Distribution{ID: "ubuntu", VersionID: "24.04", Family: constants.FamilyDebian}
+	repos := []pkg.Repository{
+		{Name: "ubuntu", URL: "http://archive.ubuntu.com/ubuntu", Enabled: true},
+		{Name: "cuda-ubuntu2404-x86_64", URL: "https://developer.download.nvidia.com/compute/cuda/repos/ubuntu2404/x86_64/", Enabled: true},
+		{Name: "graphics-drivers-ubuntu-ppa-noble", URL: "https://ppa.launchpadcontent.net/graphics-drivers/ppa/ubuntu", Enabled: true},
+	}
+
+	analysis := AnalyzeRepositoryList(repos, dist)
+
+	assert.Equal(t, []string{"graphics-drivers-ppa", "nvidia-cuda"}, sourceNames(analysis.Sources))
+	assert.True(t, analysis.Sources[0].Preferred)
+	assert.False(t, analysis.Sources[1].Preferred)
+	assert.Equal(t, []string{"graphics-drivers-ubuntu-ppa-noble"}, analysis.Sources[0].RepositoryNames())
+
+	require.True(t, analysis.HasConflicts())
+	require.Len(t, analysis.Conflicts, 1)
+	assert.Equal(t, "graphics-drivers-ppa", analysis.Conflicts[0].Source)
+	assert.Equal(t, "nvidia-cuda", analysis.Conflicts[0].Other)
+	assert.Contains(t, analysis.Conflicts[0].Packages, "nvidia-driver-*")
+	assert.NotContains(t, analysis.Conflicts[0].Packages, "cuda-drivers")
+	assert.Contains(t, analysis.Conflicts[0].String(), "graphics-drivers-ppa and nvidia-cuda both provide ")
+
+	assert.Equal(t, "graphics-drivers-ppa", analysis.Preferred().Name)
+	assert.Equal(t, []string{"nvidia-cuda"}, sourceNames(analysis.Competing()))
+	assert.Equal(t, []pkg.Repository{repos[1]}, analysis.CompetingRepositories())
+}
+
+func TestAnalyzeRepositoryList_Fedora(t *testing.T) {
+	dist := &distro.Distribution{ID: "fedora", VersionID: "40", Family: constants.FamilyRHEL}
+	repos := []pkg.Repository{
+		{Name: "fedora", Enabled: true},
+		{Name: "fedora-nvidia", Enabled: true},
+		{Name: "rpmfusion-nonfree", Enabled: true},
+		{Name: "rpmfusion-nonfree-updates", Enabled: true},
+		{Name: "rpmfusion-nonfree-updates-testing", Enabled: false},
+		{Name: "cuda-fedora39-x86_64", Enabled: true},
+	}
+
+	analysis := AnalyzeRepositoryList(repos, dist)
+
+	assert.Equal(t, []string{"rpmfusion-nonfree", "negativo17", "nvidia-cuda"}, sourceNames(analysis.Sources))
+	assert.Equal(t, []string{"rpmfusion-nonfree", "rpmfusion-nonfree-updates"}, analysis.Sources[0].RepositoryNames())
+	assert.Len(t, analysis.Conflicts, 3)
+	assert.Equal(t, []string{"negativo17", "nvidia-cuda"}, sourceNames(analysis.Competing()))
+
+	var names []string
+	for _, repo := range analysis.CompetingRepositories() {
+		names = append(names, repo.Name)
+	}
+	assert.Equal(t, []string{"fedora-nvidia", "cuda-fedora39-x86_64"}, names)
+}
+
+func TestAnalyzeRepositoryList_PreferredNotEnabled(t *testing.T) {
+	dist := &distro.Distribution{ID: "fedora", VersionID: "40", Family: constants.FamilyRHEL}
+	repos := []pkg.Repository{
+		{Name: "cuda-fedora39-x86_64", Enabled: true},
+		{Name: "fedora-nvidia", Enabled: true},
+	}
+
+	analysis := AnalyzeRepositoryList(repos, dist)
+
+	assert.Equal(t, []string{"negativo17", "nvidia-cuda"}, sourceNames(analysis.Sources))
+	assert.False(t, analysis.Sources[0].Preferred)
+	assert.Equal(t, []string{"nvidia-cuda"}, sourceNames(analysis.Competing()))
+}
+
+func TestAnalyzeRepositoryList_OpenSUSE(t *testing.T) {
+	dist := &distro.Distribution{ID: "opensuse-tumbleweed", Family: constants.FamilySUSE}
+	repos := []pkg.Repository{
+		{Name: "repo-oss", Enabled: true},
+		{Name: "cuda-opensuse15-x86_64", Enabled: true},
+		{Name: "NVIDIA-openSUSE-Tumbleweed", Enabled: true},
+	}
+
+	analysis := AnalyzeRepositoryList(repos, dist)
+
+	assert.Equal(t, []string{"nvidia-opensuse", "nvidia-cuda"}, sourceNames(analysis.Sources))
+	assert.True(t, analysis.Sources[0].Preferred)
+	assert.True(t, analysis.HasConflicts())
+}
+
+func TestAnalyzeRepositoryList_NoConflicts(t *testing.T) {
+	dist := &distro.Distribution{ID: "ubuntu", VersionID: "24.04", Family: constants.FamilyDebian}
+
+	analysis := AnalyzeRepositoryList([]pkg.Repository{
+		{Name: "ubuntu", Enabled: true},
+		{Name: "graphics-drivers-ubuntu-ppa-noble", Enabled: true},
+		{Name: "cuda-ubuntu2404-x86_64", Enabled: false},
+	}, dist)
+
+	assert.Equal(t, []string{"graphics-drivers-ppa"}, sourceNames(analysis.Sources))
+	assert.False(t, analysis.HasConflicts())
+	assert.Empty(t, analysis.Competing())
+
+	analysis = AnalyzeRepositoryList([]pkg.Repository{{Name: "extra", Enabled: true}},
+		&distro.Distribution{ID: "arch", Family: constants.FamilyArch})
+	assert.Empty(t, analysis.Sources)
+	assert.Nil(t, analysis.Preferred())
+	assert.Empty(t, analysis.Competing())
+
+	analysis = AnalyzeRepositoryList(nil, nil)
+	assert.Empty(t, analysis.Sources)
+}
+
+func TestAnalyzeRepositories(t *testing.T) {
+	dist := &distro.Distribution{ID: "ubuntu", VersionID: "24.04", Family: constants.FamilyDebian}
+	pm := &repoListManager{repos: []pkg.Repository{
+		{Name: "cuda-ubuntu2404-x86_64", Enabled: true},
+		{Name: "graphics-drivers-ubuntu-ppa-noble", Enabled: true},
+	}}
+
+	analysis, err := AnalyzeRepositories(context.Background(), pm, dist)
+	require.NoError(t, err)
+	assert.True(t, analysis.HasConflicts())
+
+	pm.err = errors.New("permission denied")
+	_, err = AnalyzeRepositories(context.Background(), pm, dist)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to list repositories")
+
+	_, err = AnalyzeRepositories(context.Background(), nil, dist)
+	assert.Error(t, err)
+	_, err = AnalyzeRepositories(context.Background(), pm, nil)
+	assert.Error(t, err)
+}
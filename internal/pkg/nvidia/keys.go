@@ -0,0 +1,72 @@
+package nvidia
+
+import (
+	"strings"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// Fingerprints of the pinned repository signing keys.
+const (
+	// CUDAKeyFingerprint is the fingerprint of the CUDA repository key
+	// (3bf863cc.pub), used since April 2022.
+	CUDAKeyFingerprint = "EB693B3035CD5710E231E123A4B469963BF863CC"
+
+	// CUDARetiredKeyFingerprint is the fingerprint of the CUDA repository
+	// key 7fa2af80, rotated out in April 2022.
+	CUDARetiredKeyFingerprint = "AE09FE4BBD223A84B2CCFCE3F60F4B3D7FA2AF80"
+
+	// RPMFusionNonfreeFedoraKeyFingerprint is the fingerprint of the RPM
+	// Fusion Nonfree key for Fedora 32 and later.
+	RPMFusionNonfreeFedoraKeyFingerprint = "79BDB88F9BBF73910FD4095B6A2AF96194843C65"
+)
+
+// pinnedKey is a pinned repository signing key and the key URLs it is
+// downloaded from.
+type pinnedKey struct {
+	// urls are substrings of the key URLs. They name the repository
+	// rather than the key file, so a key replaced under a new file name
+	// is still checked against the pin and rejected.
+	urls []string
+	pin  pkg.KeyPin
+}
+
+// pinnedKeys lists the signing keys of the repositories igor adds. When a
+// repository rotates its key, the new fingerprint is added to Fingerprints
+// and the old one moved to Retired.
+var pinnedKeys = []pinnedKey{
+	{
+		urls: []string{"developer.download.nvidia.com/compute/cuda/repos/"},
+		pin: pkg.KeyPin{
+			Name:         "nvidia-cuda",
+			Fingerprints: []string{CUDAKeyFingerprint},
+			Retired: []pkg.RetiredKey{
+				{
+					Fingerprint: CUDARetiredKeyFingerprint,
+					ReplacedBy:  CUDAKeyFingerprint,
+					Date:        "2022-04-27",
+				},
+			},
+		},
+	},
+	{
+		urls: []string{"RPM-GPG-KEY-rpmfusion-nonfree-fedora"},
+		pin: pkg.KeyPin{
+			Name:         "rpmfusion-nonfree-fedora",
+			Fingerprints: []string{RPMFusionNonfreeFedoraKeyFingerprint},
+		},
+	},
+}
+
+// GetKeyPin returns the pin of the repository signing key at keyURL.
+// It returns false if no key is pinned for the URL.
+func GetKeyPin(keyURL string) (pkg.KeyPin, bool) {
+	for _, k := range pinnedKeys {
+		for _, u := range k.urls {
+			if keyURL != "" && strings.Contains(keyURL, u) {
+				return k.pin, true
+			}
+		}
+	}
+	return pkg.KeyPin{}, false
+}
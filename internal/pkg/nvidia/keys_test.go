@@ -0,0 +1,72 @@
+package nvidia
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tungetti/igor/internal/constants"
+	"github.com/tungetti/igor/internal/distro"
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+func TestGetKeyPin_CUDA(t *testing.T) {
+	for _, repoPath := range []string{"ubuntu2204", "debian12", "rhel9", "fedora40"} {
+		t.Run(repoPath, func(t *testing.T) {
+			pin, ok := GetKeyPin(fmt.Sprintf(CUDAGPGKeyURL, repoPath))
+			require.True(t, ok)
+			assert.Equal(t, "nvidia-cuda", pin.Name)
+			assert.True(t, pin.Accepts(CUDAKeyFingerprint))
+			assert.False(t, pin.Accepts(CUDARetiredKeyFingerprint))
+			require.Len(t, pin.Retired, 1)
+			assert.Equal(t, CUDARetiredKeyFingerprint, pin.Retired[0].Fingerprint)
+		})
+	}
+}
+
+func TestGetKeyPin_RenamedKeyFile(t *testing.T) {
+	// A key served under another file name is still checked against the pin
+	pin, ok := GetKeyPin("https://developer.download.nvidia.com/compute/cuda/repos/ubuntu2404/x86_64/0123abcd.pub")
+	require.True(t, ok)
+	assert.Equal(t, "nvidia-cuda", pin.Name)
+}
+
+func TestGetKeyPin_RPMFusion(t *testing.T) {
+	pin, ok := GetKeyPin(RPMFusionNonfreeFedoraGPGKeyURL)
+	require.True(t, ok)
+	assert.Equal(t, "rpmfusion-nonfree-fedora", pin.Name)
+	assert.True(t, pin.Accepts(RPMFusionNonfreeFedoraKeyFingerprint))
+}
+
+func TestGetKeyPin_Unknown(t *testing.T) {
+	_, ok := GetKeyPin("https://example.com/key.pub")
+	assert.False(t, ok)
+
+	_, ok = GetKeyPin("")
+	assert.False(t, ok)
+}
+
+func TestGetKeyPin_RepositoryKeys(t *testing.T) {
+	// Every key URL of the repositories igor adds is pinned
+	dists := []*distro.Distribution{
+		{ID: "ubuntu", VersionCodename: "noble", Family: constants.FamilyDebian},
+		{ID: "debian", VersionCodename: "bookworm", Family: constants.FamilyDebian},
+		{ID: "fedora", VersionID: "40", Family: constants.FamilyRHEL},
+		{ID: "rocky", VersionID: "9.3", Family: constants.FamilyRHEL},
+		{ID: "opensuse-tumbleweed", Family: constants.FamilySUSE},
+	}
+	for _, dist := range dists {
+		t.Run(dist.ID, func(t *testing.T) {
+			for _, get := range []func(*distro.Distribution) (*pkg.Repository, error){GetRepository, GetCUDARepository} {
+				repo, err := get(dist)
+				if err != nil || repo == nil || repo.GPGKey == "" {
+					continue
+				}
+				_, ok := GetKeyPin(repo.GPGKey)
+				assert.True(t, ok, "key %s of %s is not pinned", repo.GPGKey, repo.Name)
+			}
+		})
+	}
+}
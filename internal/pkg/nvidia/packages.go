@@ -1,6 +1,9 @@
 // Package nvidia provides NVIDIA-specific package mappings and repository definitions
 // for different Linux distributions. It maps NVIDIA software components (drivers, CUDA,
 // cuDNN, etc.) to the actual package names used by each distribution's package manager.
+//
+// The package names are defined in packages.yaml, a catalog embedded in
+// igor that override files can patch without a new release; see LoadCatalog.
 package nvidia
 
 import (
@@ -71,50 +74,54 @@ func (c Component) IsValid() bool {
 // multiple packages, or different versions may be available.
 type PackageSet struct {
 	// Family is the distribution family (Debian, RHEL, Arch, SUSE).
-	Family constants.DistroFamily
+	Family constants.DistroFamily `json:"family"`
 
 	// Distribution is the specific distro ID (e.g., "ubuntu", "fedora", "arch").
-	Distribution string
+	Distribution string `json:"distribution"`
 
 	// Driver contains the main driver packages.
-	Driver []string
+	Driver []string `json:"driver,omitempty"`
 
 	// DriverDKMS contains the DKMS driver packages.
-	DriverDKMS []string
+	DriverDKMS []string `json:"driver_dkms,omitempty"`
 
 	// Utils contains utility packages (nvidia-smi, etc.).
-	Utils []string
+	Utils []string `json:"utils,omitempty"`
 
 	// Settings contains the nvidia-settings GUI packages.
-	Settings []string
+	Settings []string `json:"settings,omitempty"`
 
 	// CUDA contains CUDA toolkit packages.
-	CUDA []string
+	CUDA []string `json:"cuda,omitempty"`
 
 	// CUDACompiler contains NVCC compiler packages.
-	CUDACompiler []string
+	CUDACompiler []string `json:"cuda_compiler,omitempty"`
 
 	// CUDALibs contains CUDA library packages.
-	CUDALibs []string
+	CUDALibs []string `json:"cuda_libs,omitempty"`
 
 	// CUDnn contains cuDNN packages.
-	CUDnn []string
+	CUDnn []string `json:"cudnn,omitempty"`
 
 	// OpenCL contains OpenCL packages.
-	OpenCL []string
+	OpenCL []string `json:"opencl,omitempty"`
 
 	// Vulkan contains Vulkan ICD packages.
-	Vulkan []string
+	Vulkan []string `json:"vulkan,omitempty"`
 
 	// DriverVersionPattern is a format string for version-specific driver packages.
 	// For example, "nvidia-driver-%s" where %s is replaced with the version.
-	DriverVersionPattern string
+	DriverVersionPattern string `json:"driver_version_pattern,omitempty"`
 
 	// DKMSVersionPattern is a format string for version-specific DKMS packages.
-	DKMSVersionPattern string
+	DKMSVersionPattern string `json:"dkms_version_pattern,omitempty"`
 
 	// Notes contains additional information about the package set.
-	Notes string
+	Notes string `json:"notes,omitempty"`
+
+	// Sources lists the catalog files that defined or changed the package
+	// set, starting with BuiltinCatalogSource for the embedded ones.
+	Sources []string `json:"sources,omitempty"`
 }
 
 // SupportedDriverVersions contains the currently supported NVIDIA driver versions.
@@ -177,6 +184,29 @@ func (ps *PackageSet) GetDKMSPackagesForVersion(version string) []string {
 	return []string{fmt.Sprintf(ps.DKMSVersionPattern, version)}
 }
 
+// GetCUDAPackagesForVersion returns CUDA toolkit packages for a specific version
+// (e.g., "12.4"). The "cuda" meta package from NVIDIA's repositories is replaced
+// by the matching "cuda-toolkit-12-4" package. Distribution packages such as
+// "nvidia-cuda-toolkit" and Arch's "cuda" are not versioned and are returned
+// unchanged.
+func (ps *PackageSet) GetCUDAPackagesForVersion(version string) []string {
+	version = strings.TrimSpace(version)
+	if version == "" || ps.Family == constants.FamilyArch {
+		return ps.CUDA
+	}
+
+	versioned := "cuda-toolkit-" + strings.ReplaceAll(version, ".", "-")
+
+	packages := make([]string, 0, len(ps.CUDA))
+	for _, p := range ps.CUDA {
+		if p == "cuda" {
+			p = versioned
+		}
+		packages = append(packages, p)
+	}
+	return packages
+}
+
 // GetAllPackages returns all packages needed for a full NVIDIA installation.
 // This includes the driver, utilities, settings, CUDA, and all supporting packages.
 func (ps *PackageSet) GetAllPackages() []string {
@@ -277,440 +307,80 @@ func (ps *PackageSet) GetGraphicsPackages() []string {
 	return packages
 }
 
-// packageSets holds the package mappings for each distribution family.
-var packageSets = map[constants.DistroFamily]*PackageSet{
-	constants.FamilyDebian: {
-		Family:       constants.FamilyDebian,
-		Distribution: "debian/ubuntu",
-		Driver: []string{
-			"nvidia-driver-550",
-			"nvidia-driver-545",
-			"nvidia-driver-535",
-		},
-		DriverDKMS: []string{
-			"nvidia-dkms-550",
-			"nvidia-dkms-545",
-			"nvidia-dkms-535",
-		},
-		Utils:    []string{"nvidia-utils-550"},
-		Settings: []string{"nvidia-settings"},
-		CUDA: []string{
-			"nvidia-cuda-toolkit",
-		},
-		CUDACompiler: []string{
-			"nvidia-cuda-toolkit",
-		},
-		CUDALibs: []string{
-			"nvidia-cuda-dev",
-		},
-		CUDnn: []string{
-			"libcudnn8",
-			"libcudnn8-dev",
-		},
-		OpenCL: []string{
-			"nvidia-opencl-icd",
-		},
-		Vulkan: []string{
-			"nvidia-vulkan-icd",
-		},
-		DriverVersionPattern: "nvidia-driver-%s",
-		DKMSVersionPattern:   "nvidia-dkms-%s",
-		Notes:                "Ubuntu/Debian use the graphics-drivers PPA or official CUDA repository",
-	},
-
-	constants.FamilyRHEL: {
-		Family:       constants.FamilyRHEL,
-		Distribution: "fedora/rhel",
-		Driver: []string{
-			"akmod-nvidia",
-			"xorg-x11-drv-nvidia",
-		},
-		DriverDKMS: []string{
-			"akmod-nvidia",
-		},
-		Utils: []string{
-			"nvidia-settings",
-			"xorg-x11-drv-nvidia-libs",
-			"xorg-x11-drv-nvidia-libs.i686", // 32-bit support
-		},
-		Settings: []string{
-			"nvidia-settings",
-		},
-		CUDA: []string{
-			"cuda",
-		},
-		CUDACompiler: []string{
-			"cuda-compiler",
-		},
-		CUDALibs: []string{
-			"cuda-libs",
-			"cuda-devel",
-		},
-		CUDnn: []string{
-			"cudnn",
-		},
-		OpenCL: []string{
-			"nvidia-driver-cuda",
-			"xorg-x11-drv-nvidia-cuda-libs",
-		},
-		Vulkan: []string{
-			"vulkan-loader",
-			"xorg-x11-drv-nvidia-vulkan",
-		},
-		Notes: "Requires RPM Fusion nonfree repository",
-	},
-
-	constants.FamilyArch: {
-		Family:       constants.FamilyArch,
-		Distribution: "arch",
-		Driver: []string{
-			"nvidia",
-		},
-		DriverDKMS: []string{
-			"nvidia-dkms",
-		},
-		Utils: []string{
-			"nvidia-utils",
-			"lib32-nvidia-utils", // 32-bit support
-		},
-		Settings: []string{
-			"nvidia-settings",
-		},
-		CUDA: []string{
-			"cuda",
-		},
-		CUDACompiler: []string{
-			"cuda",
-		},
-		CUDALibs: []string{
-			"cuda",
-		},
-		CUDnn: []string{
-			"cudnn",
-		},
-		OpenCL: []string{
-			"opencl-nvidia",
-		},
-		Vulkan: []string{
-			"nvidia-utils", // Vulkan ICD is included in nvidia-utils
-		},
-		Notes: "Uses official Arch repositories, no extra repository needed",
-	},
-
-	constants.FamilySUSE: {
-		Family:       constants.FamilySUSE,
-		Distribution: "opensuse",
-		Driver: []string{
-			"nvidia-driver-G06-kmp-default",
-		},
-		DriverDKMS: []string{
-			"nvidia-driver-G06-kmp-default",
-		},
-		Utils: []string{
-			"nvidia-driver-G06",
-		},
-		Settings: []string{
-			"nvidia-settings",
-		},
-		CUDA: []string{
-			"cuda",
-		},
-		CUDACompiler: []string{
-			"cuda",
-		},
-		CUDALibs: []string{
-			"cuda-devel",
-		},
-		CUDnn: []string{
-			"libcudnn8",
-			"libcudnn8-devel",
-		},
-		OpenCL: []string{
-			"nvidia-driver-G06",
-		},
-		Vulkan: []string{
-			"nvidia-driver-G06",
-		},
-		Notes: "Uses official NVIDIA openSUSE repository",
-	},
+// UsesAkmods returns true if the driver is an akmod package, whose kernel
+// module is built by akmods rather than DKMS, as with RPM Fusion.
+func (ps *PackageSet) UsesAkmods() bool {
+	for _, packages := range [][]string{ps.Driver, ps.DriverDKMS} {
+		for _, p := range packages {
+			if IsAkmodPackage(p) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
-// distroSpecificPackageSets contains overrides for specific distributions.
-var distroSpecificPackageSets = map[string]*PackageSet{
-	"ubuntu": {
-		Family:       constants.FamilyDebian,
-		Distribution: "ubuntu",
-		Driver: []string{
-			"nvidia-driver-550",
-			"nvidia-driver-545",
-			"nvidia-driver-535",
-		},
-		DriverDKMS: []string{
-			"nvidia-dkms-550",
-			"nvidia-dkms-545",
-			"nvidia-dkms-535",
-		},
-		Utils: []string{
-			"nvidia-utils-550",
-		},
-		Settings: []string{
-			"nvidia-settings",
-		},
-		CUDA: []string{
-			"nvidia-cuda-toolkit",
-		},
-		CUDACompiler: []string{
-			"nvidia-cuda-toolkit",
-		},
-		CUDALibs: []string{
-			"nvidia-cuda-dev",
-			"libcublas-dev",
-		},
-		CUDnn: []string{
-			"libcudnn8",
-			"libcudnn8-dev",
-		},
-		OpenCL: []string{
-			"nvidia-opencl-icd",
-		},
-		Vulkan: []string{
-			"nvidia-vulkan-icd",
-		},
-		DriverVersionPattern: "nvidia-driver-%s",
-		DKMSVersionPattern:   "nvidia-dkms-%s",
-		Notes:                "Ubuntu uses the graphics-drivers PPA or official NVIDIA CUDA repository",
-	},
-
-	"pop": {
-		Family:       constants.FamilyDebian,
-		Distribution: "pop",
-		Driver: []string{
-			"system76-driver-nvidia",
-		},
-		DriverDKMS: []string{
-			"system76-driver-nvidia",
-		},
-		Utils: []string{
-			"nvidia-utils-550",
-		},
-		Settings: []string{
-			"nvidia-settings",
-		},
-		CUDA: []string{
-			"nvidia-cuda-toolkit",
-		},
-		CUDACompiler: []string{
-			"nvidia-cuda-toolkit",
-		},
-		CUDALibs: []string{
-			"nvidia-cuda-dev",
-		},
-		CUDnn: []string{
-			"libcudnn8",
-			"libcudnn8-dev",
-		},
-		OpenCL: []string{
-			"nvidia-opencl-icd",
-		},
-		Vulkan: []string{
-			"nvidia-vulkan-icd",
-		},
-		Notes: "Pop!_OS uses System76's driver package",
-	},
-
-	"fedora": {
-		Family:       constants.FamilyRHEL,
-		Distribution: "fedora",
-		Driver: []string{
-			"akmod-nvidia",
-			"xorg-x11-drv-nvidia",
-		},
-		DriverDKMS: []string{
-			"akmod-nvidia",
-		},
-		Utils: []string{
-			"nvidia-settings",
-			"xorg-x11-drv-nvidia-libs",
-			"xorg-x11-drv-nvidia-libs.i686",
-		},
-		Settings: []string{
-			"nvidia-settings",
-		},
-		CUDA: []string{
-			"cuda",
-			"xorg-x11-drv-nvidia-cuda",
-		},
-		CUDACompiler: []string{
-			"cuda-compiler",
-		},
-		CUDALibs: []string{
-			"cuda-libs",
-			"cuda-devel",
-			"xorg-x11-drv-nvidia-cuda-libs",
-		},
-		CUDnn: []string{
-			"cudnn",
-		},
-		OpenCL: []string{
-			"xorg-x11-drv-nvidia-cuda-libs",
-		},
-		Vulkan: []string{
-			"vulkan-loader",
-			"xorg-x11-drv-nvidia-vulkan",
-		},
-		Notes: "Fedora requires RPM Fusion nonfree repository",
-	},
-
-	"opensuse-tumbleweed": {
-		Family:       constants.FamilySUSE,
-		Distribution: "opensuse-tumbleweed",
-		Driver: []string{
-			"nvidia-driver-G06-kmp-default",
-		},
-		DriverDKMS: []string{
-			"nvidia-driver-G06-kmp-default",
-		},
-		Utils: []string{
-			"nvidia-driver-G06",
-			"nvidia-compute-utils-G06",
-		},
-		Settings: []string{
-			"nvidia-settings",
-		},
-		CUDA: []string{
-			"cuda",
-		},
-		CUDACompiler: []string{
-			"cuda",
-		},
-		CUDALibs: []string{
-			"cuda-devel",
-		},
-		CUDnn: []string{
-			"libcudnn8",
-		},
-		OpenCL: []string{
-			"nvidia-gl-G06",
-		},
-		Vulkan: []string{
-			"nvidia-gl-G06",
-		},
-		Notes: "openSUSE Tumbleweed uses the official NVIDIA repository",
-	},
-
-	"opensuse-leap": {
-		Family:       constants.FamilySUSE,
-		Distribution: "opensuse-leap",
-		Driver: []string{
-			"nvidia-driver-G06-kmp-default",
-		},
-		DriverDKMS: []string{
-			"nvidia-driver-G06-kmp-default",
-		},
-		Utils: []string{
-			"nvidia-driver-G06",
-		},
-		Settings: []string{
-			"nvidia-settings",
-		},
-		CUDA: []string{
-			"cuda",
-		},
-		CUDACompiler: []string{
-			"cuda",
-		},
-		CUDALibs: []string{
-			"cuda-devel",
-		},
-		CUDnn: []string{
-			"libcudnn8",
-		},
-		OpenCL: []string{
-			"nvidia-gl-G06",
-		},
-		Vulkan: []string{
-			"nvidia-gl-G06",
-		},
-		Notes: "openSUSE Leap uses the official NVIDIA repository for the specific version",
-	},
-
-	"manjaro": {
-		Family:       constants.FamilyArch,
-		Distribution: "manjaro",
-		Driver: []string{
-			"linux-nvidia", // Manjaro's version-matched driver
-			"nvidia",
-		},
-		DriverDKMS: []string{
-			"nvidia-dkms",
-		},
-		Utils: []string{
-			"nvidia-utils",
-			"lib32-nvidia-utils",
-		},
-		Settings: []string{
-			"nvidia-settings",
-		},
-		CUDA: []string{
-			"cuda",
-		},
-		CUDACompiler: []string{
-			"cuda",
-		},
-		CUDALibs: []string{
-			"cuda",
-		},
-		CUDnn: []string{
-			"cudnn",
-		},
-		OpenCL: []string{
-			"opencl-nvidia",
-		},
-		Vulkan: []string{
-			"nvidia-utils",
-		},
-		Notes: "Manjaro provides version-matched nvidia packages via mhwd",
-	},
+// IsAkmodPackage returns true if the package is the source of a kernel
+// module built by akmods, such as "akmod-nvidia".
+func IsAkmodPackage(name string) bool {
+	return strings.HasPrefix(name, "akmod-")
 }
 
-// GetPackageSet returns the PackageSet for a specific distribution.
-// It first checks for distribution-specific overrides, then falls back
-// to the family-level package set.
-func GetPackageSet(dist *distro.Distribution) *PackageSet {
-	if dist == nil {
-		return nil
-	}
+// kernelDrivers are the driver packages prebuilt for a kernel package, by
+// distribution family: Arch ships nvidia for linux and nvidia-lts for
+// linux-lts, while other kernels such as linux-zen need nvidia-dkms.
+var kernelDrivers = map[constants.DistroFamily]map[string]string{
+	constants.FamilyArch: {"linux": "nvidia", "linux-lts": "nvidia-lts"},
+}
+
+// HasPrebuiltDrivers returns true if the driver of the distribution family
+// is prebuilt for specific kernel packages, so that the driver package
+// depends on the kernel flavor, see DriverPackagesForKernel.
+func (ps *PackageSet) HasPrebuiltDrivers() bool {
+	return len(kernelDrivers[ps.Family]) > 0
+}
 
-	// Check for distribution-specific package set first
-	if ps, ok := distroSpecificPackageSets[dist.ID]; ok {
-		return ps
+// DriverPackagesForKernel returns the driver packages matching a kernel
+// package, such as "linux-lts" on Arch, and whether they are DKMS packages
+// needing the headers of the kernel. The driver prebuilt for the kernel is
+// preferred, falling back to the DKMS driver. If the distribution family has
+// no prebuilt drivers or the kernel package is unknown, the default driver
+// packages are returned.
+func (ps *PackageSet) DriverPackagesForKernel(kernelPackage string) ([]string, bool) {
+	drivers := kernelDrivers[ps.Family]
+	if len(drivers) == 0 || kernelPackage == "" {
+		return ps.Driver, false
 	}
+	if driver, ok := drivers[kernelPackage]; ok {
+		return []string{driver}, false
+	}
+	if len(ps.DriverDKMS) == 0 {
+		return ps.Driver, false
+	}
+	return ps.DriverDKMS, true
+}
 
-	// Fall back to family-level package set
-	return GetPackageSetForFamily(dist.Family)
+// GetPackageSet returns the PackageSet for a specific distribution.
+// It first checks for distribution-specific overrides, then falls back
+// to the family-level package set. The package sets are those of the
+// catalog in use, see SetCatalog.
+func GetPackageSet(dist *distro.Distribution) *PackageSet {
+	return CurrentCatalog().PackageSet(dist)
 }
 
 // GetPackageSetForFamily returns the default PackageSet for a distribution family.
 func GetPackageSetForFamily(family constants.DistroFamily) *PackageSet {
-	if ps, ok := packageSets[family]; ok {
-		return ps
-	}
-	return nil
+	return CurrentCatalog().ForFamily(family)
 }
 
 // GetPackageSetByID returns the PackageSet for a specific distribution ID.
 func GetPackageSetByID(distroID string) *PackageSet {
-	distroID = strings.ToLower(distroID)
-	if ps, ok := distroSpecificPackageSets[distroID]; ok {
-		return ps
-	}
-	return nil
+	return CurrentCatalog().ByID(distroID)
 }
 
 // IsSupported returns true if the given distribution family is supported.
 func IsSupported(family constants.DistroFamily) bool {
-	_, ok := packageSets[family]
-	return ok
+	return GetPackageSetForFamily(family) != nil
 }
 
 // SupportedFamilies returns a list of all supported distribution families.
@@ -723,6 +393,19 @@ func SupportedFamilies() []constants.DistroFamily {
 	}
 }
 
+// optPackages are the packages installing their files in /opt rather than
+// /usr, by distribution family: Arch ships the CUDA toolkit in /opt/cuda.
+var optPackages = map[constants.DistroFamily]map[string]bool{
+	constants.FamilyArch: {"cuda": true, "cuda-tools": true},
+}
+
+// InstallsInOpt reports whether a package of the distribution family
+// installs its files in /opt, so that the disk space it needs can be
+// checked against the right filesystem.
+func InstallsInOpt(family constants.DistroFamily, name string) bool {
+	return optPackages[family][name]
+}
+
 // GetRecommendedDriverVersion returns the recommended driver version for new installations.
 func GetRecommendedDriverVersion() string {
 	if len(SupportedDriverVersions) > 0 {
@@ -242,6 +242,52 @@ func TestPackageSet_GetGraphicsPackages(t *testing.T) {
 	assert.NotContains(t, packages, "nvidia-cuda-toolkit")
 }
 
+func TestPackageSet_UsesAkmods(t *testing.T) {
+	assert.True(t, GetPackageSetByID("fedora").UsesAkmods())
+	assert.True(t, GetPackageSetForFamily(constants.FamilyRHEL).UsesAkmods())
+	assert.False(t, GetPackageSetForFamily(constants.FamilyDebian).UsesAkmods())
+	assert.False(t, GetPackageSetForFamily(constants.FamilyArch).UsesAkmods())
+
+	assert.True(t, IsAkmodPackage("akmod-nvidia"))
+	assert.False(t, IsAkmodPackage("kmod-nvidia"))
+}
+
+func TestPackageSet_DriverPackagesForKernel(t *testing.T) {
+	arch := GetPackageSetForFamily(constants.FamilyArch)
+	require.NotNil(t, arch)
+	assert.True(t, arch.HasPrebuiltDrivers())
+
+	tests := []struct {
+		kernelPackage string
+		packages      []string
+		dkms          bool
+	}{
+		{"linux", []string{"nvidia"}, false},
+		{"linux-lts", []string{"nvidia-lts"}, false},
+		{"linux-zen", []string{"nvidia-dkms"}, true},
+		{"linux-hardened", []string{"nvidia-dkms"}, true},
+		{"", []string{"nvidia"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kernelPackage, func(t *testing.T) {
+			packages, dkms := arch.DriverPackagesForKernel(tt.kernelPackage)
+			assert.Equal(t, tt.packages, packages)
+			assert.Equal(t, tt.dkms, dkms)
+		})
+	}
+
+	t.Run("no prebuilt drivers", func(t *testing.T) {
+		debian := GetPackageSetForFamily(constants.FamilyDebian)
+		require.NotNil(t, debian)
+		assert.False(t, debian.HasPrebuiltDrivers())
+
+		packages, dkms := debian.DriverPackagesForKernel("linux-image-6.8.0-1012-azure")
+		assert.Equal(t, debian.Driver, packages)
+		assert.False(t, dkms)
+	})
+}
+
 func TestGetPackageSetForFamily(t *testing.T) {
 	tests := []struct {
 		family   constants.DistroFamily
@@ -551,6 +597,52 @@ func TestGetDKMSPackagesForVersion_NoPattern(t *testing.T) {
 	assert.Equal(t, []string{"nvidia-dkms"}, packages)
 }
 
+func TestGetCUDAPackagesForVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		ps       *PackageSet
+		version  string
+		expected []string
+	}{
+		{
+			"nvidia repository",
+			&PackageSet{Family: constants.FamilyRHEL, CUDA: []string{"cuda", "xorg-x11-drv-nvidia-cuda"}},
+			"12.4",
+			[]string{"cuda-toolkit-12-4", "xorg-x11-drv-nvidia-cuda"},
+		},
+		{
+			"major only",
+			&PackageSet{Family: constants.FamilySUSE, CUDA: []string{"cuda"}},
+			"12",
+			[]string{"cuda-toolkit-12"},
+		},
+		{
+			"empty version",
+			&PackageSet{Family: constants.FamilyRHEL, CUDA: []string{"cuda"}},
+			"",
+			[]string{"cuda"},
+		},
+		{
+			"distribution package",
+			&PackageSet{Family: constants.FamilyDebian, CUDA: []string{"nvidia-cuda-toolkit"}},
+			"12.4",
+			[]string{"nvidia-cuda-toolkit"},
+		},
+		{
+			"arch",
+			&PackageSet{Family: constants.FamilyArch, CUDA: []string{"cuda"}},
+			"12.4",
+			[]string{"cuda"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.ps.GetCUDAPackagesForVersion(tt.version))
+		})
+	}
+}
+
 func TestGetPackageSet_FallbackToFamily(t *testing.T) {
 	// Test that an unknown distro ID falls back to family-level package set
 	dist := &distro.Distribution{
@@ -611,3 +703,10 @@ func TestSupportedDriverVersions_Order(t *testing.T) {
 	// First version should be the recommended one
 	assert.Equal(t, GetRecommendedDriverVersion(), versions[0])
 }
+
+func TestInstallsInOpt(t *testing.T) {
+	assert.True(t, InstallsInOpt(constants.FamilyArch, "cuda"))
+	assert.False(t, InstallsInOpt(constants.FamilyArch, "cudnn"))
+	assert.False(t, InstallsInOpt(constants.FamilyArch, "nvidia-utils"))
+	assert.False(t, InstallsInOpt(constants.FamilyDebian, "cuda"))
+}
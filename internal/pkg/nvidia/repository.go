@@ -59,6 +59,9 @@ const (
 	// The %fedora variable is expanded by rpm.
 	RPMFusionNonfreeFedoraURL = "https://download1.rpmfusion.org/nonfree/fedora/rpmfusion-nonfree-release-%s.noarch.rpm"
 
+	// RPM Fusion Nonfree signing key for Fedora 32 and later.
+	RPMFusionNonfreeFedoraGPGKeyURL = "https://rpmfusion.org/keys?action=AttachFile&do=get&target=RPM-GPG-KEY-rpmfusion-nonfree-fedora-2020"
+
 	// RPM Fusion Free repository URL template for Fedora.
 	RPMFusionFreeFedoraURL = "https://download1.rpmfusion.org/free/fedora/rpmfusion-free-release-%s.noarch.rpm"
 
@@ -76,6 +79,18 @@ const (
 	OpenSUSELeapNvidiaURL = "https://download.nvidia.com/opensuse/leap/%s"
 )
 
+// PreferredPackages are the package globs the NVIDIA repository is given
+// priority for over the distribution repositories, so the driver and CUDA
+// packages all come from it rather than from whichever has the newest
+// version of each.
+var PreferredPackages = []string{
+	"nvidia-*",
+	"libnvidia-*",
+	"xserver-xorg-video-nvidia-*",
+	"cuda",
+	"cuda-*",
+}
+
 // Ubuntu codename to CUDA repository path mapping.
 var ubuntuCUDARepos = map[string]string{
 	"noble":  "ubuntu2404", // Ubuntu 24.04
@@ -303,6 +318,7 @@ func getRHELRepository(dist *distro.Distribution) (*pkg.Repository, error) {
 			Name:    "rpmfusion-nonfree",
 			URL:     fmt.Sprintf(RPMFusionNonfreeFedoraURL, version),
 			Enabled: true,
+			GPGKey:  RPMFusionNonfreeFedoraGPGKeyURL,
 			Type:    "rpm",
 		}, nil
 	}
@@ -557,7 +573,10 @@ func GetGPGKeyURL(dist *distro.Distribution) (string, error) {
 		return fmt.Sprintf(CUDAGPGKeyURL, repoPath), nil
 
 	case constants.FamilyRHEL:
-		// RPM Fusion handles its own keys
+		// On EL, the RPM Fusion release package installs its own keys
+		if dist.ID == "fedora" {
+			return RPMFusionNonfreeFedoraGPGKeyURL, nil
+		}
 		return "", nil
 
 	case constants.FamilyArch:
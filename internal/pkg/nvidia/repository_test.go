@@ -481,11 +481,19 @@ func TestGetGPGKeyURL(t *testing.T) {
 			wantContain: "3bf863cc.pub",
 		},
 		{
-			name: "rhel",
+			name: "fedora",
 			dist: &distro.Distribution{
 				ID:     "fedora",
 				Family: constants.FamilyRHEL,
 			},
+			wantContain: "RPM-GPG-KEY-rpmfusion-nonfree-fedora",
+		},
+		{
+			name: "rhel",
+			dist: &distro.Distribution{
+				ID:     "rocky",
+				Family: constants.FamilyRHEL,
+			},
 			wantEmpty: true,
 		},
 		{
@@ -0,0 +1,67 @@
+package pacman
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// DownloadBundle downloads the packages and their dependencies into dir
+// with pacman -Syw and creates the repository database with repo-add.
+// The sync databases are refreshed into an empty temporary database path,
+// so pacman sees no installed package and downloads the whole dependency
+// closure, including the packages installed on this system, and the bundle
+// can be installed on a minimal system of the same release. The database
+// of the system is neither read nor locked.
+func (m *Manager) DownloadBundle(ctx context.Context, dir string, packages ...string) error {
+	if len(packages) == 0 {
+		return nil
+	}
+
+	root, err := pkg.NewBundleRoot(ctx, m.executor)
+	if err != nil {
+		return err
+	}
+	defer root.Remove(ctx)
+
+	args := []string{"-Syw", "--noconfirm", "--dbpath", root.Path, "--cachedir", dir}
+	args = append(args, packages...)
+	result := m.executor.ExecuteElevated(ctx, "pacman", args...)
+	if result.Failed() {
+		stderr := result.StderrString()
+		if strings.Contains(stderr, "failed to retrieve some files") ||
+			strings.Contains(stderr, "failed to synchronize") {
+			return pkg.Wrap(pkg.ErrNetworkUnavailable, fmt.Errorf("pacman -Syw failed: %s", stderr))
+		}
+		return pkg.Wrap(pkg.ErrBundleFailed, fmt.Errorf("pacman -Syw failed (exit code %d): %s", result.ExitCode, stderr))
+	}
+
+	// find runs repo-add once with all the package files, leaving out
+	// their signatures
+	db := filepath.Join(dir, pkg.BundleRepositoryName+".db.tar.gz")
+	result = m.executor.ExecuteElevated(ctx, "find", dir, "-maxdepth", "1",
+		"-name", "*.pkg.tar.*", "!", "-name", "*.sig", "-exec", "repo-add", db, "{}", "+")
+	if result.Failed() {
+		return pkg.Wrap(pkg.ErrBundleFailed, fmt.Errorf("repo-add failed (exit code %d): %s", result.ExitCode, result.StderrString()))
+	}
+
+	return nil
+}
+
+// BundleRepository returns the local repository serving the bundle in dir.
+// Without a GPG key the repository is added with SigLevel Optional TrustAll.
+func (m *Manager) BundleRepository(dir string) pkg.Repository {
+	return pkg.Repository{
+		Name:    pkg.BundleRepositoryName,
+		URL:     "file://" + dir,
+		Enabled: true,
+		Type:    "pkg",
+		Trusted: true,
+	}
+}
+
+// Ensure Manager implements pkg.BundleManager interface.
+var _ pkg.BundleManager = (*Manager)(nil)
@@ -0,0 +1,45 @@
+package pacman
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// pacmanLogPath is the pacman log, which records every transaction.
+const pacmanLogPath = "/var/log/pacman.log"
+
+// History returns the pacman transaction history, newest first.
+// It is read from /var/log/pacman.log. pacman has no transaction IDs, so
+// entries are numbered in log order, oldest first.
+func (m *Manager) History(ctx context.Context, limit int) ([]pkg.HistoryEntry, error) {
+	result := m.executor.Execute(ctx, "cat", pacmanLogPath)
+	if result.Failed() {
+		if strings.Contains(result.StderrString(), "No such file") {
+			return []pkg.HistoryEntry{}, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %s", pacmanLogPath, result.StderrString())
+	}
+
+	entries := parsePacmanLog(result.StdoutString())
+	for i := range entries {
+		entries[i].ID = strconv.Itoa(i + 1)
+	}
+	return pkg.NewestHistory(entries, limit), nil
+}
+
+// Undo is not supported: pacman cannot undo transactions.
+func (m *Manager) Undo(ctx context.Context, id string) error {
+	return pkg.Wrap(pkg.ErrUnsupportedOperation, fmt.Errorf("pacman cannot undo transactions"))
+}
+
+// Redo is not supported: pacman cannot redo transactions.
+func (m *Manager) Redo(ctx context.Context, id string) error {
+	return pkg.Wrap(pkg.ErrUnsupportedOperation, fmt.Errorf("pacman cannot redo transactions"))
+}
+
+// Ensure Manager implements pkg.HistoryManager interface.
+var _ pkg.HistoryManager = (*Manager)(nil)
@@ -0,0 +1,59 @@
+package pacman
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// Hold prevents packages from being upgraded.
+// For Pacman, held packages are added to the IgnorePkg option of the
+// [options] section of /etc/pacman.conf.
+func (m *Manager) Hold(ctx context.Context, packages ...string) error {
+	if len(packages) == 0 {
+		return nil
+	}
+	return m.updateIgnorePkg(ctx, packages, nil)
+}
+
+// Unhold releases package holds by removing the packages from IgnorePkg.
+func (m *Manager) Unhold(ctx context.Context, packages ...string) error {
+	if len(packages) == 0 {
+		return nil
+	}
+	return m.updateIgnorePkg(ctx, nil, packages)
+}
+
+// ListHeld returns the packages listed in IgnorePkg in /etc/pacman.conf.
+func (m *Manager) ListHeld(ctx context.Context) ([]string, error) {
+	result := m.executor.Execute(ctx, "cat", pacmanConfPath)
+	if result.Failed() {
+		return nil, fmt.Errorf("failed to read pacman.conf: %s", result.StderrString())
+	}
+	return parseIgnorePkg(result.StdoutString()), nil
+}
+
+// updateIgnorePkg adds and removes packages in the IgnorePkg option of
+// /etc/pacman.conf. The file is only written if it changes.
+func (m *Manager) updateIgnorePkg(ctx context.Context, add, remove []string) error {
+	result := m.executor.Execute(ctx, "cat", pacmanConfPath)
+	if result.Failed() {
+		return pkg.Wrap(pkg.ErrHoldFailed, fmt.Errorf("failed to read pacman.conf: %s", result.StderrString()))
+	}
+
+	content, changed := setIgnorePkg(result.StdoutString(), add, remove)
+	if !changed {
+		return nil
+	}
+
+	result = m.executor.ExecuteWithInput(ctx, []byte(content), "sudo", "tee", pacmanConfPath)
+	if result.Failed() {
+		return pkg.Wrap(pkg.ErrHoldFailed, fmt.Errorf("failed to update pacman.conf: %s", result.StderrString()))
+	}
+
+	return nil
+}
+
+// Ensure Manager implements pkg.HoldManager interface.
+var _ pkg.HoldManager = (*Manager)(nil)
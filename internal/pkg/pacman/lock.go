@@ -0,0 +1,45 @@
+package pacman
+
+import (
+	"context"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// pacmanLockFiles are the lock files of pacman. pacman refuses to run while
+// db.lck exists, even when the process that created it is gone.
+var pacmanLockFiles = []pkg.LockFile{
+	{Path: "/var/lib/pacman/db.lck", Kind: pkg.LockKindExistence},
+}
+
+// AcquireLock verifies that the pacman lock is free.
+// Pacman commands take the lock themselves, so it is not held afterwards.
+// Returns ErrLockAcquireFailed, naming the process holding the lock, if it
+// is held.
+func (m *Manager) AcquireLock(ctx context.Context) error {
+	return m.locks.AcquireLock(ctx)
+}
+
+// ReleaseLock does nothing, since AcquireLock does not hold the lock.
+func (m *Manager) ReleaseLock(ctx context.Context) error {
+	return m.locks.ReleaseLock(ctx)
+}
+
+// IsLocked checks if another process holds the pacman lock.
+func (m *Manager) IsLocked(ctx context.Context) (bool, error) {
+	return m.locks.IsLocked(ctx)
+}
+
+// WaitForLock waits until the pacman lock is free or ctx is done.
+func (m *Manager) WaitForLock(ctx context.Context) error {
+	return m.locks.WaitForLock(ctx)
+}
+
+// LockHolder returns the process holding the pacman lock, or nil if the
+// lock is free.
+func (m *Manager) LockHolder(ctx context.Context) (*pkg.LockHolder, error) {
+	return m.locks.LockHolder(ctx)
+}
+
+// Ensure Manager implements pkg.LockableManager interface.
+var _ pkg.LockableManager = (*Manager)(nil)
@@ -19,6 +19,8 @@ import (
 type Manager struct {
 	executor  igorexec.Executor
 	privilege *privilege.Manager
+	locks     *pkg.LockChecker
+	tx        pkg.Transaction
 }
 
 // NewManager creates a new Pacman package manager.
@@ -28,6 +30,7 @@ func NewManager(executor igorexec.Executor, priv *privilege.Manager) *Manager {
 	return &Manager{
 		executor:  executor,
 		privilege: priv,
+		locks:     pkg.NewLockChecker(executor, "pacman", pacmanLockFiles...),
 	}
 }
 
@@ -48,14 +51,21 @@ func (m *Manager) IsAvailable() bool {
 }
 
 // Install installs one or more packages using pacman -S.
-// Uses --noconfirm for non-interactive operation.
+// Uses --noconfirm for non-interactive operation. If opts.Progress is set,
+// the download and installation progress is read from the output of pacman
+// as it runs.
 func (m *Manager) Install(ctx context.Context, opts pkg.InstallOptions, packages ...string) error {
 	if len(packages) == 0 {
 		return nil
 	}
 
 	args := m.buildInstallArgs(opts, packages)
-	result := m.executor.ExecuteElevated(ctx, "pacman", args...)
+	var result *igorexec.Result
+	if opts.Progress != nil {
+		result = m.executeElevatedWithProgress(ctx, opts.Progress, args...)
+	} else {
+		result = m.executor.ExecuteElevated(ctx, "pacman", args...)
+	}
 
 	if result.Failed() {
 		stderr := result.StderrString()
@@ -102,6 +112,14 @@ func (m *Manager) buildInstallArgs(opts pkg.InstallOptions, packages []string) [
 	// Signature verification is controlled via SigLevel in pacman.conf
 	// We don't add any flag here as there's no safe per-command option
 
+	// A repository prefix makes pacman take the package from that repository
+	if opts.Repository != "" {
+		for _, p := range packages {
+			args = append(args, opts.Repository+"/"+p)
+		}
+		return args
+	}
+
 	args = append(args, packages...)
 	return args
 }
@@ -154,6 +172,8 @@ func (m *Manager) Remove(ctx context.Context, opts pkg.RemoveOptions, packages .
 }
 
 // Update updates the package database using pacman -Sy.
+// If opts.Repository is set, only a failure to refresh that repository is
+// reported.
 func (m *Manager) Update(ctx context.Context, opts pkg.UpdateOptions) error {
 	args := []string{"-Sy"}
 
@@ -169,6 +189,11 @@ func (m *Manager) Update(ctx context.Context, opts pkg.UpdateOptions) error {
 			strings.Contains(stderr, "database is locked") {
 			return pkg.Wrap(pkg.ErrLockAcquireFailed, fmt.Errorf("pacman -Sy failed: %s", stderr))
 		}
+		// pacman cannot refresh a single repository, so failures of the
+		// others are ignored
+		if opts.Repository != "" && !strings.Contains(stderr, opts.Repository+".db") {
+			return nil
+		}
 		// Network errors
 		if strings.Contains(stderr, "failed to retrieve") ||
 			strings.Contains(stderr, "failed to download") ||
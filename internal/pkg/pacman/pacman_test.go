@@ -2,7 +2,9 @@ package pacman
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -1186,3 +1188,629 @@ func TestParsePacmanSs_InstalledVariations(t *testing.T) {
 	require.Len(t, packages, 1)
 	assert.True(t, packages[0].Installed)
 }
+
+// =============================================================================
+// Hold Tests
+// =============================================================================
+
+const testPacmanConf = `[options]
+HoldPkg     = pacman glibc
+#IgnorePkg   =
+Architecture = auto
+
+[core]
+Include = /etc/pacman.d/mirrorlist
+`
+
+func TestManager_Hold(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("cat", exec.SuccessResult(testPacmanConf))
+
+	err := mgr.Hold(context.Background(), "nvidia", "nvidia-utils")
+	require.NoError(t, err)
+
+	call := mockExec.LastCall()
+	assert.Equal(t, "sudo", call.Command)
+	assert.Equal(t, []string{"tee", pacmanConfPath}, call.Args)
+	assert.Contains(t, string(call.Input), "[options]\nIgnorePkg = nvidia nvidia-utils\nHoldPkg")
+	assert.Contains(t, string(call.Input), "#IgnorePkg   =\n")
+}
+
+func TestManager_Hold_AlreadyHeld(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("cat", exec.SuccessResult("[options]\nIgnorePkg = nvidia\n"))
+
+	err := mgr.Hold(context.Background(), "nvidia")
+	require.NoError(t, err)
+	assert.False(t, mockExec.WasCalled("sudo"))
+}
+
+func TestManager_Hold_ReadFailure(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("cat", exec.FailureResult(1, "No such file or directory"))
+
+	err := mgr.Hold(context.Background(), "nvidia")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, pkg.ErrHoldFailed)
+}
+
+func TestManager_Hold_WriteFailure(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("cat", exec.SuccessResult(testPacmanConf))
+	mockExec.SetResponse("sudo", exec.FailureResult(1, "Permission denied"))
+
+	err := mgr.Hold(context.Background(), "nvidia")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, pkg.ErrHoldFailed)
+}
+
+func TestManager_Hold_EmptyPackages(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	require.NoError(t, mgr.Hold(context.Background()))
+	require.NoError(t, mgr.Unhold(context.Background()))
+	assert.Equal(t, 0, mockExec.CallCount())
+}
+
+func TestManager_Unhold(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("cat", exec.SuccessResult("[options]\nIgnorePkg = nvidia linux\n"))
+
+	err := mgr.Unhold(context.Background(), "nvidia")
+	require.NoError(t, err)
+	assert.Equal(t, "[options]\nIgnorePkg = linux\n", string(mockExec.LastCall().Input))
+}
+
+func TestManager_Unhold_NotHeld(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("cat", exec.SuccessResult(testPacmanConf))
+
+	err := mgr.Unhold(context.Background(), "nvidia")
+	require.NoError(t, err)
+	assert.False(t, mockExec.WasCalled("sudo"))
+}
+
+func TestManager_ListHeld(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("cat", exec.SuccessResult("[options]\nIgnorePkg = nvidia\nIgnorePkg = linux nvidia-utils\n[extra]\nIgnorePkg = ignored\n"))
+
+	held, err := mgr.ListHeld(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nvidia", "linux", "nvidia-utils"}, held)
+}
+
+func TestSetIgnorePkg(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		add      []string
+		remove   []string
+		expected string
+		changed  bool
+	}{
+		{
+			name:     "add to existing line",
+			content:  "[options]\nIgnorePkg = linux\n",
+			add:      []string{"nvidia"},
+			expected: "[options]\nIgnorePkg = linux nvidia\n",
+			changed:  true,
+		},
+		{
+			name:     "add new line",
+			content:  "[options]\nArchitecture = auto\n",
+			add:      []string{"nvidia"},
+			expected: "[options]\nIgnorePkg = nvidia\nArchitecture = auto\n",
+			changed:  true,
+		},
+		{
+			name:     "add options section",
+			content:  "[core]\n",
+			add:      []string{"nvidia"},
+			expected: "[options]\nIgnorePkg = nvidia\n\n[core]\n",
+			changed:  true,
+		},
+		{
+			name:     "remove last entry deletes line",
+			content:  "[options]\nIgnorePkg = nvidia\nArchitecture = auto\n",
+			remove:   []string{"nvidia"},
+			expected: "[options]\nArchitecture = auto\n",
+			changed:  true,
+		},
+		{
+			name:     "commented line untouched",
+			content:  "[options]\n#IgnorePkg = nvidia\n",
+			remove:   []string{"nvidia"},
+			expected: "[options]\n#IgnorePkg = nvidia\n",
+			changed:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, changed := setIgnorePkg(tt.content, tt.add, tt.remove)
+			assert.Equal(t, tt.expected, content)
+			assert.Equal(t, tt.changed, changed)
+		})
+	}
+}
+
+// =============================================================================
+// Lock Tests
+// =============================================================================
+
+func TestManager_LockHolder(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("test", exec.SuccessResult(""))
+	mockExec.SetResponse("find", exec.SuccessResult("/proc/300/fd/3 /var/lib/pacman/db.lck\n"))
+	mockExec.SetResponse("cat", exec.SuccessResult("pacman\n"))
+
+	holder, err := mgr.LockHolder(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, holder)
+	assert.Equal(t, "pacman (pid 300)", holder.String())
+	assert.True(t, mockExec.WasCalledWith("test", "-e", "/var/lib/pacman/db.lck"))
+}
+
+func TestManager_LockHolder_StaleLock(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("test", exec.SuccessResult(""))
+	mockExec.SetResponse("find", exec.SuccessResult(""))
+
+	locked, err := mgr.IsLocked(context.Background())
+	require.NoError(t, err)
+	assert.True(t, locked, "pacman refuses to run while db.lck exists")
+
+	err = mgr.AcquireLock(context.Background())
+	require.Error(t, err)
+	assert.ErrorIs(t, err, pkg.ErrLockAcquireFailed)
+	assert.Contains(t, err.Error(), "/var/lib/pacman/db.lck exists")
+}
+
+func TestManager_IsLocked_Free(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("test", exec.FailureResult(1, ""))
+
+	locked, err := mgr.IsLocked(context.Background())
+	require.NoError(t, err)
+	assert.False(t, locked)
+	assert.False(t, mockExec.WasCalled("find"))
+	assert.NoError(t, mgr.AcquireLock(context.Background()))
+}
+
+// =============================================================================
+// History Tests
+// =============================================================================
+
+const testPacmanLog = `[2024-01-10T09:00:00+0000] [PACMAN] Running 'pacman -Syu'
+[2024-01-10T09:00:01+0000] [PACMAN] synchronizing package lists
+[2024-01-10T09:00:12+0000] [ALPM] transaction started
+[2024-01-10T09:00:20+0000] [ALPM] upgraded nvidia-utils (545.29.06-1 -> 550.54.14-1)
+[2024-01-10T09:00:21+0000] [ALPM] upgraded nvidia (545.29.06-1 -> 550.54.14-1)
+[2024-01-10T09:00:22+0000] [ALPM-SCRIPTLET] ==> Building module nvidia
+[2024-01-10T09:00:30+0000] [ALPM] installed linux-headers (6.7.2.arch1-1)
+[2024-01-10T09:00:40+0000] [ALPM] transaction completed
+[2024-01-15T10:23:45+0000] [PACMAN] Running 'pacman -R nvidia'
+[2024-01-15T10:23:46+0000] [ALPM] transaction started
+[2024-01-15T10:23:50+0000] [ALPM] removed nvidia (550.54.14-1)
+[2024-01-15T10:23:51+0000] [ALPM] transaction failed
+[2019-01-15 10:23] [ALPM] transaction started
+[2019-01-15 10:23] [ALPM] reinstalled nvidia (415.25-2)
+`
+
+func TestParsePacmanLog(t *testing.T) {
+	entries := parsePacmanLog(testPacmanLog)
+	require.Len(t, entries, 3)
+
+	assert.True(t, entries[0].Success)
+	assert.Equal(t, "pacman -Syu", entries[0].Details)
+	assert.Equal(t, "upgrade, install", entries[0].Operation)
+	assert.Equal(t, []string{"nvidia-utils", "nvidia", "linux-headers"}, entries[0].Packages)
+	assert.Equal(t, time.Date(2024, 1, 10, 9, 0, 12, 0, time.UTC).Unix(), entries[0].Timestamp)
+
+	assert.False(t, entries[1].Success)
+	assert.Equal(t, "remove", entries[1].Operation)
+	assert.Equal(t, "pacman -R nvidia", entries[1].Details)
+
+	// Unterminated transaction with the pre-5.1 timestamp format
+	assert.False(t, entries[2].Success)
+	assert.Empty(t, entries[2].Details)
+	assert.Equal(t, "reinstall", entries[2].Operation)
+	assert.Equal(t, time.Date(2019, 1, 15, 10, 23, 0, 0, time.Local).Unix(), entries[2].Timestamp)
+}
+
+func TestManager_History(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("cat", exec.SuccessResult(testPacmanLog))
+
+	entries, err := mgr.History(context.Background(), 2)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "3", entries[0].ID)
+	assert.Equal(t, "2", entries[1].ID)
+	assert.True(t, mockExec.WasCalledWith("cat", "/var/log/pacman.log"))
+}
+
+func TestManager_History_NoLog(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("cat", exec.FailureResult(1, "cat: /var/log/pacman.log: No such file or directory"))
+
+	entries, err := mgr.History(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestManager_Undo_NotSupported(t *testing.T) {
+	mgr, _ := setupTest()
+
+	assert.ErrorIs(t, mgr.Undo(context.Background(), "1"), pkg.ErrUnsupportedOperation)
+	assert.ErrorIs(t, mgr.Redo(context.Background(), "1"), pkg.ErrUnsupportedOperation)
+}
+
+// =============================================================================
+// Transaction Tests
+// =============================================================================
+
+const testPacmanCache = `/var/cache/pacman/pkg/nvidia-utils-535.154.05-1-x86_64.pkg.tar.zst
+/var/cache/pacman/pkg/nvidia-utils-535.154.05-1-x86_64.pkg.tar.zst.sig
+/var/cache/pacman/pkg/nvidia-535.154.05-1-x86_64.pkg.tar.zst
+/var/cache/pacman/pkg/nvidia-utils-550.54.14-3-x86_64.pkg.tar.zst
+/var/cache/pacman/pkg/xf86-video-nouveau-1.0.17-3-x86_64.pkg.tar.zst
+`
+
+func TestFindCachedPackages(t *testing.T) {
+	files := strings.Fields(testPacmanCache)
+
+	found, missing := findCachedPackages(files, []pkg.Package{
+		{Name: "nvidia", Version: "535.154.05-1"},
+		{Name: "nvidia-utils", Version: "535.154.05-1"},
+		{Name: "mesa", Version: "1:24.0.1-1"},
+	})
+
+	assert.Equal(t, []string{
+		"/var/cache/pacman/pkg/nvidia-535.154.05-1-x86_64.pkg.tar.zst",
+		"/var/cache/pacman/pkg/nvidia-utils-535.154.05-1-x86_64.pkg.tar.zst",
+	}, found)
+	assert.Equal(t, []string{"mesa"}, missing)
+}
+
+func TestManager_Transaction_Commit(t *testing.T) {
+	mgr, mockExec := setupTest()
+	ctx := context.Background()
+	mockExec.SetResponse("pacman", exec.SuccessResult("nvidia 535.154.05-1\nnvidia-utils 535.154.05-1\n"))
+
+	require.NoError(t, mgr.BeginTransaction(ctx))
+	assert.True(t, mgr.InTransaction())
+	assert.True(t, mockExec.WasCalledWith("pacman", "-Q"))
+
+	// Operations run right away
+	require.NoError(t, mgr.Install(ctx, pkg.NonInteractiveInstallOptions(), "nvidia-settings"))
+	assert.True(t, mockExec.WasCalledWith("pacman", "-S", "--noconfirm", "nvidia-settings"))
+
+	require.NoError(t, mgr.CommitTransaction(ctx))
+	assert.False(t, mgr.InTransaction())
+}
+
+func TestManager_Transaction_Rollback(t *testing.T) {
+	mgr, mockExec := setupTest()
+	ctx := context.Background()
+	mockExec.SetResponse("pacman", exec.SuccessResult("nvidia 535.154.05-1\nnvidia-utils 535.154.05-1\n"))
+
+	require.NoError(t, mgr.BeginTransaction(ctx))
+	mockExec.SetResponse("pacman", exec.SuccessResult("nvidia 535.154.05-1\nnvidia-utils 550.54.14-3\nnvidia-settings 550.54.14-1\n"))
+	mockExec.SetResponse("find", exec.SuccessResult(testPacmanCache))
+	require.NoError(t, mgr.RollbackTransaction(ctx))
+
+	assert.False(t, mgr.InTransaction())
+	assert.True(t, mockExec.WasCalledWith("find", "/var/cache/pacman/pkg", "-maxdepth", "1", "-name", "*.pkg.tar.*"))
+	assert.True(t, mockExec.WasCalledWith("pacman", "-U", "--noconfirm",
+		"/var/cache/pacman/pkg/nvidia-utils-535.154.05-1-x86_64.pkg.tar.zst"))
+	assert.True(t, mockExec.WasCalledWith("pacman", "-R", "--noconfirm", "nvidia-settings"))
+}
+
+func TestManager_Transaction_RollbackNotCached(t *testing.T) {
+	mgr, mockExec := setupTest()
+	ctx := context.Background()
+	mockExec.SetResponse("pacman", exec.SuccessResult("xf86-video-nouveau 1.0.17-4\n"))
+
+	require.NoError(t, mgr.BeginTransaction(ctx))
+	mockExec.SetResponse("pacman", exec.SuccessResult("nvidia 550.54.14-3\n"))
+	mockExec.SetResponse("find", exec.SuccessResult(testPacmanCache))
+	err := mgr.RollbackTransaction(ctx)
+
+	assert.ErrorIs(t, err, pkg.ErrTransactionFailed)
+	assert.Contains(t, err.Error(), "previous versions of xf86-video-nouveau are not in /var/cache/pacman/pkg")
+	// Nothing is changed
+	assert.False(t, mockExec.WasCalledWith("pacman", "-R", "--noconfirm", "nvidia"))
+}
+
+func TestManager_Transaction_NotInProgress(t *testing.T) {
+	mgr, _ := setupTest()
+	ctx := context.Background()
+
+	assert.ErrorIs(t, mgr.CommitTransaction(ctx), pkg.ErrTransactionFailed)
+	assert.ErrorIs(t, mgr.RollbackTransaction(ctx), pkg.ErrTransactionFailed)
+}
+
+// =============================================================================
+// Bundle Tests
+// =============================================================================
+
+// setupBundleTest creates a manager whose mktemp creates the bundle root.
+func setupBundleTest() (*Manager, *exec.MockExecutor) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("mktemp", exec.SuccessResult("/tmp/igor-bundle.Xy12Ab34Cd\n"))
+	return mgr, mockExec
+}
+
+func TestManager_DownloadBundle(t *testing.T) {
+	mgr, mockExec := setupBundleTest()
+	ctx := context.Background()
+
+	err := mgr.DownloadBundle(ctx, "/srv/bundle", "nvidia", "nvidia-utils")
+	require.NoError(t, err)
+
+	calls := mockExec.Calls()
+	require.Len(t, calls, 4)
+	assert.Equal(t, "mktemp", calls[0].Command)
+	assert.Equal(t, "pacman", calls[1].Command)
+	assert.Equal(t, []string{
+		"-Syw", "--noconfirm", "--dbpath", "/tmp/igor-bundle.Xy12Ab34Cd", "--cachedir", "/srv/bundle",
+		"nvidia", "nvidia-utils",
+	}, calls[1].Args)
+	assert.Equal(t, "find", calls[2].Command)
+	assert.Equal(t, []string{
+		"/srv/bundle", "-maxdepth", "1", "-name", "*.pkg.tar.*", "!", "-name", "*.sig",
+		"-exec", "repo-add", "/srv/bundle/igor-bundle.db.tar.gz", "{}", "+",
+	}, calls[2].Args)
+	assert.Equal(t, "rm", calls[3].Command)
+	assert.Equal(t, []string{"-rf", "/tmp/igor-bundle.Xy12Ab34Cd"}, calls[3].Args)
+}
+
+func TestManager_DownloadBundle_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("bundle root fails", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		mockExec.SetResponse("mktemp", exec.FailureResult(1, "mktemp: failed to create directory"))
+		err := mgr.DownloadBundle(ctx, "/srv/bundle", "nvidia")
+		assert.ErrorIs(t, err, pkg.ErrBundleFailed)
+		assert.False(t, mockExec.WasCalled("pacman"))
+	})
+
+	t.Run("download fails", func(t *testing.T) {
+		mgr, mockExec := setupBundleTest()
+		mockExec.SetResponse("pacman", exec.FailureResult(1, "error: target not found: nvidia-999"))
+		err := mgr.DownloadBundle(ctx, "/srv/bundle", "nvidia-999")
+		assert.ErrorIs(t, err, pkg.ErrBundleFailed)
+		assert.False(t, mockExec.WasCalled("find"))
+		assert.True(t, mockExec.WasCalledWith("rm", "-rf", "/tmp/igor-bundle.Xy12Ab34Cd"))
+	})
+
+	t.Run("network unavailable", func(t *testing.T) {
+		mgr, mockExec := setupBundleTest()
+		mockExec.SetResponse("pacman", exec.FailureResult(1, "error: failed retrieving file 'core.db' from mirror\nerror: failed to synchronize all databases"))
+		err := mgr.DownloadBundle(ctx, "/srv/bundle", "nvidia")
+		assert.ErrorIs(t, err, pkg.ErrNetworkUnavailable)
+	})
+
+	t.Run("repo-add fails", func(t *testing.T) {
+		mgr, mockExec := setupBundleTest()
+		mockExec.SetResponse("find", exec.FailureResult(1, "==> ERROR: File 'nvidia.pkg.tar.zst' not found."))
+		err := mgr.DownloadBundle(ctx, "/srv/bundle", "nvidia")
+		assert.ErrorIs(t, err, pkg.ErrBundleFailed)
+	})
+}
+
+func TestManager_Repository_Restricted(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("install", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		require.NoError(t, mgr.Install(ctx, pkg.InstallOptions{Repository: "igor-bundle"}, "nvidia", "nvidia-utils"))
+		assert.Equal(t, []string{"-S", "--noconfirm", "igor-bundle/nvidia", "igor-bundle/nvidia-utils"}, mockExec.LastCall().Args)
+	})
+
+	t.Run("other repositories unreachable", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		mockExec.SetResponse("pacman", exec.FailureResult(1, "error: failed retrieving file 'core.db' from mirror\nerror: failed to synchronize all databases"))
+		assert.NoError(t, mgr.Update(ctx, pkg.UpdateOptions{Repository: "igor-bundle"}))
+	})
+
+	t.Run("bundle unreachable", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		mockExec.SetResponse("pacman", exec.FailureResult(1, "error: failed retrieving file 'igor-bundle.db' from disk"))
+		assert.Error(t, mgr.Update(ctx, pkg.UpdateOptions{Repository: "igor-bundle"}))
+	})
+}
+
+// =============================================================================
+// Simulation Tests
+// =============================================================================
+
+const testPacmanPrint = `:: nvidia-utils and nvidia-470xx-utils are in conflict. Remove nvidia-470xx-utils? [Y/n]
+extra nvidia-utils 550.120-1 52428800
+extra nvidia 550.120-1 41943040
+extra mesa 1:24.2.3-1 10485760
+extra libglvnd 1.7.0-1 1048576
+local nvidia-470xx-utils 470.256.02-1 209715200
+`
+
+// operationExecutor is a mock executor answering Execute according to the
+// command and its first argument, such as "pacman -Q".
+type operationExecutor struct {
+	*exec.MockExecutor
+	responses map[string]*exec.Result
+}
+
+func (e *operationExecutor) Execute(ctx context.Context, cmd string, args ...string) *exec.Result {
+	result := e.MockExecutor.Execute(ctx, cmd, args...)
+	if len(args) > 0 {
+		if r, ok := e.responses[cmd+" "+args[0]]; ok {
+			return r
+		}
+	}
+	return result
+}
+
+func TestParsePacmanPrint(t *testing.T) {
+	sim := parsePacmanPrint(testPacmanPrint)
+
+	assert.Equal(t, []pkg.PackageChange{
+		{Name: "nvidia-utils", Version: "550.120-1", Size: 52428800},
+		{Name: "nvidia", Version: "550.120-1", Size: 41943040},
+		{Name: "mesa", Version: "1:24.2.3-1", Size: 10485760},
+		{Name: "libglvnd", Version: "1.7.0-1", Size: 1048576},
+	}, sim.Install)
+	assert.Equal(t, []pkg.PackageChange{
+		{Name: "nvidia-470xx-utils", Version: "470.256.02-1", InstalledSize: 209715200},
+	}, sim.Remove)
+	assert.Equal(t, int64(52428800+41943040+10485760+1048576), sim.DownloadSize)
+	assert.True(t, parsePacmanPrint("").IsEmpty())
+}
+
+const testPacmanSiSizes = `Repository      : extra
+Name            : nvidia
+Version         : 550.120-1
+Installed Size  : 100.00 MiB
+
+Repository      : extra
+Name            : mesa
+Version         : 1:24.2.3-1
+Installed Size  : 30.00 MiB
+
+Repository      : testing
+Name            : mesa
+Version         : 1:24.3.0-1
+Installed Size  : 31.00 MiB
+
+Repository      : extra
+Name            : libglvnd
+Version         : 1.7.0-1
+Installed Size  : 2.00 MiB
+`
+
+func TestParsePacmanInstalledSizes(t *testing.T) {
+	assert.Equal(t, map[string]int64{
+		"nvidia":   100 << 20,
+		"mesa":     30 << 20,
+		"libglvnd": 2 << 20,
+	}, parsePacmanInstalledSizes(testPacmanSiSizes))
+	assert.Empty(t, parsePacmanInstalledSizes(""))
+}
+
+func TestManager_SimulateInstall(t *testing.T) {
+	mockExec := exec.NewMockExecutor()
+	executor := &operationExecutor{
+		MockExecutor: mockExec,
+		responses: map[string]*exec.Result{
+			"pacman -S":         exec.SuccessResult(testPacmanPrint),
+			"pacman -Q":         exec.FailureResult(1, "error: package 'nvidia' was not found"),
+			"vercmp 1:24.2.3-1": exec.SuccessResult("1\n"),
+			"vercmp 1.7.0-1":    exec.SuccessResult("-1\n"),
+			"pacman -Si":        exec.SuccessResult(testPacmanSiSizes),
+			"pacman -Qi":        exec.SuccessResult("Name            : mesa\nInstalled Size  : 29.00 MiB\n\nName            : libglvnd\nInstalled Size  : 2.00 MiB\n"),
+		},
+	}
+	executor.responses["pacman -Q"].Stdout = []byte("nvidia-utils 550.120-1\nmesa 1:24.2.2-1\nlibglvnd 1:1.7.0-2\n")
+	priv := privilege.NewManager()
+	priv.SetRoot(true)
+	mgr := NewManager(executor, priv)
+
+	sim, err := mgr.SimulateInstall(context.Background(), pkg.NonInteractiveInstallOptions(), "nvidia", "nvidia-utils")
+	require.NoError(t, err)
+
+	calls := mockExec.Calls()
+	require.NotEmpty(t, calls)
+	assert.Equal(t, []string{"-S", "-p", "--print-format", "%r %n %v %s", "--ask", "6", "--noconfirm", "nvidia", "nvidia-utils"}, calls[0].Args)
+	assert.False(t, calls[0].Elevated)
+
+	// nvidia-utils is installed at the same version and is only reinstalled
+	assert.Equal(t, []pkg.PackageChange{{Name: "nvidia", Version: "550.120-1", Size: 41943040, InstalledSize: 100 << 20}}, sim.Install)
+	assert.Equal(t, []pkg.PackageChange{{Name: "mesa", Version: "1:24.2.3-1", OldVersion: "1:24.2.2-1", Size: 10485760, InstalledSize: 30 << 20}}, sim.Upgrade)
+	assert.Equal(t, []pkg.PackageChange{{Name: "libglvnd", Version: "1.7.0-1", OldVersion: "1:1.7.0-2", Size: 1048576, InstalledSize: 2 << 20}}, sim.Downgrade)
+	assert.Len(t, sim.Remove, 1)
+
+	// New and upgraded sizes, less the replaced versions and the removal
+	assert.Equal(t, int64((100+30+2-29-2-200)<<20), sim.InstallSize)
+}
+
+func TestManager_SimulateInstall_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("no packages", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		sim, err := mgr.SimulateInstall(ctx, pkg.NonInteractiveInstallOptions())
+		require.NoError(t, err)
+		assert.True(t, sim.IsEmpty())
+		assert.Equal(t, 0, mockExec.CallCount())
+	})
+
+	t.Run("target not found", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		mockExec.SetResponse("pacman", exec.FailureResult(1, "error: target not found: nvidia-999"))
+		_, err := mgr.SimulateInstall(ctx, pkg.NonInteractiveInstallOptions(), "nvidia-999")
+		assert.ErrorIs(t, err, pkg.ErrPackageNotFound)
+	})
+
+	t.Run("unresolvable conflicts", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		mockExec.SetResponse("pacman", exec.FailureResult(1, "error: unresolvable package conflicts detected"))
+		_, err := mgr.SimulateInstall(ctx, pkg.NonInteractiveInstallOptions(), "nvidia")
+		assert.ErrorIs(t, err, pkg.ErrSimulationFailed)
+	})
+}
+
+// =============================================================================
+// Progress Tests
+// =============================================================================
+
+func TestManager_Install_Progress(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("pacman", exec.SuccessResult(`resolving dependencies...
+looking for conflicting packages...
+
+Packages (3) egl-wayland-4:1.1.13-1  nvidia-550.54.14-1  nvidia-utils-550.54.14-1
+
+Total Download Size:    64.20 MiB
+Total Installed Size:  241.32 MiB
+
+:: Proceed with installation? [Y/n]
+:: Retrieving packages...
+ nvidia-utils-550.54.14-1-x86_64 downloading...
+ nvidia-550.54.14-1-x86_64 downloading...
+(3/3) checking keys in keyring
+(3/3) checking package integrity
+:: Processing package changes...
+(1/3) installing egl-wayland
+(2/3) installing nvidia-utils
+(3/3) upgrading nvidia
+:: Running post-transaction hooks...
+(1/2) Arming ConditionNeedsUpdate...
+`))
+
+	var reported []pkg.Progress
+	opts := pkg.NonInteractiveInstallOptions()
+	opts.Progress = func(p pkg.Progress) { reported = append(reported, p) }
+	require.NoError(t, mgr.Install(context.Background(), opts, "nvidia"))
+
+	call := mockExec.LastCall()
+	assert.True(t, call.Elevated)
+	assert.Equal(t, "pacman", call.Command)
+	assert.Equal(t, []pkg.Progress{
+		{Phase: pkg.ProgressDownload, Package: "nvidia-utils", Percent: pkg.StepPercent(1, 3)},
+		{Phase: pkg.ProgressDownload, Package: "nvidia", Percent: pkg.StepPercent(2, 3)},
+		{Phase: pkg.ProgressInstall, Package: "egl-wayland", Percent: pkg.StepPercent(1, 3)},
+		{Phase: pkg.ProgressInstall, Package: "nvidia-utils", Percent: pkg.StepPercent(2, 3)},
+		{Phase: pkg.ProgressInstall, Package: "nvidia", Percent: 100},
+	}, reported)
+}
+
+func TestManager_Install_ProgressFailure(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("pacman", exec.FailureResult(1, "error: target not found: nvidia-999"))
+
+	opts := pkg.NonInteractiveInstallOptions()
+	opts.Progress = func(pkg.Progress) {}
+	err := mgr.Install(context.Background(), opts, "nvidia-999")
+	assert.ErrorIs(t, err, pkg.ErrPackageNotFound)
+}
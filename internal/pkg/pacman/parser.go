@@ -4,6 +4,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/tungetti/igor/internal/pkg"
 )
@@ -131,6 +132,30 @@ func parsePacmanInfo(output string) (*pkg.Package, error) {
 	return p, nil
 }
 
+// parsePacmanInstalledSizes parses the package stanzas printed by pacman -Si
+// or -Qi and returns the installed size of each package in bytes, keyed by
+// name. Only the first stanza of a package is used, as pacman -Si prints
+// one per repository providing it, in the order of precedence.
+func parsePacmanInstalledSizes(output string) map[string]int64 {
+	sizes := make(map[string]int64)
+	var name string
+	for _, line := range strings.Split(output, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found || strings.HasPrefix(line, " ") {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "Name":
+			name = strings.TrimSpace(value)
+		case "Installed Size":
+			if _, seen := sizes[name]; name != "" && !seen {
+				sizes[name] = parseSize(value)
+			}
+		}
+	}
+	return sizes
+}
+
 // parsePacmanSs parses pacman -Ss output (search results).
 // Format:
 // repo/package-name version [installed]
@@ -359,3 +384,305 @@ func sanitizeRepoName(name string) string {
 
 	return name
 }
+
+// parseIgnorePkg returns the packages listed in the IgnorePkg options of the
+// [options] section of pacman.conf.
+func parseIgnorePkg(content string) []string {
+	ignored := make([]string, 0)
+	inOptions := false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inOptions = line == "[options]"
+			continue
+		}
+		if !inOptions {
+			continue
+		}
+		if values, ok := splitIgnorePkgLine(line); ok {
+			for _, name := range values {
+				if !containsString(ignored, name) {
+					ignored = append(ignored, name)
+				}
+			}
+		}
+	}
+	return ignored
+}
+
+// setIgnorePkg adds and removes packages in the IgnorePkg option of the
+// [options] section of pacman.conf. Packages are added to the first IgnorePkg
+// line, or to a new one after the [options] header; lines left empty are
+// removed. Returns the new content and whether it changed.
+func setIgnorePkg(content string, add, remove []string) (string, bool) {
+	existing := parseIgnorePkg(content)
+	var toAdd []string
+	for _, name := range add {
+		if !containsString(existing, name) && !containsString(toAdd, name) {
+			toAdd = append(toAdd, name)
+		}
+	}
+
+	lines := strings.Split(content, "\n")
+	out := make([]string, 0, len(lines)+2)
+	changed := false
+	inOptions, optionsFound := false, false
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			inOptions = trimmed == "[options]"
+			out = append(out, line)
+			if inOptions && !optionsFound {
+				optionsFound = true
+				if len(toAdd) > 0 && !hasIgnorePkgLine(content) {
+					out = append(out, "IgnorePkg = "+strings.Join(toAdd, " "))
+					toAdd = nil
+					changed = true
+				}
+			}
+			continue
+		}
+
+		values, ok := splitIgnorePkgLine(trimmed)
+		if !inOptions || !ok {
+			out = append(out, line)
+			continue
+		}
+
+		lineChanged := len(toAdd) > 0
+		kept := make([]string, 0, len(values)+len(toAdd))
+		for _, name := range values {
+			if containsString(remove, name) {
+				lineChanged = true
+				continue
+			}
+			kept = append(kept, name)
+		}
+		kept = append(kept, toAdd...)
+		toAdd = nil
+
+		if lineChanged {
+			changed = true
+			if len(kept) == 0 {
+				continue
+			}
+			line = "IgnorePkg = " + strings.Join(kept, " ")
+		}
+		out = append(out, line)
+	}
+
+	if len(toAdd) > 0 {
+		// No [options] section: pacman.conf always has one, but add it
+		// rather than dropping the hold
+		out = append([]string{"[options]", "IgnorePkg = " + strings.Join(toAdd, " "), ""}, out...)
+		changed = true
+	}
+
+	if !changed {
+		return content, false
+	}
+	return strings.Join(out, "\n"), true
+}
+
+// hasIgnorePkgLine reports whether the [options] section of pacman.conf has
+// an uncommented IgnorePkg line.
+func hasIgnorePkgLine(content string) bool {
+	inOptions := false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inOptions = line == "[options]"
+			continue
+		}
+		if _, ok := splitIgnorePkgLine(line); ok && inOptions {
+			return true
+		}
+	}
+	return false
+}
+
+// splitIgnorePkgLine returns the packages of an uncommented IgnorePkg line.
+func splitIgnorePkgLine(line string) ([]string, bool) {
+	eqIdx := strings.Index(line, "=")
+	if eqIdx == -1 || strings.HasPrefix(line, "#") {
+		return nil, false
+	}
+	if strings.TrimSpace(line[:eqIdx]) != "IgnorePkg" {
+		return nil, false
+	}
+	return strings.Fields(line[eqIdx+1:]), true
+}
+
+// containsString reports whether list includes s.
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// pacmanLogOperations maps the package actions logged by libalpm to history
+// operations.
+var pacmanLogOperations = map[string]string{
+	"installed":   pkg.HistoryInstall,
+	"upgraded":    pkg.HistoryUpgrade,
+	"downgraded":  pkg.HistoryDowngrade,
+	"reinstalled": pkg.HistoryReinstall,
+	"removed":     pkg.HistoryRemove,
+}
+
+// pacmanLogTimeLayouts are the formats of the timestamps of the pacman log,
+// current and before pacman 5.1.
+var pacmanLogTimeLayouts = []string{"2006-01-02T15:04:05-0700", "2006-01-02 15:04"}
+
+// parsePacmanLog parses the pacman log into history entries, in log order.
+// A transaction runs from "[ALPM] transaction started" to "[ALPM]
+// transaction completed"; transactions that end otherwise, or never end, are
+// failed. The command line is taken from the last "[PACMAN] Running" line
+// before the transaction. IDs are not set.
+//
+// Example lines:
+//
+//	[2024-01-15T10:23:45+0100] [PACMAN] Running 'pacman -S nvidia'
+//	[2024-01-15T10:23:46+0100] [ALPM] transaction started
+//	[2024-01-15T10:23:50+0100] [ALPM] upgraded nvidia-utils (550.40.07-1 -> 550.54.14-1)
+//	[2024-01-15T10:23:51+0100] [ALPM] transaction completed
+func parsePacmanLog(content string) []pkg.HistoryEntry {
+	entries := make([]pkg.HistoryEntry, 0)
+	var current *pkg.HistoryEntry
+	command := ""
+
+	for _, line := range strings.Split(content, "\n") {
+		timestamp, source, message, ok := splitPacmanLogLine(line)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case source == "PACMAN" && strings.HasPrefix(message, "Running "):
+			command = strings.Trim(strings.TrimPrefix(message, "Running "), "'")
+
+		case source != "ALPM":
+			continue
+
+		case message == "transaction started":
+			entries = append(entries, pkg.HistoryEntry{
+				Timestamp: parsePacmanLogTime(timestamp),
+				Details:   command,
+			})
+			current = &entries[len(entries)-1]
+			command = ""
+
+		case strings.HasPrefix(message, "transaction "):
+			if current != nil {
+				current.Success = message == "transaction completed"
+				current = nil
+			}
+
+		case current != nil:
+			action, rest, _ := strings.Cut(message, " ")
+			if operation, ok := pacmanLogOperations[action]; ok {
+				name, _, _ := strings.Cut(rest, " ")
+				current.AddOperation(operation)
+				current.AddPackage(name)
+			}
+		}
+	}
+
+	return entries
+}
+
+// splitPacmanLogLine splits a line of the pacman log into its timestamp,
+// source and message.
+func splitPacmanLogLine(line string) (string, string, string, bool) {
+	if !strings.HasPrefix(line, "[") {
+		return "", "", "", false
+	}
+	timestamp, rest, ok := strings.Cut(line[1:], "] [")
+	if !ok {
+		return "", "", "", false
+	}
+	source, message, ok := strings.Cut(rest, "] ")
+	if !ok {
+		return "", "", "", false
+	}
+	return timestamp, source, strings.TrimSpace(message), true
+}
+
+// parsePacmanLogTime parses a timestamp of the pacman log. Timestamps
+// without a time zone are in the local time zone. It returns 0 if the
+// timestamp cannot be parsed.
+func parsePacmanLogTime(value string) int64 {
+	for _, layout := range pacmanLogTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t.Unix()
+		}
+	}
+	return 0
+}
+
+// findCachedPackages returns the package files, among the files of the
+// package cache, holding the given packages at their version. Cached files
+// are named name-version-arch.pkg.tar.*, with detached signatures next to
+// them. Returns the names of the packages that are not in the cache.
+func findCachedPackages(files []string, packages []pkg.Package) ([]string, []string) {
+	var found, missing []string
+	for _, p := range packages {
+		prefix := p.Name + "-" + p.Version + "-"
+		path := ""
+		for _, file := range files {
+			base := file[strings.LastIndex(file, "/")+1:]
+			if !strings.HasPrefix(base, prefix) || strings.HasSuffix(base, ".sig") {
+				continue
+			}
+			arch := strings.TrimPrefix(base, prefix)
+			if i := strings.Index(arch, ".pkg.tar"); i > 0 && !strings.Contains(arch[:i], "-") {
+				path = file
+				break
+			}
+		}
+
+		if path == "" {
+			missing = append(missing, p.Name)
+		} else {
+			found = append(found, path)
+		}
+	}
+	return found, missing
+}
+
+// pacmanPrintFormat is the --print-format of simulated transactions: the
+// repository, name, version and size of each target. Removed packages come
+// from the "local" repository, and their size is the installed size.
+const pacmanPrintFormat = "%r %n %v %s"
+
+// parsePacmanPrint parses the targets printed by pacman -Sp with
+// pacmanPrintFormat into a simulation. Targets from the local repository are
+// removed; the others are reported as installed, to be told apart from
+// upgrades and downgrades by the caller.
+func parsePacmanPrint(output string) *pkg.Simulation {
+	sim := pkg.NewSimulation()
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 4 || fields[0] == "::" {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		if fields[0] == "local" {
+			sim.Remove = append(sim.Remove, pkg.PackageChange{Name: fields[1], Version: fields[2], InstalledSize: size})
+			continue
+		}
+		sim.Install = append(sim.Install, pkg.PackageChange{Name: fields[1], Version: fields[2], Size: size})
+		sim.DownloadSize += size
+	}
+
+	return sim
+}
@@ -0,0 +1,77 @@
+package pacman
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+
+	igorexec "github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+var (
+	// packagesLine matches the list of the packages of the transaction:
+	// "Packages (3) egl-wayland-4:1.1.13-1  nvidia-550.54.14-1  nvidia-utils-550.54.14-1"
+	packagesLine = regexp.MustCompile(`^Packages \((\d+)\)`)
+
+	// downloadLine matches the line pacman prints when it starts to download
+	// a package and its output is not a terminal:
+	// "nvidia-utils-550.54.14-1-x86_64 downloading..."
+	downloadLine = regexp.MustCompile(`^(\S+) downloading\.\.\.$`)
+
+	// transactionLine matches the lines of the transaction:
+	// "(2/3) installing nvidia-utils"
+	transactionLine = regexp.MustCompile(`^\((\d+)/(\d+)\) (installing|upgrading|reinstalling|downgrading) (\S+)`)
+)
+
+// executeElevatedWithProgress runs pacman with root privileges, with its
+// output parsed into progress passed to report.
+func (m *Manager) executeElevatedWithProgress(ctx context.Context, report func(pkg.Progress), args ...string) *igorexec.Result {
+	w := pkg.NewProgressWriter(newProgressParser(), report)
+	result := m.executor.StreamElevated(ctx, w, nil, "pacman", args...)
+	w.Flush()
+	return result
+}
+
+// newProgressParser returns a parser of the output of pacman -S. Download
+// lines are not numbered, so their progress is counted against the number
+// of packages of the transaction; packages already in the cache are not
+// downloaded, so the download may end before 100%.
+func newProgressParser() pkg.ProgressParser {
+	var total, downloaded int
+	return func(line string) (pkg.Progress, bool) {
+		if match := packagesLine.FindStringSubmatch(line); match != nil {
+			total, _ = strconv.Atoi(match[1])
+			return pkg.Progress{}, false
+		}
+		if match := downloadLine.FindStringSubmatch(line); match != nil {
+			downloaded++
+			return pkg.Progress{
+				Phase:   pkg.ProgressDownload,
+				Package: packageFileName(match[1]),
+				Percent: pkg.StepPercent(downloaded, total),
+			}, true
+		}
+		if match := transactionLine.FindStringSubmatch(line); match != nil {
+			done, _ := strconv.Atoi(match[1])
+			count, _ := strconv.Atoi(match[2])
+			return pkg.Progress{
+				Phase:   pkg.ProgressInstall,
+				Package: match[4],
+				Percent: pkg.StepPercent(done, count),
+			}, true
+		}
+		return pkg.Progress{}, false
+	}
+}
+
+// packageFileName returns the package name of a name-version-release-arch
+// string, as in the names of the package files, or s if it has no version.
+func packageFileName(s string) string {
+	parts := strings.Split(s, "-")
+	if len(parts) < 4 {
+		return s
+	}
+	return strings.Join(parts[:len(parts)-3], "-")
+}
@@ -0,0 +1,140 @@
+package pacman
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// SimulateInstall resolves the installation of the packages with
+// pacman -Sp, which needs no root privileges. Conflicting and replaced
+// packages are accepted for removal (--ask 6), as the user would be asked
+// to, so they show up among the targets. The installed versions of the
+// targets tell new packages from upgrades and downgrades. pacman -Sp does
+// not print installed sizes, so they are read from pacman -Si for the
+// targets and pacman -Qi for the versions they replace.
+func (m *Manager) SimulateInstall(ctx context.Context, opts pkg.InstallOptions, packages ...string) (*pkg.Simulation, error) {
+	if len(packages) == 0 {
+		return pkg.NewSimulation(), nil
+	}
+
+	installArgs := m.buildInstallArgs(opts, packages)
+	args := append([]string{"-S", "-p", "--print-format", pacmanPrintFormat, "--ask", "6"}, installArgs[1:]...)
+	result := m.executor.Execute(ctx, "pacman", args...)
+	if result.Failed() {
+		combined := result.StderrString() + result.StdoutString()
+		if strings.Contains(combined, "target not found") {
+			return nil, pkg.Wrap(pkg.ErrPackageNotFound, fmt.Errorf("pacman -Sp failed: %s", combined))
+		}
+		return nil, pkg.Wrap(pkg.ErrSimulationFailed, fmt.Errorf("pacman -Sp failed (exit code %d): %s", result.ExitCode, combined))
+	}
+
+	sim := parsePacmanPrint(result.StdoutString())
+	m.classifySimulatedTargets(ctx, sim)
+	m.addSimulationSizes(ctx, sim)
+	return sim, nil
+}
+
+// addSimulationSizes fills in the installed sizes of a simulation. The
+// installed size change is left unknown if the sizes of the targets cannot
+// be read.
+func (m *Manager) addSimulationSizes(ctx context.Context, sim *pkg.Simulation) {
+	var targets, replaced []string
+	for _, changes := range [][]pkg.PackageChange{sim.Install, sim.Upgrade, sim.Downgrade} {
+		for _, change := range changes {
+			targets = append(targets, change.Name)
+		}
+	}
+	for _, changes := range [][]pkg.PackageChange{sim.Upgrade, sim.Downgrade} {
+		for _, change := range changes {
+			replaced = append(replaced, change.Name)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	result := m.executor.Execute(ctx, "pacman", append([]string{"-Si"}, targets...)...)
+	sizes := parsePacmanInstalledSizes(result.StdoutString())
+	if len(sizes) == 0 {
+		return
+	}
+	for _, changes := range [][]pkg.PackageChange{sim.Install, sim.Upgrade, sim.Downgrade} {
+		for i := range changes {
+			changes[i].InstalledSize = sizes[changes[i].Name]
+			sim.InstallSize += changes[i].InstalledSize
+		}
+	}
+
+	if len(replaced) > 0 {
+		result = m.executor.Execute(ctx, "pacman", append([]string{"-Qi"}, replaced...)...)
+		for _, size := range parsePacmanInstalledSizes(result.StdoutString()) {
+			sim.InstallSize -= size
+		}
+	}
+	for _, change := range sim.Remove {
+		sim.InstallSize -= change.InstalledSize
+	}
+}
+
+// classifySimulatedTargets moves the targets of a simulation that are
+// installed already to the upgrades or downgrades, comparing versions with
+// vercmp. Targets whose installed version cannot be read are left as new
+// installations.
+func (m *Manager) classifySimulatedTargets(ctx context.Context, sim *pkg.Simulation) {
+	if len(sim.Install) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(sim.Install))
+	for _, change := range sim.Install {
+		names = append(names, change.Name)
+	}
+
+	// pacman -Q fails if a package is not installed, but still prints the others
+	result := m.executor.Execute(ctx, "pacman", append([]string{"-Q"}, names...)...)
+	installed, _ := parsePacmanQ(result.StdoutString())
+	versions := make(map[string]string, len(installed))
+	for _, p := range installed {
+		versions[p.Name] = p.Version
+	}
+
+	targets := sim.Install
+	sim.Install = make([]pkg.PackageChange, 0, len(targets))
+	for _, change := range targets {
+		change.OldVersion = versions[change.Name]
+		switch {
+		case change.OldVersion == "":
+			sim.Install = append(sim.Install, change)
+		case change.OldVersion == change.Version:
+			// Reinstalls change nothing
+		case m.vercmp(ctx, change.Version, change.OldVersion) < 0:
+			sim.Downgrade = append(sim.Downgrade, change)
+		default:
+			sim.Upgrade = append(sim.Upgrade, change)
+		}
+	}
+}
+
+// vercmp compares two package versions with vercmp, returning a negative
+// number if a is older than b, 0 if they are equal and a positive number if
+// a is newer. Versions that cannot be compared are reported as newer.
+func (m *Manager) vercmp(ctx context.Context, a, b string) int {
+	result := m.executor.Execute(ctx, "vercmp", a, b)
+	if result.Failed() {
+		return 1
+	}
+	switch out := strings.TrimSpace(result.StdoutString()); {
+	case strings.HasPrefix(out, "-"):
+		return -1
+	case out == "0":
+		return 0
+	default:
+		return 1
+	}
+}
+
+// Ensure Manager implements pkg.SimulationManager interface.
+var _ pkg.SimulationManager = (*Manager)(nil)
@@ -0,0 +1,82 @@
+package pacman
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// pacmanCacheDir is the pacman package cache, which keeps the package files
+// of installed and previously installed versions.
+const pacmanCacheDir = "/var/cache/pacman/pkg"
+
+// BeginTransaction starts a transaction.
+// pacman has no transactions spanning several calls, so they are emulated:
+// the installed packages and their versions are recorded when the
+// transaction begins, Install and Remove run right away, and a rollback
+// restores the recorded packages.
+func (m *Manager) BeginTransaction(ctx context.Context) error {
+	installed, err := m.ListInstalled(ctx)
+	if err != nil {
+		return pkg.Wrap(pkg.ErrTransactionFailed, fmt.Errorf("failed to record the installed packages: %w", err))
+	}
+	return m.tx.Begin(pkg.InstalledVersions(installed))
+}
+
+// CommitTransaction keeps the changes made during the transaction.
+func (m *Manager) CommitTransaction(ctx context.Context) error {
+	_, err := m.tx.End()
+	return err
+}
+
+// RollbackTransaction reverts the changes made during the transaction.
+// Packages that were removed or changed version are installed again at
+// their recorded version with pacman -U, from the package files in
+// /var/cache/pacman/pkg, then the packages that were added are removed.
+// Nothing is changed if a recorded version is missing from the cache.
+func (m *Manager) RollbackTransaction(ctx context.Context) error {
+	state, err := m.tx.End()
+	if err != nil {
+		return err
+	}
+
+	installed, err := m.ListInstalled(ctx)
+	if err != nil {
+		return pkg.Wrap(pkg.ErrTransactionFailed, fmt.Errorf("failed to list the installed packages: %w", err))
+	}
+	remove, restore := pkg.RevertPlan(state.Snapshot, pkg.InstalledVersions(installed))
+
+	if len(restore) > 0 {
+		result := m.executor.Execute(ctx, "find", pacmanCacheDir, "-maxdepth", "1", "-name", "*.pkg.tar.*")
+		if result.Failed() {
+			return pkg.Wrap(pkg.ErrTransactionFailed, fmt.Errorf("failed to list %s: %s", pacmanCacheDir, result.StderrString()))
+		}
+
+		files, missing := findCachedPackages(strings.Fields(result.StdoutString()), restore)
+		if len(missing) > 0 {
+			return pkg.Wrap(pkg.ErrTransactionFailed, fmt.Errorf("previous versions of %s are not in %s", strings.Join(missing, ", "), pacmanCacheDir))
+		}
+
+		args := append([]string{"-U", "--noconfirm"}, files...)
+		result = m.executor.ExecuteElevated(ctx, "pacman", args...)
+		if result.Failed() {
+			return pkg.Wrap(pkg.ErrTransactionFailed, fmt.Errorf("failed to restore packages: %s", result.StderrString()))
+		}
+	}
+
+	if err := m.Remove(ctx, pkg.RemoveOptions{NoConfirm: true}, remove...); err != nil {
+		return pkg.Wrap(pkg.ErrTransactionFailed, fmt.Errorf("failed to remove added packages: %w", err))
+	}
+
+	return nil
+}
+
+// InTransaction returns true if a transaction is in progress.
+func (m *Manager) InTransaction() bool {
+	return m.tx.Active()
+}
+
+// Ensure Manager implements pkg.TransactionalManager interface.
+var _ pkg.TransactionalManager = (*Manager)(nil)
@@ -0,0 +1,138 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ProgressPhase is the phase of a package installation.
+type ProgressPhase string
+
+// Phases of a package installation.
+const (
+	// ProgressDownload is the download of the packages.
+	ProgressDownload ProgressPhase = "download"
+	// ProgressInstall is the unpacking and configuration of the packages.
+	ProgressInstall ProgressPhase = "install"
+)
+
+// Progress is the progress of a package installation, parsed from the
+// output of the package manager.
+type Progress struct {
+	// Phase is the phase of the installation.
+	Phase ProgressPhase
+
+	// Package is the name of the package being downloaded or installed,
+	// or empty if the package manager does not tell.
+	Package string
+
+	// Percent is the progress of the phase (0-100).
+	Percent float64
+}
+
+// String returns a human-readable representation of the progress, such as
+// "Installing nvidia-driver-550 (42%)".
+func (p Progress) String() string {
+	action := "Installing"
+	if p.Phase == ProgressDownload {
+		action = "Downloading"
+	}
+	if p.Package == "" {
+		return fmt.Sprintf("%s packages (%.0f%%)", action, p.Percent)
+	}
+	return fmt.Sprintf("%s %s (%.0f%%)", action, p.Package, p.Percent)
+}
+
+// ProgressParser parses a line of the output of a package manager. It
+// returns false for the lines that do not report progress. A parser may
+// keep state between lines, such as the number of packages to download.
+type ProgressParser func(line string) (Progress, bool)
+
+// ProgressWriter is an io.Writer passing the lines written to it to a
+// parser and the progress parsed to a callback. Lines end with a newline or
+// a carriage return, which progress bars use to redraw a line.
+type ProgressWriter struct {
+	mu     sync.Mutex
+	parse  ProgressParser
+	report func(Progress)
+	line   []byte
+}
+
+// NewProgressWriter creates a ProgressWriter.
+func NewProgressWriter(parse ProgressParser, report func(Progress)) *ProgressWriter {
+	return &ProgressWriter{
+		parse:  parse,
+		report: report,
+	}
+}
+
+// Write implements io.Writer. It never fails, so the output of the command
+// is never cut short by the progress reporting.
+func (w *ProgressWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, b := range p {
+		if b != '\n' && b != '\r' {
+			w.line = append(w.line, b)
+			continue
+		}
+		w.flushLine()
+	}
+	return len(p), nil
+}
+
+// Flush parses the last line if it did not end with a newline.
+func (w *ProgressWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flushLine()
+}
+
+// flushLine parses the buffered line.
+func (w *ProgressWriter) flushLine() {
+	line := strings.TrimSpace(string(w.line))
+	w.line = w.line[:0]
+	if line == "" {
+		return
+	}
+	if progress, ok := w.parse(line); ok {
+		w.report(progress)
+	}
+}
+
+// RPMPackageName returns the name of a package from its NEVRA, such as
+// "nvidia-driver-3:550.54.14-1.fc40.x86_64" or, as yum prints it,
+// "3:nvidia-driver-550.54.14-1.fc40.x86_64", or from the file name of an
+// RPM package, by removing the extension, the architecture, the version
+// and the release. Strings that are not a NEVRA are returned unchanged.
+func RPMPackageName(nevra string) string {
+	name := strings.TrimSuffix(nevra, ".rpm")
+	if i := strings.Index(name, ":"); i > 0 && !strings.Contains(name[:i], "-") {
+		name = name[i+1:]
+	}
+	if i := strings.LastIndex(name, "."); i > 0 {
+		name = name[:i]
+	}
+	for n := 0; n < 2; n++ {
+		i := strings.LastIndex(name, "-")
+		if i <= 0 {
+			return nevra
+		}
+		name = name[:i]
+	}
+	return name
+}
+
+// StepPercent returns the progress in percent of item done out of total,
+// as reported in "(3/12)" lines.
+func StepPercent(done, total int) float64 {
+	if total <= 0 {
+		return 0
+	}
+	if done > total {
+		done = total
+	}
+	return float64(done) / float64(total) * 100
+}
@@ -0,0 +1,74 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgress_String(t *testing.T) {
+	assert.Equal(t, "Installing nvidia-driver-550 (42%)",
+		Progress{Phase: ProgressInstall, Package: "nvidia-driver-550", Percent: 42.4}.String())
+	assert.Equal(t, "Downloading cuda-toolkit-12-4 (100%)",
+		Progress{Phase: ProgressDownload, Package: "cuda-toolkit-12-4", Percent: 100}.String())
+	assert.Equal(t, "Downloading packages (10%)",
+		Progress{Phase: ProgressDownload, Percent: 10}.String())
+}
+
+// testProgressParser reports the lines starting with "step " as progress
+// of the package named after it.
+func testProgressParser(line string) (Progress, bool) {
+	name, ok := strings.CutPrefix(line, "step ")
+	if !ok {
+		return Progress{}, false
+	}
+	return Progress{Phase: ProgressInstall, Package: name}, true
+}
+
+func TestProgressWriter(t *testing.T) {
+	var reported []string
+	w := NewProgressWriter(testProgressParser, func(p Progress) {
+		reported = append(reported, p.Package)
+	})
+
+	// Lines split across writes, ended with newlines or carriage returns
+	n, err := w.Write([]byte("Reading package lists...\nstep a\nst"))
+	assert.NoError(t, err)
+	assert.Equal(t, 34, n)
+	w.Write([]byte("ep b\rstep c\r\n\n"))
+	w.Write([]byte("step d"))
+	assert.Equal(t, []string{"a", "b", "c"}, reported)
+
+	// The last line is parsed once flushed
+	w.Flush()
+	assert.Equal(t, []string{"a", "b", "c", "d"}, reported)
+	w.Flush()
+	assert.Len(t, reported, 4)
+}
+
+func TestRPMPackageName(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"nvidia-driver-3:550.54.14-1.fc40.x86_64", "nvidia-driver"},
+		{"xorg-x11-drv-nvidia-cuda-libs-550.54.14-1.fc40.i686.rpm", "xorg-x11-drv-nvidia-cuda-libs"},
+		{"cuda-toolkit-12-4-12.4.1-1.x86_64", "cuda-toolkit-12-4"},
+		{"nvidia-gl-G06-550.54.14-lp155.1.x86_64.rpm", "nvidia-gl-G06"},
+		{"3:nvidia-driver-550.54.14-1.el9.x86_64", "nvidia-driver"},
+		{"kernel", "kernel"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.expected, RPMPackageName(tt.input))
+		})
+	}
+}
+
+func TestStepPercent(t *testing.T) {
+	assert.Equal(t, 25.0, StepPercent(1, 4))
+	assert.Equal(t, 100.0, StepPercent(5, 4))
+	assert.Equal(t, 0.0, StepPercent(1, 0))
+}
@@ -0,0 +1,220 @@
+package pkg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PackageChange is the change a simulated transaction makes to a package.
+type PackageChange struct {
+	// Name is the package name.
+	Name string `json:"name"`
+
+	// Version is the version installed by the transaction, or the version
+	// removed for removals. Empty if the package manager does not tell.
+	Version string `json:"version,omitempty"`
+
+	// OldVersion is the installed version replaced by an upgrade or a
+	// downgrade, if known.
+	OldVersion string `json:"old_version,omitempty"`
+
+	// Size is the download size of the package in bytes, or 0 if unknown.
+	Size int64 `json:"size,omitempty"`
+
+	// InstalledSize is the installed size in bytes of the version installed
+	// by the transaction, or of the version removed for removals; 0 if
+	// unknown.
+	InstalledSize int64 `json:"installed_size,omitempty"`
+
+	// ReplacedBy names the package obsoleting or conflicting with a removed
+	// package, if known.
+	ReplacedBy string `json:"replaced_by,omitempty"`
+
+	// Essential marks a package the package manager itself reports as
+	// essential to the system.
+	Essential bool `json:"essential,omitempty"`
+}
+
+// Simulation is the result of resolving a transaction without running it.
+type Simulation struct {
+	// Install lists the packages that would be newly installed.
+	Install []PackageChange `json:"install"`
+
+	// Upgrade lists the installed packages that would be upgraded.
+	Upgrade []PackageChange `json:"upgrade"`
+
+	// Remove lists the installed packages that would be removed, including
+	// the packages replaced by an installed package.
+	Remove []PackageChange `json:"remove"`
+
+	// Downgrade lists the installed packages that would be downgraded.
+	Downgrade []PackageChange `json:"downgrade"`
+
+	// DownloadSize is the total download size in bytes, or 0 if unknown.
+	DownloadSize int64 `json:"download_size"`
+
+	// InstallSize is the change of the installed size in bytes; it is
+	// negative when the transaction frees disk space, and 0 if unknown.
+	InstallSize int64 `json:"install_size"`
+}
+
+// NewSimulation returns an empty simulation.
+func NewSimulation() *Simulation {
+	return &Simulation{
+		Install:   make([]PackageChange, 0),
+		Upgrade:   make([]PackageChange, 0),
+		Remove:    make([]PackageChange, 0),
+		Downgrade: make([]PackageChange, 0),
+	}
+}
+
+// IsEmpty returns true if the transaction would change no package.
+func (s *Simulation) IsEmpty() bool {
+	return len(s.Install)+len(s.Upgrade)+len(s.Remove)+len(s.Downgrade) == 0
+}
+
+// EssentialRemovals returns the removed packages that are essential to the
+// system, either reported as such by the package manager or listed by
+// IsEssentialPackage.
+func (s *Simulation) EssentialRemovals() []PackageChange {
+	var essential []PackageChange
+	for _, change := range s.Remove {
+		if change.Essential || IsEssentialPackage(change.Name) {
+			essential = append(essential, change)
+		}
+	}
+	return essential
+}
+
+// Summary returns a one-line summary of the transaction, such as
+// "3 to install, 1 to remove, 120.4 MiB to download".
+func (s *Simulation) Summary() string {
+	if s.IsEmpty() {
+		return "no changes"
+	}
+
+	var parts []string
+	for _, count := range []struct {
+		n    int
+		verb string
+	}{
+		{len(s.Install), "install"},
+		{len(s.Upgrade), "upgrade"},
+		{len(s.Downgrade), "downgrade"},
+		{len(s.Remove), "remove"},
+	} {
+		if count.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d to %s", count.n, count.verb))
+		}
+	}
+	if s.DownloadSize > 0 {
+		parts = append(parts, FormatSize(s.DownloadSize)+" to download")
+	}
+	switch {
+	case s.InstallSize > 0:
+		parts = append(parts, FormatSize(s.InstallSize)+" of disk space used")
+	case s.InstallSize < 0:
+		parts = append(parts, FormatSize(-s.InstallSize)+" of disk space freed")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// essentialPackages are the packages without which the system may not
+// boot, log in or manage packages anymore.
+var essentialPackages = map[string]bool{
+	// C library and init system
+	"libc6": true, "glibc": true, "systemd": true, "systemd-sysv": true, "init": true, "udev": true,
+	// Shell, login and privilege escalation
+	"bash": true, "coreutils": true, "util-linux": true, "login": true, "sudo": true, "shadow": true, "passwd": true,
+	// Package management
+	"apt": true, "dpkg": true, "rpm": true, "dnf": true, "yum": true, "pacman": true, "zypper": true, "libzypp": true,
+	// Kernel and boot
+	"linux": true, "linux-lts": true, "linux-zen": true, "linux-hardened": true,
+	"kernel": true, "kernel-core": true, "kernel-default": true,
+	"grub2": true, "grub-pc": true, "grub-efi-amd64": true, "grub2-efi-x64": true, "shim": true, "shim-signed": true,
+	"dracut": true, "initramfs-tools": true, "mkinitcpio": true,
+	// Display server
+	"xserver-xorg-core": true, "xorg-x11-server-Xorg": true, "xorg-server": true, "xorg-x11-server": true,
+}
+
+// essentialPrefixes are name prefixes of essential packages, such as the
+// versioned kernel images of Debian and Ubuntu.
+var essentialPrefixes = []string{
+	"linux-image-",
+	"linux-generic",
+}
+
+// IsEssentialPackage reports whether removing the package may leave the
+// system unable to boot, log in or manage packages.
+func IsEssentialPackage(name string) bool {
+	if essentialPackages[name] {
+		return true
+	}
+	for _, prefix := range essentialPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseSize parses a size printed by a package manager, such as "512 B",
+// "1,234 kB", "40 k", "1.2 M" or "250.3 MiB", into bytes. Units ending in
+// "B" after a prefix (kB, MB, GB) are decimal, as printed by apt; the others
+// are binary. It returns false if s is not a size.
+func ParseSize(s string) (int64, bool) {
+	s = strings.TrimSpace(strings.ReplaceAll(s, ",", ""))
+	if s == "" {
+		return 0, false
+	}
+
+	end := 0
+	for end < len(s) && (s[end] == '.' || (s[end] >= '0' && s[end] <= '9')) {
+		end++
+	}
+	value, err := strconv.ParseFloat(s[:end], 64)
+	if err != nil {
+		return 0, false
+	}
+
+	var multiplier float64
+	switch strings.TrimSpace(s[end:]) {
+	case "", "B", "b":
+		multiplier = 1
+	case "k", "K", "KiB":
+		multiplier = 1 << 10
+	case "M", "MiB":
+		multiplier = 1 << 20
+	case "G", "GiB":
+		multiplier = 1 << 30
+	case "kB", "KB":
+		multiplier = 1e3
+	case "MB":
+		multiplier = 1e6
+	case "GB":
+		multiplier = 1e9
+	default:
+		return 0, false
+	}
+	return int64(value * multiplier), true
+}
+
+// FormatSize formats a size in bytes with binary units, such as "120.4 MiB".
+func FormatSize(size int64) string {
+	if size < 0 {
+		return "-" + FormatSize(-size)
+	}
+	if size < 1<<10 {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	value := float64(size)
+	for _, unit := range []string{"KiB", "MiB", "GiB"} {
+		value /= 1 << 10
+		if value < 1<<10 || unit == "GiB" {
+			return fmt.Sprintf("%.1f %s", value, unit)
+		}
+	}
+	return fmt.Sprintf("%d B", size)
+}
@@ -0,0 +1,93 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimulation_IsEmpty(t *testing.T) {
+	sim := NewSimulation()
+	assert.True(t, sim.IsEmpty())
+
+	sim.Remove = append(sim.Remove, PackageChange{Name: "xserver-xorg-video-nouveau"})
+	assert.False(t, sim.IsEmpty())
+}
+
+func TestSimulation_EssentialRemovals(t *testing.T) {
+	sim := NewSimulation()
+	sim.Remove = []PackageChange{
+		{Name: "xserver-xorg-video-nouveau"},
+		{Name: "linux-image-6.8.0-45-generic"},
+		{Name: "e2fsprogs", Essential: true},
+		{Name: "systemd"},
+	}
+
+	var names []string
+	for _, change := range sim.EssentialRemovals() {
+		names = append(names, change.Name)
+	}
+	assert.Equal(t, []string{"linux-image-6.8.0-45-generic", "e2fsprogs", "systemd"}, names)
+}
+
+func TestSimulation_Summary(t *testing.T) {
+	assert.Equal(t, "no changes", NewSimulation().Summary())
+
+	sim := NewSimulation()
+	sim.Install = make([]PackageChange, 3)
+	sim.Remove = make([]PackageChange, 1)
+	sim.DownloadSize = 120 << 20
+	sim.InstallSize = 400 << 20
+	assert.Equal(t, "3 to install, 1 to remove, 120.0 MiB to download, 400.0 MiB of disk space used", sim.Summary())
+
+	sim = NewSimulation()
+	sim.Downgrade = make([]PackageChange, 2)
+	sim.InstallSize = -(2 << 10)
+	assert.Equal(t, "2 to downgrade, 2.0 KiB of disk space freed", sim.Summary())
+}
+
+func TestIsEssentialPackage(t *testing.T) {
+	for _, name := range []string{"libc6", "glibc", "systemd", "kernel-core", "linux", "linux-image-generic", "xorg-x11-server-Xorg"} {
+		assert.True(t, IsEssentialPackage(name), name)
+	}
+	for _, name := range []string{"nvidia-driver-550", "mesa-libGL", "xserver-xorg-video-nouveau", "linux-firmware", ""} {
+		assert.False(t, IsEssentialPackage(name), name)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int64
+	}{
+		{"512 B", 512},
+		{"1,234 kB", 1234000},
+		{"12.5 MB", 12500000},
+		{"40 k", 40 << 10},
+		{"1.5 M", 3 << 19},
+		{"2 G", 2 << 30},
+		{"250.5 MiB", 262668288},
+		{"0 B", 0},
+		{"1024", 1024},
+	}
+	for _, tt := range tests {
+		got, ok := ParseSize(tt.input)
+		assert.True(t, ok, tt.input)
+		assert.Equal(t, tt.want, got, tt.input)
+	}
+
+	for _, input := range []string{"", "unknown", "12 parsecs", "MiB"} {
+		_, ok := ParseSize(input)
+		assert.False(t, ok, input)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	assert.Equal(t, "0 B", FormatSize(0))
+	assert.Equal(t, "1023 B", FormatSize(1023))
+	assert.Equal(t, "1.5 KiB", FormatSize(1536))
+	assert.Equal(t, "120.4 MiB", FormatSize(126248550))
+	assert.Equal(t, "2.0 GiB", FormatSize(2<<30))
+	assert.Equal(t, "2048.0 GiB", FormatSize(2<<40))
+	assert.Equal(t, "-1.0 KiB", FormatSize(-1024))
+}
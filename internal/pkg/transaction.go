@@ -0,0 +1,159 @@
+package pkg
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Transaction holds the state of a package transaction for the backends
+// implementing TransactionalManager. Backends with native transactions
+// queue the operations made during the transaction and run them together
+// on commit. The others run operations at once and keep a snapshot of the
+// installed packages, taken when the transaction begins, to revert to on
+// rollback. The zero value is an inactive transaction.
+type Transaction struct {
+	mu       sync.Mutex
+	active   bool
+	install  []string
+	remove   []string
+	opts     InstallOptions
+	snapshot map[string]string
+}
+
+// TransactionState is the state of a transaction when it ends.
+type TransactionState struct {
+	// Install lists the packages queued for installation, in order.
+	Install []string
+
+	// Remove lists the packages queued for removal, in order.
+	Remove []string
+
+	// Options combines the options of the queued installations.
+	Options InstallOptions
+
+	// Snapshot maps the packages installed when the transaction began to
+	// their versions, or is nil if no snapshot was taken.
+	Snapshot map[string]string
+}
+
+// Begin starts the transaction. snapshot maps the installed packages to
+// their versions, for backends that revert to it on rollback, and may be nil.
+// Returns ErrTransactionFailed if a transaction is already in progress.
+func (t *Transaction) Begin(snapshot map[string]string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.active {
+		return Wrap(ErrTransactionFailed, fmt.Errorf("a transaction is already in progress"))
+	}
+	t.active = true
+	t.install = nil
+	t.remove = nil
+	t.opts = InstallOptions{}
+	t.snapshot = snapshot
+	return nil
+}
+
+// Active returns true if the transaction is in progress.
+func (t *Transaction) Active() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.active
+}
+
+// QueueInstall queues packages for installation if the transaction is in
+// progress, and returns false otherwise. The flags of opts are combined
+// with those of the installations queued before, and a repository or a
+// progress callback set in opts replaces the one queued before.
+func (t *Transaction) QueueInstall(opts InstallOptions, packages ...string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.active {
+		return false
+	}
+	t.install = append(t.install, packages...)
+	t.opts.Force = t.opts.Force || opts.Force
+	t.opts.NoConfirm = t.opts.NoConfirm || opts.NoConfirm
+	t.opts.SkipVerify = t.opts.SkipVerify || opts.SkipVerify
+	t.opts.DownloadOnly = t.opts.DownloadOnly || opts.DownloadOnly
+	t.opts.Reinstall = t.opts.Reinstall || opts.Reinstall
+	t.opts.AllowDowngrade = t.opts.AllowDowngrade || opts.AllowDowngrade
+	if opts.Repository != "" {
+		t.opts.Repository = opts.Repository
+	}
+	if opts.Progress != nil {
+		t.opts.Progress = opts.Progress
+	}
+	return true
+}
+
+// QueueRemove queues packages for removal if the transaction is in
+// progress, and returns false otherwise.
+func (t *Transaction) QueueRemove(packages ...string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.active {
+		return false
+	}
+	t.remove = append(t.remove, packages...)
+	return true
+}
+
+// End ends the transaction and returns its state.
+// Returns ErrTransactionFailed if no transaction is in progress.
+func (t *Transaction) End() (TransactionState, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.active {
+		return TransactionState{}, Wrap(ErrTransactionFailed, fmt.Errorf("no transaction in progress"))
+	}
+
+	state := TransactionState{
+		Install:  t.install,
+		Remove:   t.remove,
+		Options:  t.opts,
+		Snapshot: t.snapshot,
+	}
+	t.active = false
+	t.install = nil
+	t.remove = nil
+	t.opts = InstallOptions{}
+	t.snapshot = nil
+	return state, nil
+}
+
+// InstalledVersions maps the names of packages to their versions.
+func InstalledVersions(packages []Package) map[string]string {
+	versions := make(map[string]string, len(packages))
+	for _, p := range packages {
+		versions[p.Name] = p.Version
+	}
+	return versions
+}
+
+// RevertPlan compares the installed packages before and after a transaction
+// and returns what reverts it: the packages that were added, to be removed,
+// and the packages that were removed or changed version, to be installed
+// again at their previous version. Both are sorted by name.
+func RevertPlan(before, after map[string]string) (remove []string, restore []Package) {
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			remove = append(remove, name)
+		}
+	}
+	for name, version := range before {
+		if current, ok := after[name]; !ok || current != version {
+			restore = append(restore, Package{Name: name, Version: version})
+		}
+	}
+
+	sort.Strings(remove)
+	sort.Slice(restore, func(i, j int) bool {
+		return restore[i].Name < restore[j].Name
+	})
+	return remove, restore
+}
@@ -0,0 +1,115 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransaction_Lifecycle(t *testing.T) {
+	var tx Transaction
+	assert.False(t, tx.Active())
+
+	require.NoError(t, tx.Begin(map[string]string{"a": "1"}))
+	assert.True(t, tx.Active())
+
+	err := tx.Begin(nil)
+	assert.ErrorIs(t, err, ErrTransactionFailed)
+	assert.Contains(t, err.Error(), "already in progress")
+
+	state, err := tx.End()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "1"}, state.Snapshot)
+	assert.False(t, tx.Active())
+
+	_, err = tx.End()
+	assert.ErrorIs(t, err, ErrTransactionFailed)
+	assert.Contains(t, err.Error(), "no transaction in progress")
+}
+
+func TestTransaction_Queue(t *testing.T) {
+	var tx Transaction
+
+	assert.False(t, tx.QueueInstall(InstallOptions{}, "a"))
+	assert.False(t, tx.QueueRemove("b"))
+
+	require.NoError(t, tx.Begin(nil))
+	assert.True(t, tx.QueueInstall(InstallOptions{NoConfirm: true}, "a", "b"))
+	assert.True(t, tx.QueueInstall(InstallOptions{Reinstall: true, Repository: "igor-bundle"}, "c"))
+	assert.True(t, tx.QueueRemove("d"))
+
+	state, err := tx.End()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, state.Install)
+	assert.Equal(t, []string{"d"}, state.Remove)
+	assert.Equal(t, InstallOptions{NoConfirm: true, Reinstall: true, Repository: "igor-bundle"}, state.Options)
+	assert.Nil(t, state.Snapshot)
+
+	// A new transaction starts empty
+	require.NoError(t, tx.Begin(nil))
+	state, err = tx.End()
+	require.NoError(t, err)
+	assert.Empty(t, state.Install)
+	assert.Empty(t, state.Remove)
+}
+
+func TestTransaction_QueueProgress(t *testing.T) {
+	var tx Transaction
+	var reported []Progress
+
+	require.NoError(t, tx.Begin(nil))
+	tx.QueueInstall(InstallOptions{Progress: func(p Progress) { reported = append(reported, p) }}, "a")
+	tx.QueueInstall(InstallOptions{}, "b")
+
+	state, err := tx.End()
+	require.NoError(t, err)
+	require.NotNil(t, state.Options.Progress)
+	state.Options.Progress(Progress{Phase: ProgressInstall, Package: "a", Percent: 50})
+	assert.Equal(t, []Progress{{Phase: ProgressInstall, Package: "a", Percent: 50}}, reported)
+}
+
+func TestInstalledVersions(t *testing.T) {
+	versions := InstalledVersions([]Package{
+		{Name: "nvidia-driver-550", Version: "550.54.14-0ubuntu1"},
+		{Name: "vim", Version: "2:9.1.0016-1ubuntu7"},
+	})
+
+	assert.Equal(t, map[string]string{
+		"nvidia-driver-550": "550.54.14-0ubuntu1",
+		"vim":               "2:9.1.0016-1ubuntu7",
+	}, versions)
+	assert.Empty(t, InstalledVersions(nil))
+}
+
+func TestRevertPlan(t *testing.T) {
+	before := map[string]string{
+		"vim":                  "9.1",
+		"xserver-xorg-nouveau": "1.0.17",
+		"libnvidia-common-535": "535.171",
+		"nvidia-kernel-common": "535.171",
+	}
+	after := map[string]string{
+		"vim":                  "9.1",
+		"libnvidia-common-535": "535.171",
+		"nvidia-kernel-common": "550.54",
+		"nvidia-driver-550":    "550.54",
+		"libnvidia-gl-550":     "550.54",
+	}
+
+	remove, restore := RevertPlan(before, after)
+
+	assert.Equal(t, []string{"libnvidia-gl-550", "nvidia-driver-550"}, remove)
+	assert.Equal(t, []Package{
+		{Name: "nvidia-kernel-common", Version: "535.171"},
+		{Name: "xserver-xorg-nouveau", Version: "1.0.17"},
+	}, restore)
+}
+
+func TestRevertPlan_NoChanges(t *testing.T) {
+	state := map[string]string{"vim": "9.1"}
+
+	remove, restore := RevertPlan(state, state)
+	assert.Empty(t, remove)
+	assert.Empty(t, restore)
+}
@@ -90,6 +90,10 @@ type Repository struct {
 
 	// Priority is the repository priority (lower = higher priority for most managers).
 	Priority int
+
+	// Trusted marks a repository whose packages are installed without
+	// signature verification, such as a local bundle directory.
+	Trusted bool
 }
 
 // String returns a human-readable representation of the repository.
@@ -131,6 +135,15 @@ type InstallOptions struct {
 
 	// AllowDowngrade allows installing an older version of the package.
 	AllowDowngrade bool
+
+	// Repository restricts package resolution to the named repository.
+	// When empty, all enabled repositories are used.
+	Repository string
+
+	// Progress receives the progress of the installation, parsed from the
+	// output of the package manager as it runs. When nil, the output is
+	// only collected once the package manager exits.
+	Progress func(Progress)
 }
 
 // DefaultInstallOptions returns the default installation options.
@@ -165,6 +178,10 @@ type UpdateOptions struct {
 
 	// ForceRefresh forces a refresh even if the cache is fresh.
 	ForceRefresh bool
+
+	// Repository restricts the refresh to the named repository.
+	// When empty, all enabled repositories are refreshed.
+	Repository string
 }
 
 // DefaultUpdateOptions returns the default update options.
@@ -0,0 +1,69 @@
+package yum
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// History returns the YUM transaction history, newest first.
+// Transactions are listed with yum history list all, since yum history list
+// only lists the last 20, and their details are read with a single yum
+// history info call for the listed IDs.
+func (m *Manager) History(ctx context.Context, limit int) ([]pkg.HistoryEntry, error) {
+	result := m.executor.Execute(ctx, "yum", "history", "list", "all")
+	if result.Failed() {
+		return nil, fmt.Errorf("yum history list failed: %s", result.StderrString())
+	}
+
+	ids := parseHistoryIDs(result.StdoutString())
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+	if len(ids) == 0 {
+		return []pkg.HistoryEntry{}, nil
+	}
+
+	args := append([]string{"history", "info"}, ids...)
+	result = m.executor.Execute(ctx, "yum", args...)
+	if result.Failed() {
+		return nil, fmt.Errorf("yum history info failed: %s", result.StderrString())
+	}
+
+	entries := parseHistoryInfo(result.StdoutString())
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, _ := strconv.Atoi(entries[i].ID)
+		b, _ := strconv.Atoi(entries[j].ID)
+		return a > b
+	})
+	return entries, nil
+}
+
+// Undo undoes a transaction with yum history undo.
+func (m *Manager) Undo(ctx context.Context, id string) error {
+	return m.historyCommand(ctx, "undo", id)
+}
+
+// Redo repeats a transaction with yum history redo.
+func (m *Manager) Redo(ctx context.Context, id string) error {
+	return m.historyCommand(ctx, "redo", id)
+}
+
+// historyCommand runs yum history undo or redo on a transaction.
+func (m *Manager) historyCommand(ctx context.Context, command, id string) error {
+	if n, err := strconv.Atoi(id); err != nil || n <= 0 {
+		return pkg.Wrap(pkg.ErrHistoryFailed, fmt.Errorf("invalid transaction ID %q", id))
+	}
+
+	result := m.executor.ExecuteElevated(ctx, "yum", "history", command, "-y", id)
+	if result.Failed() {
+		return pkg.Wrap(pkg.ErrHistoryFailed, fmt.Errorf("yum history %s %s failed: %s", command, id, result.StderrString()))
+	}
+	return nil
+}
+
+// Ensure Manager implements pkg.HistoryManager interface.
+var _ pkg.HistoryManager = (*Manager)(nil)
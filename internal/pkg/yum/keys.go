@@ -0,0 +1,33 @@
+package yum
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// ImportPinnedGPGKey downloads the key at keyURL, verifies it against the
+// pin and imports the verified file with rpm --import. The retired keys of
+// the pin still in the RPM database are reported.
+// Returns ErrGPGKeyMismatch if the key does not match the pin.
+func (m *Manager) ImportPinnedGPGKey(ctx context.Context, keyURL string, pin pkg.KeyPin) (*pkg.KeyImport, error) {
+	file, err := pkg.FetchPinnedGPGKey(ctx, m.executor, keyURL, pin)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Remove(ctx)
+
+	result := m.executor.ExecuteElevated(ctx, "rpm", "--import", file.Path)
+	if result.Failed() {
+		return nil, fmt.Errorf("rpm --import failed: %s", result.StderrString())
+	}
+
+	return &pkg.KeyImport{
+		Fingerprints: file.Fingerprints,
+		Retired:      pin.RetiredKeys(pkg.InstalledRPMKeyIDs(ctx, m.executor)),
+	}, nil
+}
+
+// Ensure Manager implements pkg.KeyPinningManager interface.
+var _ pkg.KeyPinningManager = (*Manager)(nil)
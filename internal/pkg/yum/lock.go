@@ -0,0 +1,46 @@
+package yum
+
+import (
+	"context"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// yumLockFiles are the lock files of YUM and of the RPM database, which
+// PackageKit also locks. YUM writes its PID to yum.pid.
+var yumLockFiles = []pkg.LockFile{
+	{Path: "/var/run/yum.pid", Kind: pkg.LockKindPIDFile},
+	{Path: "/var/lib/rpm/.rpm.lock", Kind: pkg.LockKindOpenFile},
+}
+
+// AcquireLock verifies that the yum lock is free.
+// YUM commands take the lock themselves, so it is not held afterwards.
+// Returns ErrLockAcquireFailed, naming the process holding the lock, if it
+// is held.
+func (m *Manager) AcquireLock(ctx context.Context) error {
+	return m.locks.AcquireLock(ctx)
+}
+
+// ReleaseLock does nothing, since AcquireLock does not hold the lock.
+func (m *Manager) ReleaseLock(ctx context.Context) error {
+	return m.locks.ReleaseLock(ctx)
+}
+
+// IsLocked checks if another process holds the yum lock.
+func (m *Manager) IsLocked(ctx context.Context) (bool, error) {
+	return m.locks.IsLocked(ctx)
+}
+
+// WaitForLock waits until the yum lock is free or ctx is done.
+func (m *Manager) WaitForLock(ctx context.Context) error {
+	return m.locks.WaitForLock(ctx)
+}
+
+// LockHolder returns the process holding the yum lock, or nil if the
+// lock is free.
+func (m *Manager) LockHolder(ctx context.Context) (*pkg.LockHolder, error) {
+	return m.locks.LockHolder(ctx)
+}
+
+// Ensure Manager implements pkg.LockableManager interface.
+var _ pkg.LockableManager = (*Manager)(nil)
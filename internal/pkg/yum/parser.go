@@ -4,6 +4,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/tungetti/igor/internal/pkg"
 )
@@ -531,3 +532,309 @@ func sanitizeRepoID(name string) string {
 
 	return name
 }
+
+// parseVersionlockList parses the output of yum versionlock list and returns
+// the names of the locked packages.
+//
+// Each lock is printed as an [epoch:]name-version-release.arch pattern, the
+// arch often being "*". Other lines, such as plugin messages and exclusions
+// starting with "!", are ignored.
+func parseVersionlockList(output string) []string {
+	locked := make([]string, 0)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+
+		if line == "" || strings.ContainsAny(line, " \t#!") {
+			continue
+		}
+		name := nevraName(line)
+
+		if name != "" && !contains(locked, name) {
+			locked = append(locked, name)
+		}
+	}
+	return locked
+}
+
+// nevraName returns the package name of a [epoch:]name-[epoch:]version-release.arch
+// string, or "" if s has no version and release.
+func nevraName(s string) string {
+	// Strip a leading epoch, as printed by yum ("0:name-version-release.arch")
+	if idx := strings.Index(s, ":"); idx > 0 && !strings.Contains(s[:idx], "-") {
+		s = s[idx+1:]
+	}
+
+	parts := strings.Split(s, "-")
+	if len(parts) < 3 {
+		return ""
+	}
+	return strings.Join(parts[:len(parts)-2], "-")
+}
+
+// excludeKeys are the options of the [main] section that exclude packages.
+var excludeKeys = map[string]bool{"exclude": true, "excludepkgs": true}
+
+// parseExcludes returns the packages excluded in the [main] section of a
+// DNF or YUM configuration file. Values are separated by spaces or commas.
+func parseExcludes(content string) []string {
+	excluded := make([]string, 0)
+	inMain := false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			inMain = trimmed == "[main]"
+			continue
+		}
+		if !inMain {
+			continue
+		}
+		if key, values, ok := splitExcludeLine(trimmed); ok && excludeKeys[key] {
+			for _, name := range values {
+				if !contains(excluded, name) {
+					excluded = append(excluded, name)
+				}
+			}
+		}
+	}
+	return excluded
+}
+
+// setExcludes adds and removes packages in the exclude= option of the [main]
+// section of the YUM configuration file. The first exclude line is
+// updated, or a new one is added after the [main] header; lines left empty
+// are removed. Returns the new content and whether it changed.
+func setExcludes(content string, add, remove []string) (string, bool) {
+	lines := strings.Split(content, "\n")
+	out := make([]string, 0, len(lines)+2)
+	changed := false
+	inMain, mainFound, added := false, false, len(add) == 0
+
+	// addTo appends the packages to add that are not excluded yet.
+	addTo := func(values []string) []string {
+		for _, name := range add {
+			if !contains(values, name) && !contains(parseExcludes(content), name) {
+				values = append(values, name)
+				changed = true
+			}
+		}
+		added = true
+		return values
+	}
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			if inMain && !added {
+				out = appendExcludeLine(out, addTo(nil))
+			}
+			inMain = trimmed == "[main]"
+			out = append(out, line)
+			if inMain {
+				mainFound = true
+			}
+			continue
+		}
+
+		key, values, ok := splitExcludeLine(trimmed)
+		if !inMain || !ok || !excludeKeys[key] {
+			out = append(out, line)
+			continue
+		}
+
+		kept := make([]string, 0, len(values))
+		for _, name := range values {
+			if contains(remove, name) {
+				changed = true
+				continue
+			}
+			kept = append(kept, name)
+		}
+		if !added {
+			kept = addTo(kept)
+		}
+		if len(kept) == 0 {
+			changed = true
+			continue
+		}
+		if len(kept) != len(values) {
+			line = key + "=" + strings.Join(kept, " ")
+		}
+		out = append(out, line)
+	}
+
+	if !added {
+		values := addTo(nil)
+		if !mainFound && len(values) > 0 {
+			// Add a [main] section at the end, separated by an empty line
+			for len(out) > 0 && strings.TrimSpace(out[len(out)-1]) == "" {
+				out = out[:len(out)-1]
+			}
+			if len(out) > 0 {
+				out = append(out, "")
+			}
+			out = append(out, "[main]", "")
+		}
+		out = appendExcludeLine(out, values)
+	}
+
+	if !changed {
+		return content, false
+	}
+	return strings.Join(out, "\n"), true
+}
+
+// appendExcludeLine appends an exclude= line for values, if any, keeping
+// the trailing empty lines of a section after it.
+func appendExcludeLine(lines []string, values []string) []string {
+	if len(values) == 0 {
+		return lines
+	}
+	end := len(lines)
+	for end > 0 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	result := append(append([]string{}, lines[:end]...), "exclude="+strings.Join(values, " "))
+	return append(result, lines[end:]...)
+}
+
+// splitExcludeLine splits a key=value line of a configuration file into the
+// key and the values of a space or comma separated list.
+func splitExcludeLine(line string) (string, []string, bool) {
+	if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+		return "", nil, false
+	}
+	eqIdx := strings.Index(line, "=")
+	if eqIdx == -1 {
+		return "", nil, false
+	}
+	key := strings.ToLower(strings.TrimSpace(line[:eqIdx]))
+	values := strings.FieldsFunc(line[eqIdx+1:], func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ','
+	})
+	return key, values, true
+}
+
+// parseHistoryIDs returns the transaction IDs listed by yum history list, in
+// the listed order. Lines that do not start with an ID, such as headers and
+// plugin messages, are ignored.
+func parseHistoryIDs(output string) []string {
+	ids := make([]string, 0)
+	for _, line := range strings.Split(output, "\n") {
+		field := line
+		if idx := strings.Index(field, "|"); idx != -1 {
+			field = field[:idx]
+		}
+		fields := strings.Fields(field)
+		if len(fields) == 0 {
+			continue
+		}
+		if id, err := strconv.Atoi(fields[0]); err == nil && id > 0 {
+			ids = append(ids, fields[0])
+		}
+	}
+	return ids
+}
+
+// historyActions maps the actions listed under "Packages Altered" by
+// yum history info to history operations. Actions naming the replaced side
+// of a change, such as "Upgraded", affect the package but add no operation.
+var historyActions = map[string]string{
+	"install":     pkg.HistoryInstall,
+	"dep-install": pkg.HistoryInstall,
+	"obsoleting":  pkg.HistoryInstall,
+	"update":      pkg.HistoryUpgrade,
+	"updated":     "",
+	"downgrade":   pkg.HistoryDowngrade,
+	"downgraded":  "",
+	"reinstall":   pkg.HistoryReinstall,
+	"reinstalled": "",
+	"erase":       pkg.HistoryRemove,
+	"obsoleted":   pkg.HistoryRemove,
+}
+
+// historyTimeLayouts are the formats of the begin time of a transaction.
+var historyTimeLayouts = []string{
+	"Mon Jan 2 15:04:05 2006",
+}
+
+// parseHistoryInfo parses the output of yum history info for one or more
+// transactions into history entries, in the printed order.
+func parseHistoryInfo(output string) []pkg.HistoryEntry {
+	entries := make([]pkg.HistoryEntry, 0)
+	var current *pkg.HistoryEntry
+	inPackages := false
+
+	for _, line := range strings.Split(output, "\n") {
+		if inPackages && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			addHistoryPackage(current, line)
+			continue
+		}
+		inPackages = false
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if key == "transaction id" {
+			entries = append(entries, pkg.HistoryEntry{ID: value})
+			current = &entries[len(entries)-1]
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		switch key {
+		case "begin time":
+			current.Timestamp = parseHistoryTime(value)
+		case "return-code":
+			current.Success = value == "Success"
+		case "command line":
+			current.Details = value
+		case "packages altered":
+			inPackages = true
+		}
+	}
+
+	return entries
+}
+
+// addHistoryPackage adds a package line listed under "Packages Altered",
+// such as "    Install akmod-nvidia-3:550.54.14-1.fc39.x86_64 @rpmfusion",
+// to an entry.
+func addHistoryPackage(entry *pkg.HistoryEntry, line string) {
+	fields := strings.Fields(line)
+	// Problems are flagged with a leading "**"
+	for len(fields) > 0 && strings.Trim(fields[0], "*") == "" {
+		fields = fields[1:]
+	}
+	if len(fields) < 2 {
+		return
+	}
+
+	operation, ok := historyActions[strings.ToLower(fields[0])]
+	if !ok {
+		return
+	}
+	name := nevraName(fields[1])
+	if name == "" {
+		return
+	}
+	entry.AddOperation(operation)
+	entry.AddPackage(name)
+}
+
+// parseHistoryTime parses the begin time of a transaction in the local time
+// zone. It returns 0 for times it cannot parse, such as localized ones.
+func parseHistoryTime(value string) int64 {
+	value = strings.Join(strings.Fields(value), " ")
+	for _, layout := range historyTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t.Unix()
+		}
+	}
+	return 0
+}
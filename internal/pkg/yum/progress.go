@@ -0,0 +1,59 @@
+package yum
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+
+	igorexec "github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+var (
+	// downloadLine matches the line yum prints once a package is downloaded:
+	// "(1/3): nvidia-driver-550.54.14-1.el7.x86_64.rpm  |  45 MB  00:03"
+	downloadLine = regexp.MustCompile(`^\((\d+)/(\d+)\):\s+(\S+)`)
+
+	// transactionLine matches the lines of the rpm transaction:
+	// "Installing : 3:nvidia-driver-550.54.14-1.el7.x86_64    1/3"
+	transactionLine = regexp.MustCompile(`^(Installing|Updating|Reinstalling|Downgrading)\s*:\s+(\S+)\s+(\d+)/(\d+)$`)
+)
+
+// executeElevatedWithProgress runs yum with root privileges, with its output
+// parsed into progress passed to report.
+func (m *Manager) executeElevatedWithProgress(ctx context.Context, report func(pkg.Progress), args ...string) *igorexec.Result {
+	w := pkg.NewProgressWriter(parseProgressLine, report)
+	result := m.executor.StreamElevated(ctx, w, nil, "yum", args...)
+	w.Flush()
+	return result
+}
+
+// parseProgressLine parses a line of the output of yum install. The
+// progress is counted in packages from the "(n/m)" numbering of the lines.
+func parseProgressLine(line string) (pkg.Progress, bool) {
+	if match := downloadLine.FindStringSubmatch(line); match != nil {
+		return numberedProgress(pkg.ProgressDownload, match[3], match[1], match[2])
+	}
+	if match := transactionLine.FindStringSubmatch(line); match != nil {
+		return numberedProgress(pkg.ProgressInstall, match[2], match[3], match[4])
+	}
+	return pkg.Progress{}, false
+}
+
+// numberedProgress returns the progress of the done-th package out of
+// total, named after its NEVRA or file name.
+func numberedProgress(phase pkg.ProgressPhase, nevra, done, total string) (pkg.Progress, bool) {
+	n, err := strconv.Atoi(done)
+	if err != nil {
+		return pkg.Progress{}, false
+	}
+	m, err := strconv.Atoi(total)
+	if err != nil {
+		return pkg.Progress{}, false
+	}
+	return pkg.Progress{
+		Phase:   phase,
+		Package: pkg.RPMPackageName(nevra),
+		Percent: pkg.StepPercent(n, m),
+	}, true
+}
@@ -33,6 +33,7 @@ import (
 type Manager struct {
 	executor  igorexec.Executor
 	privilege *privilege.Manager
+	locks     *pkg.LockChecker
 }
 
 // NewManager creates a new YUM package manager.
@@ -42,6 +43,7 @@ func NewManager(executor igorexec.Executor, priv *privilege.Manager) *Manager {
 	return &Manager{
 		executor:  executor,
 		privilege: priv,
+		locks:     pkg.NewLockChecker(executor, "yum", yumLockFiles...),
 	}
 }
 
@@ -62,14 +64,21 @@ func (m *Manager) IsAvailable() bool {
 }
 
 // Install installs one or more packages using yum install.
-// Uses -y for non-interactive operation.
+// Uses -y for non-interactive operation. If opts.Progress is set, the
+// download and installation progress is read from the output of yum as it
+// runs.
 func (m *Manager) Install(ctx context.Context, opts pkg.InstallOptions, packages ...string) error {
 	if len(packages) == 0 {
 		return nil
 	}
 
 	args := m.buildInstallArgs(opts, packages)
-	result := m.executor.ExecuteElevated(ctx, "yum", args...)
+	var result *igorexec.Result
+	if opts.Progress != nil {
+		result = m.executeElevatedWithProgress(ctx, opts.Progress, args...)
+	} else {
+		result = m.executor.ExecuteElevated(ctx, "yum", args...)
+	}
 
 	if result.Failed() {
 		stderr := result.StderrString()
@@ -3,6 +3,7 @@ package yum
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -1341,3 +1342,449 @@ func TestManager_Install_ContextCancelled(t *testing.T) {
 	// With mock, it succeeds because mock doesn't check context
 	require.NoError(t, err)
 }
+
+// =============================================================================
+// Hold Tests
+// =============================================================================
+
+const testYumConf = `[main]
+gpgcheck=1
+installonly_limit=3
+exclude=kernel*
+`
+
+func TestManager_Hold_Versionlock(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("yum", exec.SuccessResult(""))
+
+	err := mgr.Hold(context.Background(), "kmod-nvidia", "nvidia-x11-drv")
+	require.NoError(t, err)
+
+	assert.True(t, mockExec.WasCalledWith("yum", "versionlock", "list"))
+	assert.True(t, mockExec.WasCalledWith("yum", "versionlock", "add", "kmod-nvidia", "nvidia-x11-drv"))
+	assert.True(t, mockExec.LastCall().Elevated)
+	assert.False(t, mockExec.WasCalled("tee"))
+}
+
+func TestManager_Hold_ExcludeFallback_WriteFailure(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("yum", exec.FailureResult(1, "No such command: versionlock. Please use /usr/bin/yum --help"))
+	mockExec.SetResponse("cat", exec.SuccessResult(testYumConf))
+	mockExec.SetResponse("sudo", exec.FailureResult(1, "read-only file system"))
+
+	err := mgr.Hold(context.Background(), "kmod-nvidia")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, pkg.ErrHoldFailed)
+	assert.Contains(t, err.Error(), "read-only file system")
+}
+
+func TestManager_Hold_ExcludeFallback(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("yum", exec.FailureResult(1, "No such command: versionlock. Please use /usr/bin/yum --help"))
+	mockExec.SetResponse("cat", exec.SuccessResult(testYumConf))
+
+	err := mgr.Hold(context.Background(), "kmod-nvidia", "nvidia-x11-drv")
+	require.NoError(t, err)
+
+	call := mockExec.LastCall()
+	assert.Equal(t, "sudo", call.Command)
+	assert.Equal(t, []string{"tee", yumConfPath}, call.Args)
+	assert.Contains(t, string(call.Input), "exclude=kernel* kmod-nvidia nvidia-x11-drv\n")
+	assert.Contains(t, string(call.Input), "installonly_limit=3\n")
+}
+
+func TestManager_Hold_ExcludeFallback_AlreadyHeld(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("yum", exec.FailureResult(1, "No such command: versionlock. Please use /usr/bin/yum --help"))
+	mockExec.SetResponse("cat", exec.SuccessResult(testYumConf))
+
+	err := mgr.Hold(context.Background(), "kernel*")
+	require.NoError(t, err)
+	assert.False(t, mockExec.WasCalled("sudo"))
+}
+
+func TestManager_Hold_ExcludeFallback_ReadFailure(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("yum", exec.FailureResult(1, "No such command: versionlock. Please use /usr/bin/yum --help"))
+	mockExec.SetResponse("cat", exec.FailureResult(1, "Permission denied"))
+
+	err := mgr.Hold(context.Background(), "kmod-nvidia")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, pkg.ErrHoldFailed)
+}
+
+func TestManager_Unhold_Versionlock(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("yum", exec.SuccessResult("kmod-nvidia-3:550.54.14-1.el7.*\n"))
+	mockExec.SetResponse("cat", exec.SuccessResult(testYumConf))
+
+	err := mgr.Unhold(context.Background(), "kmod-nvidia", "nvidia-x11-drv")
+	require.NoError(t, err)
+
+	assert.True(t, mockExec.WasCalledWith("yum", "versionlock", "delete", "kmod-nvidia"))
+	assert.False(t, mockExec.WasCalled("sudo"))
+}
+
+func TestManager_Unhold_Exclude(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("yum", exec.FailureResult(1, "No such command: versionlock. Please use /usr/bin/yum --help"))
+	mockExec.SetResponse("cat", exec.SuccessResult("[main]\nexclude=kmod-nvidia\ngpgcheck=1\n"))
+
+	err := mgr.Unhold(context.Background(), "kmod-nvidia")
+	require.NoError(t, err)
+
+	call := mockExec.LastCall()
+	assert.Equal(t, "sudo", call.Command)
+	assert.Equal(t, "[main]\ngpgcheck=1\n", string(call.Input))
+}
+
+func TestManager_ListHeld(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("yum", exec.SuccessResult("Loaded plugins: fastestmirror, versionlock\nkmod-nvidia-3:550.54.14-1.el7.*\n"))
+	mockExec.SetResponse("cat", exec.SuccessResult("[main]\nexclude=kernel*, kmod-nvidia\n"))
+
+	held, err := mgr.ListHeld(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kmod-nvidia", "kernel*"}, held)
+}
+
+func TestParseVersionlockList(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected []string
+	}{
+		{"empty", "", []string{}},
+		{"locks", "kmod-nvidia-3:550.54.14-1.el7.*\nnvidia-x11-drv-cuda-libs-3:550.54.14-1.el7.x86_64\n", []string{"kmod-nvidia", "nvidia-x11-drv-cuda-libs"}},
+		{"plugin messages", "Loaded plugins: fastestmirror, versionlock\n0:nvidia-driver-550.54.14-1.el7.*\nversionlock list done\n", []string{"nvidia-driver"}},
+		{"comment", "# Added locks on Fri Mar  1 10:00:00 2024\nkmod-nvidia-3:550.54.14-1.el7.*\n", []string{"kmod-nvidia"}},
+		{"exclusion", "!kernel-6.7.5-200.el7.*\n", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseVersionlockList(tt.output))
+		})
+	}
+}
+
+func TestSetExcludes(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		add      []string
+		remove   []string
+		expected string
+		changed  bool
+	}{
+		{
+			name:     "add to existing line",
+			content:  "[main]\nexclude=kernel*\n",
+			add:      []string{"kmod-nvidia"},
+			expected: "[main]\nexclude=kernel* kmod-nvidia\n",
+			changed:  true,
+		},
+		{
+			name:     "add new line",
+			content:  "[main]\ngpgcheck=1\n\n[updates]\nenabled=1\n",
+			add:      []string{"kmod-nvidia"},
+			expected: "[main]\ngpgcheck=1\nexclude=kmod-nvidia\n\n[updates]\nenabled=1\n",
+			changed:  true,
+		},
+		{
+			name:     "add main section",
+			content:  "",
+			add:      []string{"kmod-nvidia"},
+			expected: "[main]\nexclude=kmod-nvidia\n",
+			changed:  true,
+		},
+		{
+			name:     "add main section after others",
+			content:  "[updates]\nenabled=1\n",
+			add:      []string{"kmod-nvidia"},
+			expected: "[updates]\nenabled=1\n\n[main]\nexclude=kmod-nvidia\n",
+			changed:  true,
+		},
+		{
+			name:     "already excluded",
+			content:  "[main]\nexcludepkgs=kmod-nvidia\n",
+			add:      []string{"kmod-nvidia"},
+			expected: "[main]\nexcludepkgs=kmod-nvidia\n",
+			changed:  false,
+		},
+		{
+			name:     "remove keeps other entries",
+			content:  "[main]\nexclude=kernel*,kmod-nvidia\n",
+			remove:   []string{"kmod-nvidia"},
+			expected: "[main]\nexclude=kernel*\n",
+			changed:  true,
+		},
+		{
+			name:     "remove ignores other sections",
+			content:  "[main]\n[updates]\nexclude=kmod-nvidia\n",
+			remove:   []string{"kmod-nvidia"},
+			expected: "[main]\n[updates]\nexclude=kmod-nvidia\n",
+			changed:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, changed := setExcludes(tt.content, tt.add, tt.remove)
+			assert.Equal(t, tt.expected, content)
+			assert.Equal(t, tt.changed, changed)
+		})
+	}
+}
+
+// =============================================================================
+// Lock Tests
+// =============================================================================
+
+func TestManager_LockHolder_PIDFile(t *testing.T) {
+	mgr, mockExec := setupTest()
+	// cat returns the PID for yum.pid and confirms the process is running
+	mockExec.SetResponse("cat", exec.SuccessResult("2100\n"))
+
+	holder, err := mgr.LockHolder(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, holder)
+	assert.Equal(t, 2100, holder.PID)
+	assert.Equal(t, "/var/run/yum.pid", holder.Path)
+	assert.True(t, mockExec.WasCalledWith("cat", "/var/run/yum.pid"))
+	assert.True(t, mockExec.WasCalledWith("cat", "/proc/2100/comm"))
+	assert.False(t, mockExec.WasCalled("find"))
+
+	err = mgr.AcquireLock(context.Background())
+	assert.ErrorIs(t, err, pkg.ErrLockAcquireFailed)
+}
+
+func TestManager_IsLocked_Free(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("cat", exec.FailureResult(1, "No such file or directory"))
+	mockExec.SetResponse("find", exec.SuccessResult(""))
+
+	locked, err := mgr.IsLocked(context.Background())
+	require.NoError(t, err)
+	assert.False(t, locked)
+	assert.True(t, mockExec.WasCalled("find"))
+	assert.NoError(t, mgr.WaitForLock(context.Background()))
+}
+
+// =============================================================================
+// History Tests
+// =============================================================================
+
+const testYumHistoryList = `Loaded plugins: fastestmirror
+ID     | Login user               | Date and time    | Action(s)      | Altered
+-------------------------------------------------------------------------------
+     8 | root <root>              | 2024-01-15 10:23 | Install        |    2
+     7 | root <root>              | 2024-01-10 09:00 | Update         |    1
+history list
+`
+
+const testYumHistoryInfo = `Loaded plugins: fastestmirror
+Transaction ID : 7
+Begin time     : Wed Jan 10 09:00:12 2024
+End time       :            09:03:40 2024 (208 seconds)
+User           : root <root>
+Return-Code    : Success
+Command Line   : update kmod-nvidia
+Transaction performed with:
+    Installed     rpm-4.11.3-45.el7.x86_64   @base
+Packages Altered:
+    Updated kmod-nvidia-545.29.06-1.el7_9.elrepo.x86_64 @elrepo
+    Update              550.54.14-1.el7_9.elrepo.x86_64 @elrepo
+-------------------------------------------------------------------------------
+Transaction ID : 8
+Begin time     : Mon Jan 15 10:23:45 2024
+User           : root <root>
+Return-Code    : Success
+Command Line   : install nvidia-x11-drv
+Packages Altered:
+    Install     nvidia-x11-drv-550.54.14-1.el7_9.elrepo.x86_64 @elrepo
+    Dep-Install nvidia-x11-drv-libs-550.54.14-1.el7_9.elrepo.x86_64 @elrepo
+history info
+`
+
+func TestParseHistoryIDs(t *testing.T) {
+	assert.Equal(t, []string{"8", "7"}, parseHistoryIDs(testYumHistoryList))
+	assert.Empty(t, parseHistoryIDs("Loaded plugins: fastestmirror\nNo transactions\n"))
+}
+
+func TestParseHistoryInfo(t *testing.T) {
+	entries := parseHistoryInfo(testYumHistoryInfo)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "7", entries[0].ID)
+	assert.True(t, entries[0].Success)
+	assert.Equal(t, "update kmod-nvidia", entries[0].Details)
+	assert.Equal(t, []string{"kmod-nvidia"}, entries[0].Packages)
+	assert.Equal(t, time.Date(2024, 1, 10, 9, 0, 12, 0, time.Local).Unix(), entries[0].Timestamp)
+
+	assert.Equal(t, "8", entries[1].ID)
+	assert.Equal(t, "install", entries[1].Operation)
+	assert.Equal(t, []string{"nvidia-x11-drv", "nvidia-x11-drv-libs"}, entries[1].Packages)
+}
+
+func TestManager_History(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("yum", exec.SuccessResult(testYumHistoryList+testYumHistoryInfo))
+
+	entries, err := mgr.History(context.Background(), 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "8", entries[0].ID)
+	assert.Equal(t, "7", entries[1].ID)
+	assert.True(t, mockExec.WasCalledWith("yum", "history", "list", "all"))
+	assert.True(t, mockExec.WasCalledWith("yum", "history", "info", "8", "7"))
+}
+
+func TestManager_History_Failure(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("yum", exec.FailureResult(1, "You need to be root"))
+
+	_, err := mgr.History(context.Background(), 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "You need to be root")
+}
+
+func TestManager_Undo(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	require.NoError(t, mgr.Undo(context.Background(), "8"))
+	assert.True(t, mockExec.WasCalledWith("yum", "history", "undo", "-y", "8"))
+	assert.True(t, mockExec.LastCall().Elevated)
+
+	require.NoError(t, mgr.Redo(context.Background(), "8"))
+	assert.True(t, mockExec.WasCalledWith("yum", "history", "redo", "-y", "8"))
+
+	err := mgr.Undo(context.Background(), "last")
+	assert.ErrorIs(t, err, pkg.ErrHistoryFailed)
+}
+
+func testCUDAKeyPin() pkg.KeyPin {
+	return pkg.KeyPin{
+		Name:         "nvidia-cuda",
+		Fingerprints: []string{"EB693B3035CD5710E231E123A4B469963BF863CC"},
+		Retired: []pkg.RetiredKey{
+			{Fingerprint: "AE09FE4BBD223A84B2CCFCE3F60F4B3D7FA2AF80", ReplacedBy: "EB693B3035CD5710E231E123A4B469963BF863CC", Date: "2022-04-27"},
+		},
+	}
+}
+
+func TestManager_ImportPinnedGPGKey(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetDefaultResponse(exec.SuccessResult(""))
+	mockExec.SetResponse("mktemp", exec.SuccessResult("/tmp/igor-key.abc\n"))
+	mockExec.SetResponse("gpg", exec.SuccessResult("pub:-:4096:1:A4B469963BF863CC:1650000000:::-:::scSC:\nfpr:::::::::EB693B3035CD5710E231E123A4B469963BF863CC:\n"))
+	// The retired key is still in the RPM database
+	mockExec.SetResponse("rpm", exec.SuccessResult("3bf863cc\n7fa2af80\n"))
+
+	keyURL := "https://developer.download.nvidia.com/compute/cuda/repos/rhel9/x86_64/3bf863cc.pub"
+	imp, err := mgr.ImportPinnedGPGKey(context.Background(), keyURL, testCUDAKeyPin())
+	require.NoError(t, err)
+
+	assert.Empty(t, imp.Keyring)
+	assert.Equal(t, []string{"EB693B3035CD5710E231E123A4B469963BF863CC"}, imp.Fingerprints)
+	require.Len(t, imp.Retired, 1)
+	assert.Equal(t, "AE09FE4BBD223A84B2CCFCE3F60F4B3D7FA2AF80", imp.Retired[0].Fingerprint)
+
+	// The verified file is imported, not the URL
+	assert.True(t, mockExec.WasCalledWith("rpm", "--import", "/tmp/igor-key.abc"))
+	assert.False(t, mockExec.WasCalledWith("rpm", "--import", keyURL))
+	assert.True(t, mockExec.WasCalledWith("rm", "-f", "/tmp/igor-key.abc"))
+}
+
+func TestManager_ImportPinnedGPGKey_Mismatch(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetDefaultResponse(exec.SuccessResult(""))
+	mockExec.SetResponse("mktemp", exec.SuccessResult("/tmp/igor-key.abc\n"))
+	mockExec.SetResponse("gpg", exec.SuccessResult("pub:-:4096:1:0123456789ABCDEF:1650000000:::-:::scSC:\nfpr:::::::::00000000000000000000000000000000DEADBEEF:\n"))
+
+	_, err := mgr.ImportPinnedGPGKey(context.Background(), "https://example.com/key.pub", testCUDAKeyPin())
+	require.Error(t, err)
+	assert.ErrorIs(t, err, pkg.ErrGPGKeyMismatch)
+	assert.False(t, mockExec.WasCalled("rpm"))
+}
+
+func TestManager_PreferRepository(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetResponse("yum", exec.SuccessResult(`repo id                           repo name                                 status
+base/7/x86_64                     CentOS-7 - Base                           enabled
+rpmfusion-nonfree                 RPM Fusion Nonfree                        enabled
+rpmfusion-nonfree-updates         RPM Fusion Nonfree - Updates              enabled
+repolist: 10000`))
+	mockExec.SetResponse("yum-config-manager", exec.SuccessResult(""))
+
+	err := mgr.PreferRepository(context.Background(), pkg.Repository{Name: "rpmfusion-nonfree"}, []string{"nvidia-*"})
+	require.NoError(t, err)
+	assert.True(t, mockExec.WasCalledWith("yum-config-manager", "--save",
+		"--setopt=rpmfusion-nonfree.priority=90", "--setopt=rpmfusion-nonfree-updates.priority=90"))
+}
+
+func TestManager_RemoveRepositoryPreference(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetResponse("yum", exec.FailureResult(1, "repolist failed"))
+	mockExec.SetResponse("yum-config-manager", exec.SuccessResult(""))
+
+	err := mgr.RemoveRepositoryPreference(context.Background(), "rpmfusion-nonfree")
+	require.NoError(t, err)
+	assert.True(t, mockExec.WasCalledWith("yum-config-manager", "--save", "--setopt=rpmfusion-nonfree.priority=99"))
+}
+
+func TestManager_RemoveRepositoryPreference_Failure(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetResponse("yum-config-manager", exec.FailureResult(1, "Permission denied"))
+
+	err := mgr.RemoveRepositoryPreference(context.Background(), "rpmfusion-nonfree")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "yum-config-manager --setopt failed")
+}
+
+// =============================================================================
+// Progress Tests
+// =============================================================================
+
+func TestManager_Install_Progress(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("yum", exec.SuccessResult(`Downloading packages:
+(1/2): nvidia-x11-drv-libs-550.54.14-1.el7_9.elrepo.x86_64.rpm  |  45 MB  00:03
+(2/2): kmod-nvidia-550.54.14-1.el7_9.elrepo.x86_64.rpm           |  40 MB  00:02
+Running transaction
+  Installing : nvidia-x11-drv-libs-550.54.14-1.el7_9.elrepo.x86_64      1/3
+  Updating   : 1:kmod-nvidia-550.54.14-1.el7_9.elrepo.x86_64            2/3
+  Cleanup    : 1:kmod-nvidia-535.171.04-1.el7_9.elrepo.x86_64           3/3
+  Verifying  : 1:kmod-nvidia-550.54.14-1.el7_9.elrepo.x86_64            1/3
+Complete!`))
+
+	var reported []pkg.Progress
+	opts := pkg.NonInteractiveInstallOptions()
+	opts.Progress = func(p pkg.Progress) { reported = append(reported, p) }
+	require.NoError(t, mgr.Install(context.Background(), opts, "kmod-nvidia"))
+
+	call := mockExec.LastCall()
+	assert.True(t, call.Elevated)
+	assert.Equal(t, "yum", call.Command)
+	assert.Equal(t, []pkg.Progress{
+		{Phase: pkg.ProgressDownload, Package: "nvidia-x11-drv-libs", Percent: 50},
+		{Phase: pkg.ProgressDownload, Package: "kmod-nvidia", Percent: 100},
+		{Phase: pkg.ProgressInstall, Package: "nvidia-x11-drv-libs", Percent: pkg.StepPercent(1, 3)},
+		{Phase: pkg.ProgressInstall, Package: "kmod-nvidia", Percent: pkg.StepPercent(2, 3)},
+	}, reported)
+}
+
+func TestManager_Install_ProgressFailure(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("yum", exec.FailureResult(1, "No package kmod-nvidia-999 available."))
+
+	opts := pkg.NonInteractiveInstallOptions()
+	opts.Progress = func(pkg.Progress) {}
+	err := mgr.Install(context.Background(), opts, "kmod-nvidia-999")
+	assert.ErrorIs(t, err, pkg.ErrPackageNotFound)
+}
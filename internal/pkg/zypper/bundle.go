@@ -0,0 +1,75 @@
+package zypper
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// DownloadBundle downloads the packages and their dependencies into dir
+// with zypper install --download-only.
+// The packages are resolved with --root on an empty temporary root holding
+// only the repositories and the base product of this system, so zypper sees
+// no installed package and downloads the whole dependency closure,
+// including the packages installed on this system, and the bundle can be
+// installed on a minimal system of the same release. The repository keys
+// are imported into the temporary root only.
+// The package cache of the root keeps one subdirectory per repository; it
+// is copied into dir and served as a plain directory repository without
+// metadata.
+func (m *Manager) DownloadBundle(ctx context.Context, dir string, packages ...string) error {
+	if len(packages) == 0 {
+		return nil
+	}
+
+	root, err := pkg.NewBundleRoot(ctx, m.executor)
+	if err != nil {
+		return err
+	}
+	defer root.Remove(ctx)
+
+	// $releasever in the repository URLs is read from the base product
+	result := m.executor.ExecuteElevated(ctx, "sh", "-c",
+		`mkdir -p "$1/etc/zypp" && cp -a /etc/zypp/repos.d "$1/etc/zypp/" && cp -a /etc/products.d "$1/etc/"`,
+		"sh", root.Path)
+	if result.Failed() {
+		return pkg.Wrap(pkg.ErrBundleFailed, fmt.Errorf("failed to prepare the bundle root: %s", result.StderrString()))
+	}
+
+	args := []string{"--root", root.Path, "--non-interactive", "--gpg-auto-import-keys", "install", "--download-only"}
+	args = append(args, packages...)
+	result = m.executor.ExecuteElevated(ctx, "zypper", args...)
+	if result.Failed() {
+		stderr := result.StderrString()
+		if strings.Contains(stderr, "System management is locked") ||
+			strings.Contains(stderr, "another zypper is running") {
+			return pkg.Wrap(pkg.ErrLockAcquireFailed, fmt.Errorf("zypper install --download-only failed: %s", stderr))
+		}
+		return pkg.Wrap(pkg.ErrBundleFailed, fmt.Errorf("zypper install --download-only failed (exit code %d): %s", result.ExitCode, stderr))
+	}
+
+	result = m.executor.ExecuteElevated(ctx, "cp", "-a", filepath.Join(root.Path, "var/cache/zypp/packages")+"/.", dir)
+	if result.Failed() {
+		return pkg.Wrap(pkg.ErrBundleFailed, fmt.Errorf("failed to copy the packages into %s: %s", dir, result.StderrString()))
+	}
+
+	return nil
+}
+
+// BundleRepository returns the plain directory repository serving the
+// bundle in dir.
+func (m *Manager) BundleRepository(dir string) pkg.Repository {
+	return pkg.Repository{
+		Name:    pkg.BundleRepositoryName,
+		URL:     "file://" + dir,
+		Enabled: true,
+		Type:    "plaindir",
+		Trusted: true,
+	}
+}
+
+// Ensure Manager implements pkg.BundleManager interface.
+var _ pkg.BundleManager = (*Manager)(nil)
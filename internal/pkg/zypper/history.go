@@ -0,0 +1,47 @@
+package zypper
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// zyppHistoryPath is the libzypp history log, shared by zypper, YaST and
+// PackageKit.
+const zyppHistoryPath = "/var/log/zypp/history"
+
+// History returns the libzypp transaction history, newest first.
+// It is read from /var/log/zypp/history, which only root can read. libzypp
+// has no transaction IDs, so entries are numbered in log order, oldest
+// first. Rotated logs are not read.
+func (m *Manager) History(ctx context.Context, limit int) ([]pkg.HistoryEntry, error) {
+	result := m.executor.ExecuteElevated(ctx, "cat", zyppHistoryPath)
+	if result.Failed() {
+		if strings.Contains(result.StderrString(), "No such file") {
+			return []pkg.HistoryEntry{}, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %s", zyppHistoryPath, result.StderrString())
+	}
+
+	entries := parseZyppHistory(result.StdoutString())
+	for i := range entries {
+		entries[i].ID = strconv.Itoa(i + 1)
+	}
+	return pkg.NewestHistory(entries, limit), nil
+}
+
+// Undo is not supported: zypper cannot undo transactions.
+func (m *Manager) Undo(ctx context.Context, id string) error {
+	return pkg.Wrap(pkg.ErrUnsupportedOperation, fmt.Errorf("zypper cannot undo transactions"))
+}
+
+// Redo is not supported: zypper cannot redo transactions.
+func (m *Manager) Redo(ctx context.Context, id string) error {
+	return pkg.Wrap(pkg.ErrUnsupportedOperation, fmt.Errorf("zypper cannot redo transactions"))
+}
+
+// Ensure Manager implements pkg.HistoryManager interface.
+var _ pkg.HistoryManager = (*Manager)(nil)
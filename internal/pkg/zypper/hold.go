@@ -0,0 +1,54 @@
+package zypper
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// Hold prevents packages from being upgraded or removed with zypper addlock.
+// Locking an already locked package is not an error.
+func (m *Manager) Hold(ctx context.Context, packages ...string) error {
+	if len(packages) == 0 {
+		return nil
+	}
+
+	args := append([]string{"--non-interactive", "addlock"}, packages...)
+	result := m.executor.ExecuteElevated(ctx, "zypper", args...)
+
+	if result.Failed() {
+		return pkg.Wrap(pkg.ErrHoldFailed, fmt.Errorf("zypper addlock failed: %s", result.StderrString()))
+	}
+
+	return nil
+}
+
+// Unhold releases package holds with zypper removelock.
+// Packages that are not locked are ignored by zypper.
+func (m *Manager) Unhold(ctx context.Context, packages ...string) error {
+	if len(packages) == 0 {
+		return nil
+	}
+
+	args := append([]string{"--non-interactive", "removelock"}, packages...)
+	result := m.executor.ExecuteElevated(ctx, "zypper", args...)
+
+	if result.Failed() {
+		return pkg.Wrap(pkg.ErrHoldFailed, fmt.Errorf("zypper removelock failed: %s", result.StderrString()))
+	}
+
+	return nil
+}
+
+// ListHeld returns the packages locked with zypper addlock.
+func (m *Manager) ListHeld(ctx context.Context) ([]string, error) {
+	result := m.executor.Execute(ctx, "zypper", "--non-interactive", "locks")
+	if result.Failed() {
+		return nil, fmt.Errorf("zypper locks failed: %s", result.StderrString())
+	}
+	return parseZypperLocks(result.StdoutString()), nil
+}
+
+// Ensure Manager implements pkg.HoldManager interface.
+var _ pkg.HoldManager = (*Manager)(nil)
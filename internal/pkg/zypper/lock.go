@@ -0,0 +1,45 @@
+package zypper
+
+import (
+	"context"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// zypperLockFiles are the lock files of libzypp, shared by zypper, YaST and
+// PackageKit. The process holding the lock writes its PID to zypp.pid.
+var zypperLockFiles = []pkg.LockFile{
+	{Path: "/var/run/zypp.pid", Kind: pkg.LockKindPIDFile},
+}
+
+// AcquireLock verifies that the zypp lock is free.
+// Zypper commands take the lock themselves, so it is not held afterwards.
+// Returns ErrLockAcquireFailed, naming the process holding the lock, if it
+// is held.
+func (m *Manager) AcquireLock(ctx context.Context) error {
+	return m.locks.AcquireLock(ctx)
+}
+
+// ReleaseLock does nothing, since AcquireLock does not hold the lock.
+func (m *Manager) ReleaseLock(ctx context.Context) error {
+	return m.locks.ReleaseLock(ctx)
+}
+
+// IsLocked checks if another process holds the zypp lock.
+func (m *Manager) IsLocked(ctx context.Context) (bool, error) {
+	return m.locks.IsLocked(ctx)
+}
+
+// WaitForLock waits until the zypp lock is free or ctx is done.
+func (m *Manager) WaitForLock(ctx context.Context) error {
+	return m.locks.WaitForLock(ctx)
+}
+
+// LockHolder returns the process holding the zypp lock, or nil if the
+// lock is free.
+func (m *Manager) LockHolder(ctx context.Context) (*pkg.LockHolder, error) {
+	return m.locks.LockHolder(ctx)
+}
+
+// Ensure Manager implements pkg.LockableManager interface.
+var _ pkg.LockableManager = (*Manager)(nil)
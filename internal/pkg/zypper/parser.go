@@ -4,6 +4,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/tungetti/igor/internal/pkg"
 )
@@ -124,6 +125,29 @@ func parseZypperInfo(output string) (*pkg.Package, error) {
 	return p, nil
 }
 
+// parseZypperInstalledSizes parses the package stanzas printed by
+// zypper info for several packages and returns the installed size of each
+// package in bytes, keyed by name.
+func parseZypperInstalledSizes(output string) map[string]int64 {
+	sizes := make(map[string]int64)
+	var name string
+	for _, line := range strings.Split(output, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "Name":
+			name = strings.TrimSpace(value)
+		case "Installed Size":
+			if name != "" {
+				sizes[name] = parseSize(value)
+			}
+		}
+	}
+	return sizes
+}
+
 // parseSize parses a size string like "123 k", "1.2 M", "1.2 MiB" to bytes.
 func parseSize(sizeStr string) int64 {
 	sizeStr = strings.TrimSpace(strings.ToLower(sizeStr))
@@ -568,3 +592,193 @@ func isArchitecture(s string) bool {
 	}
 	return archs[s]
 }
+
+// parseZypperLocks parses the output of zypper locks and returns the locked
+// package names.
+// Example output:
+//
+//	# | Name              | Type    | Repository
+//	--+-------------------+---------+-----------
+//	1 | nvidia-driver-G06 | package | (any)
+func parseZypperLocks(output string) []string {
+	locked := make([]string, 0)
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.Contains(line, "|") ||
+			strings.HasPrefix(line, "#") ||
+			strings.HasPrefix(line, "--+") {
+			continue
+		}
+
+		fields := strings.Split(line, "|")
+		if len(fields) < 2 {
+			continue
+		}
+		if name := strings.TrimSpace(fields[1]); name != "" {
+			locked = append(locked, name)
+		}
+	}
+
+	return locked
+}
+
+// parseZyppHistory parses the libzypp history log into history entries, in
+// log order. Each "command" line starts a transaction and the install and
+// remove lines after it are its packages. libzypp only logs packages that
+// were installed or removed, so all transactions succeeded. Commands that
+// changed no packages, such as repository refreshes, are dropped. IDs are
+// not set.
+//
+// Example lines:
+//
+//	2024-01-15 10:23:40|command|root@host|'zypper' 'install' 'nvidia-video-G06'|
+//	2024-01-15 10:23:45|install|nvidia-video-G06|550.54.14-lp156.1|x86_64|root@host|nvidia|0123abcd|
+//	2024-01-15 10:23:46|remove |nvidia-gl-G05|535.154.05-lp155.1|x86_64|root@host|
+func parseZyppHistory(content string) []pkg.HistoryEntry {
+	entries := make([]pkg.HistoryEntry, 0)
+	var current *pkg.HistoryEntry
+
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "|")
+		if len(fields) < 3 {
+			continue
+		}
+
+		action := strings.TrimSpace(fields[1])
+		switch action {
+		case "command":
+			details := ""
+			if len(fields) > 3 {
+				details = unquoteZyppCommand(fields[3])
+			}
+			entries = append(entries, pkg.HistoryEntry{
+				Timestamp: parseZyppHistoryTime(fields[0]),
+				Success:   true,
+				Details:   details,
+			})
+			current = &entries[len(entries)-1]
+
+		case "install", "remove":
+			// Packages changed by a program that logged no command line
+			if current == nil {
+				entries = append(entries, pkg.HistoryEntry{
+					Timestamp: parseZyppHistoryTime(fields[0]),
+					Success:   true,
+				})
+				current = &entries[len(entries)-1]
+			}
+			operation := pkg.HistoryInstall
+			if action == "remove" {
+				operation = pkg.HistoryRemove
+			}
+			current.AddOperation(operation)
+			current.AddPackage(strings.TrimSpace(fields[2]))
+		}
+	}
+
+	changed := make([]pkg.HistoryEntry, 0, len(entries))
+	for _, entry := range entries {
+		if len(entry.Packages) > 0 {
+			changed = append(changed, entry)
+		}
+	}
+	return changed
+}
+
+// unquoteZyppCommand converts a command line logged by libzypp, such as
+// "'zypper' 'install' 'nvidia-video-G06'", to "zypper install nvidia-video-G06".
+func unquoteZyppCommand(command string) string {
+	args := strings.Split(strings.TrimSpace(command), "' '")
+	for i, arg := range args {
+		args[i] = strings.Trim(arg, "'")
+	}
+	return strings.Join(args, " ")
+}
+
+// parseZyppHistoryTime parses a timestamp of the libzypp history log, in the
+// local time zone. It returns 0 if the timestamp cannot be parsed.
+func parseZyppHistoryTime(value string) int64 {
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", strings.TrimSpace(value), time.Local)
+	if err != nil {
+		return 0
+	}
+	return t.Unix()
+}
+
+// Size summaries of a zypper transaction, such as "Overall download size:
+// 250.3 MiB. Already cached: 0 B. After the operation, additional 800.5 MiB
+// will be used."
+var (
+	zypperDownloadSizeRe = regexp.MustCompile(`Overall download size: ([\d.,]+ ?[A-Za-z]+)`)
+	zypperSpaceUsedRe    = regexp.MustCompile(`After the operation, additional ([\d.,]+ ?[A-Za-z]+) will be used`)
+	zypperSpaceFreedRe   = regexp.MustCompile(`After the operation, ([\d.,]+ ?[A-Za-z]+) will be freed`)
+)
+
+// parseZypperDryRun parses the transaction summary printed by
+// zypper install --dry-run. zypper lists the package names of each kind of
+// change under a header, without their versions; sections about patterns,
+// products, recommended packages and vendor changes are skipped.
+//
+// Example:
+//
+//	The following 2 NEW packages are going to be installed:
+//	  nvidia-compute-G06 nvidia-driver-G06-kmp-default
+//
+//	The following package is going to be REMOVED:
+//	  x11-video-nvidiaG05
+func parseZypperDryRun(output string) *pkg.Simulation {
+	sim := pkg.NewSimulation()
+	var section *[]pkg.PackageChange
+
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			section = nil
+		case strings.HasPrefix(trimmed, "The following "):
+			section = zypperDryRunSection(sim, trimmed)
+		case strings.HasPrefix(line, " ") && section != nil:
+			for _, name := range strings.Fields(trimmed) {
+				*section = append(*section, pkg.PackageChange{Name: name})
+			}
+		default:
+			section = nil
+		}
+	}
+
+	if m := zypperDownloadSizeRe.FindStringSubmatch(output); m != nil {
+		sim.DownloadSize, _ = pkg.ParseSize(m[1])
+	}
+	if m := zypperSpaceUsedRe.FindStringSubmatch(output); m != nil {
+		sim.InstallSize, _ = pkg.ParseSize(m[1])
+	} else if m := zypperSpaceFreedRe.FindStringSubmatch(output); m != nil {
+		freed, _ := pkg.ParseSize(m[1])
+		sim.InstallSize = -freed
+	}
+
+	return sim
+}
+
+// zypperDryRunSection returns the change list of the simulation a section
+// header of the transaction summary adds to, or nil for skipped sections.
+func zypperDryRunSection(sim *pkg.Simulation, header string) *[]pkg.PackageChange {
+	if !strings.Contains(header, " package") {
+		return nil
+	}
+	switch {
+	case strings.Contains(header, "NEW package"):
+		return &sim.Install
+	case strings.HasSuffix(header, "going to be upgraded:"):
+		return &sim.Upgrade
+	case strings.HasSuffix(header, "going to be REMOVED:"):
+		return &sim.Remove
+	case strings.HasSuffix(header, "going to be downgraded:"):
+		return &sim.Downgrade
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,35 @@
+package zypper
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// Repository priorities. Lower values win, and repositories get 99 by
+// default.
+const (
+	preferredPriority = 90
+	defaultPriority   = 99
+)
+
+// PreferRepository gives the repository priority 90 with zypper modifyrepo
+// --priority. Priorities apply to whole repositories, so the package globs
+// are not used.
+func (m *Manager) PreferRepository(ctx context.Context, repo pkg.Repository, packages []string) error {
+	return m.SetRepositoryPriority(ctx, repo.Name, preferredPriority)
+}
+
+// RemoveRepositoryPreference restores the default priority of the
+// repository.
+func (m *Manager) RemoveRepositoryPreference(ctx context.Context, name string) error {
+	err := m.SetRepositoryPriority(ctx, name, defaultPriority)
+	if errors.Is(err, pkg.ErrRepositoryNotFound) {
+		return nil
+	}
+	return err
+}
+
+// Ensure Manager implements pkg.PreferenceManager interface.
+var _ pkg.PreferenceManager = (*Manager)(nil)
@@ -0,0 +1,89 @@
+package zypper
+
+import (
+	"context"
+	"encoding/xml"
+	"path"
+	"regexp"
+	"strconv"
+
+	igorexec "github.com/tungetti/igor/internal/exec"
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// installLabel matches the label of the progress of a package installation:
+// "(2/3) Installing: nvidia-gl-G06-550.54.14-lp155.1.x86_64"
+var installLabel = regexp.MustCompile(`^\((\d+)/(\d+)\) (?:Installing|Upgrading|Reinstalling|Downgrading): (\S+)`)
+
+// xmlProgress is a <download> or <progress> element written by zypper
+// --xmlout. An element without percent or value marks the end of the
+// download or of the step.
+type xmlProgress struct {
+	XMLName xml.Name
+	URL     string `xml:"url,attr"`
+	Percent string `xml:"percent,attr"`
+	Name    string `xml:"name,attr"`
+	Value   string `xml:"value,attr"`
+}
+
+// executeElevatedWithProgress runs zypper with root privileges and its
+// output in XML, parsed into progress passed to report. --xmlout is a
+// global option, so it goes before the command in args.
+func (m *Manager) executeElevatedWithProgress(ctx context.Context, report func(pkg.Progress), args ...string) *igorexec.Result {
+	args = append([]string{"--xmlout"}, args...)
+
+	w := pkg.NewProgressWriter(parseProgressLine, report)
+	result := m.executor.StreamElevated(ctx, w, nil, "zypper", args...)
+	w.Flush()
+	return result
+}
+
+// parseProgressLine parses a line of the XML output of zypper install,
+// which writes one element per line:
+//
+//	<download url="https://.../nvidia-gl-G06-550.54.14-lp155.1.x86_64.rpm" percent="42" rate="1048576"/>
+//	<progress id="12" name="(2/3) Installing: nvidia-gl-G06-550.54.14-lp155.1.x86_64" value="50"/>
+//
+// zypper does not number the downloads, so their percentage is the one of
+// the package file; the installation percentage spans all the packages.
+func parseProgressLine(line string) (pkg.Progress, bool) {
+	var element xmlProgress
+	if err := xml.Unmarshal([]byte(line), &element); err != nil {
+		return pkg.Progress{}, false
+	}
+
+	switch element.XMLName.Local {
+	case "download":
+		percent, err := strconv.ParseFloat(element.Percent, 64)
+		if err != nil || percent < 0 {
+			return pkg.Progress{}, false
+		}
+		return pkg.Progress{
+			Phase:   pkg.ProgressDownload,
+			Package: pkg.RPMPackageName(path.Base(element.URL)),
+			Percent: percent,
+		}, true
+
+	case "progress":
+		match := installLabel.FindStringSubmatch(element.Name)
+		if match == nil {
+			return pkg.Progress{}, false
+		}
+		value, err := strconv.ParseFloat(element.Value, 64)
+		if err != nil {
+			return pkg.Progress{}, false
+		}
+		done, _ := strconv.Atoi(match[1])
+		total, _ := strconv.Atoi(match[2])
+		if done < 1 || total < 1 {
+			return pkg.Progress{}, false
+		}
+		return pkg.Progress{
+			Phase:   pkg.ProgressInstall,
+			Package: pkg.RPMPackageName(match[3]),
+			Percent: (float64(done-1) + value/100) / float64(total) * 100,
+		}, true
+	}
+
+	return pkg.Progress{}, false
+}
@@ -36,10 +36,15 @@ func (m *Manager) AddRepository(ctx context.Context, repo pkg.Repository) error
 		args = append(args, "--disable")
 	}
 
-	if repo.GPGKey == "" {
+	if repo.GPGKey == "" || repo.Trusted {
 		args = append(args, "--no-gpgcheck")
 	}
 
+	// A directory of package files without metadata is given its type explicitly
+	if repo.Type == "plaindir" {
+		args = append(args, "--type", repo.Type)
+	}
+
 	args = append(args, repo.URL, repo.Name)
 
 	result := m.executor.ExecuteElevated(ctx, "zypper", args...)
@@ -0,0 +1,67 @@
+package zypper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// SimulateInstall resolves the installation of the packages with
+// zypper install --dry-run. zypper does not list versions in the summary,
+// so only package names and the total sizes are known; the installed size of
+// each installed package is read with zypper info. zypper resolves install
+// transactions only when run as root.
+func (m *Manager) SimulateInstall(ctx context.Context, opts pkg.InstallOptions, packages ...string) (*pkg.Simulation, error) {
+	if len(packages) == 0 {
+		return pkg.NewSimulation(), nil
+	}
+
+	args := make([]string, 0, len(packages)+8)
+	for _, arg := range m.buildInstallArgs(opts, packages) {
+		args = append(args, arg)
+		if arg == "install" {
+			args = append(args, "--dry-run")
+		}
+	}
+	result := m.executor.Execute(ctx, "zypper", args...)
+	if result.Failed() {
+		combined := result.StderrString() + result.StdoutString()
+		if strings.Contains(combined, "not found in package names") || strings.Contains(combined, "No provider of") {
+			return nil, pkg.Wrap(pkg.ErrPackageNotFound, fmt.Errorf("zypper install --dry-run failed: %s", combined))
+		}
+		return nil, pkg.Wrap(pkg.ErrSimulationFailed, fmt.Errorf("zypper install --dry-run failed (exit code %d): %s", result.ExitCode, combined))
+	}
+
+	sim := parseZypperDryRun(result.StdoutString())
+	m.addInstalledSizes(ctx, sim)
+	return sim, nil
+}
+
+// addInstalledSizes fills in the installed size of the packages a
+// simulation installs, upgrades or downgrades, as printed by zypper info
+// for the candidate versions. Packages whose size cannot be read are left
+// unknown.
+func (m *Manager) addInstalledSizes(ctx context.Context, sim *pkg.Simulation) {
+	var names []string
+	for _, changes := range [][]pkg.PackageChange{sim.Install, sim.Upgrade, sim.Downgrade} {
+		for _, change := range changes {
+			names = append(names, change.Name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+
+	result := m.executor.Execute(ctx, "zypper", append([]string{"--non-interactive", "info"}, names...)...)
+	sizes := parseZypperInstalledSizes(result.StdoutString())
+	for _, changes := range [][]pkg.PackageChange{sim.Install, sim.Upgrade, sim.Downgrade} {
+		for i := range changes {
+			changes[i].InstalledSize = sizes[changes[i].Name]
+		}
+	}
+}
+
+// Ensure Manager implements pkg.SimulationManager interface.
+var _ pkg.SimulationManager = (*Manager)(nil)
@@ -0,0 +1,53 @@
+package zypper
+
+import (
+	"context"
+
+	"github.com/tungetti/igor/internal/pkg"
+)
+
+// BeginTransaction starts a transaction.
+// Until it is committed or rolled back, Install and Remove only queue the
+// packages, which are then installed and removed together by a single
+// zypper install call.
+func (m *Manager) BeginTransaction(ctx context.Context) error {
+	return m.tx.Begin(nil)
+}
+
+// CommitTransaction runs the queued operations as one zypper transaction.
+// zypper install removes the packages prefixed with "!", and resolves and
+// commits all the packages of the call together, so nothing is changed if
+// one of them fails. The options of queued removals are not applied.
+func (m *Manager) CommitTransaction(ctx context.Context) error {
+	state, err := m.tx.End()
+	if err != nil {
+		return err
+	}
+	if len(state.Install) == 0 && len(state.Remove) == 0 {
+		return nil
+	}
+
+	packages := append([]string{}, state.Install...)
+	for _, name := range state.Remove {
+		packages = append(packages, "!"+name)
+	}
+
+	// The transaction has ended, so Install runs zypper right away.
+	return m.Install(ctx, state.Options, packages...)
+}
+
+// RollbackTransaction discards the queued operations.
+// Nothing has been installed or removed before the commit, so there is
+// nothing to revert.
+func (m *Manager) RollbackTransaction(ctx context.Context) error {
+	_, err := m.tx.End()
+	return err
+}
+
+// InTransaction returns true if a transaction is in progress.
+func (m *Manager) InTransaction() bool {
+	return m.tx.Active()
+}
+
+// Ensure Manager implements pkg.TransactionalManager interface.
+var _ pkg.TransactionalManager = (*Manager)(nil)
@@ -19,6 +19,8 @@ import (
 type Manager struct {
 	executor  igorexec.Executor
 	privilege *privilege.Manager
+	locks     *pkg.LockChecker
+	tx        pkg.Transaction
 }
 
 // NewManager creates a new Zypper package manager.
@@ -28,6 +30,7 @@ func NewManager(executor igorexec.Executor, priv *privilege.Manager) *Manager {
 	return &Manager{
 		executor:  executor,
 		privilege: priv,
+		locks:     pkg.NewLockChecker(executor, "zypp", zypperLockFiles...),
 	}
 }
 
@@ -48,14 +51,25 @@ func (m *Manager) IsAvailable() bool {
 }
 
 // Install installs one or more packages using zypper install.
-// Uses --non-interactive for unattended operation.
+// Uses --non-interactive for unattended operation. During a transaction the
+// packages are queued until it is committed. If opts.Progress is set, the
+// download and installation progress is read from the XML output of zypper
+// as it runs.
 func (m *Manager) Install(ctx context.Context, opts pkg.InstallOptions, packages ...string) error {
 	if len(packages) == 0 {
 		return nil
 	}
+	if m.tx.QueueInstall(opts, packages...) {
+		return nil
+	}
 
 	args := m.buildInstallArgs(opts, packages)
-	result := m.executor.ExecuteElevated(ctx, "zypper", args...)
+	var result *igorexec.Result
+	if opts.Progress != nil {
+		result = m.executeElevatedWithProgress(ctx, opts.Progress, args...)
+	} else {
+		result = m.executor.ExecuteElevated(ctx, "zypper", args...)
+	}
 
 	if result.Failed() {
 		stderr := result.StderrString()
@@ -75,8 +89,9 @@ func (m *Manager) Install(ctx context.Context, opts pkg.InstallOptions, packages
 			}
 			return pkg.Wrap(pkg.ErrPackageNotFound, fmt.Errorf("zypper install failed: %s", combined))
 		}
-		if strings.Contains(stderr, "System management is locked") ||
-			strings.Contains(stderr, "another zypper is running") {
+		// With --xmlout, the error messages are written to stdout
+		if strings.Contains(combined, "System management is locked") ||
+			strings.Contains(combined, "another zypper is running") {
 			return pkg.Wrap(pkg.ErrLockAcquireFailed, fmt.Errorf("zypper install failed: %s", stderr))
 		}
 		return pkg.Wrap(pkg.ErrInstallFailed, fmt.Errorf("zypper install failed (exit code %d): %s", result.ExitCode, combined))
@@ -101,17 +116,28 @@ func (m *Manager) buildInstallArgs(opts pkg.InstallOptions, packages []string) [
 	if opts.SkipVerify {
 		args = append(args, "--no-gpg-checks")
 	}
+	// Without refreshing, the other repositories are not contacted at all
+	if opts.Repository != "" {
+		args = append([]string{"--non-interactive", "--no-refresh"}, args[1:]...)
+		args = append(args, "--repo", opts.Repository)
+	}
 
 	args = append(args, packages...)
 	return args
 }
 
 // Remove removes one or more packages from the system.
-// Uses --non-interactive for unattended operation.
+// Uses --non-interactive for unattended operation. During a transaction the
+// packages are queued until it is committed. If opts.Progress is set, the
+// download and installation progress is read from the XML output of zypper
+// as it runs.
 func (m *Manager) Remove(ctx context.Context, opts pkg.RemoveOptions, packages ...string) error {
 	if len(packages) == 0 {
 		return nil
 	}
+	if m.tx.QueueRemove(packages...) {
+		return nil
+	}
 
 	args := []string{"--non-interactive", "remove"}
 
@@ -150,12 +176,16 @@ func (m *Manager) Remove(ctx context.Context, opts pkg.RemoveOptions, packages .
 }
 
 // Update refreshes the package database using zypper refresh.
+// If opts.Repository is set, only that repository is refreshed.
 func (m *Manager) Update(ctx context.Context, opts pkg.UpdateOptions) error {
 	args := []string{"--non-interactive", "refresh"}
 
 	if opts.ForceRefresh {
 		args = append(args, "--force")
 	}
+	if opts.Repository != "" {
+		args = append(args, opts.Repository)
+	}
 
 	result := m.executor.ExecuteElevated(ctx, "zypper", args...)
 
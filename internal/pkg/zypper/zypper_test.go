@@ -3,6 +3,7 @@ package zypper
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -1251,3 +1252,588 @@ func TestManager_SetRepositoryRefresh_NotFound(t *testing.T) {
 	require.Error(t, err)
 	assert.ErrorIs(t, err, pkg.ErrRepositoryNotFound)
 }
+
+// =============================================================================
+// Hold Tests
+// =============================================================================
+
+func TestManager_Hold(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	err := mgr.Hold(context.Background(), "nvidia-driver-G06", "nvidia-video-G06")
+	require.NoError(t, err)
+
+	assert.True(t, mockExec.WasCalledWith("zypper", "--non-interactive", "addlock", "nvidia-driver-G06", "nvidia-video-G06"))
+	assert.True(t, mockExec.LastCall().Elevated)
+}
+
+func TestManager_Hold_Failure(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("zypper", exec.FailureResult(1, "System management is locked"))
+
+	err := mgr.Hold(context.Background(), "nvidia-driver-G06")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, pkg.ErrHoldFailed)
+}
+
+func TestManager_Hold_EmptyPackages(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	require.NoError(t, mgr.Hold(context.Background()))
+	require.NoError(t, mgr.Unhold(context.Background()))
+	assert.Equal(t, 0, mockExec.CallCount())
+}
+
+func TestManager_Unhold(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	err := mgr.Unhold(context.Background(), "nvidia-driver-G06")
+	require.NoError(t, err)
+
+	assert.True(t, mockExec.WasCalledWith("zypper", "--non-interactive", "removelock", "nvidia-driver-G06"))
+	assert.True(t, mockExec.LastCall().Elevated)
+}
+
+func TestManager_ListHeld(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("zypper", exec.SuccessResult(`
+# | Name              | Type    | Repository
+--+-------------------+---------+-----------
+1 | nvidia-driver-G06 | package | (any)
+2 | kernel-default    | package | (any)
+`))
+
+	held, err := mgr.ListHeld(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nvidia-driver-G06", "kernel-default"}, held)
+	assert.False(t, mockExec.LastCall().Elevated)
+}
+
+func TestParseZypperLocks_NoLocks(t *testing.T) {
+	assert.Empty(t, parseZypperLocks("There are no package locks defined.\n"))
+}
+
+// =============================================================================
+// Lock Tests
+// =============================================================================
+
+func TestManager_LockHolder(t *testing.T) {
+	mgr, mockExec := setupTest()
+	// cat returns the PID for zypp.pid and confirms the process is running
+	mockExec.SetResponse("cat", exec.SuccessResult("4242\n"))
+
+	holder, err := mgr.LockHolder(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, holder)
+	assert.Equal(t, 4242, holder.PID)
+	assert.Equal(t, "/var/run/zypp.pid", holder.Path)
+	assert.True(t, mockExec.WasCalledWith("cat", "/var/run/zypp.pid"))
+	assert.True(t, mockExec.WasCalledWith("cat", "/proc/4242/comm"))
+
+	err = mgr.AcquireLock(context.Background())
+	require.Error(t, err)
+	assert.ErrorIs(t, err, pkg.ErrLockAcquireFailed)
+	assert.Contains(t, err.Error(), "zypp lock is held by")
+}
+
+func TestManager_IsLocked_Free(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("cat", exec.FailureResult(1, "No such file or directory"))
+
+	locked, err := mgr.IsLocked(context.Background())
+	require.NoError(t, err)
+	assert.False(t, locked)
+	assert.False(t, mockExec.WasCalled("find"))
+	assert.NoError(t, mgr.WaitForLock(context.Background()))
+	assert.NoError(t, mgr.ReleaseLock(context.Background()))
+}
+
+// =============================================================================
+// History Tests
+// =============================================================================
+
+const testZyppHistory = `# 2024-01-10 09:00:00 nvidia-video-G06-545.29.06-lp156.1.x86_64.rpm installed ok
+# Additional rpm output:
+# warning: /etc/modprobe.d/nvidia.conf created as /etc/modprobe.d/nvidia.conf.rpmnew
+#
+2024-01-10 08:59:50|command|root@host|'zypper' 'refresh'|
+2024-01-10 09:00:00|command|root@host|'zypper' 'install' '-y' 'nvidia-video-G06'|
+2024-01-10 09:00:12|install|nvidia-video-G06|545.29.06-lp156.1|x86_64|root@host|nvidia|0123abcd|
+2024-01-10 09:00:13|remove |nvidia-gl-G05|535.154.05-lp155.1|x86_64|root@host|
+2024-01-15 10:23:45|radd   |nvidia|https://download.nvidia.com/opensuse/leap/15.6|
+2024-01-15 10:30:00|command|root@host|'/usr/sbin/packagekitd'|
+2024-01-15 10:30:10|install|nvidia-video-G06|550.54.14-lp156.1|x86_64||nvidia|4567cdef|
+`
+
+func TestParseZyppHistory(t *testing.T) {
+	entries := parseZyppHistory(testZyppHistory)
+	require.Len(t, entries, 2)
+
+	assert.True(t, entries[0].Success)
+	assert.Equal(t, "zypper install -y nvidia-video-G06", entries[0].Details)
+	assert.Equal(t, "install, remove", entries[0].Operation)
+	assert.Equal(t, []string{"nvidia-video-G06", "nvidia-gl-G05"}, entries[0].Packages)
+	assert.Equal(t, time.Date(2024, 1, 10, 9, 0, 0, 0, time.Local).Unix(), entries[0].Timestamp)
+
+	assert.Equal(t, "/usr/sbin/packagekitd", entries[1].Details)
+	assert.Equal(t, []string{"nvidia-video-G06"}, entries[1].Packages)
+}
+
+func TestParseZyppHistory_NoCommand(t *testing.T) {
+	entries := parseZyppHistory("2024-01-10 09:00:12|install|nvidia-video-G06|545.29.06-lp156.1|x86_64|root@host|nvidia|0123abcd|\n")
+	require.Len(t, entries, 1)
+	assert.Empty(t, entries[0].Details)
+	assert.Equal(t, []string{"nvidia-video-G06"}, entries[0].Packages)
+}
+
+func TestManager_History(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("cat", exec.SuccessResult(testZyppHistory))
+
+	entries, err := mgr.History(context.Background(), 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "2", entries[0].ID)
+	assert.Equal(t, "1", entries[1].ID)
+	assert.True(t, mockExec.WasCalledWith("cat", "/var/log/zypp/history"))
+	assert.True(t, mockExec.LastCall().Elevated)
+}
+
+func TestManager_History_ReadFailure(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("cat", exec.FailureResult(1, "cat: /var/log/zypp/history: Permission denied"))
+
+	_, err := mgr.History(context.Background(), 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Permission denied")
+}
+
+func TestManager_Undo_NotSupported(t *testing.T) {
+	mgr, _ := setupTest()
+
+	assert.ErrorIs(t, mgr.Undo(context.Background(), "1"), pkg.ErrUnsupportedOperation)
+	assert.ErrorIs(t, mgr.Redo(context.Background(), "1"), pkg.ErrUnsupportedOperation)
+}
+
+// =============================================================================
+// Transaction Tests
+// =============================================================================
+
+func TestManager_Transaction_Commit(t *testing.T) {
+	mgr, mockExec := setupTest()
+	ctx := context.Background()
+
+	require.NoError(t, mgr.BeginTransaction(ctx))
+	assert.True(t, mgr.InTransaction())
+
+	opts := pkg.NonInteractiveInstallOptions()
+	require.NoError(t, mgr.Install(ctx, opts, "nvidia-video-G06", "nvidia-gl-G06"))
+	require.NoError(t, mgr.Install(ctx, opts, "nvidia-compute-G06"))
+	require.NoError(t, mgr.Remove(ctx, pkg.DefaultRemoveOptions(), "nvidia-video-G05"))
+	// Nothing runs until the transaction is committed
+	assert.Equal(t, 0, mockExec.CallCount())
+
+	require.NoError(t, mgr.CommitTransaction(ctx))
+	assert.False(t, mgr.InTransaction())
+
+	require.Equal(t, 1, mockExec.CallCount())
+	assert.True(t, mockExec.WasCalledWith("zypper", "--non-interactive", "install",
+		"nvidia-video-G06", "nvidia-gl-G06", "nvidia-compute-G06", "!nvidia-video-G05"))
+}
+
+func TestManager_Transaction_CommitEmpty(t *testing.T) {
+	mgr, mockExec := setupTest()
+	ctx := context.Background()
+
+	require.NoError(t, mgr.BeginTransaction(ctx))
+	require.NoError(t, mgr.CommitTransaction(ctx))
+	assert.Equal(t, 0, mockExec.CallCount())
+}
+
+func TestManager_Transaction_CommitFailure(t *testing.T) {
+	mgr, mockExec := setupTest()
+	ctx := context.Background()
+	mockExec.SetResponse("zypper", exec.FailureResult(104, "'nvidia-compute-G07' not found in package names. Trying capabilities.\nNo provider of 'nvidia-compute-G07' found."))
+
+	require.NoError(t, mgr.BeginTransaction(ctx))
+	require.NoError(t, mgr.Install(ctx, pkg.InstallOptions{}, "nvidia-video-G06", "nvidia-compute-G07"))
+	err := mgr.CommitTransaction(ctx)
+
+	assert.ErrorIs(t, err, pkg.ErrPackageNotFound)
+	assert.False(t, mgr.InTransaction())
+}
+
+func TestManager_Transaction_Rollback(t *testing.T) {
+	mgr, mockExec := setupTest()
+	ctx := context.Background()
+
+	require.NoError(t, mgr.BeginTransaction(ctx))
+	require.NoError(t, mgr.Install(ctx, pkg.InstallOptions{}, "nvidia-video-G06"))
+	require.NoError(t, mgr.RollbackTransaction(ctx))
+
+	assert.False(t, mgr.InTransaction())
+	assert.Equal(t, 0, mockExec.CallCount())
+}
+
+func TestManager_Transaction_NotInProgress(t *testing.T) {
+	mgr, _ := setupTest()
+	ctx := context.Background()
+
+	assert.ErrorIs(t, mgr.CommitTransaction(ctx), pkg.ErrTransactionFailed)
+	assert.ErrorIs(t, mgr.RollbackTransaction(ctx), pkg.ErrTransactionFailed)
+
+	require.NoError(t, mgr.BeginTransaction(ctx))
+	assert.ErrorIs(t, mgr.BeginTransaction(ctx), pkg.ErrTransactionFailed)
+}
+
+// =============================================================================
+// Bundle Tests
+// =============================================================================
+
+// setupBundleTest creates a manager whose mktemp creates the bundle root.
+func setupBundleTest() (*Manager, *exec.MockExecutor) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("mktemp", exec.SuccessResult("/tmp/igor-bundle.Xy12Ab34Cd\n"))
+	return mgr, mockExec
+}
+
+func TestManager_DownloadBundle(t *testing.T) {
+	mgr, mockExec := setupBundleTest()
+	ctx := context.Background()
+
+	err := mgr.DownloadBundle(ctx, "/srv/bundle", "nvidia-video-G06", "nvidia-gl-G06")
+	require.NoError(t, err)
+
+	calls := mockExec.Calls()
+	require.Len(t, calls, 5)
+	assert.Equal(t, "mktemp", calls[0].Command)
+	assert.Equal(t, "sh", calls[1].Command)
+	assert.Equal(t, "/tmp/igor-bundle.Xy12Ab34Cd", calls[1].Args[len(calls[1].Args)-1])
+	assert.Equal(t, "zypper", calls[2].Command)
+	assert.Equal(t, []string{
+		"--root", "/tmp/igor-bundle.Xy12Ab34Cd", "--non-interactive", "--gpg-auto-import-keys",
+		"install", "--download-only", "nvidia-video-G06", "nvidia-gl-G06",
+	}, calls[2].Args)
+	assert.Equal(t, "cp", calls[3].Command)
+	assert.Equal(t, []string{"-a", "/tmp/igor-bundle.Xy12Ab34Cd/var/cache/zypp/packages/.", "/srv/bundle"}, calls[3].Args)
+	assert.Equal(t, "rm", calls[4].Command)
+	assert.Equal(t, []string{"-rf", "/tmp/igor-bundle.Xy12Ab34Cd"}, calls[4].Args)
+}
+
+func TestManager_DownloadBundle_Failure(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("download fails", func(t *testing.T) {
+		mgr, mockExec := setupBundleTest()
+		mockExec.SetResponse("zypper", exec.FailureResult(104, "No provider of 'nvidia-video-G99' found."))
+
+		err := mgr.DownloadBundle(ctx, "/srv/bundle", "nvidia-video-G99")
+		assert.ErrorIs(t, err, pkg.ErrBundleFailed)
+		assert.False(t, mockExec.WasCalled("cp"))
+		assert.True(t, mockExec.WasCalledWith("rm", "-rf", "/tmp/igor-bundle.Xy12Ab34Cd"))
+	})
+
+	t.Run("locked", func(t *testing.T) {
+		mgr, mockExec := setupBundleTest()
+		mockExec.SetResponse("zypper", exec.FailureResult(7, "System management is locked by the application with pid 4242 (zypper)."))
+
+		err := mgr.DownloadBundle(ctx, "/srv/bundle", "nvidia-video-G06")
+		assert.ErrorIs(t, err, pkg.ErrLockAcquireFailed)
+	})
+
+	t.Run("bundle root fails", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		mockExec.SetResponse("mktemp", exec.FailureResult(1, "mktemp: failed to create directory"))
+
+		err := mgr.DownloadBundle(ctx, "/srv/bundle", "nvidia-video-G06")
+		assert.ErrorIs(t, err, pkg.ErrBundleFailed)
+		assert.False(t, mockExec.WasCalled("zypper"))
+	})
+
+	t.Run("repositories not copied", func(t *testing.T) {
+		mgr, mockExec := setupBundleTest()
+		mockExec.SetResponse("sh", exec.FailureResult(1, "cp: cannot stat '/etc/products.d': No such file or directory"))
+
+		err := mgr.DownloadBundle(ctx, "/srv/bundle", "nvidia-video-G06")
+		assert.ErrorIs(t, err, pkg.ErrBundleFailed)
+		assert.False(t, mockExec.WasCalled("zypper"))
+	})
+
+	t.Run("packages not copied", func(t *testing.T) {
+		mgr, mockExec := setupBundleTest()
+		mockExec.SetResponse("cp", exec.FailureResult(1, "cp: cannot create directory '/srv/bundle': Permission denied"))
+
+		err := mgr.DownloadBundle(ctx, "/srv/bundle", "nvidia-video-G06")
+		assert.ErrorIs(t, err, pkg.ErrBundleFailed)
+	})
+}
+
+func TestManager_BundleRepository(t *testing.T) {
+	mgr, mockExec := setupTest()
+	ctx := context.Background()
+
+	repo := mgr.BundleRepository("/srv/bundle")
+	require.NoError(t, mgr.AddRepository(ctx, repo))
+	assert.Equal(t, []string{
+		"addrepo", "--refresh", "--no-gpgcheck", "--type", "plaindir", "file:///srv/bundle", "igor-bundle",
+	}, mockExec.LastCall().Args)
+}
+
+func TestManager_Repository_Restricted(t *testing.T) {
+	mgr, mockExec := setupTest()
+	ctx := context.Background()
+
+	require.NoError(t, mgr.Update(ctx, pkg.UpdateOptions{Repository: "igor-bundle"}))
+	assert.Equal(t, []string{"--non-interactive", "refresh", "igor-bundle"}, mockExec.LastCall().Args)
+
+	require.NoError(t, mgr.Install(ctx, pkg.InstallOptions{Repository: "igor-bundle"}, "nvidia-video-G06"))
+	assert.Equal(t, []string{"--non-interactive", "--no-refresh", "install", "--repo", "igor-bundle", "nvidia-video-G06"}, mockExec.LastCall().Args)
+}
+
+// =============================================================================
+// Simulation Tests
+// =============================================================================
+
+const testZypperDryRun = `Loading repository data...
+Reading installed packages...
+Resolving package dependencies...
+
+The following 2 NEW packages are going to be installed:
+  nvidia-compute-G06 nvidia-driver-G06-kmp-default
+
+The following NEW pattern is going to be installed:
+  nvidia
+
+The following package is going to be upgraded:
+  libnvidia-egl-wayland1
+
+The following 2 packages are going to be REMOVED:
+  nvidia-gfxG05-kmp-default x11-video-nvidiaG05
+
+The following package is going to be downgraded:
+  libglvnd
+
+The following 2 recommended packages were automatically selected:
+  nvidia-compute-G06 nvidia-driver-G06-kmp-default
+
+2 new packages to install, 1 to upgrade, 1 to downgrade, 2 to remove.
+Overall download size: 250.5 MiB. Already cached: 0 B. After the operation, additional 800.0 MiB will be used.
+Continue? [y/n/v/...? shows all options] (y): y
+`
+
+func TestParseZypperDryRun(t *testing.T) {
+	sim := parseZypperDryRun(testZypperDryRun)
+
+	assert.Equal(t, []pkg.PackageChange{{Name: "nvidia-compute-G06"}, {Name: "nvidia-driver-G06-kmp-default"}}, sim.Install)
+	assert.Equal(t, []pkg.PackageChange{{Name: "libnvidia-egl-wayland1"}}, sim.Upgrade)
+	assert.Equal(t, []pkg.PackageChange{{Name: "nvidia-gfxG05-kmp-default"}, {Name: "x11-video-nvidiaG05"}}, sim.Remove)
+	assert.Equal(t, []pkg.PackageChange{{Name: "libglvnd"}}, sim.Downgrade)
+	assert.Equal(t, int64(262668288), sim.DownloadSize)
+	assert.Equal(t, int64(800<<20), sim.InstallSize)
+
+	freed := parseZypperDryRun("The following package is going to be REMOVED:\n  foo\n\nAfter the operation, 1.0 MiB will be freed.\n")
+	assert.Equal(t, int64(-(1 << 20)), freed.InstallSize)
+	assert.True(t, parseZypperDryRun("Nothing to do.\n").IsEmpty())
+}
+
+func TestManager_SimulateInstall(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("zypper", exec.SuccessResult(testZypperDryRun))
+
+	sim, err := mgr.SimulateInstall(context.Background(), pkg.NonInteractiveInstallOptions(), "nvidia-driver-G06-kmp-default")
+	require.NoError(t, err)
+
+	call := mockExec.Calls()[0]
+	assert.Equal(t, []string{"--non-interactive", "install", "--dry-run", "nvidia-driver-G06-kmp-default"}, call.Args)
+	assert.False(t, call.Elevated)
+	assert.Len(t, sim.Remove, 2)
+
+	// The installed sizes are read for the installed packages only
+	assert.True(t, mockExec.WasCalledWith("zypper", "--non-interactive", "info",
+		"nvidia-compute-G06", "nvidia-driver-G06-kmp-default", "libnvidia-egl-wayland1", "libglvnd"))
+}
+
+func TestParseZypperInstalledSizes(t *testing.T) {
+	output := `Loading repository data...
+Reading installed packages...
+
+
+Information for package nvidia-compute-G06:
+-------------------------------------------
+Repository     : NVIDIA
+Name           : nvidia-compute-G06
+Version        : 550.120-1
+Installed Size : 120.5 MiB
+Installed      : No
+
+Information for package libglvnd:
+---------------------------------
+Repository     : Main Repository (OSS)
+Name           : libglvnd
+Version        : 1.7.0-1.2
+Installed Size : 512.0 KiB
+Installed      : Yes
+`
+	assert.Equal(t, map[string]int64{
+		"nvidia-compute-G06": int64(120.5 * (1 << 20)),
+		"libglvnd":           512 << 10,
+	}, parseZypperInstalledSizes(output))
+}
+
+func TestManager_SimulateInstall_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("package not found", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		mockExec.SetResponse("zypper", exec.FailureResult(104, "'nvidia-999' not found in package names. Trying capabilities."))
+		_, err := mgr.SimulateInstall(ctx, pkg.NonInteractiveInstallOptions(), "nvidia-999")
+		assert.ErrorIs(t, err, pkg.ErrPackageNotFound)
+	})
+
+	t.Run("not root", func(t *testing.T) {
+		mgr, mockExec := setupTest()
+		mockExec.SetResponse("zypper", exec.FailureResult(5, "Root privileges are required for installing or uninstalling packages."))
+		_, err := mgr.SimulateInstall(ctx, pkg.NonInteractiveInstallOptions(), "nvidia-video-G06")
+		assert.ErrorIs(t, err, pkg.ErrSimulationFailed)
+	})
+}
+
+func testCUDAKeyPin() pkg.KeyPin {
+	return pkg.KeyPin{
+		Name:         "nvidia-cuda",
+		Fingerprints: []string{"EB693B3035CD5710E231E123A4B469963BF863CC"},
+		Retired: []pkg.RetiredKey{
+			{Fingerprint: "AE09FE4BBD223A84B2CCFCE3F60F4B3D7FA2AF80", ReplacedBy: "EB693B3035CD5710E231E123A4B469963BF863CC", Date: "2022-04-27"},
+		},
+	}
+}
+
+func TestManager_ImportPinnedGPGKey(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetDefaultResponse(exec.SuccessResult(""))
+	mockExec.SetResponse("mktemp", exec.SuccessResult("/tmp/igor-key.abc\n"))
+	mockExec.SetResponse("gpg", exec.SuccessResult("pub:-:4096:1:A4B469963BF863CC:1650000000:::-:::scSC:\nfpr:::::::::EB693B3035CD5710E231E123A4B469963BF863CC:\n"))
+	// The retired key is still in the RPM database
+	mockExec.SetResponse("rpm", exec.SuccessResult("3bf863cc\n7fa2af80\n"))
+
+	keyURL := "https://developer.download.nvidia.com/compute/cuda/repos/rhel9/x86_64/3bf863cc.pub"
+	imp, err := mgr.ImportPinnedGPGKey(context.Background(), keyURL, testCUDAKeyPin())
+	require.NoError(t, err)
+
+	assert.Empty(t, imp.Keyring)
+	assert.Equal(t, []string{"EB693B3035CD5710E231E123A4B469963BF863CC"}, imp.Fingerprints)
+	require.Len(t, imp.Retired, 1)
+	assert.Equal(t, "AE09FE4BBD223A84B2CCFCE3F60F4B3D7FA2AF80", imp.Retired[0].Fingerprint)
+
+	// The verified file is imported, not the URL
+	assert.True(t, mockExec.WasCalledWith("rpm", "--import", "/tmp/igor-key.abc"))
+	assert.False(t, mockExec.WasCalledWith("rpm", "--import", keyURL))
+	assert.True(t, mockExec.WasCalledWith("rm", "-f", "/tmp/igor-key.abc"))
+}
+
+func TestManager_ImportPinnedGPGKey_Mismatch(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetDefaultResponse(exec.SuccessResult(""))
+	mockExec.SetResponse("mktemp", exec.SuccessResult("/tmp/igor-key.abc\n"))
+	mockExec.SetResponse("gpg", exec.SuccessResult("pub:-:4096:1:0123456789ABCDEF:1650000000:::-:::scSC:\nfpr:::::::::00000000000000000000000000000000DEADBEEF:\n"))
+
+	_, err := mgr.ImportPinnedGPGKey(context.Background(), "https://example.com/key.pub", testCUDAKeyPin())
+	require.Error(t, err)
+	assert.ErrorIs(t, err, pkg.ErrGPGKeyMismatch)
+	assert.False(t, mockExec.WasCalled("rpm"))
+}
+
+func TestManager_PreferRepository(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetResponse("zypper", exec.SuccessResult(""))
+
+	err := mgr.PreferRepository(context.Background(), pkg.Repository{Name: "nvidia"}, []string{"nvidia-*"})
+	require.NoError(t, err)
+	assert.True(t, mockExec.WasCalledWith("zypper", "modifyrepo", "--priority", "90", "nvidia"))
+}
+
+func TestManager_RemoveRepositoryPreference(t *testing.T) {
+	mgr, mockExec := setupTest()
+
+	mockExec.SetResponse("zypper", exec.SuccessResult(""))
+
+	err := mgr.RemoveRepositoryPreference(context.Background(), "nvidia")
+	require.NoError(t, err)
+	assert.True(t, mockExec.WasCalledWith("zypper", "modifyrepo", "--priority", "99", "nvidia"))
+
+	// A repository removed already has no preference left
+	mockExec.SetResponse("zypper", exec.FailureResult(3, "Repository 'nvidia' not found by its alias, number, or URI."))
+	err = mgr.RemoveRepositoryPreference(context.Background(), "nvidia")
+	assert.NoError(t, err)
+}
+
+// =============================================================================
+// Progress Tests
+// =============================================================================
+
+const testZypperXMLOutput = `<?xml version='1.0'?>
+<stream>
+<message type="info">Loading repository data...</message>
+<download url="https://download.nvidia.com/opensuse/leap/15.5/x86_64/nvidia-gl-G06-550.54.14-lp155.1.x86_64.rpm" percent="-1" rate="-1"/>
+<download url="https://download.nvidia.com/opensuse/leap/15.5/x86_64/nvidia-gl-G06-550.54.14-lp155.1.x86_64.rpm" percent="60" rate="1048576"/>
+<download url="https://download.nvidia.com/opensuse/leap/15.5/x86_64/nvidia-gl-G06-550.54.14-lp155.1.x86_64.rpm" rate="1048576" done="1"/>
+<progress id="2" name="(1/2) Installing: nvidia-gl-G06-550.54.14-lp155.1.x86_64" value="0"/>
+<progress id="2" name="(1/2) Installing: nvidia-gl-G06-550.54.14-lp155.1.x86_64" value="100"/>
+<progress id="2" name="(1/2) Installing: nvidia-gl-G06-550.54.14-lp155.1.x86_64" done="0"/>
+<progress id="3" name="(2/2) Installing: nvidia-driver-G06-kmp-default-550.54.14_k5.14.21_150500.53-lp155.1.x86_64" value="50"/>
+<progress id="4" name="Checking for file conflicts:" value="100"/>
+</stream>
+`
+
+func TestManager_Install_Progress(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("zypper", exec.SuccessResult(testZypperXMLOutput))
+
+	var reported []pkg.Progress
+	opts := pkg.NonInteractiveInstallOptions()
+	opts.Progress = func(p pkg.Progress) { reported = append(reported, p) }
+	require.NoError(t, mgr.Install(context.Background(), opts, "nvidia-driver-G06-kmp-default"))
+
+	call := mockExec.LastCall()
+	assert.True(t, call.Elevated)
+	assert.Equal(t, []string{"--xmlout", "--non-interactive", "install", "nvidia-driver-G06-kmp-default"}, call.Args)
+	assert.Equal(t, []pkg.Progress{
+		{Phase: pkg.ProgressDownload, Package: "nvidia-gl-G06", Percent: 60},
+		{Phase: pkg.ProgressInstall, Package: "nvidia-gl-G06", Percent: 0},
+		{Phase: pkg.ProgressInstall, Package: "nvidia-gl-G06", Percent: 50},
+		{Phase: pkg.ProgressInstall, Package: "nvidia-driver-G06-kmp-default", Percent: 75},
+	}, reported)
+}
+
+func TestManager_Install_ProgressLocked(t *testing.T) {
+	mgr, mockExec := setupTest()
+	mockExec.SetResponse("zypper", &exec.Result{
+		ExitCode: 7,
+		Stdout:   []byte(`<message type="error">System management is locked by the application with pid 1234 (zypper).</message>`),
+	})
+
+	opts := pkg.NonInteractiveInstallOptions()
+	opts.Progress = func(pkg.Progress) {}
+	err := mgr.Install(context.Background(), opts, "nvidia-driver-G06-kmp-default")
+	assert.ErrorIs(t, err, pkg.ErrLockAcquireFailed)
+}
+
+func TestManager_Transaction_CommitProgress(t *testing.T) {
+	mgr, mockExec := setupTest()
+	ctx := context.Background()
+	mockExec.SetResponse("zypper", exec.SuccessResult(testZypperXMLOutput))
+
+	var reported []pkg.Progress
+	opts := pkg.NonInteractiveInstallOptions()
+	opts.Progress = func(p pkg.Progress) { reported = append(reported, p) }
+
+	require.NoError(t, mgr.BeginTransaction(ctx))
+	require.NoError(t, mgr.Install(ctx, opts, "nvidia-driver-G06-kmp-default"))
+	require.NoError(t, mgr.CommitTransaction(ctx))
+
+	assert.Equal(t, "--xmlout", mockExec.LastCall().Args[0])
+	assert.Len(t, reported, 4)
+}
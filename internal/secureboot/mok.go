@@ -0,0 +1,243 @@
+package secureboot
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/tungetti/igor/internal/exec"
+)
+
+// DKMS signing configuration.
+const (
+	// DKMSConfigPath is the DKMS framework configuration written by Igor to
+	// sign the modules with the key.
+	DKMSConfigPath = "/etc/dkms/framework.conf.d/igor-mok-signing.conf"
+
+	// DKMSSignToolPath is the sign_tool script written for DKMS 2, which
+	// ignores mok_signing_key and mok_certificate.
+	DKMSSignToolPath = "/etc/dkms/igor-sign-tool.sh"
+)
+
+// Manager manages the Machine Owner Keys and their enrollment.
+type Manager struct {
+	executor exec.Executor
+}
+
+// NewManager creates a manager running commands with the executor.
+func NewManager(executor exec.Executor) *Manager {
+	return &Manager{executor: executor}
+}
+
+// FindKey returns the first key of the tool whose private key and
+// certificate both exist, and true; or the key to create and false.
+func (m *Manager) FindKey(ctx context.Context, tool Tool) (Key, bool) {
+	for _, key := range Keys(tool) {
+		if m.fileExists(ctx, key.PrivateKey) && m.fileExists(ctx, key.Certificate) {
+			return key, true
+		}
+	}
+	return DefaultKey(tool), false
+}
+
+// fileExists reports whether path is a file. The private keys are in
+// directories only root can read, so keys are only found when running as
+// root, as installations do.
+func (m *Manager) fileExists(ctx context.Context, path string) bool {
+	return m.executor.Execute(ctx, "test", "-f", path).ExitCode == 0
+}
+
+// GenerateKey creates the key with a self-signed certificate valid for 100
+// years. kmodgenca creates the akmods key when available, so that akmods can
+// read it.
+func (m *Manager) GenerateKey(ctx context.Context, tool Tool, key Key) error {
+	if tool == ToolAkmods && key == AkmodsKey &&
+		m.executor.Execute(ctx, "which", "kmodgenca").ExitCode == 0 {
+		return commandError("kmodgenca", m.executor.ExecuteElevated(ctx, "kmodgenca", "-a"))
+	}
+
+	dirs := []string{filepath.Dir(key.PrivateKey)}
+	if dir := filepath.Dir(key.Certificate); dir != dirs[0] {
+		dirs = append(dirs, dir)
+	}
+	if err := commandError("mkdir", m.executor.ExecuteElevated(ctx, "mkdir", append([]string{"-p"}, dirs...)...)); err != nil {
+		return err
+	}
+
+	result := m.executor.ExecuteElevated(ctx, "openssl", "req", "-new", "-x509",
+		"-newkey", "rsa:2048", "-nodes", "-sha256", "-days", "36500",
+		"-subj", KeySubject,
+		"-keyout", key.PrivateKey,
+		"-outform", "DER", "-out", key.Certificate)
+	if err := commandError("openssl", result); err != nil {
+		return err
+	}
+	return commandError("chmod", m.executor.ExecuteElevated(ctx, "chmod", "600", key.PrivateKey))
+}
+
+// RemoveKey removes the private key and the certificate.
+func (m *Manager) RemoveKey(ctx context.Context, key Key) error {
+	return commandError("rm", m.executor.ExecuteElevated(ctx, "rm", "-f", key.PrivateKey, key.Certificate))
+}
+
+// fingerprintPattern matches a SHA-1 fingerprint, as printed by openssl
+// ("sha1 Fingerprint=AB:CD:...") and mokutil ("SHA1 Fingerprint: ab:cd:...").
+var fingerprintPattern = regexp.MustCompile(`(?i)SHA1 Fingerprint\s*[=:]\s*((?:[0-9a-f]{2}:){19}[0-9a-f]{2})`)
+
+// Fingerprint returns the SHA-1 fingerprint of the certificate of the key,
+// in lower case with colons.
+func (m *Manager) Fingerprint(ctx context.Context, key Key) (string, error) {
+	result := m.executor.ExecuteElevated(ctx, "openssl", "x509", "-inform", "DER",
+		"-in", key.Certificate, "-noout", "-fingerprint", "-sha1")
+	if err := commandError("openssl", result); err != nil {
+		return "", err
+	}
+
+	fingerprints := parseFingerprints(result.StdoutString())
+	if len(fingerprints) == 0 {
+		return "", fmt.Errorf("no fingerprint in the output of openssl for %s", key.Certificate)
+	}
+	return fingerprints[0], nil
+}
+
+// parseFingerprints returns the SHA-1 fingerprints in the output of openssl
+// or mokutil, in lower case.
+//
+// Example of mokutil --list-enrolled:
+//
+//	[key 1]
+//	SHA1 Fingerprint: 76:a0:92:06:58:00:bf:37:69:01:c3:72:cd:55:a9:0e:1f:de:d2:e0
+//	Certificate:
+//	    Data:
+func parseFingerprints(output string) []string {
+	var fingerprints []string
+	for _, match := range fingerprintPattern.FindAllStringSubmatch(output, -1) {
+		fingerprints = append(fingerprints, strings.ToLower(match[1]))
+	}
+	return fingerprints
+}
+
+// EnrollmentStatus returns whether the key is enrolled, with
+// mokutil --list-enrolled, or queued for enrollment, with mokutil --list-new.
+func (m *Manager) EnrollmentStatus(ctx context.Context, key Key) (EnrollmentStatus, error) {
+	fingerprint, err := m.Fingerprint(ctx, key)
+	if err != nil {
+		return NotEnrolled, err
+	}
+
+	for _, list := range []struct {
+		flag   string
+		status EnrollmentStatus
+	}{
+		{"--list-enrolled", Enrolled},
+		{"--list-new", EnrollmentPending},
+	} {
+		// mokutil exits with an error when the list is empty
+		result := m.executor.Execute(ctx, "mokutil", list.flag)
+		if result.Error != nil {
+			return NotEnrolled, fmt.Errorf("failed to run mokutil: %w", result.Error)
+		}
+		for _, enrolled := range parseFingerprints(result.StdoutString()) {
+			if enrolled == fingerprint {
+				return list.status, nil
+			}
+		}
+	}
+	return NotEnrolled, nil
+}
+
+// QueueEnrollment queues the certificate of the key for enrollment with
+// mokutil --import. The MOK manager asks for the password at the next reboot
+// to confirm the enrollment.
+func (m *Manager) QueueEnrollment(ctx context.Context, key Key, password string) error {
+	// mokutil asks for the password twice
+	input := []byte(password + "\n" + password + "\n")
+	return commandError("mokutil --import", m.executor.ExecuteElevatedWithInput(ctx, input, "mokutil", "--import", key.Certificate))
+}
+
+// CancelEnrollment cancels the pending enrollment requests with
+// mokutil --revoke-import.
+func (m *Manager) CancelEnrollment(ctx context.Context) error {
+	return commandError("mokutil --revoke-import", m.executor.ExecuteElevated(ctx, "mokutil", "--revoke-import"))
+}
+
+// dkmsVersionPattern matches the major version printed by dkms --version,
+// such as "dkms-2.8.1" or "dkms-3.0.11".
+var dkmsVersionPattern = regexp.MustCompile(`dkms[- ](\d+)\.`)
+
+// DKMSConfig returns the DKMS framework configuration signing the modules
+// with the key. DKMS 2 signs with the sign_tool script instead.
+func DKMSConfig(key Key, signTool bool) string {
+	var b strings.Builder
+	b.WriteString("# Written by igor: sign the kernel modules built by DKMS with the\n")
+	b.WriteString("# Machine Owner Key enrolled for Secure Boot.\n")
+	fmt.Fprintf(&b, "mok_signing_key=%q\n", key.PrivateKey)
+	fmt.Fprintf(&b, "mok_certificate=%q\n", key.Certificate)
+	if signTool {
+		fmt.Fprintf(&b, "sign_tool=%q\n", DKMSSignToolPath)
+	}
+	return b.String()
+}
+
+// DKMSSignTool returns the sign_tool script of DKMS 2, which is called with
+// the kernel version and the path of the module.
+func DKMSSignTool(key Key) string {
+	return fmt.Sprintf("#!/bin/sh\n# Written by igor: sign a kernel module built by DKMS.\nexec /lib/modules/\"$1\"/build/scripts/sign-file sha512 %q %q \"$2\"\n",
+		key.PrivateKey, key.Certificate)
+}
+
+// ConfigureDKMS writes the DKMS configuration signing the modules with the
+// key. The sign_tool script is added when the installed DKMS is version 2.
+func (m *Manager) ConfigureDKMS(ctx context.Context, key Key) error {
+	signTool := false
+	if match := dkmsVersionPattern.FindStringSubmatch(m.executor.Execute(ctx, "dkms", "--version").StdoutString()); match != nil {
+		signTool = match[1] == "2"
+	}
+
+	if err := commandError("mkdir", m.executor.ExecuteElevated(ctx, "mkdir", "-p", filepath.Dir(DKMSConfigPath))); err != nil {
+		return err
+	}
+	if signTool {
+		if err := m.writeFile(ctx, DKMSSignToolPath, DKMSSignTool(key)); err != nil {
+			return err
+		}
+		if err := commandError("chmod", m.executor.ExecuteElevated(ctx, "chmod", "755", DKMSSignToolPath)); err != nil {
+			return err
+		}
+	}
+	return m.writeFile(ctx, DKMSConfigPath, DKMSConfig(key, signTool))
+}
+
+// RemoveDKMSConfig removes the DKMS configuration and sign_tool script.
+func (m *Manager) RemoveDKMSConfig(ctx context.Context) error {
+	return commandError("rm", m.executor.ExecuteElevated(ctx, "rm", "-f", DKMSConfigPath, DKMSSignToolPath))
+}
+
+// writeFile writes content to path with tee, with root privileges.
+func (m *Manager) writeFile(ctx context.Context, path, content string) error {
+	if err := commandError("tee", m.executor.ExecuteElevatedWithInput(ctx, []byte(content), "tee", path)); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// commandError returns an error describing a failed command, or nil if it
+// succeeded.
+func commandError(name string, result *exec.Result) error {
+	if result.ExitCode == 0 && result.Error == nil {
+		return nil
+	}
+	errMsg := strings.TrimSpace(result.StderrString())
+	if errMsg == "" {
+		errMsg = strings.TrimSpace(result.StdoutString())
+	}
+	if errMsg == "" && result.Error != nil {
+		errMsg = result.Error.Error()
+	}
+	if errMsg == "" {
+		errMsg = "unknown error"
+	}
+	return fmt.Errorf("%s failed: %s", name, errMsg)
+}
@@ -0,0 +1,158 @@
+// Package secureboot signs the NVIDIA kernel modules for Secure Boot.
+//
+// With Secure Boot enabled, the kernel only loads modules signed by a key the
+// firmware or shim trusts. Igor reuses the Machine Owner Key (MOK) of DKMS or
+// akmods, or creates one, has the tool building the modules sign them with it,
+// and queues the key for enrollment with mokutil. The key is only trusted once
+// the user confirms the enrollment in the MOK manager at the next reboot.
+package secureboot
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+// Tool is the tool building and signing the NVIDIA kernel modules.
+type Tool string
+
+const (
+	// ToolDKMS builds the modules with DKMS, as on Debian, Ubuntu and Arch.
+	ToolDKMS Tool = "dkms"
+
+	// ToolAkmods builds the modules with akmods, as with the akmod-nvidia
+	// package of RPM Fusion.
+	ToolAkmods Tool = "akmods"
+)
+
+// String returns the name of the tool.
+func (t Tool) String() string {
+	return string(t)
+}
+
+// Key is a Machine Owner Key: a private key and its X.509 certificate in DER
+// form, which is what mokutil enrolls.
+type Key struct {
+	// PrivateKey is the path of the private key.
+	PrivateKey string
+
+	// Certificate is the path of the DER certificate.
+	Certificate string
+}
+
+// Keys of the module build tools, in the order they are looked for.
+var (
+	// UbuntuKey is the key created by update-secureboot-policy on Ubuntu,
+	// which the DKMS of Ubuntu signs with.
+	UbuntuKey = Key{
+		PrivateKey:  "/var/lib/shim-signed/mok/MOK.priv",
+		Certificate: "/var/lib/shim-signed/mok/MOK.der",
+	}
+
+	// DKMSKey is the default key of DKMS 3.
+	DKMSKey = Key{
+		PrivateKey:  "/var/lib/dkms/mok.key",
+		Certificate: "/var/lib/dkms/mok.pub",
+	}
+
+	// AkmodsKey is the key akmods signs with, created by kmodgenca.
+	AkmodsKey = Key{
+		PrivateKey:  "/etc/pki/akmods/private/private_key.priv",
+		Certificate: "/etc/pki/akmods/certs/public_key.der",
+	}
+)
+
+// Keys returns the keys the tool may already sign with, the key to create
+// if none exists last.
+func Keys(tool Tool) []Key {
+	if tool == ToolAkmods {
+		return []Key{AkmodsKey}
+	}
+	return []Key{UbuntuKey, DKMSKey}
+}
+
+// DefaultKey returns the key to create for the tool.
+func DefaultKey(tool Tool) Key {
+	keys := Keys(tool)
+	return keys[len(keys)-1]
+}
+
+// KeySubject is the subject of the certificates created by Igor, shown by
+// the MOK manager when the key is enrolled.
+const KeySubject = "/CN=igor NVIDIA module signing key/"
+
+// PasswordLength is the number of digits of the generated enrollment
+// passwords.
+const PasswordLength = 8
+
+// GeneratePassword returns a random one-time enrollment password. It only
+// uses digits, which are typed the same whatever the keyboard layout the MOK
+// manager assumes.
+func GeneratePassword() (string, error) {
+	password := make([]byte, PasswordLength)
+	for i := range password {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", fmt.Errorf("failed to generate enrollment password: %w", err)
+		}
+		password[i] = byte('0' + n.Int64())
+	}
+	return string(password), nil
+}
+
+// EnrollmentStatus is the enrollment status of a key.
+type EnrollmentStatus int
+
+const (
+	// NotEnrolled is the status of a key unknown to shim.
+	NotEnrolled EnrollmentStatus = iota
+
+	// EnrollmentPending is the status of a key queued for enrollment, which
+	// the MOK manager asks to confirm at the next reboot.
+	EnrollmentPending
+
+	// Enrolled is the status of a key trusted by shim.
+	Enrolled
+)
+
+// String returns a description of the status.
+func (s EnrollmentStatus) String() string {
+	switch s {
+	case EnrollmentPending:
+		return "pending"
+	case Enrolled:
+		return "enrolled"
+	default:
+		return "not enrolled"
+	}
+}
+
+// Enrollment is a key queued for enrollment.
+type Enrollment struct {
+	// Certificate is the path of the certificate queued.
+	Certificate string
+
+	// Password is the one-time password the MOK manager asks for, or empty
+	// if the key was queued before with a password Igor does not know.
+	Password string
+}
+
+// Instructions returns what the user has to do in the MOK manager at the
+// next reboot to confirm the enrollment, one line per string.
+func (e Enrollment) Instructions() []string {
+	password := fmt.Sprintf("type the password %s", e.Password)
+	if e.Password == "" {
+		password = "type the password chosen when the key was queued"
+	}
+
+	return []string{
+		fmt.Sprintf("Secure Boot is enabled: the NVIDIA modules are signed with the key %s,", e.Certificate),
+		"which must be enrolled before they can load. At the next reboot, the blue MOK manager screen appears:",
+		"  1. Press a key within 10 seconds to perform MOK management",
+		"  2. Select \"Enroll MOK\", then \"Continue\"",
+		"  3. Select \"Yes\" to enroll the key",
+		fmt.Sprintf("  4. At the \"Password:\" prompt, %s (nothing is echoed)", password),
+		"  5. Select \"Reboot\"",
+		"If the screen times out, the key is not enrolled; run 'sudo mokutil --import " + e.Certificate + "' to queue it again.",
+	}
+}
@@ -0,0 +1,249 @@
+package secureboot
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/exec"
+)
+
+const (
+	testFingerprint  = "76:a0:92:06:58:00:bf:37:69:01:c3:72:cd:55:a9:0e:1f:de:d2:e0"
+	otherFingerprint = "11:22:33:44:55:66:77:88:99:00:aa:bb:cc:dd:ee:ff:11:22:33:44"
+)
+
+// mokExecutor is a mock executor answering test -f for existing files and
+// mokutil with its enrolled and pending keys.
+type mokExecutor struct {
+	*exec.MockExecutor
+	files    map[string]bool
+	enrolled []string
+	pending  []string
+}
+
+func newMokExecutor() *mokExecutor {
+	m := &mokExecutor{MockExecutor: exec.NewMockExecutor(), files: make(map[string]bool)}
+	m.SetResponse("openssl", exec.SuccessResult("sha1 Fingerprint="+strings.ToUpper(testFingerprint)+"\n"))
+	return m
+}
+
+func mokList(fingerprints []string) *exec.Result {
+	if len(fingerprints) == 0 {
+		return exec.FailureResult(1, "MokListRT is empty")
+	}
+	var b strings.Builder
+	for i, fingerprint := range fingerprints {
+		b.WriteString("[key " + string(rune('1'+i)) + "]\nSHA1 Fingerprint: " + fingerprint + "\nCertificate:\n    Data:\n")
+	}
+	return exec.SuccessResult(b.String())
+}
+
+func (m *mokExecutor) Execute(ctx context.Context, cmd string, args ...string) *exec.Result {
+	result := m.MockExecutor.Execute(ctx, cmd, args...)
+	if cmd == "test" && len(args) == 2 && !m.files[args[1]] {
+		return exec.FailureResult(1, "")
+	}
+	if cmd == "mokutil" && len(args) == 1 {
+		switch args[0] {
+		case "--list-enrolled":
+			return mokList(m.enrolled)
+		case "--list-new":
+			return mokList(m.pending)
+		}
+	}
+	return result
+}
+
+func TestKeys(t *testing.T) {
+	assert.Equal(t, []Key{AkmodsKey}, Keys(ToolAkmods))
+	assert.Equal(t, []Key{UbuntuKey, DKMSKey}, Keys(ToolDKMS))
+	assert.Equal(t, DKMSKey, DefaultKey(ToolDKMS))
+	assert.Equal(t, AkmodsKey, DefaultKey(ToolAkmods))
+}
+
+func TestGeneratePassword(t *testing.T) {
+	password, err := GeneratePassword()
+	require.NoError(t, err)
+	assert.Len(t, password, PasswordLength)
+	assert.Empty(t, strings.Trim(password, "0123456789"))
+}
+
+func TestEnrollmentInstructions(t *testing.T) {
+	lines := Enrollment{Certificate: DKMSKey.Certificate, Password: "12345678"}.Instructions()
+	text := strings.Join(lines, "\n")
+	assert.Contains(t, text, DKMSKey.Certificate)
+	assert.Contains(t, text, `"Enroll MOK"`)
+	assert.Contains(t, text, "type the password 12345678")
+	assert.Contains(t, text, `"Reboot"`)
+
+	text = strings.Join(Enrollment{Certificate: DKMSKey.Certificate}.Instructions(), "\n")
+	assert.Contains(t, text, "the password chosen when the key was queued")
+}
+
+func TestManager_FindKey(t *testing.T) {
+	executor := newMokExecutor()
+	manager := NewManager(executor)
+
+	key, found := manager.FindKey(context.Background(), ToolDKMS)
+	assert.False(t, found)
+	assert.Equal(t, DKMSKey, key)
+
+	// A key is only reused with both of its files
+	executor.files[UbuntuKey.Certificate] = true
+	executor.files[DKMSKey.PrivateKey] = true
+	executor.files[DKMSKey.Certificate] = true
+	key, found = manager.FindKey(context.Background(), ToolDKMS)
+	assert.True(t, found)
+	assert.Equal(t, DKMSKey, key)
+
+	executor.files[UbuntuKey.PrivateKey] = true
+	key, _ = manager.FindKey(context.Background(), ToolDKMS)
+	assert.Equal(t, UbuntuKey, key)
+}
+
+func TestManager_GenerateKey(t *testing.T) {
+	t.Run("openssl", func(t *testing.T) {
+		executor := newMokExecutor()
+
+		err := NewManager(executor).GenerateKey(context.Background(), ToolDKMS, DKMSKey)
+
+		require.NoError(t, err)
+		assert.True(t, executor.WasCalledWith("mkdir", "-p", "/var/lib/dkms"))
+		assert.True(t, executor.WasCalledWith("openssl", "req", "-new", "-x509",
+			"-newkey", "rsa:2048", "-nodes", "-sha256", "-days", "36500",
+			"-subj", KeySubject,
+			"-keyout", DKMSKey.PrivateKey,
+			"-outform", "DER", "-out", DKMSKey.Certificate))
+		assert.True(t, executor.WasCalledWith("chmod", "600", DKMSKey.PrivateKey))
+	})
+
+	t.Run("kmodgenca", func(t *testing.T) {
+		executor := newMokExecutor()
+
+		err := NewManager(executor).GenerateKey(context.Background(), ToolAkmods, AkmodsKey)
+
+		require.NoError(t, err)
+		assert.True(t, executor.WasCalledWith("kmodgenca", "-a"))
+		assert.False(t, executor.WasCalledWith("mkdir", "-p", "/etc/pki/akmods/private", "/etc/pki/akmods/certs"))
+	})
+
+	t.Run("akmods without kmodgenca", func(t *testing.T) {
+		executor := newMokExecutor()
+		executor.SetResponse("which", exec.FailureResult(1, ""))
+
+		err := NewManager(executor).GenerateKey(context.Background(), ToolAkmods, AkmodsKey)
+
+		require.NoError(t, err)
+		assert.False(t, executor.WasCalled("kmodgenca"))
+		assert.True(t, executor.WasCalledWith("mkdir", "-p", "/etc/pki/akmods/private", "/etc/pki/akmods/certs"))
+	})
+
+	t.Run("openssl fails", func(t *testing.T) {
+		executor := newMokExecutor()
+		executor.SetResponse("openssl", exec.FailureResult(1, "unable to write private key"))
+
+		err := NewManager(executor).GenerateKey(context.Background(), ToolDKMS, DKMSKey)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "openssl failed: unable to write private key")
+	})
+}
+
+func TestParseFingerprints(t *testing.T) {
+	assert.Equal(t, []string{testFingerprint},
+		parseFingerprints("SHA1 Fingerprint="+strings.ToUpper(testFingerprint)+"\n"))
+	assert.Equal(t, []string{testFingerprint, otherFingerprint},
+		parseFingerprints(mokList([]string{testFingerprint, otherFingerprint}).StdoutString()))
+	assert.Empty(t, parseFingerprints("MokListRT is empty\n"))
+}
+
+func TestManager_EnrollmentStatus(t *testing.T) {
+	testCases := []struct {
+		name     string
+		enrolled []string
+		pending  []string
+		expected EnrollmentStatus
+	}{
+		{"no keys", nil, nil, NotEnrolled},
+		{"other keys", []string{otherFingerprint}, []string{otherFingerprint}, NotEnrolled},
+		{"enrolled", []string{otherFingerprint, testFingerprint}, nil, Enrolled},
+		{"pending", nil, []string{testFingerprint}, EnrollmentPending},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			executor := newMokExecutor()
+			executor.enrolled = tc.enrolled
+			executor.pending = tc.pending
+
+			status, err := NewManager(executor).EnrollmentStatus(context.Background(), DKMSKey)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, status)
+		})
+	}
+
+	t.Run("invalid certificate", func(t *testing.T) {
+		executor := newMokExecutor()
+		executor.SetResponse("openssl", exec.FailureResult(1, "Could not read certificate"))
+
+		_, err := NewManager(executor).EnrollmentStatus(context.Background(), DKMSKey)
+
+		require.Error(t, err)
+		assert.False(t, executor.WasCalled("mokutil"))
+	})
+}
+
+func TestManager_QueueEnrollment(t *testing.T) {
+	executor := newMokExecutor()
+
+	err := NewManager(executor).QueueEnrollment(context.Background(), DKMSKey, "12345678")
+
+	require.NoError(t, err)
+	call := executor.LastCall()
+	assert.Equal(t, "mokutil", call.Command)
+	assert.Equal(t, []string{"--import", DKMSKey.Certificate}, call.Args)
+	assert.True(t, call.Elevated)
+	assert.Equal(t, "12345678\n12345678\n", string(call.Input))
+
+	executor.SetResponse("mokutil", exec.FailureResult(1, "Failed to enroll new keys"))
+	err = NewManager(executor).QueueEnrollment(context.Background(), DKMSKey, "12345678")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed to enroll new keys")
+}
+
+func TestManager_ConfigureDKMS(t *testing.T) {
+	t.Run("DKMS 3", func(t *testing.T) {
+		executor := newMokExecutor()
+		executor.SetResponse("dkms", exec.SuccessResult("dkms-3.0.11\n"))
+
+		err := NewManager(executor).ConfigureDKMS(context.Background(), DKMSKey)
+
+		require.NoError(t, err)
+		call := executor.LastCall()
+		assert.Equal(t, "tee", call.Command)
+		assert.Equal(t, []string{DKMSConfigPath}, call.Args)
+		assert.True(t, call.Elevated)
+		assert.Contains(t, string(call.Input), `mok_signing_key="/var/lib/dkms/mok.key"`)
+		assert.Contains(t, string(call.Input), `mok_certificate="/var/lib/dkms/mok.pub"`)
+		assert.NotContains(t, string(call.Input), "sign_tool")
+		assert.False(t, executor.WasCalledWith("tee", DKMSSignToolPath))
+	})
+
+	t.Run("DKMS 2", func(t *testing.T) {
+		executor := newMokExecutor()
+		executor.SetResponse("dkms", exec.SuccessResult("dkms-2.8.1\n"))
+
+		err := NewManager(executor).ConfigureDKMS(context.Background(), UbuntuKey)
+
+		require.NoError(t, err)
+		assert.True(t, executor.WasCalledWith("tee", DKMSSignToolPath))
+		assert.True(t, executor.WasCalledWith("chmod", "755", DKMSSignToolPath))
+		assert.Contains(t, string(executor.LastCall().Input), `sign_tool="/etc/dkms/igor-sign-tool.sh"`)
+		assert.Contains(t, DKMSSignTool(UbuntuKey),
+			`sign-file sha512 "/var/lib/shim-signed/mok/MOK.priv" "/var/lib/shim-signed/mok/MOK.der" "$2"`)
+	})
+}
@@ -21,6 +21,8 @@ import (
 	"github.com/tungetti/igor/internal/gpu/pci"
 	"github.com/tungetti/igor/internal/gpu/smi"
 	"github.com/tungetti/igor/internal/gpu/validator"
+	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/install/steps"
 	"github.com/tungetti/igor/internal/pkg"
 	"github.com/tungetti/igor/internal/pkg/factory"
 	"github.com/tungetti/igor/internal/ui/theme"
@@ -251,7 +253,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.confirmationView = m.initConfirmationView(msg.GPUInfo, msg.SelectedDriver, msg.SelectedComponents)
 		sizeMsg := tea.WindowSizeMsg{Width: m.Width, Height: m.Height}
 		m.confirmationView.SetSize(sizeMsg.Width, sizeMsg.Height)
-		return m, nil
+		m.confirmationView.SetTransactionPreviewPending()
+		return m, m.previewTransaction(msg.SelectedDriver, msg.SelectedComponents)
 
 	case views.NavigateBackToSelectionMsg:
 		m.CurrentView = ViewDriverSelection
@@ -655,6 +658,59 @@ func queryAvailableDrivers(ctx context.Context, executor exec.Executor) []gpu.Av
 	return parseDriverPackages(packages, dist)
 }
 
+// previewTransaction simulates the package transaction of the installation
+// asynchronously, so the confirmation view can show the packages it would
+// remove and the disk space it needs.
+func (m Model) previewTransaction(driver views.DriverOption, comps []views.ComponentOption) tea.Cmd {
+	return func() tea.Msg {
+		executor := exec.NewExecutor(exec.DefaultOptions(), nil)
+		sim, usage, err := simulateInstallation(m.ctx, executor, driver, comps)
+		return views.TransactionPreviewMsg{Simulation: sim, DiskUsage: usage, Err: err}
+	}
+}
+
+// simulateInstallation simulates the installation of the packages for the
+// selected driver and components, and estimates the disk space it needs.
+// It returns a nil simulation if the package manager cannot simulate
+// transactions.
+func simulateInstallation(ctx context.Context, executor exec.Executor, driver views.DriverOption, comps []views.ComponentOption) (*pkg.Simulation, []pkg.DiskUsage, error) {
+	detector := distro.NewDetector(executor, nil)
+	dist, err := detector.Detect(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pkgManager, err := factory.NewFactory(executor, nil, detector).CreateForDistribution(dist)
+	if err != nil {
+		return nil, nil, err
+	}
+	simulator, ok := pkgManager.(pkg.SimulationManager)
+	if !ok {
+		return nil, nil, nil
+	}
+
+	componentIDs := make([]string, 0, len(comps))
+	for _, comp := range comps {
+		componentIDs = append(componentIDs, comp.ID)
+	}
+	installCtx := install.NewContext(
+		install.WithDistroInfo(dist),
+		install.WithDriverVersion(driver.Version),
+		install.WithComponents(componentIDs),
+		install.WithContext(ctx),
+	)
+	packages, err := steps.NewPackageInstallationStep().Packages(installCtx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sim, err := simulator.SimulateInstall(ctx, pkg.NonInteractiveInstallOptions(), packages...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sim, steps.EstimateDiskUsage(ctx, executor, dist.Family, sim), nil
+}
+
 // parseDriverPackages extracts driver versions from package search results.
 func parseDriverPackages(packages []pkg.Package, dist *distro.Distribution) []gpu.AvailableDriver {
 	// Regex patterns for different distros
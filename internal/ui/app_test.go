@@ -1141,7 +1141,7 @@ func TestModel_Update_ViewMessages(t *testing.T) {
 		driver := views.DriverOption{Version: "550", Branch: "Latest"}
 		components := []views.ComponentOption{{Name: "Driver", ID: "driver", Selected: true}}
 
-		newModel, _ = m.Update(views.NavigateToConfirmationMsg{
+		newModel, cmd := m.Update(views.NavigateToConfirmationMsg{
 			GPUInfo:            gpuInfo,
 			SelectedDriver:     driver,
 			SelectedComponents: components,
@@ -1152,6 +1152,9 @@ func TestModel_Update_ViewMessages(t *testing.T) {
 		assert.Equal(t, gpuInfo, m.gpuInfo)
 		assert.Equal(t, driver, m.driver)
 		assert.Equal(t, components, m.components)
+		// The package transaction is previewed in the background
+		assert.NotNil(t, cmd)
+		assert.Contains(t, m.View(), "calculating...")
 	})
 
 	t.Run("StartInstallationMsg navigates to installing", func(t *testing.T) {
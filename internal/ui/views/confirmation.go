@@ -3,10 +3,14 @@
 package views
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/tungetti/igor/internal/gpu"
+	"github.com/tungetti/igor/internal/pkg"
 	"github.com/tungetti/igor/internal/ui/components"
 	"github.com/tungetti/igor/internal/ui/theme"
 )
@@ -93,6 +97,12 @@ type ConfirmationModel struct {
 	// Warnings
 	warnings []string
 
+	// Transaction preview
+	previewPending bool
+	preview        *pkg.Simulation
+	previewErr     error
+	diskUsage      []pkg.DiskUsage
+
 	// App info
 	version string
 }
@@ -175,6 +185,9 @@ func (m ConfirmationModel) Update(msg tea.Msg) (ConfirmationModel, tea.Cmd) {
 
 		case key.Matches(msg, m.keyMap.Confirm):
 			if m.buttons.FocusedIndex() == 0 {
+				if m.Blocked() {
+					return m, nil
+				}
 				return m, m.startInstallation
 			}
 			return m, m.navigateToSelection
@@ -192,6 +205,10 @@ func (m ConfirmationModel) Update(msg tea.Msg) (ConfirmationModel, tea.Cmd) {
 			return m, nil
 		}
 
+	case TransactionPreviewMsg:
+		m.SetTransactionPreview(msg.Simulation, msg.Err)
+		m.SetDiskUsage(msg.DiskUsage)
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
@@ -240,6 +257,9 @@ func (m ConfirmationModel) renderContent(height int) string {
 	// Components
 	componentsSection := m.renderComponentsSection()
 
+	// Package changes (if previewed)
+	previewSection := m.renderPreviewSection()
+
 	// Warnings (if any)
 	warningsSection := m.renderWarningsSection()
 
@@ -247,6 +267,12 @@ func (m ConfirmationModel) renderContent(height int) string {
 	confirmMsg := m.styles.Paragraph.Render(
 		"\nAre you sure you want to proceed with the installation?",
 	)
+	if m.Blocked() {
+		confirmMsg = m.styles.Error.Render(
+			"\nThe installation would remove essential packages. " +
+				"Run \"igor install --force\" to install anyway.",
+		)
+	}
 
 	// Buttons
 	buttonRow := lipgloss.NewStyle().
@@ -256,6 +282,9 @@ func (m ConfirmationModel) renderContent(height int) string {
 		Render(m.buttons.View())
 
 	sections := []string{title, "", gpuSection, driverSection, componentsSection}
+	if previewSection != "" {
+		sections = append(sections, previewSection)
+	}
 	if warningsSection != "" {
 		sections = append(sections, warningsSection)
 	}
@@ -308,16 +337,73 @@ func (m ConfirmationModel) renderComponentsSection() string {
 	return subtitle + "\n" + lipgloss.JoinVertical(lipgloss.Left, items...)
 }
 
+// renderPreviewSection renders the package changes of the simulated
+// transaction, listing the packages that would be removed, and the disk
+// space it needs in each directory.
+func (m ConfirmationModel) renderPreviewSection() string {
+	subtitle := m.styles.Subtitle.Render("Package changes:")
+
+	switch {
+	case m.previewPending:
+		return subtitle + " " + m.styles.Info.Render("calculating...")
+	case m.previewErr != nil:
+		return subtitle + " " + m.styles.Warning.Render("preview not available")
+	case m.preview == nil:
+		return ""
+	}
+
+	items := []string{subtitle + " " + m.preview.Summary()}
+	for _, change := range m.preview.Remove {
+		line := change.Name
+		if change.ReplacedBy != "" {
+			line += " (replaced by " + change.ReplacedBy + ")"
+		}
+		if change.Essential || pkg.IsEssentialPackage(change.Name) {
+			items = append(items, "  "+m.styles.Error.Render("\u2717 "+line+" [essential]"))
+			continue
+		}
+		items = append(items, "  "+m.styles.Warning.Render("-")+" "+line)
+	}
+	for _, change := range m.preview.Downgrade {
+		items = append(items, "  "+m.styles.Warning.Render("\u2193")+" "+change.Name)
+	}
+
+	if len(m.diskUsage) > 0 {
+		short := make(map[string]bool)
+		for _, shortage := range pkg.DiskShortages(m.diskUsage) {
+			short[shortage.MountPoint] = true
+		}
+		items = append(items, m.styles.Subtitle.Render("Disk space:"))
+		for _, u := range m.diskUsage {
+			if u.Measured() && short[u.MountPoint] {
+				items = append(items, "  "+m.styles.Error.Render("\u2717 "+u.String()))
+				continue
+			}
+			items = append(items, "  "+u.String())
+		}
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, items...)
+}
+
 // renderWarningsSection renders the warnings section.
 func (m ConfirmationModel) renderWarningsSection() string {
-	if len(m.warnings) == 0 {
+	warnings := append([]string{}, m.warnings...)
+	if essential := m.essentialRemovals(); len(essential) > 0 {
+		warnings = append(warnings,
+			fmt.Sprintf("Installing would remove essential packages: %s", strings.Join(essential, ", ")))
+	}
+	for _, shortage := range pkg.DiskShortages(m.diskUsage) {
+		warnings = append(warnings, "Not enough disk space: "+shortage.String())
+	}
+	if len(warnings) == 0 {
 		return ""
 	}
 
 	subtitle := m.styles.Warning.Render("Warnings:")
 
 	var items []string
-	for _, w := range m.warnings {
+	for _, w := range warnings {
 		marker := m.styles.Warning.Render("\u26A0")
 		items = append(items, "  "+marker+" "+w)
 	}
@@ -357,6 +443,16 @@ type NavigateBackToSelectionMsg struct {
 	GPUInfo *gpu.GPUInfo
 }
 
+// TransactionPreviewMsg carries the simulated package transaction of the
+// installation. Simulation is nil if the package manager cannot simulate
+// transactions. DiskUsage is the disk space the transaction needs in each
+// directory, with the free space of the filesystems when measured.
+type TransactionPreviewMsg struct {
+	Simulation *pkg.Simulation
+	DiskUsage  []pkg.DiskUsage
+	Err        error
+}
+
 // Getters
 
 // GPUInfo returns the GPU info passed to this view.
@@ -379,6 +475,37 @@ func (m ConfirmationModel) Warnings() []string {
 	return m.warnings
 }
 
+// TransactionPreview returns the simulated package transaction, or nil if
+// it is not known.
+func (m ConfirmationModel) TransactionPreview() *pkg.Simulation {
+	return m.preview
+}
+
+// DiskUsage returns the disk space the simulated transaction needs in each
+// directory, or nil if it is not known.
+func (m ConfirmationModel) DiskUsage() []pkg.DiskUsage {
+	return m.diskUsage
+}
+
+// Blocked returns whether the installation cannot be confirmed because it
+// would remove essential packages.
+func (m ConfirmationModel) Blocked() bool {
+	return len(m.essentialRemovals()) > 0
+}
+
+// essentialRemovals returns the names of the essential packages the
+// simulated transaction removes.
+func (m ConfirmationModel) essentialRemovals() []string {
+	if m.preview == nil {
+		return nil
+	}
+	var names []string
+	for _, change := range m.preview.EssentialRemovals() {
+		names = append(names, change.Name)
+	}
+	return names
+}
+
 // Width returns the current width of the view.
 func (m ConfirmationModel) Width() int {
 	return m.width
@@ -414,6 +541,29 @@ func (m ConfirmationModel) IsFullHelpShown() bool {
 	return m.footer.IsFullHelpShown()
 }
 
+// SetTransactionPreviewPending marks the package transaction as being
+// simulated.
+func (m *ConfirmationModel) SetTransactionPreviewPending() {
+	m.previewPending = true
+	m.preview = nil
+	m.previewErr = nil
+	m.diskUsage = nil
+}
+
+// SetTransactionPreview sets the simulated package transaction, or the
+// error that prevented simulating it.
+func (m *ConfirmationModel) SetTransactionPreview(sim *pkg.Simulation, err error) {
+	m.previewPending = false
+	m.preview = sim
+	m.previewErr = err
+}
+
+// SetDiskUsage sets the disk space the simulated transaction needs in each
+// directory.
+func (m *ConfirmationModel) SetDiskUsage(usage []pkg.DiskUsage) {
+	m.diskUsage = usage
+}
+
 // SetSize updates the view dimensions.
 func (m *ConfirmationModel) SetSize(width, height int) {
 	m.width = width
@@ -12,6 +12,7 @@ import (
 	"github.com/tungetti/igor/internal/gpu/nouveau"
 	"github.com/tungetti/igor/internal/gpu/nvidia"
 	"github.com/tungetti/igor/internal/gpu/pci"
+	"github.com/tungetti/igor/internal/pkg"
 )
 
 // =============================================================================
@@ -948,6 +949,92 @@ func TestNavigateBackToSelectionMsg_Struct(t *testing.T) {
 	assert.Equal(t, gpuInfo, msg.GPUInfo)
 }
 
+// =============================================================================
+// Transaction Preview Tests
+// =============================================================================
+
+func TestConfirmationModel_TransactionPreview(t *testing.T) {
+	m := NewConfirmation(getTestStyles(), "1.0.0", createMockGPUInfo(), DriverOption{Version: "550"}, nil)
+	m.SetSize(100, 40)
+
+	// No preview requested
+	assert.NotContains(t, m.View(), "Package changes:")
+
+	m.SetTransactionPreviewPending()
+	assert.Contains(t, m.View(), "calculating...")
+
+	sim := pkg.NewSimulation()
+	sim.Install = []pkg.PackageChange{{Name: "nvidia-driver-550"}}
+	sim.Remove = []pkg.PackageChange{{Name: "xserver-xorg-video-nouveau", ReplacedBy: "nvidia-driver-550"}}
+	m, _ = m.Update(TransactionPreviewMsg{Simulation: sim})
+
+	view := m.View()
+	assert.Same(t, sim, m.TransactionPreview())
+	assert.Contains(t, view, "1 to install, 1 to remove")
+	assert.Contains(t, view, "xserver-xorg-video-nouveau (replaced by nvidia-driver-550)")
+	assert.False(t, m.Blocked())
+}
+
+func TestConfirmationModel_TransactionPreviewFailed(t *testing.T) {
+	m := NewConfirmation(getTestStyles(), "1.0.0", createMockGPUInfo(), DriverOption{Version: "550"}, nil)
+	m.SetSize(100, 40)
+
+	m.SetTransactionPreview(nil, pkg.ErrSimulationFailed)
+
+	assert.Contains(t, m.View(), "preview not available")
+	assert.False(t, m.Blocked())
+}
+
+func TestConfirmationModel_EssentialRemovalsBlockInstall(t *testing.T) {
+	m := NewConfirmation(getTestStyles(), "1.0.0", createMockGPUInfo(), DriverOption{Version: "550"}, nil)
+	m.SetSize(100, 40)
+
+	sim := pkg.NewSimulation()
+	sim.Remove = []pkg.PackageChange{{Name: "xserver-xorg-core"}}
+	m.SetTransactionPreview(sim, nil)
+
+	assert.True(t, m.Blocked())
+	view := m.View()
+	assert.Contains(t, view, "Installing would remove essential packages: xserver-xorg-core")
+	assert.Contains(t, view, "igor install --force")
+
+	// Install does nothing, Go Back still works
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Nil(t, cmd)
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRight})
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	require.NotNil(t, cmd)
+	_, ok := cmd().(NavigateBackToSelectionMsg)
+	assert.True(t, ok)
+}
+
+func TestConfirmationModel_DiskUsage(t *testing.T) {
+	m := NewConfirmation(getTestStyles(), "1.0.0", createMockGPUInfo(), DriverOption{Version: "550"}, nil)
+	m.SetSize(120, 60)
+
+	sim := pkg.NewSimulation()
+	sim.Install = []pkg.PackageChange{{Name: "cuda"}}
+	usage := []pkg.DiskUsage{
+		{Dir: pkg.PackageCacheDir, Required: 2 << 30, MountPoint: "/var", Available: 10 << 30},
+		{Dir: pkg.SystemDir, Required: 1 << 30, MountPoint: "/", Available: 3 << 30},
+		{Dir: pkg.OptDir, Required: 4 << 30, MountPoint: "/", Available: 3 << 30},
+	}
+	m, _ = m.Update(TransactionPreviewMsg{Simulation: sim, DiskUsage: usage})
+
+	view := m.View()
+	assert.Equal(t, usage, m.DiskUsage())
+	assert.Contains(t, view, "Disk space:")
+	assert.Contains(t, view, "/var/cache: 2.0 GiB needed, 10.0 GiB free on /var")
+	assert.Contains(t, view, "/opt: 4.0 GiB needed, 3.0 GiB free on /")
+	assert.Contains(t, view, "Not enough disk space: / has 3.0 GiB free, 5.0 GiB needed for /usr, /opt")
+
+	// A new preview clears the estimate
+	m.SetTransactionPreviewPending()
+	assert.Nil(t, m.DiskUsage())
+	assert.NotContains(t, m.View(), "Disk space:")
+}
+
 // =============================================================================
 // Integration Tests
 // =============================================================================
@@ -3,11 +3,13 @@
 package views
 
 import (
+	"errors"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/tungetti/igor/internal/dkms"
 	"github.com/tungetti/igor/internal/ui/components"
 	"github.com/tungetti/igor/internal/ui/theme"
 )
@@ -105,8 +107,8 @@ func NewError(styles theme.Styles, version string, err error, failedStep string)
 	footer := components.NewFooter(styles, keyMap)
 	buttons := components.NewButtonGroup(styles, "Retry", "Exit")
 
-	// Build troubleshooting tips based on failed step
-	tips := buildTroubleshootingTips(failedStep)
+	// Build troubleshooting tips based on the error and the failed step
+	tips := append(buildDiagnosisTips(err), buildTroubleshootingTips(failedStep)...)
 
 	return ErrorModel{
 		header:              header,
@@ -121,6 +123,20 @@ func NewError(styles theme.Styles, version string, err error, failedStep string)
 	}
 }
 
+// buildDiagnosisTips generates troubleshooting tips from the diagnosis of a
+// failed DKMS build, if the error carries one.
+func buildDiagnosisTips(err error) []string {
+	var buildErr *dkms.BuildError
+	if !errors.As(err, &buildErr) || buildErr.Diagnosis == nil {
+		return nil
+	}
+	return []string{
+		"Cause: " + buildErr.Diagnosis.Summary,
+		"Fix: " + buildErr.Diagnosis.Remediation,
+		"Log: " + buildErr.Diagnosis.Evidence,
+	}
+}
+
 // buildTroubleshootingTips generates troubleshooting tips based on the failed step.
 func buildTroubleshootingTips(failedStep string) []string {
 	var tips []string
@@ -129,6 +145,15 @@ func buildTroubleshootingTips(failedStep string) []string {
 	step := strings.ToLower(failedStep)
 
 	switch {
+	case strings.Contains(step, "dkms"):
+		tips = append(tips, "Check the build log in /var/lib/dkms/nvidia/<version>/build/make.log")
+		tips = append(tips, "Ensure the kernel headers match the kernel")
+
+	case strings.Contains(step, "akmods"):
+		tips = append(tips, "Check the build log in /var/cache/akmods/nvidia/")
+		tips = append(tips, "Ensure kernel-devel matches the kernel")
+		tips = append(tips, "Try running 'sudo akmods --force' manually")
+
 	case strings.Contains(step, "blacklist"):
 		tips = append(tips, "Check if Nouveau driver can be unloaded")
 		tips = append(tips, "Try rebooting and running again")
@@ -2,12 +2,14 @@ package views
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tungetti/igor/internal/dkms"
 )
 
 // =============================================================================
@@ -700,6 +702,41 @@ func TestBuildTroubleshootingTips_Installing(t *testing.T) {
 	assert.True(t, containsTip(tips, "Check disk space"))
 }
 
+func TestBuildTroubleshootingTips_DKMS(t *testing.T) {
+	tips := buildTroubleshootingTips("dkms_build")
+
+	assert.True(t, containsTip(tips, "/build/make.log"))
+}
+
+func TestBuildTroubleshootingTips_Akmods(t *testing.T) {
+	tips := buildTroubleshootingTips("akmods_build")
+
+	assert.True(t, containsTip(tips, "/var/cache/akmods/nvidia/"))
+	assert.True(t, containsTip(tips, "akmods --force"))
+}
+
+func TestNewError_DKMSDiagnosis(t *testing.T) {
+	err := fmt.Errorf("step dkms_build failed: %w", &dkms.BuildError{
+		Build: dkms.Build{Module: "nvidia", Version: "470.256.02", Kernel: "6.8.0-45-generic"},
+		Err:   errors.New("dkms build failed: Bad return status for module build"),
+		Diagnosis: &dkms.Diagnosis{
+			Cause:       dkms.CauseUnsupportedKernel,
+			Summary:     "nvidia 470.256.02 does not support the kernel API of kernel 6.8.0-45-generic",
+			Remediation: "Install a newer NVIDIA driver branch supporting kernel 6.8.0-45-generic",
+			Evidence:    "error: implicit declaration of function 'drm_gem_object_put_unlocked'",
+		},
+	})
+
+	m := NewError(getTestStyles(), "1.0.0", err, "dkms_build")
+
+	tips := m.TroubleshootingTips()
+	require.GreaterOrEqual(t, len(tips), 3)
+	assert.Equal(t, "Cause: nvidia 470.256.02 does not support the kernel API of kernel 6.8.0-45-generic", tips[0])
+	assert.Equal(t, "Fix: Install a newer NVIDIA driver branch supporting kernel 6.8.0-45-generic", tips[1])
+	assert.Contains(t, tips[2], "drm_gem_object_put_unlocked")
+	assert.Empty(t, buildDiagnosisTips(errors.New("test error")))
+}
+
 func TestBuildTroubleshootingTips_Configure(t *testing.T) {
 	tips := buildTroubleshootingTips("configure")
 
@@ -13,6 +13,8 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/tungetti/igor/internal/gpu"
+	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/pkg"
 	"github.com/tungetti/igor/internal/ui/components"
 	"github.com/tungetti/igor/internal/ui/theme"
 )
@@ -138,6 +140,7 @@ type ProgressModel struct {
 	currentStep     int
 	totalSteps      int
 	overallProgress float64
+	packageProgress *pkg.Progress // Progress of the package manager, nil if none
 
 	// Log output
 	logLines    []string
@@ -266,6 +269,7 @@ func (m ProgressModel) Update(msg tea.Msg) (ProgressModel, tea.Cmd) {
 			m.steps[msg.StepIndex].StartTime = time.Now()
 			m.spinner.SetMessage(m.steps[msg.StepIndex].Description)
 		}
+		m.packageProgress = nil
 		m.overallProgress = float64(msg.StepIndex) / float64(m.totalSteps)
 
 	case InstallationStepCompleteMsg:
@@ -273,6 +277,7 @@ func (m ProgressModel) Update(msg tea.Msg) (ProgressModel, tea.Cmd) {
 			m.steps[msg.StepIndex].Status = StepComplete
 			m.steps[msg.StepIndex].EndTime = time.Now()
 		}
+		m.packageProgress = nil
 		m.overallProgress = float64(msg.StepIndex+1) / float64(m.totalSteps)
 
 	case InstallationStepFailedMsg:
@@ -284,13 +289,19 @@ func (m ProgressModel) Update(msg tea.Msg) (ProgressModel, tea.Cmd) {
 		m.hasFailed = true
 		m.failureError = msg.Error
 		m.spinner.Hide()
+		m.packageProgress = nil
 
 	case InstallationLogMsg:
 		m.addLogLine(msg.Message)
 
+	case InstallationPackageProgressMsg:
+		progress := msg.Progress
+		m.packageProgress = &progress
+
 	case InstallationCompleteMsg:
 		m.isComplete = true
 		m.overallProgress = 1.0
+		m.packageProgress = nil
 		m.spinner.Hide()
 		m.header.SetSubtitle("Installation Complete")
 
@@ -364,6 +375,9 @@ func (m ProgressModel) renderContent(height int) string {
 	var currentAction string
 	if !m.isComplete && !m.hasFailed {
 		currentAction = m.spinner.View()
+		if packageProgress := m.renderPackageProgress(); packageProgress != "" {
+			currentAction += "\n" + packageProgress
+		}
 	}
 
 	sections := []string{title, "", progressBar, "", stepsList}
@@ -423,6 +437,29 @@ func (m ProgressModel) renderProgressBar() string {
 	return label + "\n" + bar
 }
 
+// renderPackageProgress renders the package being downloaded or installed
+// by the running step and the progress of the phase, or an empty string if
+// the step reports no package progress.
+func (m ProgressModel) renderPackageProgress() string {
+	if m.packageProgress == nil {
+		return ""
+	}
+
+	const barWidth = 20
+	filled := int(float64(barWidth) * m.packageProgress.Percent / 100)
+	if filled < 0 {
+		filled = 0
+	}
+	if filled > barWidth {
+		filled = barWidth
+	}
+
+	bar := m.styles.ProgressFilled.Render(strings.Repeat("\u2588", filled)) +
+		m.styles.ProgressEmpty.Render(strings.Repeat("\u2591", barWidth-filled))
+
+	return "  " + bar + " " + m.styles.Info.Render(m.packageProgress.String())
+}
+
 // renderStepsList renders the list of installation steps with status indicators.
 func (m ProgressModel) renderStepsList() string {
 	var lines []string
@@ -538,6 +575,33 @@ type InstallationLogMsg struct {
 	Message string
 }
 
+// InstallationPackageProgressMsg reports the progress of the package
+// manager run by the current step: the package being downloaded or
+// installed and the percentage of the phase.
+type InstallationPackageProgressMsg struct {
+	Progress pkg.Progress
+}
+
+// ForwardInstallationProgress returns a progress callback for an
+// installation workflow, see install.BaseWorkflow.OnProgress, sending the
+// progress to the progress view with send, such as tea.Program.Send. The
+// progress of the package manager reported by a step with
+// install.Context.ReportPackageProgress is sent as an
+// InstallationPackageProgressMsg, other progress as an InstallationLogMsg.
+func ForwardInstallationProgress(send func(tea.Msg)) func(install.StepProgress) {
+	return func(p install.StepProgress) {
+		if p.PackagePhase != "" {
+			send(InstallationPackageProgressMsg{Progress: pkg.Progress{
+				Phase:   p.PackagePhase,
+				Package: p.Package,
+				Percent: p.PackagePercent,
+			}})
+			return
+		}
+		send(InstallationLogMsg{Message: p.Message})
+	}
+}
+
 // InstallationCompleteMsg signals installation is complete.
 type InstallationCompleteMsg struct{}
 
@@ -583,6 +647,10 @@ func (m ProgressModel) Steps() []InstallationStep { return m.steps }
 // LogLines returns the current log lines.
 func (m ProgressModel) LogLines() []string { return m.logLines }
 
+// PackageProgress returns the progress of the package manager run by the
+// current step, or nil if the step reports none.
+func (m ProgressModel) PackageProgress() *pkg.Progress { return m.packageProgress }
+
 // Width returns the current width of the view.
 func (m ProgressModel) Width() int { return m.width }
 
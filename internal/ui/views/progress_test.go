@@ -11,6 +11,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/tungetti/igor/internal/gpu"
+	"github.com/tungetti/igor/internal/install"
+	"github.com/tungetti/igor/internal/pkg"
 )
 
 // =============================================================================
@@ -581,6 +583,58 @@ func TestProgressModel_Update_InstallationLogMsg_TruncatesAtMax(t *testing.T) {
 	assert.Equal(t, "Line 5", m.LogLines()[2])
 }
 
+// =============================================================================
+// Update Tests - InstallationPackageProgressMsg
+// =============================================================================
+
+func TestProgressModel_Update_InstallationPackageProgressMsg(t *testing.T) {
+	styles := getTestStyles()
+	m := NewProgress(styles, "1.0.0", nil, DriverOption{}, nil)
+	m.SetSize(80, 24)
+	m, _ = m.Update(InstallationStepStartMsg{StepIndex: 3})
+
+	progress := pkg.Progress{Phase: pkg.ProgressDownload, Package: "nvidia-driver-550", Percent: 42}
+	m, _ = m.Update(InstallationPackageProgressMsg{Progress: progress})
+
+	require.NotNil(t, m.PackageProgress())
+	assert.Equal(t, progress, *m.PackageProgress())
+	assert.Contains(t, m.View(), "Downloading nvidia-driver-550 (42%)")
+	assert.Empty(t, m.LogLines())
+
+	m, _ = m.Update(InstallationStepCompleteMsg{StepIndex: 3})
+	assert.Nil(t, m.PackageProgress())
+	assert.NotContains(t, m.View(), "nvidia-driver-550")
+}
+
+func TestProgressModel_Update_InstallationPackageProgressMsg_ClearedOnFailure(t *testing.T) {
+	styles := getTestStyles()
+	m := NewProgress(styles, "1.0.0", nil, DriverOption{}, nil)
+	m.SetSize(80, 24)
+
+	m, _ = m.Update(InstallationPackageProgressMsg{Progress: pkg.Progress{Phase: pkg.ProgressInstall, Percent: 100}})
+	assert.Contains(t, m.View(), "Installing packages (100%)")
+
+	m, _ = m.Update(InstallationStepFailedMsg{StepIndex: 0, Error: errors.New("dpkg failed")})
+	assert.Nil(t, m.PackageProgress())
+}
+
+func TestForwardInstallationProgress(t *testing.T) {
+	var sent []tea.Msg
+	forward := ForwardInstallationProgress(func(msg tea.Msg) { sent = append(sent, msg) })
+
+	step := install.NewStepProgress("packages", 2, 8, "Waiting for the dpkg lock")
+	forward(step)
+	forward(step.WithPackageProgress(pkg.Progress{Phase: pkg.ProgressInstall, Package: "nvidia-utils-550", Percent: 60}))
+
+	require.Len(t, sent, 2)
+	assert.Equal(t, InstallationLogMsg{Message: "Waiting for the dpkg lock"}, sent[0])
+	assert.Equal(t, InstallationPackageProgressMsg{Progress: pkg.Progress{
+		Phase:   pkg.ProgressInstall,
+		Package: "nvidia-utils-550",
+		Percent: 60,
+	}}, sent[1])
+}
+
 // =============================================================================
 // Update Tests - InstallationCompleteMsg
 // =============================================================================
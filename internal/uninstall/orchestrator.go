@@ -108,6 +108,8 @@ type UninstallExecutionReport struct {
 	NeedsReboot bool
 	// ExecutionLog contains all execution events.
 	ExecutionLog []UninstallExecutionEntry
+	// FailedStep is the name of the step that caused the failure, if any.
+	FailedStep string
 	// Error is the error that caused failure, if any.
 	Error error
 }
@@ -597,6 +599,7 @@ func (o *UninstallOrchestrator) generateReport(startTime time.Time, result Unins
 		NouveauRestored: result.NouveauRestored,
 		NeedsReboot:     result.NeedsReboot,
 		ExecutionLog:    executionLog,
+		FailedStep:      result.FailedStep,
 		Error:           result.Error,
 	}
 }
@@ -614,6 +617,7 @@ func (o *UninstallOrchestrator) createInstallContext(ctx *Context) *install.Cont
 		install.WithPrivilege(ctx.Privilege),
 		install.WithLogger(ctx.Logger),
 		install.WithDryRun(ctx.DryRun),
+		install.WithContext(ctx.Context()),
 	)
 }
 
@@ -923,6 +923,9 @@ func TestUninstallOrchestrator_Execute_ValidationFailure(t *testing.T) {
 	if report.Error == nil {
 		t.Error("expected error for validation failure")
 	}
+	if report.FailedStep != "step1" {
+		t.Errorf("expected failed step step1, got %q", report.FailedStep)
+	}
 }
 
 func TestUninstallOrchestrator_Execute_ProgressCallback(t *testing.T) {
@@ -227,6 +227,7 @@ func (w *BaseUninstallWorkflow) createInstallContext(ctx *Context) *install.Cont
 		install.WithPrivilege(ctx.Privilege),
 		install.WithLogger(ctx.Logger),
 		install.WithDryRun(ctx.DryRun),
+		install.WithContext(ctx.Context()),
 	)
 }
 
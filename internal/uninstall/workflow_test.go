@@ -1,6 +1,7 @@
 package uninstall
 
 import (
+	"context"
 	"errors"
 	"sync"
 	"testing"
@@ -178,6 +179,23 @@ func TestBaseUninstallWorkflow_Execute_ContextCancelled(t *testing.T) {
 	assert.Equal(t, UninstallStatusCancelled, result.Status)
 }
 
+func TestBaseUninstallWorkflow_Execute_PropagatesParentContext(t *testing.T) {
+	w := NewUninstallWorkflow("test")
+
+	parent, cancel := context.WithCancel(context.Background())
+	var stepCancelled bool
+	w.AddStep(install.NewFuncStep("check", "Check cancellation", func(ctx *install.Context) install.StepResult {
+		cancel()
+		stepCancelled = ctx.IsCancelled()
+		return install.CompleteStep("checked")
+	}))
+
+	result := w.Execute(NewUninstallContext(WithUninstallContext(parent)))
+
+	assert.Equal(t, UninstallStatusCompleted, result.Status)
+	assert.True(t, stepCancelled)
+}
+
 func TestBaseUninstallWorkflow_Execute_EmptyWorkflow(t *testing.T) {
 	w := NewUninstallWorkflow("empty")
 